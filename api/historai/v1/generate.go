@@ -0,0 +1,5 @@
+// Package historaiv1 holds the Go code generated from historai.proto, the
+// versioned gRPC API served by 'historai grpc'.
+package historaiv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative historai/v1/historai.proto
@@ -0,0 +1,570 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: historai/v1/historai.proto
+
+package historaiv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FindRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindRequest) Reset() {
+	*x = FindRequest{}
+	mi := &file_historai_v1_historai_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindRequest) ProtoMessage() {}
+
+func (x *FindRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindRequest.ProtoReflect.Descriptor instead.
+func (*FindRequest) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FindRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *FindRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SuggestRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Query            string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Limit            int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	NoHistoryContext bool                   `protobuf:"varint,3,opt,name=no_history_context,json=noHistoryContext,proto3" json:"no_history_context,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SuggestRequest) Reset() {
+	*x = SuggestRequest{}
+	mi := &file_historai_v1_historai_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuggestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuggestRequest) ProtoMessage() {}
+
+func (x *SuggestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuggestRequest.ProtoReflect.Descriptor instead.
+func (*SuggestRequest) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SuggestRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SuggestRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SuggestRequest) GetNoHistoryContext() bool {
+	if x != nil {
+		return x.NoHistoryContext
+	}
+	return false
+}
+
+type CommandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Commands      []*CommandResult       `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResponse) Reset() {
+	*x = CommandResponse{}
+	mi := &file_historai_v1_historai_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResponse) ProtoMessage() {}
+
+func (x *CommandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResponse.ProtoReflect.Descriptor instead.
+func (*CommandResponse) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CommandResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *CommandResponse) GetCommands() []*CommandResult {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+func (x *CommandResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type CommandResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Explanation   string                 `protobuf:"bytes,2,opt,name=explanation,proto3" json:"explanation,omitempty"`
+	Risk          string                 `protobuf:"bytes,3,opt,name=risk,proto3" json:"risk,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandResult) Reset() {
+	*x = CommandResult{}
+	mi := &file_historai_v1_historai_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResult) ProtoMessage() {}
+
+func (x *CommandResult) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResult.ProtoReflect.Descriptor instead.
+func (*CommandResult) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CommandResult) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *CommandResult) GetExplanation() string {
+	if x != nil {
+		return x.Explanation
+	}
+	return ""
+}
+
+func (x *CommandResult) GetRisk() string {
+	if x != nil {
+		return x.Risk
+	}
+	return ""
+}
+
+func (x *CommandResult) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type CommandChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Commands      []*CommandResult       `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandChunk) Reset() {
+	*x = CommandChunk{}
+	mi := &file_historai_v1_historai_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandChunk) ProtoMessage() {}
+
+func (x *CommandChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandChunk.ProtoReflect.Descriptor instead.
+func (*CommandChunk) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CommandChunk) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *CommandChunk) GetCommands() []*CommandResult {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+func (x *CommandChunk) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type HistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_historai_v1_historai_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type HistoryEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_historai_v1_historai_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HistoryEntry) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type HistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryResponse) Reset() {
+	*x = HistoryResponse{}
+	mi := &file_historai_v1_historai_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryResponse) ProtoMessage() {}
+
+func (x *HistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_historai_v1_historai_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryResponse.ProtoReflect.Descriptor instead.
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return file_historai_v1_historai_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+var File_historai_v1_historai_proto protoreflect.FileDescriptor
+
+const file_historai_v1_historai_proto_rawDesc = "" +
+	"\n" +
+	"\x1ahistorai/v1/historai.proto\x12\vhistorai.v1\"9\n" +
+	"\vFindRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"j\n" +
+	"\x0eSuggestRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12,\n" +
+	"\x12no_history_context\x18\x03 \x01(\bR\x10noHistoryContext\"{\n" +
+	"\x0fCommandResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\x126\n" +
+	"\bcommands\x18\x02 \x03(\v2\x1a.historai.v1.CommandResultR\bcommands\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"w\n" +
+	"\rCommandResult\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\tR\acommand\x12 \n" +
+	"\vexplanation\x18\x02 \x01(\tR\vexplanation\x12\x12\n" +
+	"\x04risk\x18\x03 \x01(\tR\x04risk\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\"t\n" +
+	"\fCommandChunk\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x126\n" +
+	"\bcommands\x18\x02 \x03(\v2\x1a.historai.v1.CommandResultR\bcommands\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"&\n" +
+	"\x0eHistoryRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"F\n" +
+	"\fHistoryEntry\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x18\n" +
+	"\acommand\x18\x02 \x01(\tR\acommand\"F\n" +
+	"\x0fHistoryResponse\x123\n" +
+	"\aentries\x18\x01 \x03(\v2\x19.historai.v1.HistoryEntryR\aentries2\xe6\x02\n" +
+	"\bHistorai\x12>\n" +
+	"\x04Find\x12\x18.historai.v1.FindRequest\x1a\x1c.historai.v1.CommandResponse\x12D\n" +
+	"\aSuggest\x12\x1b.historai.v1.SuggestRequest\x1a\x1c.historai.v1.CommandResponse\x12C\n" +
+	"\n" +
+	"StreamFind\x12\x18.historai.v1.FindRequest\x1a\x19.historai.v1.CommandChunk0\x01\x12I\n" +
+	"\rStreamSuggest\x12\x1b.historai.v1.SuggestRequest\x1a\x19.historai.v1.CommandChunk0\x01\x12D\n" +
+	"\aHistory\x12\x1b.historai.v1.HistoryRequest\x1a\x1c.historai.v1.HistoryResponseB?Z=github.com/sanspareilsmyn/historai/api/historai/v1;historaiv1b\x06proto3"
+
+var (
+	file_historai_v1_historai_proto_rawDescOnce sync.Once
+	file_historai_v1_historai_proto_rawDescData []byte
+)
+
+func file_historai_v1_historai_proto_rawDescGZIP() []byte {
+	file_historai_v1_historai_proto_rawDescOnce.Do(func() {
+		file_historai_v1_historai_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_historai_v1_historai_proto_rawDesc), len(file_historai_v1_historai_proto_rawDesc)))
+	})
+	return file_historai_v1_historai_proto_rawDescData
+}
+
+var file_historai_v1_historai_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_historai_v1_historai_proto_goTypes = []any{
+	(*FindRequest)(nil),     // 0: historai.v1.FindRequest
+	(*SuggestRequest)(nil),  // 1: historai.v1.SuggestRequest
+	(*CommandResponse)(nil), // 2: historai.v1.CommandResponse
+	(*CommandResult)(nil),   // 3: historai.v1.CommandResult
+	(*CommandChunk)(nil),    // 4: historai.v1.CommandChunk
+	(*HistoryRequest)(nil),  // 5: historai.v1.HistoryRequest
+	(*HistoryEntry)(nil),    // 6: historai.v1.HistoryEntry
+	(*HistoryResponse)(nil), // 7: historai.v1.HistoryResponse
+}
+var file_historai_v1_historai_proto_depIdxs = []int32{
+	3, // 0: historai.v1.CommandResponse.commands:type_name -> historai.v1.CommandResult
+	3, // 1: historai.v1.CommandChunk.commands:type_name -> historai.v1.CommandResult
+	6, // 2: historai.v1.HistoryResponse.entries:type_name -> historai.v1.HistoryEntry
+	0, // 3: historai.v1.Historai.Find:input_type -> historai.v1.FindRequest
+	1, // 4: historai.v1.Historai.Suggest:input_type -> historai.v1.SuggestRequest
+	0, // 5: historai.v1.Historai.StreamFind:input_type -> historai.v1.FindRequest
+	1, // 6: historai.v1.Historai.StreamSuggest:input_type -> historai.v1.SuggestRequest
+	5, // 7: historai.v1.Historai.History:input_type -> historai.v1.HistoryRequest
+	2, // 8: historai.v1.Historai.Find:output_type -> historai.v1.CommandResponse
+	2, // 9: historai.v1.Historai.Suggest:output_type -> historai.v1.CommandResponse
+	4, // 10: historai.v1.Historai.StreamFind:output_type -> historai.v1.CommandChunk
+	4, // 11: historai.v1.Historai.StreamSuggest:output_type -> historai.v1.CommandChunk
+	7, // 12: historai.v1.Historai.History:output_type -> historai.v1.HistoryResponse
+	8, // [8:13] is the sub-list for method output_type
+	3, // [3:8] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_historai_v1_historai_proto_init() }
+func file_historai_v1_historai_proto_init() {
+	if File_historai_v1_historai_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_historai_v1_historai_proto_rawDesc), len(file_historai_v1_historai_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_historai_v1_historai_proto_goTypes,
+		DependencyIndexes: file_historai_v1_historai_proto_depIdxs,
+		MessageInfos:      file_historai_v1_historai_proto_msgTypes,
+	}.Build()
+	File_historai_v1_historai_proto = out.File
+	file_historai_v1_historai_proto_goTypes = nil
+	file_historai_v1_historai_proto_depIdxs = nil
+}
@@ -5,11 +5,14 @@ import (
 	"os"
 
 	"github.com/sanspareilsmyn/historai/internal/cli"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
 )
 
 func main() {
 	if err := cli.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if !cli.IsSilent(err) {
+			fmt.Fprint(os.Stderr, i18n.T("Error: %v\n", cli.ErrorMessage(err)))
+		}
+		os.Exit(cli.ExitCode(err))
 	}
 }
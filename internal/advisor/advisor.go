@@ -0,0 +1,377 @@
+// Package advisor notices, locally and without any LLM call, classic tools the user
+// keeps reaching for where a modern alternative would serve them better, e.g.
+// "grep -r" where ripgrep is faster and skips ignored files.
+package advisor
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Package managers a Tip has install commands for.
+const (
+	Brew   = "brew"
+	Apt    = "apt"
+	Dnf    = "dnf"
+	Pacman = "pacman"
+	Cargo  = "cargo"
+)
+
+// Tip recommends a modern tool in place of commands the user runs often.
+type Tip struct {
+	// Tool is the recommended tool, e.g. "ripgrep".
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Replaces describes the commands it replaces, e.g. "grep -r".
+	Replaces string `json:"replaces" yaml:"replaces"`
+
+	// Why says in a few words what the tool does better.
+	Why string `json:"why" yaml:"why"`
+
+	// Count is how many of the user's commands the tool would have replaced.
+	Count int `json:"count" yaml:"count"`
+
+	// Install maps package managers to the command installing the tool with it.
+	Install map[string]string `json:"install" yaml:"install"`
+
+	// Examples show the tool in use. The first ones are the user's own most frequent
+	// commands rewritten for the tool, when they could be translated.
+	Examples []Example `json:"examples" yaml:"examples"`
+}
+
+// Example is a command for the tool, with the classic command it replaces if any.
+type Example struct {
+	Before string `json:"before,omitempty" yaml:"before,omitempty"`
+	After  string `json:"after" yaml:"after"`
+}
+
+// advice describes when to recommend a tool.
+type advice struct {
+	tool     string
+	replaces string
+	why      string
+
+	// binaries are the tool's executable names; running any of them means the user
+	// already has it.
+	binaries []string
+
+	// minCount is how many matching commands it takes to recommend the tool.
+	minCount int
+
+	// matches reports whether a command is one the tool replaces.
+	matches func(fields []string) bool
+
+	// translate rewrites a matching command for the tool, or returns "" if it can't.
+	translate func(fields []string) string
+
+	install  map[string]string
+	examples []Example
+}
+
+// advices lists the tools the advisor knows about.
+var advices = []advice{
+	{
+		tool:      "ripgrep",
+		replaces:  "grep -r",
+		why:       "much faster recursive search that skips .gitignore'd and binary files",
+		binaries:  []string{"rg"},
+		minCount:  5,
+		matches:   isRecursiveGrep,
+		translate: translateGrep,
+		install:   map[string]string{Brew: "brew install ripgrep", Apt: "sudo apt install ripgrep", Dnf: "sudo dnf install ripgrep", Pacman: "sudo pacman -S ripgrep", Cargo: "cargo install ripgrep"},
+		examples: []Example{
+			{After: "rg -n 'TODO'"},
+			{After: "rg -l 'func main' --type go"},
+		},
+	},
+	{
+		tool:      "fd",
+		replaces:  "find . -name",
+		why:       "simpler syntax, faster, and skips .gitignore'd files",
+		binaries:  []string{"fd", "fdfind"},
+		minCount:  5,
+		matches:   isFindByName,
+		translate: translateFind,
+		install:   map[string]string{Brew: "brew install fd", Apt: "sudo apt install fd-find  # the command is fdfind", Dnf: "sudo dnf install fd-find", Pacman: "sudo pacman -S fd", Cargo: "cargo install fd-find"},
+		examples: []Example{
+			{Before: "find . -name '*.go'", After: "fd -e go"},
+			{Before: "find . -type d -name node_modules", After: "fd -t d node_modules"},
+		},
+	},
+	{
+		tool:     "zoxide",
+		replaces: "cd into deep paths",
+		why:      "jumps to frequent directories from a few letters of their name",
+		binaries: []string{"zoxide", "z"},
+		minCount: 20,
+		matches:  isDeepCd,
+		install:  map[string]string{Brew: "brew install zoxide", Apt: "sudo apt install zoxide", Dnf: "sudo dnf install zoxide", Pacman: "sudo pacman -S zoxide", Cargo: "cargo install zoxide"},
+		examples: []Example{
+			{After: `eval "$(zoxide init zsh)"  # in ~/.zshrc; use bash for ~/.bashrc`},
+			{Before: "cd ~/work/projects/api/internal", After: "z api int"},
+		},
+	},
+	{
+		tool:     "bat",
+		replaces: "cat on source files",
+		why:      "syntax highlighting, line numbers and git changes, and pages long files",
+		binaries: []string{"bat", "batcat"},
+		minCount: 10,
+		matches:  isCatSource,
+		translate: func(fields []string) string {
+			return "bat " + strings.Join(fields[1:], " ")
+		},
+		install: map[string]string{Brew: "brew install bat", Apt: "sudo apt install bat  # the command is batcat", Dnf: "sudo dnf install bat", Pacman: "sudo pacman -S bat", Cargo: "cargo install bat"},
+		examples: []Example{
+			{After: "bat -r 10:40 main.go"},
+		},
+	},
+	{
+		tool:     "tldr",
+		replaces: "man",
+		why:      "short, example-first summaries of commands",
+		binaries: []string{"tldr", "tlrc"},
+		minCount: 5,
+		matches:  func(fields []string) bool { return fields[0] == "man" && len(fields) == 2 },
+		translate: func(fields []string) string {
+			return "tldr " + fields[1]
+		},
+		install: map[string]string{Brew: "brew install tlrc", Apt: "sudo apt install tldr", Dnf: "sudo dnf install tldr", Pacman: "sudo pacman -S tldr", Cargo: "cargo install tlrc"},
+	},
+}
+
+// maxTranslatedExamples bounds the number of the user's own commands shown rewritten.
+const maxTranslatedExamples = 2
+
+// Advise returns the tools worth recommending given entries, best first: those the
+// user's commands match often enough, unless the user already runs the tool or
+// installed reports it is installed.
+func Advise(entries []history.HistoryEntry, installed func(binary string) bool) []Tip {
+	used := make(map[string]bool)
+	matched := make([]map[string]int, len(advices))
+	for _, entry := range entries {
+		fields := strings.Fields(entry.Command)
+		if len(fields) > 0 && fields[0] == "sudo" {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		used[fields[0]] = true
+		for i, a := range advices {
+			if a.matches(fields) {
+				if matched[i] == nil {
+					matched[i] = make(map[string]int)
+				}
+				matched[i][strings.Join(fields, " ")]++
+			}
+		}
+	}
+
+	var tips []Tip
+	for i, a := range advices {
+		count := 0
+		for _, n := range matched[i] {
+			count += n
+		}
+		if count < a.minCount || hasAny(a.binaries, func(binary string) bool { return used[binary] || installed(binary) }) {
+			continue
+		}
+		tip := Tip{Tool: a.tool, Replaces: a.replaces, Why: a.why, Count: count, Install: a.install}
+		if a.translate != nil {
+			tip.Examples = translated(matched[i], a.translate)
+		}
+		for _, example := range a.examples {
+			if !hasExample(tip.Examples, example.After) {
+				tip.Examples = append(tip.Examples, example)
+			}
+		}
+		tips = append(tips, tip)
+	}
+	sort.SliceStable(tips, func(i, j int) bool { return tips[i].Count > tips[j].Count })
+	return tips
+}
+
+// translated rewrites the user's most frequent matching commands with translate.
+func translated(commands map[string]int, translate func(fields []string) string) []Example {
+	sorted := make([]string, 0, len(commands))
+	for command := range commands {
+		sorted = append(sorted, command)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if commands[sorted[i]] != commands[sorted[j]] {
+			return commands[sorted[i]] > commands[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	var examples []Example
+	for _, command := range sorted {
+		if after := translate(strings.Fields(command)); after != "" {
+			examples = append(examples, Example{Before: command, After: after})
+		}
+		if len(examples) == maxTranslatedExamples {
+			break
+		}
+	}
+	return examples
+}
+
+// hasExample reports whether examples already show the command after.
+func hasExample(examples []Example, after string) bool {
+	for _, example := range examples {
+		if example.After == after {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAny reports whether predicate holds for any of values.
+func hasAny(values []string, predicate func(string) bool) bool {
+	for _, value := range values {
+		if predicate(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRecursiveGrep reports whether fields run grep recursively.
+func isRecursiveGrep(fields []string) bool {
+	if fields[0] != "grep" && fields[0] != "egrep" {
+		return false
+	}
+	for _, field := range fields[1:] {
+		if field == "--recursive" || field == "--dereference-recursive" || (isShortFlags(field) && strings.ContainsAny(field, "rR")) {
+			return true
+		}
+	}
+	return false
+}
+
+// translateGrep rewrites a recursive grep for ripgrep, which searches recursively
+// and shows line numbers by default. Flags ripgrep doesn't share are not translated.
+func translateGrep(fields []string) string {
+	args := []string{"rg"}
+	for _, field := range fields[1:] {
+		switch {
+		case field == "--recursive" || field == "--dereference-recursive":
+		case strings.HasPrefix(field, "--include="):
+			args = append(args, "-g", quoteGlob(strings.Trim(strings.TrimPrefix(field, "--include="), `'"`)))
+		case isShortFlags(field):
+			flags := strings.NewReplacer("r", "", "R", "", "n", "", "I", "", "E", "").Replace(field[1:])
+			for _, flag := range flags {
+				if !strings.ContainsRune("ilvwcoFx", flag) {
+					return ""
+				}
+			}
+			if flags != "" {
+				args = append(args, "-"+flags)
+			}
+		case strings.HasPrefix(field, "-"):
+			return ""
+		default:
+			args = append(args, field)
+		}
+	}
+	// rg searches the current directory by default.
+	if args[len(args)-1] == "." {
+		args = args[:len(args)-1]
+	}
+	return strings.Join(args, " ")
+}
+
+// isShortFlags reports whether field is a cluster of short flags, e.g. -rn.
+func isShortFlags(field string) bool {
+	return len(field) > 1 && field[0] == '-' && field[1] != '-'
+}
+
+// isFindByName reports whether fields run find with -name or -iname.
+func isFindByName(fields []string) bool {
+	if fields[0] != "find" {
+		return false
+	}
+	for _, field := range fields[1:] {
+		if field == "-name" || field == "-iname" {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionGlob matches a name pattern selecting an extension, e.g. "*.go".
+var extensionGlob = regexp.MustCompile(`^\*\.([A-Za-z0-9]+)$`)
+
+// translateFind rewrites a find by name for fd. Only a search root, -type f/d and
+// a single -name or -iname are translated.
+func translateFind(fields []string) string {
+	args := []string{"fd"}
+	var root, pattern string
+	for i := 1; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case (field == "-name" || field == "-iname") && i+1 < len(fields) && pattern == "":
+			i++
+			pattern = strings.Trim(fields[i], `'"`)
+			if field == "-iname" {
+				args = append(args, "-i")
+			}
+		case field == "-type" && i+1 < len(fields) && (fields[i+1] == "f" || fields[i+1] == "d"):
+			i++
+			args = append(args, "-t", fields[i])
+		case i == 1 && !strings.HasPrefix(field, "-"):
+			root = field
+		default:
+			return ""
+		}
+	}
+	if pattern == "" {
+		return ""
+	}
+	if match := extensionGlob.FindStringSubmatch(pattern); match != nil {
+		args = append(args, "-e", match[1])
+	} else {
+		// Like find -name, fd -g matches whole names; fd's default regex matches anywhere.
+		args = append(args, "-g", quoteGlob(pattern))
+	}
+	if root != "" && root != "." {
+		if args[len(args)-2] == "-e" {
+			args = append(args, ".")
+		}
+		args = append(args, root)
+	}
+	return strings.Join(args, " ")
+}
+
+// quoteGlob single-quotes a glob pattern so the shell leaves it to the tool.
+func quoteGlob(pattern string) string {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pattern
+	}
+	return "'" + pattern + "'"
+}
+
+// isDeepCd reports whether fields change into a directory at least three levels
+// away, which zoxide would reach with one word.
+func isDeepCd(fields []string) bool {
+	return fields[0] == "cd" && len(fields) == 2 && strings.Count(strings.Trim(fields[1], "/"), "/") >= 2
+}
+
+// sourceExtensions are file extensions cat is used to read as code.
+var sourceExtensions = map[string]bool{
+	"go": true, "py": true, "js": true, "ts": true, "tsx": true, "rs": true, "java": true, "c": true,
+	"h": true, "cpp": true, "rb": true, "sh": true, "json": true, "yaml": true, "yml": true,
+	"toml": true, "md": true, "sql": true, "tf": true,
+}
+
+// isCatSource reports whether fields cat a single source file.
+func isCatSource(fields []string) bool {
+	if fields[0] != "cat" || len(fields) != 2 {
+		return false
+	}
+	dot := strings.LastIndex(fields[1], ".")
+	return dot >= 0 && sourceExtensions[strings.ToLower(fields[1][dot+1:])]
+}
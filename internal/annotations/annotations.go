@@ -0,0 +1,311 @@
+// Package annotations keeps what the user attaches to commands, such as notes, tags
+// and pins, in a sidecar file next to historai's history log. Shell history files are never
+// modified; annotations are keyed by command line and apply to every run of it.
+package annotations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/atrest"
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+const fileName = "annotations.json"
+
+// Annotation is what the user attached to a command line.
+type Annotation struct {
+	Note    string    `json:"note,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+	Pinned  bool      `json:"pinned,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// empty reports whether nothing is attached any more, so the annotation can be dropped.
+func (a *Annotation) empty() bool {
+	return a.Note == "" && len(a.Tags) == 0 && !a.Pinned
+}
+
+// HasTag reports whether the annotation carries tag.
+func (a *Annotation) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Store holds the annotations of all command lines, keyed by the trimmed command.
+type Store struct {
+	path     string
+	Commands map[string]*Annotation `json:"commands"`
+
+	// encrypted reports that the file is encrypted at rest, and is saved so.
+	encrypted bool
+}
+
+// DefaultPath returns the sidecar file in historai's data directory.
+func DefaultPath() (string, error) {
+	dir, err := history.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the store at path (DefaultPath when empty). A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	if path == "" {
+		defaultPath, err := DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	store := &Store{path: path, Commands: make(map[string]*Annotation)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations %s: %w", path, err)
+	}
+	if atrest.Sealed(data) {
+		if data, err = atrest.Open(data); err != nil {
+			return nil, fmt.Errorf("failed to read annotations %s: %w", path, err)
+		}
+		store.encrypted = true
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid annotations file %s: %w", path, err)
+	}
+	if store.Commands == nil {
+		store.Commands = make(map[string]*Annotation)
+	}
+	return store, nil
+}
+
+// Path returns the file the store is saved to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Encrypted reports whether the store is encrypted at rest.
+func (s *Store) Encrypted() bool {
+	return s.encrypted
+}
+
+// SetEncrypted sets whether Save encrypts the store with atrest.
+func (s *Store) SetEncrypted(encrypted bool) {
+	s.encrypted = encrypted
+}
+
+// Get returns the annotation of command, or nil.
+func (s *Store) Get(command string) *Annotation {
+	return s.Commands[key(command)]
+}
+
+// SetNote attaches note to command, replacing any previous note. An empty note removes it.
+func (s *Store) SetNote(command, note string) {
+	s.update(command, func(a *Annotation) { a.Note = strings.TrimSpace(note) })
+}
+
+// NormalizeTag lowercases tag and checks it is a single word, so tags can be listed
+// and typed reliably.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !validTag.MatchString(tag) {
+		return "", fmt.Errorf("invalid tag %q: use letters, digits, '-', '_', '.' or '/'", tag)
+	}
+	return tag, nil
+}
+
+var validTag = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_./-]*$`)
+
+// AddTag adds tag to command. It reports false if command already had it.
+func (s *Store) AddTag(command, tag string) bool {
+	if annotation := s.Get(command); annotation != nil && annotation.HasTag(tag) {
+		return false
+	}
+	s.update(command, func(a *Annotation) {
+		a.Tags = append(a.Tags, tag)
+		sort.Strings(a.Tags)
+	})
+	return true
+}
+
+// RemoveTag removes tag from command. It reports false if command didn't have it.
+func (s *Store) RemoveTag(command, tag string) bool {
+	if annotation := s.Get(command); annotation == nil || !annotation.HasTag(tag) {
+		return false
+	}
+	s.update(command, func(a *Annotation) {
+		kept := a.Tags[:0]
+		for _, t := range a.Tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		a.Tags = kept
+	})
+	return true
+}
+
+// SetPinned pins or unpins command. It reports false if nothing changed.
+func (s *Store) SetPinned(command string, pinned bool) bool {
+	annotation := s.Get(command)
+	if (annotation != nil && annotation.Pinned) == pinned {
+		return false
+	}
+	s.update(command, func(a *Annotation) { a.Pinned = pinned })
+	return true
+}
+
+// Pinned returns the pinned command lines, most recently changed first.
+func (s *Store) Pinned() []string {
+	var commands []string
+	for _, command := range s.Sorted() {
+		if s.Commands[command].Pinned {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// TagCounts returns how many command lines carry each tag.
+func (s *Store) TagCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, annotation := range s.Commands {
+		for _, tag := range annotation.Tags {
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// Tagged returns the command lines carrying tag, most recently updated first.
+func (s *Store) Tagged(tag string) []string {
+	var commands []string
+	for _, command := range s.Sorted() {
+		if s.Commands[command].HasTag(tag) {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// Remove drops the note, tags and pin of command. It reports whether there were any.
+func (s *Store) Remove(command string) bool {
+	k := key(command)
+	if _, ok := s.Commands[k]; !ok {
+		return false
+	}
+	delete(s.Commands, k)
+	return true
+}
+
+// update applies change to the annotation of command, creating or dropping it as needed.
+func (s *Store) update(command string, change func(a *Annotation)) {
+	k := key(command)
+	annotation := s.Commands[k]
+	if annotation == nil {
+		annotation = &Annotation{}
+	}
+	change(annotation)
+	annotation.Updated = time.Now().UTC()
+	if annotation.empty() {
+		delete(s.Commands, k)
+		return
+	}
+	s.Commands[k] = annotation
+}
+
+// Sorted returns the annotated command lines, most recently updated first.
+func (s *Store) Sorted() []string {
+	commands := make([]string, 0, len(s.Commands))
+	for command := range s.Commands {
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return s.Commands[commands[i]].Updated.After(s.Commands[commands[j]].Updated)
+	})
+	return commands
+}
+
+// Save writes the store to a temporary file and renames it over the previous one,
+// encrypting it if it is encrypted at rest.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode annotations: %w", err)
+	}
+	if s.encrypted {
+		if data, err = atrest.Seal(data); err != nil {
+			return fmt.Errorf("failed to encrypt annotations: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(s.path), err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	return nil
+}
+
+// Apply returns a copy of entries with the notes, tags and pins of annotated command lines set.
+func (s *Store) Apply(entries []history.HistoryEntry) []history.HistoryEntry {
+	if len(s.Commands) == 0 {
+		return entries
+	}
+	annotated := make([]history.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		if annotation := s.Commands[key(entry.Command)]; annotation != nil {
+			entry.Note = annotation.Note
+			entry.Tags = annotation.Tags
+			entry.Pinned = annotation.Pinned
+		}
+		annotated[i] = entry
+	}
+	return annotated
+}
+
+// Unlisted returns entries for the annotated command lines selected by keep that are
+// missing from entries, e.g. because they were run before the history window, most
+// recently updated first. A nil keep selects all annotated command lines.
+func (s *Store) Unlisted(entries []history.HistoryEntry, keep func(a *Annotation) bool) []history.HistoryEntry {
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[key(entry.Command)] = true
+	}
+	var unlisted []history.HistoryEntry
+	for _, command := range s.Sorted() {
+		annotation := s.Commands[command]
+		if !present[command] && (keep == nil || keep(annotation)) {
+			unlisted = append(unlisted, history.HistoryEntry{
+				Command: command,
+				Note:    annotation.Note,
+				Tags:    annotation.Tags,
+				Pinned:  annotation.Pinned,
+			})
+		}
+	}
+	return unlisted
+}
+
+// key normalizes a command line for lookups.
+func key(command string) string {
+	return strings.TrimSpace(command)
+}
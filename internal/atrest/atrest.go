@@ -0,0 +1,153 @@
+// Package atrest encrypts historai's own files at rest, such as the shell hook's
+// log and the annotations, with AES-256-GCM and a key kept in the OS keyring, so
+// that commands, their output and notes aren't left readable on shared machines.
+//
+// Sealed data is text: a prefix followed by the base64-encoded nonce and ciphertext.
+// The log seals each line on its own, so that shells can keep appending to it.
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+// prefix starts sealed data. It can't start JSON, so sealed and plain lines can be
+// told apart.
+const prefix = "historai-sealed:v1:"
+
+const keySize = 32
+
+var (
+	keyMu sync.Mutex
+	key   []byte
+)
+
+// Sealed reports whether data was sealed by Seal.
+func Sealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(prefix))
+}
+
+// FileSealed reports whether the file at path starts with sealed data. A missing
+// file isn't sealed.
+func FileSealed(path string) (bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	head := make([]byte, len(prefix))
+	if _, err := io.ReadFull(file, head); err != nil {
+		return false, nil
+	}
+	return Sealed(head), nil
+}
+
+// Seal encrypts plain with the key from the OS keyring.
+func Seal(plain []byte) ([]byte, error) {
+	aead, err := newAEAD()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plain, nil)
+	out := make([]byte, len(prefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, prefix)
+	base64.StdEncoding.Encode(out[len(prefix):], sealed)
+	return out, nil
+}
+
+// Open decrypts data sealed by Seal.
+func Open(data []byte) ([]byte, error) {
+	if !Sealed(data) {
+		return nil, errors.New("data is not encrypted")
+	}
+	aead, err := newAEAD()
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, base64.StdEncoding.DecodedLen(len(data)-len(prefix)))
+	n, err := base64.StdEncoding.Decode(raw, bytes.TrimRight(data[len(prefix):], "\r\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	raw = raw[:n]
+	if len(raw) < aead.NonceSize() {
+		return nil, errors.New("failed to decrypt: data is truncated")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt (was it encrypted with a different key?)")
+	}
+	return plain, nil
+}
+
+// newAEAD returns the cipher for the key from the OS keyring, which is read once.
+func newAEAD() (cipher.AEAD, error) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if key == nil {
+		encoded, err := config.LoadEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := decodeKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		key = decoded
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// decodeKey decodes a key as stored in the keyring.
+func decodeKey(encoded string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) != keySize {
+		return nil, fmt.Errorf("invalid encryption key: want %d base64-encoded bytes", keySize)
+	}
+	return decoded, nil
+}
+
+// EnsureKey makes sure an encryption key is available, generating one and storing
+// it in the OS keyring if needed. It reports whether a key was created.
+func EnsureKey() (bool, error) {
+	encoded, err := config.LoadEncryptionKey()
+	if err == nil {
+		_, err = decodeKey(encoded)
+		return false, err
+	}
+	if !errors.Is(err, config.ErrNoEncryptionKey) {
+		return false, err
+	}
+	fresh := make([]byte, keySize)
+	if _, err := rand.Read(fresh); err != nil {
+		return false, fmt.Errorf("failed to generate encryption key: %w", err)
+	}
+	if err := config.StoreEncryptionKey(base64.StdEncoding.EncodeToString(fresh)); err != nil {
+		return false, err
+	}
+	keyMu.Lock()
+	key = fresh
+	keyMu.Unlock()
+	return true, nil
+}
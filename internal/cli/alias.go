@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/stats"
+)
+
+const (
+	defaultAliasTop = 10
+)
+
+// shellReserved are builtins and keywords an alias must not shadow.
+var shellReserved = map[string]bool{
+	"alias": true, "bg": true, "break": true, "builtin": true, "case": true, "cd": true, "command": true,
+	"continue": true, "do": true, "done": true, "echo": true, "elif": true, "else": true, "esac": true,
+	"eval": true, "exec": true, "exit": true, "export": true, "fc": true, "fg": true, "fi": true,
+	"for": true, "function": true, "hash": true, "if": true, "in": true, "jobs": true, "kill": true,
+	"let": true, "local": true, "read": true, "return": true, "select": true, "set": true, "shift": true,
+	"source": true, "test": true, "then": true, "time": true, "trap": true, "type": true, "ulimit": true,
+	"umask": true, "unalias": true, "unset": true, "until": true, "wait": true, "while": true,
+}
+
+// rcDefinition matches alias and function definitions in a shell rc file.
+var rcDefinition = regexp.MustCompile(`^\s*(?:alias\s+([A-Za-z0-9_.-]+)=(.*)|(?:function\s+)?([A-Za-z0-9_.-]+)\s*\(\)(.*)|function\s+([A-Za-z0-9_.-]+))`)
+
+// aliasCmd represents the alias command
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Suggest shell aliases for long commands you type often",
+	Long: `Finds long commands you type repeatedly and proposes shell aliases for them,
+computed locally without any LLM call. Commands typed with different arguments
+at the end become aliases of their common part; when the argument that varies is
+in the middle, a function taking it as $1 is proposed instead.
+
+Names are checked against the aliases and functions in your rc file, commands on
+your PATH and shell builtins, so that nothing is shadowed. Commands you already
+have an alias for are skipped.
+
+Flags:
+  --since / -s : Only look at commands run since today, yesterday, 12h, 3d, 2w or a date like 2024-05-01.
+  --top / -t   : How many aliases to propose (default: 10).
+  --rc         : The rc file to check and write to (default: ~/.zshrc or ~/.bashrc, from $SHELL).
+  --write / -w : Append the aliases to the rc file after confirmation.
+  --yes / -y   : Don't ask for confirmation before writing.
+
+Example:
+  historai alias
+  historai alias --since 1w --top 5
+  historai alias --write`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parseAliasFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Read history and find the candidates
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		entries, err := reader.ReadHistory(0)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		if !opts.since.IsZero() {
+			entries = entriesSince(entries, opts.since)
+		}
+		existing, err := readRCDefinitions(opts.rc)
+		if err != nil {
+			return err
+		}
+		// Ask for extra candidates, as some are dropped for being aliased already.
+		candidates := stats.Aliases(entries, opts.top+len(existing.commands))
+		logger.Debug("Found alias candidates", zap.Int("candidates_count", len(candidates)), zap.String("rc", opts.rc))
+
+		// 3. Name them, avoiding collisions
+		definitions := nameAliases(candidates, existing, opts.top)
+		if len(definitions) == 0 {
+			if err := infof("No long commands typed often enough to need an alias.\n"); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+
+		// 4. Print the definitions or append them to the rc file
+		if !opts.write {
+			_, err := fmt.Fprint(os.Stdout, formatAliases(definitions, !color.NoColor && !rawOutput))
+			return err
+		}
+		if !opts.yes {
+			if _, err := fmt.Fprint(os.Stderr, formatAliases(definitions, !color.NoColor)+"\n"); err != nil {
+				return err
+			}
+			ok, err := confirm(i18n.T("Append these definitions to %s?", opts.rc))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		return appendRC(opts.rc, formatAliases(definitions, false))
+	},
+}
+
+// aliasOptions holds the parsed flags of the alias command.
+type aliasOptions struct {
+	since time.Time
+	top   int
+	rc    string
+	write bool
+	yes   bool
+}
+
+// parseAliasFlags extracts and validates flags specific to the alias command.
+func parseAliasFlags(cmd *cobra.Command) (aliasOptions, error) {
+	var opts aliasOptions
+	var err error
+	flags := cmd.Flags()
+
+	sinceValue, err := flags.GetString("since")
+	if err != nil {
+		return opts, fmt.Errorf("internal error getting since flag: %w", err)
+	}
+	if sinceValue != "" {
+		if opts.since, err = parseSince(sinceValue, time.Now()); err != nil {
+			return opts, err
+		}
+	}
+	if opts.top, err = flags.GetInt("top"); err != nil {
+		return opts, fmt.Errorf("internal error getting top flag: %w", err)
+	}
+	if opts.top < 1 {
+		return opts, fmt.Errorf("--top must be at least 1")
+	}
+	if opts.rc, err = flags.GetString("rc"); err != nil {
+		return opts, fmt.Errorf("internal error getting rc flag: %w", err)
+	}
+	if opts.rc == "" {
+		if opts.rc, err = defaultRCFile(); err != nil {
+			return opts, err
+		}
+	}
+	if opts.write, err = flags.GetBool("write"); err != nil {
+		return opts, fmt.Errorf("internal error getting write flag: %w", err)
+	}
+	if opts.yes, err = flags.GetBool("yes"); err != nil {
+		return opts, fmt.Errorf("internal error getting yes flag: %w", err)
+	}
+	return opts, nil
+}
+
+// defaultRCFile returns the rc file of the user's shell, from $SHELL.
+func defaultRCFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	switch shell := filepath.Base(os.Getenv("SHELL")); shell {
+	case shellZsh:
+		if dir := os.Getenv("ZDOTDIR"); dir != "" {
+			return filepath.Join(dir, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case shellBash:
+		return filepath.Join(home, ".bashrc"), nil
+	default:
+		return "", errors.New(i18n.T("unsupported shell %q; pass the rc file with --rc", shell))
+	}
+}
+
+// rcDefinitions holds the names defined in an rc file and the commands aliased there.
+type rcDefinitions struct {
+	names    map[string]bool
+	commands map[string]string
+}
+
+// readRCDefinitions collects the aliases and functions defined in the rc file. A
+// missing file defines nothing.
+func readRCDefinitions(path string) (rcDefinitions, error) {
+	defs := rcDefinitions{names: make(map[string]bool), commands: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defs, nil
+	}
+	if err != nil {
+		return defs, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		match := rcDefinition.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		switch {
+		case match[1] != "":
+			defs.names[match[1]] = true
+			defs.commands[unquoteAlias(match[2])] = match[1]
+		case match[3] != "":
+			defs.names[match[3]] = true
+			if body := functionBody(match[4]); body != "" {
+				defs.commands[body] = match[3]
+			}
+		case match[5] != "":
+			defs.names[match[5]] = true
+		}
+	}
+	return defs, nil
+}
+
+// unquoteAlias returns the command of an alias definition's value, e.g. 'ls -la'.
+func unquoteAlias(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return strings.ReplaceAll(value, `'\''`, "'")
+}
+
+// functionBody returns the command of a one-line function definition's body, e.g.
+// { git log "$1"; }, or "" if the body spans several lines.
+func functionBody(definition string) string {
+	body := strings.TrimSpace(definition)
+	if !strings.HasPrefix(body, "{") || !strings.HasSuffix(body, "}") {
+		return ""
+	}
+	body = strings.TrimSpace(body[1 : len(body)-1])
+	return strings.TrimSpace(strings.TrimSuffix(body, ";"))
+}
+
+// aliasDefinition is a named alias or function, ready to be written.
+type aliasDefinition struct {
+	name  string
+	alias stats.Alias
+}
+
+// nameAliases names up to n candidates, skipping commands the rc file already
+// aliases and avoiding names that are taken.
+func nameAliases(candidates []stats.Alias, existing rcDefinitions, n int) []aliasDefinition {
+	taken := func(name string) bool {
+		if name == "" || existing.names[name] || shellReserved[name] {
+			return true
+		}
+		_, err := exec.LookPath(name)
+		return err == nil
+	}
+	var definitions []aliasDefinition
+	for _, candidate := range candidates {
+		if name, ok := existing.commands[candidate.Command]; ok {
+			logger.Debug("Command already aliased", zap.String("name", name), zap.String("command", candidate.Command))
+			continue
+		}
+		// The argument of a function is no word of its name.
+		base := stats.AliasName(strings.ReplaceAll(candidate.Command, `"$1"`, ""))
+		name := uniqueAliasName(base, taken)
+		existing.names[name] = true
+		definitions = append(definitions, aliasDefinition{name: name, alias: candidate})
+		if len(definitions) == n {
+			break
+		}
+	}
+	return definitions
+}
+
+// uniqueAliasName returns base, or base with the lowest number suffix, that is not taken.
+func uniqueAliasName(base string, taken func(string) bool) string {
+	if base == "" {
+		base = "a"
+	}
+	name := base
+	for i := 2; taken(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+// formatAliases writes the definitions as shell code, each with a comment saying how
+// often it was typed. Comments are dimmed when colored is set.
+func formatAliases(definitions []aliasDefinition, colored bool) string {
+	commentColor := color.New(color.Faint)
+	var b strings.Builder
+	for _, definition := range definitions {
+		comment := fmt.Sprintf("# typed %d times", definition.alias.Count)
+		if colored {
+			comment = commentColor.Sprint(comment)
+		}
+		b.WriteString(comment + "\n")
+		if definition.alias.Function {
+			b.WriteString(fmt.Sprintf("%s() { %s; }\n", definition.name, definition.alias.Command))
+		} else {
+			b.WriteString(fmt.Sprintf("alias %s=%s\n", definition.name, shellQuote(definition.alias.Command)))
+		}
+	}
+	return b.String()
+}
+
+// appendRC appends definitions to the rc file, under a heading comment.
+func appendRC(path, definitions string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	block := fmt.Sprintf("\n# Aliases suggested by historai (%s)\n%s", time.Now().Format("2006-01-02"), definitions)
+	if _, err := f.WriteString(block); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return infof("Appended the definitions to %s; open a new shell to use them.\n", path)
+}
+
+// init adds the aliasCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(aliasCmd)
+
+	aliasCmd.Flags().StringP("since", "s", "", "Only look at commands run since today, yesterday, 12h, 3d, 2w or a date")
+	aliasCmd.Flags().IntP("top", "t", defaultAliasTop, "Number of aliases to propose")
+	aliasCmd.Flags().String("rc", "", "The rc file to check and write to (default: ~/.zshrc or ~/.bashrc, from $SHELL)")
+	aliasCmd.Flags().BoolP("write", "w", false, "Append the aliases to the rc file after confirmation")
+	aliasCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before writing")
+}
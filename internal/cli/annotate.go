@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// annotateCmd represents the annotate command
+var annotateCmd = &cobra.Command{
+	Use:   "annotate <entry> [note]",
+	Short: "Attach a note to a command in your history",
+	Long: `Attaches a free-text note to a command, such as "the magic incantation for prod
+certs". Notes are kept in a sidecar file in historai's data directory; your
+history file is not modified. A note applies to every run of the same command line.
+
+'find' matches your query against notes as well as commands, and always considers
+annotated commands, even ones older than its --limit.
+
+<entry> is a command line, or a number N for the Nth most recent command (1 is the
+last one). Without a note, the current note is printed.
+
+Flags:
+  --delete      : Remove the note from the entry.
+  --list / -l   : List all annotated commands.
+
+Example:
+  historai annotate 1 "renews the prod TLS certs, run from the bastion"
+  historai annotate "kubectl rollout restart deploy/api" "safe during business hours"
+  historai annotate --list
+  historai find "prod certs"`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.RangeArgs(1, 2)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		flags := cmd.Flags()
+		list, err := flags.GetBool("list")
+		if err != nil {
+			return fmt.Errorf("internal error getting list flag: %w", err)
+		}
+		remove, err := flags.GetBool("delete")
+		if err != nil {
+			return fmt.Errorf("internal error getting delete flag: %w", err)
+		}
+		if remove && len(args) > 1 {
+			return errors.New(i18n.T("--delete takes no note"))
+		}
+
+		store, err := annotations.Load("")
+		if err != nil {
+			return err
+		}
+		if list {
+			return printNotes(store)
+		}
+
+		// 2. Resolve the entry
+		command, err := resolveEntry(args[0])
+		if err != nil {
+			return err
+		}
+
+		// 3. Show, set or remove the note
+		switch {
+		case remove:
+			if store.Get(command) == nil || store.Get(command).Note == "" {
+				return infof("No note on %s\n", command)
+			}
+			store.SetNote(command, "")
+		case len(args) == 1:
+			if annotation := store.Get(command); annotation != nil && annotation.Note != "" {
+				_, err := fmt.Fprintln(os.Stdout, annotation.Note)
+				return err
+			}
+			return infof("No note on %s\n", command)
+		default:
+			if strings.TrimSpace(args[1]) == "" {
+				return errors.New(i18n.T("note cannot be empty (use --delete to remove a note)"))
+			}
+			store.SetNote(command, args[1])
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		logger.Debug("Saved annotation", zap.String("command", command), zap.Bool("removed", remove))
+		if remove {
+			return infof("Removed the note from %s\n", command)
+		}
+		return infof("Noted %s\n", command)
+	},
+}
+
+// resolveEntry turns an entry reference into a command line: a number N selects the
+// Nth most recent command, ignoring historai's own invocations; anything else is
+// taken as the command line itself.
+func resolveEntry(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	n, err := strconv.Atoi(ref)
+	if err != nil {
+		if ref == "" {
+			return "", errors.New(i18n.T("entry cannot be empty"))
+		}
+		return ref, nil
+	}
+	if n < 1 {
+		return "", errors.New(i18n.T("entry number must be 1 or more"))
+	}
+
+	reader, err := newHistoryReader()
+	if err != nil {
+		return "", err
+	}
+	entries, err := reader.ReadHistory(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read history: %w", err)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if history.ProgramName(entries[i].Command) == "historai" {
+			continue
+		}
+		if n--; n == 0 {
+			return strings.TrimSpace(entries[i].Command), nil
+		}
+	}
+	return "", errors.New(i18n.T("history has fewer than %s commands", ref))
+}
+
+// printNotes lists the annotated commands, most recently noted first.
+func printNotes(store *annotations.Store) error {
+	noteColor := color.New(color.Faint)
+	printed := 0
+	for _, command := range store.Sorted() {
+		annotation := store.Get(command)
+		if annotation.Note == "" {
+			continue
+		}
+		printed++
+		if _, err := fmt.Fprintf(os.Stdout, "%s\n  %s\n", command, noteColor.Sprint(annotation.Note)); err != nil {
+			return err
+		}
+	}
+	if printed == 0 {
+		return infof("No annotated commands yet.\n")
+	}
+	return nil
+}
+
+// init adds the annotateCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(annotateCmd)
+
+	annotateCmd.Flags().Bool("delete", false, "Remove the note from the entry")
+	annotateCmd.Flags().BoolP("list", "l", false, "List all annotated commands")
+}
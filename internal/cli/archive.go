@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// archiveCmd represents the archive command
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Move old entries of historai's log into compressed archive segments",
+	Long: `Moves the entries of the log the shell hook records that are older than
+--months (archive.months in the config file, 6 by default) into compressed
+segments, one per month, in the "archive" directory next to the log. The log stays
+small, so find, suggest and the daemon parse it quickly, while nothing is lost.
+
+Archived commands are still searched: by 'archive search', and by the model when
+it looks further back in your history (llm.max_tool_calls). An index of the words
+of each segment lets searches skip the segments that can't match. Segments are
+encrypted when the log is (see 'historai encryption'), in which case every
+segment is read.
+
+Run it from cron, e.g. monthly, to keep the log small. 'historai forget' removes
+commands from the archive too.
+
+Subcommands:
+  list    : List the segments of the archive.
+  search  : Print the archived commands containing a keyword.
+
+Flags:
+  --months   : Archive entries older than this many months.
+  --dry-run  : Report what would be archived without changing anything.
+
+Example:
+  historai archive --dry-run
+  historai archive --months 12
+  historai archive search terraform`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		months, err := cmd.Flags().GetInt("months")
+		if err != nil {
+			return fmt.Errorf("internal error getting months flag: %w", err)
+		}
+		if !cmd.Flags().Changed("months") {
+			months = appConfig.Archive.Months
+		}
+		if months < 1 {
+			return errors.New(i18n.T("--months must be at least 1"))
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("internal error getting dry-run flag: %w", err)
+		}
+
+		// 2. Roll the old entries into the archive
+		archive, err := openArchive()
+		if err != nil {
+			return err
+		}
+		before := time.Now().AddDate(0, -months, 0)
+		result, err := archive.Roll(before, dryRun)
+		if err != nil {
+			return err
+		}
+		cutoff := before.Format("2006-01-02")
+		switch {
+		case result.Archived == 0:
+			return infof("Nothing older than %s to archive.\n", cutoff)
+		case dryRun:
+			return infof("Would archive %d entries older than %s into %s.\n", result.Archived, cutoff, strings.Join(result.Segments, ", "))
+		}
+		if err := infof("Archived %d entries older than %s into %d segments in %s.\n", result.Archived, cutoff, len(result.Segments), archive.HistoryFile()); err != nil {
+			return err
+		}
+		clearDaemonCache()
+		return nil
+	},
+}
+
+// archiveListCmd represents the archive list command
+var archiveListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the segments of the archive",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archive, err := openArchive()
+		if err != nil {
+			return err
+		}
+		segments, err := archive.Segments()
+		if err != nil {
+			return err
+		}
+		if len(segments) == 0 {
+			if err := infof("Nothing archived yet in %s.\n", archive.HistoryFile()); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+		for i := range segments {
+			// The index's words are for searches, not for reading.
+			segments[i].Words = nil
+		}
+
+		switch outputFormat {
+		case outputText:
+		case outputJSONL:
+			encoder := json.NewEncoder(os.Stdout)
+			for _, segment := range segments {
+				if err := encoder.Encode(segment); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return printStructuredResult(outputFormat, segments)
+		}
+
+		rangeColor := color.New(color.Faint)
+		var b strings.Builder
+		for _, segment := range segments {
+			span := time.Unix(segment.From, 0).Format("2006-01-02") + ".." + time.Unix(segment.To, 0).Format("2006-01-02")
+			b.WriteString(fmt.Sprintf("%s  %s  %s\n", segment.File, rangeColor.Sprint(span),
+				i18n.T("%d entries, %d KB", segment.Entries, (segment.Bytes+1023)/1024)))
+		}
+		_, err = fmt.Fprint(os.Stdout, b.String())
+		return err
+	},
+}
+
+// archiveSearchCmd represents the archive search command
+var archiveSearchCmd = &cobra.Command{
+	Use:   "search <keyword>",
+	Short: "Print the archived commands containing a keyword",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if strings.TrimSpace(args[0]) == "" {
+			return errors.New(i18n.T("keyword cannot be empty"))
+		}
+		archive, err := openArchive()
+		if err != nil {
+			return err
+		}
+		entries, err := archive.Search(args[0])
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			if err := infof("No archived commands contain %q.\n", args[0]); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+
+		switch outputFormat {
+		case outputText:
+		case outputJSONL:
+			encoder := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				if err := encoder.Encode(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return printStructuredResult(outputFormat, entries)
+		}
+
+		timeColor := color.New(color.Faint)
+		var b strings.Builder
+		for _, entry := range entries {
+			if rawOutput {
+				b.WriteString(entry.Command + "\n")
+				continue
+			}
+			b.WriteString(timeColor.Sprint(time.Unix(entry.Timestamp, 0).Format("2006-01-02 15:04")) + "  " + entry.Command + "\n")
+		}
+		_, err = fmt.Fprint(os.Stdout, b.String())
+		return err
+	},
+}
+
+// openArchive returns the archive of historai's own log, which only the shell hook's
+// log has.
+func openArchive() (*history.Archive, error) {
+	recordFile, err := defaultImportTarget()
+	if err != nil {
+		return nil, err
+	}
+	return history.OpenArchive(logger, recordFile)
+}
+
+// init adds the archiveCmd, its subcommands and flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+	archiveCmd.AddCommand(archiveListCmd, archiveSearchCmd)
+
+	archiveCmd.Flags().Int("months", 0, "Archive entries older than this many months (default: archive.months)")
+	archiveCmd.Flags().Bool("dry-run", false, "Report what would be archived without changing anything")
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+const (
+	// defaultAskHistoryLimit is larger than find's, since questions often span weeks of history.
+	defaultAskHistoryLimit = 500
+)
+
+// askCmd represents the ask command
+var askCmd = &cobra.Command{
+	Use:   "ask \"<question about your history>\"",
+	Short: "Ask a free-form question about your shell history using AI",
+	Long: `Asks an LLM a question about your habits and answers it by reasoning over
+your shell history, instead of returning matching commands. Times and working
+directories are included when the history source records them.
+
+Flags:
+  --limit / -n : How many recent history entries to reason over (default: 500).
+
+Example:
+  historai ask "which kubectl contexts have I used this month?"
+  historai ask "what flags do I usually pass to ffmpeg?"
+  historai ask --limit 2000 "which projects did I run terraform in?"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question := args[0]
+		if question == "" {
+			return errors.New(i18n.T("question cannot be empty"))
+		}
+
+		// 1. Parse and validate flags (limit)
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			logger.Error("Failed to get 'limit' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting limit flag: %w", err)
+		}
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Ask the LLM
+		started := time.Now()
+		spin := startSpinner()
+		answer, err := eng.Ask(context.Background(), question, limit)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 4. Print Header to Stderr & Answer to Stdout
+		return printExplanation(logger, commandOutput{
+			kind:         "ask",
+			query:        question,
+			output:       answer,
+			noAnswer:     "(AI could not answer this question)",
+			header:       i18n.T("--- Answer ---"),
+			logOnFailure: "No answer generated or response indicates failure.",
+			started:      started,
+		})
+	},
+}
+
+// init adds the askCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(askCmd)
+
+	askCmd.Flags().IntP("limit", "n", defaultAskHistoryLimit, "Limit the number of most recent history entries to reason over")
+}
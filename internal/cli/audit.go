@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/redact"
+)
+
+// auditFinding is a history entry that contains a secret. Command is already
+// redacted: the audit never prints the secrets it finds.
+type auditFinding struct {
+	Timestamp int64    `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Command   string   `json:"command" yaml:"command"`
+	Rules     []string `json:"rules" yaml:"rules"`
+}
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Scan your shell history for leaked secrets",
+	Long: `Scans your whole shell history locally for embedded credentials, such as
+API keys, tokens and passwords passed as flags or environment variables, using
+the same rules that redact history before it is sent to an LLM (plus any
+redaction.patterns from your config). Findings are shown with the secrets masked.
+
+With --scrub, the secrets are replaced with [REDACTED] in the history file
+itself, after a confirmation. A timestamped backup of the original file is kept
+next to it; it still contains the secrets, so delete it once you have checked
+the result. Running shells may write their in-memory history back on exit, so
+restart them afterwards.
+
+Flags:
+  --scrub    : Redact the findings in the history file.
+  --yes / -y : Don't ask for confirmation before scrubbing.
+
+Example:
+  historai audit
+  historai audit --scrub
+  historai audit -o json | jq length`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (scrub, yes)
+		scrub, err := cmd.Flags().GetBool("scrub")
+		if err != nil {
+			logger.Error("Failed to get 'scrub' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting scrub flag: %w", err)
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			logger.Error("Failed to get 'yes' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting yes flag: %w", err)
+		}
+
+		// 2. Read history and scan it
+		redactor, err := redact.FromPatterns(appConfig.Redaction.Patterns)
+		if err != nil {
+			return configError(fmt.Errorf("failed to load configuration: %w", err))
+		}
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		entries, err := reader.ReadHistory(0)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		findings := auditEntries(redactor, entries)
+		logger.Debug("Audited history", zap.Int("entries_count", len(entries)), zap.Int("findings_count", len(findings)))
+
+		// 3. Print the findings
+		if err := printAuditFindings(findings); err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			if err := infof("No secrets found in %d history entries.\n", len(entries)); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+		if !scrub {
+			return infof("Found secrets in %d history entries. Run 'historai audit --scrub' to remove them.\n", len(findings))
+		}
+
+		// 4. Scrub the history file
+		rewriter, ok := reader.(history.Rewriter)
+		if !ok {
+			return errors.New(i18n.T("history source %q can't be rewritten", appConfig.History.Source))
+		}
+		if !yes {
+			ok, err := confirm(i18n.T("Redact %d entries in %s?", len(findings), rewriter.HistoryFile()))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		result, err := rewriter.Rewrite(func(entry history.HistoryEntry) (history.HistoryEntry, bool) {
+			entry.Command = redactor.Redact(entry.Command)
+			entry.Stderr = redactor.Redact(entry.Stderr)
+			entry.Output = redactor.Redact(entry.Output)
+			return entry, true
+		})
+		if err != nil {
+			return err
+		}
+		return infof("Redacted %d entries. The original file was saved to %s; delete it once you have checked the result.\n", result.Changed, result.Backup)
+	},
+}
+
+// auditEntries returns a finding for every entry whose command or captured output
+// matches a redaction rule.
+func auditEntries(redactor *redact.Redactor, entries []history.HistoryEntry) []auditFinding {
+	findings := []auditFinding{}
+	for _, entry := range entries {
+		rules := redactor.Matches(entry.Command + "\n" + entry.Stderr + "\n" + entry.Output)
+		if len(rules) == 0 {
+			continue
+		}
+		findings = append(findings, auditFinding{
+			Timestamp: entry.Timestamp,
+			Command:   redactor.Redact(entry.Command),
+			Rules:     rules,
+		})
+	}
+	return findings
+}
+
+// printAuditFindings prints the findings in the format selected by --output.
+func printAuditFindings(findings []auditFinding) error {
+	switch outputFormat {
+	case outputJSONL:
+		encoder := json.NewEncoder(os.Stdout)
+		for _, finding := range findings {
+			if err := encoder.Encode(finding); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputJSON, outputYAML:
+		return printStructuredResult(outputFormat, findings)
+	}
+
+	ruleColor := color.New(color.FgRed)
+	for _, finding := range findings {
+		when := strings.Repeat(" ", len("2006-01-02 15:04"))
+		if finding.Timestamp > 0 {
+			when = time.Unix(finding.Timestamp, 0).Format("2006-01-02 15:04")
+		}
+		if _, err := fmt.Fprintf(os.Stdout, "%s  %s  %s\n", when, ruleColor.Sprint(strings.Join(finding.Rules, ",")), finding.Command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// init adds the auditCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().Bool("scrub", false, "Redact the findings in the history file (a backup is kept)")
+	auditCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before scrubbing")
+}
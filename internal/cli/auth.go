@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage API keys stored in the OS keyring",
+	Long: `Stores the Google AI Studio API key in the operating system's keyring
+(macOS Keychain, Windows Credential Manager, or the Secret Service on Linux),
+so it doesn't have to be exported from your shell profile.
+
+The GOOGLE_API_KEY environment variable still takes precedence when set.
+
+Example:
+  historai auth login
+  echo "$KEY" | historai auth login --stdin
+  historai auth status
+  historai auth logout`,
+}
+
+// authLoginCmd represents the auth login command
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store an API key in the OS keyring",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromStdin, err := cmd.Flags().GetBool("stdin")
+		if err != nil {
+			return fmt.Errorf("internal error getting stdin flag: %w", err)
+		}
+
+		apiKey, err := readAPIKey(fromStdin)
+		if err != nil {
+			return err
+		}
+		if apiKey == "" {
+			return errors.New(i18n.T("API key cannot be empty"))
+		}
+
+		if err := config.StoreAPIKey(config.DefaultProvider, apiKey); err != nil {
+			return err
+		}
+		return infof("API key stored in the OS keyring.\n")
+	},
+}
+
+// authLogoutCmd represents the auth logout command
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored API key from the OS keyring",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.DeleteAPIKey(config.DefaultProvider); err != nil {
+			return err
+		}
+		return infof("API key removed from the OS keyring.\n")
+	},
+}
+
+// authStatusCmd represents the auth status command
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where the API key is loaded from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if appConfig.GoogleAPIKey == "" {
+			_, err := fmt.Fprint(os.Stdout, i18n.T("No API key configured. Set %s or run 'historai auth login'.\n", config.EnvGoogleAPIKey))
+			return err
+		}
+		_, err := fmt.Fprint(os.Stdout, i18n.T("API key loaded from %s\n", appConfig.GoogleAPIKeySource))
+		return err
+	},
+}
+
+// readAPIKey reads the key from stdin, prompting without echo when stdin is a terminal.
+func readAPIKey(fromStdin bool) (string, error) {
+	stdinFd := int(os.Stdin.Fd())
+	if !fromStdin && term.IsTerminal(stdinFd) {
+		if _, err := fmt.Fprint(os.Stderr, i18n.T("Google AI Studio API key: ")); err != nil {
+			return "", err
+		}
+		key, err := term.ReadPassword(stdinFd)
+		_, _ = fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key: %w", err)
+		}
+		return strings.TrimSpace(string(key)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read API key from stdin: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// init adds the authCmd and its subcommands to the rootCmd.
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd, authLogoutCmd, authStatusCmd)
+
+	authLoginCmd.Flags().Bool("stdin", false, "Read the API key from stdin instead of prompting")
+}
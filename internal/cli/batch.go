@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// defaultBatchConcurrency is how many queries of a batch are sent at once by default.
+const defaultBatchConcurrency = 4
+
+// batchQuery is a query read from a batch file, with its line number.
+type batchQuery struct {
+	line int
+	text string
+}
+
+// batchResult is a line of 'find --batch' output: the structured result of a query,
+// or the error that kept it from being answered.
+type batchResult struct {
+	Line int `json:"line"`
+	structuredResult
+	Error string `json:"error,omitempty"`
+}
+
+// readBatchQueries reads one query per line from path, or stdin for "-". Blank lines
+// and lines starting with # are skipped.
+func readBatchQueries(path string) ([]batchQuery, error) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open batch file: %w", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		input = file
+	}
+
+	var queries []batchQuery
+	scanner := bufio.NewScanner(input)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		queries = append(queries, batchQuery{line: lineNumber, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch queries: %w", err)
+	}
+	return queries, nil
+}
+
+// runFindBatch answers every query of the batch file at path, at most concurrency
+// at a time, and writes a JSON line per query in the order of the file. The queries
+// share one engine, which parses the history once. Failed queries are reported in
+// their line; the batch fails at the end if any did. Non-nil entries are searched
+// instead of the configured history.
+func runFindBatch(path, tag string, limit, concurrency int, entries []history.HistoryEntry) error {
+	queries, err := readBatchQueries(path)
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		return errors.New(i18n.T("no queries in %s", path))
+	}
+
+	eng, err := newEngine(context.Background(), engine.Options{CacheHistory: true, History: entries})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := eng.Close(); closeErr != nil {
+			logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+	}()
+
+	// Each query gets its own channel, so results are written in order as soon as
+	// the ones before them are done.
+	results := make([]chan batchResult, len(queries))
+	for i := range results {
+		results[i] = make(chan batchResult, 1)
+	}
+	slots := make(chan struct{}, concurrency)
+	go func() {
+		for i, query := range queries {
+			slots <- struct{}{}
+			go func() {
+				defer func() { <-slots }()
+				results[i] <- findBatchQuery(eng, query, tag, limit)
+			}()
+		}
+	}()
+
+	encoder := json.NewEncoder(os.Stdout)
+	failed := 0
+	for i := range queries {
+		result := <-results[i]
+		if result.Status == statusError {
+			failed++
+		}
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode JSON output: %w", err)
+		}
+	}
+	logger.Debug("Batch finished", zap.Int("queries_count", len(queries)), zap.Int("failed_count", failed))
+	if failed > 0 {
+		return errors.New(i18n.T("%d of %d queries failed", failed, len(queries)))
+	}
+	return nil
+}
+
+// findBatchQuery answers a single query of a batch.
+func findBatchQuery(eng *engine.Engine, query batchQuery, tag string, limit int) batchResult {
+	started := time.Now()
+	answer, err := eng.FindTagStream(context.Background(), query.text, tag, limit, nil)
+	result := batchResult{Line: query.line, structuredResult: buildStructuredResult("find", query.text, answer, started)}
+	if err != nil {
+		result.Status = statusError
+		result.Error = err.Error()
+		result.Commands = []commandItem{}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clear the daemon's cache of parsed history",
+	Long: `The daemon ('historai daemon') keeps your parsed history in memory, and
+parses the history file again only when it changes. 'cache stats' shows how many
+entries it holds and how often requests were answered from memory; 'cache clear'
+drops them, to free the memory or to have the next request parse the file again.
+
+Other commands parse history afresh on every run, and nothing is cached on disk.
+
+Example:
+  historai cache stats
+  historai cache clear`,
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the entries, hits and misses of the daemon's history cache",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stats, err := daemonCache(daemon.OpCacheStats)
+		if err != nil {
+			return err
+		}
+		switch outputFormat {
+		case outputText:
+			rate := 0.0
+			if reads := stats.Hits + stats.Misses; reads > 0 {
+				rate = float64(stats.Hits) / float64(reads) * 100
+			}
+			_, err := fmt.Fprint(os.Stdout, i18n.T("History cache: %d entries, %d hits, %d misses (%.0f%% hit rate)\n",
+				stats.Entries, stats.Hits, stats.Misses, rate))
+			return err
+		case outputJSONL:
+			return json.NewEncoder(os.Stdout).Encode(stats)
+		default:
+			return printStructuredResult(outputFormat, stats)
+		}
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Drop the daemon's parsed history, so that it is parsed again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stats, err := daemonCache(daemon.OpCacheClear)
+		if err != nil {
+			return err
+		}
+		return infof("Cleared %d cached history entries.\n", stats.Entries)
+	},
+}
+
+// daemonCache sends op to the running daemon and returns its cache statistics,
+// taken before op clears them.
+func daemonCache(op string) (*daemon.CacheStats, error) {
+	client, err := daemon.Dial(daemon.DefaultSocketPath())
+	if err != nil {
+		logger.Debug("No daemon available")
+		return nil, errors.New(i18n.T("no daemon is running, and only the daemon caches history (see 'historai daemon')"))
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	resp, err := client.Do(daemon.Request{Op: op})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Cache == nil {
+		return nil, errors.New(i18n.T("the daemon returned no cache statistics"))
+	}
+	return resp.Cache, nil
+}
+
+// init adds the cacheCmd and its subcommands to the rootCmd.
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
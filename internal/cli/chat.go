@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	defaultChatHistoryLimit = config.DefaultFindLimit
+
+	// maxChatLineBytes bounds a single line of input, e.g. a pasted error message.
+	maxChatLineBytes = 1024 * 1024
+)
+
+// chatCmd represents the chat command
+var chatCmd = &cobra.Command{
+	Use:   "chat [\"<first message>\"]",
+	Short: "Chat with an AI about your shell history",
+	Long: `Starts an interactive conversation with an LLM that can see your shell
+history. Unlike find and suggest, the conversation is kept between messages, so
+you can refine an answer ("no, the one that used rsync over ssh") instead of
+starting over.
+
+Type /reset to start a new conversation, and /exit or Ctrl-D to quit.
+
+Flags:
+  --limit / -n : How many recent history entries to provide as context (default: 300, or find.limit from the config file).
+
+Example:
+  historai chat
+  historai chat "the command I used to copy my photos to the NAS"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (limit)
+		if outputFormat != outputText || rawOutput || firstOnly {
+			return errors.New(i18n.T("chat only supports text output"))
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			logger.Error("Failed to get 'limit' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting limit flag: %w", err)
+		}
+		if !cmd.Flags().Changed("limit") {
+			limit = appConfig.Find.Limit
+		}
+
+		// 2. Initialize Engine, keeping parsed history between messages
+		eng, err := newEngine(context.Background(), engine.Options{CacheHistory: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Run the conversation
+		first := ""
+		if len(args) == 1 {
+			first = args[0]
+		}
+		return runChat(eng, limit, first)
+	},
+}
+
+// runChat reads messages from stdin until EOF or /exit, answering each one with the
+// whole conversation so far as context. first, if set, is sent before reading input.
+func runChat(eng *engine.Engine, limit int, first string) error {
+	if err := infof("Chatting about your shell history. Type /reset to start over, /exit or Ctrl-D to quit.\n"); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxChatLineBytes)
+
+	var messages []llm.ChatMessage
+	for {
+		line := first
+		first = ""
+		if line == "" {
+			if _, err := fmt.Fprint(os.Stderr, "> "); err != nil {
+				return err
+			}
+			if !scanner.Scan() {
+				_, _ = fmt.Fprintln(os.Stderr)
+				return scanner.Err()
+			}
+			line = scanner.Text()
+		}
+
+		line = strings.TrimSpace(line)
+		switch line {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return nil
+		case "/reset":
+			messages = nil
+			if err := infof("Conversation cleared.\n"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		messages = append(messages, llm.ChatMessage{Role: llm.RoleUser, Content: line})
+		answer, err := chatTurn(eng, messages, limit)
+		if err != nil {
+			// Keep the conversation going; the user can rephrase or retry.
+			messages = messages[:len(messages)-1]
+			if !errors.Is(err, errNoMatches) {
+				logger.Error("Chat request failed", zap.Error(err))
+				_, _ = fmt.Fprint(os.Stderr, i18n.T("Error: %v\n", err))
+			}
+			continue
+		}
+		messages = append(messages, llm.ChatMessage{Role: llm.RoleAssistant, Content: answer})
+	}
+}
+
+// chatTurn sends the conversation to the LLM and prints the answer.
+func chatTurn(eng *engine.Engine, messages []llm.ChatMessage, limit int) (string, error) {
+	started := time.Now()
+	spin := startSpinner()
+	answer, err := eng.Chat(context.Background(), messages, limit)
+	spin.stop()
+	if err != nil {
+		return "", err
+	}
+
+	err = printExplanation(logger, commandOutput{
+		kind:         "chat",
+		query:        messages[len(messages)-1].Content,
+		output:       answer,
+		noAnswer:     llm.EmptyResponse,
+		logOnFailure: "Empty chat response.",
+		started:      started,
+	})
+	return strings.TrimSpace(answer), err
+}
+
+// init adds the chatCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(chatCmd)
+
+	chatCmd.Flags().IntP("limit", "n", defaultChatHistoryLimit, "Limit the number of most recent history entries to provide as context")
+}
@@ -0,0 +1,321 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	compareFind    = "find"
+	compareSuggest = "suggest"
+
+	// minCompareColumn is the narrowest column results are shown side by side in;
+	// narrower terminals get the results one after the other.
+	minCompareColumn = 36
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <find|suggest> \"<query>\"",
+	Short: "Run the same request against several models and compare the answers",
+	Long: `Sends the same find or suggest request to two or more models at once and
+shows their answers side by side, with how long each took, the tokens it used
+and, for known Gemini models, what it cost. Use it to choose llm.model.
+
+Models are given as a model name of the configured provider, or as
+provider:model for another provider (e.g. a historai-provider-<name> plugin,
+with "name:" for its default model). Without --with, compare.models from the
+config file is used.
+
+Flags:
+  --with / -w  : A model to compare; repeat for each one.
+  --limit / -n : How many recent history entries to send as context (default: find.limit or suggest.limit from the config file).
+
+Example:
+  historai compare suggest "compress every log file" -w gemini-1.5-flash -w gemini-1.5-pro
+  historai compare find "the ssh tunnel to staging" -w gemini-2.0-flash -w ollama:llama3
+  historai compare suggest "list open ports" -o json | jq '.[] | {model, cost_usd}'`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{compareFind, compareSuggest},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate arguments and flags
+		mode, query := args[0], strings.TrimSpace(args[1])
+		if mode != compareFind && mode != compareSuggest {
+			return errors.New(i18n.T("invalid request %q (use find or suggest)", mode))
+		}
+		if query == "" {
+			return errors.New(i18n.T("query cannot be empty"))
+		}
+		models, err := cmd.Flags().GetStringArray("with")
+		if err != nil {
+			return fmt.Errorf("internal error getting with flag: %w", err)
+		}
+		if len(models) == 0 {
+			models = appConfig.Compare.Models
+		}
+		if len(models) < 2 {
+			return errors.New(i18n.T("compare needs at least two models: pass --with for each, or set compare.models"))
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("internal error getting limit flag: %w", err)
+		}
+		if !cmd.Flags().Changed("limit") {
+			limit = appConfig.Find.Limit
+			if mode == compareSuggest {
+				limit = appConfig.Suggest.Limit
+			}
+		}
+
+		// 2. Send the request to every model at once
+		spin := startLabeledSpinner(strings.Join(models, ", "))
+		results := make([]compareResult, len(models))
+		var wg sync.WaitGroup
+		for i, model := range models {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = runComparison(model, mode, query, limit)
+			}()
+		}
+		wg.Wait()
+		spin.stop()
+
+		// 3. Print the answers
+		switch outputFormat {
+		case outputText:
+			return printComparison(results)
+		case outputJSONL:
+			encoder := json.NewEncoder(os.Stdout)
+			for _, result := range results {
+				if err := encoder.Encode(result); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return printStructuredResult(outputFormat, results)
+		}
+	},
+}
+
+// compareResult is one model's answer to the compared request.
+type compareResult struct {
+	Provider   string     `json:"provider" yaml:"provider"`
+	Model      string     `json:"model,omitempty" yaml:"model,omitempty"`
+	DurationMS int64      `json:"duration_ms" yaml:"duration_ms"`
+	Usage      *llm.Usage `json:"usage,omitempty" yaml:"usage,omitempty"`
+	CostUSD    *float64   `json:"cost_usd,omitempty" yaml:"cost_usd,omitempty"`
+	Output     string     `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// Commands are the commands of Output; none means the model found nothing.
+	Commands []llm.CommandResult `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Error    string              `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// runComparison sends the request to the model described by spec. Failures are
+// recorded in the result, so that the other models' answers are still shown.
+func runComparison(spec, mode, query string, limit int) compareResult {
+	cfg := llm.ConfigForSpec(appConfig, spec)
+	result := compareResult{Provider: cfg.LLM.Provider, Model: llm.ModelName(cfg)}
+	if result.Provider == "" {
+		result.Provider = llm.ProviderGemini
+	}
+
+	eng, err := engine.New(context.Background(), logger, cfg, engine.Options{})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer func() {
+		if closeErr := eng.Close(); closeErr != nil {
+			logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+	}()
+
+	started := time.Now()
+	var answer llm.CommandAnswer
+	if mode == compareFind {
+		answer, err = eng.Find(query, limit)
+	} else {
+		answer, err = eng.Suggest(query, limit, false)
+	}
+	result.Output, result.Commands = answer.String(), answer.Commands
+	result.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		logger.Debug("Compared model failed", zap.String("model", spec), zap.Error(err))
+		result.Error = err.Error()
+		return result
+	}
+	if usage, ok := eng.Usage(); ok {
+		result.Usage = &usage
+		if cost, ok := llm.Cost(result.Model, usage); ok {
+			result.CostUSD = &cost
+		}
+	}
+	return result
+}
+
+// printComparison prints the results side by side when the terminal is wide enough,
+// and one after the other otherwise.
+func printComparison(results []compareResult) error {
+	columns := make([][]string, len(results))
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	sideBySide := err == nil && (width-3*(len(results)-1))/len(results) >= minCompareColumn
+	columnWidth := 0
+	if sideBySide {
+		columnWidth = (width - 3*(len(results)-1)) / len(results)
+	}
+	for i, result := range results {
+		columns[i] = comparisonColumn(result, columnWidth)
+	}
+
+	var b strings.Builder
+	if !sideBySide {
+		for i, column := range columns {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(strings.Join(column, "\n") + "\n")
+		}
+		_, err := fmt.Fprint(os.Stdout, b.String())
+		return err
+	}
+
+	rows := 0
+	for _, column := range columns {
+		rows = max(rows, len(column))
+	}
+	separator := color.New(color.Faint).Sprint(" │ ")
+	for row := 0; row < rows; row++ {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cell := ""
+			if row < len(column) {
+				cell = column[row]
+			}
+			// Pad by display width, ignoring the color escapes.
+			if i < len(columns)-1 {
+				cell += strings.Repeat(" ", max(0, columnWidth-runewidth.StringWidth(stripANSI(cell))))
+			}
+			cells[i] = cell
+		}
+		b.WriteString(strings.TrimRight(strings.Join(cells, separator), " ") + "\n")
+	}
+	_, err = fmt.Fprint(os.Stdout, b.String())
+	return err
+}
+
+// comparisonColumn renders a result as lines: a title, the latency, tokens and cost,
+// then the answer wrapped to width. A width of 0 doesn't wrap.
+func comparisonColumn(result compareResult, width int) []string {
+	title := result.Provider
+	if result.Model != "" {
+		title += " · " + result.Model
+	}
+	lines := []string{color.New(color.Bold).Sprint(truncateWidth(title, width))}
+
+	stats := []string{(time.Duration(result.DurationMS) * time.Millisecond).Round(time.Second / 10).String()}
+	if result.Usage != nil {
+		stats = append(stats, i18n.T("%d+%d tokens", result.Usage.PromptTokens, result.Usage.OutputTokens))
+	}
+	if result.CostUSD != nil {
+		stats = append(stats, fmt.Sprintf("$%.4f", *result.CostUSD))
+	}
+	lines = append(lines, color.New(color.Faint).Sprint(truncateWidth(strings.Join(stats, " · "), width)), "")
+
+	body := strings.TrimSpace(result.Output)
+	if result.Error != "" {
+		body = color.New(color.FgRed).Sprint(i18n.T("Error: %s", result.Error))
+	} else if len(result.Commands) == 0 {
+		body = i18n.T(body)
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		lines = append(lines, wrapWidth(line, width)...)
+	}
+	return lines
+}
+
+// wrapWidth breaks line into lines of at most width display columns, at spaces when
+// possible. A width of 0 returns line unchanged.
+func wrapWidth(line string, width int) []string {
+	if width <= 0 || runewidth.StringWidth(stripANSI(line)) <= width {
+		return []string{line}
+	}
+	var lines []string
+	var current strings.Builder
+	currentWidth := 0
+	for _, word := range strings.SplitAfter(line, " ") {
+		wordWidth := runewidth.StringWidth(stripANSI(word))
+		if currentWidth > 0 && currentWidth+wordWidth > width {
+			lines = append(lines, strings.TrimRight(current.String(), " "))
+			current.Reset()
+			currentWidth = 0
+		}
+		// Words longer than a line are cut.
+		for wordWidth > width {
+			head := runewidth.Truncate(word, width, "")
+			lines = append(lines, head)
+			word = word[len(head):]
+			wordWidth = runewidth.StringWidth(word)
+		}
+		current.WriteString(word)
+		currentWidth += wordWidth
+	}
+	if current.Len() > 0 {
+		lines = append(lines, strings.TrimRight(current.String(), " "))
+	}
+	return lines
+}
+
+// truncateWidth shortens s to width display columns; a width of 0 leaves it as is.
+func truncateWidth(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// stripANSI removes color escape sequences from s.
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+			j := i + 2
+			for j < len(s) && (s[j] < '@' || s[j] > '~') {
+				j++
+			}
+			i = j
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// init adds the compareCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringArrayP("with", "w", nil, "A model to compare, as model or provider:model; repeat for each one")
+	compareCmd.Flags().IntP("limit", "n", 0, "Limit the number of most recent history entries sent as context")
+}
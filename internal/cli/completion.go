@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// completionTimeout bounds the provider calls made to complete a word, so that
+// pressing Tab never hangs the shell for long.
+const completionTimeout = 3 * time.Second
+
+// completeModels completes --model and compare --with with the models of the
+// provider, as given by --provider or the configuration. The Gemini models with a
+// known price are offered when Gemini can't list its own, e.g. without an API key.
+func completeModels(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadAppConfig(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	models := listModels(cfg)
+	if len(models) == 0 && cfg.LLM.Provider == llm.ProviderGemini {
+		models = llm.KnownModels()
+	}
+	return models, cobra.ShellCompDirectiveNoFileComp
+}
+
+// listModels asks the provider of cfg for its models, and returns nil if it can't
+// list them.
+func listModels(cfg *config.Config) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	client, err := llm.NewClient(ctx, zap.NewNop(), cfg)
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	lister, ok := client.(llm.ModelLister)
+	if !ok {
+		return nil
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		logger.Debug("Failed to list models for completion", zap.Error(err))
+		return nil
+	}
+	return models
+}
+
+// completeProviders completes --provider with the built-in providers and the
+// provider plugins on PATH.
+func completeProviders(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	providers := append([]string{llm.ProviderGemini, llm.ProviderMock}, pluginNames(llm.ProviderPluginPrefix)...)
+	return providers, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSources completes --source with the built-in history sources and the
+// source plugins on PATH.
+func completeSources(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	sources := []string{history.SourceZsh, history.SourcePowerShell, history.SourceClink, history.SourceHistorai}
+	return append(sources, pluginNames(history.SourcePluginPrefix)...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// pluginNames returns the names of the plugins on PATH whose executables are
+// named prefix followed by the name, sorted.
+func pluginNames(prefix string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, prefix+"*"))
+		for _, path := range matches {
+			if info, err := os.Stat(path); err != nil || info.IsDir() || (runtime.GOOS != "windows" && info.Mode()&0o111 == 0) {
+				continue
+			}
+			name := strings.TrimPrefix(filepath.Base(path), prefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeTags completes tags with those already given to commands.
+func completeTags(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	store, err := annotations.Load("")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	tags := make([]string, 0)
+	for tag := range store.TagCounts() {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFirstArg uses complete for the first argument of a command, and offers
+// nothing for the others.
+func completeFirstArg(complete cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return complete(cmd, args, toComplete)
+	}
+}
+
+// completeConfigKeys completes the keys of config get, set and unset.
+func completeConfigKeys(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return config.Keys(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOutputFormats completes --output.
+func completeOutputFormats(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return []string{outputText, outputJSON, outputYAML, outputJSONL, outputRaycast, outputAlfred}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerCompletions registers the completions of the global flags and of the
+// commands taking a tag or configuration key. It is called by Execute, once the
+// init functions of every command have defined its flags.
+func registerCompletions() {
+	flagCompletions := []struct {
+		cmd  *cobra.Command
+		flag string
+		fn   cobra.CompletionFunc
+	}{
+		{rootCmd, "model", completeModels},
+		{rootCmd, "provider", completeProviders},
+		{rootCmd, "source", completeSources},
+		{rootCmd, "output", completeOutputFormats},
+		{findCmd, "tag", completeTags},
+		{compareCmd, "with", completeModels},
+	}
+	for _, completion := range flagCompletions {
+		// Only fails for unknown or already registered flags, a programming error.
+		if err := completion.cmd.RegisterFlagCompletionFunc(completion.flag, completion.fn); err != nil {
+			panic(err)
+		}
+	}
+
+	tagListCmd.ValidArgsFunction = completeFirstArg(completeTags)
+	tagAddCmd.ValidArgsFunction = completeFirstArg(completeTags)
+	tagRemoveCmd.ValidArgsFunction = completeFirstArg(completeTags)
+	configGetCmd.ValidArgsFunction = completeFirstArg(completeConfigKeys)
+	configSetCmd.ValidArgsFunction = completeFirstArg(completeConfigKeys)
+	configUnsetCmd.ValidArgsFunction = completeFirstArg(completeConfigKeys)
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and write historai configuration values",
+	Long: `Reads and writes values in the historai config file
+(default: ~/.config/historai/config.yaml).
+
+Keys use dotted paths, e.g. llm.model or find.limit. List values are
+written as a YAML flow sequence, e.g. '["pattern-1", "pattern-2"]'.
+
+Example:
+  historai config list --show-origin
+  historai config get llm.model
+  historai config set llm.model gemini-1.5-pro
+  historai config unset llm.model`,
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a configuration key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := appConfig.Get(args[0])
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, value)
+		return err
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Validate and write a configuration value to the config file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		if err := config.SetInFile(path, args[0], args[1]); err != nil {
+			return err
+		}
+		logger.Debug("Config value written")
+		return infof("Set %s in %s\n", args[0], path)
+	},
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a configuration value from the config file, restoring its default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		if err := config.UnsetInFile(path, args[0]); err != nil {
+			return err
+		}
+		return infof("Unset %s in %s\n", args[0], path)
+	},
+}
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every configuration key and its effective value",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showOrigin, err := cmd.Flags().GetBool("show-origin")
+		if err != nil {
+			return fmt.Errorf("internal error getting show-origin flag: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, key := range config.Keys() {
+			value, _ := appConfig.Get(key)
+			if showOrigin {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, appConfig.Origin(key))
+			} else {
+				_, _ = fmt.Fprintf(w, "%s\t%s\n", key, value)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+// configPathCmd represents the config path command
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the config file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, path)
+		return err
+	},
+}
+
+// configTemplatesCmd represents the config templates command
+var configTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Write the built-in find and suggest prompt templates for customization",
+	Long: `Writes the built-in find and suggest prompts, as Go text/template files, to the
+prompt templates directory (prompt.templates, default: ~/.config/historai/templates),
+where editing them changes the prompts historai sends to Gemini. Existing files are
+left untouched; delete one to restore the built-in prompt.
+
+Templates are executed with .Query, .History, .Saved, .Workflows, .Environment,
+.Annotated, .HasHosts, .Instructions, .Language, .OS and .Shell, and can call
+history, timedHistory, workflows, environment, annotation, instructions and
+language to render them as the built-in prompts do. The part of a template inside
+{{define "system"}} ... {{end}}, the model's role and instructions, is sent as the
+system instruction, before prompt.system; the rest, the query and its context, is
+the prompt. Templates written before this split keep working as a single prompt.
+
+Example:
+  historai config templates
+  $EDITOR ~/.config/historai/templates/suggest.tmpl
+  historai suggest "list open ports" --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := llm.TemplatesDir(appConfig)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create templates directory: %w", err)
+		}
+		for _, name := range llm.TemplateNames {
+			path := filepath.Join(dir, name+".tmpl")
+			if _, err := os.Stat(path); err == nil {
+				if err := infof("%s already exists; left unchanged\n", path); err != nil {
+					return err
+				}
+				continue
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			text, err := llm.DefaultTemplate(name)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, text, 0o644); err != nil {
+				return fmt.Errorf("failed to write prompt template: %w", err)
+			}
+			if err := infof("Wrote %s\n", path); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// configFilePath returns the config file being used: --config, $HISTORAI_CONFIG or the default.
+func configFilePath() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	return config.DefaultPath()
+}
+
+// init adds the configCmd and its subcommands to the rootCmd.
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configPathCmd, configTemplatesCmd)
+
+	configListCmd.Flags().Bool("show-origin", false, "Show where each value came from (default, file, env or flag)")
+}
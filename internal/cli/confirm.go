@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// confirm asks a yes/no question on the terminal. It fails when stdin is not a
+// terminal, so that scripts must opt in explicitly (e.g. with --yes).
+func confirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New(i18n.T("stdin is not a terminal; pass --yes to confirm"))
+	}
+	if _, err := fmt.Fprint(os.Stderr, question+" [y/N] "); err != nil {
+		return false, err
+	}
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, nil
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// confirmKey asks a question answered with a single key: Enter or y accepts, any
+// other key declines. Like confirm, it fails when stdin is not a terminal.
+func confirmKey(question string) (bool, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return false, errors.New(i18n.T("stdin is not a terminal; pass --yes to confirm"))
+	}
+	if _, err := fmt.Fprint(os.Stderr, question+" "); err != nil {
+		return false, err
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return false, fmt.Errorf("failed to read a key from the terminal: %w", err)
+	}
+	key := make([]byte, 1)
+	_, readErr := os.Stdin.Read(key)
+	if err := term.Restore(fd, state); err != nil {
+		return false, fmt.Errorf("failed to restore the terminal: %w", err)
+	}
+	if _, err := fmt.Fprintln(os.Stderr); err != nil {
+		return false, err
+	}
+	if readErr != nil {
+		return false, nil
+	}
+	return key[0] == '\r' || key[0] == '\n' || key[0] == 'y' || key[0] == 'Y', nil
+}
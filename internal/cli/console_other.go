@@ -0,0 +1,9 @@
+//go:build !windows
+
+package cli
+
+// enableVirtualTerminal reports whether the terminal processes ANSI escape
+// sequences, which terminals outside Windows always do.
+func enableVirtualTerminal() bool {
+	return true
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal turns on the processing of ANSI escape sequences, which is
+// off by default in the Windows console (conhost), for stdout and stderr. It reports
+// false when the console can't process them, before Windows 10. Output that isn't
+// a console, e.g. redirected to a file, is left alone.
+func enableVirtualTerminal() bool {
+	supported := true
+	for _, file := range []*os.File{os.Stdout, os.Stderr} {
+		handle := windows.Handle(file.Fd())
+		var mode uint32
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			continue
+		}
+		if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+			continue
+		}
+		if err := windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+			supported = false
+		}
+	}
+	return supported
+}
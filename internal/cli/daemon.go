@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/timings"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run a background daemon that keeps history and the LLM client warm",
+	Long: `Starts a long-running process that keeps the parsed shell history and an
+initialized LLM client in memory, serving requests over a unix socket.
+
+While the daemon is running, 'historai find' and 'historai suggest' forward
+their requests to it instead of cold-starting on every invocation.
+Use --no-daemon to bypass it.
+
+With --metrics-addr, the daemon also serves Prometheus metrics (requests,
+latencies, history cache hits, provider errors) at /metrics and a health check at
+/healthz over HTTP on that address.
+
+With --mirror, the daemon also copies the commands Zsh writes to its history file
+into historai's own log as they are written, for shells without historai's hook
+(don't use both, or commands may be recorded twice). With history.source set to
+historai, find and suggest then see new commands within a second, and 'archive',
+'sync' and 'encryption' cover them, without running 'historai import'. Commands
+already in the file when the daemon starts are left to 'historai import'. Zsh
+must save times (setopt EXTENDED_HISTORY) and write commands as they run
+(setopt INC_APPEND_HISTORY).
+
+Example:
+  historai daemon &
+  historai daemon --socket /tmp/historai.sock
+  historai daemon --metrics-addr 127.0.0.1:9464
+  historai daemon --mirror`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := cmd.Flags().GetString("socket")
+		if err != nil {
+			return fmt.Errorf("internal error getting socket flag: %w", err)
+		}
+		metricsAddr, err := cmd.Flags().GetString("metrics-addr")
+		if err != nil {
+			return fmt.Errorf("internal error getting metrics-addr flag: %w", err)
+		}
+		mirrorHistory, err := cmd.Flags().GetBool("mirror")
+		if err != nil {
+			return fmt.Errorf("internal error getting mirror flag: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		eng, err := newEngine(ctx, engine.Options{CacheHistory: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		if mirrorHistory {
+			mirror, err := newMirror()
+			if err != nil {
+				return err
+			}
+			logger.Info("Mirroring shell history", zap.String("history_file", mirror.HistoryFile()))
+			go mirror.Run(ctx, mirrorInterval)
+		}
+
+		server := daemon.NewServer(logger, eng, appConfig, socketPath)
+		if metricsAddr != "" {
+			go func() {
+				if err := server.Metrics().ListenAndServe(ctx, logger, metricsAddr); err != nil {
+					logger.Error("Failed to serve metrics", zap.Error(err))
+				}
+			}()
+		}
+		return server.ListenAndServe(ctx)
+	},
+}
+
+// mirrorInterval is how often the daemon checks the shell's history file for new
+// commands with --mirror.
+const mirrorInterval = time.Second
+
+// newMirror returns the Mirror of the shell's history file into historai's log. With
+// the shell hook's log as the source, the shell's history is Zsh's own file.
+func newMirror() (*history.Mirror, error) {
+	historyFile := ""
+	switch appConfig.History.Source {
+	case history.SourceHistorai:
+	case "", history.SourceZsh:
+		historyFile = appConfig.History.File
+	default:
+		return nil, errors.New(i18n.T("--mirror only supports Zsh history, not %q", appConfig.History.Source))
+	}
+	recordFile, err := defaultImportTarget()
+	if err != nil {
+		return nil, err
+	}
+	return history.NewMirror(logger, historyFile, recordFile)
+}
+
+// tryDaemon forwards req to a running daemon. ok reports whether the daemon handled
+// the request; when it is false the caller should fall back to local execution. The
+// daemon refuses requests made with a configuration other than the one it loaded,
+// e.g. in a project with a .historai.yaml or with --model, which then run locally.
+func tryDaemon(logger *zap.Logger, req daemon.Request) (answer llm.CommandAnswer, ok bool, err error) {
+	// The daemon's prompts can't be shown, so those flags run locally too.
+	if noDaemon || dryRun || showPrompt {
+		return llm.CommandAnswer{}, false, nil
+	}
+
+	client, dialErr := daemon.Dial(daemon.DefaultSocketPath())
+	if dialErr != nil {
+		logger.Debug("No daemon available, running locally", zap.Error(dialErr))
+		return llm.CommandAnswer{}, false, nil
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	logger.Debug("Forwarding request to daemon", zap.String("op", req.Op))
+	req.Config = appConfig.Fingerprint()
+	stopDaemon := invocationTimings.Start(timings.StageDaemon)
+	resp, err := client.Do(req)
+	stopDaemon()
+	if errors.Is(err, daemon.ErrConfigMismatch) {
+		logger.Debug("Daemon runs with a different configuration, running locally")
+		return llm.CommandAnswer{}, false, nil
+	}
+	if err != nil {
+		return llm.CommandAnswer{}, true, err
+	}
+	if resp.Answer != nil {
+		answer = *resp.Answer
+	}
+	return answer, true, nil
+}
+
+// init adds the daemonCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().String("socket", daemon.DefaultSocketPath(), "Path of the unix socket to listen on")
+	daemonCmd.Flags().String("metrics-addr", "", "Also serve /metrics and /healthz over HTTP on this address, e.g. 127.0.0.1:9464")
+	daemonCmd.Flags().Bool("mirror", false, "Also copy new commands from Zsh's history file into historai's own log as they are written")
+}
@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/redact"
+)
+
+// maxBundleProblems bounds the parse problems sampled into a debug bundle.
+const maxBundleProblems = 50
+
+// maxBundleProblemText bounds the text of a line kept for each parse problem.
+const maxBundleProblemText = 200
+
+// maxBundleLogLines bounds the lines of the log file included in a debug bundle.
+const maxBundleLogLines = 500
+
+// bundleURLKeys are the config keys holding URLs, which may embed credentials.
+var bundleURLKeys = map[string]bool{
+	"llm.endpoint":  true,
+	"network.proxy": true,
+	"sync.url":      true,
+	"sync.endpoint": true,
+}
+
+// bundleParseProblems is the parse-problems.json file of a debug bundle.
+type bundleParseProblems struct {
+	Source   string                 `json:"source,omitempty"`
+	File     string                 `json:"file,omitempty"`
+	Total    int                    `json:"total"`
+	Problems []history.ParseProblem `json:"problems"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// debugBundleCmd represents the debug-bundle command
+var debugBundleCmd = &cobra.Command{
+	Use:   "debug-bundle [file]",
+	Short: "Collect sanitized diagnostics into a tarball to attach to bug reports",
+	Long: `Writes a .tar.gz with what a bug report needs, to attach to an issue:
+
+  version.json        : the version, build, provider and model, as 'historai version'.
+  doctor.json         : the outcome of 'historai doctor', without checking the API key.
+  config.txt          : every configuration key, its value and where it came from.
+  env.txt             : the platform and shell, and which HISTORAI_* variables are set.
+  parse-problems.json : a sample of the history lines that couldn't be parsed.
+  historai.log        : the last lines of the log file, when log.enabled is set.
+
+Secrets are stripped: the API key is never included, credentials are removed from
+URLs, prompt instructions and redaction patterns are replaced by their size, your
+home directory is shortened to ~, and everything passes through redaction. Only the
+names of environment variables are included, not their values. Review the bundle
+before attaching it anyway.
+
+The bundle is written to historai-debug-<time>.tar.gz in the current directory
+unless a file is given. Without a log file, run the failing command again with
+--debug for its logs, or set log.enabled to keep them from now on.
+
+Example:
+  historai debug-bundle
+  historai debug-bundle /tmp/historai-debug.tar.gz`,
+	Args: cobra.MaximumNArgs(1),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// A broken config file is likely what the bug report is about, so don't stop at it.
+		err := rootCmd.PersistentPreRunE(cmd, args)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) && exitErr.code == ExitConfig {
+			doctorConfigErr, versionConfigErr = err, err
+			return nil
+		}
+		return err
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "historai-debug-" + time.Now().Format("20060102-150405") + ".tar.gz"
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		// 1. Set up redaction, with the configured patterns when they load
+		redactor := redact.New()
+		if doctorConfigErr == nil {
+			configured, err := redact.FromPatterns(appConfig.Redaction.Patterns)
+			if err != nil {
+				return err
+			}
+			redactor = configured
+		}
+		home, _ := os.UserHomeDir()
+		sanitize := func(s string) string {
+			if home != "" {
+				s = strings.ReplaceAll(s, home, "~")
+			}
+			return redactor.Redact(s)
+		}
+
+		// 2. Collect the files
+		versionJSON, err := json.MarshalIndent(currentVersion(), "", "  ")
+		if err != nil {
+			return err
+		}
+		doctorJSON, err := json.MarshalIndent(bundleDoctorChecks(sanitize), "", "  ")
+		if err != nil {
+			return err
+		}
+		problemsJSON, err := json.MarshalIndent(bundleProblems(sanitize), "", "  ")
+		if err != nil {
+			return err
+		}
+		files := []struct {
+			name string
+			data []byte
+		}{
+			{"version.json", append(versionJSON, '\n')},
+			{"doctor.json", append(doctorJSON, '\n')},
+			{"config.txt", bundleConfig(sanitize)},
+			{"env.txt", bundleEnv()},
+			{"parse-problems.json", append(problemsJSON, '\n')},
+			{"historai.log", bundleLog(sanitize)},
+		}
+
+		// 3. Write the tarball
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		dir := strings.TrimSuffix(filepath.Base(path), ".tar.gz")
+		now := time.Now()
+		for _, file := range files {
+			header := &tar.Header{Name: dir + "/" + file.name, Mode: 0o600, Size: int64(len(file.data)), ModTime: now}
+			if err := tw.WriteHeader(header); err != nil {
+				return fmt.Errorf("failed to write %s to the bundle: %w", file.name, err)
+			}
+			if _, err := tw.Write(file.data); err != nil {
+				return fmt.Errorf("failed to write %s to the bundle: %w", file.name, err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return fmt.Errorf("failed to write the bundle: %w", err)
+		}
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to write the bundle: %w", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+
+		logger.Debug("Wrote debug bundle", zap.String("path", path), zap.Int("size", buf.Len()))
+		if err := infof("Wrote %s\n", path); err != nil {
+			return err
+		}
+		return infof("Review it before attaching it to an issue.\n")
+	},
+}
+
+// bundleDoctorChecks runs the doctor checks without network requests, sanitizing
+// their output.
+func bundleDoctorChecks(sanitize func(string) string) []doctorCheck {
+	checks := []doctorCheck{checkConfig()}
+	if doctorConfigErr == nil {
+		checks = append(checks,
+			checkShell(),
+			checkHistory(),
+			checkAPIKey(context.Background(), false),
+			checkAnnotations(),
+			checkDaemon(),
+			checkHook(),
+		)
+	}
+	for i := range checks {
+		checks[i].Detail = sanitize(checks[i].Detail)
+		checks[i].Fix = sanitize(checks[i].Fix)
+	}
+	return checks
+}
+
+// bundleConfig lists every configuration key with its sanitized value and origin,
+// or the error loading the configuration.
+func bundleConfig(sanitize func(string) string) []byte {
+	var buf bytes.Buffer
+	if doctorConfigErr != nil {
+		_, _ = fmt.Fprintf(&buf, "error: %s\n", sanitize(doctorConfigErr.Error()))
+		return buf.Bytes()
+	}
+
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, key := range config.Keys() {
+		value, _ := appConfig.Get(key)
+		switch {
+		case key == "redaction.patterns":
+			value = fmt.Sprintf("<%d patterns>", len(appConfig.Redaction.Patterns))
+		case (key == "prompt.instructions" || key == "prompt.system") && value != "":
+			value = fmt.Sprintf("<%d characters>", len(value))
+		case bundleURLKeys[key] && value != "":
+			value = stripURLCredentials(value)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", key, sanitize(value), appConfig.Origin(key))
+	}
+	_ = w.Flush()
+	return buf.Bytes()
+}
+
+// stripURLCredentials removes the user info and query of a URL, which may hold
+// credentials. Values that don't parse are left out entirely.
+func stripURLCredentials(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "<unparsable URL>"
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	return u.String()
+}
+
+// bundleEnv describes the platform and shell, and lists the names of the HISTORAI_*
+// variables that are set; their values may be secrets.
+func bundleEnv() []byte {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "os: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	_, _ = fmt.Fprintf(&buf, "shell: %s\n", filepath.Base(os.Getenv("SHELL")))
+	_, _ = fmt.Fprintf(&buf, "term: %s\n", os.Getenv("TERM"))
+	_, _ = fmt.Fprintf(&buf, "%s set: %t\n", config.EnvGoogleAPIKey, os.Getenv(config.EnvGoogleAPIKey) != "")
+
+	var names []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, "HISTORAI_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintf(&buf, "HISTORAI_* set: %s\n", strings.Join(names, " "))
+	return buf.Bytes()
+}
+
+// bundleLog returns the last lines of the log file, sanitized, or why there are none.
+func bundleLog(sanitize func(string) string) []byte {
+	if doctorConfigErr != nil {
+		return []byte("no log: the configuration failed to load\n")
+	}
+	path, err := logFilePath()
+	if err != nil {
+		return []byte(sanitize(err.Error()) + "\n")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []byte(fmt.Sprintf("no log file at %s (log.enabled is %t)\n", sanitize(path), appConfig.Log.Enabled))
+	}
+	if len(data) == 0 {
+		return []byte(fmt.Sprintf("%s is empty\n", sanitize(path)))
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > maxBundleLogLines {
+		lines = lines[len(lines)-maxBundleLogLines:]
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(sanitize(line))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// bundleProblems samples the lines of the history file that the reader skipped or
+// repaired, sanitizing and shortening them.
+func bundleProblems(sanitize func(string) string) bundleParseProblems {
+	if doctorConfigErr != nil {
+		return bundleParseProblems{Problems: []history.ParseProblem{}, Error: "the configuration failed to load"}
+	}
+	result := bundleParseProblems{Source: appConfig.History.Source, Problems: []history.ParseProblem{}}
+	if result.Source == "" {
+		result.Source = history.SourceZsh
+	}
+
+	reader, err := history.NewReader(zap.NewNop(), appConfig.History.Source, appConfig.History.File)
+	if err != nil {
+		result.Error = sanitize(err.Error())
+		return result
+	}
+	if fileReader, ok := reader.(interface{ HistoryFile() string }); ok {
+		result.File = sanitize(fileReader.HistoryFile())
+	}
+	reporter, ok := reader.(history.ProblemReporter)
+	if !ok {
+		result.Error = "the history source doesn't report parse problems"
+		return result
+	}
+	problems, err := reporter.ParseProblems()
+	if err != nil {
+		result.Error = sanitize(err.Error())
+		return result
+	}
+
+	result.Total = len(problems)
+	if len(problems) > maxBundleProblems {
+		problems = problems[:maxBundleProblems]
+	}
+	for _, problem := range problems {
+		problem.Text = sanitize(problem.Text)
+		if runes := []rune(problem.Text); len(runes) > maxBundleProblemText {
+			problem.Text = string(runes[:maxBundleProblemText]) + "…"
+		}
+		result.Problems = append(result.Problems, problem)
+	}
+	return result
+}
+
+// init adds the debugBundleCmd to the rootCmd.
+func init() {
+	rootCmd.AddCommand(debugBundleCmd)
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// diffToken is a word of a command line, marked when it is not part of the
+// longest common subsequence with the other command line.
+type diffToken struct {
+	text    string
+	changed bool
+}
+
+// diffWords compares two command lines word by word.
+func diffWords(before, after string) (removed, added []diffToken) {
+	a, b := strings.Fields(before), strings.Fields(after)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			removed = append(removed, diffToken{text: a[i]})
+			added = append(added, diffToken{text: b[j]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, diffToken{text: b[j], changed: true})
+			j++
+		default:
+			removed = append(removed, diffToken{text: a[i], changed: true})
+			i++
+		}
+	}
+	return removed, added
+}
+
+// formatDiffLine renders tokens after prefix in base color, emphasizing changed words.
+func formatDiffLine(prefix string, tokens []diffToken, base color.Attribute) string {
+	plain := color.New(base)
+	emphasized := color.New(base, color.Bold, color.Underline)
+
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		if token.changed {
+			words[i] = emphasized.Sprint(token.text)
+		} else {
+			words[i] = plain.Sprint(token.text)
+		}
+	}
+	return plain.Sprint(prefix) + strings.Join(words, " ")
+}
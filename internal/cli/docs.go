@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/version"
+)
+
+// Values of docs --format.
+const (
+	docsFormatMan      = "man"
+	docsFormatMarkdown = "markdown"
+)
+
+// docsCmd represents the hidden docs command used by packagers to generate manuals.
+var docsCmd = &cobra.Command{
+	Use:   "docs <dir>",
+	Short: "Generate man pages or a markdown command reference (for packagers)",
+	Long: `Generate a man page, or a markdown page, for every command into <dir>, which is
+created if needed. Hidden commands are left out.
+
+The man pages are dated from SOURCE_DATE_EPOCH when set, for reproducible builds.`,
+	Example: `  historai docs --format man ./man/man1
+  historai docs --format markdown ./docs/reference`,
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	// Generating the docs needs neither the config nor an API key.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		logger = zap.NewNop()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("internal error getting format flag: %w", err)
+		}
+		if format != docsFormatMan && format != docsFormatMarkdown {
+			return errors.New(i18n.T("invalid --format %q (use man or markdown)", format))
+		}
+		dir := args[0]
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+
+		// The generated pages would otherwise differ on every run.
+		rootCmd.DisableAutoGenTag = true
+		switch format {
+		case docsFormatMan:
+			header := &doc.GenManHeader{
+				Title:   "HISTORAI",
+				Section: "1",
+				Source:  "historai " + version.Get().Version,
+				Manual:  "historai Manual",
+			}
+			err = doc.GenManTree(rootCmd, header, dir)
+		case docsFormatMarkdown:
+			err = doc.GenMarkdownTree(rootCmd, dir)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to generate the docs: %w", err)
+		}
+		return infof("Wrote the %s docs to %s\n", format, dir)
+	},
+}
+
+// init adds the docsCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().String("format", docsFormatMan, "Format of the docs: man or markdown")
+}
@@ -0,0 +1,459 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// Outcomes of a doctor check.
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorVerifyTimeout bounds the request checking the API key.
+const doctorVerifyTimeout = 15 * time.Second
+
+// doctorCheck is the outcome of one diagnostic, with how to fix it when it didn't pass.
+type doctorCheck struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+	Detail string `json:"detail" yaml:"detail"`
+	Fix    string `json:"fix,omitempty" yaml:"fix,omitempty"`
+}
+
+// doctorConfigErr is the error loading the configuration, which doctor reports
+// instead of stopping at it.
+var doctorConfigErr error
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check your setup and explain how to fix what is wrong",
+	Long: `Checks that historai is set up correctly and prints how to fix each problem:
+
+  config   : the config file loads and is valid.
+  policy   : what the organization policy file, if any, enforces.
+  shell    : your shell is supported by the configured history source.
+  history  : the history file exists and parses, and records when commands ran.
+  api key  : an API key is configured, and the provider accepts it. For Gemini
+             this looks up the configured model, which costs no tokens.
+  data     : the annotations file (tags, notes and saved commands) is readable.
+  daemon   : a running daemon answers, and no stale socket is left behind.
+  hook     : the shell hook from 'historai init' is installed and recording.
+
+Failed checks make historai exit with a non-zero status; warnings don't.
+
+Flags:
+  --dry-run : Don't send the request checking the API key.
+
+Example:
+  historai doctor
+  historai doctor --dry-run
+  historai doctor --output json`,
+	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// A broken config file is one of the problems doctor reports, so don't stop at it.
+		err := rootCmd.PersistentPreRunE(cmd, args)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) && exitErr.code == ExitConfig {
+			doctorConfigErr = err
+			return nil
+		}
+		return err
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Run the checks; the others need a valid configuration
+		checks := []doctorCheck{checkConfig()}
+		if doctorConfigErr == nil {
+			checks = append(checks,
+				checkPolicy(),
+				checkShell(),
+				checkHistory(),
+				checkAPIKey(context.Background(), !dryRun),
+				checkAnnotations(),
+				checkDaemon(),
+				checkHook(),
+			)
+		}
+
+		// 2. Print the outcomes
+		if err := printDoctorChecks(checks); err != nil {
+			return err
+		}
+
+		// 3. Summarize
+		var failed, warned int
+		for _, check := range checks {
+			switch check.Status {
+			case doctorFail:
+				failed++
+			case doctorWarn:
+				warned++
+			}
+		}
+		logger.Debug("Doctor finished", zap.Int("failed_count", failed), zap.Int("warned_count", warned))
+		switch {
+		case failed > 0:
+			return errors.New(i18n.T("%d of %d checks failed", failed, len(checks)))
+		case warned > 0:
+			return infof("No problems found, with %d warnings.\n", warned)
+		default:
+			return infof("No problems found.\n")
+		}
+	},
+}
+
+// checkConfig reports whether the configuration loaded, and from where.
+func checkConfig() doctorCheck {
+	check := doctorCheck{Name: "config", Status: doctorOK}
+	if doctorConfigErr != nil {
+		check.Status = doctorFail
+		check.Detail = doctorConfigErr.Error()
+		check.Fix = "Fix the config file ('historai config path' prints where it is), or set HISTORAI_CONFIG to another one. The other checks need a valid configuration."
+		return check
+	}
+	path := configPath
+	if path == "" {
+		path, _ = config.DefaultPath()
+	}
+	if _, err := os.Stat(path); err != nil {
+		check.Detail = "no config file, using the defaults"
+	} else {
+		check.Detail = "loaded " + path
+	}
+	return check
+}
+
+// checkPolicy describes what the organization policy enforces, if there is one.
+func checkPolicy() doctorCheck {
+	check := doctorCheck{Name: "policy", Status: doctorOK}
+	policy := appConfig.Policy
+	if policy == nil {
+		check.Detail = "none at " + config.PolicyPath()
+		return check
+	}
+	var rules []string
+	if len(policy.Providers) > 0 {
+		rules = append(rules, "providers "+strings.Join(policy.Providers, ", "))
+	}
+	if policy.LocalOnly {
+		rules = append(rules, "local only")
+	}
+	if len(policy.Redaction) > 0 {
+		rules = append(rules, fmt.Sprintf("%d redaction patterns", len(policy.Redaction)))
+	}
+	if len(policy.Deny) > 0 {
+		rules = append(rules, fmt.Sprintf("%d denied command patterns", len(policy.Deny)))
+	}
+	if len(rules) == 0 {
+		rules = append(rules, "nothing")
+	}
+	check.Detail = "enforcing " + policy.Path + ": " + strings.Join(rules, "; ")
+	if err := policy.AllowProvider(appConfig.LLM.Provider); err != nil {
+		check.Status = doctorWarn
+		check.Detail += "; " + err.Error()
+		check.Fix = "Set llm.provider to a provider the policy allows; until then find and suggest only search your history by keyword."
+	}
+	return check
+}
+
+// checkShell reports whether the user's shell matches the history source.
+func checkShell() doctorCheck {
+	check := doctorCheck{Name: "shell", Status: doctorOK}
+	shell := filepath.Base(os.Getenv("SHELL"))
+	source := appConfig.History.Source
+	switch {
+	case history.SourceShell(source) != "" && (os.Getenv("SHELL") == "" || runtime.GOOS == "windows"):
+		check.Detail = history.SourceShell(source)
+	case os.Getenv("SHELL") == "":
+		check.Status = doctorWarn
+		check.Detail = "$SHELL is not set"
+		check.Fix = "Set SHELL to your login shell, e.g. in your terminal's profile."
+	case shell != shellZsh && shell != shellBash:
+		check.Status = doctorWarn
+		check.Detail = shell + " has no built-in history source"
+		check.Fix = "historai reads zsh history and its own log, recorded in zsh or bash. Set history.source to a source plugin for " + shell + ", or import its history with 'historai import'."
+	case shell == shellBash && (source == "" || source == history.SourceZsh):
+		check.Status = doctorWarn
+		check.Detail = "bash, but history.source reads zsh history"
+		check.Fix = `Add eval "$(historai init bash)" to ~/.bashrc and set history.source to historai.`
+	default:
+		check.Detail = shell
+	}
+	return check
+}
+
+// checkHistory reports whether the configured history can be read, and whether it
+// records when commands ran.
+func checkHistory() doctorCheck {
+	check := doctorCheck{Name: "history", Status: doctorOK}
+	var missingFix string
+	switch appConfig.History.Source {
+	case "", history.SourceZsh:
+		missingFix = "Set history.file to your zsh history file (HISTFILE in your shell)."
+	case history.SourcePowerShell:
+		missingFix = "Set history.file to PSReadLine's history file, (Get-PSReadLineOption).HistorySavePath in PowerShell."
+	case history.SourceClink:
+		missingFix = "Install clink (https://chrislant.github.io/clink) to save cmd.exe's history, or set history.file to the output of 'doskey /history'."
+	case history.SourceHistorai:
+		missingFix = `Add eval "$(historai init zsh)" to ~/.zshrc (or init bash to ~/.bashrc) and open a new shell.`
+	default:
+		missingFix = "Install the source plugin " + history.SourcePluginPrefix + appConfig.History.Source + " on your PATH, or set history.source to zsh, powershell, clink or historai."
+	}
+
+	// Problems are reported below, so the reader doesn't need to log them too.
+	reader, err := history.NewReader(zap.NewNop(), appConfig.History.Source, appConfig.History.File)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Fix = missingFix
+		return check
+	}
+
+	from := "source " + appConfig.History.Source
+	if fileReader, ok := reader.(interface{ HistoryFile() string }); ok {
+		from = fileReader.HistoryFile()
+		if _, err := os.Stat(from); err != nil {
+			check.Status = doctorFail
+			check.Detail = from + " does not exist"
+			check.Fix = missingFix
+			return check
+		}
+	}
+
+	entries, err := reader.ReadHistory(0)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Fix = "Check that " + from + " is readable; 'historai --debug doctor' shows details."
+		return check
+	}
+	if len(entries) == 0 {
+		check.Status = doctorWarn
+		check.Detail = "no entries in " + from
+		check.Fix = "Run some commands in a new shell; find and suggest have nothing to work with yet."
+		return check
+	}
+	timed := 0
+	for _, entry := range entries {
+		if entry.Timestamp > 0 {
+			timed++
+		}
+	}
+	check.Detail = fmt.Sprintf("%d entries in %s", len(entries), from)
+	if appConfig.History.Source == history.SourcePowerShell {
+		// PSReadLine never records times, so there is nothing to fix.
+		return check
+	}
+	if timed == 0 {
+		check.Status = doctorWarn
+		check.Detail += ", without times"
+		check.Fix = "Add 'setopt EXTENDED_HISTORY' to ~/.zshrc, so that commands are saved with when they ran."
+		if appConfig.History.Source == history.SourceClink {
+			check.Fix = "Run 'clink set history.time_stamp save', so that commands are saved with when they ran."
+		}
+	}
+	return check
+}
+
+// checkAPIKey reports whether the provider has credentials, and makes one request
+// checking them when verify is set.
+func checkAPIKey(ctx context.Context, verify bool) doctorCheck {
+	check := doctorCheck{Name: "api key", Status: doctorOK}
+	provider := appConfig.LLM.Provider
+	switch provider {
+	case llm.ProviderMock:
+		check.Detail = "the mock provider needs none"
+		return check
+	case "", llm.ProviderGemini:
+		// A missing key is reported below, so don't log it too.
+		if err := appConfig.RequireGoogleAPIKey(zap.NewNop()); err != nil {
+			check.Status = doctorFail
+			check.Detail = "no Google AI Studio API key"
+			check.Fix = fmt.Sprintf("Run 'historai auth login', or set %s.", config.EnvGoogleAPIKey)
+			return check
+		}
+		check.Detail = "loaded from " + appConfig.GoogleAPIKeySource
+	default:
+		check.Detail = "provider plugin " + provider
+	}
+	if !verify {
+		check.Detail += " (not verified)"
+		return check
+	}
+
+	client, err := llm.NewClient(ctx, logger, appConfig)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Fix = "Check llm.provider and the provider's settings with 'historai config list'."
+		return check
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	verifier, ok := client.(llm.Verifier)
+	if !ok {
+		check.Detail += " (not verified: the provider has no check)"
+		return check
+	}
+	ctx, cancel := context.WithTimeout(ctx, doctorVerifyTimeout)
+	defer cancel()
+	if err := verifier.Verify(ctx); err != nil {
+		check.Status = doctorFail
+		check.Detail += ", but the check failed: " + err.Error()
+		var providerErr *llm.ProviderError
+		var apiErr *googleapi.Error
+		switch {
+		case errors.As(err, &providerErr):
+			check.Fix = providerErr.Hint
+		case errors.As(err, &apiErr) && apiErr.Code < 500:
+			check.Fix = "Create a new key at https://aistudio.google.com/app/apikey and run 'historai auth login'."
+		default:
+			check.Fix = "Check your network connection, or network.proxy and network.ca_file if you are behind a proxy."
+		}
+		return check
+	}
+	check.Detail += ", accepted"
+	return check
+}
+
+// checkAnnotations reports whether the annotations file, holding tags, notes and
+// saved commands, can be read.
+func checkAnnotations() doctorCheck {
+	check := doctorCheck{Name: "data", Status: doctorOK}
+	store, err := annotations.Load("")
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Fix = "Repair the file or move it aside; tags, notes and saved commands are kept there."
+		return check
+	}
+	check.Detail = fmt.Sprintf("%d annotated commands in %s", len(store.Commands), store.Path())
+	return check
+}
+
+// checkDaemon reports whether a daemon is running, and whether it left a stale
+// socket behind.
+func checkDaemon() doctorCheck {
+	check := doctorCheck{Name: "daemon", Status: doctorOK}
+	socket := daemon.DefaultSocketPath()
+	if _, err := os.Stat(socket); err != nil {
+		check.Detail = "not running (optional: 'historai daemon' answers faster)"
+		return check
+	}
+	if err := daemon.Ping(socket); err != nil {
+		check.Status = doctorWarn
+		check.Detail = "stale socket " + socket
+		check.Fix = "Remove " + socket + " or start 'historai daemon' again."
+		return check
+	}
+	check.Detail = "running at " + socket
+	return check
+}
+
+// checkHook reports whether the shell hook is in the shell's startup file and has
+// recorded commands.
+func checkHook() doctorCheck {
+	check := doctorCheck{Name: "hook", Status: doctorOK}
+	shell := filepath.Base(os.Getenv("SHELL"))
+	home, err := os.UserHomeDir()
+	if err != nil || (shell != shellZsh && shell != shellBash) {
+		check.Status = doctorWarn
+		check.Detail = "can't check the hook of this shell"
+		return check
+	}
+	rcFile := filepath.Join(home, ".bashrc")
+	if shell == shellZsh {
+		rcFile = filepath.Join(home, ".zshrc")
+		if zdotdir := os.Getenv("ZDOTDIR"); zdotdir != "" {
+			rcFile = filepath.Join(zdotdir, ".zshrc")
+		}
+	}
+
+	rc, _ := os.ReadFile(rcFile)
+	if !strings.Contains(string(rc), "historai init") {
+		check.Status = doctorWarn
+		check.Detail = "not installed in " + rcFile
+		check.Fix = fmt.Sprintf(`Add eval "$(historai init %s)" to %s to record exit status, directory and duration, which fix, why and oops use.`, shell, rcFile)
+		if appConfig.History.Source == history.SourceHistorai {
+			check.Status = doctorFail
+			check.Fix = fmt.Sprintf(`history.source is historai, which reads what the hook records: add eval "$(historai init %s)" to %s.`, shell, rcFile)
+		}
+		return check
+	}
+
+	recordFile := appConfig.History.File
+	if recordFile == "" || appConfig.History.Source != history.SourceHistorai {
+		recordFile, _ = history.DefaultRecordFile()
+	}
+	info, err := os.Stat(recordFile)
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = "installed in " + rcFile + ", but nothing was recorded yet"
+		check.Fix = "Open a new shell so the hook is loaded, or check the --file given to 'historai init'."
+		return check
+	}
+	check.Detail = fmt.Sprintf("installed in %s, last recorded %s", rcFile, info.ModTime().Format("2006-01-02 15:04"))
+	return check
+}
+
+// printDoctorChecks prints the checks in the format selected by --output.
+func printDoctorChecks(checks []doctorCheck) error {
+	switch outputFormat {
+	case outputJSONL:
+		encoder := json.NewEncoder(os.Stdout)
+		for _, check := range checks {
+			if err := encoder.Encode(check); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputJSON, outputYAML:
+		return printStructuredResult(outputFormat, checks)
+	}
+
+	marks := map[string]string{
+		doctorOK:   color.New(color.FgGreen).Sprint("✓"),
+		doctorWarn: color.New(color.FgYellow).Sprint("!"),
+		doctorFail: color.New(color.FgRed).Sprint("✗"),
+	}
+	fixColor := color.New(color.Faint)
+	for _, check := range checks {
+		if _, err := fmt.Fprintf(os.Stdout, "%s %-8s %s\n", marks[check.Status], check.Name, check.Detail); err != nil {
+			return err
+		}
+		if check.Fix != "" {
+			if _, err := fmt.Fprintf(os.Stdout, "  %-8s %s\n", "", fixColor.Sprint("→ "+check.Fix)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// init adds the doctorCmd to the rootCmd.
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
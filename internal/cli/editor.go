@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/editor"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/version"
+)
+
+// editorCmd represents the editor command
+var editorCmd = &cobra.Command{
+	Use:   "editor",
+	Short: "Serve editor extensions with JSON-RPC over stdio",
+	Long: `Answers the requests of an editor extension (VS Code, Neovim, ...) with
+newline-delimited JSON-RPC 2.0 messages over stdin/stdout, keeping the parsed
+history and the LLM client warm between requests.
+
+Methods:
+  initialize       : Server name, version and capabilities.
+  history/recent   : The most recent commands ({"limit"}), oldest first.
+  history/find     : Search history ({"query", "tag", "limit", "stream"}).
+  commands/suggest : Suggest commands ({"task", "limit", "no_history_context",
+                     "dir", "listing", "stream"}).
+
+find and suggest answer with the text, commands and message of the answer. With
+"stream": true, each command is also sent as soon as the model has written it, as a
+"commands/partial" notification holding the request's id, its rank and the command.
+
+Requests are handled concurrently, and responses sent as they are ready. Send
+{"jsonrpc": "2.0", "method": "$/cancelRequest", "params": {"id": <id>}} to cancel
+one: it then fails with code -32800.
+
+Secrets are redacted from history before it is returned or sent to the LLM.`,
+	Example: `  echo '{"jsonrpc":"2.0","id":1,"method":"history/find","params":{"query":"restart the api"}}' | historai editor`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		eng, err := newEngine(ctx, engine.Options{CacheHistory: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		return editor.NewServer(logger, eng, version.Get().Version).Serve(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+// init adds the editorCmd to the rootCmd.
+func init() {
+	rootCmd.AddCommand(editorCmd)
+}
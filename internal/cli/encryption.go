@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/atrest"
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// encryptionCmd represents the encryption command
+var encryptionCmd = &cobra.Command{
+	Use:   "encryption",
+	Short: "Encrypt historai's history log and annotations at rest",
+	Long: `Encrypts the files historai keeps your history in with AES-256-GCM, so the
+commands, directories and output the shell hook records, and your notes and tags,
+aren't readable by others on a shared machine or from a stolen backup.
+
+The key is generated by 'encryption enable' and kept in the operating system's
+keyring (macOS Keychain, Windows Credential Manager, or the Secret Service on
+Linux). On machines without one, set $HISTORAI_ENCRYPTION_KEY to a base64-encoded
+32-byte key instead; it takes precedence over the keyring. Without the key the
+files can't be read, so keep a copy of it somewhere safe.
+
+Encrypted are historai's own log (history.source: historai) and the annotations.
+Your shell's history file, the outbound log, backups made earlier and 'historai
+export' output are not; the daemon's cache is only kept in memory.
+
+Example:
+  historai encryption enable
+  historai encryption status
+  historai encryption disable`,
+}
+
+// encryptionEnableCmd represents the encryption enable command
+var encryptionEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Generate a key if needed and encrypt the history log and annotations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		created, err := atrest.EnsureKey()
+		if err != nil {
+			return err
+		}
+		if created {
+			if err := infof("Generated an encryption key and stored it in the OS keyring.\n"); err != nil {
+				return err
+			}
+		}
+		return sealFiles(true)
+	},
+}
+
+// encryptionDisableCmd represents the encryption disable command
+var encryptionDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Decrypt the history log and annotations and delete the key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := sealFiles(false); err != nil {
+			return err
+		}
+		if os.Getenv(config.EnvEncryptionKey) != "" {
+			return infof("The files no longer need the key in $%s.\n", config.EnvEncryptionKey)
+		}
+		if err := config.DeleteEncryptionKey(); err != nil {
+			return err
+		}
+		return infof("Encryption key removed from the OS keyring.\n")
+	},
+}
+
+// encryptionStatusCmd represents the encryption status command
+var encryptionStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which files are encrypted and whether the key is available",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, err := encryptedFiles()
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			sealed, err := atrest.FileSealed(path)
+			if err != nil {
+				return err
+			}
+			state := i18n.T("not encrypted")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				state = i18n.T("missing")
+			} else if sealed {
+				state = i18n.T("encrypted")
+			}
+			if _, err := fmt.Fprintf(os.Stdout, "%s: %s\n", path, state); err != nil {
+				return err
+			}
+		}
+		if _, err := config.LoadEncryptionKey(); err != nil {
+			_, err = fmt.Fprint(os.Stdout, i18n.T("Encryption key: not available (%v)\n", err))
+			return err
+		}
+		_, err = fmt.Fprint(os.Stdout, i18n.T("Encryption key: available\n"))
+		return err
+	},
+}
+
+// encryptedFiles returns the files 'encryption enable' encrypts: historai's own log
+// and the annotations.
+func encryptedFiles() ([]string, error) {
+	recordFile, err := defaultImportTarget()
+	if err != nil {
+		return nil, err
+	}
+	annotationsFile, err := annotations.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{recordFile, annotationsFile}, nil
+}
+
+// sealFiles encrypts historai's own log, its archive and the annotations when seal
+// is set, and decrypts them otherwise.
+func sealFiles(seal bool) error {
+	recordFile, err := defaultImportTarget()
+	if err != nil {
+		return err
+	}
+	converted, err := history.SealRecords(recordFile, seal)
+	if err != nil {
+		return err
+	}
+	if seal {
+		err = infof("Encrypted %d entries of %s.\n", converted, recordFile)
+	} else {
+		err = infof("Decrypted %d entries of %s.\n", converted, recordFile)
+	}
+	if err != nil {
+		return err
+	}
+	archive, err := history.OpenArchive(logger, recordFile)
+	if err != nil {
+		return err
+	}
+	if converted, err = archive.Seal(seal); err != nil {
+		return err
+	}
+	if converted > 0 && seal {
+		err = infof("Encrypted %d archived entries in %s.\n", converted, archive.HistoryFile())
+	} else if converted > 0 {
+		err = infof("Decrypted %d archived entries in %s.\n", converted, archive.HistoryFile())
+	}
+	if err != nil {
+		return err
+	}
+
+	store, err := annotations.Load("")
+	if err != nil {
+		return err
+	}
+	if store.Encrypted() == seal {
+		return nil
+	}
+	store.SetEncrypted(seal)
+	if err := store.Save(); err != nil {
+		return err
+	}
+	if seal {
+		return infof("Encrypted %s.\n", store.Path())
+	}
+	return infof("Decrypted %s.\n", store.Path())
+}
+
+// init adds the encryptionCmd and its subcommands to the rootCmd.
+func init() {
+	rootCmd.AddCommand(encryptionCmd)
+	encryptionCmd.AddCommand(encryptionEnableCmd, encryptionDisableCmd, encryptionStatusCmd)
+}
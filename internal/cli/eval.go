@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/eval"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// evalCmd represents the eval command
+var evalCmd = &cobra.Command{
+	Use:   "eval [dataset.yaml]",
+	Short: "Score models and prompt instructions on a dataset of queries with known answers",
+	Long: `Runs every case of a dataset — a history fixture, a find or suggest query, and
+the commands a good answer contains — against one or more models, and reports for
+each how many cases it passed, how high the expected command ranked (MRR), how long
+it took and, for known Gemini models, what it cost. Use it to check that a prompt
+or model change is an improvement.
+
+Without a dataset file, the golden dataset shipped with historai is used. A
+dataset may list named prompt instructions under "prompts"; each is then scored
+with every model, in place of prompt.instructions from the config file. Your own
+history and annotations are never sent.
+
+Flags:
+  --with / -w      : A model to score, as model or provider:model; repeat for each one (default: the configured model).
+  --min-score      : Fail when a variant passes fewer than this share of the cases, from 0 to 1 (e.g. in CI).
+  --failures / -f  : List the cases each variant failed, with its answer.
+
+Example:
+  historai eval
+  historai eval my-cases.yaml -w gemini-1.5-flash -w gemini-2.0-flash --failures
+  historai eval --min-score 0.8
+  historai eval -o json | jq '.[] | {name: .variant.name, score, mrr}'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate arguments and flags
+		opts, err := parseEvalFlags(cmd)
+		if err != nil {
+			return err
+		}
+		path := ""
+		if len(args) == 1 {
+			path = args[0]
+		}
+		dataset, err := eval.Load(path)
+		if err != nil {
+			return err
+		}
+
+		// 2. Score every variant at once
+		variants := evalVariants(opts.models, dataset.Prompts)
+		runner := &eval.Runner{Logger: logger, Config: appConfig}
+		names := make([]string, len(variants))
+		for i, variant := range variants {
+			names[i] = variant.Name
+		}
+		spin := startLabeledSpinner(strings.Join(names, ", "))
+		reports := make([]eval.Report, len(variants))
+		errs := make([]error, len(variants))
+		var wg sync.WaitGroup
+		for i, variant := range variants {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reports[i], errs[i] = runner.Run(context.Background(), dataset, variant)
+			}()
+		}
+		wg.Wait()
+		spin.stop()
+		for i, err := range errs {
+			if err != nil {
+				return fmt.Errorf("%s: %w", variants[i].Name, err)
+			}
+		}
+
+		// 3. Print the reports
+		switch outputFormat {
+		case outputText:
+			if err := printEvalReports(reports, opts.failures); err != nil {
+				return err
+			}
+		case outputJSONL:
+			encoder := json.NewEncoder(os.Stdout)
+			for _, report := range reports {
+				if err := encoder.Encode(report); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := printStructuredResult(outputFormat, reports); err != nil {
+				return err
+			}
+		}
+
+		// 4. Check the score threshold
+		for _, report := range reports {
+			if report.Score < opts.minScore {
+				return fmt.Errorf("%s scored %.2f, below --min-score %.2f", report.Variant.Name, report.Score, opts.minScore)
+			}
+		}
+		return nil
+	},
+}
+
+// evalOptions holds the parsed flags of the eval command.
+type evalOptions struct {
+	models   []string
+	minScore float64
+	failures bool
+}
+
+// parseEvalFlags extracts and validates flags specific to the eval command.
+func parseEvalFlags(cmd *cobra.Command) (evalOptions, error) {
+	var opts evalOptions
+	var err error
+	flags := cmd.Flags()
+
+	if opts.models, err = flags.GetStringArray("with"); err != nil {
+		return opts, fmt.Errorf("internal error getting with flag: %w", err)
+	}
+	if opts.minScore, err = flags.GetFloat64("min-score"); err != nil {
+		return opts, fmt.Errorf("internal error getting min-score flag: %w", err)
+	}
+	if opts.minScore < 0 || opts.minScore > 1 {
+		return opts, fmt.Errorf("--min-score must be between 0 and 1")
+	}
+	if opts.failures, err = flags.GetBool("failures"); err != nil {
+		return opts, fmt.Errorf("internal error getting failures flag: %w", err)
+	}
+	return opts, nil
+}
+
+// evalVariants pairs every model spec (as for compare: model or provider:model; the
+// configured model when there are none) with every prompt (the configured
+// instructions when there are none).
+func evalVariants(specs []string, prompts []eval.Prompt) []eval.Variant {
+	if len(specs) == 0 {
+		specs = []string{""}
+	}
+	if len(prompts) == 0 {
+		prompts = []eval.Prompt{{Instructions: appConfig.Prompt.Instructions}}
+	}
+	var variants []eval.Variant
+	for _, spec := range specs {
+		for _, prompt := range prompts {
+			variant := eval.Variant{Provider: appConfig.LLM.Provider, Model: appConfig.LLM.Model, Instructions: prompt.Instructions}
+			if provider, model, ok := strings.Cut(spec, ":"); ok {
+				variant.Provider, variant.Model = provider, model
+			} else if spec != "" {
+				variant.Model = spec
+			}
+			if variant.Provider == "" {
+				variant.Provider = llm.ProviderGemini
+			}
+			variant.Name = variant.Provider
+			if model := llm.ModelName(&config.Config{LLM: config.LLMConfig{Provider: variant.Provider, Model: variant.Model}}); model != "" {
+				variant.Name = model
+			}
+			if prompt.Name != "" {
+				variant.Name += " (" + prompt.Name + ")"
+			}
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// printEvalReports prints a table of the variants' scores and, with failures, the
+// cases each one failed.
+func printEvalReports(reports []eval.Report, failures bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VARIANT\tPASSED\tMRR\tLATENCY\tTOKENS\tCOST")
+	for _, report := range reports {
+		tokens, cost := "-", "-"
+		if report.Usage != nil {
+			tokens = fmt.Sprintf("%d+%d", report.Usage.PromptTokens, report.Usage.OutputTokens)
+		}
+		if report.CostUSD != nil {
+			cost = fmt.Sprintf("$%.4f", *report.CostUSD)
+		}
+		latency := (time.Duration(report.AvgDurationMS) * time.Millisecond).Round(time.Second / 10)
+		_, _ = fmt.Fprintf(w, "%s\t%d/%d (%.0f%%)\t%.2f\t%s\t%s\t%s\n",
+			report.Variant.Name, report.Passed, report.Total, report.Score*100, report.MRR, latency, tokens, cost)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !failures {
+		return nil
+	}
+
+	red, faint := color.New(color.FgRed), color.New(color.Faint)
+	var b strings.Builder
+	for _, report := range reports {
+		for _, result := range report.Cases {
+			if result.Passed {
+				continue
+			}
+			b.WriteString("\n" + red.Sprint("✗ ") + report.Variant.Name + ": " + result.Name + "\n")
+			switch {
+			case result.Error != "":
+				b.WriteString(faint.Sprint("  "+i18n.T("Error: %s", result.Error)) + "\n")
+			case len(result.Commands) == 0:
+				b.WriteString(faint.Sprint("  "+i18n.T("(no commands)")) + "\n")
+			default:
+				for _, command := range result.Commands {
+					b.WriteString(faint.Sprint("  "+command) + "\n")
+				}
+			}
+		}
+	}
+	_, err := fmt.Fprint(os.Stdout, b.String())
+	return err
+}
+
+// init adds the evalCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(evalCmd)
+
+	evalCmd.Flags().StringArrayP("with", "w", nil, "A model to score, as model or provider:model; repeat for each one")
+	evalCmd.Flags().Float64("min-score", 0, "Fail when a variant passes fewer than this share of the cases (0 to 1)")
+	evalCmd.Flags().BoolP("failures", "f", false, "List the cases each variant failed, with its answer")
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// Exit codes returned by the historai binary, so that wrappers and shell widgets
+// can branch on the outcome without parsing messages.
+const (
+	// ExitOK means results were found and printed.
+	ExitOK = 0
+	// ExitError is any failure not covered by a more specific code.
+	ExitError = 1
+	// ExitNoMatches means the command succeeded but found or suggested nothing.
+	ExitNoMatches = 2
+	// ExitBlocked means the request was blocked by the provider's safety filters.
+	ExitBlocked = 3
+	// ExitConfig means the configuration or credentials are missing or invalid, or
+	// the organization policy doesn't allow what was asked.
+	ExitConfig = 4
+)
+
+// exitError carries a specific exit code. A nil err means the outcome needs no
+// message, e.g. for ExitNoMatches.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return ""
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// errNoMatches is returned by find/suggest when the LLM returned no commands.
+var errNoMatches = &exitError{code: ExitNoMatches}
+
+// configError marks err as a configuration or credentials problem.
+func configError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: ExitConfig, err: err}
+}
+
+// ExitCode maps an error returned by Execute to the process exit code.
+func ExitCode(err error) int {
+	var exitErr *exitError
+	switch {
+	case err == nil, errors.Is(err, llm.ErrDryRun):
+		return ExitOK
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.Is(err, llm.ErrBlocked):
+		return ExitBlocked
+	case errors.Is(err, config.ErrMissingAPIKey), errors.Is(err, config.ErrPolicy):
+		return ExitConfig
+	case isConfigProviderError(err):
+		return ExitConfig
+	default:
+		return ExitError
+	}
+}
+
+// isConfigProviderError reports whether err is a provider failure fixed in the
+// configuration or credentials, such as a rejected API key or an unknown model.
+func isConfigProviderError(err error) bool {
+	var providerErr *llm.ProviderError
+	return errors.As(err, &providerErr) && providerErr.Config
+}
+
+// ErrorMessage returns the message to print for err. A provider failure the user
+// can fix is printed by itself, with the fix: the errors wrapping it add nothing
+// but noise, and the API's own error may be long and cryptic.
+func ErrorMessage(err error) string {
+	var providerErr *llm.ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Error()
+	}
+	return err.Error()
+}
+
+// IsSilent reports whether err only carries an exit code and should not be printed.
+// A dry run ends with llm.ErrDryRun once the prompt is printed, which is no failure.
+func IsSilent(err error) bool {
+	var exitErr *exitError
+	return (errors.As(err, &exitErr) && exitErr.err == nil) || errors.Is(err, llm.ErrDryRun)
+}
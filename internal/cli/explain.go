@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+const (
+	defaultExplainHistoryLimit = config.DefaultFindLimit
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain [\"<command>\"]",
+	Short: "Explain a shell command flag by flag using AI",
+	Long: `Asks an LLM to explain a shell command: what it does, and what each
+subcommand, flag, argument and redirection means. Previous runs of the same
+program in your shell history are used as context, so the explanation can point
+out how this invocation differs from how you usually run it.
+
+Without an argument, the most recent command in your history is explained.
+
+Flags:
+  --back / -b   : Explain the N-th most recent history entry instead (default: 1, the last command).
+  --limit / -n  : How many recent history entries to search for previous uses (default: 300, or find.limit from the config file).
+
+Example:
+  historai explain "tar -xzvf archive.tar.gz -C /tmp"
+  historai explain
+  historai explain --back 3`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (back, limit)
+		back, limit, err := parseExplainFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Pick the command to explain
+		command := ""
+		if len(args) == 1 {
+			command = strings.TrimSpace(args[0])
+		} else {
+			command, err = recentCommand(eng, back)
+			if err != nil {
+				return err
+			}
+		}
+		if command == "" {
+			return errors.New(i18n.T("command cannot be empty"))
+		}
+		logger.Debug("Explaining command", zap.String("command", command))
+
+		// 4. Ask the LLM for an explanation
+		started := time.Now()
+		spin := startSpinner()
+		explanation, err := eng.Explain(context.Background(), command, limit)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 5. Print Header to Stderr & Explanation to Stdout
+		return printExplanation(logger, commandOutput{
+			kind:         "explain",
+			query:        command,
+			output:       explanation,
+			noAnswer:     "(AI could not explain this command)",
+			header:       i18n.T("--- Explanation: %s ---", command),
+			logOnFailure: "No explanation generated or response indicates failure.",
+			started:      started,
+		})
+	},
+}
+
+// parseExplainFlags extracts and validates flags specific to the explain command.
+func parseExplainFlags(cmd *cobra.Command) (back int, limit int, err error) {
+	back, err = cmd.Flags().GetInt("back")
+	if err != nil {
+		logger.Error("Failed to get 'back' flag value", zap.Error(err))
+		err = fmt.Errorf("internal error getting back flag: %w", err)
+		return
+	}
+	if back < 1 {
+		err = errors.New("--back must be at least 1")
+		return
+	}
+
+	limit, err = cmd.Flags().GetInt("limit")
+	if err != nil {
+		logger.Error("Failed to get 'limit' flag value", zap.Error(err))
+		err = fmt.Errorf("internal error getting limit flag: %w", err)
+		return
+	}
+	if !cmd.Flags().Changed("limit") {
+		limit = appConfig.Find.Limit
+	}
+	return back, limit, nil
+}
+
+// recentCommand returns the back-th most recent history entry, skipping historai's own invocations.
+func recentCommand(eng *engine.Engine, back int) (string, error) {
+	entries, err := eng.History(0)
+	if err != nil {
+		return "", err
+	}
+	entries = withoutSelf(entries)
+	if back > len(entries) {
+		return "", fmt.Errorf("history has only %d entries", len(entries))
+	}
+	return entries[len(entries)-back].Command, nil
+}
+
+// withoutSelf drops historai's own invocations, which are rarely what the user means
+// by "the last command".
+func withoutSelf(entries []history.HistoryEntry) []history.HistoryEntry {
+	var filtered []history.HistoryEntry
+	for _, entry := range entries {
+		if fields := strings.Fields(entry.Command); len(fields) > 0 && fields[0] == "historai" {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// init adds the explainCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().IntP("back", "b", 1, "Explain the N-th most recent history entry when no command is given")
+	explainCmd.Flags().IntP("limit", "n", defaultExplainHistoryLimit, "Limit the number of most recent history entries searched for previous uses")
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/redact"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export your parsed shell history",
+	Long: `Writes your parsed shell history, with whatever metadata the source records
+(timestamps, working directories, exit codes, durations), for backup, analysis
+or migration to other tools.
+
+Formats:
+  json   : A JSON array of entries.
+  jsonl  : One JSON entry per line.
+  csv    : timestamp, command, cwd, exit_code, duration_ms, host columns with a header row, e.g. for pandas.
+  atuin  : One JSON object per line with the fields of Atuin's history table.
+  zsh    : Zsh extended history, which Atuin and most history tools can import.
+  plain  : One command per line.
+
+Flags:
+  --format / -f : Output format (default: json).
+  --file        : Write to this file instead of stdout.
+  --since / -s  : Only export commands run since today, yesterday, 12h, 3d, 2w or a date like 2024-05-01.
+  --redact      : Mask secrets in the exported commands.
+
+Example:
+  historai export > history.json
+  historai export -f csv --file history.csv
+  historai export -f zsh --file /tmp/h && HISTFILE=/tmp/h atuin import zsh`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		flags := cmd.Flags()
+		format, err := flags.GetString("format")
+		if err != nil {
+			return fmt.Errorf("internal error getting format flag: %w", err)
+		}
+		if !isExportFormat(format) {
+			return fmt.Errorf("invalid --format %q (expected one of %s)", format, strings.Join(history.ExportFormats, ", "))
+		}
+		file, err := flags.GetString("file")
+		if err != nil {
+			return fmt.Errorf("internal error getting file flag: %w", err)
+		}
+		sinceValue, err := flags.GetString("since")
+		if err != nil {
+			return fmt.Errorf("internal error getting since flag: %w", err)
+		}
+		redactSecrets, err := flags.GetBool("redact")
+		if err != nil {
+			return fmt.Errorf("internal error getting redact flag: %w", err)
+		}
+
+		// 2. Read history
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		entries, err := reader.ReadHistory(0)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		if sinceValue != "" {
+			since, err := parseSince(sinceValue, time.Now())
+			if err != nil {
+				return err
+			}
+			entries = entriesSince(entries, since)
+		}
+		if redactSecrets {
+			redactor, err := redact.FromPatterns(appConfig.Redaction.Patterns)
+			if err != nil {
+				return configError(fmt.Errorf("failed to load configuration: %w", err))
+			}
+			entries = redactor.RedactEntries(entries)
+		}
+		logger.Debug("Exporting history", zap.String("format", format), zap.Int("entries_count", len(entries)))
+
+		// 3. Write the export
+		var out io.Writer = os.Stdout
+		if file != "" {
+			f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", file, err)
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := history.Export(out, format, entries); err != nil {
+			return fmt.Errorf("failed to export history: %w", err)
+		}
+		if file != "" {
+			return infof("Exported %d entries to %s\n", len(entries), file)
+		}
+		return nil
+	},
+}
+
+// isExportFormat reports whether format is one of history.ExportFormats.
+func isExportFormat(format string) bool {
+	for _, known := range history.ExportFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// init adds the exportCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringP("format", "f", history.FormatJSON, "Output format: "+strings.Join(history.ExportFormats, ", "))
+	exportCmd.Flags().String("file", "", "Write to this file instead of stdout")
+	exportCmd.Flags().StringP("since", "s", "", "Only export commands run since today, yesterday, 12h, 3d, 2w or a date")
+	exportCmd.Flags().Bool("redact", false, "Mask secrets in the exported commands")
+}
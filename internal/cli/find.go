@@ -4,15 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/sanspareilsmyn/historai/internal/annotations"
 	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/engine"
 	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
 	"github.com/sanspareilsmyn/historai/internal/llm"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"time"
 )
 
 const (
-	defaultFindHistoryLimit = 300
+	defaultFindHistoryLimit = config.DefaultFindLimit
 )
 
 // findCmd represents the find command
@@ -22,41 +27,92 @@ var findCmd = &cobra.Command{
 	Long: `Searches your shell history file (currently Zsh: ~/.zsh_history) using an LLM
 to find commands that match the provided natural language description.
 
-You can limit the scope of the history search using the flag:
-  --limit / -n : How many recent entries to consider (default: 300).
+When your history merges several machines (see 'historai import --host'), the
+query can name the machine a command ran on.
+
+When the AI provider can't be used, because there is no network, no API key or an
+outage, find falls back to a keyword and fuzzy search of the same history. Its
+results are labeled as not coming from the AI.
+
+You can limit the scope of the history search using the flags:
+  --limit / -n : How many commands to consider, the recent, frequent and matching ones
+                 (see context.selection; default: 300, or find.limit from the config file).
+  --tag / -t   : Only search commands with this tag (see 'historai tag').
+
+To search other commands than your shell history, e.g. an old backup, use:
+  --history : Read them from this file, or stdin for "-". Zsh, bash, plain (one command
+              per line) and the other 'historai import' formats are detected.
+
+To run many queries, e.g. for scripts or to evaluate prompts, use:
+  --batch            : Read one query per line from this file, or stdin for "-", and write
+                       a JSON line per query, in order. Blank lines and # comments are skipped.
+                       Queries that fail are reported in their line, and don't fall back to
+                       keyword search.
+  --concurrency / -j : How many queries of a batch to send at once (default: 4).
 
 Example:
   historai find "how I listed files sorted by size last month"
-  historai find --limit 500 "the ssh command to connect to the webserver"`,
-	Args: cobra.ExactArgs(1),
+  historai find --limit 500 "the ssh command to connect to the webserver"
+  historai find "the docker build I ran on the build server"
+  historai find --tag deploy "the one for the EU cluster"
+  cat old_history_backup | historai find --history - "the rsync to the NAS"
+  historai find --batch queries.txt > results.jsonl
+  printf 'ssh to the web server\nlast docker build\n' | historai find --batch -`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger.Debug("Executing find command")
 
-		query := args[0]
-		if query == "" {
-			return errors.New("query cannot be empty")
-		}
-		logger.Debug("Received query", zap.String("query", query))
-
 		// 1. Parse and validate flags
-		limit, err := parseFindFlags(cmd)
+		limit, tag, err := parseFindFlags(cmd)
+		if err != nil {
+			return err
+		}
+		batch, concurrency, err := parseBatchFlags(cmd, len(args))
 		if err != nil {
 			return err
 		}
+		entries, err := parseFindHistoryInput(cmd, batch, tag)
+		if err != nil {
+			return err
+		}
+		if batch != "" {
+			return runFindBatch(batch, tag, limit, concurrency, entries)
+		}
+
+		if len(args) == 0 || args[0] == "" {
+			return errors.New(i18n.T("query cannot be empty"))
+		}
+		query := args[0]
+		logger.Debug("Received query", zap.String("query", query))
 
 		// 2. Execute the core finding logic
-		result, err := runFind(logger, query, limit)
+		started := time.Now()
+		stream := newCommandStreamer("find", query)
+		spin := startSpinner()
+		result, err := runFind(logger, query, tag, limit, entries, spin.stopOnChunk(stream.chunkFunc()))
+		spin.stop()
+		header := i18n.T("--- Found Commands ---")
+		offline := !dryRun && llm.IsUnavailable(err)
+		if offline {
+			// 3. Without the provider, fall back to a local keyword search
+			result, err = searchOffline(logger, err, query, tag, limit, entries)
+			header = i18n.T("--- Found Commands (keyword search, not AI) ---")
+		}
 		if err != nil {
 			return err
 		}
 
-		// 3. Print Header to Stderr & Result to Stdout
-		err = printCommandOutput(
-			logger,
-			result,
-			"--- Found Commands ---",
-			"No relevant commands found or response indicates failure.",
-		)
+		// 4. Print Header to Stderr & Result to Stdout
+		err = printResult(logger, commandOutput{
+			kind:         "find",
+			query:        query,
+			answer:       result,
+			header:       header,
+			logOnFailure: "No relevant commands found or response indicates failure.",
+			started:      started,
+			stream:       stream,
+			offline:      offline,
+		})
 		if err != nil {
 			return err
 		}
@@ -66,69 +122,108 @@ Example:
 }
 
 // parseFindFlags extracts and validates flags specific to the find command.
-func parseFindFlags(cmd *cobra.Command) (limit int, err error) {
+func parseFindFlags(cmd *cobra.Command) (limit int, tag string, err error) {
 	limit, err = cmd.Flags().GetInt("limit")
 	if err != nil {
 		logger.Error("Failed to get 'limit' flag value", zap.Error(err))
 		err = fmt.Errorf("internal error getting limit flag: %w", err)
 		return
 	}
-	return limit, nil
-}
+	if !cmd.Flags().Changed("limit") {
+		limit = appConfig.Find.Limit
+	}
 
-// runFind executes the main logic: config, history, LLM interaction.
-func runFind(logger *zap.Logger, query string, limit int) (string, error) {
-	// 1. Load Configuration
-	logger.Debug("Loading configuration...")
-	cfg, err := config.LoadConfig(logger)
+	tag, err = cmd.Flags().GetString("tag")
 	if err != nil {
-		return "", fmt.Errorf("failed to load configuration: %w", err)
+		err = fmt.Errorf("internal error getting tag flag: %w", err)
+		return
 	}
-	logger.Debug("Configuration loaded successfully")
+	if tag != "" {
+		tag, err = annotations.NormalizeTag(tag)
+	}
+	return limit, tag, err
+}
 
-	// 2. Read Shell History
-	// TODO: Replace with a factory when supporting multiple shells!!
-	historyReader, err := history.NewZshHistoryReader(logger)
+// parseBatchFlags extracts and validates the flags of find --batch, given the
+// number of query arguments.
+func parseBatchFlags(cmd *cobra.Command, queryCount int) (batch string, concurrency int, err error) {
+	batch, err = cmd.Flags().GetString("batch")
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize history reader: %w", err)
+		return "", 0, fmt.Errorf("internal error getting batch flag: %w", err)
+	}
+	concurrency, err = cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return "", 0, fmt.Errorf("internal error getting concurrency flag: %w", err)
+	}
+	if batch == "" {
+		return "", 0, nil
 	}
 
-	historyEntries, err := historyReader.ReadHistory(limit)
+	switch {
+	case queryCount > 0:
+		return "", 0, errors.New(i18n.T("--batch reads the queries from a file; don't give one as an argument"))
+	case concurrency < 1:
+		return "", 0, errors.New(i18n.T("--concurrency must be at least 1"))
+	case dryRun:
+		return "", 0, errors.New(i18n.T("--dry-run cannot be combined with --batch"))
+	case rawOutput || firstOnly || (outputFormat != outputText && outputFormat != outputJSONL):
+		return "", 0, errors.New(i18n.T("--batch always writes JSON lines; drop --raw, --first and --output"))
+	}
+	return batch, concurrency, nil
+}
+
+// parseFindHistoryInput reads the history given with --history, which can't share
+// stdin with the batch queries or be searched by tag: annotations don't apply to it.
+func parseFindHistoryInput(cmd *cobra.Command, batch, tag string) ([]history.HistoryEntry, error) {
+	path, err := cmd.Flags().GetString("history")
 	if err != nil {
-		return "", fmt.Errorf("failed to read history: %w", err)
+		return nil, fmt.Errorf("internal error getting history flag: %w", err)
+	}
+	switch {
+	case path == "":
+		return nil, nil
+	case path == "-" && batch == "-":
+		return nil, errors.New(i18n.T("--history and --batch can't both read stdin"))
+	case tag != "":
+		return nil, errors.New(i18n.T("--tag cannot be combined with --history, whose commands have no tags"))
 	}
-	logger.Debug("History read successfully", zap.Int("entries_count", len(historyEntries)))
+	return readHistoryInput(cmd)
+}
 
-	// 3. Initialize LLM Client
-	ctx := context.Background()
-	logger.Debug("Initializing LLM client...")
-	// TODO: Add support for other LLMs!!
-	llmClient, err := llm.NewGeminiClient(ctx, logger, cfg.GoogleAPIKey)
+// runFind executes the main logic, preferring a running daemon over a cold start.
+// A non-empty tag restricts the search to commands with that tag, and non-nil
+// entries are searched instead of the configured history.
+// onChunk, if set, receives the response as it streams in from the LLM.
+func runFind(logger *zap.Logger, query, tag string, limit int, entries []history.HistoryEntry, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	// 1. Try the daemon, which keeps parsed history and a warm LLM client
+	if entries == nil {
+		if result, ok, err := tryDaemon(logger, daemon.Request{Op: daemon.OpFind, Query: query, Limit: limit, Tag: tag}); ok {
+			return result, err
+		}
+	}
+
+	// 2. Initialize Engine (config, history, LLM client)
+	eng, err := newEngine(context.Background(), engine.Options{History: entries})
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize LLM client: %w", err)
+		return llm.CommandAnswer{}, err
 	}
 	defer func() {
-		logger.Debug("Closing LLM client...")
-		if closeErr := llmClient.Close(); closeErr != nil {
+		if closeErr := eng.Close(); closeErr != nil {
 			logger.Error("Failed to close LLM client", zap.Error(closeErr))
 		}
 	}()
-	logger.Debug("LLM client initialized successfully")
-
-	// 4. Call LLM API
-	logger.Debug("Sending query and history context to LLM...", zap.Int("history_context_size", len(historyEntries)))
-	result, err := llmClient.FindHistoryEntries(query, historyEntries)
-	if err != nil {
-		return "", fmt.Errorf("failed to get results from LLM: %w", err)
-	}
-	logger.Debug("Received response from LLM")
 
-	return result, nil
+	// 3. Search History via LLM
+	return eng.FindTagStream(context.Background(), query, tag, limit, onChunk)
 }
 
 // init adds the findCmd and its flags to the rootCmd.
 func init() {
 	rootCmd.AddCommand(findCmd)
 
-	findCmd.Flags().IntP("limit", "n", defaultFindHistoryLimit, "Limit the number of most recent history entries to analyze")
+	findCmd.Flags().IntP("limit", "n", defaultFindHistoryLimit, "Limit the number of history entries to analyze, picked by context.selection")
+	findCmd.Flags().StringP("tag", "t", "", "Only search commands with this tag")
+	findCmd.Flags().String("history", "", historyFlagUsage)
+	findCmd.Flags().String("batch", "", "Answer one query per line of this file (\"-\" for stdin), as JSON lines")
+	findCmd.Flags().IntP("concurrency", "j", defaultBatchConcurrency, "How many queries of a batch to send at once")
 }
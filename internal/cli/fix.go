@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+const (
+	defaultFixHistoryLimit = config.DefaultFindLimit
+)
+
+// fixCmd represents the fix command
+var fixCmd = &cobra.Command{
+	Use:   "fix [\"<command>\"]",
+	Short: "Suggest a corrected version of the last failed command",
+	Long: `Asks an LLM to correct a command that failed, and shows the fix as a
+word-level diff against the original. Successful previous runs of the same
+program in your shell history are used as context.
+
+Without an argument, the most recent command that exited with a non-zero status
+is used. Exit codes are only known for commands recorded by historai's shell
+hook; see 'historai init'.
+
+Flags:
+  --limit / -n : How many recent history entries to search for previous uses (default: 300, or find.limit from the config file).
+
+Example:
+  historai fix
+  historai fix "git comit -m 'update readme'"
+  historai fix --first | sh     # run the fix directly (review it first!)`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (limit)
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			logger.Error("Failed to get 'limit' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting limit flag: %w", err)
+		}
+		if !cmd.Flags().Changed("limit") {
+			limit = appConfig.Find.Limit
+		}
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Pick the failed command
+		var failed history.HistoryEntry
+		if len(args) == 1 {
+			failed.Command = strings.TrimSpace(args[0])
+		} else if failed, err = eng.LastFailed(); err != nil {
+			return err
+		}
+		if failed.Command == "" {
+			return errors.New(i18n.T("command cannot be empty"))
+		}
+		logger.Debug("Fixing command", zap.String("command", failed.Command))
+
+		// 4. Ask the LLM for a fix
+		started := time.Now()
+		spin := startSpinner()
+		result, err := eng.Fix(context.Background(), failed, limit)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 5. Print the fix
+		out := commandOutput{
+			kind:         "fix",
+			query:        failed.Command,
+			answer:       result,
+			header:       i18n.T("--- Fix for: %s ---", failed.Command),
+			logOnFailure: "No fix generated or response indicates failure.",
+			started:      started,
+		}
+		if outputFormat != outputText || rawOutput || firstOnly {
+			return printResult(logger, out)
+		}
+		return printFixDiff(logger, out)
+	},
+}
+
+// printFixDiff prints the first suggested command as a word-level diff against the
+// original, followed by the model's explanation.
+func printFixDiff(logger *zap.Logger, out commandOutput) error {
+	commands := out.answer.Commands
+	if len(commands) == 0 {
+		return printCommandOutput(logger, out)
+	}
+	fix := commands[0]
+
+	if !quiet {
+		if _, err := color.New(color.FgYellow).Fprintln(os.Stderr, "\n"+out.header); err != nil {
+			return err
+		}
+	}
+	if strings.Join(strings.Fields(fix.Command), " ") == strings.Join(strings.Fields(out.query), " ") {
+		return infof("The command looks correct already.\n")
+	}
+
+	removed, added := diffWords(out.query, fix.Command)
+	if _, err := fmt.Fprintln(os.Stdout, formatDiffLine("- ", removed, color.FgRed)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(os.Stdout, formatDiffLine("+ ", added, color.FgGreen)); err != nil {
+		return err
+	}
+	if fix.Explanation != "" {
+		_, err := color.New(color.Faint).Fprintln(os.Stdout, "# "+fix.Explanation)
+		return err
+	}
+	return nil
+}
+
+// init adds the fixCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(fixCmd)
+
+	fixCmd.Flags().IntP("limit", "n", defaultFixHistoryLimit, "Limit the number of most recent history entries searched for previous uses")
+}
@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// forgetCmd represents the forget command
+var forgetCmd = &cobra.Command{
+	Use:   "forget <text or N>",
+	Short: "Delete commands from historai's history, annotations and caches",
+	Long: `Deletes every command containing <text> from the history the shell hook
+records, along with their notes, tags and saved status, and drops the daemon's
+cached history, e.g. after typing a password as a command by mistake. A number N
+forgets the Nth most recent command (1 is the last one) wherever it appears.
+
+Your shell's own history file is only rewritten with --shell-history; historai
+tells you when it still holds matching commands. Its original is saved with a
+timestamped name next to it, as with 'historai prune', unless --no-backup is
+given: that copy still holds the forgotten commands, so delete it once you are
+sure. historai's own log and its archive are rewritten without a backup.
+
+Running shells keep their history in memory and may write it back on exit, so
+restart them afterwards. Copies made earlier, such as prune's backups or the
+outbound log, are not changed. The forgotten entries are remembered, by a hash
+rather than the command, so that 'historai sync' doesn't pull them back and
+removes them from the remote copy on its next push; run forget on your other
+machines too, as their own logs still hold them.
+
+Flags:
+  --regex / -E     : Treat <text> as a regular expression.
+  --shell-history  : Also rewrite your shell's history file.
+  --no-backup      : Don't keep a copy of the shell's original history file.
+  --dry-run        : List what would be forgotten without changing anything.
+  --yes / -y       : Don't ask for confirmation.
+
+Example:
+  historai forget 1
+  historai forget 'hunter2' --shell-history --no-backup
+  historai forget -E 'export [A-Z_]+_TOKEN=' --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags and the pattern
+		opts, err := parseForgetFlags(cmd)
+		if err != nil {
+			return err
+		}
+		match, err := forgetMatcher(args[0], opts.regex)
+		if err != nil {
+			return err
+		}
+
+		// 2. Find the matching commands without changing anything
+		targets, shell, err := forgetTargets(opts.shellHistory)
+		if err != nil {
+			return err
+		}
+		total := 0
+		for i := range targets {
+			if targets[i].matches, err = planForget(targets[i].rewriter, match); err != nil {
+				return err
+			}
+			total += len(targets[i].matches)
+		}
+		store, err := annotations.Load("")
+		if err != nil {
+			return err
+		}
+		var annotated []string
+		for _, command := range store.Sorted() {
+			if match(command) {
+				annotated = append(annotated, command)
+			}
+		}
+		total += len(annotated)
+		var remaining []string
+		if shell != nil {
+			if remaining, err = planForget(shell, match); err != nil {
+				logger.Debug("Failed to check the shell history", zap.Error(err))
+			}
+		}
+		logger.Debug("Planned forget", zap.Int("targets_count", len(targets)), zap.Int("annotated_count", len(annotated)),
+			zap.Int("remaining_count", len(remaining)))
+
+		if total == 0 {
+			if len(remaining) == 0 {
+				err = infof("Nothing matches %q.\n", args[0])
+			} else {
+				err = remindShellHistory(shell, remaining)
+			}
+			if err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+		if opts.dryRun {
+			locationColor := color.New(color.Faint)
+			for _, target := range targets {
+				for _, command := range target.matches {
+					if _, err := fmt.Fprintf(os.Stdout, "%s  %s\n", locationColor.Sprint(target.rewriter.HistoryFile()), command); err != nil {
+						return err
+					}
+				}
+			}
+			for _, command := range annotated {
+				if _, err := fmt.Fprintf(os.Stdout, "%s  %s\n", locationColor.Sprint(store.Path()), command); err != nil {
+					return err
+				}
+			}
+			if err := infof("Would forget %d entries.\n", total); err != nil {
+				return err
+			}
+			return remindShellHistory(shell, remaining)
+		}
+
+		// 3. Forget them everywhere
+		if !opts.yes {
+			ok, err := confirm(i18n.T("Forget %d entries matching %q?", total, args[0]))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		forgotten, err := history.LoadForgotten(forgetRecordFile())
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if len(target.matches) == 0 {
+				continue
+			}
+			if err := forgetIn(target, match, opts.noBackup, forgotten); err != nil {
+				return err
+			}
+		}
+		if err := forgotten.Save(); err != nil {
+			return err
+		}
+		if len(annotated) > 0 {
+			for _, command := range annotated {
+				store.Remove(command)
+			}
+			if err := store.Save(); err != nil {
+				return err
+			}
+			if err := infof("Forgot the notes, tags and saved status of %d commands.\n", len(annotated)); err != nil {
+				return err
+			}
+		}
+		clearDaemonCache()
+		return remindShellHistory(shell, remaining)
+	},
+}
+
+// forgetOptions holds the parsed flags of the forget command.
+type forgetOptions struct {
+	regex        bool
+	shellHistory bool
+	noBackup     bool
+	dryRun       bool
+	yes          bool
+}
+
+// parseForgetFlags extracts the flags specific to the forget command.
+func parseForgetFlags(cmd *cobra.Command) (forgetOptions, error) {
+	var opts forgetOptions
+	for name, target := range map[string]*bool{
+		"regex":         &opts.regex,
+		"shell-history": &opts.shellHistory,
+		"no-backup":     &opts.noBackup,
+		"dry-run":       &opts.dryRun,
+		"yes":           &opts.yes,
+	} {
+		value, err := cmd.Flags().GetBool(name)
+		if err != nil {
+			return opts, fmt.Errorf("internal error getting %s flag: %w", name, err)
+		}
+		*target = value
+	}
+	if opts.noBackup && !opts.shellHistory {
+		return opts, errors.New(i18n.T("--no-backup only applies with --shell-history"))
+	}
+	return opts, nil
+}
+
+// forgetMatcher returns the function reporting whether a command line is to be
+// forgotten: the Nth most recent command for a number N, the lines matching ref
+// with regex, and those containing ref otherwise.
+func forgetMatcher(ref string, regex bool) (func(command string) bool, error) {
+	if strings.TrimSpace(ref) == "" {
+		return nil, errors.New(i18n.T("pattern cannot be empty"))
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(ref)); err == nil && !regex {
+		command, err := resolveEntry(ref)
+		if err != nil {
+			return nil, err
+		}
+		return func(c string) bool { return strings.TrimSpace(c) == command }, nil
+	}
+	if regex {
+		re, err := regexp.Compile(ref)
+		if err != nil {
+			return nil, errors.New(i18n.T("invalid pattern %q: %v", ref, err))
+		}
+		return re.MatchString, nil
+	}
+	return func(c string) bool { return strings.Contains(c, ref) }, nil
+}
+
+// forgetTarget is a history file forget rewrites, with the commands it will remove.
+type forgetTarget struct {
+	rewriter history.Rewriter
+
+	// shell reports that the file is the shell's own, whose original is kept.
+	shell   bool
+	matches []string
+}
+
+// forgetTargets returns the history files to rewrite: historai's log and its
+// archive, if there are any, and the shell's history file with shellHistory. Otherwise shell is that file,
+// to check whether it still holds matching commands; it is nil when the shell's
+// history can't be rewritten.
+func forgetTargets(shellHistory bool) (targets []forgetTarget, shell history.Rewriter, err error) {
+	recordFile := forgetRecordFile()
+	if record, err := history.NewRecordedHistoryReader(zap.NewNop(), recordFile); err == nil {
+		targets = append(targets, forgetTarget{rewriter: record})
+	}
+	if archive, err := history.OpenArchive(zap.NewNop(), recordFile); err == nil {
+		if segments, err := archive.Segments(); err == nil && len(segments) > 0 {
+			targets = append(targets, forgetTarget{rewriter: archive})
+		}
+	}
+
+	// With the shell hook as the source, the shell's history is Zsh's own file.
+	var reader history.HistoryReader
+	source := appConfig.History.Source
+	if source == history.SourceHistorai {
+		source = history.SourceZsh
+		reader, err = history.NewReader(zap.NewNop(), source, "")
+	} else {
+		reader, err = newHistoryReader()
+	}
+	rewriter, ok := reader.(history.Rewriter)
+	switch {
+	case err != nil && shellHistory:
+		return nil, nil, err
+	case err != nil:
+		logger.Debug("No shell history to check", zap.Error(err))
+		return targets, nil, nil
+	case !ok && shellHistory:
+		return nil, nil, errors.New(i18n.T("history source %q can't be rewritten", source))
+	case !ok:
+		return targets, nil, nil
+	case shellHistory:
+		return append(targets, forgetTarget{rewriter: rewriter, shell: true}), nil, nil
+	default:
+		return targets, rewriter, nil
+	}
+}
+
+// forgetRecordFile returns the configured log when historai reads its own, and ""
+// for the shell hook's default log otherwise.
+func forgetRecordFile() string {
+	if appConfig.History.Source == history.SourceHistorai {
+		return appConfig.History.File
+	}
+	return ""
+}
+
+// planForget returns the commands of rewriter's file that match, without changing it.
+func planForget(rewriter history.Rewriter, match func(command string) bool) ([]string, error) {
+	var matches []string
+	_, err := rewriter.Rewrite(func(entry history.HistoryEntry) (history.HistoryEntry, bool) {
+		if match(entry.Command) {
+			matches = append(matches, entry.Command)
+		}
+		return entry, true
+	})
+	return matches, err
+}
+
+// forgetIn removes the matching commands from target's file. Only the shell's
+// original file is kept, unless noBackup is set. Those removed from historai's log
+// and archive are added to forgotten, so that 'historai sync' doesn't restore them.
+func forgetIn(target forgetTarget, match func(command string) bool, noBackup bool, forgotten *history.Forgotten) error {
+	result, err := target.rewriter.Rewrite(func(entry history.HistoryEntry) (history.HistoryEntry, bool) {
+		if !match(entry.Command) {
+			return entry, true
+		}
+		if !target.shell {
+			forgotten.Add([]history.HistoryEntry{entry})
+		}
+		return entry, false
+	})
+	if err != nil {
+		return err
+	}
+	if target.shell && !noBackup {
+		return infof("Forgot %d entries from %s. The original file was saved to %s; delete it once you are sure, as it still holds them.\n",
+			result.Removed, target.rewriter.HistoryFile(), result.Backup)
+	}
+	if result.Backup != "" {
+		if err := os.Remove(result.Backup); err != nil {
+			return fmt.Errorf("failed to remove backup %s: %w", result.Backup, err)
+		}
+	}
+	return infof("Forgot %d entries from %s.\n", result.Removed, target.rewriter.HistoryFile())
+}
+
+// remindShellHistory tells that the shell's history file still holds the remaining
+// matching commands, if any.
+func remindShellHistory(shell history.Rewriter, remaining []string) error {
+	if shell == nil || len(remaining) == 0 {
+		return nil
+	}
+	return infof("%s still holds %d matching entries; run again with --shell-history to forget them too.\n", shell.HistoryFile(), len(remaining))
+}
+
+// clearDaemonCache makes a running daemon drop its parsed history, so it stops
+// answering with forgotten commands. Without a daemon there is nothing to clear.
+func clearDaemonCache() {
+	if _, err := daemonCache(daemon.OpCacheClear); err != nil {
+		logger.Debug("Daemon cache not cleared", zap.Error(err))
+		return
+	}
+	_ = infof("Cleared the daemon's cached history.\n")
+}
+
+// init adds the forgetCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(forgetCmd)
+
+	forgetCmd.Flags().BoolP("regex", "E", false, "Treat the pattern as a regular expression")
+	forgetCmd.Flags().Bool("shell-history", false, "Also rewrite your shell's history file")
+	forgetCmd.Flags().Bool("no-backup", false, "Don't keep a copy of the shell's original history file")
+	forgetCmd.Flags().Bool("dry-run", false, "List what would be forgotten without changing anything")
+	forgetCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/grpcapi"
+)
+
+const defaultGRPCAddr = "127.0.0.1:8766"
+
+// grpcCmd represents the grpc command
+var grpcCmd = &cobra.Command{
+	Use:   "grpc",
+	Short: "Serve the versioned historai.v1 gRPC API for editor/plugin integrations",
+	Long: `Starts a gRPC server implementing the historai.v1.Historai service
+(see api/historai/v1/historai.proto), including server-streaming variants
+of find and suggest, which send the answer as the model generates it. Server reflection is enabled, so tools like grpcurl
+can discover the API.
+
+If --token (or the HISTORAI_SERVE_TOKEN environment variable) is set, every
+call must carry an "authorization: Bearer <token>" metadata entry. Without one,
+--addr must be a loopback address, and only calls addressed to localhost or a
+loopback address are served, as with 'historai serve'.
+
+Example:
+  historai grpc
+  grpcurl -plaintext -d '{"query":"list docker images"}' 127.0.0.1:8766 historai.v1.Historai/Find`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, token, err := parseServeFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		eng, err := newEngine(ctx, engine.Options{CacheHistory: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		return grpcapi.New(logger, eng, token).ListenAndServe(ctx, addr)
+	},
+}
+
+// init adds the grpcCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(grpcCmd)
+
+	grpcCmd.Flags().String("addr", defaultGRPCAddr, "Address to listen on")
+	grpcCmd.Flags().String("token", "", "Bearer token required on every call (env: "+envServeToken+")")
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const (
+	highlightLexer     = "bash"
+	highlightFormatter = "terminal256"
+	highlightStyle     = "monokai"
+)
+
+// highlightShell renders commands with shell syntax highlighting for the terminal.
+// Markdown code fences around the commands are dropped.
+func highlightShell(commands string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(commands, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			lines = append(lines, line)
+		}
+	}
+
+	lexer := lexers.Get(highlightLexer)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, strings.Join(lines, "\n"))
+	if err != nil {
+		return "", err
+	}
+
+	var highlighted strings.Builder
+	if err := formatters.Get(highlightFormatter).Format(&highlighted, styles.Get(highlightStyle), iterator); err != nil {
+		return "", err
+	}
+	return highlighted.String(), nil
+}
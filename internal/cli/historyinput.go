@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// historyInputSource is the history source reported in structured output for
+// commands given with --history.
+const historyInputSource = "input"
+
+// historyFlagUsage is the usage of the --history flag of find and suggest.
+const historyFlagUsage = "Use the commands in this file (\"-\" for stdin) instead of your shell history"
+
+// readHistoryInput reads the history given with --history, from a file or stdin for
+// "-", in any format 'historai import' understands. It returns nil when the flag
+// isn't set.
+func readHistoryInput(cmd *cobra.Command) ([]history.HistoryEntry, error) {
+	path, err := cmd.Flags().GetString("history")
+	if err != nil {
+		return nil, fmt.Errorf("internal error getting history flag: %w", err)
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	var input io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open history file: %w", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		input, name = file, path
+	}
+
+	entries, err := history.Parse(logger, input, history.FormatAuto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New(i18n.T("no commands in %s", name))
+	}
+	appConfig.History.Source = historyInputSource
+	logger.Debug("Read history from --history", zap.String("from", name), zap.Int("entries_count", len(entries)))
+	return entries, nil
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/redact"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file|->",
+	Short: "Import shell history into historai's own history log",
+	Long: `Reads history from another shell, machine or tool and appends it to the log
+historai's shell hook records to. Entries already in the log, compared by
+host, timestamp and command, are skipped, so importing the same file twice is safe.
+
+Label the history of another machine with --host to merge several machines into
+one searchable history, so 'find' can answer "the command I ran on the build
+server". Atuin exports carry their own host names.
+
+Formats:
+  auto     : Detect the format from the content (default).
+  bash     : ~/.bash_history, with "#<epoch>" timestamp lines when HISTTIMEFORMAT is set.
+  zsh      : Zsh extended history.
+  atuin    : One JSON object per line, as written by 'historai export -f atuin'.
+  json     : A JSON array of entries, as written by 'historai export'.
+  jsonl    : One JSON entry per line, e.g. another machine's historai log.
+  terminal : A terminal recording, e.g. an iTerm2 session log or script(1) output,
+             from a shell with iTerm2's or WezTerm's shell integration. Commands
+             are read with their working directory, exit status and output.
+  plain    : One command per line.
+
+Flags:
+  --format / -f : Input format (default: auto).
+  --file        : The log to import into (default: the shell hook's log).
+  --host        : Label entries without a host with this machine name.
+
+Example:
+  historai import ~/.bash_history
+  historai import -f zsh ~/.zsh_history
+  historai import ~/Documents/iTerm2-logs/session.log
+  ssh buildbox cat .bash_history | historai import --host buildbox -`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		flags := cmd.Flags()
+		format, err := flags.GetString("format")
+		if err != nil {
+			return fmt.Errorf("internal error getting format flag: %w", err)
+		}
+		if !isImportFormat(format) {
+			return fmt.Errorf("invalid --format %q (expected one of %s)", format, strings.Join(history.ImportFormats, ", "))
+		}
+		target, err := flags.GetString("file")
+		if err != nil {
+			return fmt.Errorf("internal error getting file flag: %w", err)
+		}
+		host, err := flags.GetString("host")
+		if err != nil {
+			return fmt.Errorf("internal error getting host flag: %w", err)
+		}
+		if target == "" {
+			target, err = defaultImportTarget()
+			if err != nil {
+				return err
+			}
+		}
+
+		// 2. Parse the source
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", args[0], err)
+			}
+			defer f.Close()
+			in = f
+		}
+		incoming, err := history.Parse(logger, in, format)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", args[0], err)
+		}
+		labelHosts(incoming, host)
+		redactOutputs(incoming)
+
+		// 3. Merge with the existing log
+		existing, err := readRecordedLog(target)
+		if err != nil {
+			return err
+		}
+		added := history.Merge(existing, incoming)
+		logger.Debug("Merged imported history",
+			zap.String("target", target),
+			zap.Int("incoming_count", len(incoming)),
+			zap.Int("existing_count", len(existing)),
+			zap.Int("added_count", len(added)))
+
+		// 4. Append the new entries
+		if len(added) > 0 {
+			if err := history.AppendRecords(target, added); err != nil {
+				return err
+			}
+		}
+		if err := infof("Imported %d new entries (%d duplicates skipped) into %s\n", len(added), len(incoming)-len(added), target); err != nil {
+			return err
+		}
+		if appConfig.History.Source != history.SourceHistorai && len(added) > 0 {
+			return infof("Set history.source to %q in your config file to use them.\n", history.SourceHistorai)
+		}
+		return nil
+	},
+}
+
+// labelHosts sets host on entries without one, and clears the label of entries from
+// this machine, which the shell hook records without a host.
+func labelHosts(entries []history.HistoryEntry, host string) {
+	localHost, _ := os.Hostname()
+	for i := range entries {
+		if entries[i].Host == "" {
+			entries[i].Host = host
+		}
+		if entries[i].Host == localHost {
+			entries[i].Host = ""
+		}
+	}
+}
+
+// redactOutputs redacts the output of entries, as the shell hook does: it often
+// holds secrets (cat .env).
+func redactOutputs(entries []history.HistoryEntry) {
+	redactor := redact.New()
+	for i := range entries {
+		entries[i].Output = redactor.Redact(entries[i].Output)
+	}
+}
+
+// readRecordedLog reads all entries of the log at path; a missing log has none.
+func readRecordedLog(path string) ([]history.HistoryEntry, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	reader, err := history.NewRecordedHistoryReader(logger, path)
+	if err != nil {
+		return nil, err
+	}
+	return reader.ReadHistory(0)
+}
+
+// defaultImportTarget returns the configured history file when historai reads its own
+// log, and the shell hook's default log otherwise.
+func defaultImportTarget() (string, error) {
+	if appConfig.History.Source == history.SourceHistorai && appConfig.History.File != "" {
+		return appConfig.History.File, nil
+	}
+	return history.DefaultRecordFile()
+}
+
+// isImportFormat reports whether format is one of history.ImportFormats.
+func isImportFormat(format string) bool {
+	for _, known := range history.ImportFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// init adds the importCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringP("format", "f", history.FormatAuto, "Input format: "+strings.Join(history.ImportFormats, ", "))
+	importCmd.Flags().String("file", "", "The log to import into (default: the shell hook's log)")
+	importCmd.Flags().String("host", "", "Label entries without a host with this machine name")
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// stderrTailLines is how many lines of a failed command's stderr the hook keeps.
+const stderrTailLines = 40
+
+// fullScreenPrograms are left writing to the terminal by --capture-output, as they
+// don't work, or lose their pager, when their output is a pipe.
+var fullScreenPrograms = []string{
+	"vi", "vim", "nvim", "nano", "emacs", "less", "more", "man", "top", "htop", "btop",
+	"watch", "fzf", "ssh", "mosh", "tmux", "screen", "tig", "lazygit", "k9s",
+}
+
+// Supported shells for the init command.
+const (
+	shellZsh  = "zsh"
+	shellBash = "bash"
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init <zsh|bash>",
+	Short: "Print the shell hook that records commands with their exit status",
+	Long: `Prints a shell hook that records every command you run, together with its
+exit status, working directory and duration, to historai's own history log
+(~/.local/share/historai/history.jsonl). This metadata lets commands like
+'historai fix' find the last command that failed.
+
+Commands starting with a space are not recorded. To read this log instead of
+your shell's history file everywhere, set history.source to "historai".
+
+With --capture-stderr (zsh only), the last lines of stderr of failed commands are
+recorded too, so 'historai why' and 'historai fix' can use the real error message.
+This routes the shell's stderr through a pipe, so some programs stop coloring
+their error output.
+
+With --capture-output N (zsh only), the last N lines of every command's output are
+recorded too (at most 4 KiB, with secrets redacted), so 'historai find' and
+'historai ask' can answer questions like "the command that printed that checksum".
+Commands then write to a pipe instead of the terminal, so ls prints one name per
+line and programs stop coloring or paging their output. Full-screen programs such
+as vim, less or ssh are left on the terminal and not captured.
+
+Flags:
+  --file             : Record to this file instead of the default location.
+  --capture-stderr   : Also record the stderr tail of failed commands (zsh only).
+  --capture-output N : Also record the last N lines of every command's output (zsh only).
+
+Example:
+  # ~/.zshrc
+  eval "$(historai init zsh)"
+  eval "$(historai init zsh --capture-stderr)"
+  eval "$(historai init zsh --capture-output 20)"
+
+  # ~/.bashrc (bash 4.4+)
+  eval "$(historai init bash)"`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{shellZsh, shellBash},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return fmt.Errorf("internal error getting file flag: %w", err)
+		}
+		captureStderr, err := cmd.Flags().GetBool("capture-stderr")
+		if err != nil {
+			return fmt.Errorf("internal error getting capture-stderr flag: %w", err)
+		}
+		outputLines, err := cmd.Flags().GetInt("capture-output")
+		if err != nil {
+			return fmt.Errorf("internal error getting capture-output flag: %w", err)
+		}
+		if outputLines < 0 {
+			return errors.New("--capture-output must not be negative")
+		}
+
+		// 1. Resolve the binary, so the hook works even if historai isn't on PATH
+		executable, err := os.Executable()
+		if err != nil {
+			executable = "historai"
+		}
+		record := shellQuote(executable) + " record"
+		if file != "" {
+			record += " --file " + shellQuote(file)
+		}
+
+		// 2. Print the hook for the requested shell
+		var script string
+		switch args[0] {
+		case shellZsh:
+			script = zshHook
+			if captureStderr {
+				script += zshStderrCapture
+			}
+			if outputLines > 0 {
+				script += zshOutputCapture
+			}
+		case shellBash:
+			// Bash prints its prompt and line editing through stderr, which can't be teed safely.
+			if captureStderr {
+				return errors.New("--capture-stderr is only supported for zsh")
+			}
+			if outputLines > 0 {
+				return errors.New("--capture-output is only supported for zsh")
+			}
+			script = bashHook
+		default:
+			return fmt.Errorf("unsupported shell %q (supported: %s, %s)", args[0], shellZsh, shellBash)
+		}
+		script = strings.NewReplacer(
+			"__HISTORAI_RECORD__", record,
+			"__HISTORAI_STDERR_LINES__", strconv.Itoa(stderrTailLines),
+			"__HISTORAI_OUTPUT_LINES__", strconv.Itoa(outputLines),
+			"__HISTORAI_FULL_SCREEN__", strings.Join(fullScreenPrograms, "|"),
+		).Replace(script)
+		_, err = fmt.Print(script)
+		return err
+	},
+}
+
+// shellQuote quotes s for safe use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+const zshHook = `# historai shell integration (zsh)
+zmodload zsh/datetime 2>/dev/null
+autoload -Uz add-zsh-hook
+
+_historai_preexec() {
+  _historai_cmd=$1
+  _historai_cwd=$PWD
+  _historai_start=$EPOCHREALTIME
+  [[ -n $_historai_errfile ]] && : >| "$_historai_errfile"
+  if [[ -n $_historai_outfile ]]; then
+    : >| "$_historai_outfile"
+    [[ ${${(z)1}[1]:t} != (__HISTORAI_FULL_SCREEN__) ]] && exec 1>&$_historai_outfd
+  fi
+}
+
+_historai_precmd() {
+  local exit_code=$?
+  [[ -z $_historai_cmd ]] && return
+  local -i duration_ms=$(( (EPOCHREALTIME - _historai_start) * 1000 ))
+  local -a stderr_args stdout_args
+  if [[ -n $_historai_errfile && $exit_code -ne 0 ]]; then
+    stderr_args=(--stderr "$(tail -n __HISTORAI_STDERR_LINES__ "$_historai_errfile" 2>/dev/null)")
+  fi
+  if [[ -n $_historai_outfile ]]; then
+    exec 1>&$_historai_ttyfd
+    stdout_args=(--stdout "$(tail -n __HISTORAI_OUTPUT_LINES__ "$_historai_outfile" 2>/dev/null)")
+  fi
+  ( __HISTORAI_RECORD__ --exit-code $exit_code --duration-ms $duration_ms --cwd "$_historai_cwd" "${stderr_args[@]}" "${stdout_args[@]}" -- "$_historai_cmd" >/dev/null 2>&1 & )
+  unset _historai_cmd
+}
+
+add-zsh-hook preexec _historai_preexec
+add-zsh-hook precmd _historai_precmd
+`
+
+// zshStderrCapture tees the shell's stderr into a per-shell file, which the hook
+// truncates before each command and reads after a failed one.
+const zshStderrCapture = `
+# Capture stderr of failed commands for 'historai why'.
+_historai_errfile="${TMPDIR:-/tmp}/historai-stderr.$$"
+( umask 077; : >| "$_historai_errfile" )
+exec 2> >(tee -a "$_historai_errfile" >&2)
+_historai_cleanup() { rm -f "$_historai_errfile"; }
+add-zsh-hook zshexit _historai_cleanup
+`
+
+// zshOutputCapture opens a pipe teeing into a per-shell file next to the terminal.
+// The hook points stdout at the pipe before each command, except full-screen
+// programs, truncating the file, and back at the terminal before the prompt.
+const zshOutputCapture = `
+# Capture the output of commands for 'historai find' and 'historai ask'.
+_historai_outfile="${TMPDIR:-/tmp}/historai-output.$$"
+( umask 077; : >| "$_historai_outfile" )
+exec {_historai_ttyfd}>&1
+exec {_historai_outfd}> >(tee -a "$_historai_outfile")
+_historai_output_cleanup() { rm -f "$_historai_outfile"; }
+add-zsh-hook zshexit _historai_output_cleanup
+`
+
+// bashHook uses a DEBUG trap as preexec. _historai_ready is set as the last step of
+// PROMPT_COMMAND, so only the first command typed at the prompt is captured, not
+// the prompt commands themselves or later parts of a pipeline.
+const bashHook = `# historai shell integration (bash)
+_historai_preexec() {
+  [[ $_historai_ready == 1 && -z $COMP_LINE && $BASH_COMMAND != _historai_precmd* ]] || return
+  _historai_ready=0
+  local num cmd
+  IFS=' ' read -r -d '' num cmd < <(HISTTIMEFORMAT= builtin history 1)
+  # Commands kept out of history (e.g. HISTCONTROL=ignorespace) leave the number unchanged.
+  [[ $num == "$_historai_num" ]] && return
+  _historai_num=$num
+  _historai_cmd=$cmd
+  _historai_cwd=$PWD
+  _historai_start=${EPOCHREALTIME/[.,]/}
+}
+
+_historai_precmd() {
+  local exit_code=$?
+  if [[ -n $_historai_cmd ]]; then
+    local duration_ms=0
+    [[ -n $_historai_start && -n $EPOCHREALTIME ]] && duration_ms=$(( (${EPOCHREALTIME/[.,]/} - _historai_start) / 1000 ))
+    ( __HISTORAI_RECORD__ --exit-code $exit_code --duration-ms $duration_ms --cwd "$_historai_cwd" -- "$_historai_cmd" >/dev/null 2>&1 & )
+  fi
+  _historai_cmd=
+  return $exit_code
+}
+
+trap '_historai_preexec' DEBUG
+PROMPT_COMMAND="_historai_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND};_historai_ready=1"
+`
+
+// init adds the initCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().String("file", "", "Record to this file instead of the default location")
+	initCmd.Flags().Bool("capture-stderr", false, "Also record the stderr tail of failed commands (zsh only)")
+	initCmd.Flags().Int("capture-output", 0, "Also record the last `N` lines of every command's output (zsh only)")
+}
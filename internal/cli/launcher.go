@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// Values of the --output flag for launchers. Both print the items of a find,
+// suggest, fix or next answer, or of a prose answer, in the JSON the launcher reads
+// from a script: Alfred's script filter format, and its title, subtitle and arg
+// subset for Raycast extensions. Other commands print JSON.
+const (
+	outputRaycast = "raycast"
+	outputAlfred  = "alfred"
+)
+
+// launcherResult is the document read by the launcher.
+type launcherResult struct {
+	Items []launcherItem `json:"items"`
+}
+
+// launcherItem is a row of the launcher. Arg is what selecting it hands over,
+// e.g. to paste or run; items without one only say something.
+type launcherItem struct {
+	UID      string      `json:"uid,omitempty"`
+	Title    string      `json:"title"`
+	Subtitle string      `json:"subtitle,omitempty"`
+	Arg      string      `json:"arg,omitempty"`
+	Valid    *bool       `json:"valid,omitempty"`
+	Text     *alfredText `json:"text,omitempty"`
+}
+
+// alfredText is the text Alfred copies with ⌘C and shows with ⌘L.
+type alfredText struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
+}
+
+// isLauncherOutput reports whether --output selects a launcher format.
+func isLauncherOutput() bool {
+	return outputFormat == outputRaycast || outputFormat == outputAlfred
+}
+
+// commandLauncherItems returns the items of the commands of answer, best first, or
+// a single item with its message when it has none.
+func commandLauncherItems(answer llm.CommandAnswer) []launcherItem {
+	if len(answer.Commands) == 0 {
+		return []launcherItem{messageLauncherItem(answer.Message)}
+	}
+	items := make([]launcherItem, len(answer.Commands))
+	for i, command := range answer.Commands {
+		items[i] = newLauncherItem(command.Command, commandSubtitle(command), command.Command)
+	}
+	return items
+}
+
+// commandSubtitle describes command under its title: its explanation, or where it
+// comes from, after a warning for risky commands.
+func commandSubtitle(command llm.CommandResult) string {
+	subtitle := command.Explanation
+	if subtitle == "" {
+		switch command.Source {
+		case llm.SourceHistory:
+			subtitle = i18n.T("From your history")
+		case llm.SourceModel:
+			subtitle = i18n.T("Written by the AI")
+		case llm.SourceTeam:
+			subtitle = i18n.T("From your team's snippets")
+		}
+	}
+	if command.Risk == llm.RiskHigh {
+		subtitle = strings.TrimSpace(i18n.T("⚠ High risk") + " " + subtitle)
+	}
+	return subtitle
+}
+
+// explanationLauncherItems returns the item of a prose answer about query: its
+// first line under the query, handing over the whole answer.
+func explanationLauncherItems(query, explanation string) []launcherItem {
+	subtitle, _, _ := strings.Cut(explanation, "\n")
+	return []launcherItem{newLauncherItem(query, subtitle, explanation)}
+}
+
+// newLauncherItem returns an item handing over arg.
+func newLauncherItem(title, subtitle, arg string) launcherItem {
+	item := launcherItem{Title: title, Subtitle: subtitle, Arg: arg}
+	if outputFormat == outputAlfred {
+		valid := true
+		// Alfred learns from the uid which items are picked for which query.
+		item.UID, item.Valid = title, &valid
+		item.Text = &alfredText{Copy: arg, LargeType: arg}
+	}
+	return item
+}
+
+// messageLauncherItem returns an item saying message, e.g. that there are no
+// results, which can't be selected.
+func messageLauncherItem(message string) launcherItem {
+	item := launcherItem{Title: i18n.T(message)}
+	if outputFormat == outputAlfred {
+		valid := false
+		item.Valid = &valid
+	}
+	return item
+}
+
+// printLauncherItems writes items to stdout in the launcher format.
+func printLauncherItems(items []launcherItem) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(launcherResult{Items: items}); err != nil {
+		return fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+	return nil
+}
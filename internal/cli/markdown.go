@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	defaultWrapWidth = 80
+
+	// defaultMarkdownStyle is used unless $GLAMOUR_STYLE names another style or a
+	// style file. Auto-detection is avoided because it queries the terminal.
+	defaultMarkdownStyle = "dark"
+)
+
+// looksLikeMarkdown reports whether an LLM answer contains markdown structure,
+// as opposed to bare commands and shell comments.
+func looksLikeMarkdown(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || llm.IsMarkdownProse(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderMarkdown renders markdown for the terminal, wrapped to its width.
+func renderMarkdown(text string) (string, error) {
+	width := defaultWrapWidth
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		width = w
+	}
+	style := os.Getenv("GLAMOUR_STYLE")
+	if style == "" {
+		style = defaultMarkdownStyle
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStylePath(style),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return "", err
+	}
+	return renderer.Render(text)
+}
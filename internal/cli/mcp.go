@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/mcp"
+	"github.com/sanspareilsmyn/historai/internal/version"
+)
+
+// mcpCmd represents the mcp command
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server over stdio",
+	Long: `Exposes history search and command suggestion as Model Context Protocol (MCP)
+tools over stdin/stdout, so AI agents and IDE assistants can query your shell history.
+
+Tools:
+  find_commands    : Search shell history with a natural language description.
+  suggest_commands : Suggest commands for a task.
+  recent_history   : Return the most recent commands.
+
+Secrets are redacted from history before it is returned or sent to the LLM.
+
+Example MCP client configuration:
+  {"command": "historai", "args": ["mcp"]}`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		eng, err := newEngine(ctx, engine.Options{CacheHistory: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		return mcp.NewServer(logger, eng, appConfig, version.Get().Version).Serve(ctx, os.Stdin, os.Stdout)
+	},
+}
+
+// init adds the mcpCmd to the rootCmd.
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
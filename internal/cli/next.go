@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	defaultNextRecent       = 5
+	defaultNextHistoryLimit = config.DefaultFindLimit
+)
+
+// nextCmd represents the next command
+var nextCmd = &cobra.Command{
+	Use:   "next",
+	Short: "Predict the next command in your current workflow using AI",
+	Long: `Asks an LLM for the command you are most likely to run next, based on
+the last few commands you ran (e.g. 'docker build' then 'docker tag' suggests
+'docker push'). Earlier shell history is used to learn your usual sequences.
+
+It is quick enough to back a shell widget when the historai daemon is running;
+combine it with --first to get a single bare command.
+
+Flags:
+  --recent / -r : How many of the latest commands to continue from (default: 5).
+  --limit / -n  : How many recent history entries to learn workflows from (default: 300, or find.limit from the config file).
+
+Example:
+  historai next
+  historai next --recent 2
+  historai next --first -q`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (recent, limit)
+		recent, limit, err := parseNextFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Ask the LLM for the next command
+		started := time.Now()
+		spin := startSpinner()
+		next, err := runNext(logger, recent, limit)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 3. Print Header to Stderr & Result to Stdout
+		return printResult(logger, commandOutput{
+			kind:         "next",
+			query:        "",
+			answer:       next,
+			header:       i18n.T("--- Likely Next Commands ---"),
+			logOnFailure: "No next command predicted or response indicates failure.",
+			started:      started,
+		})
+	},
+}
+
+// parseNextFlags extracts and validates flags specific to the next command.
+func parseNextFlags(cmd *cobra.Command) (recent int, limit int, err error) {
+	recent, err = cmd.Flags().GetInt("recent")
+	if err != nil {
+		logger.Error("Failed to get 'recent' flag value", zap.Error(err))
+		err = fmt.Errorf("internal error getting recent flag: %w", err)
+		return
+	}
+	if recent < 1 {
+		err = errors.New("--recent must be at least 1")
+		return
+	}
+
+	limit, err = cmd.Flags().GetInt("limit")
+	if err != nil {
+		logger.Error("Failed to get 'limit' flag value", zap.Error(err))
+		err = fmt.Errorf("internal error getting limit flag: %w", err)
+		return
+	}
+	if !cmd.Flags().Changed("limit") {
+		limit = appConfig.Find.Limit
+	}
+	return recent, limit, nil
+}
+
+// runNext predicts the next command, preferring a running daemon over a cold start.
+func runNext(logger *zap.Logger, recent int, limit int) (llm.CommandAnswer, error) {
+	// 1. Try the daemon, which keeps parsed history and a warm LLM client
+	req := daemon.Request{Op: daemon.OpNext, Recent: recent, Limit: limit}
+	if next, ok, err := tryDaemon(logger, req); ok {
+		return next, err
+	}
+
+	// 2. Initialize Engine (config, history, LLM client)
+	eng, err := newEngine(context.Background(), engine.Options{})
+	if err != nil {
+		return llm.CommandAnswer{}, err
+	}
+	defer func() {
+		if closeErr := eng.Close(); closeErr != nil {
+			logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+	}()
+
+	// 3. Call LLM API to predict the next command
+	return eng.Next(context.Background(), recent, limit)
+}
+
+// init adds the nextCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(nextCmd)
+
+	nextCmd.Flags().IntP("recent", "r", defaultNextRecent, "Number of latest commands to continue from")
+	nextCmd.Flags().IntP("limit", "n", defaultNextHistoryLimit, "Limit the number of most recent history entries to learn workflows from")
+}
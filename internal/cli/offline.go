@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// offlineProvider is the provider reported in structured output for answers from
+// the local search, which involves no LLM.
+const offlineProvider = "offline"
+
+// searchOffline searches history for query locally, without an LLM, after cause
+// showed that the provider can't be used, e.g. without network or API key. It tells
+// the user that the results are keyword matches rather than the model's answer.
+// Non-nil entries are searched instead of the configured history.
+func searchOffline(logger *zap.Logger, cause error, query, tag string, limit int, entries []history.HistoryEntry) (llm.CommandAnswer, error) {
+	logger.Debug("Provider unavailable, searching history locally", zap.Error(cause))
+	eng, err := newEngine(context.Background(), engine.Options{Offline: true, History: entries})
+	if err != nil {
+		return llm.CommandAnswer{}, err
+	}
+	defer func() {
+		_ = eng.Close()
+	}()
+
+	if err := infof("The AI provider is unavailable (%s).\nShowing keyword matches from your history instead; these are not AI results.\n", unavailableReason(cause)); err != nil {
+		return llm.CommandAnswer{}, err
+	}
+	return eng.FindLocal(query, tag, limit)
+}
+
+// unavailableReason describes why the provider couldn't be used, briefly: the
+// errors themselves can be long, and may contain request URLs with the API key.
+func unavailableReason(err error) string {
+	var providerErr *llm.ProviderError
+	var apiErr *googleapi.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, config.ErrMissingAPIKey):
+		return i18n.T("no API key is configured")
+	case errors.Is(err, config.ErrPolicy):
+		return i18n.T("the organization policy doesn't allow it")
+	case errors.As(err, &providerErr):
+		return providerErr.Reason
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return i18n.T("the request timed out")
+	case errors.As(err, &apiErr):
+		return i18n.T("the provider answered with HTTP %d", apiErr.Code)
+	case errors.As(err, &netErr):
+		return i18n.T("network error")
+	default:
+		return i18n.T("unavailable")
+	}
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/corrector"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+const (
+	defaultOopsHistoryLimit = config.DefaultFindLimit
+)
+
+// oopsCmd represents the oops command
+var oopsCmd = &cobra.Command{
+	Use:   "oops [\"<command>\"]",
+	Short: "Correct the previous command and run it with one key",
+	Long: `Corrects the command you just ran and offers to run the fix: press Enter to
+run it, any other key to cancel.
+
+Common mistakes are corrected instantly by local rules: typos in program names
+and subcommands, a missing sudo, a missing -r or -p, and fixes the error output
+itself suggests (e.g. git's "did you mean" and --set-upstream hints). When no
+rule applies, an LLM is asked for the fix, like 'historai fix'.
+
+The previous command, its exit status and its error output come from the shell
+hook (see 'historai init'; use --capture-stderr for the error output). Without
+the hook, the last command of your shell history is used.
+
+The fix runs in a new shell, so a corrected cd doesn't move your shell; use
+eval "$(historai oops --raw)" to run it in the current one.
+
+Flags:
+  --yes / -y   : Run the fix without asking.
+  --local / -l : Only use the local rules, without asking the LLM.
+  --limit / -n : How many recent history entries to search for previous uses when asking the LLM (default: 300, or find.limit from the config file).
+
+Example:
+  historai oops
+  historai oops --local
+  historai oops "gti stauts"
+  eval "$(historai oops --raw)"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parseOopsFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Pick the previous command
+		entries, err := oopsHistory()
+		if err != nil {
+			return err
+		}
+		var previous history.HistoryEntry
+		if len(args) == 1 {
+			previous.Command = strings.TrimSpace(args[0])
+		} else {
+			previous = lastCommand(entries)
+		}
+		if previous.Command == "" {
+			return errors.New(i18n.T("command cannot be empty"))
+		}
+		logger.Debug("Correcting command", zap.String("command", previous.Command))
+
+		// 3. Correct it with the local rules, then with the LLM
+		correction, ok := corrector.New(entries, corrector.Executables(os.Getenv("PATH"))).Correct(previous)
+		if ok {
+			correction.Reason = i18n.T(correction.Reason)
+		} else if !opts.local {
+			if correction, ok, err = llmCorrection(previous, opts.limit); err != nil {
+				return err
+			}
+		}
+		if !ok || correction.Command == strings.TrimSpace(previous.Command) {
+			if err := infof("No correction found for: %s\n", previous.Command); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+
+		// 4. Print the correction and run it
+		if outputFormat != outputText {
+			return printOopsResult(previous.Command, correction)
+		}
+		if rawOutput || firstOnly {
+			_, err := fmt.Fprintln(os.Stdout, correction.Command)
+			return err
+		}
+		if err := printCorrection(previous.Command, correction); err != nil {
+			return err
+		}
+		if !opts.yes {
+			ok, err := confirmKey(i18n.T("[Enter] run, any other key to cancel"))
+			if err != nil {
+				// Without a terminal to ask on, hand the fix over instead of running it.
+				_, printErr := fmt.Fprintln(os.Stdout, correction.Command)
+				return printErr
+			}
+			if !ok {
+				return nil
+			}
+		}
+		return runInShell(correction.Command)
+	},
+}
+
+// oopsOptions holds the parsed flags of the oops command.
+type oopsOptions struct {
+	yes   bool
+	local bool
+	limit int
+}
+
+// parseOopsFlags extracts and validates flags specific to the oops command.
+func parseOopsFlags(cmd *cobra.Command) (oopsOptions, error) {
+	var opts oopsOptions
+	var err error
+	flags := cmd.Flags()
+
+	if opts.yes, err = flags.GetBool("yes"); err != nil {
+		return opts, fmt.Errorf("internal error getting yes flag: %w", err)
+	}
+	if opts.local, err = flags.GetBool("local"); err != nil {
+		return opts, fmt.Errorf("internal error getting local flag: %w", err)
+	}
+	if opts.limit, err = flags.GetInt("limit"); err != nil {
+		return opts, fmt.Errorf("internal error getting limit flag: %w", err)
+	}
+	if !flags.Changed("limit") {
+		opts.limit = appConfig.Find.Limit
+	}
+	return opts, nil
+}
+
+// oopsHistory returns the commands recorded by the shell hook, which know their exit
+// status and error output, or the configured history if the hook recorded nothing.
+func oopsHistory() ([]history.HistoryEntry, error) {
+	file := ""
+	if appConfig.History.Source == history.SourceHistorai {
+		file = appConfig.History.File
+	}
+	if recorded, err := history.NewRecordedHistoryReader(logger, file); err == nil {
+		if entries, err := recorded.ReadHistory(0); err == nil && len(entries) > 0 {
+			return entries, nil
+		}
+	}
+	reader, err := newHistoryReader()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := reader.ReadHistory(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// lastCommand returns the most recent entry that isn't a historai invocation.
+func lastCommand(entries []history.HistoryEntry) history.HistoryEntry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if history.ProgramName(entries[i].Command) != "historai" {
+			return entries[i]
+		}
+	}
+	return history.HistoryEntry{}
+}
+
+// llmCorrection asks the LLM for a fix of previous, as the fix command does, and
+// returns its first suggested command.
+func llmCorrection(previous history.HistoryEntry, limit int) (corrector.Correction, bool, error) {
+	eng, err := newEngine(context.Background(), engine.Options{})
+	if err != nil {
+		return corrector.Correction{}, false, err
+	}
+	defer func() {
+		if closeErr := eng.Close(); closeErr != nil {
+			logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+	}()
+
+	spin := startSpinner()
+	result, err := eng.Fix(context.Background(), previous, limit)
+	spin.stop()
+	if err != nil {
+		return corrector.Correction{}, false, err
+	}
+	commands := result.Commands
+	if len(commands) == 0 {
+		logger.Warn("No fix generated or response indicates failure.", zap.String("response", result.String()))
+		return corrector.Correction{}, false, nil
+	}
+	return corrector.Correction{Command: commands[0].Command, Reason: commands[0].Explanation}, true, nil
+}
+
+// printCorrection shows the correction on stderr as a word-level diff against the
+// previous command, with the reason for it.
+func printCorrection(previous string, correction corrector.Correction) error {
+	removed, added := diffWords(previous, correction.Command)
+	var b strings.Builder
+	b.WriteString(formatDiffLine("- ", removed, color.FgRed) + "\n")
+	b.WriteString(formatDiffLine("+ ", added, color.FgGreen) + "\n")
+	if correction.Reason != "" {
+		b.WriteString(color.New(color.Faint).Sprint("# "+correction.Reason) + "\n")
+	}
+	_, err := fmt.Fprint(os.Stderr, b.String())
+	return err
+}
+
+// oopsResult is the structured output of the oops command.
+type oopsResult struct {
+	Command   string `json:"command" yaml:"command"`
+	Corrected string `json:"corrected" yaml:"corrected"`
+	Reason    string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// printOopsResult prints the correction in the requested structured format.
+func printOopsResult(previous string, correction corrector.Correction) error {
+	result := oopsResult{Command: previous, Corrected: correction.Command, Reason: correction.Reason}
+	if outputFormat == outputJSONL {
+		return json.NewEncoder(os.Stdout).Encode(result)
+	}
+	return printStructuredResult(outputFormat, result)
+}
+
+// init adds the oopsCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(oopsCmd)
+
+	oopsCmd.Flags().BoolP("yes", "y", false, "Run the fix without asking")
+	oopsCmd.Flags().BoolP("local", "l", false, "Only use the local rules, without asking the LLM")
+	oopsCmd.Flags().IntP("limit", "n", defaultOopsHistoryLimit, "Limit the number of most recent history entries searched for previous uses")
+}
@@ -4,46 +4,219 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/timings"
 )
 
-var knownFailureMessages = map[string]struct{}{
-	"": {},
-	"(No relevant commands found or AI response was empty)":                    {},
-	"No relevant commands found.":                                              {},
-	"(AI could not suggest a command for this task or the response was empty)": {},
-	"Cannot suggest a command for this task.":                                  {},
-	"suggestion blocked due to safety settings":                                {},
+// escapesSupported reports whether the terminal processes ANSI escape sequences,
+// which the spinner needs too. Only consoles of Windows before 10 don't.
+var escapesSupported = true
+
+// configureColor disables colored output for --no-color, NO_COLOR (https://no-color.org),
+// dumb terminals and consoles without escape sequences, after turning them on in the
+// Windows console. fatih/color already checks the environment when the program
+// starts; this makes the decision explicit and adds the flag.
+func configureColor() {
+	escapesSupported = enableVirtualTerminal()
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !escapesSupported {
+		color.NoColor = true
+	}
+}
+
+// infof translates and writes a status message to stderr unless --quiet was given.
+// Stdout is reserved for results, so that it can always be piped.
+func infof(format string, args ...any) error {
+	if quiet {
+		return nil
+	}
+	_, err := fmt.Fprint(os.Stderr, i18n.T(format, args...))
+	return err
+}
+
+// commandOutput describes a find/suggest answer, or a prose answer, to be printed.
+type commandOutput struct {
+	kind  string
+	query string
+
+	// answer is the answer printed by printResult.
+	answer llm.CommandAnswer
+
+	// output is the prose answer printed by printExplanation, and noAnswer the
+	// message printed instead when it is empty.
+	output   string
+	noAnswer string
+
+	header       string
+	logOnFailure string
+	started      time.Time
+
+	// stream is set for --output jsonl and has already printed part of the output.
+	stream *commandStreamer
+
+	// offline reports that output comes from the local search, not the provider.
+	offline bool
+}
+
+// printResult prints a find/suggest answer in the format selected by --output.
+func printResult(logger *zap.Logger, out commandOutput) error {
+	defer invocationTimings.Start(timings.StageRender)()
+	if out.stream != nil {
+		count, err := out.stream.finish(out.answer)
+		if err == nil && count == 0 {
+			logger.Warn(out.logOnFailure, zap.String("response", out.answer.String()))
+			return errNoMatches
+		}
+		return err
+	}
+	if rawOutput || firstOnly {
+		return printRawCommands(logger, out)
+	}
+	if isLauncherOutput() {
+		if err := printLauncherItems(commandLauncherItems(out.answer)); err != nil {
+			return err
+		}
+		if len(out.answer.Commands) == 0 {
+			logger.Warn(out.logOnFailure, zap.String("response", out.answer.Message))
+			return errNoMatches
+		}
+		return nil
+	}
+	if outputFormat != outputText {
+		result := buildStructuredResult(out.kind, out.query, out.answer, out.started)
+		if out.offline {
+			result.Metadata.Provider, result.Metadata.Model = offlineProvider, ""
+		}
+		if err := printStructuredResult(outputFormat, result); err != nil {
+			return err
+		}
+		if result.Status != statusOK {
+			logger.Warn(out.logOnFailure, zap.String("response", result.Message))
+			return errNoMatches
+		}
+		return nil
+	}
+	return printCommandOutput(logger, out)
 }
 
-func printCommandOutput(logger *zap.Logger, output string, header string, logOnFailure string) (err error) {
-	// Trim whitespace just in case
-	trimmedOutput := strings.TrimSpace(output)
+// printExplanation prints a prose answer about out.query, e.g. from explain.
+// Unlike printResult it doesn't extract commands: the whole answer is the result.
+func printExplanation(logger *zap.Logger, out commandOutput) error {
+	defer invocationTimings.Start(timings.StageRender)()
+	trimmed := strings.TrimSpace(out.output)
+	if trimmed == "" {
+		if isLauncherOutput() {
+			if err := printLauncherItems([]launcherItem{messageLauncherItem(out.noAnswer)}); err != nil {
+				return err
+			}
+		}
+		return printNoResults(logger, out.logOnFailure, out.noAnswer)
+	}
 
-	// Check if the output is effectively empty or a known failure message
-	_, isKnownFailure := knownFailureMessages[trimmedOutput]
+	switch {
+	case isLauncherOutput():
+		return printLauncherItems(explanationLauncherItems(out.query, trimmed))
+	case outputFormat == outputJSONL:
+		return newCommandStreamer(out.kind, out.query).encodeItem(explanationItem(out.query, trimmed))
+	case outputFormat != outputText:
+		return printStructuredResult(outputFormat, buildExplanationResult(out, trimmed))
+	case rawOutput || firstOnly:
+		_, err := fmt.Fprintln(os.Stdout, trimmed)
+		return err
+	}
 
-	if !isKnownFailure {
-		infoColor := color.New(color.FgYellow)
-		_, err = infoColor.Fprintln(os.Stderr, "\n"+header)
-		if err != nil {
+	if !quiet && out.header != "" {
+		if _, err := color.New(color.FgYellow).Fprintln(os.Stderr, "\n"+out.header); err != nil {
+			return err
+		}
+	}
+	rendered := trimmed
+	if !color.NoColor {
+		markdown, err := renderMarkdown(trimmed)
+		if err == nil {
+			rendered = strings.TrimRight(markdown, "\n")
+		} else {
+			logger.Debug("Rendering failed, printing plain text", zap.Error(err))
+		}
+	}
+	_, err := fmt.Fprintln(os.Stdout, rendered)
+	return err
+}
+
+// printRawCommands prints the bare commands (or only the top one for --first), so
+// that stdout can be piped straight into another program.
+func printRawCommands(logger *zap.Logger, out commandOutput) error {
+	commands := out.answer.Commands
+	if len(commands) == 0 {
+		logger.Warn(out.logOnFailure, zap.String("response", out.answer.String()))
+		return errNoMatches
+	}
+	if firstOnly {
+		commands = commands[:1]
+	}
+	for _, item := range commands {
+		if _, err := fmt.Fprintln(os.Stdout, item.Command); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		resultColor := color.New(color.FgGreen)
-		_, err = resultColor.Println(trimmedOutput)
-		if err != nil {
+// printCommands prints the commands to stdout, syntax-highlighted when color is
+// enabled. Answers containing explanations or steps are rendered as markdown.
+func printCommands(logger *zap.Logger, commands string) error {
+	if !color.NoColor {
+		render := highlightShell
+		if looksLikeMarkdown(commands) {
+			render = renderMarkdown
+		}
+		highlighted, err := render(commands)
+		if err == nil {
+			_, err = fmt.Fprintln(os.Stdout, strings.TrimRight(highlighted, "\n"))
 			return err
 		}
+		logger.Debug("Rendering failed, falling back to plain color", zap.Error(err))
+	}
+	resultColor := color.New(color.FgGreen)
+	_, err := resultColor.Println(commands)
+	return err
+}
 
-	} else {
-		logger.Warn(logOnFailure, zap.String("response", trimmedOutput))
-		_, err = fmt.Fprintln(os.Stderr, trimmedOutput)
-		if err != nil {
+// printCommandOutput prints out.answer for people: the header to stderr, then the
+// answer as written, or the message saying why there are no commands.
+func printCommandOutput(logger *zap.Logger, out commandOutput) error {
+	if len(out.answer.Commands) == 0 {
+		return printNoResults(logger, out.logOnFailure, out.answer.String())
+	}
+	if !quiet {
+		if _, err := color.New(color.FgYellow).Fprintln(os.Stderr, "\n"+out.header); err != nil {
 			return err
 		}
 	}
+	if err := printCommands(logger, out.answer.Text); err != nil {
+		return err
+	}
+	for _, command := range out.answer.Commands {
+		if command.Source == llm.SourceTeam {
+			if err := infof("From your team's snippets: %s\n", command.Command); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+// printNoResults tells the user that there is no result, with message, and returns
+// errNoMatches.
+func printNoResults(logger *zap.Logger, logMessage, message string) error {
+	logger.Warn(logMessage, zap.String("response", message))
+	if err := infof("%s\n", i18n.T(message)); err != nil {
+		return err
+	}
+	return errNoMatches
+}
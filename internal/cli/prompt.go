@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// promptHook returns the hook printing prompts for --dry-run and --show-prompt, or nil
+// when neither was given. Prompts are shown after redaction and history limits, as
+// they would be sent. A dry run prints the first prompt to stdout and stops there;
+// --show-prompt prints every prompt to stderr and lets the call go ahead.
+func promptHook() llm.PromptHook {
+	if !dryRun && !showPrompt {
+		return nil
+	}
+	label := appConfig.LLM.Provider
+	if model := llm.ModelName(appConfig); model != "" {
+		label = model
+	}
+	return func(prompt string) error {
+		prompt = strings.TrimRight(prompt, "\n")
+		if dryRun {
+			if _, err := fmt.Fprintln(os.Stdout, prompt); err != nil {
+				return err
+			}
+			return llm.ErrDryRun
+		}
+		faint := color.New(color.Faint)
+		_, err := fmt.Fprintf(os.Stderr, "%s\n%s\n%s\n",
+			faint.Sprint(i18n.T("--- Prompt for %s (%d characters) ---", label, len(prompt))),
+			prompt,
+			faint.Sprint(i18n.T("--- End of prompt ---")))
+		return err
+	}
+}
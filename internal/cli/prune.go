@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// Reasons an entry is removed by prune.
+const (
+	pruneDuplicate = "duplicate"
+	pruneTrivial   = "trivial"
+	pruneIgnored   = "ignored"
+)
+
+// trivialCommands are command lines with no value as history: they are quicker to
+// type again than to find, and only add noise to LLM context.
+var trivialCommands = map[string]bool{
+	"ls": true, "ll": true, "la": true, "l": true, "dir": true,
+	"cd": true, "cd ..": true, "cd -": true, "cd ~": true, "..": true,
+	"pwd": true, "clear": true, "cls": true, "exit": true, "logout": true,
+	"history": true, "fg": true, "bg": true, "jobs": true,
+}
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove duplicates and noise from your history file",
+	Long: `Rewrites your history file without exact duplicate commands (keeping the most
+recent run), trivial commands such as 'ls', 'cd' or 'clear', and entries
+matching ignore patterns (--ignore, or prune.ignore in the config file).
+
+The original file is saved with a timestamped name next to it, and the new file
+replaces it atomically. Use --dry-run to preview what would be removed. Running
+shells may write their in-memory history back on exit, so restart them afterwards.
+
+Flags:
+  --dry-run          : List what would be removed without changing the file.
+  --ignore           : Also remove entries matching this regular expression (repeatable).
+  --keep-duplicates  : Don't remove duplicate commands.
+  --keep-trivial     : Don't remove trivial commands.
+  --yes / -y         : Don't ask for confirmation.
+
+Example:
+  historai prune --dry-run
+  historai prune --ignore '^git (status|diff)$'
+  historai prune --keep-trivial -y`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parsePruneFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Plan the removals without changing the file
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		rewriter, ok := reader.(history.Rewriter)
+		if !ok {
+			return errors.New(i18n.T("history source %q can't be rewritten", appConfig.History.Source))
+		}
+		plan, err := planPrune(rewriter, opts)
+		if err != nil {
+			return err
+		}
+		logger.Debug("Planned prune", zap.Int("entries_count", plan.total), zap.Int("removed_count", len(plan.removed)))
+
+		if len(plan.removed) == 0 {
+			return infof("Nothing to prune in %d history entries.\n", plan.total)
+		}
+		if opts.dryRun {
+			reasonColor := color.New(color.Faint)
+			for _, removal := range plan.removed {
+				if _, err := fmt.Fprintf(os.Stdout, "%s  %s\n", reasonColor.Sprintf("%-9s", removal.reason), removal.command); err != nil {
+					return err
+				}
+			}
+			return infof("Would remove %d of %d entries (%s).\n", len(plan.removed), plan.total, plan.breakdown())
+		}
+
+		// 3. Rewrite the file
+		if !opts.yes {
+			ok, err := confirm(i18n.T("Remove %d of %d entries (%s) from %s?", len(plan.removed), plan.total, plan.breakdown(), rewriter.HistoryFile()))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		pruner := newPruner(opts, plan.totals)
+		result, err := rewriter.Rewrite(func(entry history.HistoryEntry) (history.HistoryEntry, bool) {
+			return entry, pruner.reason(entry) == ""
+		})
+		if err != nil {
+			return err
+		}
+		return infof("Removed %d entries. The original file was saved to %s.\n", result.Removed, result.Backup)
+	},
+}
+
+// pruneOptions holds the parsed flags of the prune command.
+type pruneOptions struct {
+	dryRun         bool
+	yes            bool
+	keepDuplicates bool
+	keepTrivial    bool
+	ignore         []*regexp.Regexp
+}
+
+// parsePruneFlags extracts and validates flags specific to the prune command.
+func parsePruneFlags(cmd *cobra.Command) (pruneOptions, error) {
+	var opts pruneOptions
+	flags := cmd.Flags()
+	for name, target := range map[string]*bool{
+		"dry-run":         &opts.dryRun,
+		"yes":             &opts.yes,
+		"keep-duplicates": &opts.keepDuplicates,
+		"keep-trivial":    &opts.keepTrivial,
+	} {
+		value, err := flags.GetBool(name)
+		if err != nil {
+			return opts, fmt.Errorf("internal error getting %s flag: %w", name, err)
+		}
+		*target = value
+	}
+
+	patterns, err := flags.GetStringArray("ignore")
+	if err != nil {
+		return opts, fmt.Errorf("internal error getting ignore flag: %w", err)
+	}
+	for _, pattern := range append(appConfig.Prune.Ignore, patterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return opts, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+		opts.ignore = append(opts.ignore, re)
+	}
+	return opts, nil
+}
+
+// pruneRemoval is an entry prune removes, and why.
+type pruneRemoval struct {
+	command string
+	reason  string
+}
+
+// prunePlan describes the removals prune would make.
+type prunePlan struct {
+	total   int
+	totals  map[string]int
+	removed []pruneRemoval
+}
+
+// breakdown counts the removals by reason, e.g. "12 duplicate, 3 trivial".
+func (p prunePlan) breakdown() string {
+	counts := make(map[string]int)
+	for _, removal := range p.removed {
+		counts[removal.reason]++
+	}
+	var parts []string
+	for _, reason := range []string{pruneDuplicate, pruneTrivial, pruneIgnored} {
+		if counts[reason] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[reason], reason))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// planPrune walks the history file twice without modifying it: once to count each
+// command line, then to decide which entries to remove. Counting through the
+// rewriter guarantees the decisions see exactly the entries the rewrite will.
+func planPrune(rewriter history.Rewriter, opts pruneOptions) (prunePlan, error) {
+	plan := prunePlan{totals: make(map[string]int)}
+	_, err := rewriter.Rewrite(func(entry history.HistoryEntry) (history.HistoryEntry, bool) {
+		plan.total++
+		plan.totals[strings.TrimSpace(entry.Command)]++
+		return entry, true
+	})
+	if err != nil {
+		return plan, err
+	}
+
+	pruner := newPruner(opts, plan.totals)
+	_, err = rewriter.Rewrite(func(entry history.HistoryEntry) (history.HistoryEntry, bool) {
+		if reason := pruner.reason(entry); reason != "" {
+			plan.removed = append(plan.removed, pruneRemoval{command: entry.Command, reason: reason})
+		}
+		return entry, true
+	})
+	return plan, err
+}
+
+// pruner decides which entries to remove, in file order.
+type pruner struct {
+	opts   pruneOptions
+	totals map[string]int
+	seen   map[string]int
+}
+
+func newPruner(opts pruneOptions, totals map[string]int) *pruner {
+	return &pruner{opts: opts, totals: totals, seen: make(map[string]int)}
+}
+
+// reason returns why entry should be removed, or "" to keep it. Of duplicate
+// command lines only the last one is kept, as it records the most recent use.
+func (p *pruner) reason(entry history.HistoryEntry) string {
+	command := strings.TrimSpace(entry.Command)
+	p.seen[command]++
+
+	for _, re := range p.opts.ignore {
+		if re.MatchString(command) {
+			return pruneIgnored
+		}
+	}
+	if !p.opts.keepTrivial && trivialCommands[command] {
+		return pruneTrivial
+	}
+	if !p.opts.keepDuplicates && p.seen[command] < p.totals[command] {
+		return pruneDuplicate
+	}
+	return ""
+}
+
+// init adds the pruneCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().Bool("dry-run", false, "List what would be removed without changing the file")
+	pruneCmd.Flags().StringArray("ignore", nil, "Also remove entries matching this regular expression (repeatable)")
+	pruneCmd.Flags().Bool("keep-duplicates", false, "Don't remove duplicate commands")
+	pruneCmd.Flags().Bool("keep-trivial", false, "Don't remove trivial commands such as ls, cd or clear")
+	pruneCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/redact"
+)
+
+// recordCmd represents the hidden record command called by the shell hook.
+var recordCmd = &cobra.Command{
+	Use:    "record [flags] -- <command>",
+	Short:  "Append a command to historai's history log (used by the shell hook)",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	// Runs after every shell command: skip config loading (and the keyring lookup it may do).
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		logger = zap.NewNop()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		exitCode, err := flags.GetInt("exit-code")
+		if err != nil {
+			return fmt.Errorf("internal error getting exit-code flag: %w", err)
+		}
+		durationMS, err := flags.GetInt64("duration-ms")
+		if err != nil {
+			return fmt.Errorf("internal error getting duration-ms flag: %w", err)
+		}
+		cwd, err := flags.GetString("cwd")
+		if err != nil {
+			return fmt.Errorf("internal error getting cwd flag: %w", err)
+		}
+		file, err := flags.GetString("file")
+		if err != nil {
+			return fmt.Errorf("internal error getting file flag: %w", err)
+		}
+		stderr, err := flags.GetString("stderr")
+		if err != nil {
+			return fmt.Errorf("internal error getting stderr flag: %w", err)
+		}
+		stdout, err := flags.GetString("stdout")
+		if err != nil {
+			return fmt.Errorf("internal error getting stdout flag: %w", err)
+		}
+
+		command := strings.Join(args, " ")
+		// Like HIST_IGNORE_SPACE / HISTCONTROL=ignorespace: a leading space opts out.
+		if strings.TrimSpace(command) == "" || strings.HasPrefix(command, " ") {
+			return nil
+		}
+
+		entry := history.HistoryEntry{
+			Timestamp:  time.Now().Add(-time.Duration(durationMS) * time.Millisecond).Unix(),
+			Command:    strings.TrimRight(command, "\n"),
+			Cwd:        cwd,
+			DurationMS: durationMS,
+			Stderr:     history.TailBytes(strings.TrimSpace(stderr), history.MaxRecordedStderr),
+			// Output is kept for every command, not just failed ones, and often holds
+			// secrets (cat .env), so it is stored redacted.
+			Output: redact.New().Redact(history.TailBytes(strings.TrimSpace(stdout), history.MaxRecordedOutput)),
+		}
+		if flags.Changed("exit-code") {
+			entry.ExitCode = &exitCode
+		}
+		return history.AppendRecord(file, entry)
+	},
+}
+
+// init adds the recordCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(recordCmd)
+
+	recordCmd.Flags().Int("exit-code", 0, "Exit status of the command")
+	recordCmd.Flags().Int64("duration-ms", 0, "How long the command ran, in milliseconds")
+	recordCmd.Flags().String("cwd", "", "Working directory the command ran in")
+	recordCmd.Flags().String("stderr", "", "Tail of the command's error output")
+	recordCmd.Flags().String("stdout", "", "Tail of the command's output")
+	recordCmd.Flags().String("file", "", "History log to append to (default: ~/.local/share/historai/history.jsonl)")
+}
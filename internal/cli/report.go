@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/stats"
+)
+
+const (
+	defaultReportTop = 10
+	reportDirName    = "reports"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write a markdown digest of your shell history for a period",
+	Long: `Writes a markdown digest of a period of your shell history to a file:
+activity statistics, programs you started using, commands that failed
+repeatedly and long commands you keep typing, with suggested aliases.
+It is computed locally; pass --summary to add a short AI-written summary of
+what you worked on.
+
+It is designed to be run from cron or launchd, e.g. every Monday morning:
+  0 9 * * 1  historai report --weekly -q
+
+Flags:
+  --weekly      : Cover the last 7 days (the default).
+  --daily       : Cover the last 24 hours.
+  --since / -s  : Cover the period since today, yesterday, 12h, 3d, 2w or a date like 2024-05-01.
+  --file / -f   : Where to write the report; - for stdout (default: ~/.local/share/historai/reports/<period>-<date>.md).
+  --summary     : Add an AI-written summary of what you worked on (calls the LLM).
+  --top / -t    : How many items to show in each list (default: 10).
+
+Example:
+  historai report --weekly
+  historai report --daily --file ~/notes/today.md
+  historai report --since 2024-05-01 --summary -f -`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parseReportFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Read history and compute the digest
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		entries, err := reader.ReadHistory(0)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		digest := stats.ComputeDigest(entries, opts.since, opts.until, opts.top)
+		logger.Debug("Computed report digest", zap.Int("entries_count", digest.Report.Total))
+
+		// 3. Optionally ask the LLM for a summary
+		summary := ""
+		if opts.summary && digest.Report.Total > 0 {
+			summary, err = reportSummary(opts.since, describeSince(opts.sinceValue, opts.since))
+			if err != nil {
+				return err
+			}
+		}
+
+		// 4. Write the report
+		report := formatReport(opts.title, digest, summary)
+		if opts.file == "-" {
+			_, err := fmt.Fprint(os.Stdout, report)
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(opts.file), 0o700); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+		if err := os.WriteFile(opts.file, []byte(report), 0o600); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		return infof("Report written to %s\n", opts.file)
+	},
+}
+
+// reportOptions holds the parsed flags of the report command.
+type reportOptions struct {
+	title      string
+	sinceValue string
+	since      time.Time
+	until      time.Time
+	file       string
+	summary    bool
+	top        int
+}
+
+// parseReportFlags extracts and validates flags specific to the report command.
+func parseReportFlags(cmd *cobra.Command) (reportOptions, error) {
+	flags := cmd.Flags()
+	opts := reportOptions{title: "weekly", sinceValue: "1w", until: time.Now()}
+
+	daily, err := flags.GetBool("daily")
+	if err != nil {
+		return opts, fmt.Errorf("internal error getting daily flag: %w", err)
+	}
+	if daily {
+		opts.title, opts.sinceValue = "daily", "1d"
+	}
+	if flags.Changed("since") {
+		if opts.sinceValue, err = flags.GetString("since"); err != nil {
+			return opts, fmt.Errorf("internal error getting since flag: %w", err)
+		}
+		opts.title = "report"
+	}
+	if opts.since, err = parseSince(opts.sinceValue, opts.until); err != nil {
+		return opts, err
+	}
+
+	if opts.file, err = flags.GetString("file"); err != nil {
+		return opts, fmt.Errorf("internal error getting file flag: %w", err)
+	}
+	if opts.file == "" {
+		dir, err := history.DataDir()
+		if err != nil {
+			return opts, err
+		}
+		opts.file = filepath.Join(dir, reportDirName, fmt.Sprintf("%s-%s.md", opts.title, opts.until.Format("2006-01-02")))
+	}
+
+	if opts.summary, err = flags.GetBool("summary"); err != nil {
+		return opts, fmt.Errorf("internal error getting summary flag: %w", err)
+	}
+	if opts.top, err = flags.GetInt("top"); err != nil {
+		return opts, fmt.Errorf("internal error getting top flag: %w", err)
+	}
+	if opts.top < 1 {
+		return opts, fmt.Errorf("--top must be at least 1")
+	}
+	return opts, nil
+}
+
+// reportSummary asks the LLM for a summary of the work done since since.
+func reportSummary(since time.Time, period string) (string, error) {
+	eng, err := newEngine(context.Background(), engine.Options{})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := eng.Close(); closeErr != nil {
+			logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+	}()
+
+	spin := startSpinner()
+	defer spin.stop()
+	summary, err := eng.Summarize(context.Background(), since, period)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// formatReport renders digest as a markdown document.
+func formatReport(title string, digest stats.Digest, summary string) string {
+	var b strings.Builder
+	report := digest.Report
+
+	b.WriteString(fmt.Sprintf("# historai %s report: %s to %s\n\n", title,
+		digest.Since.Format("2006-01-02"), digest.Until.Format("2006-01-02")))
+	b.WriteString(fmt.Sprintf("%d commands, %d unique.\n", report.Total, report.Unique))
+	if report.Total == 0 {
+		b.WriteString("\nNo commands with a timestamp were recorded in this period.\n")
+		return b.String()
+	}
+
+	if summary != "" {
+		b.WriteString("\n## Summary\n\n" + summary + "\n")
+	}
+
+	writeCounts := func(heading string, counts []stats.Count, format func(stats.Count) string) {
+		if len(counts) == 0 {
+			return
+		}
+		b.WriteString("\n## " + heading + "\n\n")
+		for _, count := range counts {
+			b.WriteString("- " + format(count) + "\n")
+		}
+	}
+	runs := func(count stats.Count) string {
+		return fmt.Sprintf("`%s` (%d)", count.Name, count.Count)
+	}
+
+	writeCounts("Top programs", report.TopPrograms, runs)
+	writeCounts("New this period", digest.NewPrograms, runs)
+	writeCounts("Repeated failures", digest.RepeatedFailures, func(count stats.Count) string {
+		return fmt.Sprintf("`%s` failed %d times", count.Name, count.Count)
+	})
+	writeCounts("Suggested aliases", digest.AliasCandidates, func(count stats.Count) string {
+		return fmt.Sprintf("`alias %s=%s` (typed %d times)", stats.AliasName(count.Name), shellQuote(count.Name), count.Count)
+	})
+	writeCounts("Top directories", report.TopDirectories, runs)
+
+	if len(report.Slowest) > 0 {
+		b.WriteString("\n## Longest-running commands\n\n")
+		for _, entry := range report.Slowest {
+			duration := (time.Duration(entry.DurationMS) * time.Millisecond).Round(time.Second / 10)
+			b.WriteString(fmt.Sprintf("- `%s` (%s)\n", entry.Command, duration))
+		}
+	}
+	return b.String()
+}
+
+// init adds the reportCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().Bool("weekly", false, "Cover the last 7 days (the default)")
+	reportCmd.Flags().Bool("daily", false, "Cover the last 24 hours")
+	reportCmd.Flags().StringP("since", "s", "", "Cover the period since today, yesterday, 12h, 3d, 2w or a date")
+	reportCmd.Flags().StringP("file", "f", "", "File to write the report to, or - for stdout")
+	reportCmd.Flags().Bool("summary", false, "Add an AI-written summary of what you worked on")
+	reportCmd.Flags().IntP("top", "t", defaultReportTop, "Number of items to show in each list")
+	reportCmd.MarkFlagsMutuallyExclusive("weekly", "daily", "since")
+}
@@ -1,9 +1,21 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/logfile"
+	"github.com/sanspareilsmyn/historai/internal/timings"
 )
 
 var (
@@ -12,6 +24,33 @@ var (
 	// Flag variable to store the value of the --debug flag.
 	debugMode bool
 
+	// Flag variable to store the value of the --no-daemon flag.
+	noDaemon bool
+
+	// Flag variable to store the value of the --config flag.
+	configPath string
+
+	// Flag variable to store the value of the --output flag.
+	outputFormat string
+
+	// Flag variable to store the value of the --quiet flag.
+	quiet bool
+
+	// Flag variable to store the value of the --no-color flag.
+	noColor bool
+
+	// Flag variables for --raw (bare commands only) and --first (bare top command only).
+	rawOutput bool
+	firstOnly bool
+
+	// Flag variables for --dry-run (print the prompt instead of sending it) and
+	// --show-prompt (print the prompt, then send it).
+	dryRun     bool
+	showPrompt bool
+
+	// appConfig is loaded once per invocation, with command-line flags applied on top.
+	appConfig *config.Config
+
 	// rootCmd represents the base command when called without any subcommands
 	rootCmd = &cobra.Command{
 		Use:   "historai",
@@ -20,6 +59,8 @@ var (
 or get command suggestions using natural language queries powered by LLM APIs.
 Find or discover commands based on what they do, not just keywords.`,
 		SilenceUsage: true,
+		// main reports the returned error; don't print it twice.
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var zapConfig zap.Config
@@ -28,6 +69,9 @@ Find or discover commands based on what they do, not just keywords.`,
 			} else {
 				zapConfig = zap.NewProductionConfig()
 				zapConfig.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+				if quiet {
+					zapConfig.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+				}
 			}
 
 			logger, err = zapConfig.Build()
@@ -38,18 +82,170 @@ Find or discover commands based on what they do, not just keywords.`,
 			logger.Debug("Debug logging enabled.")
 			logger.Debug("Logger initialized successfully.")
 
+			configureColor()
+
+			if err := validateOutputFlags(); err != nil {
+				return err
+			}
+
+			appConfig, err = loadAppConfig(cmd)
+			if err != nil {
+				return configError(err)
+			}
+			i18n.SetLocale(appConfig.UI.Locale)
+			invocationTimings = timings.New()
+
+			if appConfig.Log.Enabled {
+				teeLogFile(cmd)
+			}
+
 			return nil
 		},
 	}
 )
 
+// loadAppConfig loads the config file and environment, then applies global flag overrides.
+func loadAppConfig(cmd *cobra.Command) (*config.Config, error) {
+	logger.Debug("Loading configuration...")
+	cfg, err := config.LoadConfig(logger, configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	flags := cmd.Flags()
+	overrides := []struct {
+		flag string
+		key  string
+	}{
+		{"source", "history.source"},
+		{"provider", "llm.provider"},
+		{"model", "llm.model"},
+		{"lang", "prompt.language"},
+	}
+	for _, override := range overrides {
+		if !flags.Changed(override.flag) {
+			continue
+		}
+		value, err := flags.GetString(override.flag)
+		if err != nil {
+			return nil, fmt.Errorf("internal error getting %s flag: %w", override.flag, err)
+		}
+		if err := cfg.Set(override.key, value, "flag --"+override.flag); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Debug("Configuration loaded successfully",
+		zap.String("provider", cfg.LLM.Provider), zap.String("source", cfg.History.Source))
+	return cfg, nil
+}
+
+// logFilePath returns the configured log file, or the default one.
+func logFilePath() (string, error) {
+	if appConfig.Log.File != "" {
+		return appConfig.Log.File, nil
+	}
+	return logfile.DefaultPath()
+}
+
+// teeLogFile makes the logger also write to the log file, as JSON lines tagged with
+// the command and process, so that failures of the daemon and shell widgets can be
+// looked at later. A log file that can't be opened is reported but not fatal.
+func teeLogFile(cmd *cobra.Command) {
+	path, err := logFilePath()
+	if err != nil {
+		logger.Warn("Failed to locate log file", zap.Error(err))
+		return
+	}
+	writer, err := logfile.Open(path, int64(appConfig.Log.MaxSize)*1024*1024, appConfig.Log.MaxBackups)
+	if err != nil {
+		logger.Warn("Failed to open log file", zap.Error(err))
+		return
+	}
+
+	level, _ := zapcore.ParseLevel(appConfig.Log.Level) // checked by config.Validate
+	if debugMode {
+		level = zapcore.DebugLevel
+	}
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileCore := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), writer, level).
+		With([]zap.Field{zap.String("command", cmd.CommandPath()), zap.Int("pid", os.Getpid())})
+	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, fileCore)
+	}))
+	logger.Debug("Logging to file", zap.String("path", path))
+}
+
+// newEngine creates an engine from the loaded configuration, showing its prompts for
+// --dry-run and --show-prompt and timing its stages.
+func newEngine(ctx context.Context, opts engine.Options) (*engine.Engine, error) {
+	if opts.PromptHook == nil {
+		opts.PromptHook = promptHook()
+	}
+	// Engines keeping history warm serve many requests (servers, chat, batch), so
+	// only the others are timed.
+	if !opts.CacheHistory {
+		opts.Timings = invocationTimings
+	}
+	return engine.New(ctx, logger, appConfig, opts)
+}
+
+// newHistoryReader creates the configured history reader, for commands that work
+// on history locally and don't need an LLM client.
+func newHistoryReader() (history.HistoryReader, error) {
+	reader, err := history.NewReader(logger, appConfig.History.Source, appConfig.History.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize history reader: %w", err)
+	}
+	return reader, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
-	err := rootCmd.Execute()
+	registerCompletions()
+	started := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	finishTimings(cmd, time.Since(started), err)
 	return err
 }
 
+// localizedHelpFunc wraps help to translate command descriptions. Help is rendered
+// without running PersistentPreRunE, so the locale from the config file is applied here.
+func localizedHelpFunc(help func(*cobra.Command, []string)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if cfg, err := config.LoadConfig(zap.NewNop(), configPath); err == nil {
+			i18n.SetLocale(cfg.UI.Locale)
+		}
+		localizeHelp(rootCmd)
+		help(cmd, args)
+	}
+}
+
+// localizeHelp translates the short descriptions of cmd and its subcommands.
+func localizeHelp(cmd *cobra.Command) {
+	cmd.Short = i18n.T(cmd.Short)
+	for _, sub := range cmd.Commands() {
+		localizeHelp(sub)
+	}
+}
+
 // init is called when the package is imported.
 func init() {
+	rootCmd.SetHelpFunc(localizedHelpFunc(rootCmd.HelpFunc()))
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default: ~/.config/historai/config.yaml, or %APPDATA%\\historai\\config.yaml on Windows)")
+	rootCmd.PersistentFlags().String("source", config.DefaultSource, "History source: built-in shell or an external historai-source-<name> plugin")
+	rootCmd.PersistentFlags().String("provider", config.DefaultProvider, "LLM provider: built-in or an external historai-provider-<name> plugin")
+	rootCmd.PersistentFlags().String("model", "", "LLM model name (default: provider's default)")
+	rootCmd.PersistentFlags().String("lang", "", "Language for explanations in responses, e.g. Korean (commands are never translated)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "Output format: text, json, yaml, jsonl (one JSON object per command, streamed), or raycast or alfred (launcher items)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress headers, the progress spinner, warnings and status messages on stderr")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also set by the NO_COLOR environment variable)")
+	rootCmd.PersistentFlags().BoolVar(&rawOutput, "raw", false, "Print only the bare commands, one per line, with no headers, comments or color")
+	rootCmd.PersistentFlags().BoolVar(&firstOnly, "first", false, "Print only the bare top command (implies --raw)")
+	rootCmd.PersistentFlags().BoolVar(&noDaemon, "no-daemon", false, "Do not use a running historai daemon")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the prompt that would be sent to the LLM, after redaction, without sending it")
+	rootCmd.PersistentFlags().BoolVar(&showPrompt, "show-prompt", false, "Print every prompt sent to the LLM to stderr")
+	rootCmd.PersistentFlags().BoolVar(&showTimings, "timings", false, "Print the time spent parsing history, retrieving context, waiting for the LLM and rendering to stderr")
 }
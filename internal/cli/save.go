@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// saveCmd represents the save command
+var saveCmd = &cobra.Command{
+	Use:   "save <entry>",
+	Short: "Save a command to your favorites",
+	Long: `Pins an important command so it is never lost in your history. Saved commands
+are always included in the context of 'suggest', even when they are older than
+its --limit, and are listed by 'historai saved'.
+
+<entry> is a command line, or a number N for the Nth most recent command (1 is the
+last one).
+
+Flags:
+  --remove : Remove the command from your favorites.
+
+Example:
+  historai save 1
+  historai save "rsync -avz --delete ./site/ web:/var/www/"
+  historai save --remove 1`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remove, err := cmd.Flags().GetBool("remove")
+		if err != nil {
+			return fmt.Errorf("internal error getting remove flag: %w", err)
+		}
+		command, err := resolveEntry(args[0])
+		if err != nil {
+			return err
+		}
+		store, err := annotations.Load("")
+		if err != nil {
+			return err
+		}
+
+		if !store.SetPinned(command, !remove) {
+			if remove {
+				return infof("%s is not saved\n", command)
+			}
+			return infof("%s is already saved\n", command)
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		if remove {
+			return infof("Removed %s from your saved commands\n", command)
+		}
+		return infof("Saved %s\n", command)
+	},
+}
+
+// savedCmd represents the saved command
+var savedCmd = &cobra.Command{
+	Use:   "saved [number]",
+	Short: "List your saved commands, or print or run one",
+	Long: `Lists the commands saved with 'historai save', numbered, most recently changed first.
+
+With a number, prints that command alone, ready for $(historai saved 2) or a shell
+key binding. With --run, executes it in your shell after confirmation and exits
+with its exit code.
+
+Flags:
+  --run / -r : Run the selected command.
+  --yes / -y : Don't ask for confirmation before running.
+
+Example:
+  historai saved
+  historai saved 2
+  historai saved 2 --run`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		flags := cmd.Flags()
+		run, err := flags.GetBool("run")
+		if err != nil {
+			return fmt.Errorf("internal error getting run flag: %w", err)
+		}
+		yes, err := flags.GetBool("yes")
+		if err != nil {
+			return fmt.Errorf("internal error getting yes flag: %w", err)
+		}
+		if run && len(args) == 0 {
+			return errors.New(i18n.T("--run needs the number of a saved command"))
+		}
+
+		store, err := annotations.Load("")
+		if err != nil {
+			return err
+		}
+		saved := store.Pinned()
+
+		// 2. List the saved commands
+		if len(args) == 0 {
+			return printSaved(store, saved)
+		}
+
+		// 3. Print or run the selected one
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(saved) {
+			return errors.New(i18n.T("no saved command number %s (see 'historai saved')", args[0]))
+		}
+		command := saved[n-1]
+		if !run {
+			_, err := fmt.Fprintln(os.Stdout, command)
+			return err
+		}
+		if !yes {
+			ok, err := confirm(i18n.T("Run %s?", command))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		return runInShell(command)
+	},
+}
+
+// printSaved lists the saved commands with their number and note.
+func printSaved(store *annotations.Store, saved []string) error {
+	if len(saved) == 0 {
+		return infof("No saved commands yet. Save one with 'historai save <entry>'.\n")
+	}
+	if rawOutput || firstOnly {
+		if firstOnly {
+			saved = saved[:1]
+		}
+		for _, command := range saved {
+			if _, err := fmt.Fprintln(os.Stdout, command); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	numberColor := color.New(color.Faint)
+	noteColor := color.New(color.Faint)
+	for i, command := range saved {
+		line := fmt.Sprintf("%s  %s", numberColor.Sprintf("%3d", i+1), command)
+		if note := store.Get(command).Note; note != "" {
+			line += "  " + noteColor.Sprint("# "+note)
+		}
+		if _, err := fmt.Fprintln(os.Stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runInShell runs command with $SHELL (or sh) attached to the terminal. A non-zero
+// exit status becomes historai's own exit code, without an error message.
+func runInShell(command string) error {
+	if pattern := appConfig.Policy.Denied(command); pattern != "" {
+		return configError(errors.New(i18n.T("not running %s: the organization policy %s denies commands matching %s", command, appConfig.Policy.Path, pattern)))
+	}
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+	logger.Debug("Running command", zap.String("shell", shell), zap.String("command", command))
+	c := exec.Command(shell, "-c", command)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &exitError{code: exitErr.ExitCode()}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", shell, err)
+	}
+	return nil
+}
+
+// init adds the saveCmd, the savedCmd and their flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(saveCmd, savedCmd)
+
+	saveCmd.Flags().Bool("remove", false, "Remove the command from your favorites")
+	savedCmd.Flags().BoolP("run", "r", false, "Run the selected command")
+	savedCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before running")
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+const (
+	defaultScriptPick = 30
+	strictMode        = "set -euo pipefail"
+)
+
+// scriptCmd represents the script command
+var scriptCmd = &cobra.Command{
+	Use:   "script [\"<purpose>\"]",
+	Short: "Turn commands from your history into a shell script using AI",
+	Long: `Asks an LLM to turn a series of commands from your history into a cleaned-up,
+commented bash script: duplicates, typos, failed attempts and commands that only
+inspect things are dropped, repeated values become variables, and the script
+runs with 'set -euo pipefail'.
+
+Select the commands with --since and/or --last. Otherwise, on a terminal, the
+last 30 commands are listed and you pick the ones to keep, e.g. "3-12,15".
+An optional purpose tells the LLM what the script is for.
+
+Flags:
+  --since / -s : Use the commands run since today, yesterday, 12h, 3d, 2w or a date like 2024-05-01.
+  --last / -l  : Use the last N commands (of the --since period, if given).
+  --pick / -p  : Pick commands from the selection interactively.
+  --file / -f  : Write the script to this file and make it executable, instead of printing it.
+
+Example:
+  historai script
+  historai script --since 2h "set up a new dev VM"
+  historai script --last 20 --pick --file setup.sh`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parseScriptFlags(cmd)
+		if err != nil {
+			return err
+		}
+		purpose := ""
+		if len(args) == 1 {
+			purpose = strings.TrimSpace(args[0])
+		}
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Select the commands
+		commands, err := selectScriptCommands(eng, opts)
+		if err != nil {
+			return err
+		}
+		if len(commands) == 0 {
+			return errors.New(i18n.T("no commands selected"))
+		}
+		logger.Debug("Selected commands for the script", zap.Int("commands_count", len(commands)))
+
+		// 4. Ask the LLM for the script
+		started := time.Now()
+		spin := startSpinner()
+		script, err := eng.Script(context.Background(), commands, purpose)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 5. Write or print the script
+		if outputFormat != outputText || strings.TrimSpace(script) == "" {
+			return printExplanation(logger, commandOutput{
+				kind:         "script",
+				query:        purpose,
+				output:       script,
+				noAnswer:     "(AI could not write a script)",
+				logOnFailure: "No script generated or response indicates failure.",
+				started:      started,
+			})
+		}
+		script = withStrictMode(strings.TrimSpace(script)) + "\n"
+		if opts.file != "" {
+			return writeScript(opts.file, script)
+		}
+		return printScript(script, len(commands))
+	},
+}
+
+// scriptOptions holds the parsed flags of the script command.
+type scriptOptions struct {
+	since time.Time
+	last  int
+	pick  bool
+	file  string
+}
+
+// parseScriptFlags extracts and validates flags specific to the script command.
+// Without --since or --last, the last commands are picked from interactively.
+func parseScriptFlags(cmd *cobra.Command) (scriptOptions, error) {
+	var opts scriptOptions
+	flags := cmd.Flags()
+
+	if flags.Changed("since") {
+		sinceValue, err := flags.GetString("since")
+		if err != nil {
+			return opts, fmt.Errorf("internal error getting since flag: %w", err)
+		}
+		if opts.since, err = parseSince(sinceValue, time.Now()); err != nil {
+			return opts, err
+		}
+	}
+	last, err := flags.GetInt("last")
+	if err != nil {
+		return opts, fmt.Errorf("internal error getting last flag: %w", err)
+	}
+	if flags.Changed("last") && last < 1 {
+		return opts, errors.New("--last must be at least 1")
+	}
+	if flags.Changed("last") {
+		opts.last = last
+	}
+	if opts.pick, err = flags.GetBool("pick"); err != nil {
+		return opts, fmt.Errorf("internal error getting pick flag: %w", err)
+	}
+	if opts.file, err = flags.GetString("file"); err != nil {
+		return opts, fmt.Errorf("internal error getting file flag: %w", err)
+	}
+
+	if opts.since.IsZero() && opts.last == 0 {
+		opts.pick, opts.last = true, defaultScriptPick
+	}
+	if opts.pick && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return opts, errors.New(i18n.T("stdin is not a terminal; select commands with --since or --last"))
+	}
+	return opts, nil
+}
+
+// selectScriptCommands returns the commands selected by opts, oldest first,
+// ignoring historai's own invocations.
+func selectScriptCommands(eng *engine.Engine, opts scriptOptions) ([]history.HistoryEntry, error) {
+	var commands []history.HistoryEntry
+	if !opts.since.IsZero() {
+		entries, err := eng.Since(opts.since)
+		if err != nil {
+			return nil, err
+		}
+		commands = entries
+	} else {
+		entries, err := eng.History(0)
+		if err != nil {
+			return nil, err
+		}
+		commands = withoutSelf(entries)
+	}
+	if opts.last > 0 && len(commands) > opts.last {
+		commands = commands[len(commands)-opts.last:]
+	}
+	if !opts.pick || len(commands) == 0 {
+		return commands, nil
+	}
+	return pickCommands(commands)
+}
+
+// pickCommands lists commands on stderr, numbered from the oldest, and returns the
+// ones the user selects.
+func pickCommands(commands []history.HistoryEntry) ([]history.HistoryEntry, error) {
+	numberColor := color.New(color.Faint)
+	for i, entry := range commands {
+		if _, err := fmt.Fprintf(os.Stderr, "%s  %s\n", numberColor.Sprintf("%3d", i+1), entry.Command); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := fmt.Fprint(os.Stderr, i18n.T("Commands to include (e.g. 1-5,8; Enter for all): ")); err != nil {
+		return nil, err
+	}
+	answer, err := readLine(os.Stdin)
+	if err != nil && answer == "" {
+		return nil, errors.New(i18n.T("no commands selected"))
+	}
+	indexes, err := parseSelection(answer, len(commands))
+	if err != nil {
+		return nil, err
+	}
+	picked := make([]history.HistoryEntry, 0, len(indexes))
+	for _, i := range indexes {
+		picked = append(picked, commands[i])
+	}
+	return picked, nil
+}
+
+// parseSelection turns a selection like "1-5,8" into ascending 0-based indexes
+// below n. An empty selection selects everything.
+func parseSelection(selection string, n int) ([]int, error) {
+	selected := make([]bool, n)
+	selection = strings.TrimSpace(selection)
+	if selection == "" {
+		for i := range selected {
+			selected[i] = true
+		}
+	}
+	for _, part := range strings.FieldsFunc(selection, func(r rune) bool { return r == ',' || r == ' ' }) {
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(from))
+		end, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || start < 1 || end > n || start > end {
+			return nil, errors.New(i18n.T("invalid selection %q (use numbers from 1 to %d, e.g. 1-5,8)", part, n))
+		}
+		for i := start; i <= end; i++ {
+			selected[i-1] = true
+		}
+	}
+	var indexes []int
+	for i, ok := range selected {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// withStrictMode adds 'set -euo pipefail' after the shebang if the script lacks it,
+// and a shebang if that is missing too.
+func withStrictMode(script string) string {
+	if strings.Contains(script, strictMode) {
+		return script
+	}
+	if !strings.HasPrefix(script, "#!") {
+		return "#!/usr/bin/env bash\n" + strictMode + "\n\n" + script
+	}
+	shebang, rest, _ := strings.Cut(script, "\n")
+	return shebang + "\n" + strictMode + "\n" + rest
+}
+
+// writeScript writes an executable script to path, refusing to overwrite a file.
+func writeScript(path, script string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create script: %w", err)
+	}
+	if _, err := file.WriteString(script); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	return infof("Script written to %s\n", path)
+}
+
+// printScript prints the script to stdout, syntax-highlighted when color is enabled,
+// with a header on stderr.
+func printScript(script string, count int) error {
+	if !quiet && !rawOutput {
+		if _, err := color.New(color.FgYellow).Fprintln(os.Stderr, "\n"+i18n.T("--- Script from %d commands ---", count)); err != nil {
+			return err
+		}
+	}
+	if !color.NoColor && !rawOutput {
+		if highlighted, err := highlightShell(script); err == nil {
+			script = highlighted
+		}
+	}
+	_, err := fmt.Fprint(os.Stdout, script)
+	return err
+}
+
+// init adds the scriptCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(scriptCmd)
+
+	scriptCmd.Flags().StringP("since", "s", "", "Use the commands run since today, yesterday, 12h, 3d, 2w or a date")
+	scriptCmd.Flags().IntP("last", "l", 0, "Use the last N commands")
+	scriptCmd.Flags().BoolP("pick", "p", false, "Pick commands from the selection interactively")
+	scriptCmd.Flags().StringP("file", "f", "", "Write the script to this file and make it executable")
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/server"
+)
+
+const (
+	defaultServeAddr = "127.0.0.1:8765"
+	envServeToken    = "HISTORAI_SERVE_TOKEN"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve find/suggest/history as a local JSON HTTP API",
+	Long: `Starts a local HTTP server so other tools (editors, launchers, dashboards)
+can reuse historai.
+
+Endpoints:
+  POST /find     {"query": "...", "limit": 300}
+  POST /suggest  {"query": "...", "limit": 100, "no_history_context": false}
+  GET  /history?limit=50
+  GET  /metrics  Prometheus metrics: requests, latencies, history cache hits
+                 and provider errors
+  GET  /healthz  Health check, served without a token
+
+If --token (or the HISTORAI_SERVE_TOKEN environment variable) is set, every
+request must carry an "Authorization: Bearer <token>" header. Without a token,
+--addr must be a loopback address, and only requests whose Host header is
+localhost or a loopback address are served, so that web pages you visit can't
+reach the server through DNS rebinding.
+
+Example:
+  historai serve
+  historai serve --addr 127.0.0.1:9000 --token s3cret`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, token, err := parseServeFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		eng, err := newEngine(ctx, engine.Options{CacheHistory: true})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		return server.New(logger, eng, token).ListenAndServe(ctx, addr)
+	},
+}
+
+// parseServeFlags extracts flags specific to the serve command.
+func parseServeFlags(cmd *cobra.Command) (addr string, token string, err error) {
+	addr, err = cmd.Flags().GetString("addr")
+	if err != nil {
+		err = fmt.Errorf("internal error getting addr flag: %w", err)
+		return
+	}
+
+	token, err = cmd.Flags().GetString("token")
+	if err != nil {
+		err = fmt.Errorf("internal error getting token flag: %w", err)
+		return
+	}
+	if token == "" {
+		token = os.Getenv(envServeToken)
+	}
+	return addr, token, nil
+}
+
+// init adds the serveCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("addr", defaultServeAddr, "Address to listen on")
+	serveCmd.Flags().String("token", "", "Bearer token required on every request (env: "+envServeToken+")")
+}
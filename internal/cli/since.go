@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseSince converts a --since value to the start of the period it describes,
+// relative to now. Accepted values are "today", "yesterday", a number of hours,
+// days or weeks ("12h", "3d", "2w", or any Go duration) and a date ("2024-05-01").
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch value {
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return date, nil
+	}
+	if n, unit, ok := splitCount(value); ok {
+		switch unit {
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return now.Add(-duration), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (use today, yesterday, 12h, 3d, 2w or a date like 2024-05-01)", value)
+}
+
+// splitCount splits values like "3d" into their count and unit suffix.
+func splitCount(value string) (n int, unit string, ok bool) {
+	if len(value) < 2 {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, "", false
+	}
+	return n, value[len(value)-1:], true
+}
+
+// describeSince phrases a --since value for prompts and headers, e.g. "since yesterday".
+func describeSince(value string, since time.Time) string {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "today", "yesterday":
+		return "since " + value
+	}
+	return "since " + since.Format("2006-01-02 15:04")
+}
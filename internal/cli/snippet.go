@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/snippets"
+)
+
+// snippetCmd represents the snippet command
+var snippetCmd = &cobra.Command{
+	Use:   "snippet",
+	Short: "Manage parameterized command snippets",
+	Long: `Keeps a library of command templates with {placeholders}, such as
+"ssh {host} -L {port}:localhost:{port}". 'historai use' fills the placeholders in,
+with the help of the LLM when you describe what you want to do.
+
+A placeholder is a name in braces; ${name} is a shell variable and is left alone.
+Snippets are stored in snippets.json in historai's data directory.
+
+Example:
+  historai snippet add tunnel "ssh {host} -L {port}:localhost:{port}" --description "forward a remote port"
+  historai snippet list
+  historai snippet remove tunnel
+  historai use tunnel "tunnel to the staging db"`,
+}
+
+// snippetAddCmd represents the snippet add command
+var snippetAddCmd = &cobra.Command{
+	Use:   "add <name> <command>",
+	Short: "Add or replace a snippet",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		description, err := cmd.Flags().GetString("description")
+		if err != nil {
+			return fmt.Errorf("internal error getting description flag: %w", err)
+		}
+		store, err := snippets.Load("")
+		if err != nil {
+			return err
+		}
+		replaced, err := store.Set(args[0], args[1], description)
+		if err != nil {
+			return err
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+
+		placeholders := store.Get(args[0]).Placeholders()
+		if len(placeholders) == 0 {
+			if err := infof("Note: %s has no {placeholders}.\n", args[0]); err != nil {
+				return err
+			}
+		}
+		if replaced {
+			return infof("Replaced snippet %s\n", args[0])
+		}
+		return infof("Added snippet %s\n", args[0])
+	},
+}
+
+// snippetRemoveCmd represents the snippet remove command
+var snippetRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a snippet",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := snippets.Load("")
+		if err != nil {
+			return err
+		}
+		if !store.Remove(args[0]) {
+			return errors.New(i18n.T("no snippet named %s (see 'historai snippet list')", args[0]))
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		return infof("Removed snippet %s\n", args[0])
+	},
+}
+
+// snippetListCmd represents the snippet list command
+var snippetListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List your snippets",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := snippets.Load("")
+		if err != nil {
+			return err
+		}
+		sorted := store.Sorted()
+		if len(sorted) == 0 {
+			return infof("No snippets yet. Add one with 'historai snippet add <name> <command>'.\n")
+		}
+
+		nameColor := color.New(color.Bold)
+		descriptionColor := color.New(color.Faint)
+		var b strings.Builder
+		for _, snippet := range sorted {
+			if rawOutput {
+				b.WriteString(snippet.Name + "\n")
+				continue
+			}
+			b.WriteString(fmt.Sprintf("%s  %s\n", nameColor.Sprint(snippet.Name), snippet.Command))
+			if snippet.Description != "" {
+				b.WriteString("  " + descriptionColor.Sprint(snippet.Description) + "\n")
+			}
+		}
+		_, err = fmt.Fprint(os.Stdout, b.String())
+		return err
+	},
+}
+
+// init adds the snippetCmd, its subcommands and their flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(snippetCmd)
+	snippetCmd.AddCommand(snippetAddCmd, snippetRemoveCmd, snippetListCmd)
+
+	snippetAddCmd.Flags().String("description", "", "What the snippet does, shown in listings and to the LLM")
+}
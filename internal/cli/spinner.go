@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinner shows an elapsed-time status line on stderr while waiting for the LLM.
+// Pressing Ctrl-C clears the line and aborts the command.
+type spinner struct {
+	label   string
+	started time.Time
+	done    chan struct{}
+	stopped sync.Once
+	wg      sync.WaitGroup
+}
+
+// startSpinner starts a spinner unless stderr is not a terminal or --quiet/--debug
+// was given. It returns nil in that case; a nil *spinner is safe to stop.
+func startSpinner() *spinner {
+	label := appConfig.LLM.Provider
+	if model := llm.ModelName(appConfig); model != "" {
+		label = model
+	}
+	return startLabeledSpinner(label)
+}
+
+// startLabeledSpinner is startSpinner naming label as what is being contacted.
+func startLabeledSpinner(label string) *spinner {
+	if quiet || debugMode || dryRun || showPrompt || os.Getenv("TERM") == "dumb" || !escapesSupported || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil
+	}
+
+	s := &spinner{
+		label:   label,
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+func (s *spinner) run() {
+	defer s.wg.Done()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		elapsed := time.Since(s.started).Seconds()
+		_, _ = fmt.Fprintf(os.Stderr, "\r\033[K%s %s", spinnerFrames[frame%len(spinnerFrames)], i18n.T("contacting %s… %.1fs", s.label, elapsed))
+
+		select {
+		case <-s.done:
+			clearLine()
+			return
+		case <-interrupts:
+			clearLine()
+			_, _ = fmt.Fprintln(os.Stderr, i18n.T("Interrupted."))
+			os.Exit(130)
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop clears the status line. It is safe to call more than once, and on nil.
+func (s *spinner) stop() {
+	if s == nil {
+		return
+	}
+	s.stopped.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+}
+
+// stopOnChunk wraps onChunk so that the spinner is cleared before the first
+// streamed output is written.
+func (s *spinner) stopOnChunk(onChunk llm.ChunkFunc) llm.ChunkFunc {
+	if s == nil || onChunk == nil {
+		return onChunk
+	}
+	return func(text string) {
+		s.stop()
+		onChunk(text)
+	}
+}
+
+func clearLine() {
+	_, _ = fmt.Fprint(os.Stderr, "\r\033[K")
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/stats"
+)
+
+const (
+	defaultStatsTop = 10
+
+	// hourBarWidth is the width of the longest bar in the busiest-hours chart.
+	hourBarWidth = 40
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics about your shell history",
+	Long: `Computes statistics about your shell history locally, without any LLM call:
+the most used programs and commands, the directories you work in, your busiest
+hours, the programs that fail most often, the longest-running commands and the
+workflows you habitually follow (e.g. git checkout → npm install → npm test).
+With tips.enabled set, modern alternatives to the tools you use most are also
+recommended (see 'historai tips').
+
+Sections that need metadata your history source doesn't record (times, working
+directories, exit codes, durations) are skipped; the shell hook (see
+'historai init') records all of them.
+
+With --latency, shows how long historai itself takes instead: the median and
+90th and 99th percentiles of its invocations, by command and by model, and the
+median time spent in each stage (parsing history, retrieving context, waiting for
+the LLM or the daemon, and rendering). Invocations are timed in
+~/.local/state/historai/timings.jsonl; pass --timings to any command to see its own.
+
+Flags:
+  --since / -s : Only include commands run since then: today, yesterday, 12h, 3d, 2w or a date like 2024-05-01 (default: all history).
+  --top / -t   : How many items to show in each ranking (default: 10).
+  --latency    : Show historai's own latency instead of history statistics.
+
+Example:
+  historai stats
+  historai stats --since 1w --top 5
+  historai stats -o json | jq '.top_programs'
+  historai stats --latency --since 1d`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (since, top)
+		sinceValue, err := cmd.Flags().GetString("since")
+		if err != nil {
+			logger.Error("Failed to get 'since' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting since flag: %w", err)
+		}
+		top, err := cmd.Flags().GetInt("top")
+		if err != nil {
+			logger.Error("Failed to get 'top' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting top flag: %w", err)
+		}
+		if top < 1 {
+			return fmt.Errorf("--top must be at least 1")
+		}
+		latency, err := cmd.Flags().GetBool("latency")
+		if err != nil {
+			return fmt.Errorf("internal error getting latency flag: %w", err)
+		}
+		if latency {
+			return runLatencyStats(sinceValue, top)
+		}
+
+		// 2. Read history
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		entries, err := reader.ReadHistory(0)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		if sinceValue != "" {
+			since, err := parseSince(sinceValue, time.Now())
+			if err != nil {
+				return err
+			}
+			entries = entriesSince(entries, since)
+		}
+		if len(entries) == 0 {
+			if err := infof("No history entries found.\n"); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+
+		// 3. Compute and print the statistics
+		report := stats.Compute(entries, top)
+		switch outputFormat {
+		case outputText:
+			if err := printStats(os.Stdout, report); err != nil {
+				return err
+			}
+			if !appConfig.Tips.Enabled {
+				return nil
+			}
+			return printTipsSummary(os.Stdout, adviseTools(entries))
+		case outputJSONL:
+			return json.NewEncoder(os.Stdout).Encode(report)
+		default:
+			return printStructuredResult(outputFormat, report)
+		}
+	},
+}
+
+// entriesSince keeps the entries run at or after since. Entries without a timestamp
+// can't be placed in time and are dropped.
+func entriesSince(entries []history.HistoryEntry, since time.Time) []history.HistoryEntry {
+	var filtered []history.HistoryEntry
+	for _, entry := range entries {
+		if entry.Timestamp >= since.Unix() {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// printStats writes report as plain-text sections.
+func printStats(w io.Writer, report stats.Report) error {
+	title := color.New(color.Bold)
+	var b strings.Builder
+
+	b.WriteString(i18n.T("%d commands, %d unique", report.Total, report.Unique))
+	if report.First != nil && report.Last != nil {
+		b.WriteString(i18n.T(", from %s to %s", report.First.Format("2006-01-02"), report.Last.Format("2006-01-02")))
+	}
+	b.WriteString("\n")
+
+	writeCounts := func(heading string, counts []stats.Count) {
+		if len(counts) == 0 {
+			return
+		}
+		b.WriteString("\n" + title.Sprint(i18n.T(heading)) + "\n")
+		for _, count := range counts {
+			b.WriteString(fmt.Sprintf("%7d  %s\n", count.Count, count.Name))
+		}
+	}
+	writeCounts("Top programs", report.TopPrograms)
+	writeCounts("Top commands", report.TopCommands)
+	writeCounts("Top directories", report.TopDirectories)
+
+	if len(report.Hours) > 0 {
+		b.WriteString("\n" + title.Sprint(i18n.T("Busiest hours")) + "\n")
+		busiest := 0
+		for _, count := range report.Hours {
+			busiest = max(busiest, count)
+		}
+		for hour, count := range report.Hours {
+			bar := 0
+			if busiest > 0 {
+				bar = count * hourBarWidth / busiest
+			}
+			b.WriteString(fmt.Sprintf("  %02d:00 %-*s %d\n", hour, hourBarWidth, strings.Repeat("█", bar), count))
+		}
+	}
+
+	if len(report.FailureRates) > 0 {
+		b.WriteString("\n" + title.Sprint(i18n.T("Most failing programs")) + "\n")
+		for _, rate := range report.FailureRates {
+			b.WriteString(fmt.Sprintf("%6.0f%%  %s (%d/%d)\n", rate.Rate*100, rate.Program, rate.Failures, rate.Runs))
+		}
+	}
+
+	if len(report.Slowest) > 0 {
+		b.WriteString("\n" + title.Sprint(i18n.T("Longest-running commands")) + "\n")
+		for _, entry := range report.Slowest {
+			duration := (time.Duration(entry.DurationMS) * time.Millisecond).Round(time.Second / 10)
+			b.WriteString(fmt.Sprintf("%8s  %s\n", duration, entry.Command))
+		}
+	}
+
+	if len(report.Workflows) > 0 {
+		b.WriteString("\n" + title.Sprint(i18n.T("Frequent workflows")) + "\n")
+		for _, rule := range report.Workflows {
+			b.WriteString(fmt.Sprintf("%6.0f%%  %s (%d/%d)\n", rule.Confidence*100, strings.Join(rule.Steps(), " → "), rule.Count, rule.Runs))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// init adds the statsCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringP("since", "s", "", "Only include commands run since then: today, yesterday, 12h, 3d, 2w or a date")
+	statsCmd.Flags().IntP("top", "t", defaultStatsTop, "Number of items to show in each ranking")
+	statsCmd.Flags().Bool("latency", false, "Show how long historai's invocations take, by command, model and stage")
+}
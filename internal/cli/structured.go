@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// Supported values of the --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+
+	// outputJSONL streams one JSON object per command as the response arrives.
+	outputJSONL = "jsonl"
+)
+
+// Values of structuredResult.Status.
+const (
+	statusOK        = "ok"
+	statusNoResults = "no_results"
+
+	// statusEmpty marks an answer the model left empty, even when asked again, as
+	// opposed to one saying nothing matched.
+	statusEmpty = "empty"
+
+	// statusError marks a query of a batch that couldn't be answered.
+	statusError = "error"
+)
+
+// structuredResult is the machine-readable form of a find/suggest answer.
+type structuredResult struct {
+	Kind     string         `json:"kind" yaml:"kind"`
+	Query    string         `json:"query" yaml:"query"`
+	Status   string         `json:"status" yaml:"status"`
+	Message  string         `json:"message,omitempty" yaml:"message,omitempty"`
+	Commands []commandItem  `json:"commands" yaml:"commands"`
+	Metadata resultMetadata `json:"metadata" yaml:"metadata"`
+}
+
+// commandItem is a single command of the LLM response, ranked in response order.
+type commandItem struct {
+	Rank              int `json:"rank" yaml:"rank"`
+	llm.CommandResult `yaml:",inline"`
+}
+
+// resultMetadata describes how the result was produced.
+type resultMetadata struct {
+	Provider   string `json:"provider" yaml:"provider"`
+	Model      string `json:"model,omitempty" yaml:"model,omitempty"`
+	Source     string `json:"source" yaml:"source"`
+	DurationMS int64  `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// validateOutputFlags checks the values of the --output, --raw and --first flags.
+func validateOutputFlags() error {
+	switch outputFormat {
+	case outputText, outputJSON, outputYAML, outputJSONL, outputRaycast, outputAlfred:
+	default:
+		return fmt.Errorf("invalid --output %q (expected %s, %s, %s, %s, %s or %s)", outputFormat, outputText, outputJSON, outputYAML, outputJSONL, outputRaycast, outputAlfred)
+	}
+	if (rawOutput || firstOnly) && outputFormat != outputText {
+		return errors.New("--raw and --first cannot be combined with --output " + outputFormat)
+	}
+	return nil
+}
+
+// buildStructuredResult converts an LLM answer into a structuredResult.
+func buildStructuredResult(kind, query string, answer llm.CommandAnswer, started time.Time) structuredResult {
+	result := structuredResult{
+		Kind:     kind,
+		Query:    query,
+		Status:   statusOK,
+		Commands: []commandItem{},
+		Metadata: resultMetadata{
+			Provider:   appConfig.LLM.Provider,
+			Model:      llm.ModelName(appConfig),
+			Source:     appConfig.History.Source,
+			DurationMS: time.Since(started).Milliseconds(),
+		},
+	}
+
+	if len(answer.Commands) == 0 {
+		result.Status = statusNoResults
+		if answer.Empty {
+			result.Status = statusEmpty
+		}
+		result.Message = answer.Message
+		return result
+	}
+	result.Commands = rankedItems(answer.Commands)
+	return result
+}
+
+// rankedItems ranks commands in order.
+func rankedItems(commands []llm.CommandResult) []commandItem {
+	items := make([]commandItem, len(commands))
+	for i, command := range commands {
+		items[i] = commandItem{Rank: i + 1, CommandResult: command}
+	}
+	return items
+}
+
+// explanationItem wraps an explanation of command as a single result item.
+func explanationItem(command, explanation string) commandItem {
+	return commandItem{Rank: 1, CommandResult: llm.CommandResult{Command: command, Explanation: explanation}}
+}
+
+// buildExplanationResult converts a prose answer about out.query into a structuredResult.
+func buildExplanationResult(out commandOutput, explanation string) structuredResult {
+	result := buildStructuredResult(out.kind, out.query, llm.CommandAnswer{}, out.started)
+	result.Status = statusOK
+	result.Commands = []commandItem{explanationItem(out.query, explanation)}
+	return result
+}
+
+// streamedCommand is a single line of --output jsonl.
+type streamedCommand struct {
+	Kind  string `json:"kind"`
+	Query string `json:"query"`
+	commandItem
+}
+
+// commandStreamer writes each command to stdout as a JSON line as soon as the
+// response line holding it is complete.
+type commandStreamer struct {
+	kind     string
+	query    string
+	encoder  *json.Encoder
+	parser   llm.CommandParser
+	partial  strings.Builder
+	count    int
+	received bool
+	err      error
+}
+
+// newCommandStreamer returns a streamer when --output jsonl is selected, nil otherwise.
+func newCommandStreamer(kind, query string) *commandStreamer {
+	if outputFormat != outputJSONL {
+		return nil
+	}
+	return &commandStreamer{kind: kind, query: query, encoder: json.NewEncoder(os.Stdout)}
+}
+
+// chunkFunc returns the callback to pass to the engine, or nil for a nil streamer.
+func (s *commandStreamer) chunkFunc() llm.ChunkFunc {
+	if s == nil {
+		return nil
+	}
+	return s.write
+}
+
+// write consumes a piece of response text, emitting every command it completes.
+func (s *commandStreamer) write(text string) {
+	s.received = true
+	s.partial.WriteString(text)
+	buffered := s.partial.String()
+	end := strings.LastIndex(buffered, "\n")
+	if end < 0 {
+		return
+	}
+	for _, line := range strings.Split(buffered[:end], "\n") {
+		s.emitLine(line)
+	}
+	s.partial.Reset()
+	s.partial.WriteString(buffered[end+1:])
+}
+
+// finish emits whatever is left of the response and reports how many commands were
+// written. answer is the full response; its commands are written in one go if no
+// chunks were received, e.g. when the answer came from the daemon or a
+// non-streaming provider.
+func (s *commandStreamer) finish(answer llm.CommandAnswer) (int, error) {
+	if !s.received {
+		for _, command := range answer.Commands {
+			s.emit(command)
+		}
+	}
+	s.emitLine(s.partial.String())
+	s.partial.Reset()
+	return s.count, s.err
+}
+
+func (s *commandStreamer) emitLine(line string) {
+	if command, ok := s.parser.AddLine(line); ok {
+		s.emit(command)
+	}
+}
+
+func (s *commandStreamer) emit(command llm.CommandResult) {
+	if s.err != nil {
+		return
+	}
+	s.count++
+	s.err = s.encodeItem(commandItem{Rank: s.count, CommandResult: command})
+}
+
+// encodeItem writes a single item as a JSON line.
+func (s *commandStreamer) encodeItem(item commandItem) error {
+	if err := s.encoder.Encode(streamedCommand{Kind: s.kind, Query: s.query, commandItem: item}); err != nil {
+		return fmt.Errorf("failed to encode JSON output: %w", err)
+	}
+	return nil
+}
+
+// printStructuredResult writes result to stdout in the requested format.
+func printStructuredResult(format string, result any) error {
+	switch format {
+	case outputYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode YAML output: %w", err)
+		}
+		return encoder.Close()
+	default:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode JSON output: %w", err)
+		}
+		return nil
+	}
+}
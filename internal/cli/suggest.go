@@ -6,14 +6,19 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"os"
+	"time"
 
 	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/daemon"
+	"github.com/sanspareilsmyn/historai/internal/engine"
 	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
 	"github.com/sanspareilsmyn/historai/internal/llm"
 )
 
 const (
-	defaultSuggestHistoryContextLimit = 100
+	defaultSuggestHistoryContextLimit = config.DefaultSuggestLimit
 )
 
 // suggestCmd represents the suggest command
@@ -23,41 +28,70 @@ var suggestCmd = &cobra.Command{
 	Long: `Asks an LLM to suggest shell commands for the task you describe.
 It can optionally use your recent shell history (currently Zsh) as context
 to potentially provide more relevant suggestions based on tools you typically use.
+Commands saved with 'historai save' are always included, and preferred when they fit.
+
+When the AI provider can't be used, because there is no network, no API key or an
+outage, suggest falls back to the history commands matching the task's keywords,
+labeled as not coming from the AI.
 
 You can control the history context using flags:
-  --limit / -n        : How many recent history entries to provide as context (default: 100).
+  --limit / -n        : How many history entries to provide as context, picked by context.selection (default: 100, or suggest.limit from the config file).
   --no-history-context: Disable using shell history as context for the suggestion.
+  --with-ls           : Also send the names of the files in the current directory (at most 100), so the command uses the right ones.
+  --history           : Use the commands in this file, or stdin for "-", as context instead of your shell history,
+                        in any format 'historai import' understands. They're sent without this machine's context.
 
 Example:
   historai suggest "how to convert a video file to an animated gif"
   historai suggest --limit 200 "command to find all python files modified today"
-  historai suggest --no-history-context "recursively remove all .DS_Store files"`,
+  historai suggest --no-history-context "recursively remove all .DS_Store files"
+  historai suggest --with-ls "extract the tarball into a new directory"
+  ssh build-server 'cat ~/.bash_history' | historai suggest --history - "restart the CI runner"`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
 		if query == "" {
-			return errors.New("task description cannot be empty")
+			return errors.New(i18n.T("task description cannot be empty"))
 		}
 
-		// 1. Parse and validate flags (limit, no-history-context)
-		limit, noHistoryContext, err := parseSuggestFlags(cmd)
+		// 1. Parse and validate flags (limit, no-history-context, with-ls)
+		limit, noHistoryContext, withListing, err := parseSuggestFlags(cmd)
+		if err != nil {
+			return err
+		}
+		entries, err := parseSuggestHistoryInput(cmd, noHistoryContext, withListing)
 		if err != nil {
 			return err
 		}
 
 		// 2. Execute the core suggestion logic
-		suggestions, err := runSuggestCore(logger, query, limit, noHistoryContext)
+		started := time.Now()
+		stream := newCommandStreamer("suggest", query)
+		spin := startSpinner()
+		suggestions, err := runSuggestCore(logger, query, limit, noHistoryContext, withListing, entries, spin.stopOnChunk(stream.chunkFunc()))
+		spin.stop()
+		header := i18n.T("--- Suggested Commands ---")
+		offline := !dryRun && llm.IsUnavailable(err) && !noHistoryContext
+		if offline {
+			// 3. Without the provider, fall back to history commands matching the task
+			suggestions, err = searchOffline(logger, err, query, "", limit, entries)
+			header = i18n.T("--- Matching Commands from History (keyword search, not AI) ---")
+		}
 		if err != nil {
 			return err
 		}
 
-		// 3. Print Header to Stderr & Result to Stdout
-		err = printCommandOutput(
-			logger,
-			suggestions,
-			"--- Suggested Commands ---",
-			"No suggestions generated or suggestions indicate failure.",
-		)
+		// 4. Print Header to Stderr & Result to Stdout
+		err = printResult(logger, commandOutput{
+			kind:         "suggest",
+			query:        query,
+			answer:       suggestions,
+			header:       header,
+			logOnFailure: "No suggestions generated or suggestions indicate failure.",
+			started:      started,
+			stream:       stream,
+			offline:      offline,
+		})
 		if err != nil {
 			return err
 		}
@@ -66,13 +100,16 @@ Example:
 }
 
 // parseSuggestFlags extracts and validates flags specific to the suggest command.
-func parseSuggestFlags(cmd *cobra.Command) (limit int, noHistoryContext bool, err error) {
+func parseSuggestFlags(cmd *cobra.Command) (limit int, noHistoryContext, withListing bool, err error) {
 	limit, err = cmd.Flags().GetInt("limit")
 	if err != nil {
 		logger.Error("Failed to get 'limit' flag value", zap.Error(err))
 		err = fmt.Errorf("internal error getting limit flag: %w", err)
 		return
 	}
+	if !cmd.Flags().Changed("limit") {
+		limit = appConfig.Suggest.Limit
+	}
 
 	noHistoryContext, err = cmd.Flags().GetBool("no-history-context")
 	if err != nil {
@@ -81,66 +118,76 @@ func parseSuggestFlags(cmd *cobra.Command) (limit int, noHistoryContext bool, er
 		return
 	}
 
-	return limit, noHistoryContext, nil
+	withListing, err = cmd.Flags().GetBool("with-ls")
+	if err != nil {
+		logger.Error("Failed to get 'with-ls' flag value", zap.Error(err))
+		err = fmt.Errorf("internal error getting with-ls flag: %w", err)
+		return
+	}
+
+	return limit, noHistoryContext, withListing, nil
 }
 
-// runSuggestCore executes the main logic: config, optional history, LLM interaction.
-func runSuggestCore(logger *zap.Logger, query string, limit int, noHistoryContext bool) (string, error) {
-	// 1. Load Configuration
-	cfg, err := config.LoadConfig(logger)
+// parseSuggestHistoryInput reads the history given with --history. Like a history
+// recorded elsewhere, it is sent without this machine's context, so it can't be
+// combined with --with-ls.
+func parseSuggestHistoryInput(cmd *cobra.Command, noHistoryContext, withListing bool) ([]history.HistoryEntry, error) {
+	path, err := cmd.Flags().GetString("history")
 	if err != nil {
-		return "", fmt.Errorf("failed to load configuration: %w", err)
+		return nil, fmt.Errorf("internal error getting history flag: %w", err)
+	}
+	switch {
+	case path == "":
+		return nil, nil
+	case noHistoryContext:
+		return nil, errors.New(i18n.T("--no-history-context cannot be combined with --history"))
+	case withListing:
+		return nil, errors.New(i18n.T("--with-ls cannot be combined with --history"))
 	}
+	return readHistoryInput(cmd)
+}
 
-	// 2. Read Shell History (Optional, for Context)
-	var historyEntries []history.HistoryEntry
-	if !noHistoryContext {
-		// TODO: Replace with multi-shell logic later
-		historyReader, err := history.NewZshHistoryReader(logger)
-		if err != nil {
-			logger.Error("Failed to initialize Zsh history reader for context", zap.Error(err))
-			return "", fmt.Errorf("failed to initialize history reader for context: %w", err)
-		}
+// runSuggestCore executes the main logic, preferring a running daemon over a cold start.
+// Non-nil entries are used as context instead of the configured history.
+// onChunk, if set, receives the response as it streams in from the LLM.
+func runSuggestCore(logger *zap.Logger, query string, limit int, noHistoryContext, withListing bool, entries []history.HistoryEntry, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	// A daemon started elsewhere needs the working directory for the git context and
+	// listing, and the shell's variables for the cloud context.
+	dir, err := os.Getwd()
+	if err != nil {
+		logger.Debug("Could not determine the working directory", zap.Error(err))
+	}
+	env := engine.CloudEnv()
 
-		// Call ReadHistory with only limit, matching the updated interface/implementation
-		historyEntries, err = historyReader.ReadHistory(limit)
-		if err != nil {
-			logger.Error("Failed to read history for context", zap.Error(err))
-			return "", fmt.Errorf("failed to read history for context: %w", err)
-		}
-		if len(historyEntries) == 0 {
-			logger.Warn("No history entries found matching the criteria (limit) to provide as context.")
+	// 1. Try the daemon, which keeps parsed history and a warm LLM client
+	req := daemon.Request{Op: daemon.OpSuggest, Query: query, Limit: limit, NoHistoryContext: noHistoryContext, Dir: dir, Listing: withListing, Env: env}
+	if entries == nil {
+		if suggestions, ok, err := tryDaemon(logger, req); ok {
+			return suggestions, err
 		}
-	} else {
-		logger.Debug("Skipping history reading as --no-history-context flag was provided.")
 	}
 
-	// 3. Initialize LLM Client
-	ctx := context.Background()
-	logger.Debug("Initializing LLM client (Gemini)...")
-	llmClient, err := llm.NewGeminiClient(ctx, logger, cfg.GoogleAPIKey)
+	// 2. Initialize Engine (config, history, LLM client)
+	eng, err := newEngine(context.Background(), engine.Options{History: entries})
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize LLM client: %w", err)
+		return llm.CommandAnswer{}, err
 	}
 	defer func() {
-		if closeErr := llmClient.Close(); closeErr != nil {
+		if closeErr := eng.Close(); closeErr != nil {
 			logger.Error("Failed to close LLM client", zap.Error(closeErr))
 		}
 	}()
 
-	// 4. Call LLM API to suggest commands
-	suggestions, err := llmClient.SuggestCommands(query, historyEntries)
-	if err != nil {
-		return "", fmt.Errorf("failed to get suggestions from LLM: %w", err)
-	}
-
-	return suggestions, nil
+	// 3. Call LLM API to suggest commands
+	return eng.SuggestStream(context.Background(), query, limit, noHistoryContext, engine.SuggestContext{Dir: dir, Listing: withListing, Env: env}, onChunk)
 }
 
 // init adds the suggestCmd and its flags to the rootCmd.
 func init() {
 	rootCmd.AddCommand(suggestCmd)
 
-	suggestCmd.Flags().IntP("limit", "n", defaultSuggestHistoryContextLimit, "Limit the number of most recent history entries to provide as context")
+	suggestCmd.Flags().IntP("limit", "n", defaultSuggestHistoryContextLimit, "Limit the number of history entries to provide as context, picked by context.selection")
 	suggestCmd.Flags().Bool("no-history-context", false, "Do not use shell history as context for suggestions")
+	suggestCmd.Flags().Bool("with-ls", false, "Also send the names of the files in the current directory")
+	suggestCmd.Flags().String("history", "", historyFlagUsage)
 }
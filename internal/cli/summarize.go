@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// summarizeCmd represents the summarize command
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize",
+	Short: "Summarize what you worked on from your shell history using AI",
+	Long: `Groups the commands you ran in a period by project (the git repository or
+directory they were run in) and asks an LLM for a short summary of what you
+worked on, e.g. for a standup or a timesheet.
+
+Commands need timestamps, and working directories to be grouped by project;
+the shell hook (see 'historai init') records both.
+
+Flags:
+  --since / -s : Start of the period: today, yesterday, 12h, 3d, 2w or a date like 2024-05-01 (default: yesterday).
+
+Example:
+  historai summarize
+  historai summarize --since today
+  historai summarize --since 1w --lang German`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (since)
+		sinceValue, err := cmd.Flags().GetString("since")
+		if err != nil {
+			logger.Error("Failed to get 'since' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting since flag: %w", err)
+		}
+		since, err := parseSince(sinceValue, time.Now())
+		if err != nil {
+			return err
+		}
+		period := describeSince(sinceValue, since)
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Ask the LLM for a summary
+		started := time.Now()
+		spin := startSpinner()
+		summary, err := eng.Summarize(context.Background(), since, period)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 4. Print Header to Stderr & Summary to Stdout
+		return printExplanation(logger, commandOutput{
+			kind:         "summarize",
+			query:        period,
+			output:       summary,
+			noAnswer:     "(AI could not summarize this activity)",
+			header:       i18n.T("--- Summary %s ---", period),
+			logOnFailure: "No summary generated or response indicates failure.",
+			started:      started,
+		})
+	},
+}
+
+// init adds the summarizeCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(summarizeCmd)
+
+	summarizeCmd.Flags().StringP("since", "s", "yesterday", "Start of the period to summarize: today, yesterday, 12h, 3d, 2w or a date")
+}
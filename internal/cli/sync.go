@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/remote"
+)
+
+// syncObjectName is the name of the encrypted log in the remote store.
+const syncObjectName = "history.jsonl.age"
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync your history log with an encrypted remote copy",
+	Long: `Merges historai's own history log with a copy kept in cloud storage, so your
+history follows you across machines. The copy is encrypted on this machine with
+age before upload, so the storage provider can't read it.
+
+Each entry is labeled with the machine it ran on, as with 'historai import --host',
+and merging skips entries already present, so sync is safe to run from cron.
+Commands removed with 'historai forget' aren't pulled back, and the next push
+removes them from the remote copy.
+
+The remote location is set with sync.url in the config file:
+  s3://bucket/prefix         : Amazon S3 (AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY),
+                               or any S3-compatible service with sync.endpoint.
+  gs://bucket/prefix         : Google Cloud Storage (Application Default Credentials).
+  https://host/path          : WebDAV, e.g. Nextcloud (sync.username, $HISTORAI_WEBDAV_PASSWORD).
+  git+ssh://host/repo.git    : A private git repository, also git+https:// and git+file://.
+                               Add #branch to use a branch other than main.
+  file:///path               : A local directory, e.g. a mounted network drive.
+
+Run 'historai sync keygen' once, then copy the key file it prints to your other
+machines. Without the key, the remote copy can't be read by anyone, including you.
+
+Flags:
+  --pull : Only download and merge the remote copy.
+  --push : Only upload the local log.
+
+Example:
+  historai config set sync.url s3://my-bucket/historai
+  historai sync keygen
+  historai sync`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		flags := cmd.Flags()
+		pullOnly, err := flags.GetBool("pull")
+		if err != nil {
+			return fmt.Errorf("internal error getting pull flag: %w", err)
+		}
+		pushOnly, err := flags.GetBool("push")
+		if err != nil {
+			return fmt.Errorf("internal error getting push flag: %w", err)
+		}
+
+		// 2. Open the key and the remote store
+		ctx := context.Background()
+		identity, err := loadSyncIdentity()
+		if err != nil {
+			return err
+		}
+		store, err := remote.New(ctx, logger, appConfig)
+		if err != nil {
+			return configError(err)
+		}
+		target, err := defaultImportTarget()
+		if err != nil {
+			return err
+		}
+		s := &syncer{store: store, identity: identity, target: target}
+
+		// 3. Pull, then push
+		if !pushOnly {
+			if err := s.pull(ctx); err != nil {
+				return err
+			}
+		}
+		if !pullOnly {
+			return s.push(ctx)
+		}
+		return nil
+	},
+}
+
+// syncKeygenCmd represents the sync keygen command
+var syncKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create the key the synced history is encrypted with",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := remote.IdentityFile(appConfig.Sync.Identity)
+		if err != nil {
+			return err
+		}
+		identity, err := remote.GenerateIdentity(path)
+		if err != nil {
+			return err
+		}
+		return infof("Created sync key %s (public key %s).\nCopy it to the same path, or to sync.identity, on your other machines and keep a backup: without it the synced history can't be decrypted.\n", path, identity.Recipient())
+	},
+}
+
+// loadSyncIdentity reads the configured age identity.
+func loadSyncIdentity() (*age.X25519Identity, error) {
+	path, err := remote.IdentityFile(appConfig.Sync.Identity)
+	if err != nil {
+		return nil, err
+	}
+	return remote.LoadIdentity(path)
+}
+
+// syncer merges the local log at target with the encrypted copy in store.
+type syncer struct {
+	store    remote.Store
+	identity *age.X25519Identity
+	target   string
+}
+
+// pull appends the remote entries missing from the local log, except those
+// forgotten with 'historai forget'.
+func (s *syncer) pull(ctx context.Context) error {
+	remoteEntries, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	labelHosts(remoteEntries, "")
+	local, err := readRecordedLog(s.target)
+	if err != nil {
+		return err
+	}
+	forgotten, err := history.LoadForgotten(s.target)
+	if err != nil {
+		return err
+	}
+	added := forgotten.Drop(history.Merge(local, remoteEntries))
+	logger.Debug("Pulled remote history", zap.Int("remote_count", len(remoteEntries)), zap.Int("added_count", len(added)))
+	if len(added) > 0 {
+		if err := history.AppendRecords(s.target, added); err != nil {
+			return err
+		}
+	}
+	return infof("Pulled %d new entries from %s\n", len(added), s.store)
+}
+
+// push uploads the union of the remote copy and the local log, with local entries
+// labeled with this machine's host name, leaving out the entries forgotten here.
+// Nothing is uploaded when nothing changed.
+func (s *syncer) push(ctx context.Context) error {
+	remoteEntries, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	local, err := readRecordedLog(s.target)
+	if err != nil {
+		return err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to determine host name: %w", err)
+	}
+	for i := range local {
+		if local[i].Host == "" {
+			local[i].Host = hostname
+		}
+	}
+	forgotten, err := history.LoadForgotten(s.target)
+	if err != nil {
+		return err
+	}
+	kept := forgotten.Drop(remoteEntries)
+	removed := len(remoteEntries) - len(kept)
+	added := history.Merge(kept, local)
+	if len(added) == 0 && removed == 0 {
+		return infof("Nothing new to push to %s\n", s.store)
+	}
+
+	merged := append(kept, added...)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp < merged[j].Timestamp })
+	var plain bytes.Buffer
+	if err := history.Export(&plain, history.FormatJSONL, merged); err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+	encrypted, err := remote.Encrypt(plain.Bytes(), s.identity)
+	if err != nil {
+		return err
+	}
+	logger.Debug("Pushing history", zap.Int("entries_count", len(merged)), zap.Int("encrypted_bytes", len(encrypted)))
+	if err := s.store.Put(ctx, syncObjectName, encrypted); err != nil {
+		return fmt.Errorf("failed to upload history: %w", err)
+	}
+	if removed > 0 {
+		if err := infof("Removed %d forgotten entries from %s\n", removed, s.store); err != nil {
+			return err
+		}
+	}
+	return infof("Pushed %d new entries to %s\n", len(added), s.store)
+}
+
+// fetch downloads and decrypts the remote copy; a missing copy has no entries.
+func (s *syncer) fetch(ctx context.Context) ([]history.HistoryEntry, error) {
+	encrypted, err := s.store.Get(ctx, syncObjectName)
+	if errors.Is(err, remote.ErrNotFound) {
+		logger.Debug("No remote history yet", zap.Stringer("store", s.store))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download history: %w", err)
+	}
+	plain, err := remote.Decrypt(encrypted, s.identity)
+	if err != nil {
+		return nil, err
+	}
+	return history.Parse(logger, bytes.NewReader(plain), history.FormatJSONL)
+}
+
+// init adds the syncCmd, its subcommands and flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncKeygenCmd)
+
+	syncCmd.Flags().Bool("pull", false, "Only download and merge the remote copy")
+	syncCmd.Flags().Bool("push", false, "Only upload the local log")
+	syncCmd.MarkFlagsMutuallyExclusive("pull", "push")
+}
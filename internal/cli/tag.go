@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag commands in your history",
+	Long: `Labels commands with tags such as "deploy" or "k8s", turning your history into
+a lightweight knowledge base. Tags are kept in the same sidecar file as notes
+(see 'historai annotate'); your history file is not modified.
+
+'find --tag <tag>' searches only the commands with that tag, and tags are shown
+to the LLM alongside each command, so queries can mention them.
+
+<entry> is a command line, or a number N for the Nth most recent command (1 is the
+last one).
+
+Example:
+  historai tag add deploy 1
+  historai tag add k8s "kubectl rollout restart deploy/api"
+  historai tag list
+  historai tag list deploy
+  historai find --tag deploy "the one for the EU cluster"`,
+}
+
+// tagAddCmd represents the tag add command
+var tagAddCmd = &cobra.Command{
+	Use:   "add <tag> <entry>",
+	Short: "Add a tag to a command",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeTag(args[0], args[1], true)
+	},
+}
+
+// tagRemoveCmd represents the tag remove command
+var tagRemoveCmd = &cobra.Command{
+	Use:     "remove <tag> <entry>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a tag from a command",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeTag(args[0], args[1], false)
+	},
+}
+
+// tagListCmd represents the tag list command
+var tagListCmd = &cobra.Command{
+	Use:   "list [tag]",
+	Short: "List tags, or the commands with a tag",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := annotations.Load("")
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return printTagCounts(store.TagCounts())
+		}
+
+		tag, err := annotations.NormalizeTag(args[0])
+		if err != nil {
+			return err
+		}
+		commands := store.Tagged(tag)
+		if len(commands) == 0 {
+			return infof("No commands are tagged %s.\n", tag)
+		}
+		for _, command := range commands {
+			if _, err := fmt.Fprintln(os.Stdout, command); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// changeTag adds tag to, or removes it from, the command entryRef resolves to.
+func changeTag(tagArg, entryRef string, add bool) error {
+	tag, err := annotations.NormalizeTag(tagArg)
+	if err != nil {
+		return err
+	}
+	command, err := resolveEntry(entryRef)
+	if err != nil {
+		return err
+	}
+	store, err := annotations.Load("")
+	if err != nil {
+		return err
+	}
+
+	if add && !store.AddTag(command, tag) {
+		return infof("%s is already tagged %s\n", command, tag)
+	}
+	if !add && !store.RemoveTag(command, tag) {
+		return infof("%s is not tagged %s\n", command, tag)
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	if add {
+		return infof("Tagged %s with %s\n", command, tag)
+	}
+	return infof("Removed tag %s from %s\n", tag, command)
+}
+
+// printTagCounts lists the tags in use with the number of commands carrying each.
+func printTagCounts(counts map[string]int) error {
+	if len(counts) == 0 {
+		return infof("No tags yet. Add one with 'historai tag add <tag> <entry>'.\n")
+	}
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	countColor := color.New(color.Faint)
+	var b strings.Builder
+	for _, tag := range tags {
+		b.WriteString(fmt.Sprintf("%s  %s\n", tag, countColor.Sprintf("(%d)", counts[tag])))
+	}
+	_, err := fmt.Fprint(os.Stdout, b.String())
+	return err
+}
+
+// init adds the tagCmd and its subcommands to the rootCmd.
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagAddCmd, tagRemoveCmd, tagListCmd)
+}
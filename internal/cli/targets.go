@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/workflow"
+)
+
+const (
+	defaultTargetsTop = 5
+)
+
+// buildFiles maps the --format values to the file the targets belong in.
+var buildFiles = map[string]string{
+	llm.BuildFormatMake: "Makefile",
+	llm.BuildFormatJust: "justfile",
+}
+
+// targetsCmd represents the targets command
+var targetsCmd = &cobra.Command{
+	Use:   "targets",
+	Short: "Generate Makefile or justfile targets from workflows you repeat",
+	Long: `Finds series of commands you keep running one after the other (e.g. build,
+tag and push an image) in your history, and asks an LLM to turn them into
+Makefile or justfile targets with descriptive names and comments.
+
+The sequences are detected locally; only the repeated commands are sent to the
+LLM. Run it from the project the targets are for, ideally with --since covering
+recent work in that project.
+
+Flags:
+  --format / -f : make or just (default: just if a justfile exists here, else make).
+  --since / -s  : Only look at commands run since today, yesterday, 12h, 3d, 2w or a date like 2024-05-01.
+  --top / -t    : How many workflows to turn into targets (default: 5).
+  --write / -w  : Append the targets to ./Makefile or ./justfile after confirmation.
+  --yes / -y    : Don't ask for confirmation before writing.
+
+Example:
+  historai targets
+  historai targets --since 2w --format just
+  historai targets --write`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parseTargetsFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Find the repeated workflows
+		entries, err := eng.History(0)
+		if err != nil {
+			return err
+		}
+		if !opts.since.IsZero() {
+			entries = entriesSince(entries, opts.since)
+		}
+		sequences := workflow.Sequences(entries, opts.top)
+		logger.Debug("Found repeated command sequences", zap.Int("sequences_count", len(sequences)))
+		if len(sequences) == 0 {
+			if err := infof("No repeated command sequences found.\n"); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+		if err := printSequences(sequences); err != nil {
+			return err
+		}
+
+		// 4. Ask the LLM for the targets
+		workflows := make([]llm.Workflow, len(sequences))
+		for i, sequence := range sequences {
+			workflows[i] = llm.Workflow{Commands: sequence.Commands, Count: sequence.Count}
+		}
+		started := time.Now()
+		spin := startSpinner()
+		targets, err := eng.BuildTargets(context.Background(), workflows, opts.format)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+		if outputFormat != outputText || strings.TrimSpace(targets) == "" {
+			return printExplanation(logger, commandOutput{
+				kind:         "targets",
+				query:        opts.format,
+				output:       targets,
+				noAnswer:     "(AI could not write build targets)",
+				logOnFailure: "No build targets generated or response indicates failure.",
+				started:      started,
+			})
+		}
+		targets = strings.TrimSpace(targets) + "\n"
+		if opts.format == llm.BuildFormatMake {
+			targets = tabIndentRecipes(targets)
+		}
+
+		// 5. Print the targets or append them to the build file
+		file := buildFiles[opts.format]
+		if !opts.write {
+			if !quiet && !rawOutput {
+				if _, err := color.New(color.FgYellow).Fprintln(os.Stderr, "\n"+i18n.T("--- %s targets ---", file)); err != nil {
+					return err
+				}
+			}
+			_, err := fmt.Fprint(os.Stdout, targets)
+			return err
+		}
+		if !opts.yes {
+			if _, err := fmt.Fprint(os.Stderr, "\n"+targets+"\n"); err != nil {
+				return err
+			}
+			ok, err := confirm(i18n.T("Append these targets to %s?", file))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		return appendBuildFile(file, targets)
+	},
+}
+
+// targetsOptions holds the parsed flags of the targets command.
+type targetsOptions struct {
+	format string
+	since  time.Time
+	top    int
+	write  bool
+	yes    bool
+}
+
+// parseTargetsFlags extracts and validates flags specific to the targets command.
+func parseTargetsFlags(cmd *cobra.Command) (targetsOptions, error) {
+	var opts targetsOptions
+	var err error
+	flags := cmd.Flags()
+
+	if opts.format, err = flags.GetString("format"); err != nil {
+		return opts, fmt.Errorf("internal error getting format flag: %w", err)
+	}
+	if opts.format == "" {
+		opts.format = llm.BuildFormatMake
+		if _, err := os.Stat(buildFiles[llm.BuildFormatJust]); err == nil {
+			opts.format = llm.BuildFormatJust
+		}
+	}
+	if _, ok := buildFiles[opts.format]; !ok {
+		return opts, errors.New(i18n.T("invalid --format %q (use make or just)", opts.format))
+	}
+	sinceValue, err := flags.GetString("since")
+	if err != nil {
+		return opts, fmt.Errorf("internal error getting since flag: %w", err)
+	}
+	if sinceValue != "" {
+		if opts.since, err = parseSince(sinceValue, time.Now()); err != nil {
+			return opts, err
+		}
+	}
+	if opts.top, err = flags.GetInt("top"); err != nil {
+		return opts, fmt.Errorf("internal error getting top flag: %w", err)
+	}
+	if opts.top < 1 {
+		return opts, fmt.Errorf("--top must be at least 1")
+	}
+	if opts.write, err = flags.GetBool("write"); err != nil {
+		return opts, fmt.Errorf("internal error getting write flag: %w", err)
+	}
+	if opts.yes, err = flags.GetBool("yes"); err != nil {
+		return opts, fmt.Errorf("internal error getting yes flag: %w", err)
+	}
+	return opts, nil
+}
+
+// printSequences lists the detected workflows on stderr.
+func printSequences(sequences []workflow.Sequence) error {
+	if quiet {
+		return nil
+	}
+	countColor := color.New(color.Faint)
+	var b strings.Builder
+	b.WriteString(i18n.T("Repeated workflows:") + "\n")
+	for _, sequence := range sequences {
+		b.WriteString(fmt.Sprintf("%s  %s\n", countColor.Sprintf("%4d×", sequence.Count), strings.Join(sequence.Commands, " → ")))
+	}
+	_, err := fmt.Fprint(os.Stderr, b.String())
+	return err
+}
+
+// tabIndentRecipes replaces the leading spaces of indented lines with a tab, as make
+// requires tabs before recipe lines and models often write spaces.
+func tabIndentRecipes(makefile string) string {
+	lines := strings.Split(makefile, "\n")
+	for i, line := range lines {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" && trimmed != line {
+			lines[i] = "\t" + trimmed
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendBuildFile appends targets to file, creating it if needed.
+func appendBuildFile(file, targets string) error {
+	existing, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	if len(existing) > 0 {
+		separator := "\n"
+		if !strings.HasSuffix(string(existing), "\n") {
+			separator = "\n\n"
+		}
+		targets = separator + targets
+	}
+	if _, err := f.WriteString(targets); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return infof("Appended the targets to %s\n", file)
+}
+
+// init adds the targetsCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(targetsCmd)
+
+	targetsCmd.Flags().StringP("format", "f", "", "Build file format: make or just (default: just if a justfile exists, else make)")
+	targetsCmd.Flags().StringP("since", "s", "", "Only look at commands run since today, yesterday, 12h, 3d, 2w or a date")
+	targetsCmd.Flags().IntP("top", "t", defaultTargetsTop, "Number of workflows to turn into targets")
+	targetsCmd.Flags().BoolP("write", "w", false, "Append the targets to ./Makefile or ./justfile after confirmation")
+	targetsCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before writing")
+}
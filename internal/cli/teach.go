@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	defaultTeachCount        = 5
+	defaultTeachHistoryLimit = config.DefaultFindLimit
+)
+
+// teachCmd represents the teach command
+var teachCmd = &cobra.Command{
+	Use:   "teach",
+	Short: "Quiz yourself on the flags of commands from your history",
+	Long: `Starts a short quiz on commands you actually ran: an LLM picks commands from
+your history and asks what one of their flags or arguments does. Type your answer
+and it is graded, with an explanation; press Enter without an answer to see the
+answer, or Ctrl-D to stop.
+
+Flags:
+  --count / -c : How many questions to ask (default: 5).
+  --limit / -n : How many recent history entries to pick commands from (default: 300, or find.limit from the config file).
+
+Example:
+  historai teach
+  historai teach --count 10 --limit 1000`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		count, err := cmd.Flags().GetInt("count")
+		if err != nil {
+			return fmt.Errorf("internal error getting count flag: %w", err)
+		}
+		if count < 1 {
+			return fmt.Errorf("--count must be at least 1")
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("internal error getting limit flag: %w", err)
+		}
+		if !cmd.Flags().Changed("limit") {
+			limit = appConfig.Find.Limit
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return errors.New(i18n.T("stdin is not a terminal; teach is interactive"))
+		}
+
+		// 2. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 3. Ask the LLM for the questions
+		spin := startSpinner()
+		questions, err := eng.Quiz(context.Background(), count, limit)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+		logger.Debug("Quiz written", zap.Int("questions_count", len(questions)))
+		if len(questions) == 0 {
+			if err := infof("No commands with flags to ask about in your history.\n"); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+
+		// 4. Ask them one by one
+		return runQuiz(eng, questions)
+	},
+}
+
+// runQuiz asks questions on the terminal, grades the answers and prints the score.
+func runQuiz(eng *engine.Engine, questions []llm.QuizQuestion) error {
+	titleColor := color.New(color.FgYellow)
+	faint := color.New(color.Faint)
+	score, asked := 0, 0
+	for i, question := range questions {
+		command := question.Command
+		if !color.NoColor {
+			if highlighted, err := highlightShell(command); err == nil {
+				command = strings.TrimRight(highlighted, "\n")
+			}
+		}
+		if _, err := fmt.Fprintf(os.Stderr, "\n%s\n  %s\n%s\n> ", titleColor.Sprint(i18n.T("Question %d/%d", i+1, len(questions))), command, question.Question); err != nil {
+			return err
+		}
+		answer, err := readLine(os.Stdin)
+		if err != nil && answer == "" {
+			// Ctrl-D ends the quiz early.
+			if _, err := fmt.Fprintln(os.Stderr); err != nil {
+				return err
+			}
+			break
+		}
+		asked++
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if _, err := fmt.Fprintln(os.Stderr, faint.Sprint(i18n.T("Answer:"))+" "+question.Answer); err != nil {
+				return err
+			}
+			continue
+		}
+
+		spin := startSpinner()
+		grade, err := eng.GradeAnswer(context.Background(), question, answer)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+		if err := printGrade(grade, question); err != nil {
+			return err
+		}
+		if grade.Correct {
+			score++
+		}
+	}
+	if asked == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintln(os.Stderr, "\n"+color.New(color.Bold).Sprint(i18n.T("Score: %d/%d", score, asked)))
+	return err
+}
+
+// printGrade prints the verdict on an answer with the feedback, and the expected
+// answer when the answer was wrong.
+func printGrade(grade llm.QuizGrade, question llm.QuizQuestion) error {
+	feedback := strings.TrimSpace(grade.Feedback)
+	if feedback == "" {
+		feedback = i18n.T("(AI could not grade this answer)")
+	}
+	var b strings.Builder
+	if grade.Correct {
+		b.WriteString(color.New(color.FgGreen).Sprint("✓ "+i18n.T("Correct!")) + " " + feedback + "\n")
+	} else {
+		b.WriteString(color.New(color.FgRed).Sprint("✗ "+i18n.T("Not quite.")) + " " + feedback + "\n")
+		b.WriteString(color.New(color.Faint).Sprint(i18n.T("Answer:")) + " " + question.Answer + "\n")
+	}
+	_, err := fmt.Fprint(os.Stderr, b.String())
+	return err
+}
+
+// init adds the teachCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(teachCmd)
+
+	teachCmd.Flags().IntP("count", "c", defaultTeachCount, "Number of questions to ask")
+	teachCmd.Flags().IntP("limit", "n", defaultTeachHistoryLimit, "Limit the number of most recent history entries to pick commands from")
+}
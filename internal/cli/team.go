@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/team"
+)
+
+// teamCmd represents the team command
+var teamCmd = &cobra.Command{
+	Use:   "team",
+	Short: "Use the snippets and runbooks your team shares in a git repository",
+	Long: `Lets a team write down "how we deploy" once, in a shared git repository, and
+find it by describing it: 'find' and 'suggest' search the team's commands along
+with your own history, and label the ones that come from the team.
+
+The repository is set with team.repo, a git URL or a local directory, and holds:
+  *.yaml / *.yml : Lists of snippets, each with a command and optionally a name
+                   and a description.
+  *.md           : Runbooks. Commands are read from their shell code blocks,
+                   described by the heading above them.
+
+'team pull' clones the repository into historai's data directory and updates it;
+run it again, or from cron, to get the team's latest snippets. find and suggest
+only read the clone, so they never wait for the network. A local directory is read
+as it is.
+
+Example:
+  historai config set team.repo git@github.com:acme/runbooks.git
+  historai team pull
+  historai team list
+  historai find "how we deploy the api"`,
+}
+
+// teamPullCmd represents the team pull command
+var teamPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Clone or update the team's snippets repository",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if appConfig.Team.Repo == "" {
+			return errors.New(i18n.T("no team repository configured; set team.repo to its git URL"))
+		}
+		if !team.IsLocal(appConfig.Team.Repo) {
+			if err := appConfig.Policy.RequireNetwork("cloning the team repository"); err != nil {
+				return err
+			}
+		}
+		ctx := context.Background()
+		dir, err := team.Pull(ctx, logger, appConfig.Team.Repo, appConfig.Team.Branch)
+		if err != nil {
+			return err
+		}
+		snippets, err := team.Load(logger, dir)
+		if err != nil {
+			return err
+		}
+		if updated := team.Updated(ctx, dir); updated != "" {
+			return infof("%d team snippets in %s, last changed %s.\n", len(snippets), appConfig.Team.Repo, updated)
+		}
+		return infof("%d team snippets in %s.\n", len(snippets), appConfig.Team.Repo)
+	},
+}
+
+// teamListCmd represents the team list command
+var teamListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the team's snippets",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if appConfig.Team.Repo == "" {
+			return errors.New(i18n.T("no team repository configured; set team.repo to its git URL"))
+		}
+		dir, err := team.Dir(appConfig.Team.Repo)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return errors.New(i18n.T("the team repository hasn't been cloned yet; run 'historai team pull'"))
+		}
+		snippets, err := team.Load(logger, dir)
+		if err != nil {
+			return err
+		}
+		if len(snippets) == 0 {
+			if err := infof("No snippets found in %s.\n", appConfig.Team.Repo); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+
+		switch outputFormat {
+		case outputText:
+		case outputJSONL:
+			encoder := json.NewEncoder(os.Stdout)
+			for _, snippet := range snippets {
+				if err := encoder.Encode(snippet); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return printStructuredResult(outputFormat, snippets)
+		}
+
+		fileColor := color.New(color.Bold)
+		descriptionColor := color.New(color.Faint)
+		var b strings.Builder
+		file := ""
+		for _, snippet := range snippets {
+			if rawOutput {
+				b.WriteString(snippet.Command + "\n")
+				continue
+			}
+			if snippet.File != file {
+				file = snippet.File
+				b.WriteString(fileColor.Sprint(file) + "\n")
+			}
+			b.WriteString("  " + snippet.Command + "\n")
+			if description := snippet.Label(); description != "" {
+				b.WriteString("    " + descriptionColor.Sprint(description) + "\n")
+			}
+		}
+		_, err = fmt.Fprint(os.Stdout, b.String())
+		return err
+	},
+}
+
+// init adds the teamCmd and its subcommands to the rootCmd.
+func init() {
+	rootCmd.AddCommand(teamCmd)
+	teamCmd.AddCommand(teamPullCmd, teamListCmd)
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/timings"
+)
+
+var (
+	// Flag variable to store the value of the --timings flag.
+	showTimings bool
+
+	// invocationTimings adds up the stages of this invocation. It stays nil for
+	// commands that don't load the config, which are never timed.
+	invocationTimings *timings.Timings
+)
+
+// finishTimings records the stages of cmd, which took total and returned err, in
+// the timings log, and prints them for --timings. Invocations that neither read
+// history nor called the LLM or daemon aren't recorded.
+func finishTimings(cmd *cobra.Command, total time.Duration, err error) {
+	stages := invocationTimings.Stages()
+	if len(stages) == 0 || appConfig == nil {
+		return
+	}
+	if showTimings {
+		printTimings(stages, total)
+	}
+
+	record := timings.NewRecord(strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "), invocationTimings, total,
+		err != nil && !errors.Is(err, errNoMatches))
+	record.Provider = appConfig.LLM.Provider
+	if record.Provider == "" {
+		record.Provider = llm.ProviderGemini
+	}
+	record.Model = llm.ModelName(appConfig)
+	path, pathErr := timings.DefaultPath()
+	if pathErr == nil {
+		pathErr = timings.Append(path, record)
+	}
+	if pathErr != nil {
+		// The timings are only statistics; don't fail the command over them.
+		logger.Debug("Failed to record timings", zap.Error(pathErr))
+	}
+}
+
+// printTimings writes the time spent in each stage, and in total, to stderr.
+func printTimings(stages map[string]time.Duration, total time.Duration) {
+	var parts []string
+	for _, stage := range timings.Stages {
+		if d, ok := stages[stage]; ok {
+			parts = append(parts, stage+" "+formatLatency(d))
+		}
+	}
+	parts = append(parts, i18n.T("total")+" "+formatLatency(total))
+	_, _ = fmt.Fprintln(os.Stderr, i18n.T("Timings: %s", strings.Join(parts, ", ")))
+}
+
+// formatLatency rounds d for display: to the millisecond under a second, and to
+// the hundredth of a second above.
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// latencyReport is the result of 'historai stats --latency'.
+type latencyReport struct {
+	Invocations int        `json:"invocations" yaml:"invocations"`
+	First       *time.Time `json:"first,omitempty" yaml:"first,omitempty"`
+	Last        *time.Time `json:"last,omitempty" yaml:"last,omitempty"`
+
+	// Commands summarizes the invocations of each command, most used first.
+	Commands []timings.Summary `json:"commands" yaml:"commands"`
+
+	// Models summarizes the invocations that waited for each provider and model.
+	Models []timings.Summary `json:"models" yaml:"models"`
+}
+
+// runLatencyStats prints the latency of the invocations recorded in the timings
+// log since sinceValue, if not empty, with at most top commands and models.
+func runLatencyStats(sinceValue string, top int) error {
+	path, err := timings.DefaultPath()
+	if err != nil {
+		return err
+	}
+	records, err := timings.Read(path)
+	if err != nil {
+		return err
+	}
+	if sinceValue != "" {
+		since, err := parseSince(sinceValue, time.Now())
+		if err != nil {
+			return err
+		}
+		var recent []timings.Record
+		for _, record := range records {
+			if !record.Time.Before(since) {
+				recent = append(recent, record)
+			}
+		}
+		records = recent
+	}
+	if len(records) == 0 {
+		if err := infof("No timed invocations found.\n"); err != nil {
+			return err
+		}
+		return errNoMatches
+	}
+
+	report := latencyReport{
+		Invocations: len(records),
+		First:       &records[0].Time,
+		Last:        &records[len(records)-1].Time,
+		Commands:    timings.Summarize(records, func(r timings.Record) string { return r.Command }),
+		Models: timings.Summarize(records, func(r timings.Record) string {
+			_, llmCalled := r.Stages[timings.StageLLM]
+			_, daemonCalled := r.Stages[timings.StageDaemon]
+			if !llmCalled && !daemonCalled {
+				return ""
+			}
+			if r.Model == "" {
+				return r.Provider
+			}
+			return r.Provider + ":" + r.Model
+		}),
+	}
+	report.Commands = report.Commands[:min(top, len(report.Commands))]
+	report.Models = report.Models[:min(top, len(report.Models))]
+
+	switch outputFormat {
+	case outputText:
+		return printLatency(report)
+	case outputJSONL:
+		return json.NewEncoder(os.Stdout).Encode(report)
+	default:
+		return printStructuredResult(outputFormat, report)
+	}
+}
+
+// printLatency writes report as tables of percentiles and stage medians.
+func printLatency(report latencyReport) error {
+	title := color.New(color.Bold)
+	if _, err := fmt.Fprintf(os.Stdout, "%s\n", i18n.T("%d invocations, from %s to %s",
+		report.Invocations, report.First.Local().Format("2006-01-02"), report.Last.Local().Format("2006-01-02"))); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	writeSummaries := func(heading, name string, summaries []timings.Summary) {
+		if len(summaries) == 0 {
+			return
+		}
+		_, _ = fmt.Fprintf(w, "\n%s\n", title.Sprint(i18n.T(heading)))
+		header := []string{name, "RUNS", "FAILED", "P50", "P90", "P99"}
+		for _, stage := range timings.Stages {
+			header = append(header, strings.ToUpper(stage))
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, summary := range summaries {
+			row := []string{
+				summary.Name,
+				fmt.Sprint(summary.Count),
+				fmt.Sprint(summary.Failed),
+				formatLatency(time.Duration(summary.P50MS) * time.Millisecond),
+				formatLatency(time.Duration(summary.P90MS) * time.Millisecond),
+				formatLatency(time.Duration(summary.P99MS) * time.Millisecond),
+			}
+			for _, stage := range timings.Stages {
+				if ms, ok := summary.StagesP50MS[stage]; ok {
+					row = append(row, formatLatency(time.Duration(ms)*time.Millisecond))
+				} else {
+					row = append(row, "-")
+				}
+			}
+			_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+	}
+	writeSummaries("By command", "COMMAND", report.Commands)
+	writeSummaries("By model", "MODEL", report.Models)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(os.Stdout, i18n.T("Stage columns are medians, over the runs that had the stage."))
+	return err
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/advisor"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+// packageManagers are the package managers tried for install commands, preferred first.
+var packageManagers = []struct {
+	name   string
+	binary string
+}{
+	{advisor.Brew, "brew"},
+	{advisor.Apt, "apt-get"},
+	{advisor.Dnf, "dnf"},
+	{advisor.Pacman, "pacman"},
+	{advisor.Cargo, "cargo"},
+}
+
+// tipsCmd represents the tips command
+var tipsCmd = &cobra.Command{
+	Use:   "tips",
+	Short: "Recommend modern alternatives to tools you use often",
+	Long: `Looks for classic commands you use heavily where a modern tool would serve you
+better, such as 'grep -r' (ripgrep), 'find . -name' (fd), cd into deep paths
+(zoxide), cat on source files (bat) or man (tldr), computed locally without any
+LLM call. Tools you already run or have installed are not recommended.
+
+Each tip comes with the command installing the tool with your package manager
+and examples, including some of your own commands rewritten for it.
+
+Set tips.enabled to true ('historai config set tips.enabled true') to also see
+the recommendations at the end of 'historai stats'.
+
+Flags:
+  --since / -s : Only look at commands run since today, yesterday, 12h, 3d, 2w or a date like 2024-05-01.
+
+Example:
+  historai tips
+  historai tips --since 4w
+  historai tips -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		sinceValue, err := cmd.Flags().GetString("since")
+		if err != nil {
+			return fmt.Errorf("internal error getting since flag: %w", err)
+		}
+
+		// 2. Read history
+		reader, err := newHistoryReader()
+		if err != nil {
+			return err
+		}
+		entries, err := reader.ReadHistory(0)
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		if sinceValue != "" {
+			since, err := parseSince(sinceValue, time.Now())
+			if err != nil {
+				return err
+			}
+			entries = entriesSince(entries, since)
+		}
+
+		// 3. Find and print the tips
+		tips := adviseTools(entries)
+		logger.Debug("Computed tips", zap.Int("tips_count", len(tips)))
+		if len(tips) == 0 {
+			if err := infof("No recommendations: you already use modern tools where it matters.\n"); err != nil {
+				return err
+			}
+			return errNoMatches
+		}
+		switch outputFormat {
+		case outputText:
+			_, err := fmt.Fprint(os.Stdout, formatTips(tips))
+			return err
+		case outputJSONL:
+			encoder := json.NewEncoder(os.Stdout)
+			for _, tip := range tips {
+				if err := encoder.Encode(tip); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return printStructuredResult(outputFormat, tips)
+		}
+	},
+}
+
+// adviseTools returns the advisor's tips for entries, checking for installed tools on $PATH.
+func adviseTools(entries []history.HistoryEntry) []advisor.Tip {
+	return advisor.Advise(entries, func(binary string) bool {
+		_, err := exec.LookPath(binary)
+		return err == nil
+	})
+}
+
+// installCommand returns the command installing the tool of tip with the first
+// available package manager, or with Homebrew on macOS if none is found.
+func installCommand(tip advisor.Tip) string {
+	for _, manager := range packageManagers {
+		if _, err := exec.LookPath(manager.binary); err == nil && tip.Install[manager.name] != "" {
+			return tip.Install[manager.name]
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		return tip.Install[advisor.Brew]
+	}
+	return tip.Install[advisor.Cargo]
+}
+
+// formatTips renders tips as plain-text sections.
+func formatTips(tips []advisor.Tip) string {
+	title := color.New(color.Bold)
+	faint := color.New(color.Faint)
+	var b strings.Builder
+	for i, tip := range tips {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(title.Sprint(i18n.T("%s instead of %s", tip.Tool, tip.Replaces)) + faint.Sprint(" "+i18n.T("(%d times)", tip.Count)) + "\n")
+		b.WriteString("  " + i18n.T(tip.Why) + "\n")
+		if install := installCommand(tip); install != "" {
+			b.WriteString("  " + i18n.T("Install:") + " " + install + "\n")
+		}
+		for _, example := range tip.Examples {
+			if example.Before != "" {
+				b.WriteString("  " + faint.Sprint(example.Before) + "\n    → " + example.After + "\n")
+			} else {
+				b.WriteString("  " + example.After + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// printTipsSummary writes a short section listing tips, for the stats command.
+func printTipsSummary(w io.Writer, tips []advisor.Tip) error {
+	if len(tips) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("\n" + color.New(color.Bold).Sprint(i18n.T("Modern alternatives")) + "\n")
+	for _, tip := range tips {
+		b.WriteString(fmt.Sprintf("%7d  %s\n", tip.Count, i18n.T("%s instead of %s", tip.Tool, tip.Replaces)))
+	}
+	b.WriteString(color.New(color.Faint).Sprint(i18n.T("Run 'historai tips' for install commands and examples.")) + "\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// init adds the tipsCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(tipsCmd)
+
+	tipsCmd.Flags().StringP("since", "s", "", "Only look at commands run since today, yesterday, 12h, 3d, 2w or a date")
+}
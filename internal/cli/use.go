@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+	"github.com/sanspareilsmyn/historai/internal/snippets"
+)
+
+const (
+	defaultUseHistoryLimit = config.DefaultFindLimit
+)
+
+// useCmd represents the use command
+var useCmd = &cobra.Command{
+	Use:   "use <snippet> [\"<description>\"]",
+	Short: "Fill in a snippet's placeholders and print or run it",
+	Long: `Fills in the {placeholders} of a snippet saved with 'historai snippet add' and
+prints the resulting command.
+
+With a description such as "tunnel to the staging db", the LLM picks the values,
+preferring the hosts, ports and paths found in your recent history. On a terminal
+you are then asked for every placeholder, with the LLM's choice as the default:
+press Enter to accept it. Values given with --set are used as-is.
+
+Flags:
+  --set / -s   : Set a placeholder, as name=value. Can be repeated.
+  --run / -r   : Run the command after confirmation instead of printing it.
+  --yes / -y   : Don't ask for values or confirmation; fail if a value is missing.
+  --limit / -n : How many recent history entries the LLM sees (default: 300, or find.limit from the config file).
+
+Example:
+  historai use tunnel "tunnel to the staging db"
+  historai use tunnel --set host=bastion --set port=8080
+  historai use tunnel "the prod replica" --run`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags
+		opts, err := parseUseFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		// 2. Look up the snippet and apply --set values
+		store, err := snippets.Load("")
+		if err != nil {
+			return err
+		}
+		snippet := store.Get(args[0])
+		if snippet == nil {
+			return errors.New(i18n.T("no snippet named %s (see 'historai snippet list')", args[0]))
+		}
+		placeholders := snippet.Placeholders()
+		values, err := parseSetValues(opts.set, placeholders)
+		if err != nil {
+			return err
+		}
+		set := make(map[string]bool, len(values))
+		for name := range values {
+			set[name] = true
+		}
+
+		// 3. Let the LLM fill in the rest from the description
+		if len(args) == 2 && len(values) < len(placeholders) {
+			suggested, err := fillSnippet(snippet, args[1], opts.limit)
+			if err != nil {
+				return err
+			}
+			for name, value := range suggested {
+				if !set[name] {
+					values[name] = value
+				}
+			}
+		}
+
+		// 4. Ask for the values on the terminal, or check that none is missing
+		interactive := !opts.yes && term.IsTerminal(int(os.Stdin.Fd()))
+		if interactive {
+			for _, name := range placeholders {
+				if set[name] {
+					continue
+				}
+				if values[name], err = promptValue(name, values[name]); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range placeholders {
+			if values[name] == "" {
+				return errors.New(i18n.T("no value for {%[1]s}; pass --set %[1]s=<value>", name))
+			}
+		}
+		command := snippet.Fill(values)
+		logger.Debug("Filled snippet", zap.String("snippet", snippet.Name), zap.String("command", command))
+
+		// 5. Print or run the command
+		if !opts.run {
+			_, err := fmt.Fprintln(os.Stdout, command)
+			return err
+		}
+		if !opts.yes {
+			ok, err := confirm(i18n.T("Run %s?", command))
+			if err != nil || !ok {
+				return err
+			}
+		}
+		return runInShell(command)
+	},
+}
+
+// useOptions holds the parsed flags of the use command.
+type useOptions struct {
+	set   []string
+	run   bool
+	yes   bool
+	limit int
+}
+
+// parseUseFlags extracts and validates flags specific to the use command.
+func parseUseFlags(cmd *cobra.Command) (useOptions, error) {
+	var opts useOptions
+	var err error
+	flags := cmd.Flags()
+	if opts.set, err = flags.GetStringArray("set"); err != nil {
+		return opts, fmt.Errorf("internal error getting set flag: %w", err)
+	}
+	if opts.run, err = flags.GetBool("run"); err != nil {
+		return opts, fmt.Errorf("internal error getting run flag: %w", err)
+	}
+	if opts.yes, err = flags.GetBool("yes"); err != nil {
+		return opts, fmt.Errorf("internal error getting yes flag: %w", err)
+	}
+	if opts.limit, err = flags.GetInt("limit"); err != nil {
+		return opts, fmt.Errorf("internal error getting limit flag: %w", err)
+	}
+	if !flags.Changed("limit") {
+		opts.limit = appConfig.Find.Limit
+	}
+	return opts, nil
+}
+
+// parseSetValues turns name=value pairs into placeholder values, rejecting names the
+// snippet doesn't have.
+func parseSetValues(pairs []string, placeholders []string) (map[string]string, error) {
+	known := make(map[string]bool, len(placeholders))
+	for _, name := range placeholders {
+		known[name] = true
+	}
+	values := make(map[string]string, len(placeholders))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, errors.New(i18n.T("--set expects name=value, got %q", pair))
+		}
+		if !known[name] {
+			return nil, errors.New(i18n.T("the snippet has no placeholder {%s}", name))
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// fillSnippet asks the LLM for the placeholder values matching description.
+func fillSnippet(snippet *snippets.Snippet, description string, limit int) (map[string]string, error) {
+	eng, err := newEngine(context.Background(), engine.Options{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := eng.Close(); closeErr != nil {
+			logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+	}()
+
+	spin := startSpinner()
+	values, err := eng.FillSnippet(context.Background(), snippet, description, limit)
+	spin.stop()
+	return values, err
+}
+
+// promptValue asks for the value of a placeholder on stderr, offering suggested as
+// the default. Stdin is read unbuffered, so typed-ahead answers to later prompts
+// (including the confirmation of --run) are not lost.
+func promptValue(name, suggested string) (string, error) {
+	question := name
+	if suggested != "" {
+		question += " [" + suggested + "]"
+	}
+	if _, err := fmt.Fprint(os.Stderr, question+": "); err != nil {
+		return "", err
+	}
+	answer, err := readLine(os.Stdin)
+	if err != nil && answer == "" {
+		return "", errors.New(i18n.T("no value for {%[1]s}; pass --set %[1]s=<value>", name))
+	}
+	if answer = strings.TrimRight(answer, "\r"); answer != "" {
+		return answer, nil
+	}
+	return suggested, nil
+}
+
+// readLine reads up to and excluding the next newline, one byte at a time.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// init adds the useCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(useCmd)
+
+	useCmd.Flags().StringArrayP("set", "s", nil, "Set a placeholder, as name=value")
+	useCmd.Flags().BoolP("run", "r", false, "Run the command after confirmation instead of printing it")
+	useCmd.Flags().BoolP("yes", "y", false, "Don't ask for values or confirmation")
+	useCmd.Flags().IntP("limit", "n", defaultUseHistoryLimit, "Limit the number of most recent history entries sent as context")
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/version"
+)
+
+// versionResult is the version command's output: the build metadata and the
+// provider and model the configuration selects.
+type versionResult struct {
+	version.Info `yaml:",inline"`
+	Provider     string `json:"provider" yaml:"provider"`
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+}
+
+// versionConfigErr is the error loading the configuration, which shouldn't keep
+// version from printing what a bug report needs.
+var versionConfigErr error
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, build and active provider of historai",
+	Long: `Prints historai's version, the commit and date it was built from, the Go version
+and platform, and the provider and model the configuration selects. Please include
+it in bug reports.
+
+Example:
+  historai version
+  historai version --output json`,
+	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := rootCmd.PersistentPreRunE(cmd, args)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) && exitErr.code == ExitConfig {
+			versionConfigErr = err
+			return nil
+		}
+		return err
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result := currentVersion()
+		switch outputFormat {
+		case outputJSONL:
+			return json.NewEncoder(os.Stdout).Encode(result)
+		case outputJSON, outputYAML:
+			return printStructuredResult(outputFormat, result)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		rows := [][2]string{{"version", result.Version}}
+		if result.Commit != "" {
+			commit := result.Commit
+			if result.Modified {
+				commit += " (modified)"
+			}
+			rows = append(rows, [2]string{"commit", commit})
+		}
+		if result.Date != "" {
+			rows = append(rows, [2]string{"built", result.Date})
+		}
+		rows = append(rows,
+			[2]string{"go", result.GoVersion + " " + result.Platform},
+			[2]string{"provider", result.Provider},
+		)
+		if result.Model != "" {
+			rows = append(rows, [2]string{"model", result.Model})
+		}
+		for _, row := range rows {
+			if _, err := fmt.Fprintf(writer, "%s:\t%s\n", row[0], row[1]); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	},
+}
+
+// currentVersion returns the build metadata, and the provider and model unless the
+// configuration failed to load.
+func currentVersion() versionResult {
+	result := versionResult{Info: version.Get(), Provider: "unknown (invalid configuration)"}
+	if versionConfigErr == nil {
+		result.Provider = appConfig.LLM.Provider
+		result.Model = llm.ModelName(appConfig)
+	}
+	return result
+}
+
+// init adds the versionCmd to the rootCmd, and --version for the bare version.
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.Version = version.Get().Version
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/i18n"
+)
+
+const (
+	defaultWhyHistoryLimit = config.DefaultFindLimit
+)
+
+// whyCmd represents the why command
+var whyCmd = &cobra.Command{
+	Use:   "why [\"<command>\"]",
+	Short: "Diagnose why the last command failed using AI",
+	Long: `Asks an LLM to explain why a command failed and how to resolve it, using
+the real error output rather than just the command line.
+
+Without an argument, the most recent command that exited with a non-zero status
+is used, together with its error output if the shell hook captured it
+(see 'historai init --capture-stderr'). Error output piped to stdin is used
+instead of the captured output.
+
+Flags:
+  --limit / -n : How many recent history entries to search for previous uses (default: 300, or find.limit from the config file).
+
+Example:
+  historai why
+  make 2>&1 | historai why "make"
+  historai why "docker compose up" < error.log`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Parse and validate flags (limit)
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			logger.Error("Failed to get 'limit' flag value", zap.Error(err))
+			return fmt.Errorf("internal error getting limit flag: %w", err)
+		}
+		if !cmd.Flags().Changed("limit") {
+			limit = appConfig.Find.Limit
+		}
+
+		// 2. Read error output from stdin, if piped
+		piped, err := readPipedStderr()
+		if err != nil {
+			return err
+		}
+
+		// 3. Initialize Engine (config, history, LLM client)
+		eng, err := newEngine(context.Background(), engine.Options{})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := eng.Close(); closeErr != nil {
+				logger.Error("Failed to close LLM client", zap.Error(closeErr))
+			}
+		}()
+
+		// 4. Pick the failed command
+		var failed history.HistoryEntry
+		if len(args) == 1 {
+			failed.Command = strings.TrimSpace(args[0])
+		} else if failed, err = eng.LastFailed(); err != nil {
+			return err
+		}
+		if failed.Command == "" {
+			return errors.New(i18n.T("command cannot be empty"))
+		}
+		if piped != "" {
+			failed.Stderr = piped
+		}
+		logger.Debug("Diagnosing command", zap.String("command", failed.Command), zap.Int("stderr_bytes", len(failed.Stderr)))
+
+		// 5. Ask the LLM for a diagnosis
+		started := time.Now()
+		spin := startSpinner()
+		diagnosis, err := eng.Why(context.Background(), failed, limit)
+		spin.stop()
+		if err != nil {
+			return err
+		}
+
+		// 6. Print the diagnosis
+		return printExplanation(logger, commandOutput{
+			kind:         "why",
+			query:        failed.Command,
+			output:       diagnosis,
+			noAnswer:     "(AI could not diagnose this failure)",
+			header:       i18n.T("--- Diagnosis: %s ---", failed.Command),
+			logOnFailure: "No diagnosis generated or response indicates failure.",
+			started:      started,
+		})
+	},
+}
+
+// readPipedStderr returns the tail of stdin when it is not a terminal, so that error
+// output can be piped in. It returns "" when stdin is a terminal.
+func readPipedStderr() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read error output from stdin: %w", err)
+	}
+	return history.TailBytes(strings.TrimSpace(string(data)), history.MaxRecordedStderr), nil
+}
+
+// init adds the whyCmd and its flags to the rootCmd.
+func init() {
+	rootCmd.AddCommand(whyCmd)
+
+	whyCmd.Flags().IntP("limit", "n", defaultWhyHistoryLimit, "Limit the number of most recent history entries searched for previous uses")
+}
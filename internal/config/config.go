@@ -1,34 +1,581 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	EnvGoogleAPIKey = "GOOGLE_API_KEY"
+
+	// EnvConfigFile overrides the config file location.
+	EnvConfigFile = "HISTORAI_CONFIG"
+
+	// envPrefix prefixes the environment variable of every config key,
+	// e.g. llm.model is overridden by HISTORAI_LLM_MODEL.
+	envPrefix = "HISTORAI_"
+
+	appName        = "historai"
+	configFileName = "config.yaml"
+)
+
+// ErrMissingAPIKey is returned by RequireGoogleAPIKey when no API key is configured.
+var ErrMissingAPIKey = errors.New("required environment variable " + EnvGoogleAPIKey + " is not set (or run 'historai auth login')")
+
+// Default values used when neither the config file nor the environment set a key.
+const (
+	DefaultProvider      = "gemini"
+	DefaultFindLimit     = 300
+	DefaultSuggestLimit  = 100
+	DefaultLogLevel      = "info"
+	DefaultLogMaxSize    = 10
+	DefaultLogBackups    = 3
+	DefaultArchiveMonths = 6
+
+	// DefaultContextMaxTokens bounds the history find and suggest send, about 32 KB.
+	DefaultContextMaxTokens = 8000
+
+	// DefaultSafetyThreshold blocks content with a medium or high probability of harm.
+	DefaultSafetyThreshold = "medium_and_above"
 )
 
+// MaxCandidates is the most answers Gemini generates for a single request.
+const MaxCandidates = 8
+
+// Values of context.selection.
+const (
+	// SelectionScored picks the history entries scoring highest for recency,
+	// frequency and overlap with the query.
+	SelectionScored = "scored"
+	// SelectionRecent picks the most recent history entries.
+	SelectionRecent = "recent"
+)
+
+// Selections lists the values of context.selection.
+var Selections = []string{SelectionScored, SelectionRecent}
+
+// SafetyThresholds lists the values of the safety.* keys, from the least strict.
+var SafetyThresholds = []string{"none", "only_high", DefaultSafetyThreshold, "low_and_above"}
+
+// DefaultSource is the history source used when none is configured: PSReadLine's
+// history on Windows, where zsh is rare, and zsh's elsewhere.
+var DefaultSource = defaultSource()
+
+// defaultSource returns DefaultSource for the current platform.
+func defaultSource() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "zsh"
+}
+
 // Config holds the application configuration.
 type Config struct {
-	GoogleAPIKey string
+	GoogleAPIKey string `yaml:"-"`
+
+	// GoogleAPIKeySource describes where GoogleAPIKey came from
+	// ("env GOOGLE_API_KEY", "env file <path>" or "keyring").
+	GoogleAPIKeySource string `yaml:"-"`
+
+	LLM       LLMConfig       `yaml:"llm"`
+	History   HistoryConfig   `yaml:"history"`
+	Find      FindConfig      `yaml:"find"`
+	Suggest   SuggestConfig   `yaml:"suggest"`
+	Redaction RedactionConfig `yaml:"redaction"`
+	Prompt    PromptConfig    `yaml:"prompt"`
+	Network   NetworkConfig   `yaml:"network"`
+	UI        UIConfig        `yaml:"ui"`
+	Prune     PruneConfig     `yaml:"prune"`
+	Archive   ArchiveConfig   `yaml:"archive"`
+	Sync      SyncConfig      `yaml:"sync"`
+	Team      TeamConfig      `yaml:"team"`
+	Tips      TipsConfig      `yaml:"tips"`
+	Compare   CompareConfig   `yaml:"compare"`
+	Mock      MockConfig      `yaml:"mock"`
+	Context   ContextConfig   `yaml:"context"`
+	Log       LogConfig       `yaml:"log"`
+	Outbound  OutboundConfig  `yaml:"outbound"`
+	Safety    SafetyConfig    `yaml:"safety"`
+
+	// Path is the config file the values were loaded from, if any.
+	Path string `yaml:"-"`
+
+	// ProjectRoot is the directory holding the project config file, if one was found.
+	ProjectRoot string `yaml:"-"`
+
+	// Policy is the organization policy, if a policy file exists (see PolicyPath).
+	Policy *Policy `yaml:"-"`
+
+	// origins records where each non-default key's value came from.
+	origins map[string]string
 }
 
-// LoadConfig loads the configuration, currently only from environment variables.
-func LoadConfig(logger *zap.Logger) (*Config, error) {
-	apiKey := os.Getenv(EnvGoogleAPIKey)
-	if apiKey == "" {
-		logger.Error("Google AI API Key not found in environment variable",
-			zap.String("variable_name", EnvGoogleAPIKey))
-		return nil, errors.New("required environment variable " + EnvGoogleAPIKey + " is not set")
+// LLMConfig selects the LLM provider and model.
+type LLMConfig struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+
+	// Endpoint overrides the provider's API base URL, e.g. for an API gateway.
+	Endpoint string `yaml:"endpoint"`
+
+	// MaxToolCalls is how many rounds of tool calls, such as searching older
+	// history, the model may make before answering find, ask and chat. 0 disables tools.
+	MaxToolCalls int `yaml:"max_tool_calls"`
+
+	// Candidates is how many answers Gemini generates for find and suggest, of which
+	// the best is picked locally. 1 asks for a single answer.
+	Candidates int `yaml:"candidates"`
+
+	// Race is a second model, "model" of the same provider or "provider:model",
+	// that find, suggest and next are also sent to; the first acceptable answer is
+	// kept and the other request cancelled. Empty sends requests to one model only.
+	Race string `yaml:"race"`
+}
+
+// HistoryConfig selects where shell history is read from.
+type HistoryConfig struct {
+	Source string `yaml:"source"`
+	File   string `yaml:"file"`
+
+	// ProjectOnly restricts context to commands run inside the project root.
+	ProjectOnly bool `yaml:"project_only"`
+}
+
+// FindConfig holds defaults for the find command.
+type FindConfig struct {
+	Limit int `yaml:"limit"`
+}
+
+// SuggestConfig holds defaults for the suggest command.
+type SuggestConfig struct {
+	Limit int `yaml:"limit"`
+}
+
+// RedactionConfig holds additional secret patterns, on top of the built-in ones.
+type RedactionConfig struct {
+	Patterns []string `yaml:"patterns"`
+
+	// Anonymize replaces the literal arguments of the history commands sent to the
+	// LLM with typed placeholders, e.g. "scp <FILE> <USER>@<HOST>:<PATH>", and leaves
+	// out their directories, hosts and output. Commands the model picks are mapped
+	// back to the user's own.
+	Anonymize bool `yaml:"anonymize"`
+}
+
+// PromptConfig customizes the prompts sent to the LLM.
+type PromptConfig struct {
+	// Instructions are appended to every find/suggest prompt.
+	Instructions string `yaml:"instructions"`
+
+	// Language is the language explanations are written in, e.g. "Korean".
+	// Commands themselves are never translated. Empty means the model's default.
+	Language string `yaml:"language"`
+
+	// System is a system instruction applied to every request, e.g. "never suggest
+	// curl | sh". Providers give it more weight than Instructions.
+	System string `yaml:"system"`
+
+	// Templates is the directory of find.tmpl and suggest.tmpl, Go text/template
+	// files replacing the built-in prompts. Empty means "templates" in the config directory.
+	Templates string `yaml:"templates"`
+}
+
+// NetworkConfig configures outbound HTTP(S) connections.
+type NetworkConfig struct {
+	// Proxy is an explicit proxy URL; HTTPS_PROXY is honored when empty.
+	Proxy string `yaml:"proxy"`
+
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string `yaml:"ca_file"`
+}
+
+// UIConfig controls the command-line interface itself.
+type UIConfig struct {
+	// Locale selects the language of messages, e.g. "ko" or "ja". Empty means the
+	// locale is taken from $LC_ALL, $LC_MESSAGES or $LANG.
+	Locale string `yaml:"locale"`
+}
+
+// PruneConfig holds defaults for the prune command.
+type PruneConfig struct {
+	// Ignore lists regular expressions; matching entries are removed by prune.
+	Ignore []string `yaml:"ignore"`
+}
+
+// ArchiveConfig holds defaults for the archive command.
+type ArchiveConfig struct {
+	// Months is the age, in months, past which archive moves entries out of
+	// historai's log into compressed segments.
+	Months int `yaml:"months"`
+}
+
+// SyncConfig configures the encrypted remote copy of historai's own history log.
+type SyncConfig struct {
+	// URL is where the encrypted log is stored; its scheme selects the backend:
+	// s3://bucket/prefix, gs://bucket/prefix, https://host/path (WebDAV),
+	// git+ssh://host/repo.git#branch or file:///dir.
+	URL string `yaml:"url"`
+
+	// Endpoint overrides the S3 API endpoint, for S3-compatible services.
+	Endpoint string `yaml:"endpoint"`
+	// Region is the S3 region, us-east-1 when empty.
+	Region string `yaml:"region"`
+	// Username authenticates to WebDAV; the password is read from $HISTORAI_WEBDAV_PASSWORD.
+	Username string `yaml:"username"`
+
+	// Identity is the age identity file the log is encrypted to, sync.key in the
+	// config directory when empty. Copy it to every machine that syncs.
+	Identity string `yaml:"identity"`
+}
+
+// TeamConfig configures the repository of snippets and runbooks shared by the
+// user's team, which find and suggest search alongside the user's history.
+type TeamConfig struct {
+	// Repo is the git repository holding the team's snippets, cloned by 'historai
+	// team pull', or a local directory used as it is.
+	Repo string `yaml:"repo"`
+
+	// Branch is the branch to check out, the repository's default branch when empty.
+	Branch string `yaml:"branch"`
+}
+
+// TipsConfig controls the modern-alternative advisor.
+type TipsConfig struct {
+	// Enabled shows the tools the advisor recommends at the end of the stats command.
+	// The tips command shows them regardless.
+	Enabled bool `yaml:"enabled"`
+}
+
+// CompareConfig holds defaults for the compare command.
+type CompareConfig struct {
+	// Models are the models compared when no --with flag is given, each either a
+	// model of the configured provider or provider:model.
+	Models []string `yaml:"models"`
+}
+
+// MockConfig configures the offline mock provider.
+type MockConfig struct {
+	// Responses is a YAML file of canned responses by method, used instead of the
+	// mock provider's rule-based answers.
+	Responses string `yaml:"responses"`
+}
+
+// ContextConfig selects the facts about the user's surroundings sent with suggest
+// requests, besides history.
+type ContextConfig struct {
+	// Environment sends the OS and its version, architecture, shell, flavor of the
+	// core utilities and which common tools are installed.
+	Environment bool `yaml:"environment"`
+
+	// Git sends the branch, its upstream, how many files changed and the remotes of
+	// the git repository the user is in.
+	Git bool `yaml:"git"`
+
+	// Cloud sends the current kubectl context and namespace, AWS profile and region
+	// and gcloud project. It is off by default, as these names can be sensitive.
+	Cloud bool `yaml:"cloud"`
+
+	// Selection picks the history find and suggest send, one of Selections: "scored"
+	// sends the commands scoring highest for recency, frequency and overlap with the
+	// query, once each; "recent" the most recent entries.
+	Selection string `yaml:"selection"`
+
+	// MaxTokens bounds the estimated size of the history "scored" selection sends,
+	// besides find.limit and suggest.limit. 0 leaves only those.
+	MaxTokens int `yaml:"max_tokens"`
+}
+
+// LogConfig controls the log file, which keeps what the daemon and shell widgets
+// logged after their terminal is gone.
+type LogConfig struct {
+	// Enabled writes logs to File, besides stderr.
+	Enabled bool `yaml:"enabled"`
+
+	// File is the log file, historai.log in $XDG_STATE_HOME/historai (or
+	// ~/.local/state/historai) when empty.
+	File string `yaml:"file"`
+
+	// Level is the least severe level written to the file: debug, info, warn or
+	// error. --debug writes debug messages regardless.
+	Level string `yaml:"level"`
+
+	// MaxSize is the size in megabytes at which the file is rotated.
+	MaxSize int `yaml:"max_size"`
+
+	// MaxBackups is how many rotated files are kept.
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// OutboundConfig controls the outbound log, an audit trail of what was sent to
+// LLM providers.
+type OutboundConfig struct {
+	// Log appends every prompt sent to a provider, after redaction, to File with
+	// when it was sent and its size. Prompts are not sent if it can't be written.
+	Log bool `yaml:"log"`
+
+	// File is the outbound log, outbound.jsonl in $XDG_STATE_HOME/historai (or
+	// ~/.local/state/historai) when empty.
+	File string `yaml:"file"`
+}
+
+// SafetyConfig sets how readily Gemini blocks prompts and answers, for each category
+// of harm, as one of SafetyThresholds. Questions about kill, shred or security
+// tools are sometimes blocked as dangerous content; a less strict DangerousContent
+// lets them through.
+type SafetyConfig struct {
+	Harassment       string `yaml:"harassment"`
+	HateSpeech       string `yaml:"hate_speech"`
+	SexuallyExplicit string `yaml:"sexually_explicit"`
+	DangerousContent string `yaml:"dangerous_content"`
+}
+
+// Default returns the configuration used when no file or environment overrides exist.
+func Default() *Config {
+	return &Config{
+		LLM:     LLMConfig{Provider: DefaultProvider, Candidates: 1},
+		History: HistoryConfig{Source: DefaultSource},
+		Find:    FindConfig{Limit: DefaultFindLimit},
+		Suggest: SuggestConfig{Limit: DefaultSuggestLimit},
+		Archive: ArchiveConfig{Months: DefaultArchiveMonths},
+		Context: ContextConfig{Environment: true, Selection: SelectionScored, MaxTokens: DefaultContextMaxTokens},
+		Log:     LogConfig{Level: DefaultLogLevel, MaxSize: DefaultLogMaxSize, MaxBackups: DefaultLogBackups},
+		Safety: SafetyConfig{
+			Harassment:       DefaultSafetyThreshold,
+			HateSpeech:       DefaultSafetyThreshold,
+			SexuallyExplicit: DefaultSafetyThreshold,
+			DangerousContent: DefaultSafetyThreshold,
+		},
 	}
-	logger.Debug("Successfully loaded Google AI API Key from environment variable")
+}
 
-	cfg := &Config{
-		GoogleAPIKey: apiKey,
+// Dir returns historai's config directory, honoring $XDG_CONFIG_HOME. On Windows it
+// defaults to %APPDATA%\historai.
+func Dir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, appName), nil
+	}
+	if appData := os.Getenv("APPDATA"); appData != "" && runtime.GOOS == "windows" {
+		return filepath.Join(appData, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
 	}
+	return filepath.Join(home, ".config", appName), nil
+}
+
+// DefaultPath returns the config file location, honoring $HISTORAI_CONFIG.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		return path, nil
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFileName), nil
+}
+
+// LoadConfig loads the configuration. Values are layered, lowest precedence first:
+// built-in defaults, the config file, the project config file (see loadProject),
+// then environment variables. Command-line flags are applied on top by the caller.
+// The organization policy, if any, is enforced over all of them (see Policy).
+//
+// If path is empty the default location is used and a missing file is not an error.
+// A missing API key is not an error here either, as not every provider needs one;
+// see RequireGoogleAPIKey.
+func LoadConfig(logger *zap.Logger, path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		defaultPath, err := DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+		explicit = os.Getenv(EnvConfigFile) != ""
+	}
+
+	if err := cfg.loadFile(logger, path, explicit); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadProject(logger); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadEnv(logger); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadPolicy(logger, PolicyPath()); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.History.File = ExpandHome(cfg.History.File)
+	cfg.Network.CAFile = ExpandHome(cfg.Network.CAFile)
+	cfg.Sync.Identity = ExpandHome(cfg.Sync.Identity)
+	cfg.Team.Repo = ExpandHome(cfg.Team.Repo)
+	cfg.Log.File = ExpandHome(cfg.Log.File)
+	cfg.Outbound.File = ExpandHome(cfg.Outbound.File)
 
 	return cfg, nil
 }
+
+// ExpandHome replaces a leading "~/" in path with the user's home directory, or
+// "~\\" on Windows.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// loadFile merges the YAML config file at path into cfg.
+func (c *Config) loadFile(logger *zap.Logger, path string, mustExist bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !mustExist {
+			logger.Debug("No config file found, using defaults", zap.String("path", path))
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err == nil {
+		for _, key := range leafKeys(&root) {
+			c.setOrigin(key, "file "+path)
+		}
+	}
+	c.Path = path
+	logger.Debug("Loaded config file", zap.String("path", path))
+	return nil
+}
+
+// loadEnv applies HISTORAI_* environment variable overrides and loads the API key,
+// preferring the environment (including env files, see loadDotenv) over the OS keyring.
+func (c *Config) loadEnv(logger *zap.Logger) error {
+	dotenvSources, err := loadDotenv(logger)
+	if err != nil {
+		return err
+	}
+	envOrigin := func(name string) string {
+		if path, ok := dotenvSources[name]; ok {
+			return "env file " + path
+		}
+		return "env " + name
+	}
+
+	for _, f := range fields {
+		value, ok := os.LookupEnv(f.envName())
+		if !ok {
+			continue
+		}
+		if err := f.set(c, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", f.envName(), err)
+		}
+		c.setOrigin(f.key, envOrigin(f.envName()))
+		logger.Debug("Config value overridden by environment", zap.String("key", f.key))
+	}
+
+	if apiKey := os.Getenv(EnvGoogleAPIKey); apiKey != "" {
+		c.GoogleAPIKey = apiKey
+		c.GoogleAPIKeySource = envOrigin(EnvGoogleAPIKey)
+		logger.Debug("Successfully loaded Google AI API Key from environment variable")
+	} else if apiKey := loadKeyringAPIKey(logger, DefaultProvider); apiKey != "" {
+		c.GoogleAPIKey = apiKey
+		c.GoogleAPIKeySource = "keyring"
+		logger.Debug("Successfully loaded Google AI API Key from the OS keyring")
+	}
+	return nil
+}
+
+// Validate checks that the configuration values are usable.
+func (c *Config) Validate() error {
+	if c.LLM.Candidates < 1 || c.LLM.Candidates > MaxCandidates {
+		return fmt.Errorf("llm.candidates must be between 1 and %d, got %d", MaxCandidates, c.LLM.Candidates)
+	}
+	if c.Find.Limit < 0 {
+		return fmt.Errorf("find.limit must not be negative, got %d", c.Find.Limit)
+	}
+	if c.Suggest.Limit < 0 {
+		return fmt.Errorf("suggest.limit must not be negative, got %d", c.Suggest.Limit)
+	}
+	if c.Archive.Months < 1 {
+		return fmt.Errorf("archive.months must be at least 1, got %d", c.Archive.Months)
+	}
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		return fmt.Errorf("log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.MaxSize < 1 {
+		return fmt.Errorf("log.max_size must be at least 1, got %d", c.Log.MaxSize)
+	}
+	if c.Log.MaxBackups < 0 {
+		return fmt.Errorf("log.max_backups must not be negative, got %d", c.Log.MaxBackups)
+	}
+	if !slices.Contains(Selections, c.Context.Selection) {
+		return fmt.Errorf("context.selection must be one of %s, got %q", strings.Join(Selections, ", "), c.Context.Selection)
+	}
+	if c.Context.MaxTokens < 0 {
+		return fmt.Errorf("context.max_tokens must not be negative, got %d", c.Context.MaxTokens)
+	}
+	for key, threshold := range map[string]string{
+		"safety.harassment":        c.Safety.Harassment,
+		"safety.hate_speech":       c.Safety.HateSpeech,
+		"safety.sexually_explicit": c.Safety.SexuallyExplicit,
+		"safety.dangerous_content": c.Safety.DangerousContent,
+	} {
+		if !slices.Contains(SafetyThresholds, threshold) {
+			return fmt.Errorf("%s must be one of %s, got %q", key, strings.Join(SafetyThresholds, ", "), threshold)
+		}
+	}
+	for _, pattern := range c.Redaction.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Fingerprint returns a hash of the effective values of every key, wherever they
+// came from: config file, project config, environment or flags. Two processes
+// with the same fingerprint answer requests the same way.
+func (c *Config) Fingerprint() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		// Config holds only plain values, which always marshal.
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RequireGoogleAPIKey returns an error if the Google AI API key is not configured.
+func (c *Config) RequireGoogleAPIKey(logger *zap.Logger) error {
+	if c.GoogleAPIKey == "" {
+		logger.Error("Google AI API Key not found in environment variable or OS keyring",
+			zap.String("variable_name", EnvGoogleAPIKey))
+		return ErrMissingAPIKey
+	}
+	return nil
+}
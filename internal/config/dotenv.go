@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// envFileName is the user-wide env file inside the config directory.
+	envFileName = "env"
+
+	// projectEnvFileName is looked up in the working directory.
+	projectEnvFileName = ".env"
+
+	// credentialSuffix marks the only variables loaded from a project .env file.
+	credentialSuffix = "_API_KEY"
+)
+
+// loadDotenv sets variables from env files that are not already present in the
+// process environment, so real environment variables always win. Precedence,
+// highest first: the process environment, ./.env (credentials only, i.e.
+// *_API_KEY variables, since it belongs to whatever project you happen to be in),
+// then ~/.config/historai/env (any variable, including HISTORAI_* overrides).
+// It returns the file each variable was loaded from.
+func loadDotenv(logger *zap.Logger) (map[string]string, error) {
+	sources := make(map[string]string)
+
+	if cwd, err := os.Getwd(); err == nil {
+		path := filepath.Join(cwd, projectEnvFileName)
+		if err := applyEnvFile(logger, path, isCredentialVar, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir, err := Dir(); err == nil {
+		path := filepath.Join(dir, envFileName)
+		if err := applyEnvFile(logger, path, func(string) bool { return true }, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	return sources, nil
+}
+
+// applyEnvFile sets the variables of the env file at path accepted by allow,
+// unless they are already set. A missing file is not an error.
+func applyEnvFile(logger *zap.Logger, path string, allow func(string) bool, sources map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+
+	vars, err := parseEnvFile(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file %s: %w", path, err)
+	}
+	for _, v := range vars {
+		if !allow(v.key) {
+			continue
+		}
+		if _, exists := os.LookupEnv(v.key); exists {
+			continue
+		}
+		if err := os.Setenv(v.key, v.value); err != nil {
+			return fmt.Errorf("failed to set %s from %s: %w", v.key, path, err)
+		}
+		sources[v.key] = path
+		logger.Debug("Loaded variable from env file", zap.String("variable_name", v.key), zap.String("path", path))
+	}
+	return nil
+}
+
+// envVar is a single KEY=VALUE assignment from an env file.
+type envVar struct {
+	key   string
+	value string
+}
+
+// parseEnvFile parses dotenv syntax: KEY=VALUE lines, optional "export " prefix,
+// # comments, and single- or double-quoted values.
+func parseEnvFile(data []byte) ([]envVar, error) {
+	var vars []envVar
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		vars = append(vars, envVar{key: key, value: unquoteEnvValue(strings.TrimSpace(value))})
+	}
+	return vars, scanner.Err()
+}
+
+// unquoteEnvValue strips surrounding quotes, or a trailing " # comment" from unquoted values.
+func unquoteEnvValue(value string) string {
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		quote := value[0]
+		for i := 1; i < len(value); i++ {
+			if value[i] == '\\' && quote == '"' {
+				i++
+				continue
+			}
+			if value[i] == quote {
+				inner := value[1:i]
+				if quote == '"' {
+					inner = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(inner)
+				}
+				return inner
+			}
+		}
+	}
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	return value
+}
+
+func isCredentialVar(key string) bool {
+	return strings.HasSuffix(key, credentialSuffix)
+}
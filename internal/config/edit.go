@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// SetInFile validates value for key and writes it to the config file at path,
+// creating the file if needed. Comments and unrelated keys are preserved.
+func SetInFile(path, key, value string) error {
+	f, err := lookupField(key)
+	if err != nil {
+		return err
+	}
+
+	// Validate against the file's current contents plus the new value.
+	probe := Default()
+	if err := probe.loadFile(zap.NewNop(), path, false); err != nil {
+		return err
+	}
+	if err := f.set(probe, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	if err := probe.Validate(); err != nil {
+		return err
+	}
+
+	// Re-encode the typed value so integers and lists keep their YAML types.
+	var valueNode yaml.Node
+	if err := valueNode.Encode(f.typed(probe)); err != nil {
+		return fmt.Errorf("failed to encode value for %s: %w", key, err)
+	}
+
+	return editFile(path, func(root *yaml.Node) {
+		setNode(root, strings.Split(key, "."), &valueNode)
+	})
+}
+
+// UnsetInFile removes key from the config file at path, reverting it to its default.
+func UnsetInFile(path, key string) error {
+	if _, err := lookupField(key); err != nil {
+		return err
+	}
+	return editFile(path, func(root *yaml.Node) {
+		unsetNode(root, strings.Split(key, "."))
+	})
+}
+
+// editFile loads the YAML document at path (or an empty one), applies edit and writes it back.
+func editFile(path string, edit func(root *yaml.Node)) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			root = doc.Content[0]
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	edit(root)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setNode sets the value at path inside a mapping node, creating intermediate mappings.
+func setNode(mapping *yaml.Node, path []string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			mapping.Content[i+1] = value
+			return
+		}
+		child := mapping.Content[i+1]
+		if child.Kind != yaml.MappingNode {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			mapping.Content[i+1] = child
+		}
+		setNode(child, path[1:], value)
+		return
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}
+	if len(path) == 1 {
+		mapping.Content = append(mapping.Content, keyNode, value)
+		return
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, keyNode, child)
+	setNode(child, path[1:], value)
+}
+
+// unsetNode removes the value at path inside a mapping node, if present,
+// along with any parent mapping left empty.
+func unsetNode(mapping *yaml.Node, path []string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+		child := mapping.Content[i+1]
+		if len(path) > 1 {
+			if child.Kind != yaml.MappingNode {
+				return
+			}
+			unsetNode(child, path[1:])
+			if len(child.Content) > 0 {
+				return
+			}
+		}
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		return
+	}
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field describes a single dotted config key and how to read and write it as a string.
+type field struct {
+	key string
+	get func(c *Config) string
+	set func(c *Config, value string) error
+
+	// typed returns the value as it should be encoded in YAML.
+	typed func(c *Config) any
+}
+
+// envName returns the environment variable that overrides the field.
+func (f field) envName() string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(f.key))
+}
+
+// fields lists every configurable key, in display order.
+var fields = []field{
+	stringField("llm.provider", func(c *Config) *string { return &c.LLM.Provider }),
+	stringField("llm.model", func(c *Config) *string { return &c.LLM.Model }),
+	stringField("llm.endpoint", func(c *Config) *string { return &c.LLM.Endpoint }),
+	intField("llm.max_tool_calls", func(c *Config) *int { return &c.LLM.MaxToolCalls }),
+	intField("llm.candidates", func(c *Config) *int { return &c.LLM.Candidates }),
+	stringField("llm.race", func(c *Config) *string { return &c.LLM.Race }),
+	stringField("history.source", func(c *Config) *string { return &c.History.Source }),
+	stringField("history.file", func(c *Config) *string { return &c.History.File }),
+	boolField("history.project_only", func(c *Config) *bool { return &c.History.ProjectOnly }),
+	intField("find.limit", func(c *Config) *int { return &c.Find.Limit }),
+	intField("suggest.limit", func(c *Config) *int { return &c.Suggest.Limit }),
+	listField("redaction.patterns", func(c *Config) *[]string { return &c.Redaction.Patterns }),
+	boolField("redaction.anonymize", func(c *Config) *bool { return &c.Redaction.Anonymize }),
+	stringField("prompt.instructions", func(c *Config) *string { return &c.Prompt.Instructions }),
+	stringField("prompt.language", func(c *Config) *string { return &c.Prompt.Language }),
+	stringField("prompt.system", func(c *Config) *string { return &c.Prompt.System }),
+	stringField("prompt.templates", func(c *Config) *string { return &c.Prompt.Templates }),
+	stringField("network.proxy", func(c *Config) *string { return &c.Network.Proxy }),
+	stringField("network.ca_file", func(c *Config) *string { return &c.Network.CAFile }),
+	stringField("ui.locale", func(c *Config) *string { return &c.UI.Locale }),
+	listField("prune.ignore", func(c *Config) *[]string { return &c.Prune.Ignore }),
+	intField("archive.months", func(c *Config) *int { return &c.Archive.Months }),
+	stringField("sync.url", func(c *Config) *string { return &c.Sync.URL }),
+	stringField("sync.endpoint", func(c *Config) *string { return &c.Sync.Endpoint }),
+	stringField("sync.region", func(c *Config) *string { return &c.Sync.Region }),
+	stringField("sync.username", func(c *Config) *string { return &c.Sync.Username }),
+	stringField("sync.identity", func(c *Config) *string { return &c.Sync.Identity }),
+	stringField("team.repo", func(c *Config) *string { return &c.Team.Repo }),
+	stringField("team.branch", func(c *Config) *string { return &c.Team.Branch }),
+	boolField("tips.enabled", func(c *Config) *bool { return &c.Tips.Enabled }),
+	listField("compare.models", func(c *Config) *[]string { return &c.Compare.Models }),
+	stringField("mock.responses", func(c *Config) *string { return &c.Mock.Responses }),
+	boolField("context.environment", func(c *Config) *bool { return &c.Context.Environment }),
+	boolField("context.git", func(c *Config) *bool { return &c.Context.Git }),
+	boolField("context.cloud", func(c *Config) *bool { return &c.Context.Cloud }),
+	stringField("context.selection", func(c *Config) *string { return &c.Context.Selection }),
+	intField("context.max_tokens", func(c *Config) *int { return &c.Context.MaxTokens }),
+	boolField("log.enabled", func(c *Config) *bool { return &c.Log.Enabled }),
+	stringField("log.file", func(c *Config) *string { return &c.Log.File }),
+	stringField("log.level", func(c *Config) *string { return &c.Log.Level }),
+	intField("log.max_size", func(c *Config) *int { return &c.Log.MaxSize }),
+	intField("log.max_backups", func(c *Config) *int { return &c.Log.MaxBackups }),
+	boolField("outbound.log", func(c *Config) *bool { return &c.Outbound.Log }),
+	stringField("outbound.file", func(c *Config) *string { return &c.Outbound.File }),
+	stringField("safety.harassment", func(c *Config) *string { return &c.Safety.Harassment }),
+	stringField("safety.hate_speech", func(c *Config) *string { return &c.Safety.HateSpeech }),
+	stringField("safety.sexually_explicit", func(c *Config) *string { return &c.Safety.SexuallyExplicit }),
+	stringField("safety.dangerous_content", func(c *Config) *string { return &c.Safety.DangerousContent }),
+}
+
+func stringField(key string, ptr func(c *Config) *string) field {
+	return field{
+		key:   key,
+		typed: func(c *Config) any { return *ptr(c) },
+		get:   func(c *Config) string { return *ptr(c) },
+		set: func(c *Config, value string) error {
+			*ptr(c) = value
+			return nil
+		},
+	}
+}
+
+func intField(key string, ptr func(c *Config) *int) field {
+	return field{
+		key:   key,
+		typed: func(c *Config) any { return *ptr(c) },
+		get:   func(c *Config) string { return strconv.Itoa(*ptr(c)) },
+		set: func(c *Config, value string) error {
+			parsed, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("%q is not an integer", value)
+			}
+			*ptr(c) = parsed
+			return nil
+		},
+	}
+}
+
+func boolField(key string, ptr func(c *Config) *bool) field {
+	return field{
+		key:   key,
+		typed: func(c *Config) any { return *ptr(c) },
+		get:   func(c *Config) string { return strconv.FormatBool(*ptr(c)) },
+		set: func(c *Config, value string) error {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("%q is not a boolean", value)
+			}
+			*ptr(c) = parsed
+			return nil
+		},
+	}
+}
+
+// listField values are written as a YAML flow sequence (["a", "b"]);
+// a plain value is treated as a single-element list.
+func listField(key string, ptr func(c *Config) *[]string) field {
+	return field{
+		key:   key,
+		typed: func(c *Config) any { return *ptr(c) },
+		get: func(c *Config) string {
+			list := *ptr(c)
+			if len(list) == 0 {
+				return "[]"
+			}
+			quoted := make([]string, len(list))
+			for i, item := range list {
+				quoted[i] = strconv.Quote(item)
+			}
+			return "[" + strings.Join(quoted, ", ") + "]"
+		},
+		set: func(c *Config, value string) error {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				*ptr(c) = nil
+				return nil
+			}
+			if !strings.HasPrefix(value, "[") {
+				*ptr(c) = []string{value}
+				return nil
+			}
+			var list []string
+			if err := yaml.Unmarshal([]byte(value), &list); err != nil {
+				return fmt.Errorf("%q is not a list: %w", value, err)
+			}
+			*ptr(c) = list
+			return nil
+		},
+	}
+}
+
+// Keys returns every configurable key, in display order.
+func Keys() []string {
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = f.key
+	}
+	return keys
+}
+
+// lookupField returns the field for key.
+func lookupField(key string) (field, error) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, nil
+		}
+	}
+	return field{}, fmt.Errorf("unknown config key %q (valid keys: %s)", key, strings.Join(Keys(), ", "))
+}
+
+// Get returns the current value of key formatted as a string.
+func (c *Config) Get(key string) (string, error) {
+	f, err := lookupField(key)
+	if err != nil {
+		return "", err
+	}
+	return f.get(c), nil
+}
+
+// Set overrides key with value, recording origin as where the value came from.
+func (c *Config) Set(key, value, origin string) error {
+	f, err := lookupField(key)
+	if err != nil {
+		return err
+	}
+	if err := f.set(c, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	c.setOrigin(key, origin)
+	return nil
+}
+
+// Origin describes where the value of key came from: "default", "file <path>",
+// "env <VAR>" or whatever origin was passed to Set.
+func (c *Config) Origin(key string) string {
+	if origin, ok := c.origins[key]; ok {
+		return origin
+	}
+	return "default"
+}
+
+func (c *Config) setOrigin(key, origin string) {
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+	c.origins[key] = origin
+}
+
+// leafKeys returns the dotted paths of every scalar or sequence value in a YAML document.
+func leafKeys(node *yaml.Node) []string {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	var keys []string
+	var walk func(n *yaml.Node, prefix string)
+	walk = func(n *yaml.Node, prefix string) {
+		if n.Kind != yaml.MappingNode {
+			if prefix != "" {
+				keys = append(keys, prefix)
+			}
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			name := n.Content[i].Value
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			walk(n.Content[i+1], name)
+		}
+	}
+	walk(node, "")
+	return keys
+}
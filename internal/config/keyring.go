@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/zalando/go-keyring"
+	"go.uber.org/zap"
+)
+
+// keyringService is the service name historai's secrets are stored under in the OS keyring.
+const keyringService = "historai"
+
+// KeyringUser returns the keyring account name holding the API key for provider.
+func KeyringUser(provider string) string {
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	return provider + "-api-key"
+}
+
+// StoreAPIKey saves the API key for provider in the OS keyring.
+func StoreAPIKey(provider, apiKey string) error {
+	if err := keyring.Set(keyringService, KeyringUser(provider), apiKey); err != nil {
+		return fmt.Errorf("failed to store API key in the OS keyring: %w", err)
+	}
+	return nil
+}
+
+// DeleteAPIKey removes the API key for provider from the OS keyring.
+// It is not an error if no key was stored.
+func DeleteAPIKey(provider string) error {
+	err := keyring.Delete(keyringService, KeyringUser(provider))
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete API key from the OS keyring: %w", err)
+	}
+	return nil
+}
+
+// loadKeyringAPIKey reads the API key for provider from the OS keyring.
+// Keyring errors (e.g. no secret service on a headless machine) are logged, not returned.
+func loadKeyringAPIKey(logger *zap.Logger, provider string) string {
+	apiKey, err := keyring.Get(keyringService, KeyringUser(provider))
+	if err != nil {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			logger.Debug("OS keyring unavailable", zap.Error(err))
+		}
+		return ""
+	}
+	return apiKey
+}
+
+// EnvEncryptionKey holds the key historai's files are encrypted with at rest, for
+// machines without a keyring. It takes precedence over the keyring.
+const EnvEncryptionKey = "HISTORAI_ENCRYPTION_KEY"
+
+// encryptionKeyUser is the keyring account name holding the encryption key.
+const encryptionKeyUser = "encryption-key"
+
+// ErrNoEncryptionKey is returned by LoadEncryptionKey when no key is stored.
+var ErrNoEncryptionKey = errors.New("no encryption key in the OS keyring or " + EnvEncryptionKey + " (run 'historai encryption enable')")
+
+// LoadEncryptionKey returns the encoded key historai's files are encrypted with,
+// from EnvEncryptionKey or the OS keyring.
+func LoadEncryptionKey() (string, error) {
+	if key := os.Getenv(EnvEncryptionKey); key != "" {
+		return key, nil
+	}
+	key, err := keyring.Get(keyringService, encryptionKeyUser)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNoEncryptionKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the encryption key from the OS keyring: %w", err)
+	}
+	return key, nil
+}
+
+// StoreEncryptionKey saves the encoded encryption key in the OS keyring.
+func StoreEncryptionKey(key string) error {
+	if err := keyring.Set(keyringService, encryptionKeyUser, key); err != nil {
+		return fmt.Errorf("failed to store the encryption key in the OS keyring: %w", err)
+	}
+	return nil
+}
+
+// DeleteEncryptionKey removes the encryption key from the OS keyring.
+// It is not an error if no key was stored.
+func DeleteEncryptionKey() error {
+	err := keyring.Delete(keyringService, encryptionKeyUser)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete the encryption key from the OS keyring: %w", err)
+	}
+	return nil
+}
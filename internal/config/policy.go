@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// policyFileName is the name of the organization policy file in PolicyDir.
+const policyFileName = "policy.yaml"
+
+// mockProvider is the built-in offline provider, which sends nothing anywhere and
+// so is allowed by every policy.
+const mockProvider = "mock"
+
+// ErrPolicy is wrapped by the errors of actions the organization policy doesn't allow.
+var ErrPolicy = errors.New("not allowed by the organization policy")
+
+// Policy is set by the organization's administrators in the policy file (see
+// PolicyPath), and overrides the user's configuration: neither the config files nor
+// the environment can loosen it.
+type Policy struct {
+	// Providers lists the LLM providers historai may use, such as gemini or the name
+	// of a provider plugin. Empty allows any.
+	Providers []string `yaml:"providers"`
+
+	// Redaction lists regexes of secrets masked before anything is sent to the LLM,
+	// on top of the user's redaction.patterns.
+	Redaction []string `yaml:"redaction"`
+
+	// LocalOnly keeps history on the machine: the built-in Gemini provider, sync to
+	// remote storage and cloning a remote team repository are refused. Plugin
+	// providers listed in Providers, e.g. one running a local model, may still be used.
+	LocalOnly bool `yaml:"local_only"`
+
+	// Deny lists regexes of commands historai never runs, e.g. with 'use --run' or
+	// 'saved --run'.
+	Deny []string `yaml:"deny"`
+
+	// Path is the file the policy was loaded from.
+	Path string `yaml:"-"`
+
+	deny []*regexp.Regexp
+}
+
+// PolicyDir returns the directory holding the organization policy file:
+// /etc/historai, or %ProgramData%\historai on Windows. Unlike the config directory,
+// it can't be changed by the user.
+func PolicyDir() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, appName)
+	}
+	return filepath.Join("/etc", appName)
+}
+
+// PolicyPath returns the location of the organization policy file.
+func PolicyPath() string {
+	return filepath.Join(PolicyDir(), policyFileName)
+}
+
+// loadPolicy reads the policy file at path, if it exists, and applies it on top of
+// the user's configuration. A policy that exists but can't be read or parsed is an
+// error rather than ignored, so a broken policy doesn't silently lift its rules.
+func (c *Config) loadPolicy(logger *zap.Logger, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read policy file %s: %w", path, err)
+	}
+
+	policy := &Policy{Path: path}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	for _, pattern := range policy.Redaction {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q in %s: %w", pattern, path, err)
+		}
+	}
+	for _, pattern := range policy.Deny {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid deny pattern %q in %s: %w", pattern, path, err)
+		}
+		policy.deny = append(policy.deny, re)
+	}
+
+	if len(policy.Redaction) > 0 {
+		c.Redaction.Patterns = append(c.Redaction.Patterns, policy.Redaction...)
+		c.setOrigin("redaction.patterns", "policy "+path)
+	}
+	c.Policy = policy
+	logger.Debug("Loaded policy file", zap.String("path", path))
+	return nil
+}
+
+// AllowProvider returns an error wrapping ErrPolicy if the policy doesn't allow
+// provider, the default one when empty. A nil policy allows any.
+func (p *Policy) AllowProvider(provider string) error {
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	switch {
+	case p == nil, provider == mockProvider:
+		return nil
+	case p.LocalOnly && provider == DefaultProvider:
+		return fmt.Errorf("llm.provider %s sends history off the machine, which is %w %s (local_only)", provider, ErrPolicy, p.Path)
+	case len(p.Providers) > 0 && !slices.Contains(p.Providers, provider):
+		return fmt.Errorf("llm.provider %s is %w %s (allowed: %s)", provider, ErrPolicy, p.Path, strings.Join(p.Providers, ", "))
+	}
+	return nil
+}
+
+// RequireNetwork returns an error wrapping ErrPolicy if the policy is local-only,
+// for action, which would send data off the machine. A nil policy allows it.
+func (p *Policy) RequireNetwork(action string) error {
+	if p == nil || !p.LocalOnly {
+		return nil
+	}
+	return fmt.Errorf("%s sends data off the machine, which is %w %s (local_only)", action, ErrPolicy, p.Path)
+}
+
+// Denied returns the deny pattern of the policy matching command, or "" if command
+// may be run. A nil policy denies nothing.
+func (p *Policy) Denied(command string) string {
+	if p == nil {
+		return ""
+	}
+	for _, re := range p.deny {
+		if re.MatchString(command) {
+			return re.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the per-project config file looked up from the working directory.
+const ProjectFileName = ".historai.yaml"
+
+// projectKeys are the keys a project config file may set. Keys that select
+// executables or files (provider, source, history file) are deliberately
+// excluded, so cloning an untrusted repository cannot redirect historai.
+// redaction.patterns is added to the user's patterns rather than replacing them,
+// so a project can only redact more.
+var projectKeys = map[string]bool{
+	"llm.model":            true,
+	"history.project_only": true,
+	"find.limit":           true,
+	"suggest.limit":        true,
+	"redaction.patterns":   true,
+	"prompt.instructions":  true,
+	"prompt.language":      true,
+	"prompt.system":        true,
+}
+
+// FindProjectFile looks for ProjectFileName in dir and its parents, stopping at the
+// first directory containing .git (the repository root). It returns "" if none is found.
+func FindProjectFile(dir string) string {
+	for {
+		candidate := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadProject merges the allowed keys of the project config file, if any, into c.
+func (c *Config) loadProject(logger *zap.Logger) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		logger.Debug("Could not determine working directory, skipping project config", zap.Error(err))
+		return nil
+	}
+	path := FindProjectFile(cwd)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read project config file %s: %w", path, err)
+	}
+
+	var root yaml.Node
+	project := &Config{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("failed to parse project config file %s: %w", path, err)
+	}
+	if err := root.Decode(project); err != nil {
+		return fmt.Errorf("failed to parse project config file %s: %w", path, err)
+	}
+
+	for _, key := range leafKeys(&root) {
+		if !projectKeys[key] {
+			logger.Warn("Ignoring key not allowed in project config", zap.String("key", key), zap.String("path", path))
+			continue
+		}
+		f, err := lookupField(key)
+		if err != nil {
+			logger.Warn("Ignoring unknown key in project config", zap.String("key", key), zap.String("path", path))
+			continue
+		}
+		if key == "redaction.patterns" {
+			c.Redaction.Patterns = append(c.Redaction.Patterns, project.Redaction.Patterns...)
+		} else if err := f.set(c, f.get(project)); err != nil {
+			return fmt.Errorf("invalid value for %s in %s: %w", key, path, err)
+		}
+		c.setOrigin(key, "project "+path)
+	}
+
+	c.ProjectRoot = filepath.Dir(path)
+	logger.Debug("Loaded project config file", zap.String("path", path))
+	return nil
+}
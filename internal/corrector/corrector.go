@@ -0,0 +1,331 @@
+// Package corrector fixes common mistakes in a command line with local rules, without
+// any LLM call: typos in program names and subcommands, a missing sudo, and flags
+// that the error output says are missing.
+package corrector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Reasons a command was corrected, shown to the user.
+const (
+	ReasonSudo        = "missing sudo"
+	ReasonProgram     = "typo in the program name"
+	ReasonSubcommand  = "typo in the subcommand"
+	ReasonSuggested   = "suggested by the error output"
+	ReasonRecursive   = "missing -r for a directory"
+	ReasonMakeParents = "missing -p for parent directories"
+)
+
+// Correction is a corrected command line and why it was changed.
+type Correction struct {
+	Command string `json:"command" yaml:"command"`
+	Reason  string `json:"reason" yaml:"reason"`
+}
+
+var (
+	// permissionPattern matches error output of commands that need root.
+	permissionPattern = regexp.MustCompile(`(?i)permission denied|operation not permitted|are you root|must be (run as )?root|requires? (root|superuser)|superuser privileges|EACCES`)
+
+	// notFoundPattern matches a shell's error for an unknown program.
+	notFoundPattern = regexp.MustCompile(`(?i)command not found|not found$|unknown command`)
+
+	// similarPattern matches git's and other tools' "did you mean" hints, e.g.
+	// "The most similar command is\n\tcommit".
+	similarPattern = regexp.MustCompile(`(?i)(?:most similar commands? (?:is|are)|did you mean(?: this)?\??)[:\s]*\n?\s*['"]?([A-Za-z][\w-]*)`)
+
+	// suggestedCommandPattern matches a full command the error output tells the user
+	// to run, e.g. git's "git push --set-upstream origin feature".
+	suggestedCommandPattern = regexp.MustCompile(`(?m)^\s+(git push --set-upstream \S+ \S+)\s*$`)
+
+	// Error output of cp and rm given a directory without -r, and of mkdir given a
+	// path whose parents don't exist.
+	directoryPattern = regexp.MustCompile(`(?i)is a directory|-r not specified|omitting directory`)
+	parentsPattern   = regexp.MustCompile(`(?i)cannot create directory.*no such file or directory`)
+)
+
+// adminCommands need root when they change the system; run without sudo, their
+// failure is taken to be missing sudo even without error output.
+var adminCommands = map[string]bool{
+	"apt install": true, "apt remove": true, "apt update": true, "apt upgrade": true,
+	"apt-get install": true, "apt-get remove": true, "apt-get update": true, "apt-get upgrade": true,
+	"dnf install": true, "dnf remove": true, "yum install": true, "pacman -S": true, "pacman -Syu": true,
+	"systemctl start": true, "systemctl stop": true, "systemctl restart": true, "systemctl enable": true,
+	"systemctl disable": true, "mount": true, "umount": true, "chown": true, "useradd": true,
+}
+
+// Corrector corrects command lines, knowing the programs on $PATH and the programs
+// and subcommands in the user's history.
+type Corrector struct {
+	executables map[string]bool
+
+	// programs counts successful runs of each program in history.
+	programs map[string]int
+
+	// subcommands counts the first arguments each program was successfully run with.
+	subcommands map[string]map[string]int
+}
+
+// New creates a Corrector learning from the commands in entries that didn't fail,
+// and from the given executable names.
+func New(entries []history.HistoryEntry, executables []string) *Corrector {
+	c := &Corrector{
+		executables: make(map[string]bool, len(executables)),
+		programs:    make(map[string]int),
+		subcommands: make(map[string]map[string]int),
+	}
+	for _, name := range executables {
+		c.executables[name] = true
+	}
+	for _, entry := range entries {
+		if entry.Failed() {
+			continue
+		}
+		fields := strings.Fields(entry.Command)
+		i := programIndex(fields)
+		if i < 0 {
+			continue
+		}
+		program := fields[i]
+		c.programs[program]++
+		if i+1 < len(fields) && isWord(fields[i+1]) {
+			if c.subcommands[program] == nil {
+				c.subcommands[program] = make(map[string]int)
+			}
+			c.subcommands[program][fields[i+1]]++
+		}
+	}
+	return c
+}
+
+// Correct returns the most likely correction of entry, using its exit code and
+// error output when they were recorded. It reports false when no rule applies.
+func (c *Corrector) Correct(entry history.HistoryEntry) (Correction, bool) {
+	command := strings.TrimSpace(entry.Command)
+	fields := strings.Fields(command)
+	i := programIndex(fields)
+	if i < 0 {
+		return Correction{}, false
+	}
+	program := fields[i]
+	stderr := entry.Stderr
+	failed := entry.Failed() || stderr != ""
+
+	// The error output's own suggestion is the most reliable.
+	if match := suggestedCommandPattern.FindStringSubmatch(stderr); match != nil {
+		return Correction{Command: match[1], Reason: ReasonSuggested}, true
+	}
+	if match := similarPattern.FindStringSubmatch(stderr); match != nil && i+1 < len(fields) && match[1] != fields[i+1] {
+		return Correction{Command: replaceField(fields, i+1, match[1]), Reason: ReasonSuggested}, true
+	}
+
+	unknown := !c.known(program) && (entry.ExitCode == nil || *entry.ExitCode == 127 || notFoundPattern.MatchString(stderr))
+	if unknown {
+		if name := closest(program, c.programCandidates(), c.programs); name != "" {
+			return Correction{Command: replaceField(fields, i, name), Reason: ReasonProgram}, true
+		}
+	}
+	if !failed {
+		return Correction{}, false
+	}
+
+	sudo := fields[0] == "sudo"
+	if !sudo && program != "cd" && (permissionPattern.MatchString(stderr) || (stderr == "" && c.isAdmin(fields, i))) {
+		return Correction{Command: "sudo " + command, Reason: ReasonSudo}, true
+	}
+	if directoryPattern.MatchString(stderr) && (program == "cp" || program == "rm" || program == "scp") {
+		return Correction{Command: insertField(fields, i+1, "-r"), Reason: ReasonRecursive}, true
+	}
+	if parentsPattern.MatchString(stderr) && program == "mkdir" {
+		return Correction{Command: insertField(fields, i+1, "-p"), Reason: ReasonMakeParents}, true
+	}
+	if i+1 < len(fields) && isWord(fields[i+1]) {
+		known := c.subcommands[program]
+		if sub := fields[i+1]; known[sub] == 0 {
+			candidates := make([]string, 0, len(known))
+			for name := range known {
+				candidates = append(candidates, name)
+			}
+			if name := closest(sub, candidates, known); name != "" {
+				return Correction{Command: replaceField(fields, i+1, name), Reason: ReasonSubcommand}, true
+			}
+		}
+	}
+	return Correction{}, false
+}
+
+// known reports whether program is an executable on $PATH or ran successfully before,
+// which covers aliases and functions.
+func (c *Corrector) known(program string) bool {
+	return c.executables[program] || c.programs[program] > 0 || strings.ContainsAny(program, "/"+string(filepath.Separator))
+}
+
+// programCandidates returns the names a mistyped program may have been meant as.
+func (c *Corrector) programCandidates() []string {
+	candidates := make([]string, 0, len(c.executables)+len(c.programs))
+	for name := range c.executables {
+		candidates = append(candidates, name)
+	}
+	for name := range c.programs {
+		if !c.executables[name] {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// isAdmin reports whether fields, with the program at i, run one of adminCommands.
+func (c *Corrector) isAdmin(fields []string, i int) bool {
+	if adminCommands[fields[i]] {
+		return true
+	}
+	return i+1 < len(fields) && adminCommands[fields[i]+" "+fields[i+1]]
+}
+
+// Executables lists the names of the executable files in the directories of path, a
+// list like $PATH. Windows has no executable bit: there, files with an extension
+// listed in %PATHEXT% are executables, named without it as they are typed.
+func Executables(path string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(path) {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			name, executable := file.Name(), file.Type()&os.ModeSymlink != 0
+			if runtime.GOOS == "windows" {
+				name, executable = windowsExecutableName(name)
+			} else if info, err := file.Info(); err == nil && info.Mode()&0o111 != 0 {
+				executable = true
+			}
+			if executable && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// windowsExecutableName returns name without its extension, and whether that
+// extension makes it an executable according to %PATHEXT%.
+func windowsExecutableName(name string) (string, bool) {
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = ".COM;.EXE;.BAT;.CMD"
+	}
+	ext := filepath.Ext(name)
+	for _, known := range filepath.SplitList(pathExt) {
+		if ext != "" && strings.EqualFold(ext, known) {
+			return strings.TrimSuffix(name, ext), true
+		}
+	}
+	return name, false
+}
+
+// programIndex returns the index of the program in fields, after sudo and variable
+// assignments, or -1 if there is none.
+func programIndex(fields []string) int {
+	for i, field := range fields {
+		if field != "sudo" && !strings.Contains(field, "=") {
+			return i
+		}
+	}
+	return -1
+}
+
+// isWord reports whether arg looks like a subcommand rather than a flag or value.
+func isWord(arg string) bool {
+	if arg == "" || arg[0] < 'a' || arg[0] > 'z' {
+		return false
+	}
+	for _, r := range arg {
+		if !('a' <= r && r <= 'z') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceField returns fields joined with the field at i replaced by value.
+func replaceField(fields []string, i int, value string) string {
+	replaced := append([]string(nil), fields...)
+	replaced[i] = value
+	return strings.Join(replaced, " ")
+}
+
+// insertField returns fields joined with value inserted at i.
+func insertField(fields []string, i int, value string) string {
+	inserted := append(append(append([]string(nil), fields[:i]...), value), fields[i:]...)
+	return strings.Join(inserted, " ")
+}
+
+// closest returns the candidate nearest to word by edit distance, if it is close
+// enough to be a typo: one edit for short words, two for longer ones. Ties go to the
+// candidate with the highest count, then alphabetically.
+func closest(word string, candidates []string, counts map[string]int) string {
+	maxDistance := 1
+	if len(word) > 4 {
+		maxDistance = 2
+	}
+	sort.Strings(candidates)
+	best, bestDistance := "", maxDistance+1
+	for _, candidate := range candidates {
+		if candidate == word || abs(len(candidate)-len(word)) > maxDistance {
+			continue
+		}
+		d := distance(word, candidate)
+		if d < bestDistance || (d == bestDistance && counts[candidate] > counts[best]) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// distance is the optimal string alignment distance between a and b: the number of
+// insertions, deletions, substitutions and transpositions of adjacent characters
+// that turn one into the other.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
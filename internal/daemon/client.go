@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const dialTimeout = 100 * time.Millisecond
+
+// ErrConfigMismatch is returned by Do when the daemon refused a request because it
+// runs with a different configuration than the client's.
+var ErrConfigMismatch = errors.New("the daemon's configuration differs from this one")
+
+// Client talks to a running daemon over its unix socket.
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	encoder *json.Encoder
+}
+
+// Dial connects to the daemon listening on socketPath.
+func Dial(socketPath string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		encoder: json.NewEncoder(conn),
+	}, nil
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends req and waits for the daemon's response.
+func (c *Client) Do(req Request) (*Response, error) {
+	if err := c.encoder.Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to daemon: %w", err)
+	}
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from daemon: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response from daemon: %w", err)
+	}
+	if resp.ConfigMismatch {
+		return &resp, ErrConfigMismatch
+	}
+	if resp.Error != "" {
+		err := &remoteError{message: resp.Error, blocked: resp.Blocked, unavailable: resp.Unavailable}
+		if resp.Reason != "" {
+			return &resp, &llm.ProviderError{Reason: resp.Reason, Hint: resp.Hint, Config: resp.Config, Err: err}
+		}
+		return &resp, err
+	}
+	return &resp, nil
+}
+
+// remoteError is an error reported by the daemon. It matches llm.ErrBlocked and
+// llm.ErrUnavailable when the daemon's error did, so callers can classify errors the
+// same way either way.
+type remoteError struct {
+	message     string
+	blocked     bool
+	unavailable bool
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Is(target error) bool {
+	return (e.blocked && target == llm.ErrBlocked) || (e.unavailable && target == llm.ErrUnavailable)
+}
+
+// Ping checks whether a daemon is responding on socketPath.
+func Ping(socketPath string) error {
+	client, err := Dial(socketPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	_, err = client.Do(Request{Op: OpPing})
+	return err
+}
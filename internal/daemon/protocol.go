@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// Operations understood by the daemon.
+const (
+	OpPing    = "ping"
+	OpFind    = "find"
+	OpSuggest = "suggest"
+	OpHistory = "history"
+	OpNext    = "next"
+
+	// OpCacheStats and OpCacheClear inspect and empty the cache of parsed history.
+	OpCacheStats = "cache-stats"
+	OpCacheClear = "cache-clear"
+)
+
+const socketFileName = "historai.sock"
+
+// Request is a single newline-delimited JSON request sent over the unix socket.
+type Request struct {
+	Op               string `json:"op"`
+	Query            string `json:"query,omitempty"`
+	Limit            int    `json:"limit,omitempty"`
+	NoHistoryContext bool   `json:"no_history_context,omitempty"`
+
+	// Recent is the number of latest commands to continue from, for OpNext.
+	Recent int `json:"recent,omitempty"`
+
+	// Tag restricts OpFind to commands with this tag.
+	Tag string `json:"tag,omitempty"`
+
+	// Dir is the client's working directory, for the context of OpSuggest.
+	Dir string `json:"dir,omitempty"`
+
+	// Listing sends the names of the files in Dir with OpSuggest.
+	Listing bool `json:"listing,omitempty"`
+
+	// Env holds the client's cloud environment variables, for the context of OpSuggest.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Config is the fingerprint of the client's effective configuration (see
+	// config.Config.Fingerprint). When set, the daemon refuses the request if its
+	// own configuration differs, so the client runs it locally instead.
+	Config string `json:"config,omitempty"`
+}
+
+// Response is the newline-delimited JSON reply to a Request.
+type Response struct {
+	Result  string                 `json:"result,omitempty"`
+	Entries []history.HistoryEntry `json:"entries,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+
+	// Answer is the answer to OpFind, OpSuggest and OpNext.
+	Answer *llm.CommandAnswer `json:"answer,omitempty"`
+
+	// Cache describes the cache of parsed history, for OpCacheStats and OpCacheClear.
+	Cache *CacheStats `json:"cache,omitempty"`
+
+	// Blocked reports that Error was caused by the provider's safety filters.
+	Blocked bool `json:"blocked,omitempty"`
+
+	// Unavailable reports that Error means the provider couldn't be reached.
+	Unavailable bool `json:"unavailable,omitempty"`
+
+	// Reason and Hint describe a provider failure the user can fix, and Config that
+	// the fix is in the configuration; see llm.ProviderError.
+	Reason string `json:"reason,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+	Config bool   `json:"config,omitempty"`
+
+	// ConfigMismatch reports that the request was refused because the client's
+	// configuration differs from the daemon's.
+	ConfigMismatch bool `json:"config_mismatch,omitempty"`
+}
+
+// CacheStats describes the daemon's cache of parsed history.
+type CacheStats struct {
+	// Entries is how many parsed entries are held, before OpCacheClear drops them.
+	Entries int `json:"entries" yaml:"entries"`
+
+	// Hits and Misses count the reads answered from memory and those that parsed
+	// the history file, since the daemon started.
+	Hits   uint64 `json:"hits" yaml:"hits"`
+	Misses uint64 `json:"misses" yaml:"misses"`
+}
+
+// DefaultSocketPath returns the unix socket path, preferring $XDG_RUNTIME_DIR.
+func DefaultSocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, socketFileName)
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "historai", socketFileName)
+	}
+	return filepath.Join(os.TempDir(), socketFileName)
+}
@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/metrics"
+)
+
+// Server serves engine requests over a unix socket.
+type Server struct {
+	logger     *zap.Logger
+	engine     *engine.Engine
+	socketPath string
+	metrics    *metrics.Registry
+
+	// fingerprint is that of the configuration eng was created with.
+	fingerprint string
+}
+
+// NewServer creates a daemon server backed by a warm engine, created with cfg.
+func NewServer(logger *zap.Logger, eng *engine.Engine, cfg *config.Config, socketPath string) *Server {
+	return &Server{
+		logger:      logger,
+		engine:      eng,
+		socketPath:  socketPath,
+		metrics:     metrics.New(eng.HistoryCacheStats),
+		fingerprint: cfg.Fingerprint(),
+	}
+}
+
+// Metrics returns the metrics of the requests served.
+func (s *Server) Metrics() *metrics.Registry {
+	return s.metrics
+}
+
+// ListenAndServe listens on the unix socket and serves connections until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0o700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	// Remove a stale socket left behind by a previous daemon.
+	if _, err := os.Stat(s.socketPath); err == nil {
+		if Ping(s.socketPath) == nil {
+			return fmt.Errorf("a daemon is already listening on %s", s.socketPath)
+		}
+		_ = os.Remove(s.socketPath)
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	defer func() {
+		_ = os.Remove(s.socketPath)
+	}()
+	if err := os.Chmod(s.socketPath, 0o600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	s.logger.Info("Daemon listening", zap.String("socket", s.socketPath))
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			s.logger.Error("Failed to accept connection", zap.Error(err))
+			continue
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn processes requests on a single connection until the client disconnects.
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp = s.handle(req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			s.logger.Debug("Failed to write response", zap.Error(err))
+			return
+		}
+	}
+}
+
+// cacheStats describes the engine's history cache.
+func (s *Server) cacheStats() (*CacheStats, error) {
+	entries, ok := s.engine.HistoryCacheEntries()
+	if !ok {
+		return nil, errors.New("history is not cached")
+	}
+	hits, misses, _ := s.engine.HistoryCacheStats()
+	return &CacheStats{Entries: entries, Hits: hits, Misses: misses}, nil
+}
+
+// handle dispatches a single request to the engine.
+func (s *Server) handle(req Request) Response {
+	s.logger.Debug("Handling daemon request", zap.String("op", req.Op))
+	started := time.Now()
+
+	var resp Response
+	if req.Config != "" && req.Config != s.fingerprint {
+		// The client's project config, flags or environment would change the answer.
+		s.logger.Debug("Refusing request made with a different configuration", zap.String("op", req.Op))
+		resp.Error = ErrConfigMismatch.Error()
+		resp.ConfigMismatch = true
+		return resp
+	}
+	var answer llm.CommandAnswer
+	var err error
+	switch req.Op {
+	case OpPing:
+		resp.Result = "pong"
+	case OpFind:
+		answer, err = s.engine.FindTagStream(context.Background(), req.Query, req.Tag, req.Limit, nil)
+		resp.Answer = &answer
+	case OpSuggest:
+		answer, err = s.engine.SuggestStream(context.Background(), req.Query, req.Limit, req.NoHistoryContext, engine.SuggestContext{Dir: req.Dir, Listing: req.Listing, Env: req.Env}, nil)
+		resp.Answer = &answer
+	case OpNext:
+		answer, err = s.engine.Next(context.Background(), req.Recent, req.Limit)
+		resp.Answer = &answer
+	case OpHistory:
+		resp.Entries, err = s.engine.History(req.Limit)
+	case OpCacheStats, OpCacheClear:
+		resp.Cache, err = s.cacheStats()
+		if err == nil && req.Op == OpCacheClear {
+			s.engine.ClearHistoryCache()
+			s.logger.Info("Cleared the history cache", zap.Int("entries", resp.Cache.Entries))
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", req.Op)
+	}
+	switch req.Op {
+	case OpFind, OpSuggest, OpNext, OpHistory:
+		s.metrics.Observe(req.Op, time.Since(started), err)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		resp.Blocked = errors.Is(err, llm.ErrBlocked)
+		resp.Unavailable = llm.IsUnavailable(err)
+		var providerErr *llm.ProviderError
+		if errors.As(err, &providerErr) {
+			resp.Reason, resp.Hint, resp.Config = providerErr.Reason, providerErr.Hint, providerErr.Config
+		}
+	}
+	return resp
+}
@@ -0,0 +1,219 @@
+// Package editor serves historai to editor extensions (VS Code, Neovim, ...) as
+// newline-delimited JSON-RPC 2.0 over stdio. Requests are handled concurrently and
+// can be cancelled with "$/cancelRequest", as in LSP; find and suggest can stream
+// their commands as "commands/partial" notifications before the response.
+package editor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/jsonrpc"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	serverName = "historai"
+
+	defaultFindLimit    = 300
+	defaultSuggestLimit = 100
+	defaultHistoryLimit = 50
+)
+
+// Methods understood by the server, besides jsonrpc.CancelMethod.
+const (
+	methodInitialize    = "initialize"
+	methodRecentHistory = "history/recent"
+	methodFind          = "history/find"
+	methodSuggest       = "commands/suggest"
+
+	// methodPartial is the notification carrying a command of a streamed answer.
+	methodPartial = "commands/partial"
+)
+
+// Server answers the requests of an editor extension with an engine.
+type Server struct {
+	logger  *zap.Logger
+	engine  *engine.Engine
+	version string
+}
+
+// historyParams are the parameters of history/recent.
+type historyParams struct {
+	Limit int `json:"limit"`
+}
+
+// historyResult is the result of history/recent.
+type historyResult struct {
+	Entries []history.HistoryEntry `json:"entries"`
+}
+
+// findParams are the parameters of history/find.
+type findParams struct {
+	Query string `json:"query"`
+	Tag   string `json:"tag"`
+	Limit int    `json:"limit"`
+
+	// Stream sends each command as a commands/partial notification once complete.
+	Stream bool `json:"stream"`
+}
+
+// suggestParams are the parameters of commands/suggest.
+type suggestParams struct {
+	Task             string `json:"task"`
+	Limit            int    `json:"limit"`
+	NoHistoryContext bool   `json:"no_history_context"`
+
+	// Dir is the working directory of the editor's terminal or workspace, for the
+	// git context; Listing also sends the names of its files.
+	Dir     string `json:"dir"`
+	Listing bool   `json:"listing"`
+
+	Stream bool `json:"stream"`
+}
+
+// partialParams are the parameters of a commands/partial notification: a command
+// of the answer to the request with the id, ranked in answer order.
+type partialParams struct {
+	ID   json.RawMessage `json:"id"`
+	Rank int             `json:"rank"`
+	llm.CommandResult
+}
+
+// NewServer creates an editor server backed by eng.
+func NewServer(logger *zap.Logger, eng *engine.Engine, version string) *Server {
+	return &Server{
+		logger:  logger,
+		engine:  eng,
+		version: version,
+	}
+}
+
+// Serve handles requests from r, writing responses and notifications to w, until
+// EOF or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	conn := jsonrpc.NewConn(s.logger, r, w)
+	return conn.ServeConcurrent(ctx, func(ctx context.Context, req *jsonrpc.Request) (any, error) {
+		return s.handle(ctx, conn, req)
+	})
+}
+
+// handle dispatches a single request. Errors of cancelled requests are reported as
+// cancellations, whatever the provider made of them.
+func (s *Server) handle(ctx context.Context, conn *jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+	result, err := s.call(ctx, conn, req)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return result, err
+}
+
+func (s *Server) call(ctx context.Context, conn *jsonrpc.Conn, req *jsonrpc.Request) (any, error) {
+	switch req.Method {
+	case methodInitialize:
+		return map[string]any{
+			"serverInfo": map[string]any{
+				"name":    serverName,
+				"version": s.version,
+			},
+			"capabilities": map[string]any{
+				"methods":      []string{methodRecentHistory, methodFind, methodSuggest},
+				"streaming":    true,
+				"cancellation": jsonrpc.CancelMethod,
+			},
+		}, nil
+	case methodRecentHistory:
+		var params historyParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		entries, err := s.engine.History(limitOrDefault(params.Limit, defaultHistoryLimit))
+		if err != nil {
+			return nil, err
+		}
+		if entries == nil {
+			entries = []history.HistoryEntry{}
+		}
+		return historyResult{Entries: entries}, nil
+	case methodFind:
+		var params findParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if params.Query == "" {
+			return nil, jsonrpc.NewError(jsonrpc.CodeInvalidParams, "query cannot be empty")
+		}
+		onChunk, flush := s.partials(conn, req, params.Stream)
+		answer, err := s.engine.FindTagStream(ctx, params.Query, params.Tag, limitOrDefault(params.Limit, defaultFindLimit), onChunk)
+		if err != nil {
+			return nil, err
+		}
+		flush()
+		return answer, nil
+	case methodSuggest:
+		var params suggestParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if params.Task == "" {
+			return nil, jsonrpc.NewError(jsonrpc.CodeInvalidParams, "task description cannot be empty")
+		}
+		onChunk, flush := s.partials(conn, req, params.Stream)
+		sc := engine.SuggestContext{Dir: params.Dir, Listing: params.Listing}
+		answer, err := s.engine.SuggestStream(ctx, params.Task, limitOrDefault(params.Limit, defaultSuggestLimit), params.NoHistoryContext, sc, onChunk)
+		if err != nil {
+			return nil, err
+		}
+		flush()
+		return answer, nil
+	default:
+		return nil, jsonrpc.NewError(jsonrpc.CodeMethodNotFound, "method not found: "+req.Method)
+	}
+}
+
+// partials returns the ChunkFunc sending the commands of the answer to req as
+// commands/partial notifications as they complete, or nil unless stream is set.
+// flush sends the last command, which only the end of the answer completes.
+func (s *Server) partials(conn *jsonrpc.Conn, req *jsonrpc.Request, stream bool) (onChunk llm.ChunkFunc, flush func()) {
+	if !stream || req.IsNotification() {
+		return nil, func() {}
+	}
+	var parser llm.CommandParser
+	var partial []byte
+	rank := 0
+	send := func(line string) {
+		if command, ok := parser.AddLine(line); ok {
+			rank++
+			conn.Notify(methodPartial, partialParams{ID: req.ID, Rank: rank, CommandResult: command})
+		}
+	}
+	onChunk = func(text string) {
+		partial = append(partial, text...)
+		for {
+			end := bytes.IndexByte(partial, '\n')
+			if end < 0 {
+				return
+			}
+			send(string(partial[:end]))
+			partial = partial[end+1:]
+		}
+	}
+	flush = func() {
+		send(string(partial))
+		partial = nil
+	}
+	return onChunk, flush
+}
+
+func limitOrDefault(limit, fallback int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	return limit
+}
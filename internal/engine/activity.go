@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/timings"
+)
+
+// Since returns the history entries run at or after since, ignoring historai's own
+// invocations. Entries without a timestamp can't be placed in time and are dropped.
+func (e *Engine) Since(since time.Time) ([]history.HistoryEntry, error) {
+	entries, err := e.History(0)
+	if err != nil {
+		return nil, err
+	}
+	var recent []history.HistoryEntry
+	for _, entry := range entries {
+		if entry.Timestamp >= since.Unix() && history.ProgramName(entry.Command) != "historai" {
+			recent = append(recent, entry)
+		}
+	}
+	e.logger.Debug("Selected entries by time", zap.Time("since", since), zap.Int("entries_count", len(recent)))
+	return recent, nil
+}
+
+// Summarize asks the LLM for a summary of the work done since since, with the
+// commands grouped by project. period describes since for the prompt, e.g. "since yesterday".
+func (e *Engine) Summarize(ctx context.Context, since time.Time, period string) (string, error) {
+	entries, err := e.Since(since)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no commands with a timestamp found %s (does your history source record times?)", period)
+	}
+
+	groups := GroupByProject(entries)
+	e.logger.Debug("Grouped commands by project", zap.Int("projects_count", len(groups)))
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	summary, err := e.client.SummarizeActivity(ctx, llm.SummaryRequest{Period: period, Groups: e.anonymizeGroups(groups)})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get summary from LLM: %w", err)
+	}
+	return summary, nil
+}
+
+// GroupByProject groups entries by the repository root of their working directory,
+// or the directory itself outside a repository. Entries without a working directory
+// form a group with an empty Project. Groups are ordered by size, largest first.
+func GroupByProject(entries []history.HistoryEntry) []llm.ActivityGroup {
+	roots := make(map[string]string)
+	index := make(map[string]int)
+	var groups []llm.ActivityGroup
+	for _, entry := range entries {
+		project := ""
+		if entry.Cwd != "" {
+			root, ok := roots[entry.Cwd]
+			if !ok {
+				root = projectRoot(entry.Cwd)
+				roots[entry.Cwd] = root
+			}
+			project = root
+		}
+		i, ok := index[project]
+		if !ok {
+			i = len(groups)
+			index[project] = i
+			groups = append(groups, llm.ActivityGroup{Project: project})
+		}
+		groups[i].Entries = append(groups[i].Entries, entry)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Entries) > len(groups[j].Entries)
+	})
+	return groups
+}
+
+// projectRoot returns the nearest parent of dir (or dir itself) containing .git,
+// or dir when there is none, e.g. because it was deleted since.
+func projectRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// restore maps the commands of answer that the model took from the anonymized
+// history back to the user's own, with their risk assessed again. Other commands,
+// such as new ones the model wrote with placeholders, are left as they are.
+func (e *Engine) restore(answer llm.CommandAnswer) llm.CommandAnswer {
+	if e.anonymizer == nil || len(answer.Commands) == 0 {
+		return answer
+	}
+	commands := make([]llm.CommandResult, len(answer.Commands))
+	for i, command := range answer.Commands {
+		if original, ok := e.anonymizer.Original(command.Command); ok {
+			answer.Text = strings.Replace(answer.Text, command.Command, original, 1)
+			command.Command = original
+			if command.Risk != llm.RiskHigh {
+				command.Risk = llm.RiskOf(original)
+			}
+		}
+		commands[i] = command
+	}
+	answer.Commands = commands
+	return answer
+}
+
+// anonymizeGroups anonymizes the commands of groups, with redaction.anonymize on,
+// and numbers their projects rather than naming them by path.
+func (e *Engine) anonymizeGroups(groups []llm.ActivityGroup) []llm.ActivityGroup {
+	if e.anonymizer == nil {
+		return groups
+	}
+	anonymized := make([]llm.ActivityGroup, len(groups))
+	for i, group := range groups {
+		anonymized[i].Entries = e.anonymizer.Entries(group.Entries)
+		if group.Project != "" {
+			anonymized[i].Project = strconv.Itoa(i + 1)
+		}
+	}
+	return anonymized
+}
@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// archived returns the entries read reads from the archive of historai's log (see
+// 'historai archive'), redacted, for the model to search beyond the log. Other
+// sources and fixture histories have no archive. The archive is extra context, so
+// failures are logged and yield none.
+func (e *Engine) archived(read func(*history.Archive) ([]history.HistoryEntry, error)) []history.HistoryEntry {
+	if e.archive == nil {
+		return nil
+	}
+	entries, err := read(e.archive)
+	if err != nil {
+		e.logger.Warn("Ignoring archived history", zap.Error(err))
+		return nil
+	}
+	if e.cfg.History.ProjectOnly && e.cfg.ProjectRoot != "" {
+		entries = e.filterProject(entries)
+	}
+	return e.redactor.RedactEntries(entries)
+}
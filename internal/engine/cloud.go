@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// cloudEnvVars are the environment variables selecting the Kubernetes and cloud
+// accounts in use, which commonly differ between shells.
+var cloudEnvVars = []string{
+	"KUBECONFIG",
+	"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION",
+	"CLOUDSDK_CONFIG", "CLOUDSDK_ACTIVE_CONFIG_NAME", "CLOUDSDK_CORE_PROJECT",
+}
+
+// CloudEnv returns the variables of cloudEnvVars set in this process, for
+// SuggestContext.Env.
+func CloudEnv() map[string]string {
+	env := make(map[string]string)
+	for _, name := range cloudEnvVars {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// cloudContext describes the current kubectl context and namespace, AWS profile and
+// region and gcloud project, reading variables from env (or this process when nil)
+// and the tools' own config files. It returns nil when none is set. Values are
+// redacted, as they may leave the machine.
+func (e *Engine) cloudContext(env map[string]string) *llm.CloudContext {
+	getenv := os.Getenv
+	if env != nil {
+		getenv = func(name string) string { return env[name] }
+	}
+
+	cloud := &llm.CloudContext{}
+	cloud.KubeContext, cloud.KubeNamespace = kubeContext(getenv("KUBECONFIG"))
+	cloud.AWSProfile = firstNonEmpty(getenv("AWS_PROFILE"), getenv("AWS_DEFAULT_PROFILE"))
+	cloud.AWSRegion = firstNonEmpty(getenv("AWS_REGION"), getenv("AWS_DEFAULT_REGION"))
+	cloud.GCloudProject = firstNonEmpty(getenv("CLOUDSDK_CORE_PROJECT"), gcloudProject(getenv("CLOUDSDK_CONFIG"), getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")))
+	if *cloud == (llm.CloudContext{}) {
+		return nil
+	}
+
+	for _, value := range []*string{&cloud.KubeContext, &cloud.KubeNamespace, &cloud.AWSProfile, &cloud.AWSRegion, &cloud.GCloudProject} {
+		*value = e.redactor.Redact(*value)
+	}
+	e.logger.Debug("Collected cloud context", zap.String("kube_context", cloud.KubeContext), zap.String("aws_profile", cloud.AWSProfile), zap.String("gcloud_project", cloud.GCloudProject))
+	return cloud
+}
+
+// kubeconfig holds the parts of a kubeconfig file needed for the current context.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// kubeContext returns the current context of kubectl and its namespace, reading the
+// files of paths (a KUBECONFIG value) the way kubectl merges them: the first file
+// setting current-context wins, and so does the first file defining a context.
+func kubeContext(paths string) (context, namespace string) {
+	if paths == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		paths = filepath.Join(home, ".kube", "config")
+	}
+
+	var configs []kubeconfig
+	for _, path := range filepath.SplitList(paths) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var config kubeconfig
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			continue
+		}
+		if context == "" {
+			context = config.CurrentContext
+		}
+		configs = append(configs, config)
+	}
+	if context == "" {
+		return "", ""
+	}
+	for _, config := range configs {
+		for _, entry := range config.Contexts {
+			if entry.Name == context {
+				return context, firstNonEmpty(entry.Context.Namespace, "default")
+			}
+		}
+	}
+	return context, ""
+}
+
+// gcloudProject returns the core/project property of the active gcloud
+// configuration, from configDir (CLOUDSDK_CONFIG) or gcloud's default directory.
+func gcloudProject(configDir, configName string) string {
+	if configDir == "" {
+		if runtime.GOOS == "windows" {
+			configDir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			configDir = filepath.Join(home, ".config", "gcloud")
+		} else {
+			return ""
+		}
+	}
+	if configName == "" {
+		data, err := os.ReadFile(filepath.Join(configDir, "active_config"))
+		configName = strings.TrimSpace(string(data))
+		if err != nil || configName == "" {
+			configName = "default"
+		}
+	}
+
+	file, err := os.Open(filepath.Join(configDir, "configurations", "config_"+configName))
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && section == "core" && strings.TrimSpace(key) == "project" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// firstNonEmpty returns the first of values that isn't empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,834 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/outbound"
+	"github.com/sanspareilsmyn/historai/internal/redact"
+	"github.com/sanspareilsmyn/historai/internal/snippets"
+	"github.com/sanspareilsmyn/historai/internal/timings"
+	"github.com/sanspareilsmyn/historai/internal/workflow"
+)
+
+const (
+	// suggestWorkflowLimit is the number of habitual workflows sent with suggest requests.
+	suggestWorkflowLimit = 10
+
+	// quizCandidatesPerQuestion is how many commands are offered to the LLM for each
+	// quiz question, so it can pick the ones most worth learning.
+	quizCandidatesPerQuestion = 3
+)
+
+// Engine bundles the configuration, history reader and LLM client needed to
+// answer find/suggest requests. It is used both for one-shot CLI invocations
+// and by the long-running daemon, which keeps a single Engine warm.
+type Engine struct {
+	logger   *zap.Logger
+	cfg      *config.Config
+	reader   history.HistoryReader
+	client   llm.LLMClient
+	redactor *redact.Redactor
+
+	// anonymizer, when not nil, templates the arguments of the history sent to the
+	// LLM (redaction.anonymize).
+	anonymizer *redact.Anonymizer
+
+	// archive, when not nil, holds the entries rolled out of historai's log, which
+	// the model's history tools also search.
+	archive *history.Archive
+
+	// fixture reports that the history is Options.History rather than the user's,
+	// so the user's annotations don't apply to it.
+	fixture bool
+
+	// timings, when not nil, adds up the time spent in each stage of requests.
+	timings *timings.Timings
+
+	// envOnce guards env, the machine facts detected on first use.
+	envOnce sync.Once
+	env     *llm.Environment
+}
+
+// Options controls how an Engine is constructed.
+type Options struct {
+	// CacheHistory keeps parsed history in memory until the history file changes.
+	CacheHistory bool
+
+	// History, when not nil, is used instead of the configured history source and
+	// the user's annotations, e.g. to evaluate prompts on fixed fixtures or to search
+	// commands given with --history.
+	History []history.HistoryEntry
+
+	// PromptHook, when not nil, is shown every prompt before it is sent, for clients
+	// that support it (see llm.PromptObserver).
+	PromptHook llm.PromptHook
+
+	// Offline skips the LLM client, and the API key it needs, for local searches
+	// with FindLocal when the provider can't be used. Other methods need a client.
+	Offline bool
+
+	// Timings, when not nil, adds up the time spent parsing history, retrieving
+	// context and waiting for the LLM, for one-shot invocations.
+	Timings *timings.Timings
+}
+
+// New initializes the history reader, redactor and LLM client described by cfg.
+func New(ctx context.Context, logger *zap.Logger, cfg *config.Config, opts Options) (*Engine, error) {
+	// 1. Validate Configuration
+	if !opts.Offline {
+		// Checked before the API key, which a provider the policy forbids doesn't need.
+		if err := cfg.Policy.AllowProvider(cfg.LLM.Provider); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.Offline && (cfg.LLM.Provider == "" || cfg.LLM.Provider == llm.ProviderGemini) {
+		// The error is returned, so don't log it too.
+		if err := cfg.RequireGoogleAPIKey(zap.NewNop()); err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+	redactor, err := redact.FromPatterns(cfg.Redaction.Patterns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// 2. Initialize History Reader
+	var reader history.HistoryReader = fixtureReader(opts.History)
+	if opts.History == nil {
+		if reader, err = history.NewReader(logger, cfg.History.Source, cfg.History.File); err != nil {
+			return nil, fmt.Errorf("failed to initialize history reader: %w", err)
+		}
+		if opts.CacheHistory {
+			reader = history.WithCache(logger, reader)
+		}
+	}
+	var archive *history.Archive
+	if opts.History == nil && cfg.History.Source == history.SourceHistorai {
+		if archive, err = history.OpenArchive(logger, cfg.History.File); err != nil {
+			return nil, fmt.Errorf("failed to initialize history reader: %w", err)
+		}
+	}
+
+	e := &Engine{
+		logger:   logger,
+		cfg:      cfg,
+		reader:   reader,
+		redactor: redactor,
+		archive:  archive,
+		fixture:  opts.History != nil,
+		timings:  opts.Timings,
+	}
+	if cfg.Redaction.Anonymize {
+		e.anonymizer = redact.NewAnonymizer()
+	}
+	if opts.Offline {
+		logger.Debug("Offline engine, without an LLM client")
+		return e, nil
+	}
+
+	// 3. Initialize LLM Client
+	logger.Debug("Initializing LLM client...")
+	client, err := llm.NewClient(ctx, logger, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	logger.Debug("LLM client initialized successfully")
+	hook, rivalHook := opts.PromptHook, opts.PromptHook
+	var rivalCfg *config.Config
+	if cfg.LLM.Race != "" {
+		rivalCfg = llm.ConfigForSpec(cfg, cfg.LLM.Race)
+	}
+	recordPrimary := cfg.LLM.Provider != llm.ProviderMock
+	recordRival := rivalCfg != nil && rivalCfg.LLM.Provider != llm.ProviderMock
+	if cfg.Outbound.Log && (recordPrimary || recordRival) {
+		outboundLog, err := outbound.New(cfg.Outbound.File)
+		if err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to open outbound log: %w", err)
+		}
+		if recordPrimary {
+			hook = outboundHook(hook, outboundLog, cfg)
+		}
+		if recordRival {
+			rivalHook = outboundHook(rivalHook, outboundLog, rivalCfg)
+		}
+	}
+	if racer, ok := client.(*llm.RaceClient); ok {
+		// Each prompt is recorded with the model it is sent to.
+		racer.SetPromptHooks(hook, rivalHook)
+	} else if observer, ok := client.(llm.PromptObserver); ok && hook != nil {
+		observer.SetPromptHook(hook)
+	} else if cfg.Outbound.Log && !ok {
+		logger.Warn("The LLM client can't show its prompts; they are not written to the outbound log")
+	}
+
+	e.client = client
+	if caller, ok := client.(llm.ToolCaller); ok {
+		caller.SetHistorySearcher(historySearcher{engine: e})
+	}
+	return e, nil
+}
+
+// outboundHook returns a PromptHook calling next, then recording the prompt in
+// outboundLog unless next cancelled it, as for a dry run. A prompt that can't be
+// recorded is not sent. The mock provider sends nothing, so it isn't recorded.
+func outboundHook(next llm.PromptHook, outboundLog *outbound.Log, cfg *config.Config) llm.PromptHook {
+	provider := cfg.LLM.Provider
+	if provider == "" {
+		provider = llm.ProviderGemini
+	}
+	model := llm.ModelName(cfg)
+	return func(prompt string) error {
+		if next != nil {
+			if err := next(prompt); err != nil {
+				return err
+			}
+		}
+		return outboundLog.Append(outbound.Record{
+			Time:     time.Now().UTC(),
+			Provider: provider,
+			Model:    model,
+			PID:      os.Getpid(),
+			Bytes:    len(prompt),
+			Prompt:   prompt,
+		})
+	}
+}
+
+// fixtureReader is a history.HistoryReader over fixed entries, oldest first.
+type fixtureReader []history.HistoryEntry
+
+// ReadHistory implements the HistoryReader interface method.
+func (r fixtureReader) ReadHistory(limit int) ([]history.HistoryEntry, error) {
+	if limit > 0 && limit < len(r) {
+		return r[len(r)-limit:], nil
+	}
+	return r, nil
+}
+
+// Close releases the underlying LLM client, if any.
+func (e *Engine) Close() error {
+	if e.client == nil {
+		return nil
+	}
+	e.logger.Debug("Closing LLM client...")
+	return e.client.Close()
+}
+
+// Usage returns the token usage of the engine's most recent LLM call, and false if
+// the provider doesn't report it.
+func (e *Engine) Usage() (llm.Usage, bool) {
+	if reporter, ok := e.client.(llm.UsageReporter); ok {
+		return reporter.LastUsage()
+	}
+	return llm.Usage{}, false
+}
+
+// HistoryCacheStats returns the hits and misses of the history cache kept with
+// Options.CacheHistory. ok is false when there is no cache.
+func (e *Engine) HistoryCacheStats() (hits, misses uint64, ok bool) {
+	cached, ok := e.reader.(*history.CachedHistoryReader)
+	if !ok {
+		return 0, 0, false
+	}
+	hits, misses = cached.CacheStats()
+	return hits, misses, true
+}
+
+// HistoryCacheEntries returns how many parsed entries the history cache holds. ok
+// is false when there is no cache.
+func (e *Engine) HistoryCacheEntries() (entries int, ok bool) {
+	cached, ok := e.reader.(*history.CachedHistoryReader)
+	if !ok {
+		return 0, false
+	}
+	return cached.CachedEntries(), true
+}
+
+// ClearHistoryCache drops the parsed history of the history cache, so that the
+// next request parses the history file again. It reports whether there was a cache.
+func (e *Engine) ClearHistoryCache() bool {
+	cached, ok := e.reader.(*history.CachedHistoryReader)
+	if ok {
+		cached.Clear()
+	}
+	return ok
+}
+
+// History returns the most recent history entries, limited by limit, with the
+// user's annotations. Secrets are redacted, as these entries may leave the machine.
+func (e *Engine) History(limit int) ([]history.HistoryEntry, error) {
+	return e.history(limit, nil)
+}
+
+// Selectors of the annotated commands outside the history window that history adds.
+var (
+	allAnnotated = func(*annotations.Annotation) bool { return true }
+	pinnedOnly   = func(a *annotations.Annotation) bool { return a.Pinned }
+)
+
+// history is History, preceded by the annotated commands outside the window that
+// unlisted selects. A nil unlisted adds none.
+func (e *Engine) history(limit int, unlisted func(*annotations.Annotation) bool) ([]history.HistoryEntry, error) {
+	entries, err := e.annotatedHistory(limit, unlisted)
+	if err != nil {
+		return nil, err
+	}
+	return e.redactor.RedactEntries(entries), nil
+}
+
+// annotatedHistory is history before redaction.
+func (e *Engine) annotatedHistory(limit int, unlisted func(*annotations.Annotation) bool) ([]history.HistoryEntry, error) {
+	stopParse := e.timings.Start(timings.StageParse)
+	entries, err := e.reader.ReadHistory(limit)
+	stopParse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	e.logger.Debug("History read successfully", zap.Int("entries_count", len(entries)))
+	defer e.timings.Start(timings.StageRetrieve)()
+
+	if e.cfg.History.ProjectOnly && e.cfg.ProjectRoot != "" {
+		entries = e.filterProject(entries)
+	}
+
+	if e.fixture {
+		return entries, nil
+	}
+
+	// Annotations are best-effort context; a broken sidecar file shouldn't stop a search.
+	store, err := annotations.Load("")
+	if err != nil {
+		e.logger.Warn("Ignoring annotations", zap.Error(err))
+	} else {
+		if unlisted != nil {
+			entries = append(store.Unlisted(entries, unlisted), entries...)
+		}
+		entries = store.Apply(entries)
+	}
+	return entries, nil
+}
+
+// filterProject keeps only entries run inside the project root. Sources that don't
+// record a working directory can't be filtered, so their entries are kept as-is.
+func (e *Engine) filterProject(entries []history.HistoryEntry) []history.HistoryEntry {
+	root := e.cfg.ProjectRoot
+	var filtered []history.HistoryEntry
+	hasCwd := false
+	for _, entry := range entries {
+		if entry.Cwd == "" {
+			continue
+		}
+		hasCwd = true
+		if entry.Cwd == root || strings.HasPrefix(entry.Cwd, root+string(filepath.Separator)) {
+			filtered = append(filtered, entry)
+		}
+	}
+	if !hasCwd {
+		e.logger.Warn("history.project_only is set but the history source records no working directories; using unfiltered history")
+		return entries
+	}
+	e.logger.Debug("Filtered history to project", zap.String("root", root), zap.Int("entries_count", len(filtered)))
+	return filtered
+}
+
+// Find searches the history entries that best fit query (see contextHistory), all
+// annotated commands and the team's snippets for commands matching query.
+func (e *Engine) Find(query string, limit int) (llm.CommandAnswer, error) {
+	return e.FindStream(query, limit, nil)
+}
+
+// FindStream is like Find, but passes response text to onChunk as it is generated
+// when the LLM client supports streaming. onChunk may be nil.
+func (e *Engine) FindStream(query string, limit int, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	return e.FindTagStream(context.Background(), query, "", limit, onChunk)
+}
+
+// FindTagStream is like FindStream, but only searches commands tagged with tag
+// when it is not empty, leaving out the team's snippets, and gives up when ctx is
+// done.
+func (e *Engine) FindTagStream(ctx context.Context, query, tag string, limit int, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	historyEntries, err := e.contextHistory(query, limit, allAnnotated)
+	if err != nil {
+		return llm.CommandAnswer{}, err
+	}
+	if tag != "" {
+		historyEntries = withTag(historyEntries, tag)
+		if len(historyEntries) == 0 {
+			return llm.CommandAnswer{}, fmt.Errorf("no commands are tagged %q (see 'historai tag list')", tag)
+		}
+	} else {
+		stopRetrieve := e.timings.Start(timings.StageRetrieve)
+		historyEntries = append(e.teamEntries(), historyEntries...)
+		stopRetrieve()
+	}
+
+	if e.anonymizer != nil {
+		// The answer streams in anonymized; it is only shown once mapped back.
+		historyEntries, onChunk = e.anonymizer.Entries(historyEntries), nil
+	}
+
+	e.logger.Debug("Sending query and history context to LLM...", zap.Int("history_context_size", len(historyEntries)))
+	req := llm.FindRequest{Query: query, History: historyEntries}
+	var resp llm.FindResponse
+	stopLLM := e.timings.Start(timings.StageLLM)
+	if streamer, ok := e.client.(llm.StreamingClient); ok && onChunk != nil {
+		resp, err = streamer.StreamFind(ctx, req, onChunk)
+	} else {
+		resp, err = e.client.Find(ctx, req)
+	}
+	stopLLM()
+	if err != nil {
+		return llm.CommandAnswer{}, fmt.Errorf("failed to get results from LLM: %w", err)
+	}
+	e.logger.Debug("Received response from LLM")
+
+	return e.restore(resp.CommandAnswer), nil
+}
+
+// withTag keeps the entries tagged with tag.
+func withTag(entries []history.HistoryEntry, tag string) []history.HistoryEntry {
+	var tagged []history.HistoryEntry
+	for _, entry := range entries {
+		for _, t := range entry.Tags {
+			if t == tag {
+				tagged = append(tagged, entry)
+				break
+			}
+		}
+	}
+	return tagged
+}
+
+// SuggestContext holds what suggest requests know about the user's surroundings,
+// besides history.
+type SuggestContext struct {
+	// Dir is the user's working directory, for the git context and listing; ""
+	// means the current directory.
+	Dir string
+
+	// Listing sends the names of the files in Dir, e.g. for 'suggest --with-ls'.
+	Listing bool
+
+	// Env holds the user's cloud environment variables (see CloudEnv), for the
+	// cloud context; nil means this process's own.
+	Env map[string]string
+}
+
+// Suggest asks the LLM for commands accomplishing taskDescription, optionally
+// using the history entries that best fit it (see contextHistory), all saved
+// commands, the team's snippets and the workflows mined from the whole history as
+// context.
+func (e *Engine) Suggest(taskDescription string, limit int, noHistoryContext bool) (llm.CommandAnswer, error) {
+	return e.SuggestStream(context.Background(), taskDescription, limit, noHistoryContext, SuggestContext{}, nil)
+}
+
+// SuggestStream is like Suggest, from within sc, but passes response text to onChunk
+// as it is generated when the LLM client supports streaming, and gives up when ctx
+// is done. onChunk may be nil.
+func (e *Engine) SuggestStream(ctx context.Context, taskDescription string, limit int, noHistoryContext bool, sc SuggestContext, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	var historyEntries []history.HistoryEntry
+	var workflows []llm.Workflow
+	if !noHistoryContext {
+		entries, err := e.contextHistory(taskDescription, limit, pinnedOnly)
+		if err != nil {
+			e.logger.Error("Failed to read history for context", zap.Error(err))
+			return llm.CommandAnswer{}, fmt.Errorf("failed to read history for context: %w", err)
+		}
+		if len(entries) == 0 {
+			e.logger.Warn("No history entries found matching the criteria (limit) to provide as context.")
+		}
+		stopRetrieve := e.timings.Start(timings.StageRetrieve)
+		historyEntries = append(e.teamEntries(), entries...)
+		stopRetrieve()
+		workflows = e.workflows()
+	} else {
+		e.logger.Debug("Skipping history reading as --no-history-context flag was provided.")
+	}
+
+	if sc.Dir == "" {
+		sc.Dir = "."
+	}
+	if e.anonymizer != nil {
+		historyEntries, onChunk = e.anonymizer.Entries(historyEntries), nil
+	}
+	stopRetrieve := e.timings.Start(timings.StageRetrieve)
+	req := llm.SuggestRequest{Task: taskDescription, History: historyEntries, Workflows: workflows, Environment: e.environment(sc)}
+	stopRetrieve()
+	var resp llm.SuggestResponse
+	var err error
+	stopLLM := e.timings.Start(timings.StageLLM)
+	if streamer, ok := e.client.(llm.StreamingClient); ok && onChunk != nil {
+		resp, err = streamer.StreamSuggest(ctx, req, onChunk)
+	} else {
+		resp, err = e.client.Suggest(ctx, req)
+	}
+	stopLLM()
+	if err != nil {
+		return llm.CommandAnswer{}, fmt.Errorf("failed to get suggestions from LLM: %w", err)
+	}
+
+	return e.restore(resp.CommandAnswer), nil
+}
+
+// workflows mines the whole history for the workflows the user habitually follows.
+// They are extra context, so failures are logged and yield none.
+func (e *Engine) workflows() []llm.Workflow {
+	stopParse := e.timings.Start(timings.StageParse)
+	entries, err := e.reader.ReadHistory(0)
+	stopParse()
+	if err != nil {
+		e.logger.Warn("Ignoring workflows", zap.Error(err))
+		return nil
+	}
+	defer e.timings.Start(timings.StageRetrieve)()
+	if e.cfg.History.ProjectOnly && e.cfg.ProjectRoot != "" {
+		entries = e.filterProject(entries)
+	}
+	rules := workflow.Rules(entries, suggestWorkflowLimit)
+	workflows := make([]llm.Workflow, len(rules))
+	for i, rule := range rules {
+		workflows[i] = llm.Workflow{Count: rule.Count}
+		for _, step := range rule.Steps() {
+			workflows[i].Commands = append(workflows[i].Commands, e.anonymizer.Command(e.redactor.Redact(step)))
+		}
+	}
+	e.logger.Debug("Mined workflows", zap.Int("workflows_count", len(workflows)))
+	return workflows
+}
+
+// Explain asks the LLM to explain command, using previous runs of the same program
+// among the most recent history entries (limited by limit) as context.
+func (e *Engine) Explain(ctx context.Context, command string, limit int) (string, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return "", err
+	}
+	usages := e.anonymizer.Entries(ProgramUsages(entries, command))
+	e.logger.Debug("Found previous uses of the program", zap.Int("usages_count", len(usages)))
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	explanation, err := e.client.ExplainCommand(ctx, llm.ExplainRequest{Command: e.redactor.Redact(command), History: usages})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get explanation from LLM: %w", err)
+	}
+	return explanation, nil
+}
+
+// Next asks the LLM for the likely next command after the recent most recent
+// history entries, using the earlier entries (up to limit in total) as examples
+// of the user's workflows. historai's own invocations are ignored.
+func (e *Engine) Next(ctx context.Context, recent int, limit int) (llm.CommandAnswer, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return llm.CommandAnswer{}, err
+	}
+	var commands []history.HistoryEntry
+	for _, entry := range entries {
+		if history.ProgramName(entry.Command) != "historai" {
+			commands = append(commands, entry)
+		}
+	}
+	commands = e.anonymizer.Entries(commands)
+	if len(commands) == 0 {
+		return llm.CommandAnswer{}, fmt.Errorf("no commands found in history")
+	}
+	if recent > len(commands) {
+		recent = len(commands)
+	}
+	split := len(commands) - recent
+	e.logger.Debug("Predicting next command", zap.Int("recent_count", recent), zap.Int("context_count", split))
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	next, err := e.client.PredictNext(ctx, llm.NextRequest{Recent: commands[split:], History: commands[:split]})
+	stopLLM()
+	if err != nil {
+		return llm.CommandAnswer{}, fmt.Errorf("failed to get next command from LLM: %w", err)
+	}
+	return e.restore(next), nil
+}
+
+// Chat asks the LLM to answer the last user message in messages, with the most
+// recent history entries (limited by limit) as context.
+func (e *Engine) Chat(ctx context.Context, messages []llm.ChatMessage, limit int) (string, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return "", err
+	}
+
+	redacted := make([]llm.ChatMessage, len(messages))
+	for i, message := range messages {
+		redacted[i] = llm.ChatMessage{Role: message.Role, Content: e.redactor.Redact(message.Content)}
+	}
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	answer, err := e.client.Chat(ctx, llm.ChatRequest{Messages: redacted, History: e.anonymizer.Entries(entries)})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get chat response from LLM: %w", err)
+	}
+	return answer, nil
+}
+
+// Ask asks the LLM to answer a free-form question by reasoning over the most recent
+// history entries (limited by limit).
+func (e *Engine) Ask(ctx context.Context, question string, limit int) (string, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return "", err
+	}
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	answer, err := e.client.AnswerQuestion(ctx, llm.AskRequest{Question: e.redactor.Redact(question), History: e.anonymizer.Entries(entries)})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get answer from LLM: %w", err)
+	}
+	return answer, nil
+}
+
+// FillSnippet asks the LLM for the placeholder values of snippet that accomplish
+// description, using the most recent history entries (limited by limit) to pick the
+// hosts, ports and paths the user actually uses.
+func (e *Engine) FillSnippet(ctx context.Context, snippet *snippets.Snippet, description string, limit int) (map[string]string, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	values, err := e.client.FillSnippet(ctx, llm.FillRequest{
+		Snippet: llm.Snippet{
+			Command:      snippet.Command,
+			Description:  snippet.Description,
+			Placeholders: snippet.Placeholders(),
+		},
+		Description: e.redactor.Redact(description),
+		History:     entries,
+	})
+	stopLLM()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get placeholder values from LLM: %w", err)
+	}
+	e.logger.Debug("Received placeholder values from LLM", zap.Int("values_count", len(values)))
+	return values, nil
+}
+
+// Script asks the LLM to turn commands, oldest first, into a commented shell script.
+// Commands repeated back to back are sent once. purpose may be empty.
+func (e *Engine) Script(ctx context.Context, commands []history.HistoryEntry, purpose string) (string, error) {
+	var deduped []history.HistoryEntry
+	for _, entry := range commands {
+		if n := len(deduped); n > 0 && strings.TrimSpace(deduped[n-1].Command) == strings.TrimSpace(entry.Command) {
+			deduped[n-1] = entry // keep the latest run, with its exit status
+			continue
+		}
+		deduped = append(deduped, entry)
+	}
+	e.logger.Debug("Writing script", zap.Int("commands_count", len(deduped)))
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	script, err := e.client.WriteScript(ctx, llm.ScriptRequest{Commands: deduped, Purpose: e.redactor.Redact(purpose)})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get script from LLM: %w", err)
+	}
+	return script, nil
+}
+
+// Quiz asks the LLM for up to count questions about the flags of commands picked at
+// random among the distinct commands with flags in the most recent history entries,
+// limited by limit.
+func (e *Engine) Quiz(ctx context.Context, count, limit int) ([]llm.QuizQuestion, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var candidates []history.HistoryEntry
+	for _, entry := range entries {
+		command := strings.TrimSpace(entry.Command)
+		if seen[command] || history.ProgramName(command) == "historai" || !hasFlag(command) {
+			continue
+		}
+		seen[command] = true
+		candidates = append(candidates, history.HistoryEntry{Command: command})
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if n := count * quizCandidatesPerQuestion; len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	e.logger.Debug("Writing quiz", zap.Int("candidates_count", len(candidates)), zap.Int("questions_count", count))
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	questions, err := e.client.WriteQuiz(ctx, llm.QuizRequest{Commands: e.anonymizer.Entries(candidates), Count: count})
+	stopLLM()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz from LLM: %w", err)
+	}
+	if len(questions) > count {
+		questions = questions[:count]
+	}
+	return questions, nil
+}
+
+// hasFlag reports whether command has an argument that looks like a flag.
+func hasFlag(command string) bool {
+	fields := strings.Fields(command)
+	for i := 1; i < len(fields); i++ {
+		if len(fields[i]) > 1 && fields[i][0] == '-' {
+			return true
+		}
+	}
+	return false
+}
+
+// GradeAnswer asks the LLM whether answer answers question.
+func (e *Engine) GradeAnswer(ctx context.Context, question llm.QuizQuestion, answer string) (llm.QuizGrade, error) {
+	stopLLM := e.timings.Start(timings.StageLLM)
+	grade, err := e.client.GradeAnswer(ctx, llm.GradeRequest{Question: question, Answer: e.redactor.Redact(answer)})
+	stopLLM()
+	if err != nil {
+		return llm.QuizGrade{}, fmt.Errorf("failed to get grade from LLM: %w", err)
+	}
+	return grade, nil
+}
+
+// BuildTargets asks the LLM to turn workflows into targets for a build file in
+// format, llm.BuildFormatMake or llm.BuildFormatJust.
+func (e *Engine) BuildTargets(ctx context.Context, workflows []llm.Workflow, format string) (string, error) {
+	redacted := make([]llm.Workflow, len(workflows))
+	for i, workflow := range workflows {
+		redacted[i] = llm.Workflow{Count: workflow.Count}
+		for _, command := range workflow.Commands {
+			redacted[i].Commands = append(redacted[i].Commands, e.redactor.Redact(command))
+		}
+	}
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	targets, err := e.client.WriteBuildTargets(ctx, llm.TargetsRequest{Workflows: redacted, Format: format})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get build targets from LLM: %w", err)
+	}
+	return targets, nil
+}
+
+// ProgramUsages returns the entries that run the same program as command,
+// ignoring a leading sudo or environment assignments.
+func ProgramUsages(entries []history.HistoryEntry, command string) []history.HistoryEntry {
+	program := history.ProgramName(command)
+	if program == "" {
+		return nil
+	}
+	var usages []history.HistoryEntry
+	for _, entry := range entries {
+		if history.ProgramName(entry.Command) == program && strings.TrimSpace(entry.Command) != strings.TrimSpace(command) {
+			usages = append(usages, entry)
+		}
+	}
+	return usages
+}
+
+// LastFailed returns the most recent command recorded by the shell hook that exited
+// with a non-zero status, ignoring historai's own invocations.
+func (e *Engine) LastFailed() (history.HistoryEntry, error) {
+	file := ""
+	if e.cfg.History.Source == history.SourceHistorai {
+		file = e.cfg.History.File
+	}
+	reader, err := history.NewRecordedHistoryReader(e.logger, file)
+	if err != nil {
+		return history.HistoryEntry{}, fmt.Errorf("exit codes are only known for commands recorded by the shell hook: %w", err)
+	}
+	entries, err := reader.ReadHistory(0)
+	if err != nil {
+		return history.HistoryEntry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Failed() && history.ProgramName(entries[i].Command) != "historai" {
+			return entries[i], nil
+		}
+	}
+	return history.HistoryEntry{}, fmt.Errorf("no failed command found in %s", reader.HistoryFile())
+}
+
+// Fix asks the LLM for a corrected version of failed, using successful previous
+// runs of the same program among the most recent history entries as context.
+func (e *Engine) Fix(ctx context.Context, failed history.HistoryEntry, limit int) (llm.CommandAnswer, error) {
+	usages, err := e.successfulUsages(failed.Command, limit)
+	if err != nil {
+		return llm.CommandAnswer{}, err
+	}
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	fix, err := e.client.FixCommand(ctx, llm.FixRequest{Failed: e.redactFailure(failed), History: usages})
+	stopLLM()
+	if err != nil {
+		return llm.CommandAnswer{}, fmt.Errorf("failed to get fix from LLM: %w", err)
+	}
+	return e.restore(fix), nil
+}
+
+// Why asks the LLM to diagnose why failed failed, using its captured error output
+// and successful previous runs of the same program as context.
+func (e *Engine) Why(ctx context.Context, failed history.HistoryEntry, limit int) (string, error) {
+	usages, err := e.successfulUsages(failed.Command, limit)
+	if err != nil {
+		return "", err
+	}
+
+	stopLLM := e.timings.Start(timings.StageLLM)
+	diagnosis, err := e.client.DiagnoseFailure(ctx, llm.FixRequest{Failed: e.redactFailure(failed), History: usages})
+	stopLLM()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diagnosis from LLM: %w", err)
+	}
+	return diagnosis, nil
+}
+
+// successfulUsages returns the previous runs of command's program among the most
+// recent history entries that did not fail.
+func (e *Engine) successfulUsages(command string, limit int) ([]history.HistoryEntry, error) {
+	entries, err := e.History(limit)
+	if err != nil {
+		return nil, err
+	}
+	var usages []history.HistoryEntry
+	for _, entry := range ProgramUsages(entries, command) {
+		if !entry.Failed() {
+			usages = append(usages, entry)
+		}
+	}
+	e.logger.Debug("Found previous successful uses of the program", zap.Int("usages_count", len(usages)))
+	return e.anonymizer.Entries(usages), nil
+}
+
+// redactFailure masks secrets in the failed command and its error output before
+// they are sent to the LLM. With redaction.anonymize on, the command is sent as a
+// template, without its directory, host or error output.
+func (e *Engine) redactFailure(failed history.HistoryEntry) history.HistoryEntry {
+	failed.Command = e.redactor.Redact(failed.Command)
+	failed.Stderr = e.redactor.Redact(failed.Stderr)
+	if e.anonymizer != nil {
+		failed = e.anonymizer.Entries([]history.HistoryEntry{failed})[0]
+	}
+	return failed
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// listingLimit is the number of directory entries sent with 'suggest --with-ls'.
+const listingLimit = 100
+
+// environmentTools are the tools whose presence is worth telling the LLM, as
+// suggestions commonly rely on them or on an alternative.
+var environmentTools = []string{
+	"docker", "podman", "kubectl", "helm", "git", "gh", "jq", "yq", "rg", "fd", "fzf",
+	"curl", "wget", "python3", "node", "go", "make", "brew", "apt", "dnf", "pacman",
+	"systemctl", "terraform", "aws", "gcloud", "az",
+}
+
+// environment returns the context sent with suggest requests from sc.Dir: the
+// machine facts (context.environment), detected once per engine, the git repository
+// (context.git), the cloud accounts (context.cloud) and the directory listing
+// (sc.Listing). It returns nil when there is none of them, and for fixture
+// histories, which were recorded elsewhere.
+func (e *Engine) environment(sc SuggestContext) *llm.Environment {
+	if e.fixture {
+		return nil
+	}
+	var env llm.Environment
+	if e.cfg.Context.Environment {
+		e.envOnce.Do(func() {
+			e.env = detectEnvironment(e.cfg.History.Source)
+		})
+		env = *e.env
+	}
+	if e.cfg.Context.Git {
+		env.Git = e.gitContext(sc.Dir)
+	}
+	if e.cfg.Context.Cloud {
+		env.Cloud = e.cloudContext(sc.Env)
+	}
+	if sc.Listing {
+		env.Listing, env.ListingOmitted = e.listing(sc.Dir)
+	}
+	if env.OS == "" && env.Git == nil && env.Cloud == nil && len(env.Listing) == 0 {
+		return nil
+	}
+	return &env
+}
+
+// listing returns the names in dir, directories ending in "/", up to listingLimit,
+// and how many were left out. Names are redacted, as they may leave the machine.
+func (e *Engine) listing(dir string) ([]string, int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		e.logger.Warn("Could not list the current directory", zap.Error(err))
+		return nil, 0
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		name := e.redactor.Redact(entry.Name())
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	if len(names) > listingLimit {
+		return names[:listingLimit], len(names) - listingLimit
+	}
+	return names, 0
+}
+
+// detectEnvironment inspects the current machine. The shell is the one whose history
+// source reads, if it is a Windows shell, and $SHELL otherwise.
+func detectEnvironment(source string) *llm.Environment {
+	env := &llm.Environment{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		OSVersion: osVersion(),
+		Coreutils: coreutilsFlavor(),
+	}
+	if shell := history.SourceShell(source); shell != "" {
+		env.Shell = shell
+	} else if shell := os.Getenv("SHELL"); shell != "" {
+		env.Shell = filepath.Base(shell)
+	}
+	for _, tool := range environmentTools {
+		if _, err := exec.LookPath(tool); err == nil {
+			env.Tools = append(env.Tools, tool)
+		} else {
+			env.MissingTools = append(env.MissingTools, tool)
+		}
+	}
+	return env
+}
+
+// osVersion returns the name and version of the OS release, or "" if unknown.
+func osVersion() string {
+	switch runtime.GOOS {
+	case "linux":
+		file, err := os.Open("/etc/os-release")
+		if err != nil {
+			return ""
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if value, ok := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); ok {
+				return strings.Trim(value, `"'`)
+			}
+		}
+	case "darwin":
+		if out, err := exec.Command("sw_vers", "-productVersion").Output(); err == nil {
+			return "macOS " + strings.TrimSpace(string(out))
+		}
+	}
+	return ""
+}
+
+// coreutilsFlavor tells GNU, BSD and BusyBox core utilities apart by how ls answers
+// --version, which only GNU supports. It returns "" when unknown, e.g. on Windows.
+func coreutilsFlavor() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	out, _ := exec.Command("ls", "--version").CombinedOutput()
+	switch {
+	case strings.Contains(string(out), "GNU"):
+		return llm.CoreutilsGNU
+	case strings.Contains(string(out), "BusyBox"):
+		return llm.CoreutilsBusyBox
+	case runtime.GOOS == "darwin" || strings.HasSuffix(runtime.GOOS, "bsd"):
+		return llm.CoreutilsBSD
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// historySearcher implements llm.HistorySearcher over the engine's whole history, so
+// the model can look beyond the entries sent with the prompt.
+type historySearcher struct {
+	engine *Engine
+}
+
+// HistoryBefore implements the HistorySearcher interface method.
+func (s historySearcher) HistoryBefore(before time.Time, skip, limit int) ([]history.HistoryEntry, error) {
+	entries, err := s.engine.History(0)
+	if err != nil {
+		return nil, err
+	}
+	entries = entries[:max(len(entries)-skip, 0)]
+	if !before.IsZero() {
+		var older []history.HistoryEntry
+		for _, entry := range entries {
+			// Entries without a time can't be placed, so they are left out.
+			if entry.Timestamp > 0 && entry.Timestamp < before.Unix() {
+				older = append(older, entry)
+			}
+		}
+		entries = older
+	}
+	if limit <= 0 || len(entries) < limit {
+		// The log holds too few, so older entries come from the archive.
+		archived := s.engine.archived(func(archive *history.Archive) ([]history.HistoryEntry, error) {
+			return archive.Read(func(segment history.Segment) bool { return before.IsZero() || segment.From < before.Unix() })
+		})
+		var older []history.HistoryEntry
+		for _, entry := range archived {
+			if before.IsZero() || entry.Timestamp < before.Unix() {
+				older = append(older, entry)
+			}
+		}
+		entries = append(older, entries...)
+	}
+	s.engine.logger.Debug("Fetched older history for the model", zap.Time("before", before), zap.Int("skip", skip), zap.Int("entries_count", min(len(entries), limit)))
+	return s.engine.anonymizer.Entries(lastEntries(entries, limit)), nil
+}
+
+// SearchHistory implements the HistorySearcher interface method.
+func (s historySearcher) SearchHistory(keyword string, limit int) ([]history.HistoryEntry, error) {
+	entries, err := s.engine.History(0)
+	if err != nil {
+		return nil, err
+	}
+	keyword = strings.ToLower(keyword)
+	var matches []history.HistoryEntry
+	for _, entry := range entries {
+		text := strings.ToLower(entry.Command + " " + entry.Note + " " + strings.Join(entry.Tags, " "))
+		if strings.Contains(text, keyword) {
+			matches = append(matches, entry)
+		}
+	}
+	if limit <= 0 || len(matches) < limit {
+		archived := s.engine.archived(func(archive *history.Archive) ([]history.HistoryEntry, error) {
+			return archive.Search(keyword)
+		})
+		matches = append(archived, matches...)
+	}
+	s.engine.logger.Debug("Searched history for the model", zap.String("keyword", keyword), zap.Int("matches_count", len(matches)))
+	return s.engine.anonymizer.Entries(lastEntries(matches, limit)), nil
+}
+
+// lastEntries returns the limit most recent entries, oldest first.
+func lastEntries(entries []history.HistoryEntry, limit int) []history.HistoryEntry {
+	if limit > 0 && len(entries) > limit {
+		return entries[len(entries)-limit:]
+	}
+	return entries
+}
+
+// localMaxResults bounds the commands FindLocal returns.
+const localMaxResults = 10
+
+// localStopWords are ignored when matching a query locally.
+var localStopWords = map[string]bool{
+	"the": true, "an": true, "my": true, "to": true, "of": true, "and": true, "or": true,
+	"that": true, "this": true, "used": true, "use": true, "command": true, "on": true,
+	"for": true, "in": true, "with": true, "what": true, "how": true, "was": true, "it": true,
+	"ran": true, "run": true, "did": true, "last": true, "from": true, "which": true,
+}
+
+// FindLocal is FindTagStream without an LLM, for when the provider can't be used:
+// it ranks the same entries by how well their command, note and tags match the
+// words of query, and returns the best commands. Exact substrings
+// count most, then words sharing a stem, then fuzzy matches (the letters of a word
+// in order, e.g. "dkr" for docker). Among equal matches, recent commands come first.
+func (e *Engine) FindLocal(query, tag string, limit int) (llm.CommandAnswer, error) {
+	historyEntries, err := e.history(limit, allAnnotated)
+	if err != nil {
+		return llm.CommandAnswer{}, err
+	}
+	if tag != "" {
+		historyEntries = withTag(historyEntries, tag)
+		if len(historyEntries) == 0 {
+			return llm.CommandAnswer{}, fmt.Errorf("no commands are tagged %q (see 'historai tag list')", tag)
+		}
+	}
+
+	queryWords := localWords(query)
+	phrase := strings.ToLower(strings.TrimSpace(query))
+	type match struct {
+		command string
+		score   int
+	}
+	seen := make(map[string]bool)
+	var matches []match
+	for i := len(historyEntries) - 1; i >= 0; i-- {
+		entry := historyEntries[i]
+		if seen[entry.Command] {
+			continue
+		}
+		seen[entry.Command] = true
+		text := strings.ToLower(entry.Command + " " + entry.Note + " " + strings.Join(entry.Tags, " "))
+		score := localScore(queryWords, text)
+		if phrase != "" && strings.Contains(text, phrase) {
+			score += 5
+		}
+		if score > 0 {
+			matches = append(matches, match{command: entry.Command, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	e.logger.Debug("Searched history locally", zap.String("query", query), zap.Int("matches_count", len(matches)))
+	if len(matches) == 0 {
+		return llm.CommandAnswer{Message: llm.NoMatchesResponse}, nil
+	}
+	var answer llm.CommandAnswer
+	var commands []string
+	for _, m := range matches[:min(len(matches), localMaxResults)] {
+		answer.Commands = append(answer.Commands, llm.CommandResult{Command: m.command, Risk: llm.RiskOf(m.command), Source: llm.SourceHistory})
+		commands = append(commands, m.command)
+	}
+	answer.Text = strings.Join(commands, "\n")
+	return answer, nil
+}
+
+// localScore rates how well text matches the words of a query: 3 for each word
+// found as a substring, 2 for each sharing a stem with a word of text (one is a
+// prefix of the other, at least 3 letters), 1 for each fuzzy match.
+func localScore(queryWords []string, text string) int {
+	textWords := localWords(text)
+	score := 0
+	for _, word := range queryWords {
+		switch {
+		case strings.Contains(text, word):
+			score += 3
+		case anyWord(textWords, func(w string) bool { return sharesStem(word, w) }):
+			score += 2
+		case anyWord(textWords, func(w string) bool { return fuzzyMatch(word, w) }):
+			score++
+		}
+	}
+	return score
+}
+
+// localWords splits s into lowercase words, without stop words and single letters.
+func localWords(s string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 2 && !localStopWords[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// anyWord reports whether match holds for one of words.
+func anyWord(words []string, match func(string) bool) bool {
+	for _, w := range words {
+		if match(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharesStem reports whether one of a and b is a prefix of the other, at least
+// three letters long, e.g. "listed" and "list".
+func sharesStem(a, b string) bool {
+	if len(a) < 3 || len(b) < 3 {
+		return false
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// fuzzyMatch reports whether the letters of word appear in candidate in order,
+// starting with the same letter, e.g. "dkr" in "docker".
+func fuzzyMatch(word, candidate string) bool {
+	if len(word) < 2 || len(candidate) < len(word) || word[0] != candidate[0] {
+		return false
+	}
+	i := 0
+	for j := 0; j < len(candidate) && i < len(word); j++ {
+		if candidate[j] == word[i] {
+			i++
+		}
+	}
+	return i == len(word)
+}
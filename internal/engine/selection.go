@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/annotations"
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/timings"
+)
+
+// Weights of the parts of an entry's score in "scored" context selection: a command
+// matching the query outranks a recent or frequent one.
+const (
+	recencyWeight   = 1.0
+	frequencyWeight = 0.5
+	overlapWeight   = 2.0
+)
+
+// defaultRecencyScale is how many more recent commands halve an entry's recency
+// when there is no limit to scale it by.
+const defaultRecencyScale = 100
+
+// Token estimates of context entries, at about four characters a token, with the
+// time and punctuation each entry is written with.
+const (
+	charsPerToken       = 4
+	entryOverheadTokens = 8
+)
+
+// contextHistory returns the history sent as context for query, as selected by
+// context.selection: with "recent", the limit most recent entries, as history does;
+// otherwise the entries selectContext picks from the whole history. Either way, the
+// annotated commands unlisted selects are included. Secrets are redacted.
+func (e *Engine) contextHistory(query string, limit int, unlisted func(*annotations.Annotation) bool) ([]history.HistoryEntry, error) {
+	if e.cfg.Context.Selection == config.SelectionRecent {
+		return e.history(limit, unlisted)
+	}
+	entries, err := e.annotatedHistory(0, unlisted)
+	if err != nil {
+		return nil, err
+	}
+	defer e.timings.Start(timings.StageRetrieve)()
+
+	required := func(entry history.HistoryEntry) bool {
+		annotated := entry.Note != "" || len(entry.Tags) > 0 || entry.Pinned
+		return annotated && unlisted != nil && unlisted(&annotations.Annotation{Note: entry.Note, Tags: entry.Tags, Pinned: entry.Pinned})
+	}
+	selected := selectContext(entries, query, limit, e.cfg.Context.MaxTokens, required)
+	e.logger.Debug("Selected history context", zap.Int("entries_count", len(entries)), zap.Int("selected_count", len(selected)))
+	return e.redactor.RedactEntries(selected), nil
+}
+
+// contextCandidate is a command line of the history, scored for context selection.
+type contextCandidate struct {
+	// entry is the command's most recent run, at index last of the history.
+	entry history.HistoryEntry
+	last  int
+	count int
+	score float64
+}
+
+// selectContext picks the entries sent as context for query, one per command line
+// (its most recent run), by a score adding up:
+//   - recency: 1 for the most recent command, halving after limit others;
+//   - frequency: how often it was run, on a log scale, 1 for the most frequent;
+//   - overlap: how well it matches the words of query, as in FindLocal.
+//
+// The highest scoring are kept, up to limit entries and maxTokens estimated tokens
+// when they are positive, and returned oldest first. Entries required reports are
+// kept whatever their score, without counting against limit.
+func selectContext(entries []history.HistoryEntry, query string, limit, maxTokens int, required func(history.HistoryEntry) bool) []history.HistoryEntry {
+	byCommand := make(map[string]*contextCandidate)
+	var candidates []*contextCandidate
+	maxCount := 1
+	for i, entry := range entries {
+		key := strings.TrimSpace(entry.Command)
+		if candidate, ok := byCommand[key]; ok {
+			candidate.entry, candidate.last = entry, i
+			candidate.count++
+			maxCount = max(maxCount, candidate.count)
+			continue
+		}
+		candidate := &contextCandidate{entry: entry, last: i, count: 1}
+		byCommand[key] = candidate
+		candidates = append(candidates, candidate)
+	}
+
+	// Rank by recency, which also breaks ties between equal scores.
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].last > candidates[j].last })
+	scale := float64(defaultRecencyScale)
+	if limit > 0 {
+		scale = float64(limit)
+	}
+	queryWords := localWords(query)
+	for rank, candidate := range candidates {
+		candidate.score = recencyWeight*scale/(scale+float64(rank)) +
+			frequencyWeight*math.Log1p(float64(candidate.count))/math.Log1p(float64(maxCount))
+		if len(queryWords) > 0 {
+			entry := candidate.entry
+			text := strings.ToLower(entry.Command + " " + entry.Note + " " + strings.Join(entry.Tags, " "))
+			candidate.score += overlapWeight * float64(localScore(queryWords, text)) / float64(3*len(queryWords))
+		}
+	}
+	isRequired := make(map[*contextCandidate]bool)
+	for _, candidate := range candidates {
+		isRequired[candidate] = required(candidate.entry)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if isRequired[candidates[i]] != isRequired[candidates[j]] {
+			return isRequired[candidates[i]]
+		}
+		return candidates[i].score > candidates[j].score
+	})
+
+	var selected []*contextCandidate
+	tokens, count := 0, 0
+	for _, candidate := range candidates {
+		size := entryTokens(candidate.entry)
+		if !isRequired[candidate] {
+			if limit > 0 && count >= limit {
+				break
+			}
+			if maxTokens > 0 && tokens+size > maxTokens {
+				// A shorter command may still fit.
+				continue
+			}
+			count++
+		}
+		tokens += size
+		selected = append(selected, candidate)
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].last < selected[j].last })
+
+	result := make([]history.HistoryEntry, len(selected))
+	for i, candidate := range selected {
+		result[i] = candidate.entry
+	}
+	return result
+}
+
+// entryTokens estimates the tokens entry takes up in a prompt.
+func entryTokens(entry history.HistoryEntry) int {
+	size := len(entry.Command) + len(entry.Cwd) + len(entry.Note) + len(strings.Join(entry.Tags, " ")) +
+		len(entry.Output) + len(entry.Stderr) + len(entry.Host)
+	return size/charsPerToken + entryOverheadTokens
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/team"
+)
+
+// teamEntries returns the snippets of the team repository (team.repo) as history
+// entries labeled with their file, for find and suggest to search alongside the
+// user's history. They are extra context, so failures are logged and yield none, as
+// do fixture histories.
+func (e *Engine) teamEntries() []history.HistoryEntry {
+	if e.cfg.Team.Repo == "" || e.fixture {
+		return nil
+	}
+	dir, err := team.Dir(e.cfg.Team.Repo)
+	if err != nil {
+		e.logger.Warn("Ignoring team snippets", zap.Error(err))
+		return nil
+	}
+	snippets, err := team.Load(e.logger, dir)
+	if err != nil {
+		e.logger.Warn("Ignoring team snippets; run 'historai team pull'", zap.Error(err))
+		return nil
+	}
+	entries := make([]history.HistoryEntry, len(snippets))
+	for i, snippet := range snippets {
+		entries[i] = history.HistoryEntry{Command: snippet.Command, Note: snippet.Label(), Team: snippet.File}
+	}
+	return e.redactor.RedactEntries(entries)
+}
@@ -0,0 +1,167 @@
+// Package eval measures how well LLM providers, models and prompt instructions answer
+// find and suggest requests: a dataset pairs history fixtures and queries with the
+// commands expected back, and every variant is scored on how often, and how high, it
+// returns one of them. It turns prompt changes into numbers rather than impressions.
+package eval
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Modes of a Case: the request it sends.
+const (
+	ModeFind    = "find"
+	ModeSuggest = "suggest"
+)
+
+// fixtureEpoch is the timestamp of the first command of a fixture; the following
+// ones are a minute apart, so that prompts are the same on every run.
+var fixtureEpoch = time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC).Unix()
+
+// golden is the dataset shipped with historai, used when no dataset file is given.
+//
+//go:embed golden.yaml
+var golden []byte
+
+// Dataset is a set of cases to score variants on.
+type Dataset struct {
+	// History is the fixture of cases that don't have their own.
+	History Fixture `yaml:"history"`
+
+	// Prompts are prompt instructions to compare, each scored with every model. The
+	// configured prompt.instructions are used when there are none.
+	Prompts []Prompt `yaml:"prompts"`
+
+	Cases []Case `yaml:"cases"`
+}
+
+// Prompt is a named set of instructions appended to the prompts, like
+// prompt.instructions in the config file.
+type Prompt struct {
+	Name         string `yaml:"name"`
+	Instructions string `yaml:"instructions"`
+}
+
+// Case is a request and the commands a good answer contains.
+type Case struct {
+	Name string `yaml:"name"`
+
+	// Mode is ModeFind or ModeSuggest.
+	Mode  string `yaml:"mode"`
+	Query string `yaml:"query"`
+
+	// History is the history sent as context, instead of the dataset's.
+	History Fixture `yaml:"history"`
+
+	// Expect lists the acceptable commands; the case passes when the answer contains
+	// any of them. Commands are compared with runs of whitespace collapsed.
+	Expect []string `yaml:"expect"`
+}
+
+// Fixture is fixed shell history, oldest first. In YAML each entry is either a
+// command line or a mapping of history entry fields.
+type Fixture []history.HistoryEntry
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface method.
+func (f *Fixture) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: history must be a list", node.Line)
+	}
+	// An empty list is kept apart from a missing one: it means no history at all.
+	*f = Fixture{}
+	for i, item := range node.Content {
+		var entry history.HistoryEntry
+		if item.Kind == yaml.ScalarNode {
+			entry.Command = item.Value
+		} else if err := item.Decode(&entry); err != nil {
+			return err
+		}
+		if entry.Timestamp == 0 {
+			entry.Timestamp = fixtureEpoch + int64(i)*60
+		}
+		*f = append(*f, entry)
+	}
+	return nil
+}
+
+// Load reads the dataset at path, or the golden dataset when path is empty.
+func Load(path string) (*Dataset, error) {
+	data := golden
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read dataset: %w", err)
+		}
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a YAML dataset.
+func Parse(data []byte) (*Dataset, error) {
+	var dataset Dataset
+	if err := yaml.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("failed to parse dataset: %w", err)
+	}
+	if len(dataset.Cases) == 0 {
+		return nil, errors.New("dataset has no cases")
+	}
+	for i := range dataset.Cases {
+		c := &dataset.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if c.Mode == "" {
+			c.Mode = ModeFind
+		}
+		if c.Mode != ModeFind && c.Mode != ModeSuggest {
+			return nil, fmt.Errorf("%s: invalid mode %q (use find or suggest)", c.Name, c.Mode)
+		}
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("%s: query is empty", c.Name)
+		}
+		if len(c.Expect) == 0 {
+			return nil, fmt.Errorf("%s: no expected commands", c.Name)
+		}
+		if c.History == nil {
+			c.History = dataset.History
+		}
+		if c.Mode == ModeFind && len(c.History) == 0 {
+			return nil, fmt.Errorf("%s: find needs a history fixture", c.Name)
+		}
+	}
+	for i, prompt := range dataset.Prompts {
+		if prompt.Name == "" {
+			dataset.Prompts[i].Name = fmt.Sprintf("prompt %d", i+1)
+		}
+	}
+	return &dataset, nil
+}
+
+// rank returns the 1-based position of the first command that is one of expected,
+// or 0 if there is none.
+func rank(commands, expected []string) int {
+	want := make(map[string]bool, len(expected))
+	for _, command := range expected {
+		want[normalize(command)] = true
+	}
+	for i, command := range commands {
+		if want[normalize(command)] {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// normalize collapses runs of whitespace in command, so that spacing doesn't matter.
+func normalize(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
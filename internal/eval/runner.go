@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+// Variant is a provider, model and prompt instructions to score.
+type Variant struct {
+	// Name identifies the variant in reports, e.g. "gemini-1.5-flash (terse)".
+	Name string `json:"name" yaml:"name"`
+
+	Provider     string `json:"provider" yaml:"provider"`
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+	Instructions string `json:"-" yaml:"-"`
+}
+
+// Report is how a variant scored on a dataset.
+type Report struct {
+	Variant Variant `json:"variant" yaml:"variant"`
+
+	// Passed is how many cases had an expected command in the answer.
+	Passed int `json:"passed" yaml:"passed"`
+	Total  int `json:"total" yaml:"total"`
+
+	// Score is the share of cases passed, from 0 to 1.
+	Score float64 `json:"score" yaml:"score"`
+
+	// MRR is the mean reciprocal rank of the first expected command: 1 when it always
+	// comes first, lower when it comes later or not at all.
+	MRR float64 `json:"mrr" yaml:"mrr"`
+
+	// AvgDurationMS is the mean time the LLM took to answer.
+	AvgDurationMS int64 `json:"avg_duration_ms" yaml:"avg_duration_ms"`
+
+	// Usage is the tokens used by all cases, when the provider reports them.
+	Usage   *llm.Usage `json:"usage,omitempty" yaml:"usage,omitempty"`
+	CostUSD *float64   `json:"cost_usd,omitempty" yaml:"cost_usd,omitempty"`
+
+	Cases []CaseResult `json:"cases" yaml:"cases"`
+}
+
+// CaseResult is how a variant did on one case.
+type CaseResult struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Rank is the position of the first expected command in the answer, or 0.
+	Rank       int      `json:"rank" yaml:"rank"`
+	Passed     bool     `json:"passed" yaml:"passed"`
+	Commands   []string `json:"commands" yaml:"commands"`
+	DurationMS int64    `json:"duration_ms" yaml:"duration_ms"`
+	Error      string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Runner scores variants on datasets.
+type Runner struct {
+	Logger *zap.Logger
+
+	// Config is the base configuration; each variant overrides its provider, model and
+	// prompt instructions.
+	Config *config.Config
+}
+
+// Run sends every case of dataset to variant and scores the answers. A case whose
+// request fails counts as failed; only a variant that can't be set up is an error.
+func (r *Runner) Run(ctx context.Context, dataset *Dataset, variant Variant) (Report, error) {
+	cfg := *r.Config
+	cfg.LLM.Provider, cfg.LLM.Model = variant.Provider, variant.Model
+	cfg.Prompt.Instructions = variant.Instructions
+	// Fixtures are the whole context; nothing of the user's may add to them.
+	cfg.History.ProjectOnly = false
+
+	report := Report{Variant: variant, Total: len(dataset.Cases)}
+	var usage llm.Usage
+	reportsUsage := false
+	var totalDuration time.Duration
+	for _, c := range dataset.Cases {
+		eng, err := engine.New(ctx, r.Logger, &cfg, engine.Options{History: c.History})
+		if err != nil {
+			return report, err
+		}
+
+		started := time.Now()
+		var answer llm.CommandAnswer
+		if c.Mode == ModeFind {
+			answer, err = eng.Find(c.Query, len(c.History))
+		} else {
+			answer, err = eng.Suggest(c.Query, len(c.History), len(c.History) == 0)
+		}
+		duration := time.Since(started)
+		totalDuration += duration
+		if callUsage, ok := eng.Usage(); ok && err == nil {
+			usage.PromptTokens += callUsage.PromptTokens
+			usage.OutputTokens += callUsage.OutputTokens
+			reportsUsage = true
+		}
+		if closeErr := eng.Close(); closeErr != nil {
+			r.Logger.Error("Failed to close LLM client", zap.Error(closeErr))
+		}
+
+		result := CaseResult{Name: c.Name, DurationMS: duration.Milliseconds()}
+		if err != nil {
+			r.Logger.Debug("Eval case failed", zap.String("case", c.Name), zap.Error(err))
+			result.Error = err.Error()
+		} else {
+			result.Commands = commandLines(answer)
+			result.Rank = rank(result.Commands, c.Expect)
+			result.Passed = result.Rank > 0
+		}
+		if result.Passed {
+			report.Passed++
+			report.MRR += 1 / float64(result.Rank)
+		}
+		report.Cases = append(report.Cases, result)
+	}
+
+	report.Score = float64(report.Passed) / float64(report.Total)
+	report.MRR /= float64(report.Total)
+	report.AvgDurationMS = totalDuration.Milliseconds() / int64(report.Total)
+	if reportsUsage {
+		report.Usage = &usage
+		if cost, ok := llm.Cost(llm.ModelName(&cfg), usage); ok {
+			report.CostUSD = &cost
+		}
+	}
+	return report, nil
+}
+
+// commandLines returns the commands of answer, in order.
+func commandLines(answer llm.CommandAnswer) []string {
+	commands := make([]string, len(answer.Commands))
+	for i, command := range answer.Commands {
+		commands[i] = command.Command
+	}
+	return commands
+}
@@ -0,0 +1,267 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	historaiv1 "github.com/sanspareilsmyn/historai/api/historai/v1"
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/server"
+)
+
+// Server implements the historai.v1.Historai gRPC service on top of the engine.
+type Server struct {
+	historaiv1.UnimplementedHistoraiServer
+
+	logger *zap.Logger
+	engine *engine.Engine
+	token  string
+}
+
+// New creates a gRPC API server. An empty token disables authentication.
+func New(logger *zap.Logger, eng *engine.Engine, token string) *Server {
+	return &Server{
+		logger: logger,
+		engine: eng,
+		token:  token,
+	}
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled. Without a token,
+// addr must be a loopback address, as anyone who can reach the server could
+// otherwise use it.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if s.token == "" && !server.IsLoopback(addr) {
+		return fmt.Errorf("refusing to listen on non-loopback address %s without a token", addr)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	)
+	historaiv1.RegisterHistoraiServer(grpcServer, s)
+	reflection.Register(grpcServer)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	s.logger.Info("gRPC API listening", zap.String("addr", addr), zap.Bool("auth", s.token != ""))
+	if err := grpcServer.Serve(listener); err != nil {
+		return fmt.Errorf("grpc server failed: %w", err)
+	}
+	return nil
+}
+
+// Find implements historai.v1.Historai/Find.
+func (s *Server) Find(ctx context.Context, req *historaiv1.FindRequest) (*historaiv1.CommandResponse, error) {
+	result, err := s.find(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return commandResponse(result), nil
+}
+
+// Suggest implements historai.v1.Historai/Suggest.
+func (s *Server) Suggest(ctx context.Context, req *historaiv1.SuggestRequest) (*historaiv1.CommandResponse, error) {
+	result, err := s.suggest(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return commandResponse(result), nil
+}
+
+// StreamFind implements historai.v1.Historai/StreamFind, sending the response as
+// the LLM generates it.
+func (s *Server) StreamFind(req *historaiv1.FindRequest, stream grpc.ServerStreamingServer[historaiv1.CommandChunk]) error {
+	return s.stream(stream, func(ctx context.Context, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+		return s.find(ctx, req, onChunk)
+	})
+}
+
+// StreamSuggest implements historai.v1.Historai/StreamSuggest, sending the response
+// as the LLM generates it.
+func (s *Server) StreamSuggest(req *historaiv1.SuggestRequest, stream grpc.ServerStreamingServer[historaiv1.CommandChunk]) error {
+	return s.stream(stream, func(ctx context.Context, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+		return s.suggest(ctx, req, onChunk)
+	})
+}
+
+// History implements historai.v1.Historai/History.
+func (s *Server) History(_ context.Context, req *historaiv1.HistoryRequest) (*historaiv1.HistoryResponse, error) {
+	entries, err := s.engine.History(int(req.GetLimit()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &historaiv1.HistoryResponse{Entries: make([]*historaiv1.HistoryEntry, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, &historaiv1.HistoryEntry{Timestamp: entry.Timestamp, Command: entry.Command})
+	}
+	return resp, nil
+}
+
+// stream sends each piece of text run passes to its onChunk as a CommandChunk, the
+// moment it arrives. When nothing was streamed, e.g. because the LLM client can't
+// stream or redaction.anonymize is on, the whole result is sent, a line a chunk.
+// A last chunk without text carries the commands of the whole answer. run gets the
+// stream's context, so the LLM call stops when the client goes away.
+func (s *Server) stream(stream grpc.ServerStreamingServer[historaiv1.CommandChunk], run func(context.Context, llm.ChunkFunc) (llm.CommandAnswer, error)) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var sendErr error
+	streamed := false
+	result, err := run(ctx, func(text string) {
+		if sendErr != nil || text == "" {
+			return
+		}
+		streamed = true
+		if sendErr = stream.Send(&historaiv1.CommandChunk{Text: text}); sendErr != nil {
+			cancel()
+		}
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return err
+	}
+	if !streamed {
+		for _, line := range strings.Split(result.String(), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if err := stream.Send(&historaiv1.CommandChunk{Text: line}); err != nil {
+				return err
+			}
+		}
+	}
+	return stream.Send(&historaiv1.CommandChunk{Commands: commandResults(result), Message: result.Message})
+}
+
+// commandResponse converts an answer to its response message.
+func commandResponse(answer llm.CommandAnswer) *historaiv1.CommandResponse {
+	return &historaiv1.CommandResponse{Result: answer.String(), Commands: commandResults(answer), Message: answer.Message}
+}
+
+// commandResults converts the commands of an answer to their messages.
+func commandResults(answer llm.CommandAnswer) []*historaiv1.CommandResult {
+	results := make([]*historaiv1.CommandResult, 0, len(answer.Commands))
+	for _, command := range answer.Commands {
+		results = append(results, &historaiv1.CommandResult{
+			Command:     command.Command,
+			Explanation: command.Explanation,
+			Risk:        string(command.Risk),
+			Source:      string(command.Source),
+		})
+	}
+	return results
+}
+
+func (s *Server) find(ctx context.Context, req *historaiv1.FindRequest, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	if req.GetQuery() == "" {
+		return llm.CommandAnswer{}, status.Error(codes.InvalidArgument, "query cannot be empty")
+	}
+	result, err := s.engine.FindTagStream(ctx, req.GetQuery(), "", int(req.GetLimit()), onChunk)
+	if err != nil {
+		s.logger.Error("Find request failed", zap.Error(err))
+		return llm.CommandAnswer{}, llmError(ctx, err)
+	}
+	return result, nil
+}
+
+func (s *Server) suggest(ctx context.Context, req *historaiv1.SuggestRequest, onChunk llm.ChunkFunc) (llm.CommandAnswer, error) {
+	if req.GetQuery() == "" {
+		return llm.CommandAnswer{}, status.Error(codes.InvalidArgument, "task description cannot be empty")
+	}
+	result, err := s.engine.SuggestStream(ctx, req.GetQuery(), int(req.GetLimit()), req.GetNoHistoryContext(), engine.SuggestContext{}, onChunk)
+	if err != nil {
+		s.logger.Error("Suggest request failed", zap.Error(err))
+		return llm.CommandAnswer{}, llmError(ctx, err)
+	}
+	return result, nil
+}
+
+// llmError converts a failed find or suggest into a status error whose code tells
+// clients whether retrying may help: Canceled or DeadlineExceeded when the call's
+// context ended, and Unavailable only when the provider couldn't be reached. Answers
+// blocked by the safety filters, refused by the policy or failing because of the
+// server's configuration would fail again.
+func llmError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if ctxErr == nil {
+			ctxErr = err
+		}
+		return status.FromContextError(ctxErr).Err()
+	}
+	var providerErr *llm.ProviderError
+	switch {
+	case errors.Is(err, llm.ErrBlocked):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, config.ErrPolicy):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, config.ErrMissingAPIKey):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.As(err, &providerErr) && providerErr.Config:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case llm.IsUnavailable(err):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// authorize checks the bearer token carried in the request metadata. Without a
+// token, it checks that the call was addressed to a loopback host, as the HTTP API
+// does, so that a web page can't reach the server through a gateway by rebinding
+// its own domain name to 127.0.0.1.
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if s.token == "" {
+		for _, authority := range md.Get(":authority") {
+			if !server.IsLoopback(authority) {
+				return status.Errorf(codes.PermissionDenied, "host %q is not allowed without a bearer token", authority)
+			}
+		}
+		return nil
+	}
+	for _, value := range md.Get("authorization") {
+		provided := strings.TrimPrefix(value, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
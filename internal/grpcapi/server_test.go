@@ -0,0 +1,43 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+func TestLLMError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"blocked", fmt.Errorf("find: %w", llm.ErrBlocked), codes.FailedPrecondition},
+		{"policy", fmt.Errorf("provider gemini: %w", config.ErrPolicy), codes.PermissionDenied},
+		{"missing key", config.ErrMissingAPIKey, codes.Unauthenticated},
+		{"bad model", &llm.ProviderError{Reason: "the model was not found", Config: true, Err: errors.New("404")}, codes.InvalidArgument},
+		{"unavailable", fmt.Errorf("find: %w", llm.ErrUnavailable), codes.Unavailable},
+		{"deadline", fmt.Errorf("find: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{"other", errors.New("unexpected response"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(llmError(context.Background(), tt.err)); got != tt.want {
+				t.Errorf("llmError(%v) code = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := status.Code(llmError(ctx, errors.New("stream closed"))); got != codes.Canceled {
+		t.Errorf("llmError after cancel code = %v, want %v", got, codes.Canceled)
+	}
+}
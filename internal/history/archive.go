@@ -0,0 +1,471 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/atrest"
+)
+
+const (
+	// archiveDirName is the directory next to historai's log holding its archive.
+	archiveDirName   = "archive"
+	archiveIndexName = "index.json"
+	segmentSuffix    = ".jsonl.gz"
+
+	// minIndexWordLen is the length of the shortest words the index keeps.
+	minIndexWordLen = 3
+)
+
+// Segment is a file of the archive, holding the entries of one month as
+// gzip-compressed JSON lines, oldest first.
+type Segment struct {
+	File    string `json:"file" yaml:"file"`
+	From    int64  `json:"from" yaml:"from"`
+	To      int64  `json:"to" yaml:"to"`
+	Entries int    `json:"entries" yaml:"entries"`
+	Bytes   int64  `json:"bytes" yaml:"bytes"`
+
+	// Words are the distinct words of the segment's commands, lowercased, which
+	// searches check before reading the segment. Encrypted segments have none, as
+	// the index isn't encrypted.
+	Words []string `json:"words,omitempty" yaml:"-"`
+}
+
+// mayContain reports whether the segment may hold a command containing keyword,
+// lowercased: a word of keyword that is in no word of the segment rules it out.
+func (s Segment) mayContain(keyword string) bool {
+	if s.Words == nil {
+		return true
+	}
+	for _, word := range indexWords(keyword) {
+		found := false
+		for _, indexed := range s.Words {
+			if strings.Contains(indexed, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// archiveIndex is the index of the archive's segments, ordered by time.
+type archiveIndex struct {
+	Segments []Segment `json:"segments"`
+}
+
+// Archive holds the entries rolled out of historai's log by Roll, so that the log
+// stays small and quick to parse while old commands can still be searched.
+type Archive struct {
+	logger     *zap.Logger
+	recordFile string
+	dir        string
+}
+
+// OpenArchive returns the archive of the log at recordFile (DefaultRecordFile when
+// empty), kept in the archive directory next to it. The archive needn't exist yet.
+func OpenArchive(logger *zap.Logger, recordFile string) (*Archive, error) {
+	if recordFile == "" {
+		defaultPath, err := DefaultRecordFile()
+		if err != nil {
+			return nil, err
+		}
+		recordFile = defaultPath
+	}
+	return &Archive{logger: logger, recordFile: recordFile, dir: filepath.Join(filepath.Dir(recordFile), archiveDirName)}, nil
+}
+
+// HistoryFile returns the directory of the archive.
+func (a *Archive) HistoryFile() string {
+	return a.dir
+}
+
+// Segments returns the segments of the archive, oldest first, and none when
+// nothing was archived yet.
+func (a *Archive) Segments() ([]Segment, error) {
+	data, err := os.ReadFile(filepath.Join(a.dir, archiveIndexName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+	var index archiveIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse archive index %s: %w", filepath.Join(a.dir, archiveIndexName), err)
+	}
+	return index.Segments, nil
+}
+
+// Read returns the archived entries of the segments keep selects, oldest first.
+func (a *Archive) Read(keep func(Segment) bool) ([]HistoryEntry, error) {
+	segments, err := a.Segments()
+	if err != nil {
+		return nil, err
+	}
+	var entries []HistoryEntry
+	for _, segment := range segments {
+		if !keep(segment) {
+			continue
+		}
+		read, _, err := a.readSegment(segment.File)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, read...)
+	}
+	return entries, nil
+}
+
+// Search returns the archived entries whose command contains keyword, ignoring
+// case, oldest first. Segments the index rules out aren't read.
+func (a *Archive) Search(keyword string) ([]HistoryEntry, error) {
+	keyword = strings.ToLower(keyword)
+	read := 0
+	entries, err := a.Read(func(segment Segment) bool {
+		if !segment.mayContain(keyword) {
+			return false
+		}
+		read++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	var matches []HistoryEntry
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Command), keyword) {
+			matches = append(matches, entry)
+		}
+	}
+	a.logger.Debug("Searched archive", zap.Int("segments_read", read), zap.Int("matches_count", len(matches)))
+	return matches, nil
+}
+
+// RollResult reports what Roll moved into the archive.
+type RollResult struct {
+	// Archived is the number of entries moved out of the log.
+	Archived int
+
+	// Segments are the files the entries went to, e.g. "2025-03.jsonl.gz".
+	Segments []string
+}
+
+// Roll moves the entries of the log that ran before before into the archive, one
+// segment per month, and rewrites the log without them. Entries without a time stay
+// in the log, and a missing log has nothing to archive. With dryRun set, nothing is
+// changed. Segments are encrypted when the log is (see SealRecords).
+func (a *Archive) Roll(before time.Time, dryRun bool) (RollResult, error) {
+	data, err := os.ReadFile(a.recordFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return RollResult{}, nil
+	}
+	if err != nil {
+		return RollResult{}, fmt.Errorf("failed to read recorded history %s: %w", a.recordFile, err)
+	}
+
+	var kept bytes.Buffer
+	months := make(map[string][]HistoryEntry)
+	var result RollResult
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		trimmed := bytes.TrimRight(line, "\n")
+		if len(trimmed) == 0 {
+			continue
+		}
+		plain := trimmed
+		if atrest.Sealed(trimmed) {
+			if plain, err = atrest.Open(trimmed); err != nil {
+				return RollResult{}, fmt.Errorf("failed to read recorded history %s: %w", a.recordFile, err)
+			}
+		}
+		var entry HistoryEntry
+		if json.Unmarshal(plain, &entry) != nil || entry.Command == "" || entry.Timestamp <= 0 || entry.Timestamp >= before.Unix() {
+			kept.Write(trimmed)
+			kept.WriteByte('\n')
+			continue
+		}
+		month := time.Unix(entry.Timestamp, 0).Format("2006-01")
+		months[month] = append(months[month], entry)
+		result.Archived++
+	}
+	for month := range months {
+		result.Segments = append(result.Segments, month+segmentSuffix)
+	}
+	sort.Strings(result.Segments)
+	if result.Archived == 0 || dryRun {
+		return result, nil
+	}
+
+	sealed, err := atrest.FileSealed(a.recordFile)
+	if err != nil {
+		return RollResult{}, err
+	}
+	if err := os.MkdirAll(a.dir, 0o700); err != nil {
+		return RollResult{}, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	segments, err := a.Segments()
+	if err != nil {
+		return RollResult{}, err
+	}
+	// Segments are written before the log is, so a failure leaves entries in both
+	// rather than in neither; merging drops the duplicates on the next roll.
+	for _, name := range result.Segments {
+		existing, existingSealed, err := a.readSegment(name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return RollResult{}, err
+		}
+		segment, err := a.writeSegment(name, mergeEntries(existing, months[strings.TrimSuffix(name, segmentSuffix)]), sealed || existingSealed)
+		if err != nil {
+			return RollResult{}, err
+		}
+		segments = withSegment(segments, segment)
+	}
+	if err := a.writeIndex(segments); err != nil {
+		return RollResult{}, err
+	}
+
+	if sealed && !atrest.Sealed(kept.Bytes()) {
+		// Keep the log encrypted, as AppendRecords goes by its first line.
+		marker, err := atrest.Seal([]byte("{}"))
+		if err != nil {
+			return RollResult{}, fmt.Errorf("failed to encrypt recorded history: %w", err)
+		}
+		kept = *bytes.NewBuffer(append(append(marker, '\n'), kept.Bytes()...))
+	}
+	if err := writeFileAtomic(a.recordFile, kept.Bytes()); err != nil {
+		return RollResult{}, err
+	}
+	a.logger.Debug("Rolled history into the archive", zap.Int("archived_count", result.Archived), zap.Strings("segments", result.Segments))
+	return result, nil
+}
+
+// Rewrite implements the Rewriter interface for the archive, rewriting each
+// segment the edit changes. Archives have no backups: a rewrite is how entries are
+// removed for good.
+func (a *Archive) Rewrite(edit EditFunc) (RewriteResult, error) {
+	segments, err := a.Segments()
+	if err != nil {
+		return RewriteResult{}, err
+	}
+	var result RewriteResult
+	changed := false
+	for i, segment := range segments {
+		entries, sealed, err := a.readSegment(segment.File)
+		if err != nil {
+			return RewriteResult{}, err
+		}
+		var edited []HistoryEntry
+		segmentChanged := false
+		for _, entry := range entries {
+			replacement, keep := edit(entry)
+			switch {
+			case !keep:
+				result.Removed++
+				segmentChanged = true
+				continue
+			case !reflect.DeepEqual(replacement, entry):
+				result.Changed++
+				segmentChanged = true
+			}
+			edited = append(edited, replacement)
+		}
+		if !segmentChanged {
+			continue
+		}
+		if segments[i], err = a.writeSegment(segment.File, edited, sealed); err != nil {
+			return RewriteResult{}, err
+		}
+		changed = true
+	}
+	if !changed {
+		return result, nil
+	}
+	var remaining []Segment
+	for _, segment := range segments {
+		if segment.Entries > 0 {
+			remaining = append(remaining, segment)
+		} else if err := os.Remove(filepath.Join(a.dir, segment.File)); err != nil {
+			return RewriteResult{}, fmt.Errorf("failed to remove empty segment: %w", err)
+		}
+	}
+	return result, a.writeIndex(remaining)
+}
+
+// Seal encrypts the archive's segments when seal is set, and decrypts them
+// otherwise, as SealRecords does the log. It returns the number of entries converted.
+func (a *Archive) Seal(seal bool) (int, error) {
+	segments, err := a.Segments()
+	if err != nil {
+		return 0, err
+	}
+	converted := 0
+	for i, segment := range segments {
+		entries, sealed, err := a.readSegment(segment.File)
+		if err != nil {
+			return 0, err
+		}
+		if sealed == seal {
+			continue
+		}
+		if segments[i], err = a.writeSegment(segment.File, entries, seal); err != nil {
+			return 0, err
+		}
+		converted += len(entries)
+	}
+	if converted == 0 {
+		return 0, nil
+	}
+	return converted, a.writeIndex(segments)
+}
+
+// readSegment reads the entries of the segment file name, and reports whether they
+// are encrypted.
+func (a *Archive) readSegment(name string) ([]HistoryEntry, bool, error) {
+	path := filepath.Join(a.dir, name)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open archive segment: %w", err)
+	}
+	defer file.Close()
+	decompressed, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read archive segment %s: %w", path, err)
+	}
+	defer decompressed.Close()
+
+	var entries []HistoryEntry
+	sealed := false
+	scanner := bufio.NewScanner(decompressed)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if atrest.Sealed(line) {
+			sealed = true
+			if line, err = atrest.Open(line); err != nil {
+				return nil, false, fmt.Errorf("failed to read archive segment %s: %w", path, err)
+			}
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, false, fmt.Errorf("failed to read archive segment %s: %w", path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read archive segment %s: %w", path, err)
+	}
+	return entries, sealed, nil
+}
+
+// writeSegment replaces the segment file name with entries, encrypted when seal is
+// set, and returns its index record.
+func (a *Archive) writeSegment(name string, entries []HistoryEntry, seal bool) (Segment, error) {
+	var data bytes.Buffer
+	compressed := gzip.NewWriter(&data)
+	segment := Segment{File: name, Entries: len(entries)}
+	words := make(map[string]bool)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return Segment{}, fmt.Errorf("failed to encode history entry: %w", err)
+		}
+		if seal {
+			if line, err = atrest.Seal(line); err != nil {
+				return Segment{}, fmt.Errorf("failed to encrypt history entry: %w", err)
+			}
+		} else {
+			for _, word := range indexWords(strings.ToLower(entry.Command)) {
+				words[word] = true
+			}
+		}
+		if _, err := compressed.Write(append(line, '\n')); err != nil {
+			return Segment{}, fmt.Errorf("failed to compress archive segment: %w", err)
+		}
+		if segment.From == 0 || entry.Timestamp < segment.From {
+			segment.From = entry.Timestamp
+		}
+		segment.To = max(segment.To, entry.Timestamp)
+	}
+	if err := compressed.Close(); err != nil {
+		return Segment{}, fmt.Errorf("failed to compress archive segment: %w", err)
+	}
+	if !seal {
+		segment.Words = make([]string, 0, len(words))
+		for word := range words {
+			segment.Words = append(segment.Words, word)
+		}
+		sort.Strings(segment.Words)
+	}
+	segment.Bytes = int64(data.Len())
+	if len(entries) == 0 {
+		return segment, nil
+	}
+	return segment, writeFileAtomicMode(filepath.Join(a.dir, name), data.Bytes(), 0o600)
+}
+
+// writeIndex replaces the archive index with segments, ordered by time.
+func (a *Archive) writeIndex(segments []Segment) error {
+	sort.Slice(segments, func(i, j int) bool { return segments[i].From < segments[j].From })
+	data, err := json.MarshalIndent(archiveIndex{Segments: segments}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive index: %w", err)
+	}
+	return writeFileAtomicMode(filepath.Join(a.dir, archiveIndexName), append(data, '\n'), 0o600)
+}
+
+// withSegment returns segments with segment added, or replacing the one of the
+// same file.
+func withSegment(segments []Segment, segment Segment) []Segment {
+	for i := range segments {
+		if segments[i].File == segment.File {
+			segments[i] = segment
+			return segments
+		}
+	}
+	return append(segments, segment)
+}
+
+// mergeEntries returns the entries of existing and added, oldest first, without
+// the duplicates left by an interrupted roll.
+func mergeEntries(existing, added []HistoryEntry) []HistoryEntry {
+	seen := make(map[string]bool, len(existing)+len(added))
+	var merged []HistoryEntry
+	for _, entry := range append(existing, added...) {
+		key := strconv.FormatInt(entry.Timestamp, 10) + "\x00" + entry.Host + "\x00" + entry.Command
+		if !seen[key] {
+			seen[key] = true
+			merged = append(merged, entry)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp < merged[j].Timestamp })
+	return merged
+}
+
+// indexWords splits s into the runs of letters and digits the index keeps.
+func indexWords(s string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if len(word) >= minIndexWordLen {
+			words = append(words, word)
+		}
+	}
+	return words
+}
@@ -0,0 +1,87 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CachedHistoryReader wraps a HistoryReader and keeps the parsed entries in memory
+// until the underlying history file changes on disk.
+type CachedHistoryReader struct {
+	logger *zap.Logger
+	reader HistoryReader
+	path   string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	entries []HistoryEntry
+
+	// hits and misses count the reads answered from memory and those that parsed
+	// the file.
+	hits   uint64
+	misses uint64
+}
+
+// NewCachedHistoryReader creates a reader that re-parses path only when its size or mtime changes.
+func NewCachedHistoryReader(logger *zap.Logger, reader HistoryReader, path string) *CachedHistoryReader {
+	return &CachedHistoryReader{
+		logger: logger,
+		reader: reader,
+		path:   path,
+	}
+}
+
+// ReadHistory returns the cached entries, refreshing them first if the history file changed.
+func (c *CachedHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat history file %s: %w", c.path, err)
+	}
+
+	if c.entries == nil || !info.ModTime().Equal(c.modTime) || info.Size() != c.size {
+		c.logger.Debug("Refreshing cached history", zap.String("path", c.path))
+		entries, err := c.reader.ReadHistory(0)
+		if err != nil {
+			return nil, err
+		}
+		c.entries = entries
+		c.modTime = info.ModTime()
+		c.size = info.Size()
+		c.misses++
+	} else {
+		c.hits++
+	}
+
+	return applyLimitFilter(c.logger, c.entries, limit), nil
+}
+
+// CachedEntries returns how many parsed entries are held in memory.
+func (c *CachedHistoryReader) CachedEntries() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear drops the parsed entries, so that the next read parses the history file
+// again.
+func (c *CachedHistoryReader) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// CacheStats returns how many reads were answered from memory, and how many had to
+// parse the history file.
+func (c *CachedHistoryReader) CacheStats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
@@ -0,0 +1,131 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"go.uber.org/zap"
+)
+
+// SourceClink reads the history of cmd.exe as saved by clink, or a 'doskey /history'
+// export, which is one command per line.
+const SourceClink = "clink"
+
+// clinkTimePrefix starts the lines clink writes before a command, with the time it
+// ran, when its history.time_stamp setting is on.
+const clinkTimePrefix = "|\ttime="
+
+// ClinkHistoryReader implements the HistoryReader interface for clink's history
+// file. Besides commands, the file holds lines starting with "|": a header, the
+// times of the commands, and commands that were deleted.
+type ClinkHistoryReader struct {
+	logger      *zap.Logger
+	historyFile string
+}
+
+// NewClinkHistoryReader creates a reader for histFilePath, or for clink's history
+// file in its default profile directory when it is empty.
+func NewClinkHistoryReader(logger *zap.Logger, histFilePath string) (*ClinkHistoryReader, error) {
+	if histFilePath == "" {
+		defaultPath, err := defaultClinkHistoryPath()
+		if err != nil {
+			return nil, fmt.Errorf("could not determine clink history file path: %w", err)
+		}
+		histFilePath = defaultPath
+	}
+	if _, err := os.Stat(histFilePath); os.IsNotExist(err) {
+		logger.Error("clink history file does not exist", zap.String("path", histFilePath))
+		return nil, fmt.Errorf("clink history file not found at %s", histFilePath)
+	}
+	logger.Debug("Using clink history file", zap.String("path", histFilePath))
+	return &ClinkHistoryReader{
+		logger:      logger,
+		historyFile: histFilePath,
+	}, nil
+}
+
+// defaultClinkHistoryPath returns clink_history in clink's profile directory:
+// $CLINK_PROFILE, or %LOCALAPPDATA%\clink.
+func defaultClinkHistoryPath() (string, error) {
+	const fileName = "clink_history"
+	if profile := os.Getenv("CLINK_PROFILE"); profile != "" {
+		return filepath.Join(profile, fileName), nil
+	}
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		return "", errors.New("LOCALAPPDATA is not set; set history.file to clink's history file")
+	}
+	return filepath.Join(localAppData, "clink", fileName), nil
+}
+
+// HistoryFile returns the path of the clink history file being read.
+func (r *ClinkHistoryReader) HistoryFile() string {
+	return r.historyFile
+}
+
+// ReadHistory reads the history file and returns its most recent entries.
+func (r *ClinkHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
+	data, err := os.ReadFile(r.historyFile)
+	if err != nil {
+		r.logger.Error("Failed to read clink history file", zap.String("path", r.historyFile), zap.Error(err))
+		return nil, fmt.Errorf("failed to open history file %s: %w", r.historyFile, err)
+	}
+
+	entries, err := parseClinkHistory(data)
+	if err != nil {
+		return nil, err
+	}
+	return applyLimitFilter(r.logger, entries, limit), nil
+}
+
+// parseClinkHistory parses clink's history, or a 'doskey /history' export, which has
+// no "|" lines. Exports redirected in PowerShell are UTF-16, which is converted.
+func parseClinkHistory(data []byte) ([]HistoryEntry, error) {
+	data = decodeUTF16(data)
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	var entries []HistoryEntry
+	var timestamp int64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if value, ok := strings.CutPrefix(line, clinkTimePrefix); ok {
+			timestamp, _ = strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			continue
+		}
+		if strings.HasPrefix(line, "|") {
+			// The header, or a deleted command.
+			timestamp = 0
+			continue
+		}
+		if command := strings.ToValidUTF8(strings.TrimSpace(line), "\uFFFD"); command != "" {
+			entries = append(entries, HistoryEntry{Timestamp: timestamp, Command: command})
+		}
+		timestamp = 0
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history data: %w", err)
+	}
+	return entries, nil
+}
+
+// decodeUTF16 converts data to UTF-8 when it starts with a UTF-16 little-endian byte
+// order mark, and returns it unchanged otherwise.
+func decodeUTF16(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xfe {
+		return data
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 2; i+1 < len(data); i += 2 {
+		units = append(units, uint16(data[i])|uint16(data[i+1])<<8)
+	}
+	return []byte(string(utf16.Decode(units)))
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Export formats.
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatAtuin = "atuin"
+	FormatZsh   = "zsh"
+	FormatPlain = "plain"
+)
+
+// ExportFormats lists the formats understood by Export.
+var ExportFormats = []string{FormatJSON, FormatJSONL, FormatCSV, FormatAtuin, FormatZsh, FormatPlain}
+
+// atuinRecord mirrors the fields of a row in Atuin's history database.
+type atuinRecord struct {
+	Timestamp int64  `json:"timestamp"` // nanoseconds since the epoch
+	Duration  int64  `json:"duration"`  // nanoseconds, -1 when unknown
+	Exit      int    `json:"exit"`      // -1 when unknown
+	Command   string `json:"command"`
+	Cwd       string `json:"cwd"`
+	Hostname  string `json:"hostname"`
+}
+
+// Export writes entries to w in format, keeping whatever metadata each format can hold.
+func Export(w io.Writer, format string, entries []HistoryEntry) error {
+	switch format {
+	case FormatJSON:
+		if entries == nil {
+			entries = []HistoryEntry{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		return exportCSV(w, entries)
+	case FormatAtuin:
+		return exportAtuin(w, entries)
+	case FormatZsh:
+		for _, entry := range entries {
+			if _, err := io.WriteString(w, FormatZshEntry(entry)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatPlain:
+		for _, entry := range entries {
+			if _, err := fmt.Fprintln(w, entry.Command); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown export format %q (expected one of %s)", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+// FormatZshEntry formats entry as a Zsh extended history record, ending in a newline.
+func FormatZshEntry(entry HistoryEntry) string {
+	elapsed := entry.DurationMS / 1000
+	command := strings.ReplaceAll(entry.Command, "\n", "\\\n")
+	return fmt.Sprintf(": %d:%d;%s\n", entry.Timestamp, elapsed, command)
+}
+
+// exportCSV writes one row per entry with a header row, for spreadsheets and pandas.
+func exportCSV(w io.Writer, entries []HistoryEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"timestamp", "command", "cwd", "exit_code", "duration_ms", "host"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		exitCode := ""
+		if entry.ExitCode != nil {
+			exitCode = strconv.Itoa(*entry.ExitCode)
+		}
+		duration := ""
+		if entry.DurationMS > 0 {
+			duration = strconv.FormatInt(entry.DurationMS, 10)
+		}
+		row := []string{strconv.FormatInt(entry.Timestamp, 10), entry.Command, entry.Cwd, exitCode, duration, entry.Host}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportAtuin writes one JSON object per line with the fields of Atuin's history table.
+func exportAtuin(w io.Writer, entries []HistoryEntry) error {
+	localHost, _ := os.Hostname()
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		hostname := entry.Host
+		if hostname == "" {
+			hostname = localHost
+		}
+		record := atuinRecord{
+			Timestamp: entry.Timestamp * 1e9,
+			Duration:  -1,
+			Exit:      -1,
+			Command:   entry.Command,
+			Cwd:       entry.Cwd,
+			Hostname:  hostname,
+		}
+		if entry.DurationMS > 0 {
+			record.Duration = entry.DurationMS * 1e6
+		}
+		if entry.ExitCode != nil {
+			record.Exit = *entry.ExitCode
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package history
+
+import "go.uber.org/zap"
+
+// SourceZsh is the built-in Zsh history source and the default.
+const SourceZsh = "zsh"
+
+// NewReader returns the HistoryReader for the named source. Built-in sources are
+// handled directly; any other name is resolved to an external source plugin.
+// file overrides the default history file location of built-in sources.
+func NewReader(logger *zap.Logger, source string, file string) (HistoryReader, error) {
+	switch source {
+	case "", SourceZsh:
+		return NewZshHistoryReader(logger, file)
+	case SourceHistorai:
+		return NewRecordedHistoryReader(logger, file)
+	case SourcePowerShell:
+		return NewPowerShellHistoryReader(logger, file)
+	case SourceClink:
+		return NewClinkHistoryReader(logger, file)
+	default:
+		return NewPluginHistoryReader(logger, source)
+	}
+}
+
+// SourceShell returns the shell whose history the named source reads, for the
+// sources of Windows shells, which don't set $SHELL. It returns "" for the others.
+func SourceShell(source string) string {
+	switch source {
+	case SourcePowerShell:
+		return "powershell"
+	case SourceClink:
+		return "cmd"
+	default:
+		return ""
+	}
+}
+
+// fileBackedReader is implemented by readers that parse a single local file,
+// which lets callers cache their results until the file changes.
+type fileBackedReader interface {
+	HistoryReader
+	HistoryFile() string
+}
+
+// WithCache wraps reader in a CachedHistoryReader when it is backed by a local file.
+func WithCache(logger *zap.Logger, reader HistoryReader) HistoryReader {
+	if fileReader, ok := reader.(fileBackedReader); ok {
+		return NewCachedHistoryReader(logger, fileReader, fileReader.HistoryFile())
+	}
+	return reader
+}
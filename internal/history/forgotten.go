@@ -0,0 +1,126 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// forgottenFileName is the file next to historai's log listing the entries removed
+// by 'historai forget', so that syncing doesn't bring them back.
+const forgottenFileName = "forgotten"
+
+// Forgotten is the set of entries removed from historai's log, kept as tombstones
+// that merges of other copies of the log respect. Only a hash of each entry's host,
+// timestamp and command is kept, so the file doesn't hold the forgotten commands.
+type Forgotten struct {
+	path      string
+	localHost string
+	hashes    map[string]bool
+}
+
+// LoadForgotten reads the tombstones of the log at recordFile (DefaultRecordFile
+// when empty). There are none when nothing was forgotten yet.
+func LoadForgotten(recordFile string) (*Forgotten, error) {
+	if recordFile == "" {
+		defaultPath, err := DefaultRecordFile()
+		if err != nil {
+			return nil, err
+		}
+		recordFile = defaultPath
+	}
+	// The local log leaves the host of this machine's commands empty, while synced
+	// copies name it.
+	localHost, _ := os.Hostname()
+	f := &Forgotten{path: filepath.Join(filepath.Dir(recordFile), forgottenFileName), localHost: localHost, hashes: make(map[string]bool)}
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forgotten entries %s: %w", f.path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if hash := strings.TrimSpace(scanner.Text()); hash != "" {
+			f.hashes[hash] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read forgotten entries %s: %w", f.path, err)
+	}
+	return f, nil
+}
+
+// Path returns the file the tombstones are kept in.
+func (f *Forgotten) Path() string {
+	return f.path
+}
+
+// Add records entries as forgotten, and reports how many weren't already.
+func (f *Forgotten) Add(entries []HistoryEntry) int {
+	added := 0
+	for _, entry := range entries {
+		if hash := f.hash(entry); !f.hashes[hash] {
+			f.hashes[hash] = true
+			added++
+		}
+	}
+	return added
+}
+
+// Has reports whether entry was forgotten.
+func (f *Forgotten) Has(entry HistoryEntry) bool {
+	return f.hashes[f.hash(entry)]
+}
+
+// Drop returns the entries that weren't forgotten.
+func (f *Forgotten) Drop(entries []HistoryEntry) []HistoryEntry {
+	if len(f.hashes) == 0 {
+		return entries
+	}
+	var kept []HistoryEntry
+	for _, entry := range entries {
+		if !f.Has(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// Save writes the tombstones, readable only by the user.
+func (f *Forgotten) Save() error {
+	hashes := make([]string, 0, len(f.hashes))
+	for hash := range f.hashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	var data bytes.Buffer
+	for _, hash := range hashes {
+		data.WriteString(hash + "\n")
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return writeFileAtomicMode(f.path, data.Bytes(), 0o600)
+}
+
+// hash returns the tombstone of entry, from its merge key with this machine's host
+// name left empty.
+func (f *Forgotten) hash(entry HistoryEntry) string {
+	key := keyOf(entry)
+	if key.host == f.localHost {
+		key.host = ""
+	}
+	sum := sha256.Sum256([]byte(key.host + "\x00" + strconv.FormatInt(key.timestamp, 10) + "\x00" + key.command))
+	return hex.EncodeToString(sum[:])
+}
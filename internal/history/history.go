@@ -1,12 +1,78 @@
 package history
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 // HistoryEntry represents a single command from the shell history.
 type HistoryEntry struct {
-	Timestamp int64
-	Command   string // The command itself
+	Timestamp int64  `json:"timestamp" yaml:"timestamp"`
+	Command   string `json:"command" yaml:"command"`             // The command itself
+	Cwd       string `json:"cwd,omitempty" yaml:"cwd,omitempty"` // Working directory, when the source records it
+
+	// ExitCode is the command's exit status, when the source records it.
+	ExitCode *int `json:"exit_code,omitempty" yaml:"exit_code,omitempty"`
+	// DurationMS is how long the command ran, when the source records it.
+	DurationMS int64 `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"`
+	// Stderr is the tail of the command's error output, when the shell hook captured it.
+	Stderr string `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+	// Output is the tail of the command's output, when the shell hook captured it.
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+	// Host is the machine the command ran on, for entries imported from other machines.
+	Host string `json:"host,omitempty" yaml:"host,omitempty"`
+	// Note is what the user wrote about the command with 'historai annotate'.
+	Note string `json:"note,omitempty" yaml:"note,omitempty"`
+	// Tags are the labels the user gave the command with 'historai tag'.
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Pinned reports that the user saved the command with 'historai save'.
+	Pinned bool `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	// Team is the file of the team's shared snippets repository the command comes
+	// from (see 'historai team'); the user didn't run it. Empty for their own commands.
+	Team string `json:"team,omitempty" yaml:"team,omitempty"`
+}
+
+// Failed reports whether the entry is known to have exited with a non-zero status.
+func (e HistoryEntry) Failed() bool {
+	return e.ExitCode != nil && *e.ExitCode != 0
+}
+
+// HasHosts reports whether any entry carries a host label.
+func HasHosts(entries []HistoryEntry) bool {
+	for _, entry := range entries {
+		if entry.Host != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ProgramName returns the program a command line runs, ignoring a leading sudo
+// or environment assignments.
+func ProgramName(command string) string {
+	for _, field := range strings.Fields(command) {
+		if field == "sudo" || strings.Contains(field, "=") {
+			continue
+		}
+		return filepath.Base(field)
+	}
+	return ""
 }
 
 // HistoryReader defines the interface for reading shell history.
 type HistoryReader interface {
 	ReadHistory(limit int) ([]HistoryEntry, error)
 }
+
+// ParseProblem is a line of a history file that a reader skipped or repaired.
+type ParseProblem struct {
+	Line   int    `json:"line" yaml:"line"`
+	Reason string `json:"reason" yaml:"reason"`
+	Text   string `json:"text" yaml:"text"`
+}
+
+// ProblemReporter is implemented by readers of a history file that can list the
+// lines they skip or repair, e.g. for 'historai debug-bundle'.
+type ProblemReporter interface {
+	ParseProblems() ([]ParseProblem, error)
+}
@@ -0,0 +1,211 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Import formats, in addition to the export formats.
+const (
+	FormatAuto = "auto"
+	FormatBash = "bash"
+)
+
+// ImportFormats lists the formats understood by Parse.
+var ImportFormats = []string{FormatAuto, FormatBash, FormatZsh, FormatAtuin, FormatJSON, FormatJSONL, FormatTerminal, FormatPlain}
+
+// bashTimestamp matches the "#<epoch>" lines bash writes when HISTTIMEFORMAT is set.
+var bashTimestamp = regexp.MustCompile(`^#(\d{9,})$`)
+
+// Parse reads history in format from r. FormatAuto detects the format from the content.
+func Parse(logger *zap.Logger, r io.Reader, format string) ([]HistoryEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	if format == FormatAuto {
+		format = DetectFormat(data)
+		logger.Debug("Detected history format", zap.String("format", format))
+	}
+
+	switch format {
+	case FormatZsh:
+		return (&ZshHistoryReader{logger: logger}).parseHistory(bytes.NewReader(data), nil)
+	case FormatBash:
+		return parseBash(data), nil
+	case FormatPlain:
+		return parsePlain(data), nil
+	case FormatTerminal:
+		return parseTerminal(data), nil
+	case FormatJSON:
+		var entries []HistoryEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("invalid JSON history: %w", err)
+		}
+		return withCommands(entries), nil
+	case FormatJSONL:
+		return parseJSONLines(data, func(line []byte) (HistoryEntry, error) {
+			var entry HistoryEntry
+			err := json.Unmarshal(line, &entry)
+			return entry, err
+		})
+	case FormatAtuin:
+		return parseJSONLines(data, parseAtuinLine)
+	default:
+		return nil, fmt.Errorf("unknown import format %q (expected one of %s)", format, strings.Join(ImportFormats, ", "))
+	}
+}
+
+// DetectFormat guesses the format of history data from its first non-empty line,
+// or from the shell-integration marks of a terminal recording.
+func DetectFormat(data []byte) string {
+	if bytes.Contains(data, []byte("\x1b]133;")) {
+		return FormatTerminal
+	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	first, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	first = bytes.TrimSpace(first)
+	switch {
+	case bytes.HasPrefix(first, []byte("[")):
+		return FormatJSON
+	case bytes.HasPrefix(first, []byte("{")):
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(first, &fields) == nil {
+			if _, ok := fields["exit"]; ok {
+				return FormatAtuin
+			}
+		}
+		return FormatJSONL
+	case zshRecordStart.Match(first):
+		return FormatZsh
+	case bashTimestamp.Match(first):
+		return FormatBash
+	default:
+		return FormatPlain
+	}
+}
+
+// parseBash parses ~/.bash_history, using "#<epoch>" timestamp lines when present.
+func parseBash(data []byte) []HistoryEntry {
+	var entries []HistoryEntry
+	var timestamp int64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := bashTimestamp.FindStringSubmatch(line); match != nil {
+			timestamp, _ = strconv.ParseInt(match[1], 10, 64)
+			continue
+		}
+		if line != "" {
+			entries = append(entries, HistoryEntry{Timestamp: timestamp, Command: line})
+		}
+		timestamp = 0
+	}
+	return entries
+}
+
+// parsePlain parses one command per line, without metadata.
+func parsePlain(data []byte) []HistoryEntry {
+	var entries []HistoryEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, HistoryEntry{Command: line})
+		}
+	}
+	return entries
+}
+
+// parseJSONLines parses one JSON object per line with parse, skipping blank lines.
+func parseJSONLines(data []byte, parse func(line []byte) (HistoryEntry, error)) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		entry, err := parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return withCommands(entries), nil
+}
+
+// parseAtuinLine converts a line written by Export's atuin format.
+func parseAtuinLine(line []byte) (HistoryEntry, error) {
+	var record atuinRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return HistoryEntry{}, err
+	}
+	entry := HistoryEntry{
+		Timestamp: record.Timestamp / 1e9,
+		Command:   record.Command,
+		Cwd:       record.Cwd,
+		Host:      record.Hostname,
+	}
+	if record.Duration > 0 {
+		entry.DurationMS = record.Duration / 1e6
+	}
+	if record.Exit >= 0 {
+		exit := record.Exit
+		entry.ExitCode = &exit
+	}
+	return entry, nil
+}
+
+// withCommands drops entries without a command.
+func withCommands(entries []HistoryEntry) []HistoryEntry {
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Command) != "" {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// Merge returns the entries of incoming that are not already in existing, comparing
+// host, timestamp and command, and without duplicates among themselves.
+func Merge(existing, incoming []HistoryEntry) []HistoryEntry {
+	seen := make(map[mergeKey]bool, len(existing))
+	for _, entry := range existing {
+		seen[keyOf(entry)] = true
+	}
+	return unseen(seen, incoming)
+}
+
+// mergeKey identifies an entry when histories are merged.
+type mergeKey struct {
+	host      string
+	timestamp int64
+	command   string
+}
+
+// keyOf returns the mergeKey of entry.
+func keyOf(entry HistoryEntry) mergeKey {
+	return mergeKey{entry.Host, entry.Timestamp, strings.TrimSpace(entry.Command)}
+}
+
+// unseen returns the entries of incoming whose key isn't in seen, and adds their
+// keys to it.
+func unseen(seen map[mergeKey]bool, incoming []HistoryEntry) []HistoryEntry {
+	var added []HistoryEntry
+	for _, entry := range incoming {
+		k := keyOf(entry)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		added = append(added, entry)
+	}
+	return added
+}
@@ -0,0 +1,188 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Mirror copies the commands Zsh appends to its history file into historai's log
+// as they are written, so that the log, with its archive, sync and encryption, is
+// kept up to date without the shell hook or 'historai import'. Only Zsh's extended
+// history format records when commands ran, so other lines are skipped.
+type Mirror struct {
+	logger      *zap.Logger
+	parser      *ZshHistoryReader
+	historyFile string
+	recordFile  string
+
+	// seen holds the entries already in the log, its archive or the history file,
+	// which aren't copied again when Zsh rewrites its file.
+	seen map[mergeKey]bool
+
+	// info and offset describe the history file up to the last complete record read.
+	info   os.FileInfo
+	offset int64
+}
+
+// NewMirror returns a Mirror of the Zsh history file at historyFile (the default
+// one when empty) into the log at recordFile (DefaultRecordFile when empty). Only
+// commands written from now on are copied; 'historai import' copies the others.
+func NewMirror(logger *zap.Logger, historyFile, recordFile string) (*Mirror, error) {
+	parser, err := NewZshHistoryReader(logger, historyFile)
+	if err != nil {
+		return nil, err
+	}
+	if recordFile == "" {
+		if recordFile, err = DefaultRecordFile(); err != nil {
+			return nil, err
+		}
+	}
+	m := &Mirror{logger: logger, parser: parser, historyFile: parser.HistoryFile(), recordFile: recordFile, seen: make(map[mergeKey]bool)}
+
+	var known []HistoryEntry
+	if _, err := os.Stat(recordFile); err == nil {
+		record, err := NewRecordedHistoryReader(logger, recordFile)
+		if err != nil {
+			return nil, err
+		}
+		if known, err = record.ReadHistory(0); err != nil {
+			return nil, err
+		}
+	}
+	archive, err := OpenArchive(logger, recordFile)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := archive.Read(func(Segment) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	unseen(m.seen, append(archived, known...))
+
+	// The commands already in the file are left to 'historai import', so that those
+	// removed from the log with 'historai forget' don't come back.
+	if m.info, err = os.Stat(m.historyFile); err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", m.historyFile, err)
+	}
+	current, err := m.read(0)
+	if err != nil {
+		return nil, err
+	}
+	unseen(m.seen, current.entries)
+	m.offset = current.end
+	logger.Debug("Mirroring Zsh history", zap.String("history_file", m.historyFile), zap.String("record_file", recordFile),
+		zap.Int("known_count", len(m.seen)))
+	return m, nil
+}
+
+// HistoryFile returns the Zsh history file being mirrored.
+func (m *Mirror) HistoryFile() string {
+	return m.historyFile
+}
+
+// Poll copies the commands written to the history file since the last poll into
+// the log, and returns how many were copied. When Zsh has rewritten the file, e.g.
+// to trim it to $SAVEHIST, it is read again and only unseen commands are copied.
+func (m *Mirror) Poll() (int, error) {
+	info, err := os.Stat(m.historyFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", m.historyFile, err)
+	}
+	start := m.offset
+	if !os.SameFile(info, m.info) || info.Size() < m.offset {
+		m.logger.Debug("Zsh history file was rewritten; reading it again", zap.String("path", m.historyFile))
+		start = 0
+	} else if info.Size() == m.offset {
+		return 0, nil
+	}
+
+	chunk, err := m.read(start)
+	if err != nil {
+		return 0, err
+	}
+	added := unseen(m.seen, chunk.entries)
+	if len(added) > 0 {
+		if err := AppendRecords(m.recordFile, added); err != nil {
+			// Forget them, so the next poll tries again.
+			for _, entry := range added {
+				delete(m.seen, keyOf(entry))
+			}
+			return 0, err
+		}
+	}
+	m.info, m.offset = info, chunk.end
+	return len(added), nil
+}
+
+// Run polls the history file every interval until ctx is cancelled. Failures are
+// logged and retried, as the file may be briefly missing while Zsh replaces it.
+func (m *Mirror) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		added, err := m.Poll()
+		if err != nil {
+			m.logger.Warn("Failed to mirror Zsh history", zap.Error(err))
+			continue
+		}
+		if added > 0 {
+			m.logger.Debug("Mirrored Zsh history", zap.Int("added_count", added))
+		}
+	}
+}
+
+// mirrorChunk is what a read of the history file found.
+type mirrorChunk struct {
+	entries []HistoryEntry
+
+	// end is the offset just past the last complete record.
+	end int64
+}
+
+// read parses the complete records of the history file from offset start. A record
+// Zsh is still writing, without its final newline or continued on the next line,
+// is left for the next read.
+func (m *Mirror) read(start int64) (mirrorChunk, error) {
+	file, err := os.Open(m.historyFile)
+	if err != nil {
+		return mirrorChunk{}, fmt.Errorf("failed to open %s: %w", m.historyFile, err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return mirrorChunk{}, fmt.Errorf("failed to read %s: %w", m.historyFile, err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return mirrorChunk{}, fmt.Errorf("failed to read %s: %w", m.historyFile, err)
+	}
+
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	if bytes.HasSuffix(data, []byte("\\\n")) {
+		// The last record continues on a line not written yet.
+		lines := bytes.SplitAfter(data, []byte("\n"))
+		cut := len(data)
+		for i := len(lines) - 1; i >= 0; i-- {
+			cut -= len(lines[i])
+			if zshRecordStart.Match(lines[i]) {
+				break
+			}
+		}
+		data = data[:max(cut, 0)]
+	}
+	entries, err := m.parser.parseHistory(bytes.NewReader(data), nil)
+	if err != nil {
+		return mirrorChunk{}, err
+	}
+	return mirrorChunk{entries: entries, end: start + int64(len(data))}, nil
+}
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SourcePluginPrefix is the executable name prefix of history-source plugins.
+// A plugin for source "auditlog" is an executable named "historai-source-auditlog" on PATH.
+const SourcePluginPrefix = "historai-source-"
+
+// sourcePluginProtocolVersion is sent to plugins so they can reject requests they don't understand.
+const sourcePluginProtocolVersion = 1
+
+const sourcePluginTimeout = 30 * time.Second
+
+// sourcePluginRequest is written as a single JSON object to the plugin's stdin.
+type sourcePluginRequest struct {
+	Version int `json:"version"`
+	Limit   int `json:"limit"`
+}
+
+// PluginHistoryReader reads history from an external executable.
+//
+// Protocol: historai starts the plugin, writes one sourcePluginRequest JSON object
+// to its stdin and closes it. The plugin prints one JSON object per line to stdout,
+// each shaped like HistoryEntry ({"timestamp": 1700000000, "command": "ls -la"}),
+// oldest first, and exits with status 0. Anything on stderr is logged.
+type PluginHistoryReader struct {
+	logger *zap.Logger
+	name   string
+	path   string
+}
+
+// NewPluginHistoryReader locates the plugin executable for source name on PATH.
+func NewPluginHistoryReader(logger *zap.Logger, name string) (*PluginHistoryReader, error) {
+	path, err := exec.LookPath(SourcePluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown history source %q (no %s%s executable on PATH): %w", name, SourcePluginPrefix, name, err)
+	}
+	logger.Debug("Using history source plugin", zap.String("source", name), zap.String("path", path))
+	return &PluginHistoryReader{
+		logger: logger,
+		name:   name,
+		path:   path,
+	}, nil
+}
+
+// ReadHistory runs the plugin and parses the entries it prints.
+func (r *PluginHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
+	request, err := json.Marshal(sourcePluginRequest{Version: sourcePluginProtocolVersion, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sourcePluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.path)
+	cmd.Stdin = bytes.NewReader(request)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		r.logger.Error("History source plugin failed", zap.String("source", r.name), zap.String("stderr", strings.TrimSpace(stderr.String())), zap.Error(err))
+		return nil, fmt.Errorf("history source plugin %q failed: %w", r.name, err)
+	}
+	if stderr.Len() > 0 {
+		r.logger.Debug("History source plugin stderr", zap.String("source", r.name), zap.String("stderr", strings.TrimSpace(stderr.String())))
+	}
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			r.logger.Warn("Skipping malformed plugin entry", zap.String("source", r.name), zap.Int("line", lineNumber), zap.Error(err))
+			continue
+		}
+		if entry.Command == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading plugin output: %w", err)
+	}
+
+	return applyLimitFilter(r.logger, entries, limit), nil
+}
@@ -0,0 +1,127 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// SourcePowerShell reads the history file saved by PSReadLine, PowerShell's line
+// editor. It is the default source on Windows.
+const SourcePowerShell = "powershell"
+
+// PowerShellHistoryReader implements the HistoryReader interface for PSReadLine's
+// history file, which holds one command per line without metadata. The lines of a
+// multi-line command end in a backtick, except the last one.
+type PowerShellHistoryReader struct {
+	logger      *zap.Logger
+	historyFile string
+}
+
+// NewPowerShellHistoryReader creates a reader for histFilePath, or for PSReadLine's
+// default history file when it is empty.
+func NewPowerShellHistoryReader(logger *zap.Logger, histFilePath string) (*PowerShellHistoryReader, error) {
+	if histFilePath == "" {
+		defaultPath, err := defaultPowerShellHistoryPath()
+		if err != nil {
+			return nil, fmt.Errorf("could not determine PowerShell history file path: %w", err)
+		}
+		histFilePath = defaultPath
+	}
+	if _, err := os.Stat(histFilePath); os.IsNotExist(err) {
+		logger.Error("PowerShell history file does not exist", zap.String("path", histFilePath))
+		return nil, fmt.Errorf("PowerShell history file not found at %s", histFilePath)
+	}
+	logger.Debug("Using PowerShell history file", zap.String("path", histFilePath))
+	return &PowerShellHistoryReader{
+		logger:      logger,
+		historyFile: histFilePath,
+	}, nil
+}
+
+// defaultPowerShellHistoryPath returns where PSReadLine saves the history of the
+// console host: under %APPDATA% on Windows, and under the XDG data directory
+// elsewhere.
+func defaultPowerShellHistoryPath() (string, error) {
+	const fileName = "ConsoleHost_history.txt"
+	if runtime.GOOS == "windows" {
+		appData := os.Getenv("APPDATA")
+		if appData == "" {
+			return "", errors.New("APPDATA is not set")
+		}
+		return filepath.Join(appData, "Microsoft", "Windows", "PowerShell", "PSReadLine", fileName), nil
+	}
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "powershell", "PSReadLine", fileName), nil
+}
+
+// HistoryFile returns the path of the PowerShell history file being read.
+func (r *PowerShellHistoryReader) HistoryFile() string {
+	return r.historyFile
+}
+
+// ReadHistory opens the history file and returns its most recent entries.
+func (r *PowerShellHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
+	file, err := os.Open(r.historyFile)
+	if err != nil {
+		r.logger.Error("Failed to open PowerShell history file", zap.String("path", r.historyFile), zap.Error(err))
+		return nil, fmt.Errorf("failed to open history file %s: %w", r.historyFile, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	entries, err := parsePowerShellHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	return applyLimitFilter(r.logger, entries, limit), nil
+}
+
+// parsePowerShellHistory parses PSReadLine's history, joining the lines of
+// multi-line commands. PSReadLine writes UTF-8, possibly with CRLF line endings.
+func parsePowerShellHistory(reader io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	var current strings.Builder
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+		if strings.HasSuffix(line, "`") {
+			current.WriteString(strings.TrimSuffix(line, "`"))
+			current.WriteString("\n")
+			continue
+		}
+		current.WriteString(line)
+		if command := strings.ToValidUTF8(strings.TrimSpace(current.String()), "\uFFFD"); command != "" {
+			entries = append(entries, HistoryEntry{Command: command})
+		}
+		current.Reset()
+	}
+	if command := strings.TrimSpace(current.String()); command != "" {
+		entries = append(entries, HistoryEntry{Command: strings.ToValidUTF8(command, "\uFFFD")})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history data: %w", err)
+	}
+	return entries, nil
+}
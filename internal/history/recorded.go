@@ -0,0 +1,284 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/atrest"
+)
+
+// SourceHistorai reads the log written by historai's own shell hook
+// ("historai init zsh"), which also records exit codes and durations.
+const SourceHistorai = "historai"
+
+const (
+	recordDirName  = "historai"
+	recordFileName = "history.jsonl"
+
+	// maxRecordLineSize bounds a single JSON line, as recorded commands may be long.
+	maxRecordLineSize = 1024 * 1024
+)
+
+// MaxRecordedStderr and MaxRecordedOutput bound the stderr and output kept per
+// command, in bytes.
+const (
+	MaxRecordedStderr = 4096
+	MaxRecordedOutput = 4096
+)
+
+// TailBytes returns at most the last n bytes of s, starting at a line boundary when possible.
+func TailBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// DefaultRecordFile returns the location of the shell hook's log,
+// $XDG_DATA_HOME/historai/history.jsonl or ~/.local/share/historai/history.jsonl.
+func DefaultRecordFile() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, recordFileName), nil
+}
+
+// DataDir returns historai's data directory: $XDG_DATA_HOME/historai, or
+// ~/.local/share/historai when XDG_DATA_HOME is not set (%LOCALAPPDATA%\historai
+// on Windows).
+func DataDir() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, recordDirName), nil
+	}
+	if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" && runtime.GOOS == "windows" {
+		return filepath.Join(localAppData, recordDirName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", recordDirName), nil
+}
+
+// RecordedHistoryReader implements the HistoryReader interface for the shell hook's
+// log: one JSON-encoded HistoryEntry per line, oldest first.
+type RecordedHistoryReader struct {
+	logger     *zap.Logger
+	recordFile string
+}
+
+// NewRecordedHistoryReader creates a reader for path, or for DefaultRecordFile when it is empty.
+func NewRecordedHistoryReader(logger *zap.Logger, path string) (*RecordedHistoryReader, error) {
+	if path == "" {
+		defaultPath, err := DefaultRecordFile()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		logger.Error("Recorded history file does not exist", zap.String("path", path))
+		return nil, fmt.Errorf("no recorded history at %s (add `eval \"$(historai init zsh)\"` to your shell config)", path)
+	}
+	logger.Debug("Using recorded history file", zap.String("path", path))
+	return &RecordedHistoryReader{logger: logger, recordFile: path}, nil
+}
+
+// HistoryFile returns the path of the log being read.
+func (r *RecordedHistoryReader) HistoryFile() string {
+	return r.recordFile
+}
+
+// ReadHistory parses the log and returns the most recent entries, limited by limit.
+func (r *RecordedHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
+	entries, err := r.readEntries(nil)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debug("Parsed recorded history", zap.Int("entries_count", len(entries)))
+	return applyLimitFilter(r.logger, entries, limit), nil
+}
+
+// ParseProblems implements the ProblemReporter interface method.
+func (r *RecordedHistoryReader) ParseProblems() ([]ParseProblem, error) {
+	var problems []ParseProblem
+	if _, err := r.readEntries(&problems); err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// readEntries parses the whole log, oldest first. Lines it skips are added to
+// problems, when not nil.
+func (r *RecordedHistoryReader) readEntries(problems *[]ParseProblem) ([]HistoryEntry, error) {
+	file, err := os.Open(r.recordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recorded history %s: %w", r.recordFile, err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		line := scanner.Bytes()
+		if atrest.Sealed(line) {
+			plain, err := atrest.Open(line)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read recorded history %s, line %d: %w", r.recordFile, lineNumber, err)
+			}
+			line = plain
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// A line may be torn if the machine crashed mid-write; skip it.
+			r.logger.Debug("Skipping malformed recorded history line", zap.Int("line_number", lineNumber), zap.Error(err))
+			if problems != nil {
+				*problems = append(*problems, ParseProblem{Line: lineNumber, Reason: "malformed JSON, skipped: " + err.Error(), Text: string(line)})
+			}
+			continue
+		}
+		if entry.Command != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recorded history %s: %w", r.recordFile, err)
+	}
+
+	// The hook records in the background, so quick successive commands may land out of order.
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp < entries[j].Timestamp })
+	return entries, nil
+}
+
+// AppendRecord appends entry to the log at path (DefaultRecordFile when empty),
+// creating the file and its directory with user-only permissions.
+func AppendRecord(path string, entry HistoryEntry) error {
+	return AppendRecords(path, []HistoryEntry{entry})
+}
+
+// AppendRecords appends entries to the log at path (DefaultRecordFile when empty)
+// in a single write, creating the file and its directory with user-only permissions.
+// Entries are encrypted when the log is (see SealRecords).
+func AppendRecords(path string, entries []HistoryEntry) error {
+	if path == "" {
+		defaultPath, err := DefaultRecordFile()
+		if err != nil {
+			return err
+		}
+		path = defaultPath
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	sealed, err := atrest.FileSealed(path)
+	if err != nil {
+		return fmt.Errorf("failed to open recorded history %s: %w", path, err)
+	}
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode history entry: %w", err)
+		}
+		if sealed {
+			if line, err = atrest.Seal(line); err != nil {
+				return fmt.Errorf("failed to encrypt history entry: %w", err)
+			}
+		}
+		data = append(append(data, line...), '\n')
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open recorded history %s: %w", path, err)
+	}
+	// A single O_APPEND write keeps lines from concurrent shells intact.
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write recorded history: %w", err)
+	}
+	return file.Close()
+}
+
+// SealRecords encrypts every line of the log at path (DefaultRecordFile when empty)
+// with atrest when seal is set, and decrypts them otherwise, replacing the file
+// without keeping a copy. Once the log starts with an encrypted line, AppendRecords
+// encrypts new entries too, so an empty log gets an encrypted entry without a
+// command, which readers skip. It returns the number of lines converted.
+func SealRecords(path string, seal bool) (int, error) {
+	if path == "" {
+		defaultPath, err := DefaultRecordFile()
+		if err != nil {
+			return 0, err
+		}
+		path = defaultPath
+	}
+	data, err := os.ReadFile(path)
+	missing := errors.Is(err, os.ErrNotExist)
+	if err != nil && !missing {
+		return 0, fmt.Errorf("failed to read recorded history %s: %w", path, err)
+	}
+	if missing && !seal {
+		return 0, nil
+	}
+
+	converted := 0
+	var out bytes.Buffer
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		switch {
+		case seal && !atrest.Sealed(line):
+			if line, err = atrest.Seal(line); err != nil {
+				return 0, fmt.Errorf("failed to encrypt recorded history: %w", err)
+			}
+			converted++
+		case !seal && atrest.Sealed(line):
+			if line, err = atrest.Open(line); err != nil {
+				return 0, fmt.Errorf("failed to decrypt recorded history %s: %w", path, err)
+			}
+			converted++
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	if seal && out.Len() == 0 {
+		marker, err := atrest.Seal([]byte("{}"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt recorded history: %w", err)
+		}
+		out.Write(append(marker, '\n'))
+	}
+	if converted == 0 && out.Len() == len(data) {
+		return 0, nil
+	}
+	if missing {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return 0, fmt.Errorf("failed to create history directory: %w", err)
+		}
+		return converted, writeFileAtomicMode(path, out.Bytes(), 0o600)
+	}
+	return converted, writeFileAtomic(path, out.Bytes())
+}
@@ -0,0 +1,210 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/atrest"
+)
+
+// EditFunc decides what happens to a history entry when a file is rewritten: it
+// returns the entry to write in its place, and false to remove it.
+type EditFunc func(entry HistoryEntry) (HistoryEntry, bool)
+
+// RewriteResult reports what a rewrite changed.
+type RewriteResult struct {
+	Changed int
+	Removed int
+
+	// Backup is the copy of the original file, or "" when nothing changed.
+	Backup string
+}
+
+// Rewriter is implemented by readers of local history files that can rewrite the
+// file in place. Entries the EditFunc leaves untouched are written back byte for byte.
+type Rewriter interface {
+	HistoryFile() string
+	Rewrite(edit EditFunc) (RewriteResult, error)
+}
+
+// zshRecordStart matches the first line of a Zsh extended history record.
+var zshRecordStart = regexp.MustCompile(`^: \d{10,}:\d+;`)
+
+// Rewrite implements the Rewriter interface for Zsh extended history. Edited
+// commands are written back with the original timestamp and duration.
+func (r *ZshHistoryReader) Rewrite(edit EditFunc) (RewriteResult, error) {
+	data, err := os.ReadFile(r.historyFile)
+	if err != nil {
+		return RewriteResult{}, fmt.Errorf("failed to read history file %s: %w", r.historyFile, err)
+	}
+
+	// Split the file into records: a header line plus any continuation lines.
+	var records []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		if zshRecordStart.MatchString(line) || len(records) == 0 {
+			records = append(records, line)
+		} else {
+			records[len(records)-1] += line
+		}
+	}
+
+	var result RewriteResult
+	var out strings.Builder
+	for _, record := range records {
+		entries, err := r.parseHistory(strings.NewReader(record), nil)
+		if err != nil || len(entries) != 1 {
+			out.WriteString(record)
+			continue
+		}
+		edited, keep := edit(entries[0])
+		switch {
+		case !keep:
+			result.Removed++
+		case edited.Command != entries[0].Command:
+			result.Changed++
+			header := zshRecordStart.FindString(record)
+			out.WriteString(header + strings.ReplaceAll(edited.Command, "\n", "\\\n") + "\n")
+		default:
+			out.WriteString(record)
+		}
+	}
+
+	if result.Changed == 0 && result.Removed == 0 {
+		return result, nil
+	}
+	result.Backup, err = replaceFile(r.historyFile, []byte(out.String()))
+	r.logger.Debug("Rewrote Zsh history", zap.Int("changed", result.Changed), zap.Int("removed", result.Removed))
+	return result, err
+}
+
+// Rewrite implements the Rewriter interface for the shell hook's log. Malformed
+// lines, and entries without a command, are kept as they are; edited entries stay
+// encrypted when they were.
+func (r *RecordedHistoryReader) Rewrite(edit EditFunc) (RewriteResult, error) {
+	file, err := os.Open(r.recordFile)
+	if err != nil {
+		return RewriteResult{}, fmt.Errorf("failed to open recorded history %s: %w", r.recordFile, err)
+	}
+	defer file.Close()
+
+	var result RewriteResult
+	var out strings.Builder
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			var entry HistoryEntry
+			trimmed := []byte(strings.TrimRight(line, "\n"))
+			sealed := atrest.Sealed(trimmed)
+			if sealed {
+				if trimmed, err = atrest.Open(trimmed); err != nil {
+					return RewriteResult{}, fmt.Errorf("failed to read recorded history %s: %w", r.recordFile, err)
+				}
+			}
+			if len(trimmed) == 0 || json.Unmarshal(trimmed, &entry) != nil || entry.Command == "" {
+				out.WriteString(line)
+			} else if edited, keep := edit(entry); !keep {
+				result.Removed++
+			} else if !reflect.DeepEqual(edited, entry) {
+				encoded, err := json.Marshal(edited)
+				if err != nil {
+					return RewriteResult{}, fmt.Errorf("failed to encode history entry: %w", err)
+				}
+				if sealed {
+					if encoded, err = atrest.Seal(encoded); err != nil {
+						return RewriteResult{}, fmt.Errorf("failed to encrypt history entry: %w", err)
+					}
+				}
+				result.Changed++
+				out.Write(encoded)
+				out.WriteString("\n")
+			} else {
+				out.WriteString(line)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return RewriteResult{}, fmt.Errorf("failed to read recorded history %s: %w", r.recordFile, readErr)
+		}
+	}
+
+	if result.Changed == 0 && result.Removed == 0 {
+		return result, nil
+	}
+	result.Backup, err = replaceFile(r.recordFile, []byte(out.String()))
+	r.logger.Debug("Rewrote recorded history", zap.Int("changed", result.Changed), zap.Int("removed", result.Removed))
+	return result, err
+}
+
+// replaceFile saves a timestamped copy of path next to it, then atomically replaces
+// path with data, keeping its permissions. It returns the backup's path.
+func replaceFile(path string, data []byte) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	backup := path + ".bak-" + time.Now().Format("20060102-150405")
+	if err := os.WriteFile(backup, original, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backup, err)
+	}
+	return backup, writeFileAtomicMode(path, data, info.Mode().Perm())
+}
+
+// writeFileAtomic atomically replaces path with data, keeping its permissions.
+func writeFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return writeFileAtomicMode(path, data, info.Mode().Perm())
+}
+
+// writeFileAtomicMode writes data to a temporary file next to path, with the
+// permissions perm, and renames it over path.
+func writeFileAtomicMode(path string, data []byte, perm os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(temp.Name()) // no-op once renamed
+
+	if _, err := temp.Write(data); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := temp.Chmod(perm); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		_ = temp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
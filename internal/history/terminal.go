@@ -0,0 +1,300 @@
+package history
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatTerminal is a recording of a terminal session, such as an iTerm2 session
+// log or the output of script(1), from a shell set up with the shell integration of
+// iTerm2 or WezTerm. Their escape sequences mark where prompts, commands and their
+// output start and with which exit status commands end (OSC 133), and the working
+// directory and host (OSC 7, iTerm2's OSC 1337), so the commands can be read back
+// with their output without a historai hook. Recordings have no times.
+const FormatTerminal = "terminal"
+
+const (
+	escape = 0x1b
+	bell   = 0x07
+)
+
+// Parts of a prompt cycle, as marked by OSC 133.
+const (
+	// terminalIdle is before the first mark, or after a command ended.
+	terminalIdle = iota
+
+	// terminalPrompt follows "A", the start of the prompt.
+	terminalPrompt
+
+	// terminalInput follows "B", the end of the prompt: the user types the command.
+	terminalInput
+
+	// terminalOutput follows "C": the command runs, until "D;<exit status>".
+	terminalOutput
+)
+
+// parseTerminal reads the commands of a terminal recording in FormatTerminal.
+// Commands never run, e.g. cancelled at the prompt, are left out.
+func parseTerminal(data []byte) []HistoryEntry {
+	p := &terminalParser{}
+	for i := 0; i < len(data); {
+		if data[i] == escape {
+			i += p.escapeSequence(data[i:])
+			continue
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		p.screen().write(r)
+		i += size
+	}
+	p.finish(nil)
+	return withCommands(p.entries)
+}
+
+// terminalParser follows the prompt cycles of a terminal recording.
+type terminalParser struct {
+	entries []HistoryEntry
+	state   int
+
+	// cwd and host are the last ones reported by the shell.
+	cwd  string
+	host string
+
+	command terminalScreen
+	output  terminalScreen
+
+	// commandCwd and commandHost are those of the prompt the command was typed at.
+	commandCwd  string
+	commandHost string
+}
+
+// screen returns the screen the text being read goes to: the command while it is
+// typed, its output while it runs, and a throwaway one otherwise.
+func (p *terminalParser) screen() *terminalScreen {
+	switch p.state {
+	case terminalInput:
+		return &p.command
+	case terminalOutput:
+		return &p.output
+	default:
+		return &terminalScreen{}
+	}
+}
+
+// escapeSequence handles the escape sequence at the start of data and returns its
+// length. An unterminated sequence runs to the end of data.
+func (p *terminalParser) escapeSequence(data []byte) int {
+	if len(data) < 2 {
+		return len(data)
+	}
+	switch data[1] {
+	case ']':
+		payload, length := stringSequence(data)
+		p.osc(string(payload))
+		return length
+	case 'P', '_', '^', 'X':
+		// DCS, APC, PM and SOS strings don't change the text.
+		_, length := stringSequence(data)
+		return length
+	case '[':
+		end := 2
+		for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+			end++
+		}
+		if end == len(data) {
+			return end
+		}
+		p.screen().csi(string(data[2:end]), data[end])
+		return end + 1
+	case '(', ')', '*', '+', '#', '%':
+		// Character set designations and the like take one more byte.
+		return min(3, len(data))
+	default:
+		return 2
+	}
+}
+
+// stringSequence returns the payload of the OSC, DCS or other string sequence at
+// the start of data, terminated by BEL or ST (ESC \), and its length.
+func stringSequence(data []byte) (payload []byte, length int) {
+	for i := 2; i < len(data); i++ {
+		switch {
+		case data[i] == bell:
+			return data[2:i], i + 1
+		case data[i] == escape && i+1 < len(data) && data[i+1] == '\\':
+			return data[2:i], i + 2
+		}
+	}
+	return data[2:], len(data)
+}
+
+// osc handles an Operating System Command.
+func (p *terminalParser) osc(payload string) {
+	code, args, _ := strings.Cut(payload, ";")
+	switch code {
+	case "133":
+		p.mark(args)
+	case "7":
+		// file://host/path, with the path percent-encoded.
+		if u, err := url.Parse(args); err == nil && u.Scheme == "file" && u.Path != "" {
+			p.cwd = u.Path
+			if u.Host != "" && u.Host != "localhost" {
+				p.host = u.Host
+			}
+		}
+	case "1337":
+		key, value, _ := strings.Cut(args, "=")
+		switch key {
+		case "CurrentDir":
+			p.cwd = value
+		case "RemoteHost":
+			// user@host
+			if _, host, ok := strings.Cut(value, "@"); ok {
+				p.host = host
+			}
+		}
+	}
+}
+
+// mark handles the OSC 133 mark with args, e.g. "D;1" or "A;cl=m;aid=42".
+func (p *terminalParser) mark(args string) {
+	fields := strings.Split(args, ";")
+	switch fields[0] {
+	case "A":
+		// A prompt without "D" before it follows a command whose end wasn't marked.
+		p.finish(nil)
+		p.state = terminalPrompt
+	case "B":
+		p.state = terminalInput
+		p.command = terminalScreen{}
+		p.commandCwd, p.commandHost = p.cwd, p.host
+	case "C":
+		if p.state == terminalInput {
+			p.state = terminalOutput
+			p.output = terminalScreen{}
+		}
+	case "D":
+		var exitCode *int
+		if len(fields) > 1 {
+			if code, err := strconv.Atoi(fields[1]); err == nil {
+				exitCode = &code
+			}
+		}
+		p.finish(exitCode)
+	}
+}
+
+// finish records the running command, if any, as having ended with exitCode.
+func (p *terminalParser) finish(exitCode *int) {
+	if p.state == terminalOutput {
+		p.entries = append(p.entries, HistoryEntry{
+			Command:  p.command.String(),
+			Cwd:      p.commandCwd,
+			Host:     p.commandHost,
+			ExitCode: exitCode,
+			Output:   TailBytes(p.output.String(), MaxRecordedOutput),
+		})
+	}
+	p.state = terminalIdle
+}
+
+// terminalScreen replays the text and cursor movements a shell sent, so that what
+// line editors draw and redraw (or progress bars overwrite) reads as it was shown.
+type terminalScreen struct {
+	lines    [][]rune
+	row, col int
+}
+
+// write puts r at the cursor, or applies the control character r.
+func (s *terminalScreen) write(r rune) {
+	if s.lines == nil {
+		s.lines = [][]rune{nil}
+	}
+	switch {
+	case r == '\n':
+		s.row++
+		s.col = 0
+		for len(s.lines) <= s.row {
+			s.lines = append(s.lines, nil)
+		}
+	case r == '\r':
+		s.col = 0
+	case r == '\b':
+		s.col = max(s.col-1, 0)
+	case r == '\t':
+		s.put(r)
+	case r < 0x20 || r == 0x7f:
+	default:
+		s.put(r)
+	}
+}
+
+// put writes r over the character at the cursor, and moves past it.
+func (s *terminalScreen) put(r rune) {
+	line := s.lines[s.row]
+	for len(line) < s.col {
+		line = append(line, ' ')
+	}
+	if s.col < len(line) {
+		line[s.col] = r
+	} else {
+		line = append(line, r)
+	}
+	s.lines[s.row] = line
+	s.col++
+}
+
+// csi applies the cursor movements and erasures of the Control Sequence Introducer
+// with params and final byte; colors and modes are ignored.
+func (s *terminalScreen) csi(params string, final byte) {
+	if s.lines == nil {
+		s.lines = [][]rune{nil}
+	}
+	n, err := strconv.Atoi(params)
+	if err != nil || n < 1 {
+		n = 1
+	}
+	switch final {
+	case 'A':
+		s.row = max(s.row-n, 0)
+	case 'B':
+		s.row += n
+		for len(s.lines) <= s.row {
+			s.lines = append(s.lines, nil)
+		}
+	case 'C':
+		s.col += n
+	case 'D':
+		s.col = max(s.col-n, 0)
+	case 'G':
+		s.col = n - 1
+	case 'K':
+		line := s.lines[s.row]
+		switch params {
+		case "", "0":
+			s.lines[s.row] = line[:min(s.col, len(line))]
+		case "2":
+			s.lines[s.row] = nil
+		}
+	case 'J':
+		if params == "" || params == "0" {
+			line := s.lines[s.row]
+			s.lines = s.lines[:s.row+1]
+			s.lines[s.row] = line[:min(s.col, len(line))]
+		}
+	}
+}
+
+// String returns the text on the screen, without trailing spaces.
+func (s *terminalScreen) String() string {
+	var b bytes.Buffer
+	for i, line := range s.lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.TrimRight(string(line), " \t"))
+	}
+	return strings.TrimSpace(b.String())
+}
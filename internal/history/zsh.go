@@ -21,12 +21,15 @@ type ZshHistoryReader struct {
 	historyFile string
 }
 
-// NewZshHistoryReader remains the same.
-func NewZshHistoryReader(logger *zap.Logger) (*ZshHistoryReader, error) {
-	histFilePath, err := getDefaultZshHistoryPath()
-	if err != nil {
-		logger.Error("Failed to get default Zsh history path", zap.Error(err))
-		return nil, fmt.Errorf("could not determine Zsh history file path: %w", err)
+// NewZshHistoryReader creates a reader for histFilePath, or for ~/.zsh_history when it is empty.
+func NewZshHistoryReader(logger *zap.Logger, histFilePath string) (*ZshHistoryReader, error) {
+	if histFilePath == "" {
+		defaultPath, err := getDefaultZshHistoryPath()
+		if err != nil {
+			logger.Error("Failed to get default Zsh history path", zap.Error(err))
+			return nil, fmt.Errorf("could not determine Zsh history file path: %w", err)
+		}
+		histFilePath = defaultPath
 	}
 	if _, err := os.Stat(histFilePath); os.IsNotExist(err) {
 		logger.Error("Zsh history file does not exist", zap.String("path", histFilePath))
@@ -48,6 +51,11 @@ func getDefaultZshHistoryPath() (string, error) {
 	return filepath.Join(usr.HomeDir, ".zsh_history"), nil
 }
 
+// HistoryFile returns the path of the Zsh history file being read.
+func (r *ZshHistoryReader) HistoryFile() string {
+	return r.historyFile
+}
+
 // ReadHistory opens the history file and delegates parsing and filtering.
 func (r *ZshHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
 	file, err := os.Open(r.historyFile)
@@ -60,7 +68,7 @@ func (r *ZshHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
 	}(file)
 
 	// Delegate parsing to a separate method
-	allEntries, err := r.parseHistory(file)
+	allEntries, err := r.parseHistory(file, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +78,26 @@ func (r *ZshHistoryReader) ReadHistory(limit int) ([]HistoryEntry, error) {
 	return filteredEntries, nil
 }
 
+// ParseProblems implements the ProblemReporter interface method.
+func (r *ZshHistoryReader) ParseProblems() ([]ParseProblem, error) {
+	file, err := os.Open(r.historyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file %s: %w", r.historyFile, err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	var problems []ParseProblem
+	if _, err := r.parseHistory(file, &problems); err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
 // parseHistory reads from the reader, parses entries, handles multi-line and UTF-8.
-func (r *ZshHistoryReader) parseHistory(reader io.Reader) ([]HistoryEntry, error) {
+// Lines it skips or repairs are added to problems, when not nil.
+func (r *ZshHistoryReader) parseHistory(reader io.Reader, problems *[]ParseProblem) ([]HistoryEntry, error) {
 	var allEntries []HistoryEntry
 	scanner := bufio.NewScanner(reader)
 	// Regex still needs to capture timestamp
@@ -85,6 +111,9 @@ func (r *ZshHistoryReader) parseHistory(reader io.Reader) ([]HistoryEntry, error
 		originalLineBytes := scanner.Bytes()
 		// Process line ensuring valid UTF-8
 		line := r.ensureValidUTF8(originalLineBytes)
+		if problems != nil && !utf8.Valid(originalLineBytes) {
+			*problems = append(*problems, ParseProblem{Line: lineNumber, Reason: "invalid UTF-8, replaced", Text: line})
+		}
 
 		match := re.FindStringSubmatch(line)
 		if len(match) == 3 {
@@ -112,6 +141,8 @@ func (r *ZshHistoryReader) parseHistory(reader io.Reader) ([]HistoryEntry, error
 				currentCommand.WriteString("\n")
 				currentCommand.WriteString(nextLineStr)
 			}
+		} else if problems != nil {
+			*problems = append(*problems, ParseProblem{Line: lineNumber, Reason: "not in extended history format, skipped", Text: line})
 		}
 	}
 
@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+// IsCustomized reports whether cfg requires anything beyond Go's default transport,
+// which already honors the HTTPS_PROXY/HTTP_PROXY/NO_PROXY environment variables.
+func IsCustomized(cfg config.NetworkConfig) bool {
+	return cfg.Proxy != "" || cfg.CAFile != ""
+}
+
+// New returns an HTTP client honoring the configured proxy and extra trusted CA certificates.
+func New(cfg config.NetworkConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network.proxy %q: %w", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CAFile != "" {
+		pool, err := certPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// certPool returns the system cert pool with the PEM certificates from caFile added,
+// as needed behind TLS-intercepting corporate proxies.
+func certPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network.ca_file %s: %w", caFile, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in network.ca_file %s", caFile)
+	}
+	return pool, nil
+}
+
+// HeaderTransport adds fixed headers to every request, e.g. an API key when a
+// custom HTTP client bypasses the SDK's own authentication.
+type HeaderTransport struct {
+	Base    http.RoundTripper
+	Headers map[string]string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	for key, value := range t.Headers {
+		clone.Header.Set(key, value)
+	}
+	return t.Base.RoundTrip(clone)
+}
+
+// PluginEnv returns environment variables that pass the network settings on to
+// child processes such as provider plugins.
+func PluginEnv(cfg config.NetworkConfig) []string {
+	var env []string
+	if cfg.Proxy != "" {
+		env = append(env, "HTTPS_PROXY="+cfg.Proxy, "HTTP_PROXY="+cfg.Proxy)
+	}
+	if cfg.CAFile != "" {
+		env = append(env, "SSL_CERT_FILE="+cfg.CAFile)
+	}
+	return env
+}
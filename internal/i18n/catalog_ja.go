@@ -0,0 +1,315 @@
+package i18n
+
+// ja holds the Japanese translations.
+var ja = map[string]string{
+	// Command help
+	"An AI-powered CLI tool to find/suggest commands based on shell history.":         "シェル履歴をもとにコマンドを検索・提案する AI 搭載の CLI ツールです。",
+	"Find commands in shell history using a natural language query":                   "自然言語のクエリでシェル履歴からコマンドを検索します",
+	"Suggest shell commands based on a task description using AI":                     "作業内容の説明から AI がシェルコマンドを提案します",
+	"Explain a shell command flag by flag using AI":                                   "シェルコマンドをフラグごとに AI が解説します",
+	"Suggest a corrected version of the last failed command":                          "直前に失敗したコマンドの修正案を提案します",
+	"Diagnose why the last command failed using AI":                                   "AI を使って直前のコマンドが失敗した理由を診断します",
+	"Predict the next command in your current workflow using AI":                      "AI を使って現在のワークフローの次のコマンドを予測します",
+	"Chat with an AI about your shell history":                                        "シェル履歴について AI と対話します",
+	"Ask a free-form question about your shell history using AI":                      "シェル履歴について自由に AI に質問します",
+	"Summarize what you worked on from your shell history using AI":                   "シェル履歴から作業内容を AI で要約します",
+	"Show statistics about your shell history":                                        "シェル履歴の統計を表示します",
+	"Write a markdown digest of your shell history for a period":                      "期間ごとのシェル履歴のダイジェストを markdown で書き出します",
+	"Scan your shell history for leaked secrets":                                      "シェル履歴に漏れた秘密情報がないか検査します",
+	"Remove duplicates and noise from your history file":                              "履歴ファイルから重複や不要な項目を削除します",
+	"Export your parsed shell history":                                                "解析したシェル履歴をエクスポートします",
+	"Import shell history into historai's own history log":                            "シェル履歴を historai 独自の履歴ログにインポートします",
+	"Sync your history log with an encrypted remote copy":                             "履歴ログを暗号化されたリモートのコピーと同期します",
+	"Attach a note to a command in your history":                                      "履歴のコマンドにメモを付けます",
+	"Tag commands in your history":                                                    "履歴のコマンドにタグを付けます",
+	"Add a tag to a command":                                                          "コマンドにタグを追加します",
+	"Drop the daemon's parsed history, so that it is parsed again":                    "デーモンの解析済み履歴を破棄し、再解析させます",
+	"Show the entries, hits and misses of the daemon's history cache":                 "デーモンの履歴キャッシュの件数、ヒットとミスを表示します",
+	"Inspect and clear the daemon's cache of parsed history":                          "デーモンが解析済みの履歴キャッシュを確認・消去します",
+	"Remove a tag from a command":                                                     "コマンドからタグを外します",
+	"List tags, or the commands with a tag":                                           "タグの一覧、またはタグの付いたコマンドを表示します",
+	"Save a command to your favorites":                                                "コマンドをお気に入りに保存します",
+	"List your saved commands, or print or run one":                                   "保存したコマンドの一覧を表示するか、1 つを出力または実行します",
+	"Manage parameterized command snippets":                                           "パラメーター付きのコマンドスニペットを管理します",
+	"Add or replace a snippet":                                                        "スニペットを追加または置き換えます",
+	"Remove a snippet":                                                                "スニペットを削除します",
+	"List your snippets":                                                              "スニペットの一覧を表示します",
+	"Fill in a snippet's placeholders and print or run it":                            "スニペットのプレースホルダーを埋めて出力または実行します",
+	"Turn commands from your history into a shell script using AI":                    "AI を使って履歴のコマンドをシェルスクリプトにします",
+	"Generate Makefile or justfile targets from workflows you repeat":                 "繰り返し実行するワークフローから Makefile または justfile のターゲットを生成します",
+	"Suggest shell aliases for long commands you type often":                          "よく入力する長いコマンドのシェルエイリアスを提案します",
+	"Recommend modern alternatives to tools you use often":                            "よく使うツールのモダンな代替ツールを提案します",
+	"Correct the previous command and run it with one key":                            "直前のコマンドを修正し、キー1つで実行します",
+	"Quiz yourself on the flags of commands from your history":                        "履歴のコマンドのフラグについてクイズに挑戦します",
+	"Run the same request against several models and compare the answers":             "同じリクエストを複数のモデルに送り、回答を比較します",
+	"Score models and prompt instructions on a dataset of queries with known answers": "正解が決まったクエリのデータセットでモデルとプロンプト指示を評価します",
+	"Create the key the synced history is encrypted with":                             "同期した履歴を暗号化するキーを作成します",
+	"Print the shell hook that records commands with their exit status":               "コマンドを終了コードとともに記録するシェルフックを出力します",
+	"Run a background daemon that keeps history and the LLM client warm":              "履歴と LLM クライアントを待機させておくバックグラウンドデーモンを起動します",
+	"Serve find/suggest/history as a local JSON HTTP API":                             "find/suggest/history をローカルの JSON HTTP API として提供します",
+	"Run a Model Context Protocol server over stdio":                                  "stdio 上で Model Context Protocol サーバーを起動します",
+	"Serve the versioned historai.v1 gRPC API for editor/plugin integrations":         "エディターやプラグイン連携向けに historai.v1 gRPC API を提供します",
+	"Read and write historai configuration values":                                    "historai の設定値を読み書きします",
+	"Print the effective value of a configuration key":                                "設定キーの実際の値を表示します",
+	"Validate and write a configuration value to the config file":                     "設定値を検証して設定ファイルに書き込みます",
+	"Remove a configuration value from the config file, restoring its default":        "設定ファイルから値を削除し、既定値に戻します",
+	"List every configuration key and its effective value":                            "すべての設定キーと実際の値を一覧表示します",
+	"Print the location of the config file":                                           "設定ファイルの場所を表示します",
+	"Write the built-in find and suggest prompt templates for customization":          "カスタマイズ用に組み込みの find/suggest プロンプトテンプレートを書き出します",
+	"Manage API keys stored in the OS keyring":                                        "OS のキーリングに保存された API キーを管理します",
+	"Store an API key in the OS keyring":                                              "API キーを OS のキーリングに保存します",
+	"Remove the stored API key from the OS keyring":                                   "OS のキーリングから API キーを削除します",
+	"Show where the API key is loaded from":                                           "API キーの読み込み元を表示します",
+	"Check your setup and explain how to fix what is wrong":                           "セットアップを点検し、問題の直し方を説明します",
+	"Print the version, build and active provider of historai":                        "historai のバージョン、ビルド情報、使用中のプロバイダーを表示します",
+	"Collect sanitized diagnostics into a tarball to attach to bug reports":           "バグ報告に添付する、機密情報を除いた診断情報を tar ファイルにまとめます",
+	"Help about any command":                                                          "コマンドのヘルプを表示します",
+	"Generate the autocompletion script for the specified shell":                      "指定したシェルの補完スクリプトを生成します",
+
+	// Output
+	"--- Found Commands ---":                                          "--- 見つかったコマンド ---",
+	"--- Found Commands (keyword search, not AI) ---":                 "--- 見つかったコマンド (キーワード検索、AI ではありません) ---",
+	"--- Prompt for %s (%d characters) ---":                           "--- %s へのプロンプト (%d 文字) ---",
+	"--- End of prompt ---":                                           "--- プロンプト終わり ---",
+	"--- Suggested Commands ---":                                      "--- 提案されたコマンド ---",
+	"--- Matching Commands from History (keyword search, not AI) ---": "--- 履歴から一致したコマンド (キーワード検索、AI ではありません) ---",
+	"(No relevant commands found)":                                    "(該当するコマンドが見つかりませんでした)",
+	"(AI could not suggest a command for this task)":                  "(AI がこの作業に合うコマンドを提案できませんでした)",
+	"--- Explanation: %s ---":                                         "--- 解説: %s ---",
+	"(AI could not explain this command)":                             "(AI がこのコマンドを解説できませんでした)",
+	"--- Fix for: %s ---":                                             "--- 修正案: %s ---",
+	"--- Diagnosis: %s ---":                                           "--- 診断: %s ---",
+	"--- Likely Next Commands ---":                                    "--- 次に実行しそうなコマンド ---",
+	"--- Answer ---":                                                  "--- 回答 ---",
+	"--- Summary %s ---":                                              "--- 要約 (%s) ---",
+	"%d commands, %d unique":                                          "コマンド %d 件、重複なし %d 件",
+	", from %s to %s":                                                 "、%s から %s まで",
+	"Top programs":                                                    "よく使うプログラム",
+	"Top commands":                                                    "よく使うコマンド",
+	"Top directories":                                                 "よく使うディレクトリ",
+	"Busiest hours":                                                   "最も忙しい時間帯",
+	"Most failing programs":                                           "最も失敗が多いプログラム",
+	"Longest-running commands":                                        "実行時間が最も長いコマンド",
+	"Frequent workflows":                                              "よく使うワークフロー",
+	"No history entries found.\n":                                     "履歴が見つかりませんでした。\n",
+	"Decrypted %s.\n":                                                 "%s を復号しました。\n",
+	"Encrypted %s.\n":                                                 "%s を暗号化しました。\n",
+	"Decrypted %d entries of %s.\n":                                   "%[2]s の %[1]d 件の項目を復号しました。\n",
+	"Encrypted %d entries of %s.\n":                                   "%[2]s の %[1]d 件の項目を暗号化しました。\n",
+	"Encryption key: available\n":                                     "暗号化キー: 利用可能\n",
+	"Use the snippets and runbooks your team shares in a git repository":                       "チームが git リポジトリで共有するスニペットとランブックを使う",
+	"Clone or update the team's snippets repository":                                           "チームのスニペットリポジトリをクローンまたは更新",
+	"List the team's snippets":                                                                 "チームのスニペットを一覧表示",
+	"no team repository configured; set team.repo to its git URL":                              "チームリポジトリが設定されていません。team.repo に git URL を設定してください",
+	"the team repository hasn't been cloned yet; run 'historai team pull'":                     "チームリポジトリはまだクローンされていません。'historai team pull' を実行してください",
+	"%d team snippets in %s, last changed %s.\n":                                               "%[2]s にチームのスニペットが %[1]d 件、最終更新 %[3]s。\n",
+	"%d team snippets in %s.\n":                                                                "%[2]s にチームのスニペットが %[1]d 件。\n",
+	"No snippets found in %s.\n":                                                               "%s にスニペットが見つかりません。\n",
+	"From your team's snippets: %s\n":                                                          "チームのスニペットから: %s\n",
+	"From your team's snippets":                                                                "チームのスニペットから",
+	"Encryption key: not available (%v)\n":                                                     "暗号化キー: 利用できません (%v)\n",
+	"encrypted":                                                                                "暗号化済み",
+	"missing":                                                                                  "ありません",
+	"not encrypted":                                                                            "暗号化されていません",
+	"Encryption key removed from the OS keyring.\n":                                            "OS のキーリングから暗号化キーを削除しました。\n",
+	"The files no longer need the key in $%s.\n":                                               "ファイルには $%s の鍵はもう必要ありません。\n",
+	"Generated an encryption key and stored it in the OS keyring.\n":                           "暗号化キーを生成し、OS のキーリングに保存しました。\n",
+	"Show which files are encrypted and whether the key is available":                          "どのファイルが暗号化されているかと鍵が利用可能かを表示します",
+	"Decrypt the history log and annotations and delete the key":                               "履歴ログと注釈を復号し、鍵を削除します",
+	"Generate a key if needed and encrypt the history log and annotations":                     "必要に応じて鍵を生成し、履歴ログと注釈を暗号化します",
+	"Encrypt historai's history log and annotations at rest":                                   "historai の履歴ログと注釈を暗号化して保存します",
+	"%s still holds %d matching entries; run again with --shell-history to forget them too.\n": "%s には一致する項目がまだ %d 件あります。--shell-history を付けて再実行すると一緒に削除されます。\n",
+	"Cleared the daemon's cached history.\n":                                                   "デーモンの履歴キャッシュをクリアしました。\n",
+	"Forgot the notes, tags and saved status of %d commands.\n":                                "%d 件のコマンドのメモ・タグ・保存状態を削除しました。\n",
+	"Forgot %d entries from %s. The original file was saved to %s; delete it once you are sure, as it still holds them.\n": "%[2]s から %[1]d 件の項目を削除しました。元のファイルは %[3]s に保存されています。削除した項目がまだ含まれているので、確認後に削除してください。\n",
+	"Forgot %d entries from %s.\n":                                                     "%[2]s から %[1]d 件の項目を削除しました。\n",
+	"--months must be at least 1":                                                      "--months は 1 以上である必要があります",
+	"--mirror only supports Zsh history, not %q":                                       "--mirror は Zsh の履歴のみに対応しています (%q には対応していません)",
+	"Nothing older than %s to archive.\n":                                              "%s より古いアーカイブ対象の項目はありません。\n",
+	"Would archive %d entries older than %s into %s.\n":                                "%[2]s より古い %[1]d 件の項目を %[3]s にアーカイブします。\n",
+	"Archived %d entries older than %s into %d segments in %s.\n":                      "%[2]s より古い %[1]d 件の項目を %[4]s の %[3]d 個のセグメントにアーカイブしました。\n",
+	"Nothing archived yet in %s.\n":                                                    "%s にはまだアーカイブされた項目がありません。\n",
+	"%d entries, %d KB":                                                                "%d 件, %d KB",
+	"keyword cannot be empty":                                                          "キーワードを空にすることはできません",
+	"No archived commands contain %q.\n":                                               "%q を含むアーカイブ済みのコマンドはありません。\n",
+	"Encrypted %d archived entries in %s.\n":                                           "%[2]s のアーカイブ済み項目 %[1]d 件を暗号化しました。\n",
+	"Decrypted %d archived entries in %s.\n":                                           "%[2]s のアーカイブ済み項目 %[1]d 件を復号しました。\n",
+	"Forget %d entries matching %q?":                                                   "%[2]q に一致する %[1]d 件の項目を削除しますか？",
+	"Would forget %d entries.\n":                                                       "%d 件の項目を削除します（予定）。\n",
+	"Nothing matches %q.\n":                                                            "%q に一致するものはありません。\n",
+	"invalid pattern %q: %v":                                                           "無効なパターン %q: %v",
+	"pattern cannot be empty":                                                          "パターンは空にできません",
+	"--no-backup only applies with --shell-history":                                    "--no-backup は --shell-history と一緒にのみ使用できます",
+	"Delete commands from historai's history, annotations and caches":                  "historai の履歴・注釈・キャッシュからコマンドを削除します",
+	"the daemon returned no cache statistics":                                          "デーモンがキャッシュ統計を返しませんでした",
+	"no daemon is running, and only the daemon caches history (see 'historai daemon')": "デーモンが起動していません。履歴をキャッシュするのはデーモンだけです ('historai daemon' を参照)",
+	"Cleared %d cached history entries.\n":                                             "キャッシュされた履歴 %d 件を消去しました。\n",
+	"History cache: %d entries, %d hits, %d misses (%.0f%% hit rate)\n":                "履歴キャッシュ: %d 件、ヒット %d 回、ミス %d 回 (ヒット率 %.0f%%)\n",
+	"total":       "合計",
+	"Timings: %s": "所要時間: %s",
+	"Stage columns are medians, over the runs that had the stage.": "各段階の列は、その段階があった実行の中央値です。",
+	"By model":                                  "モデル別",
+	"By command":                                "コマンド別",
+	"%d invocations, from %s to %s":             "%d 回の実行、%s から %s まで",
+	"No timed invocations found.\n":             "時間が記録された実行はありません。\n",
+	"Report written to %s\n":                    "レポートを %s に書き出しました\n",
+	"No secrets found in %d history entries.\n": "%d 件の履歴に秘密情報は見つかりませんでした。\n",
+	"Found secrets in %d history entries. Run 'historai audit --scrub' to remove them.\n": "%d 件の履歴に秘密情報が見つかりました。削除するには 'historai audit --scrub' を実行してください。\n",
+	"Redact %d entries in %s?": "%[2]s の %[1]d 件の項目を伏せ字にしますか?",
+	"Redacted %d entries. The original file was saved to %s; delete it once you have checked the result.\n": "%d 件を伏せ字にしました。元のファイルは %s に保存されています。結果を確認したら削除してください。\n",
+	"history source %q can't be rewritten":                                   "履歴ソース %q は書き換えられません",
+	"stdin is not a terminal; pass --yes to confirm":                         "標準入力が端末ではありません。確認するには --yes を指定してください",
+	"Nothing to prune in %d history entries.\n":                              "%d 件の履歴に整理する項目はありません。\n",
+	"Would remove %d of %d entries (%s).\n":                                  "%[2]d 件中 %[1]d 件を削除します (%[3]s)。\n",
+	"Remove %d of %d entries (%s) from %s?":                                  "%[4]s から %[2]d 件中 %[1]d 件を削除しますか (%[3]s)?",
+	"Removed %d entries. The original file was saved to %s.\n":               "%d 件を削除しました。元のファイルは %s に保存されています。\n",
+	"Exported %d entries to %s\n":                                            "%d 件を %s にエクスポートしました\n",
+	"Imported %d new entries (%d duplicates skipped) into %s\n":              "新しい項目 %d 件を %[3]s にインポートしました (重複 %[2]d 件をスキップ)\n",
+	"Pulled %d new entries from %s\n":                                        "%[2]s から新しい項目 %[1]d 件を取り込みました\n",
+	"Pushed %d new entries to %s\n":                                          "%[2]s に新しい項目 %[1]d 件をアップロードしました\n",
+	"Removed %d forgotten entries from %s\n":                                 "%[2]s から忘れた項目 %[1]d 件を削除しました\n",
+	"Nothing new to push to %s\n":                                            "%s にアップロードする新しい項目はありません\n",
+	"--delete takes no note":                                                 "--delete にはメモを指定できません",
+	"No note on %s\n":                                                        "%s にはメモがありません\n",
+	"note cannot be empty (use --delete to remove a note)":                   "メモは空にできません (メモを消すには --delete を使ってください)",
+	"Removed the note from %s\n":                                             "%s のメモを削除しました\n",
+	"Noted %s\n":                                                             "%s にメモを付けました\n",
+	"entry cannot be empty":                                                  "項目は空にできません",
+	"entry number must be 1 or more":                                         "項目番号は 1 以上にしてください",
+	"history has fewer than %s commands":                                     "履歴のコマンドが %s 件未満です",
+	"No annotated commands yet.\n":                                           "メモを付けたコマンドはまだありません。\n",
+	"No commands are tagged %s.\n":                                           "%s タグの付いたコマンドはありません。\n",
+	"%s is already tagged %s\n":                                              "%s にはすでに %s タグが付いています\n",
+	"%s is not tagged %s\n":                                                  "%s に %s タグは付いていません\n",
+	"Tagged %s with %s\n":                                                    "%s に %s タグを付けました\n",
+	"Removed tag %s from %s\n":                                               "%[2]s から %[1]s タグを外しました\n",
+	"No tags yet. Add one with 'historai tag add <tag> <entry>'.\n":          "タグはまだありません。'historai tag add <tag> <entry>' で追加してください。\n",
+	"%s is not saved\n":                                                      "%s は保存されていません\n",
+	"%s is already saved\n":                                                  "%s はすでに保存されています\n",
+	"Removed %s from your saved commands\n":                                  "保存したコマンドから %s を削除しました\n",
+	"Saved %s\n":                                                             "%s を保存しました\n",
+	"--run needs the number of a saved command":                              "--run には保存したコマンドの番号が必要です",
+	"not running %s: the organization policy %s denies commands matching %s": "%[1]s は実行しません: 組織のポリシー %[2]s が %[3]s に一致するコマンドを禁止しています",
+	"no saved command number %s (see 'historai saved')":                      "保存したコマンド %s 番はありません（'historai saved' を参照してください）",
+	"Run %s?": "%s を実行しますか?",
+	"No saved commands yet. Save one with 'historai save <entry>'.\n":          "保存したコマンドはまだありません。'historai save <entry>' で保存してください。\n",
+	"Note: %s has no {placeholders}.\n":                                        "注意: %s には {プレースホルダー} がありません。\n",
+	"Replaced snippet %s\n":                                                    "スニペット %s を置き換えました\n",
+	"Added snippet %s\n":                                                       "スニペット %s を追加しました\n",
+	"no snippet named %s (see 'historai snippet list')":                        "%s という名前のスニペットはありません（'historai snippet list' を参照してください）",
+	"Removed snippet %s\n":                                                     "スニペット %s を削除しました\n",
+	"No snippets yet. Add one with 'historai snippet add <name> <command>'.\n": "スニペットはまだありません。'historai snippet add <name> <command>' で追加してください。\n",
+	"no value for {%[1]s}; pass --set %[1]s=<value>":                           "{%[1]s} の値がありません。--set %[1]s=<値> を指定してください",
+	"--set expects name=value, got %q":                                         "--set には name=value の形式が必要ですが、%q が指定されました",
+	"the snippet has no placeholder {%s}":                                      "スニペットにプレースホルダー {%s} はありません",
+	"no commands selected":                                                     "コマンドが選択されていません",
+	"stdin is not a terminal; select commands with --since or --last":          "標準入力が端末ではありません。--since または --last でコマンドを選択してください",
+	"Commands to include (e.g. 1-5,8; Enter for all): ":                        "含めるコマンド (例: 1-5,8、すべてなら Enter): ",
+	"invalid selection %q (use numbers from 1 to %d, e.g. 1-5,8)":              "無効な選択 %q です (1 から %d までの番号を使ってください。例: 1-5,8)",
+	"Script written to %s\n":                                                   "スクリプトを %s に書き込みました\n",
+	"--- Script from %d commands ---":                                          "--- %d 件のコマンドから作成したスクリプト ---",
+	"No repeated command sequences found.\n":                                   "繰り返されたコマンドの並びは見つかりませんでした。\n",
+	"--- %s targets ---":                                                       "--- %s のターゲット ---",
+	"Append these targets to %s?":                                              "これらのターゲットを %s に追加しますか?",
+	"invalid --format %q (use make or just)":                                   "無効な --format %q です (make または just を使ってください)",
+	"Wrote the %s docs to %s\n":                                                "%s ドキュメントを %s に書き出しました\n",
+	"invalid --format %q (use man or markdown)":                                "無効な --format %q です (man または markdown を使ってください)",
+	"Repeated workflows:":                                                      "繰り返されたワークフロー:",
+	"Appended the targets to %s\n":                                             "ターゲットを %s に追加しました\n",
+	"No long commands typed often enough to need an alias.\n":                  "エイリアスが必要なほど頻繁に入力された長いコマンドはありません。\n",
+	"unsupported shell %q; pass the rc file with --rc":                         "サポートされていないシェルです: %q。--rc で rc ファイルを指定してください",
+	"Append these definitions to %s?":                                          "これらの定義を %s に追加しますか?",
+	"Appended the definitions to %s; open a new shell to use them.\n":          "定義を %s に追加しました。新しいシェルを開いてご利用ください。\n",
+	"No recommendations: you already use modern tools where it matters.\n":     "おすすめはありません。必要なところではすでにモダンなツールをお使いです。\n",
+	"%s instead of %s":                                                         "%[2]s の代わりに %[1]s",
+	"(%d times)":                                                               "(%d 回)",
+	"Install:":                                                                 "インストール:",
+	"much faster recursive search that skips .gitignore'd and binary files":    ".gitignore 対象とバイナリファイルをスキップする、はるかに高速な再帰検索です",
+	"simpler syntax, faster, and skips .gitignore'd files":                     "構文がよりシンプルで高速、.gitignore 対象のファイルをスキップします",
+	"jumps to frequent directories from a few letters of their name":           "名前の数文字だけでよく使うディレクトリに移動します",
+	"syntax highlighting, line numbers and git changes, and pages long files":  "シンタックスハイライト、行番号、git の変更を表示し、長いファイルはページ送りで表示します",
+	"short, example-first summaries of commands":                               "例を中心とした簡潔なコマンドの要約を表示します",
+	"Modern alternatives":                                                      "モダンな代替ツール",
+	"Run 'historai tips' for install commands and examples.":                   "インストールコマンドと例は 'historai tips' を実行してご確認ください。",
+	"No correction found for: %s\n":                                            "修正案が見つかりませんでした: %s\n",
+	"[Enter] run, any other key to cancel":                                     "[Enter] で実行、その他のキーでキャンセルします",
+	"missing sudo":                                                             "sudo の付け忘れ",
+	"typo in the program name":                                                 "プログラム名のタイプミス",
+	"typo in the subcommand":                                                   "サブコマンドのタイプミス",
+	"suggested by the error output":                                            "エラー出力が提案したコマンド",
+	"missing -r for a directory":                                               "ディレクトリに対する -r の付け忘れ",
+	"missing -p for parent directories":                                        "親ディレクトリ用の -p の付け忘れ",
+	"stdin is not a terminal; teach is interactive":                            "stdin が端末ではありません。teach は対話形式でのみ使用できます",
+	"No commands with flags to ask about in your history.\n":                   "履歴にクイズにできるフラグ付きのコマンドがありません。\n",
+	"Question %d/%d":                                                           "問題 %d/%d",
+	"Answer:":                                                                  "答え:",
+	"Correct!":                                                                 "正解です!",
+	"Not quite.":                                                               "惜しいです。",
+	"Score: %d/%d":                                                             "スコア: %d/%d",
+	"(AI could not grade this answer)":                                         "(AI がこの回答を採点できませんでした)",
+	"invalid request %q (use find or suggest)":                                 "無効なリクエスト %q (find または suggest を使用してください)",
+	"compare needs at least two models: pass --with for each, or set compare.models": "compare には 2 つ以上のモデルが必要です。モデルごとに --with を指定するか、compare.models を設定してください",
+	"%d+%d tokens":  "%d+%d トークン",
+	"Error: %s":     "エラー: %s",
+	"(no commands)": "(コマンドなし)",
+	"Created sync key %s (public key %s).\nCopy it to the same path, or to sync.identity, on your other machines and keep a backup: without it the synced history can't be decrypted.\n": "同期キー %s を作成しました (公開鍵 %s)。\n他のマシンの同じパスか sync.identity にコピーし、バックアップしてください。キーがないと同期した履歴は復号できません。\n",
+	"Set history.source to %q in your config file to use them.\n":                              "インポートした項目を使うには、設定ファイルで history.source を %q に設定してください。\n",
+	"Chatting about your shell history. Type /reset to start over, /exit or Ctrl-D to quit.\n": "シェル履歴について対話します。やり直すには /reset、終了するには /exit または Ctrl-D を入力してください。\n",
+	"Conversation cleared.\n":                 "会話をリセットしました。\n",
+	"The command looks correct already.\n":    "コマンドはすでに正しいようです。\n",
+	"(AI could not fix this command)":         "(AI がこのコマンドを修正できませんでした)",
+	"(AI could not diagnose this failure)":    "(AI がこの失敗を診断できませんでした)",
+	"(AI could not predict the next command)": "(AI が次のコマンドを予測できませんでした)",
+	"(AI response was empty)":                 "(AI の応答が空でした)",
+	"(AI could not summarize this activity)":  "(AI が作業内容を要約できませんでした)",
+	"(AI could not write a script)":           "(AI がスクリプトを作成できませんでした)",
+	"(AI could not write build targets)":      "(AI がビルドターゲットを作成できませんでした)",
+	"(AI could not answer this question)":     "(AI がこの質問に回答できませんでした)",
+	"contacting %s… %.1fs":                    "%s に問い合わせ中… %.1f秒",
+	"Interrupted.":                            "中断しました。",
+
+	// Status messages
+	"API key stored in the OS keyring.\n":                           "API キーを OS のキーリングに保存しました。\n",
+	"API key removed from the OS keyring.\n":                        "OS のキーリングから API キーを削除しました。\n",
+	"API key loaded from %s\n":                                      "API キーの読み込み元: %s\n",
+	"No API key configured. Set %s or run 'historai auth login'.\n": "API キーが設定されていません。%s を設定するか 'historai auth login' を実行してください。\n",
+	"Google AI Studio API key: ":                                    "Google AI Studio の API キー: ",
+	"Set %s in %s\n":                                                "%[2]s に %[1]s を設定しました\n",
+	"Unset %s in %s\n":                                              "%[2]s から %[1]s を削除しました\n",
+	"%s already exists; left unchanged\n":                           "%s は既に存在するため変更しませんでした\n",
+	"Wrote %s\n":                                                    "%s を書き出しました\n",
+	"No problems found, with %d warnings.\n":                        "問題は見つかりませんでした。警告が %d 件あります。\n",
+	"No problems found.\n":                                          "問題は見つかりませんでした。\n",
+	"Review it before attaching it to an issue.\n":                  "issue に添付する前に内容を確認してください。\n",
+	"unavailable":                                                   "利用不可",
+	"network error":                                                 "ネットワークエラー",
+	"the provider answered with HTTP %d":                            "プロバイダーが HTTP %d を返しました",
+	"the request timed out":                                         "リクエストがタイムアウトしました",
+	"no API key is configured":                                      "API キーが設定されていません",
+	"the organization policy doesn't allow it":                      "組織のポリシーで許可されていません",
+	"The AI provider is unavailable (%s).\nShowing keyword matches from your history instead; these are not AI results.\n": "AI プロバイダーを利用できません (%s)。\n代わりに履歴からキーワードが一致したコマンドを表示します。AI の結果ではありません。\n",
+
+	// Errors
+	"Error: %v\n":                      "エラー: %v\n",
+	"API key cannot be empty":          "API キーを空にすることはできません",
+	"command cannot be empty":          "コマンドを空にすることはできません",
+	"chat only supports text output":   "chat はテキスト出力のみ対応しています",
+	"query cannot be empty":            "クエリを空にすることはできません",
+	"task description cannot be empty": "作業内容の説明を空にすることはできません",
+	"question cannot be empty":         "質問を空にすることはできません",
+	"%d of %d checks failed":           "%[2]d 件中 %[1]d 件の点検に失敗しました",
+	"--batch always writes JSON lines; drop --raw, --first and --output":   "--batch は常に JSON Lines を出力します。--raw、--first、--output を外してください",
+	"--dry-run cannot be combined with --batch":                            "--dry-run は --batch と併用できません",
+	"--concurrency must be at least 1":                                     "--concurrency は 1 以上である必要があります",
+	"--batch reads the queries from a file; don't give one as an argument": "--batch はファイルからクエリを読み込みます。引数でクエリを指定しないでください",
+	"no queries in %s":                                                     "%s にクエリがありません",
+	"%d of %d queries failed":                                              "%[2]d 件中 %[1]d 件のクエリが失敗しました",
+	"--with-ls cannot be combined with --history":                          "--with-ls は --history と併用できません",
+	"--no-history-context cannot be combined with --history":               "--no-history-context は --history と併用できません",
+	"--tag cannot be combined with --history, whose commands have no tags": "--tag は --history と併用できません。そのコマンドにはタグがありません",
+	"--history and --batch can't both read stdin":                          "--history と --batch の両方で標準入力を読み込むことはできません",
+	"no commands in %s":                                                    "%s にコマンドがありません",
+	"⚠ High risk":                                                          "⚠ 高リスク",
+	"Written by the AI":                                                    "AI が作成",
+	"From your history":                                                    "履歴から",
+}
@@ -0,0 +1,315 @@
+package i18n
+
+// ko holds the Korean translations.
+var ko = map[string]string{
+	// Command help
+	"An AI-powered CLI tool to find/suggest commands based on shell history.":         "셸 히스토리를 바탕으로 명령어를 찾고 추천하는 AI 기반 CLI 도구입니다.",
+	"Find commands in shell history using a natural language query":                   "자연어 질의로 셸 히스토리에서 명령어를 찾습니다",
+	"Suggest shell commands based on a task description using AI":                     "작업 설명을 바탕으로 AI가 셸 명령어를 추천합니다",
+	"Explain a shell command flag by flag using AI":                                   "명령어를 플래그 단위로 AI가 설명합니다",
+	"Suggest a corrected version of the last failed command":                          "마지막으로 실패한 명령어의 수정안을 제안합니다",
+	"Diagnose why the last command failed using AI":                                   "AI로 마지막 명령어가 실패한 이유를 진단합니다",
+	"Predict the next command in your current workflow using AI":                      "현재 작업 흐름에서 다음 명령어를 AI로 예측합니다",
+	"Chat with an AI about your shell history":                                        "셸 히스토리에 대해 AI와 대화합니다",
+	"Ask a free-form question about your shell history using AI":                      "셸 히스토리에 대한 자유로운 질문을 AI에게 합니다",
+	"Summarize what you worked on from your shell history using AI":                   "셸 히스토리를 바탕으로 작업한 내용을 AI로 요약합니다",
+	"Show statistics about your shell history":                                        "셸 히스토리 통계를 보여줍니다",
+	"Write a markdown digest of your shell history for a period":                      "기간별 셸 히스토리 요약을 마크다운 파일로 작성합니다",
+	"Scan your shell history for leaked secrets":                                      "셸 히스토리에서 유출된 비밀 정보를 검사합니다",
+	"Remove duplicates and noise from your history file":                              "히스토리 파일에서 중복과 불필요한 항목을 제거합니다",
+	"Export your parsed shell history":                                                "파싱한 셸 히스토리를 내보냅니다",
+	"Import shell history into historai's own history log":                            "셸 히스토리를 historai 자체 기록 로그로 가져옵니다",
+	"Sync your history log with an encrypted remote copy":                             "기록 로그를 암호화된 원격 사본과 동기화합니다",
+	"Attach a note to a command in your history":                                      "기록의 명령어에 메모를 붙입니다",
+	"Tag commands in your history":                                                    "기록의 명령어에 태그를 붙입니다",
+	"Add a tag to a command":                                                          "명령어에 태그를 추가합니다",
+	"Drop the daemon's parsed history, so that it is parsed again":                    "데몬이 파싱해 둔 기록을 버려 다시 파싱하게 합니다",
+	"Show the entries, hits and misses of the daemon's history cache":                 "데몬 기록 캐시의 항목 수, 적중과 실패를 보여줍니다",
+	"Inspect and clear the daemon's cache of parsed history":                          "데몬이 파싱해 둔 기록 캐시를 확인하고 비웁니다",
+	"Remove a tag from a command":                                                     "명령어에서 태그를 제거합니다",
+	"List tags, or the commands with a tag":                                           "태그 목록이나 태그가 붙은 명령어를 보여 줍니다",
+	"Save a command to your favorites":                                                "명령어를 즐겨찾기에 저장합니다",
+	"List your saved commands, or print or run one":                                   "저장된 명령어 목록을 보여 주거나 하나를 출력 또는 실행합니다",
+	"Manage parameterized command snippets":                                           "매개변수가 있는 명령어 스니펫을 관리합니다",
+	"Add or replace a snippet":                                                        "스니펫을 추가하거나 교체합니다",
+	"Remove a snippet":                                                                "스니펫을 제거합니다",
+	"List your snippets":                                                              "스니펫 목록을 보여 줍니다",
+	"Fill in a snippet's placeholders and print or run it":                            "스니펫의 자리 표시자를 채워 출력하거나 실행합니다",
+	"Turn commands from your history into a shell script using AI":                    "AI를 사용해 기록의 명령어를 셸 스크립트로 만듭니다",
+	"Generate Makefile or justfile targets from workflows you repeat":                 "반복하는 작업 흐름으로 Makefile 또는 justfile 타깃을 생성합니다",
+	"Suggest shell aliases for long commands you type often":                          "자주 입력하는 긴 명령어에 대한 셸 별칭을 제안합니다",
+	"Recommend modern alternatives to tools you use often":                            "자주 사용하는 도구의 최신 대안을 추천합니다",
+	"Correct the previous command and run it with one key":                            "직전 명령어를 교정하고 키 하나로 실행합니다",
+	"Quiz yourself on the flags of commands from your history":                        "히스토리의 명령어 플래그로 퀴즈를 풉니다",
+	"Run the same request against several models and compare the answers":             "같은 요청을 여러 모델에 보내 답변을 비교합니다",
+	"Score models and prompt instructions on a dataset of queries with known answers": "정답이 정해진 질의 데이터셋으로 모델과 프롬프트 지침을 평가합니다",
+	"Create the key the synced history is encrypted with":                             "동기화된 기록을 암호화할 키를 만듭니다",
+	"Print the shell hook that records commands with their exit status":               "종료 코드와 함께 명령어를 기록하는 셸 훅을 출력합니다",
+	"Run a background daemon that keeps history and the LLM client warm":              "히스토리와 LLM 클라이언트를 미리 준비해 두는 백그라운드 데몬을 실행합니다",
+	"Serve find/suggest/history as a local JSON HTTP API":                             "find/suggest/history를 로컬 JSON HTTP API로 제공합니다",
+	"Run a Model Context Protocol server over stdio":                                  "stdio로 Model Context Protocol 서버를 실행합니다",
+	"Serve the versioned historai.v1 gRPC API for editor/plugin integrations":         "에디터/플러그인 연동을 위한 historai.v1 gRPC API를 제공합니다",
+	"Read and write historai configuration values":                                    "historai 설정 값을 읽고 씁니다",
+	"Print the effective value of a configuration key":                                "설정 키의 실제 적용 값을 출력합니다",
+	"Validate and write a configuration value to the config file":                     "설정 값을 검증한 뒤 설정 파일에 기록합니다",
+	"Remove a configuration value from the config file, restoring its default":        "설정 파일에서 값을 제거해 기본값으로 되돌립니다",
+	"List every configuration key and its effective value":                            "모든 설정 키와 실제 적용 값을 나열합니다",
+	"Print the location of the config file":                                           "설정 파일의 위치를 출력합니다",
+	"Write the built-in find and suggest prompt templates for customization":          "수정할 수 있도록 기본 find/suggest 프롬프트 템플릿을 파일로 씁니다",
+	"Manage API keys stored in the OS keyring":                                        "OS 키링에 저장된 API 키를 관리합니다",
+	"Store an API key in the OS keyring":                                              "API 키를 OS 키링에 저장합니다",
+	"Remove the stored API key from the OS keyring":                                   "OS 키링에 저장된 API 키를 삭제합니다",
+	"Show where the API key is loaded from":                                           "API 키를 어디에서 불러오는지 보여 줍니다",
+	"Check your setup and explain how to fix what is wrong":                           "설정을 점검하고 문제를 해결하는 방법을 알려 줍니다",
+	"Print the version, build and active provider of historai":                        "historai의 버전, 빌드 정보와 사용 중인 제공자를 출력합니다",
+	"Collect sanitized diagnostics into a tarball to attach to bug reports":           "버그 보고서에 첨부할 정리된 진단 정보를 tar 파일로 모읍니다",
+	"Help about any command":                                                          "도움말을 보여 줍니다",
+	"Generate the autocompletion script for the specified shell":                      "지정한 셸의 자동 완성 스크립트를 생성합니다",
+
+	// Output
+	"--- Found Commands ---":                                          "--- 찾은 명령어 ---",
+	"--- Found Commands (keyword search, not AI) ---":                 "--- 찾은 명령어 (키워드 검색, AI 아님) ---",
+	"--- Prompt for %s (%d characters) ---":                           "--- %s에 보낼 프롬프트 (%d자) ---",
+	"--- End of prompt ---":                                           "--- 프롬프트 끝 ---",
+	"--- Suggested Commands ---":                                      "--- 추천 명령어 ---",
+	"--- Matching Commands from History (keyword search, not AI) ---": "--- 기록에서 일치하는 명령어 (키워드 검색, AI 아님) ---",
+	"(No relevant commands found)":                                    "(관련된 명령어를 찾지 못했습니다)",
+	"(AI could not suggest a command for this task)":                  "(AI가 이 작업에 맞는 명령어를 추천하지 못했습니다)",
+	"--- Explanation: %s ---":                                         "--- 설명: %s ---",
+	"(AI could not explain this command)":                             "(AI가 이 명령어를 설명하지 못했습니다)",
+	"--- Fix for: %s ---":                                             "--- 수정안: %s ---",
+	"--- Diagnosis: %s ---":                                           "--- 진단: %s ---",
+	"--- Likely Next Commands ---":                                    "--- 다음에 실행할 명령어 ---",
+	"--- Answer ---":                                                  "--- 답변 ---",
+	"--- Summary %s ---":                                              "--- 요약 (%s) ---",
+	"%d commands, %d unique":                                          "명령어 %d개, 고유 %d개",
+	", from %s to %s":                                                 ", %s부터 %s까지",
+	"Top programs":                                                    "자주 쓰는 프로그램",
+	"Top commands":                                                    "자주 쓰는 명령어",
+	"Top directories":                                                 "자주 쓰는 디렉터리",
+	"Busiest hours":                                                   "가장 바쁜 시간대",
+	"Most failing programs":                                           "가장 자주 실패하는 프로그램",
+	"Longest-running commands":                                        "가장 오래 실행된 명령어",
+	"Frequent workflows":                                              "자주 쓰는 작업 흐름",
+	"No history entries found.\n":                                     "히스토리 항목을 찾지 못했습니다.\n",
+	"Decrypted %s.\n":                                                 "%s을(를) 복호화했습니다.\n",
+	"Encrypted %s.\n":                                                 "%s을(를) 암호화했습니다.\n",
+	"Decrypted %d entries of %s.\n":                                   "%[2]s의 항목 %[1]d개를 복호화했습니다.\n",
+	"Encrypted %d entries of %s.\n":                                   "%[2]s의 항목 %[1]d개를 암호화했습니다.\n",
+	"Encryption key: available\n":                                     "암호화 키: 사용 가능\n",
+	"Use the snippets and runbooks your team shares in a git repository":                       "팀이 git 저장소에서 공유하는 스니펫과 런북 사용",
+	"Clone or update the team's snippets repository":                                           "팀 스니펫 저장소를 클론하거나 업데이트",
+	"List the team's snippets":                                                                 "팀 스니펫 목록 표시",
+	"no team repository configured; set team.repo to its git URL":                              "팀 저장소가 설정되지 않았습니다. team.repo에 git URL을 설정하세요",
+	"the team repository hasn't been cloned yet; run 'historai team pull'":                     "팀 저장소가 아직 클론되지 않았습니다. 'historai team pull'을 실행하세요",
+	"%d team snippets in %s, last changed %s.\n":                                               "%[2]s에 팀 스니펫 %[1]d개, 마지막 변경 %[3]s.\n",
+	"%d team snippets in %s.\n":                                                                "%[2]s에 팀 스니펫 %[1]d개.\n",
+	"No snippets found in %s.\n":                                                               "%s에서 스니펫을 찾을 수 없습니다.\n",
+	"From your team's snippets: %s\n":                                                          "팀 스니펫에서: %s\n",
+	"From your team's snippets":                                                                "팀 스니펫에서",
+	"Encryption key: not available (%v)\n":                                                     "암호화 키: 사용할 수 없음 (%v)\n",
+	"encrypted":                                                                                "암호화됨",
+	"missing":                                                                                  "없음",
+	"not encrypted":                                                                            "암호화되지 않음",
+	"Encryption key removed from the OS keyring.\n":                                            "OS 키링에서 암호화 키를 삭제했습니다.\n",
+	"The files no longer need the key in $%s.\n":                                               "이제 파일에 $%s의 키가 필요하지 않습니다.\n",
+	"Generated an encryption key and stored it in the OS keyring.\n":                           "암호화 키를 생성해 OS 키링에 저장했습니다.\n",
+	"Show which files are encrypted and whether the key is available":                          "어떤 파일이 암호화되어 있는지와 키 사용 가능 여부를 표시합니다",
+	"Decrypt the history log and annotations and delete the key":                               "히스토리 로그와 주석을 복호화하고 키를 삭제합니다",
+	"Generate a key if needed and encrypt the history log and annotations":                     "필요하면 키를 생성하고 히스토리 로그와 주석을 암호화합니다",
+	"Encrypt historai's history log and annotations at rest":                                   "historai의 히스토리 로그와 주석을 암호화해 저장합니다",
+	"%s still holds %d matching entries; run again with --shell-history to forget them too.\n": "%s에 일치하는 항목이 아직 %d개 있습니다. --shell-history로 다시 실행하면 함께 삭제됩니다.\n",
+	"Cleared the daemon's cached history.\n":                                                   "데몬의 히스토리 캐시를 비웠습니다.\n",
+	"Forgot the notes, tags and saved status of %d commands.\n":                                "%d개 명령어의 메모, 태그, 저장 상태를 삭제했습니다.\n",
+	"Forgot %d entries from %s. The original file was saved to %s; delete it once you are sure, as it still holds them.\n": "%[2]s에서 %[1]d개 항목을 삭제했습니다. 원본 파일은 %[3]s에 저장되었습니다. 삭제한 항목이 아직 남아 있으니 확인 후 지워 주세요.\n",
+	"Forgot %d entries from %s.\n":                                                     "%[2]s에서 %[1]d개 항목을 삭제했습니다.\n",
+	"--months must be at least 1":                                                      "--months는 1 이상이어야 합니다",
+	"--mirror only supports Zsh history, not %q":                                       "--mirror는 Zsh 히스토리만 지원합니다(%q는 지원하지 않음)",
+	"Nothing older than %s to archive.\n":                                              "%s 이전의 보관할 항목이 없습니다.\n",
+	"Would archive %d entries older than %s into %s.\n":                                "%[2]s 이전의 항목 %[1]d개를 %[3]s에 보관할 예정입니다.\n",
+	"Archived %d entries older than %s into %d segments in %s.\n":                      "%[2]s 이전의 항목 %[1]d개를 %[4]s의 세그먼트 %[3]d개에 보관했습니다.\n",
+	"Nothing archived yet in %s.\n":                                                    "%s에 아직 보관된 항목이 없습니다.\n",
+	"%d entries, %d KB":                                                                "항목 %d개, %d KB",
+	"keyword cannot be empty":                                                          "키워드는 비워 둘 수 없습니다",
+	"No archived commands contain %q.\n":                                               "%q를 포함한 보관된 명령어가 없습니다.\n",
+	"Encrypted %d archived entries in %s.\n":                                           "%[2]s의 보관된 항목 %[1]d개를 암호화했습니다.\n",
+	"Decrypted %d archived entries in %s.\n":                                           "%[2]s의 보관된 항목 %[1]d개를 복호화했습니다.\n",
+	"Forget %d entries matching %q?":                                                   "%[2]q와 일치하는 %[1]d개 항목을 삭제할까요?",
+	"Would forget %d entries.\n":                                                       "%d개 항목을 삭제할 예정입니다.\n",
+	"Nothing matches %q.\n":                                                            "%q와 일치하는 항목이 없습니다.\n",
+	"invalid pattern %q: %v":                                                           "잘못된 패턴 %q: %v",
+	"pattern cannot be empty":                                                          "패턴은 비어 있을 수 없습니다",
+	"--no-backup only applies with --shell-history":                                    "--no-backup은 --shell-history와 함께만 사용할 수 있습니다",
+	"Delete commands from historai's history, annotations and caches":                  "historai의 히스토리, 주석, 캐시에서 명령어를 삭제합니다",
+	"the daemon returned no cache statistics":                                          "데몬이 캐시 통계를 돌려주지 않았습니다",
+	"no daemon is running, and only the daemon caches history (see 'historai daemon')": "실행 중인 데몬이 없습니다. 기록은 데몬만 캐시합니다 ('historai daemon' 참고)",
+	"Cleared %d cached history entries.\n":                                             "캐시된 기록 항목 %d개를 비웠습니다.\n",
+	"History cache: %d entries, %d hits, %d misses (%.0f%% hit rate)\n":                "기록 캐시: 항목 %d개, 적중 %d회, 실패 %d회 (적중률 %.0f%%)\n",
+	"total":       "전체",
+	"Timings: %s": "소요 시간: %s",
+	"Stage columns are medians, over the runs that had the stage.": "단계 열은 해당 단계가 있었던 실행의 중앙값입니다.",
+	"By model":                                  "모델별",
+	"By command":                                "명령별",
+	"%d invocations, from %s to %s":             "실행 %d회, %s부터 %s까지",
+	"No timed invocations found.\n":             "시간이 기록된 실행이 없습니다.\n",
+	"Report written to %s\n":                    "보고서를 %s에 저장했습니다\n",
+	"No secrets found in %d history entries.\n": "히스토리 항목 %d개에서 비밀 정보를 찾지 못했습니다.\n",
+	"Found secrets in %d history entries. Run 'historai audit --scrub' to remove them.\n": "히스토리 항목 %d개에서 비밀 정보를 찾았습니다. 제거하려면 'historai audit --scrub'을 실행하세요.\n",
+	"Redact %d entries in %s?": "%[2]s의 항목 %[1]d개를 가릴까요?",
+	"Redacted %d entries. The original file was saved to %s; delete it once you have checked the result.\n": "항목 %d개를 가렸습니다. 원본 파일은 %s에 저장되었습니다. 결과를 확인한 뒤 삭제하세요.\n",
+	"history source %q can't be rewritten":                                   "히스토리 소스 %q는 다시 쓸 수 없습니다",
+	"stdin is not a terminal; pass --yes to confirm":                         "표준 입력이 터미널이 아닙니다. 확인하려면 --yes를 지정하세요",
+	"Nothing to prune in %d history entries.\n":                              "히스토리 항목 %d개 중 정리할 항목이 없습니다.\n",
+	"Would remove %d of %d entries (%s).\n":                                  "항목 %[2]d개 중 %[1]d개를 제거합니다 (%[3]s).\n",
+	"Remove %d of %d entries (%s) from %s?":                                  "%[4]s에서 항목 %[2]d개 중 %[1]d개를 제거할까요 (%[3]s)?",
+	"Removed %d entries. The original file was saved to %s.\n":               "항목 %d개를 제거했습니다. 원본 파일은 %s에 저장되었습니다.\n",
+	"Exported %d entries to %s\n":                                            "항목 %d개를 %s(으)로 내보냈습니다\n",
+	"Imported %d new entries (%d duplicates skipped) into %s\n":              "새 항목 %d개를 %[3]s(으)로 가져왔습니다 (중복 %[2]d개 건너뜀)\n",
+	"Pulled %d new entries from %s\n":                                        "%[2]s에서 새 항목 %[1]d개를 가져왔습니다\n",
+	"Pushed %d new entries to %s\n":                                          "%[2]s에 새 항목 %[1]d개를 올렸습니다\n",
+	"Removed %d forgotten entries from %s\n":                                 "%[2]s에서 잊은 항목 %[1]d개를 지웠습니다\n",
+	"Nothing new to push to %s\n":                                            "%s에 올릴 새 항목이 없습니다\n",
+	"--delete takes no note":                                                 "--delete에는 메모를 지정할 수 없습니다",
+	"No note on %s\n":                                                        "%s에 메모가 없습니다\n",
+	"note cannot be empty (use --delete to remove a note)":                   "메모는 비워 둘 수 없습니다 (메모를 지우려면 --delete를 사용하세요)",
+	"Removed the note from %s\n":                                             "%s의 메모를 지웠습니다\n",
+	"Noted %s\n":                                                             "%s에 메모를 남겼습니다\n",
+	"entry cannot be empty":                                                  "항목은 비워 둘 수 없습니다",
+	"entry number must be 1 or more":                                         "항목 번호는 1 이상이어야 합니다",
+	"history has fewer than %s commands":                                     "기록에 명령어가 %s개보다 적습니다",
+	"No annotated commands yet.\n":                                           "아직 메모를 붙인 명령어가 없습니다.\n",
+	"No commands are tagged %s.\n":                                           "%s 태그가 붙은 명령어가 없습니다.\n",
+	"%s is already tagged %s\n":                                              "%s에는 이미 %s 태그가 있습니다\n",
+	"%s is not tagged %s\n":                                                  "%s에는 %s 태그가 없습니다\n",
+	"Tagged %s with %s\n":                                                    "%s에 %s 태그를 붙였습니다\n",
+	"Removed tag %s from %s\n":                                               "%[2]s에서 %[1]s 태그를 제거했습니다\n",
+	"No tags yet. Add one with 'historai tag add <tag> <entry>'.\n":          "아직 태그가 없습니다. 'historai tag add <tag> <entry>'로 추가하세요.\n",
+	"%s is not saved\n":                                                      "%s은(는) 저장되어 있지 않습니다\n",
+	"%s is already saved\n":                                                  "%s은(는) 이미 저장되어 있습니다\n",
+	"Removed %s from your saved commands\n":                                  "저장된 명령어에서 %s을(를) 제거했습니다\n",
+	"Saved %s\n":                                                             "%s을(를) 저장했습니다\n",
+	"--run needs the number of a saved command":                              "--run에는 저장된 명령어 번호가 필요합니다",
+	"not running %s: the organization policy %s denies commands matching %s": "%[1]s을(를) 실행하지 않습니다: 조직 정책 %[2]s에서 %[3]s와(과) 일치하는 명령어를 금지합니다",
+	"no saved command number %s (see 'historai saved')":                      "저장된 명령어 %s번이 없습니다 ('historai saved' 참조)",
+	"Run %s?": "%s을(를) 실행하시겠습니까?",
+	"No saved commands yet. Save one with 'historai save <entry>'.\n":          "아직 저장된 명령어가 없습니다. 'historai save <entry>'로 저장하세요.\n",
+	"Note: %s has no {placeholders}.\n":                                        "참고: %s에는 {자리 표시자}가 없습니다.\n",
+	"Replaced snippet %s\n":                                                    "스니펫 %s을(를) 교체했습니다\n",
+	"Added snippet %s\n":                                                       "스니펫 %s을(를) 추가했습니다\n",
+	"no snippet named %s (see 'historai snippet list')":                        "%s(이)라는 스니펫이 없습니다 ('historai snippet list' 참조)",
+	"Removed snippet %s\n":                                                     "스니펫 %s을(를) 제거했습니다\n",
+	"No snippets yet. Add one with 'historai snippet add <name> <command>'.\n": "아직 스니펫이 없습니다. 'historai snippet add <name> <command>'로 추가하세요.\n",
+	"no value for {%[1]s}; pass --set %[1]s=<value>":                           "{%[1]s}의 값이 없습니다. --set %[1]s=<값>을 지정하세요",
+	"--set expects name=value, got %q":                                         "--set에는 이름=값 형식이 필요하지만 %q이(가) 주어졌습니다",
+	"the snippet has no placeholder {%s}":                                      "스니펫에 {%s} 자리 표시자가 없습니다",
+	"no commands selected":                                                     "선택된 명령어가 없습니다",
+	"stdin is not a terminal; select commands with --since or --last":          "표준 입력이 터미널이 아닙니다. --since 또는 --last로 명령어를 선택하세요",
+	"Commands to include (e.g. 1-5,8; Enter for all): ":                        "포함할 명령어 (예: 1-5,8, 전체는 Enter): ",
+	"invalid selection %q (use numbers from 1 to %d, e.g. 1-5,8)":              "잘못된 선택 %q (1부터 %d까지의 번호를 사용하세요. 예: 1-5,8)",
+	"Script written to %s\n":                                                   "스크립트를 %s에 저장했습니다\n",
+	"--- Script from %d commands ---":                                          "--- 명령어 %d개로 만든 스크립트 ---",
+	"No repeated command sequences found.\n":                                   "반복된 명령어 순서를 찾지 못했습니다.\n",
+	"--- %s targets ---":                                                       "--- %s 타깃 ---",
+	"Append these targets to %s?":                                              "이 타깃을 %s에 추가하시겠습니까?",
+	"invalid --format %q (use make or just)":                                   "잘못된 --format %q (make 또는 just를 사용하세요)",
+	"Wrote the %s docs to %s\n":                                                "%s 문서를 %s에 작성했습니다\n",
+	"invalid --format %q (use man or markdown)":                                "잘못된 --format %q (man 또는 markdown을 사용하세요)",
+	"Repeated workflows:":                                                      "반복된 작업 흐름:",
+	"Appended the targets to %s\n":                                             "타깃을 %s에 추가했습니다\n",
+	"No long commands typed often enough to need an alias.\n":                  "별칭이 필요할 만큼 자주 입력한 긴 명령어가 없습니다.\n",
+	"unsupported shell %q; pass the rc file with --rc":                         "지원하지 않는 셸입니다: %q. --rc로 rc 파일을 지정해 주세요",
+	"Append these definitions to %s?":                                          "이 정의를 %s에 추가하시겠습니까?",
+	"Appended the definitions to %s; open a new shell to use them.\n":          "정의를 %s에 추가했습니다. 새 셸을 열어 사용해 주세요.\n",
+	"No recommendations: you already use modern tools where it matters.\n":     "추천할 항목이 없습니다. 이미 필요한 곳에 최신 도구를 사용하고 계십니다.\n",
+	"%s instead of %s":                                                         "%[2]s 대신 %[1]s",
+	"(%d times)":                                                               "(%d회)",
+	"Install:":                                                                 "설치:",
+	"much faster recursive search that skips .gitignore'd and binary files":    ".gitignore 대상과 바이너리 파일을 건너뛰는 훨씬 빠른 재귀 검색입니다",
+	"simpler syntax, faster, and skips .gitignore'd files":                     "문법이 더 간단하고 빠르며 .gitignore 대상 파일을 건너뜁니다",
+	"jumps to frequent directories from a few letters of their name":           "이름의 몇 글자만으로 자주 가는 디렉터리로 이동합니다",
+	"syntax highlighting, line numbers and git changes, and pages long files":  "구문 강조, 줄 번호, git 변경 사항을 표시하고 긴 파일은 페이지로 나누어 보여 줍니다",
+	"short, example-first summaries of commands":                               "예제 중심의 간결한 명령어 요약을 보여 줍니다",
+	"Modern alternatives":                                                      "최신 대안",
+	"Run 'historai tips' for install commands and examples.":                   "설치 명령어와 예제는 'historai tips'를 실행해 확인해 주세요.",
+	"No correction found for: %s\n":                                            "교정할 내용을 찾지 못했습니다: %s\n",
+	"[Enter] run, any other key to cancel":                                     "[Enter] 실행, 다른 키를 누르면 취소합니다",
+	"missing sudo":                                                             "sudo 누락",
+	"typo in the program name":                                                 "프로그램 이름 오타",
+	"typo in the subcommand":                                                   "하위 명령어 오타",
+	"suggested by the error output":                                            "오류 출력에서 제안한 명령어",
+	"missing -r for a directory":                                               "디렉터리에 -r 누락",
+	"missing -p for parent directories":                                        "상위 디렉터리용 -p 누락",
+	"stdin is not a terminal; teach is interactive":                            "stdin이 터미널이 아닙니다. teach는 대화형으로만 사용할 수 있습니다",
+	"No commands with flags to ask about in your history.\n":                   "히스토리에 퀴즈로 낼 플래그가 있는 명령어가 없습니다.\n",
+	"Question %d/%d":                                                           "문제 %d/%d",
+	"Answer:":                                                                  "정답:",
+	"Correct!":                                                                 "정답입니다!",
+	"Not quite.":                                                               "아쉽습니다.",
+	"Score: %d/%d":                                                             "점수: %d/%d",
+	"(AI could not grade this answer)":                                         "(AI가 이 답변을 채점하지 못했습니다)",
+	"invalid request %q (use find or suggest)":                                 "잘못된 요청 %q (find 또는 suggest를 사용하세요)",
+	"compare needs at least two models: pass --with for each, or set compare.models": "compare에는 모델이 두 개 이상 필요합니다. 모델마다 --with를 지정하거나 compare.models를 설정하세요",
+	"%d+%d tokens":  "%d+%d 토큰",
+	"Error: %s":     "오류: %s",
+	"(no commands)": "(명령어 없음)",
+	"Created sync key %s (public key %s).\nCopy it to the same path, or to sync.identity, on your other machines and keep a backup: without it the synced history can't be decrypted.\n": "동기화 키 %s를 만들었습니다 (공개 키 %s).\n다른 컴퓨터의 같은 경로나 sync.identity에 복사하고 백업해 두세요. 키가 없으면 동기화된 기록을 복호화할 수 없습니다.\n",
+	"Set history.source to %q in your config file to use them.\n":                              "가져온 항목을 사용하려면 설정 파일에서 history.source를 %q(으)로 설정하세요.\n",
+	"Chatting about your shell history. Type /reset to start over, /exit or Ctrl-D to quit.\n": "셸 히스토리에 대해 대화합니다. 새로 시작하려면 /reset, 종료하려면 /exit 또는 Ctrl-D를 입력하세요.\n",
+	"Conversation cleared.\n":                 "대화를 초기화했습니다.\n",
+	"The command looks correct already.\n":    "명령어가 이미 올바른 것 같습니다.\n",
+	"(AI could not fix this command)":         "(AI가 이 명령어를 고치지 못했습니다)",
+	"(AI could not diagnose this failure)":    "(AI가 이 실패를 진단하지 못했습니다)",
+	"(AI could not predict the next command)": "(AI가 다음 명령어를 예측하지 못했습니다)",
+	"(AI response was empty)":                 "(AI 응답이 비어 있습니다)",
+	"(AI could not summarize this activity)":  "(AI가 작업 내용을 요약하지 못했습니다)",
+	"(AI could not write a script)":           "(AI가 스크립트를 작성하지 못했습니다)",
+	"(AI could not write build targets)":      "(AI가 빌드 타깃을 작성하지 못했습니다)",
+	"(AI could not answer this question)":     "(AI가 이 질문에 답하지 못했습니다)",
+	"contacting %s… %.1fs":                    "%s에 요청하는 중… %.1f초",
+	"Interrupted.":                            "중단되었습니다.",
+
+	// Status messages
+	"API key stored in the OS keyring.\n":                           "API 키를 OS 키링에 저장했습니다.\n",
+	"API key removed from the OS keyring.\n":                        "OS 키링에서 API 키를 삭제했습니다.\n",
+	"API key loaded from %s\n":                                      "API 키를 %s에서 불러왔습니다\n",
+	"No API key configured. Set %s or run 'historai auth login'.\n": "API 키가 설정되지 않았습니다. %s을(를) 설정하거나 'historai auth login'을 실행하세요.\n",
+	"Google AI Studio API key: ":                                    "Google AI Studio API 키: ",
+	"Set %s in %s\n":                                                "%[2]s에 %[1]s 값을 설정했습니다\n",
+	"Unset %s in %s\n":                                              "%[2]s에서 %[1]s 값을 제거했습니다\n",
+	"%s already exists; left unchanged\n":                           "%s 파일이 이미 있어 그대로 두었습니다\n",
+	"Wrote %s\n":                                                    "%s 파일을 작성했습니다\n",
+	"No problems found, with %d warnings.\n":                        "문제가 없습니다. 경고 %d개가 있습니다.\n",
+	"No problems found.\n":                                          "문제가 없습니다.\n",
+	"Review it before attaching it to an issue.\n":                  "이슈에 첨부하기 전에 내용을 확인하세요.\n",
+	"unavailable":                                                   "사용 불가",
+	"network error":                                                 "네트워크 오류",
+	"the provider answered with HTTP %d":                            "제공자가 HTTP %d로 응답했습니다",
+	"the request timed out":                                         "요청 시간이 초과되었습니다",
+	"no API key is configured":                                      "API 키가 설정되지 않았습니다",
+	"the organization policy doesn't allow it":                      "조직 정책에서 허용하지 않습니다",
+	"The AI provider is unavailable (%s).\nShowing keyword matches from your history instead; these are not AI results.\n": "AI 제공자를 사용할 수 없습니다 (%s).\n대신 기록에서 키워드가 일치하는 명령어를 보여 줍니다. AI 결과가 아닙니다.\n",
+
+	// Errors
+	"Error: %v\n":                      "오류: %v\n",
+	"API key cannot be empty":          "API 키는 비어 있을 수 없습니다",
+	"command cannot be empty":          "명령어는 비어 있을 수 없습니다",
+	"chat only supports text output":   "chat은 텍스트 출력만 지원합니다",
+	"query cannot be empty":            "검색어는 비어 있을 수 없습니다",
+	"task description cannot be empty": "작업 설명은 비어 있을 수 없습니다",
+	"question cannot be empty":         "질문은 비어 있을 수 없습니다",
+	"%d of %d checks failed":           "점검 %[2]d개 중 %[1]d개가 실패했습니다",
+	"--batch always writes JSON lines; drop --raw, --first and --output":   "--batch는 항상 JSON 줄로 출력합니다. --raw, --first, --output을 빼세요",
+	"--dry-run cannot be combined with --batch":                            "--dry-run은 --batch와 함께 쓸 수 없습니다",
+	"--concurrency must be at least 1":                                     "--concurrency는 1 이상이어야 합니다",
+	"--batch reads the queries from a file; don't give one as an argument": "--batch는 파일에서 검색어를 읽습니다. 인수로 검색어를 주지 마세요",
+	"no queries in %s":                                                     "%s에 검색어가 없습니다",
+	"%d of %d queries failed":                                              "검색어 %[2]d개 중 %[1]d개가 실패했습니다",
+	"--with-ls cannot be combined with --history":                          "--with-ls는 --history와 함께 쓸 수 없습니다",
+	"--no-history-context cannot be combined with --history":               "--no-history-context는 --history와 함께 쓸 수 없습니다",
+	"--tag cannot be combined with --history, whose commands have no tags": "--tag는 --history와 함께 쓸 수 없습니다. 그 명령어에는 태그가 없습니다",
+	"--history and --batch can't both read stdin":                          "--history와 --batch가 둘 다 표준 입력을 읽을 수는 없습니다",
+	"no commands in %s":                                                    "%s에 명령어가 없습니다",
+	"⚠ High risk":                                                          "⚠ 위험 높음",
+	"Written by the AI":                                                    "AI가 작성",
+	"From your history":                                                    "내 기록에서",
+}
@@ -0,0 +1,85 @@
+// Package i18n translates historai's user-facing messages.
+//
+// Messages are looked up by their English text (gettext style), so English needs no
+// catalog and untranslated messages fall back to English automatically. Format
+// verbs in a translation must match those of the English message.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvLocale overrides the locale detected from the standard locale variables.
+// It is also the environment override of the ui.locale config key.
+const EnvLocale = "HISTORAI_UI_LOCALE"
+
+// catalogs maps a language code to its translations, keyed by the English message.
+var catalogs = map[string]map[string]string{
+	"ko": ko,
+	"ja": ja,
+}
+
+var (
+	mu      sync.RWMutex
+	current = Detect()
+)
+
+// Detect returns the locale from $HISTORAI_UI_LOCALE, falling back to $LC_ALL,
+// $LC_MESSAGES and $LANG, in that order.
+func Detect() string {
+	for _, name := range []string{EnvLocale, "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return normalize(value)
+		}
+	}
+	return "en"
+}
+
+// SetLocale selects the locale, e.g. "ko" or "ja_JP.UTF-8". An empty locale keeps
+// the detected one.
+func SetLocale(locale string) {
+	if locale == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = normalize(locale)
+}
+
+// Locale returns the language code in use.
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Supported returns the language codes that have a catalog, plus "en".
+func Supported() []string {
+	return []string{"en", "ja", "ko"}
+}
+
+// T translates msg and, if args are given, formats it with fmt.Sprintf.
+func T(msg string, args ...any) string {
+	if translated, ok := catalogs[Locale()][msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// normalize reduces a POSIX locale such as "ko_KR.UTF-8" to its language code.
+func normalize(locale string) string {
+	locale = strings.ToLower(locale)
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "c" || locale == "posix" || locale == "" {
+		return "en"
+	}
+	return locale
+}
@@ -0,0 +1,241 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeRequestCancelled answers requests cancelled by the peer, as in LSP.
+	CodeRequestCancelled = -32800
+)
+
+// CancelMethod is the notification cancelling the request whose id it names, as in
+// LSP. ServeConcurrent handles it.
+const CancelMethod = "$/cancelRequest"
+
+const maxMessageBytes = 4 * 1024 * 1024
+
+// Request is a JSON-RPC 2.0 request or notification (when ID is absent).
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request expects no response.
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is a JSON-RPC 2.0 response.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object. It also implements the error interface
+// so handlers can return a specific code.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// NewError creates an Error with the given code and message.
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// HandlerFunc handles a single request and returns its result.
+type HandlerFunc func(ctx context.Context, req *Request) (any, error)
+
+// Conn reads newline-delimited JSON-RPC messages from r and writes responses to w.
+type Conn struct {
+	logger  *zap.Logger
+	reader  io.Reader
+	writer  io.Writer
+	writeMu sync.Mutex
+}
+
+// NewConn creates a connection over a reader/writer pair (typically stdin/stdout).
+func NewConn(logger *zap.Logger, r io.Reader, w io.Writer) *Conn {
+	return &Conn{
+		logger: logger,
+		reader: r,
+		writer: w,
+	}
+}
+
+// Serve reads requests until EOF or ctx is cancelled, dispatching each to handler.
+// Requests are handled sequentially, in the order they are received.
+func (c *Conn) Serve(ctx context.Context, handler HandlerFunc) error {
+	return c.serve(ctx, func(req *Request) {
+		c.dispatch(ctx, req, handler)
+	})
+}
+
+// ServeConcurrent is like Serve, but handles each request in its own goroutine, so
+// that a slow request doesn't hold up the others; responses are written as they
+// are ready. The context of a request is cancelled by a CancelMethod notification
+// naming it, or when ctx is. ServeConcurrent returns once every handler has.
+func (c *Conn) ServeConcurrent(ctx context.Context, handler HandlerFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	// The peer may close its end once it has sent its requests: answer them first.
+	defer wg.Wait()
+
+	var mu sync.Mutex
+	inFlight := make(map[string]context.CancelFunc)
+	return c.serve(ctx, func(req *Request) {
+		if req.Method == CancelMethod {
+			var params struct {
+				ID json.RawMessage `json:"id"`
+			}
+			if err := DecodeParams(req, &params); err != nil {
+				c.logger.Debug("Ignoring invalid cancellation", zap.Error(err))
+				return
+			}
+			mu.Lock()
+			if cancelRequest, ok := inFlight[string(params.ID)]; ok {
+				cancelRequest()
+			}
+			mu.Unlock()
+			return
+		}
+
+		reqCtx, cancelRequest := context.WithCancel(ctx)
+		id := string(req.ID)
+		if !req.IsNotification() {
+			mu.Lock()
+			inFlight[id] = cancelRequest
+			mu.Unlock()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancelRequest()
+			c.dispatch(reqCtx, req, handler)
+			if !req.IsNotification() {
+				mu.Lock()
+				delete(inFlight, id)
+				mu.Unlock()
+			}
+		}()
+	})
+}
+
+// serve reads requests until EOF or ctx is cancelled, passing each to dispatch.
+func (c *Conn) serve(ctx context.Context, dispatch func(*Request)) error {
+	scanner := bufio.NewScanner(c.reader)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			c.writeResponse(Response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: NewError(CodeParseError, err.Error())})
+			continue
+		}
+		dispatch(&req)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read JSON-RPC stream: %w", err)
+	}
+	return nil
+}
+
+// dispatch runs handler for req and writes its response unless req is a notification.
+func (c *Conn) dispatch(ctx context.Context, req *Request, handler HandlerFunc) {
+	c.logger.Debug("Handling JSON-RPC request", zap.String("method", req.Method))
+
+	result, err := handler(ctx, req)
+	if req.IsNotification() {
+		if err != nil {
+			c.logger.Debug("Notification handler failed", zap.String("method", req.Method), zap.Error(err))
+		}
+		return
+	}
+
+	resp := Response{JSONRPC: "2.0", ID: req.ID}
+	if err != nil {
+		var rpcErr *Error
+		switch {
+		case errors.As(err, &rpcErr):
+			resp.Error = rpcErr
+		case errors.Is(err, context.Canceled):
+			resp.Error = NewError(CodeRequestCancelled, "request cancelled")
+		default:
+			resp.Error = NewError(CodeInternalError, err.Error())
+		}
+	} else {
+		resp.Result = result
+	}
+	c.writeResponse(resp)
+}
+
+// Notify sends a notification (a request without an ID) to the peer.
+func (c *Conn) Notify(method string, params any) {
+	c.write(struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params,omitempty"`
+	}{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *Conn) writeResponse(resp Response) {
+	c.write(resp)
+}
+
+func (c *Conn) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		c.logger.Error("Failed to encode JSON-RPC message", zap.Error(err))
+		return
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.writer.Write(append(data, '\n')); err != nil {
+		c.logger.Error("Failed to write JSON-RPC message", zap.Error(err))
+	}
+}
+
+// DecodeParams unmarshals req.Params into v, returning an invalid-params error on failure.
+func DecodeParams(req *Request, v any) error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return NewError(CodeInvalidParams, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Scores of selectSuggestion: an answer without commands loses to any answer with
+// some, a line that doesn't parse costs more than a flagged risk, and an unflagged
+// risk costs the most.
+const (
+	noCommandScore   = -1000
+	invalidLineScore = -10
+	riskyLineScore   = -20
+	flaggedRiskScore = -2
+)
+
+// openers maps the brackets that close a group in a command to those that open it.
+var openers = map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+// riskyCommand matches commands that can destroy data or a system, or run code from
+// the network, whatever the prompt asked for.
+var riskyCommand = regexp.MustCompile(`(?i)\brm\s+-\w*[rf]\w*\s+(--\s+)?(/|~|\*|\.\s*$)|\bdd\b.*\bof=/dev/|\bmkfs(\.\w+)?\b|:\(\)\s*\{|\bchmod\s+(-\w+\s+)*0?777\s+/|>\s*/dev/(sd|nvme|disk)|\b(curl|wget|iwr|Invoke-WebRequest)\b.*\|\s*(sudo\s+)?(ba|z)?sh\b|\bgit\s+push\b.*\s(--force|-f)\b|\bgit\s+(reset\s+--hard|clean\s+-\w*f)|\bRemove-Item\b.*-Recurse\b.*-Force\b|\bformat\s+[a-z]:|\bdel\s+/s\b`)
+
+// answerLines returns the non-empty lines of a model's answer, without the markdown
+// code fences and backticks models sometimes wrap commands in despite the prompt.
+func answerLines(answer string) []string {
+	var lines []string
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "```") {
+			continue
+		}
+		if len(line) > 1 && strings.HasPrefix(line, "`") && strings.HasSuffix(line, "`") {
+			line = strings.TrimSpace(line[1 : len(line)-1])
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// selectFound merges the answers of several find candidates: the history commands
+// that at least half of the answering candidates named, most named first. Commands
+// that aren't in historyContext were made up and are dropped, unless no answer
+// names any other.
+func selectFound(answers []string, historyContext []history.HistoryEntry) string {
+	known := make(map[string]bool, len(historyContext))
+	for _, entry := range historyContext {
+		known[strings.TrimSpace(entry.Command)] = true
+	}
+
+	votes := make(map[string]int)
+	var commands []string
+	answered := 0
+	for _, answer := range answers {
+		seen := make(map[string]bool)
+		for _, line := range answerLines(answer) {
+			if line == noMatchesAnswer || seen[line] {
+				continue
+			}
+			seen[line] = true
+			if votes[line] == 0 {
+				commands = append(commands, line)
+			}
+			votes[line]++
+		}
+		if len(seen) > 0 {
+			answered++
+		}
+	}
+
+	commands = keepIfAny(commands, func(command string) bool { return known[command] })
+	commands = keepIfAny(commands, func(command string) bool { return votes[command]*2 >= answered })
+	if len(commands) == 0 {
+		return noMatchesAnswer
+	}
+	sort.SliceStable(commands, func(i, j int) bool { return votes[commands[i]] > votes[commands[j]] })
+	return strings.Join(commands, "\n")
+}
+
+// keepIfAny returns the commands keep is true for, or all of them if it is true for
+// none.
+func keepIfAny(commands []string, keep func(string) bool) []string {
+	var kept []string
+	for _, command := range commands {
+		if keep(command) {
+			kept = append(kept, command)
+		}
+	}
+	if len(kept) == 0 {
+		return commands
+	}
+	return kept
+}
+
+// selectSuggestion picks the best of the answers of several suggest candidates. Each
+// scores points for the commands other candidates suggested too, and loses points
+// for commands that don't parse and for risky ones, more so when not flagged by a
+// warning comment. Ties go to the earliest, the model's first choice.
+func selectSuggestion(answers []string) string {
+	commands := make([][]suggestedCommand, len(answers))
+	votes := make(map[string]int)
+	for i, answer := range answers {
+		commands[i] = suggestedCommands(answer)
+		seen := make(map[string]bool)
+		for _, command := range commands[i] {
+			if !seen[command.text] {
+				seen[command.text] = true
+				votes[command.text]++
+			}
+		}
+	}
+
+	best, bestScore := 0, 0
+	for i := range answers {
+		score := suggestionScore(commands[i], votes)
+		if i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return answers[best]
+}
+
+// suggestedCommand is a command of a suggest answer.
+type suggestedCommand struct {
+	text string
+
+	// warned reports that a warning comment comes before the command.
+	warned bool
+}
+
+// suggestedCommands returns the commands of a suggest answer, joining the lines of
+// commands continued with a backslash. Comments are left out.
+func suggestedCommands(answer string) []suggestedCommand {
+	var commands []suggestedCommand
+	var current strings.Builder
+	warned := false
+	for _, line := range answerLines(answer) {
+		if line == cannotSuggestAnswer {
+			continue
+		}
+		if current.Len() == 0 && strings.HasPrefix(line, "#") {
+			warned = warned || strings.HasPrefix(strings.ToLower(line), "# warning")
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(line)
+		commands = append(commands, suggestedCommand{text: current.String(), warned: warned})
+		current.Reset()
+		warned = false
+	}
+	if current.Len() > 0 {
+		commands = append(commands, suggestedCommand{text: strings.TrimSpace(current.String()), warned: warned})
+	}
+	return commands
+}
+
+// suggestionScore scores the commands of a suggest answer for selectSuggestion.
+func suggestionScore(commands []suggestedCommand, votes map[string]int) int {
+	if len(commands) == 0 {
+		return noCommandScore
+	}
+	score := 0
+	for _, command := range commands {
+		score += votes[command.text] - 1
+		if !balanced(command.text) {
+			score += invalidLineScore
+		}
+		if riskyCommand.MatchString(command.text) {
+			if command.warned {
+				score += flaggedRiskScore
+			} else {
+				score += riskyLineScore
+			}
+		}
+	}
+	return score
+}
+
+// balanced reports whether the quotes, parentheses, brackets and braces of command
+// are closed, as they are in a command a shell can parse. A comment after the
+// command is ignored.
+func balanced(command string) bool {
+	var open []rune
+	var quote rune
+	escaped := false
+	prev := ' '
+	for _, r := range command {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '#' && (prev == ' ' || prev == '\t'):
+			return len(open) == 0
+		case r == '(' || r == '[' || r == '{':
+			open = append(open, r)
+		case r == ')' || r == ']' || r == '}':
+			if len(open) == 0 || open[len(open)-1] != openers[r] {
+				return false
+			}
+			open = open[:len(open)-1]
+		}
+		prev = r
+	}
+	return quote == 0 && len(open) == 0
+}
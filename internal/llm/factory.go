@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+// ProviderGemini is the built-in Google AI (Gemini) provider and the default.
+const ProviderGemini = "gemini"
+
+// NewClient returns the LLMClient for the configured provider. Built-in providers are
+// handled directly (ProviderMock needs no network or key); any other name is resolved to an external provider plugin.
+// With llm.race set, the client races the configured model against that one.
+func NewClient(ctx context.Context, logger *zap.Logger, cfg *config.Config) (LLMClient, error) {
+	if cfg.LLM.Race != "" {
+		return newRaceClient(ctx, logger, cfg)
+	}
+	return newProviderClient(ctx, logger, cfg)
+}
+
+// newProviderClient returns the LLMClient of the configured provider alone, if the
+// organization policy allows it.
+func newProviderClient(ctx context.Context, logger *zap.Logger, cfg *config.Config) (LLMClient, error) {
+	if err := cfg.Policy.AllowProvider(cfg.LLM.Provider); err != nil {
+		return nil, err
+	}
+	switch cfg.LLM.Provider {
+	case "", ProviderGemini:
+		return NewGeminiClient(ctx, logger, cfg)
+	case ProviderMock:
+		return NewMockClient(logger, cfg)
+	default:
+		return NewPluginClient(ctx, logger, cfg)
+	}
+}
+
+// ModelName returns the model the configured provider will use. It is empty for
+// plugin providers that have no llm.model set, as they choose their own default.
+func ModelName(cfg *config.Config) string {
+	if cfg.LLM.Model != "" {
+		return cfg.LLM.Model
+	}
+	if cfg.LLM.Provider == "" || cfg.LLM.Provider == ProviderGemini {
+		return defaultModelName
+	}
+	return ""
+}
+
+// ConfigForSpec returns a copy of cfg using the model described by spec: "model" of
+// the configured provider, or "provider:model". The copy doesn't race another model.
+func ConfigForSpec(cfg *config.Config, spec string) *config.Config {
+	copied := *cfg
+	if provider, model, ok := strings.Cut(spec, ":"); ok {
+		copied.LLM.Provider, copied.LLM.Model = provider, model
+	} else {
+		copied.LLM.Model = spec
+	}
+	copied.LLM.Race = ""
+	return &copied
+}
+
+// newRaceClient returns a RaceClient racing the configured model against the one of
+// llm.race.
+func newRaceClient(ctx context.Context, logger *zap.Logger, cfg *config.Config) (LLMClient, error) {
+	primaryCfg := *cfg
+	primaryCfg.LLM.Race = ""
+	primary, err := newProviderClient(ctx, logger, &primaryCfg)
+	if err != nil {
+		return nil, err
+	}
+	rivalCfg := ConfigForSpec(cfg, cfg.LLM.Race)
+	rival, err := newProviderClient(ctx, logger, rivalCfg)
+	if err != nil {
+		_ = primary.Close()
+		return nil, fmt.Errorf("failed to initialize llm.race model %q: %w", cfg.LLM.Race, err)
+	}
+	return NewRaceClient(logger, primary, rival, modelLabel(&primaryCfg), modelLabel(rivalCfg)), nil
+}
+
+// modelLabel names the provider and model of cfg for logs, e.g. "gemini:gemini-2.0-flash".
+func modelLabel(cfg *config.Config) string {
+	provider := cfg.LLM.Provider
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	if model := ModelName(cfg); model != "" {
+		return provider + ":" + model
+	}
+	return provider
+}
@@ -4,59 +4,294 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/sanspareilsmyn/historai/internal/config"
 	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/httpclient"
+	"github.com/sanspareilsmyn/historai/internal/redact"
 
 	"github.com/google/generative-ai-go/genai"
 	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
 const (
 	defaultModelName = "gemini-1.5-flash-latest"
 
+	// apiKeyURL is where Gemini API keys are created.
+	apiKeyURL = "https://aistudio.google.com/app/apikey"
+
+	// modelsURL lists the Gemini models.
+	modelsURL = "https://ai.google.dev/gemini-api/docs/models"
+
 	findHistoryContextLimit    = 150
 	suggestHistoryContextLimit = 50
+	explainHistoryContextLimit = 20
+	nextHistoryContextLimit    = 150
+	chatHistoryContextLimit    = 150
+	askHistoryContextLimit     = 500
+	summaryGroupLimit          = 100
+	fillHistoryContextLimit    = 150
 )
 
 // GeminiClient implements the LLMClient interface using the Google AI (Gemini) API.
 type GeminiClient struct {
-	logger *zap.Logger
-	client *genai.Client
-	model  *genai.GenerativeModel
+	logger       *zap.Logger
+	client       *genai.Client
+	model        *genai.GenerativeModel
+	instructions string
+	language     string
+	system       string
+	templates    *PromptTemplates
+	maxToolCalls int
+
+	// modelName names the model, for errors.
+	modelName string
+
+	// candidates is how many answers to generate for find and suggest.
+	candidates int
+
+	// shell is the shell of Windows history sources, which don't set $SHELL.
+	shell string
+
+	// searcher, when set, lets the model fetch more history through tools.
+	searcher HistorySearcher
+
+	// usageMu guards lastUsage, as the daemon serves requests concurrently.
+	usageMu   sync.Mutex
+	lastUsage *Usage
+
+	promptHook PromptHook
 }
 
 // NewGeminiClient creates a new client specifically for the Google Gemini models.
-func NewGeminiClient(ctx context.Context, logger *zap.Logger, apiKey string) (*GeminiClient, error) {
+// An empty llm.model selects the default model.
+func NewGeminiClient(ctx context.Context, logger *zap.Logger, cfg *config.Config) (*GeminiClient, error) {
+	apiKey := cfg.GoogleAPIKey
 	if apiKey == "" {
 		return nil, errors.New("google AI (Gemini) API key is required")
 	}
 
-	modelName := defaultModelName
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	modelName := cfg.LLM.Model
+	if modelName == "" {
+		modelName = defaultModelName
+	}
+	logger.Debug("Using Gemini model", zap.String("model", modelName))
+	opts, err := geminiClientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := genai.NewClient(ctx, opts...)
 	if err != nil {
 		logger.Error("Failed to create Google AI (genai) client", zap.Error(err))
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
 
 	model := client.GenerativeModel(modelName)
-	model.SafetySettings = defaultSafetySettings()
+	model.SafetySettings = safetySettings(cfg.Safety)
+	if system := strings.TrimSpace(cfg.Prompt.System); system != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(system)}}
+	}
+
+	templatesDir, err := TemplatesDir(cfg)
+	if err != nil {
+		logger.Warn("Could not locate prompt templates; using the built-in ones", zap.Error(err))
+	}
+	templates, err := LoadPromptTemplates(logger, templatesDir)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
 
 	return &GeminiClient{
-		logger: logger,
-		client: client,
-		model:  model,
+		logger:       logger,
+		client:       client,
+		model:        model,
+		instructions: cfg.Prompt.Instructions,
+		language:     cfg.Prompt.Language,
+		system:       strings.TrimSpace(cfg.Prompt.System),
+		templates:    templates,
+		maxToolCalls: cfg.LLM.MaxToolCalls,
+		modelName:    modelName,
+		candidates:   cfg.LLM.Candidates,
+		shell:        history.SourceShell(cfg.History.Source),
 	}, nil
 }
 
-// defaultSafetySettings returns a default set of safety settings.
-func defaultSafetySettings() []*genai.SafetySetting {
+// geminiClientOptions builds the genai client options for the configured
+// API key, endpoint, proxy and CA certificates.
+func geminiClientOptions(cfg *config.Config) ([]option.ClientOption, error) {
+	opts := []option.ClientOption{option.WithAPIKey(cfg.GoogleAPIKey)}
+	if cfg.LLM.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(cfg.LLM.Endpoint))
+	}
+
+	if httpclient.IsCustomized(cfg.Network) {
+		httpClient, err := httpclient.New(cfg.Network)
+		if err != nil {
+			return nil, err
+		}
+		// A custom HTTP client bypasses the SDK's API key handling, so send the key ourselves.
+		httpClient.Transport = &httpclient.HeaderTransport{
+			Base:    httpClient.Transport,
+			Headers: map[string]string{"x-goog-api-key": cfg.GoogleAPIKey},
+		}
+		opts = append(opts, option.WithHTTPClient(httpClient))
+	}
+	return opts, nil
+}
+
+// retryTemperature is the temperature of the second try at a request whose response
+// was empty or blocked: above the default of Gemini models, so that the answer differs.
+const retryTemperature = 1.3
+
+// Notes added to the prompt of the second try, saying what went wrong with the first.
+const (
+	retryEmptyNote   = "\n\nYour previous answer to this was empty. Answer again, following the instructions."
+	retryBlockedNote = "\n\nYour previous answer to this was withheld by safety filters. Answer again, leaving out anything unsafe, or answer with the phrase you were asked to give when you have no answer."
+)
+
+// errResponseBlocked is ErrBlocked for a response, rather than a prompt, that the
+// safety filters blocked: a second try may answer differently and pass them.
+var errResponseBlocked = fmt.Errorf("%w", ErrBlocked)
+
+// safetyThresholds maps the values of the safety.* config keys to Gemini's thresholds.
+var safetyThresholds = map[string]genai.HarmBlockThreshold{
+	"none":             genai.HarmBlockNone,
+	"only_high":        genai.HarmBlockOnlyHigh,
+	"medium_and_above": genai.HarmBlockMediumAndAbove,
+	"low_and_above":    genai.HarmBlockLowAndAbove,
+}
+
+// safetyKeys names the config key setting the threshold of each category of harm.
+var safetyKeys = map[genai.HarmCategory]string{
+	genai.HarmCategoryHarassment:       "safety.harassment",
+	genai.HarmCategoryHateSpeech:       "safety.hate_speech",
+	genai.HarmCategorySexuallyExplicit: "safety.sexually_explicit",
+	genai.HarmCategoryDangerousContent: "safety.dangerous_content",
+}
+
+// safetySettings returns the safety settings configured in cfg. Unknown thresholds,
+// which Config.Validate rejects, fall back to medium and above.
+func safetySettings(cfg config.SafetyConfig) []*genai.SafetySetting {
+	threshold := func(name string) genai.HarmBlockThreshold {
+		if t, ok := safetyThresholds[name]; ok {
+			return t
+		}
+		return genai.HarmBlockMediumAndAbove
+	}
 	return []*genai.SafetySetting{
-		{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockMediumAndAbove},
-		{Category: genai.HarmCategoryHateSpeech, Threshold: genai.HarmBlockMediumAndAbove},
-		{Category: genai.HarmCategorySexuallyExplicit, Threshold: genai.HarmBlockMediumAndAbove},
-		{Category: genai.HarmCategoryDangerousContent, Threshold: genai.HarmBlockMediumAndAbove},
+		{Category: genai.HarmCategoryHarassment, Threshold: threshold(cfg.Harassment)},
+		{Category: genai.HarmCategoryHateSpeech, Threshold: threshold(cfg.HateSpeech)},
+		{Category: genai.HarmCategorySexuallyExplicit, Threshold: threshold(cfg.SexuallyExplicit)},
+		{Category: genai.HarmCategoryDangerousContent, Threshold: threshold(cfg.DangerousContent)},
+	}
+}
+
+// blockedHint names the config keys of the categories that ratings blocked, so the
+// user knows which threshold to lower, e.g. " (lower safety.dangerous_content to
+// allow it)". It returns "" when no rating says which.
+func blockedHint(ratings []*genai.SafetyRating) string {
+	var keys []string
+	for _, rating := range ratings {
+		if key, ok := safetyKeys[rating.Category]; ok && rating.Blocked && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	return " (lower " + strings.Join(keys, " or ") + " to allow it)"
+}
+
+// providerError describes the API errors users can fix themselves, such as a rejected
+// API key, an unknown model, an exhausted quota or an unsupported region, as a
+// ProviderError saying how. Other errors are returned as they are.
+func (c *GeminiClient) providerError(err error) error {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	message := strings.ToLower(apiErr.Error())
+	switch {
+	case strings.Contains(message, "api key expired"):
+		return &ProviderError{
+			Reason: "the API key has expired",
+			Hint:   "Create a new key at " + apiKeyURL + " and run 'historai auth login'.",
+			Config: true,
+			Err:    err,
+		}
+	case apiErr.Code == 401, strings.Contains(message, "api_key_invalid"), strings.Contains(message, "api key not valid"):
+		return &ProviderError{
+			Reason: "the API key was rejected",
+			Hint:   "Check " + config.EnvGoogleAPIKey + " or run 'historai auth status'; create a new key at " + apiKeyURL + " and run 'historai auth login'.",
+			Config: true,
+			Err:    err,
+		}
+	case strings.Contains(message, "location is not supported"), strings.Contains(message, "not available in your country"):
+		return &ProviderError{
+			Reason: "the Gemini API is not available in your region",
+			Hint:   "Send requests through a supported region with network.proxy or llm.endpoint, enable billing for the key's project where only the free tier is restricted, or use another provider with llm.provider.",
+			Config: true,
+			Err:    err,
+		}
+	case apiErr.Code == 403:
+		return &ProviderError{
+			Reason: "the API key is not allowed to use the Gemini API",
+			Hint:   "Enable the Generative Language API for the key's Google Cloud project and lift any API restrictions on the key, or create a key at " + apiKeyURL + ".",
+			Config: true,
+			Err:    err,
+		}
+	case apiErr.Code == 404:
+		return &ProviderError{
+			Reason: fmt.Sprintf("the model %q was not found", c.modelName),
+			Hint:   "Set llm.model to a model your key can use ('historai config set llm.model <name>'; see " + modelsURL + ").",
+			Config: true,
+			Err:    err,
+		}
+	case apiErr.Code == 429:
+		return &ProviderError{
+			Reason: "the Gemini API quota or rate limit was exceeded",
+			Hint:   "Wait a minute and try again. For higher limits, enable billing for the key's project in Google AI Studio, or switch llm.model to a model with a separate quota.",
+			Err:    err,
+		}
+	}
+	return err
+}
+
+// Verify implements the Verifier interface method. It looks up the configured model,
+// which needs a valid API key but costs no tokens.
+func (c *GeminiClient) Verify(ctx context.Context) error {
+	if _, err := c.model.Info(ctx); err != nil {
+		return fmt.Errorf("API call error: %w", c.providerError(err))
+	}
+	return nil
+}
+
+// ListModels implements the ModelLister interface method: the names of the models
+// that can generate content, without their "models/" prefix.
+func (c *GeminiClient) ListModels(ctx context.Context) ([]string, error) {
+	var models []string
+	it := c.client.ListModels(ctx)
+	for {
+		info, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return models, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list models: %w", c.providerError(err))
+		}
+		if slices.Contains(info.SupportedGenerationMethods, "generateContent") {
+			models = append(models, strings.TrimPrefix(info.Name, "models/"))
+		}
 	}
 }
 
@@ -68,127 +303,988 @@ func (c *GeminiClient) Close() error {
 	return nil
 }
 
-// FindHistoryEntries implements the LLMClient interface method.
-func (c *GeminiClient) FindHistoryEntries(query string, historyContext []history.HistoryEntry) (string, error) {
-	prompt := c.buildFindPrompt(query, historyContext)
+// Find implements the LLMClient interface method.
+func (c *GeminiClient) Find(ctx context.Context, req FindRequest) (FindResponse, error) {
+	return c.find(ctx, req, nil)
+}
+
+// StreamFind implements the StreamingClient interface method.
+func (c *GeminiClient) StreamFind(ctx context.Context, req FindRequest, onChunk ChunkFunc) (FindResponse, error) {
+	return c.find(ctx, req, onChunk)
+}
+
+// Suggest implements the LLMClient interface method.
+func (c *GeminiClient) Suggest(ctx context.Context, req SuggestRequest) (SuggestResponse, error) {
+	return c.suggest(ctx, req, nil)
+}
+
+// StreamSuggest implements the StreamingClient interface method.
+func (c *GeminiClient) StreamSuggest(ctx context.Context, req SuggestRequest, onChunk ChunkFunc) (SuggestResponse, error) {
+	return c.suggest(ctx, req, onChunk)
+}
+
+func (c *GeminiClient) find(ctx context.Context, req FindRequest, onChunk ChunkFunc) (FindResponse, error) {
+	system, prompt := c.buildFindPrompt(req)
 	if prompt == "" {
-		return "(No relevant commands found or AI response was empty)", nil
+		return FindResponse{CommandAnswer{Message: NoMatchesResponse}}, nil
 	}
 
-	result, err := c.generateGeminiContent(context.Background(), prompt)
+	call := geminiCall{system: system, prompt: prompt, options: req.Options}
+	var result string
+	var err error
+	if c.candidates > 1 && !c.toolsEnabled() {
+		pick := func(answers []string) string { return selectFound(answers, req.History) }
+		result, err = c.generateBest(ctx, call, pick, onChunk)
+	} else {
+		result, err = c.generateWithTools(ctx, call, onChunk)
+	}
 	if err != nil {
-		c.logger.Error("Gemini content generation failed for FindHistoryEntries", zap.Error(err))
-		return "", fmt.Errorf("gemini API call failed (Find): %w", err)
+		c.logCallError("Gemini content generation failed for Find", err)
+		return FindResponse{}, fmt.Errorf("gemini API call failed (Find): %w", err)
 	}
 
-	if result == "" || result == "No relevant commands found." {
+	if result == "" {
+		return FindResponse{emptyCommandAnswer()}, nil
+	}
+	if result == noMatchesAnswer {
 		c.logger.Info("Gemini indicated no relevant commands found for the query.")
-		return "(No relevant commands found or AI response was empty)", nil
 	}
 
-	return result, nil
+	return FindResponse{newCommandAnswer(result, req.History, NoMatchesResponse)}, nil
 }
 
-// SuggestCommands implements the LLMClient interface method.
-func (c *GeminiClient) SuggestCommands(taskDescription string, historyContext []history.HistoryEntry) (string, error) {
-	prompt := c.buildSuggestPrompt(taskDescription, historyContext)
+func (c *GeminiClient) suggest(ctx context.Context, req SuggestRequest, onChunk ChunkFunc) (SuggestResponse, error) {
+	system, prompt := c.buildSuggestPrompt(req)
 
-	result, err := c.generateGeminiContent(context.Background(), prompt)
+	call := geminiCall{system: system, prompt: prompt, options: req.Options}
+	var result string
+	var err error
+	if c.candidates > 1 {
+		result, err = c.generateBest(ctx, call, selectSuggestion, onChunk)
+	} else {
+		result, err = c.generate(ctx, call, onChunk)
+	}
 	if err != nil {
-		c.logger.Error("Gemini content generation failed for SuggestCommands", zap.Error(err))
-		if strings.Contains(err.Error(), "blocked due to safety settings") {
-			return "", errors.New("suggestion blocked due to safety settings") // Return specific user-friendly error
+		c.logCallError("Gemini content generation failed for Suggest", err)
+		if errors.Is(err, ErrBlocked) {
+			// Return specific user-friendly error, keeping the reason and which threshold to lower
+			_, details, _ := strings.Cut(err.Error(), ErrBlocked.Error())
+			return SuggestResponse{}, fmt.Errorf("suggestion %w%s", ErrBlocked, details)
 		}
-		return "", fmt.Errorf("gemini API call failed (Suggest): %w", err)
+		return SuggestResponse{}, fmt.Errorf("gemini API call failed (Suggest): %w", err)
 	}
 
-	if result == "" || result == "Cannot suggest a command for this task." {
+	if result == "" {
+		return SuggestResponse{emptyCommandAnswer()}, nil
+	}
+	if result == cannotSuggestAnswer {
 		c.logger.Info("Gemini indicated it cannot suggest a command for the task.")
-		return "(AI could not suggest a command for this task or the response was empty)", nil
+	}
+
+	return SuggestResponse{newCommandAnswer(result, req.History, CannotSuggestResponse)}, nil
+}
+
+// ExplainCommand implements the LLMClient interface method.
+func (c *GeminiClient) ExplainCommand(ctx context.Context, req ExplainRequest) (string, error) {
+	prompt := c.buildExplainPrompt(req.Command, lastEntries(req.History, req.Options.HistoryLimit))
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for ExplainCommand", err)
+		return "", fmt.Errorf("gemini API call failed (Explain): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no explanation for the command.")
+	}
+
+	return result, nil
+}
+
+// FixCommand implements the LLMClient interface method.
+func (c *GeminiClient) FixCommand(ctx context.Context, req FixRequest) (CommandAnswer, error) {
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	prompt := c.buildFixPrompt(req.Failed, historyContext)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for FixCommand", err)
+		return CommandAnswer{}, fmt.Errorf("gemini API call failed (Fix): %w", err)
+	}
+
+	if result == "" {
+		return emptyCommandAnswer(), nil
+	}
+	if result == cannotFixAnswer {
+		c.logger.Info("Gemini indicated it cannot fix the command.")
+	}
+
+	return newCommandAnswer(result, historyContext, CannotFixResponse), nil
+}
+
+// DiagnoseFailure implements the LLMClient interface method.
+func (c *GeminiClient) DiagnoseFailure(ctx context.Context, req FixRequest) (string, error) {
+	prompt := c.buildWhyPrompt(req.Failed, lastEntries(req.History, req.Options.HistoryLimit))
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for DiagnoseFailure", err)
+		return "", fmt.Errorf("gemini API call failed (Why): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no diagnosis for the failure.")
+	}
+
+	return result, nil
+}
+
+// PredictNext implements the LLMClient interface method.
+func (c *GeminiClient) PredictNext(ctx context.Context, req NextRequest) (CommandAnswer, error) {
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	prompt := c.buildNextPrompt(req.Recent, historyContext)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for PredictNext", err)
+		return CommandAnswer{}, fmt.Errorf("gemini API call failed (Next): %w", err)
+	}
+
+	if result == "" {
+		return emptyCommandAnswer(), nil
+	}
+	if result == cannotPredictAnswer {
+		c.logger.Info("Gemini indicated it cannot predict the next command.")
+	}
+
+	return newCommandAnswer(result, slices.Concat(historyContext, req.Recent), CannotPredictResponse), nil
+}
+
+// Chat implements the LLMClient interface method.
+func (c *GeminiClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	prompt := c.buildChatPrompt(req.Messages, lastEntries(req.History, req.Options.HistoryLimit))
+
+	result, err := c.generateWithTools(ctx, geminiCall{prompt: prompt, options: req.Options}, nil)
+	if err != nil {
+		c.logCallError("Gemini content generation failed for Chat", err)
+		return "", fmt.Errorf("gemini API call failed (Chat): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned an empty chat response.")
+	}
+
+	return result, nil
+}
+
+// AnswerQuestion implements the LLMClient interface method.
+func (c *GeminiClient) AnswerQuestion(ctx context.Context, req AskRequest) (string, error) {
+	prompt := c.buildAskPrompt(req.Question, lastEntries(req.History, req.Options.HistoryLimit))
+
+	result, err := c.generateWithTools(ctx, geminiCall{prompt: prompt, options: req.Options}, nil)
+	if err != nil {
+		c.logCallError("Gemini content generation failed for AnswerQuestion", err)
+		return "", fmt.Errorf("gemini API call failed (Ask): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no answer for the question.")
 	}
 
 	return result, nil
 }
 
+// SummarizeActivity implements the LLMClient interface method.
+func (c *GeminiClient) SummarizeActivity(ctx context.Context, req SummaryRequest) (string, error) {
+	prompt := c.buildSummaryPrompt(req.Period, req.Groups)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for SummarizeActivity", err)
+		return "", fmt.Errorf("gemini API call failed (Summarize): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned an empty summary.")
+	}
+
+	return result, nil
+}
+
+// FillSnippet implements the LLMClient interface method.
+func (c *GeminiClient) FillSnippet(ctx context.Context, req FillRequest) (map[string]string, error) {
+	prompt := c.buildFillPrompt(req.Snippet, req.Description, lastEntries(req.History, req.Options.HistoryLimit))
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for FillSnippet", err)
+		return nil, fmt.Errorf("gemini API call failed (Fill): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no placeholder values.")
+		return parsePlaceholderValues(req.Snippet, "{}")
+	}
+
+	return parsePlaceholderValues(req.Snippet, result)
+}
+
+// WriteScript implements the LLMClient interface method.
+func (c *GeminiClient) WriteScript(ctx context.Context, req ScriptRequest) (string, error) {
+	prompt := c.buildScriptPrompt(req.Commands, req.Purpose)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for WriteScript", err)
+		return "", fmt.Errorf("gemini API call failed (Script): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned an empty script.")
+		return "", nil
+	}
+
+	return stripCodeFence(result), nil
+}
+
+// WriteBuildTargets implements the LLMClient interface method.
+func (c *GeminiClient) WriteBuildTargets(ctx context.Context, req TargetsRequest) (string, error) {
+	prompt := c.buildTargetsPrompt(req.Workflows, req.Format)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for WriteBuildTargets", err)
+		return "", fmt.Errorf("gemini API call failed (Targets): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no build targets.")
+		return "", nil
+	}
+
+	return stripCodeFence(result), nil
+}
+
+// WriteQuiz implements the LLMClient interface method.
+func (c *GeminiClient) WriteQuiz(ctx context.Context, req QuizRequest) ([]QuizQuestion, error) {
+	prompt := c.buildQuizPrompt(req.Commands, req.Count)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for WriteQuiz", err)
+		return nil, fmt.Errorf("gemini API call failed (Quiz): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no quiz questions.")
+		return nil, nil
+	}
+
+	return parseQuizQuestions(result)
+}
+
+// GradeAnswer implements the LLMClient interface method.
+func (c *GeminiClient) GradeAnswer(ctx context.Context, req GradeRequest) (QuizGrade, error) {
+	prompt := c.buildGradePrompt(req.Question, req.Answer)
+
+	result, err := c.generateGeminiContent(ctx, geminiCall{prompt: prompt, options: req.Options})
+	if err != nil {
+		c.logCallError("Gemini content generation failed for GradeAnswer", err)
+		return QuizGrade{}, fmt.Errorf("gemini API call failed (Grade): %w", err)
+	}
+
+	if result == "" {
+		c.logger.Info("Gemini returned no grade.")
+		return QuizGrade{}, nil
+	}
+
+	return parseQuizGrade(result)
+}
+
+// geminiCall is a request to Gemini: the prompt, the request's own system
+// instruction, if any, sent before the one set with the prompt.system config key,
+// and the request's options.
+type geminiCall struct {
+	system  string
+	prompt  string
+	options Options
+}
+
+// generate calls generateGeminiContent, or streamGeminiContent when onChunk is set.
+func (c *GeminiClient) generate(ctx context.Context, call geminiCall, onChunk ChunkFunc) (string, error) {
+	if onChunk != nil {
+		return c.streamGeminiContent(ctx, call, onChunk)
+	}
+	return c.generateGeminiContent(ctx, call)
+}
+
 // generateGeminiContent calls the Gemini API and handles common error/safety checks.
-func (c *GeminiClient) generateGeminiContent(ctx context.Context, prompt string) (string, error) {
-	resp, err := c.model.GenerateContent(ctx, genai.Text(prompt))
+func (c *GeminiClient) generateGeminiContent(ctx context.Context, call geminiCall) (string, error) {
+	return c.retrying(ctx, call, func(model *genai.GenerativeModel, prompt string) (string, error) {
+		resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+
+		// 1. Check for API call error (network, auth, etc.) and safety blocks
+		if err := c.checkResponse(resp, err); err != nil {
+			return "", err
+		}
+
+		c.recordUsage(resp)
+
+		// 2. Extract text response
+		aiResponseText := extractTextFromResponse(resp)
+		if aiResponseText == "" {
+			c.logger.Warn("Received empty text response from Gemini")
+			return "", nil
+		}
+
+		return aiResponseText, nil
+	})
+}
+
+// generateBest asks Gemini for c.candidates answers at once and returns the one pick
+// makes of them, passing it to onChunk in one piece, as the candidates arrive
+// interleaved when streamed.
+func (c *GeminiClient) generateBest(ctx context.Context, call geminiCall, pick func(answers []string) string, onChunk ChunkFunc) (string, error) {
+	result, err := c.retrying(ctx, call, func(model *genai.GenerativeModel, prompt string) (string, error) {
+		candidates := *model
+		candidates.SetCandidateCount(int32(c.candidates))
+		resp, err := candidates.GenerateContent(ctx, genai.Text(prompt))
 
+		// 1. Check for API call error (network, auth, etc.) and safety blocks
+		if err := c.checkResponse(resp, err); err != nil {
+			return "", err
+		}
+
+		c.recordUsage(resp)
+
+		// 2. Pick the best of the non-empty answers
+		var answers []string
+		for _, candidate := range resp.Candidates {
+			if text := candidateText(candidate); strings.TrimSpace(text) != "" {
+				answers = append(answers, text)
+			}
+		}
+		c.logger.Debug("Received Gemini candidates", zap.Int("requested", c.candidates), zap.Int("answers", len(answers)))
+		if len(answers) == 0 {
+			c.logger.Warn("Received empty text response from Gemini")
+			return "", nil
+		}
+		return pick(answers), nil
+	})
+	if err == nil && result != "" && onChunk != nil {
+		onChunk(result)
+	}
+	return result, err
+}
+
+// streamGeminiContent calls the Gemini streaming API, passing each piece of text to
+// onChunk as it arrives, and returns the concatenated response.
+func (c *GeminiClient) streamGeminiContent(ctx context.Context, call geminiCall, onChunk ChunkFunc) (string, error) {
+	return c.retrying(ctx, call, func(model *genai.GenerativeModel, prompt string) (string, error) {
+		iter := model.GenerateContentStream(ctx, genai.Text(prompt))
+
+		var result strings.Builder
+		for {
+			resp, err := iter.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+
+			// 1. Check each chunk for API call errors and safety blocks. The text
+			// passed on already is returned too, so that it isn't retried.
+			if err := c.checkResponse(resp, err); err != nil {
+				return result.String(), err
+			}
+
+			// The last chunk carries the usage of the whole response.
+			c.recordUsage(resp)
+
+			// 2. Hand the new text to the caller
+			if text := extractTextFromResponse(resp); text != "" {
+				result.WriteString(text)
+				onChunk(text)
+			}
+		}
+
+		if result.Len() == 0 {
+			c.logger.Warn("Received empty text response from Gemini")
+		}
+		return result.String(), nil
+	})
+}
+
+// retrying shows the prompt of call to the prompt hook and calls generate with it.
+// If the response was empty, or blocked by the safety filters, it tries once more
+// with the prompt reworded to say so, at a higher temperature, as a different answer
+// may be fine. Blocked prompts aren't retried: the same prompt would be blocked again.
+func (c *GeminiClient) retrying(ctx context.Context, call geminiCall, generate func(model *genai.GenerativeModel, prompt string) (string, error)) (string, error) {
+	if err := c.observePrompt(call.system, call.prompt); err != nil {
+		return "", err
+	}
+	model := c.modelFor(call)
+	result, err := generate(model, call.prompt)
+
+	var note string
+	switch {
+	case result == "" && errors.Is(err, errResponseBlocked):
+		note = retryBlockedNote
+	case err == nil && strings.TrimSpace(result) == "":
+		note = retryEmptyNote
+	default:
+		return result, err
+	}
+	if ctx.Err() != nil {
+		return result, err
+	}
+
+	c.logger.Info("Retrying Gemini request after an empty or blocked response", zap.NamedError("blocked", err))
+	retry := *model
+	retry.SetTemperature(retryTemperature)
+	prompt := call.prompt + note
+	if err := c.observePrompt(call.system, prompt); err != nil {
+		return "", err
+	}
+	return generate(&retry, prompt)
+}
+
+// SetPromptHook implements the PromptObserver interface method.
+func (c *GeminiClient) SetPromptHook(hook PromptHook) {
+	c.promptHook = hook
+}
+
+// observePrompt passes prompt to the prompt hook, if any, preceded by the system
+// instruction sent along with it, each under its own heading.
+func (c *GeminiClient) observePrompt(system, prompt string) error {
+	if c.promptHook == nil {
+		return nil
+	}
+	if system = c.systemInstruction(system); system != "" {
+		prompt = "System instruction:\n" + system + "\n\nPrompt:\n" + prompt
+	}
+	return c.promptHook(prompt)
+}
+
+// systemInstruction returns the system instruction of a request: its own, if any,
+// followed by the one set with the prompt.system config key.
+func (c *GeminiClient) systemInstruction(system string) string {
+	switch {
+	case system == "":
+		return c.system
+	case c.system == "":
+		return system
+	}
+	return system + "\n\n" + c.system
+}
+
+// modelFor returns the model to send call with: a copy of the client's model, as the
+// daemon serves requests concurrently, with the call's system instruction and
+// options. Keeping the instructions in the system instruction rather than in the
+// prompt makes the model follow them more closely, and keeps them cacheable across
+// requests.
+func (c *GeminiClient) modelFor(call geminiCall) *genai.GenerativeModel {
+	model := *c.model
+	if name := call.options.Model; name != "" && name != c.modelName {
+		other := c.client.GenerativeModel(name)
+		other.GenerationConfig = model.GenerationConfig
+		other.SafetySettings = model.SafetySettings
+		other.SystemInstruction = model.SystemInstruction
+		model = *other
+	}
+	if call.system != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(c.systemInstruction(call.system))}}
+	}
+	if call.options.Temperature != nil {
+		model.SetTemperature(*call.options.Temperature)
+	}
+	if call.options.MaxOutputTokens > 0 {
+		model.SetMaxOutputTokens(int32(call.options.MaxOutputTokens))
+	}
+	return &model
+}
+
+// logCallError logs a failed API call. A call cancelled by the prompt hook, as for a
+// dry run, is not a failure. Unreachable providers are only logged for debugging, as
+// find and suggest fall back to searching locally.
+func (c *GeminiClient) logCallError(msg string, err error) {
+	switch {
+	case errors.Is(err, ErrDryRun):
+		return
+	case IsUnavailable(err), errors.Is(err, ErrBlocked), errors.As(err, new(*ProviderError)):
+		// Blocks were logged by checkResponse, and all are explained to the user.
+		c.logger.Debug(msg, zap.Error(err))
+		return
+	}
+	c.logger.Error(msg, zap.Error(err))
+}
+
+// recordUsage remembers the token usage reported with resp, if any.
+func (c *GeminiClient) recordUsage(resp *genai.GenerateContentResponse) {
+	if resp == nil || resp.UsageMetadata == nil {
+		return
+	}
+	c.setUsage(Usage{
+		PromptTokens: int(resp.UsageMetadata.PromptTokenCount),
+		OutputTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+	})
+}
+
+// setUsage remembers usage as that of the most recent call.
+func (c *GeminiClient) setUsage(usage Usage) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	c.lastUsage = &usage
+}
+
+// LastUsage implements the UsageReporter interface method.
+func (c *GeminiClient) LastUsage() (Usage, bool) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	if c.lastUsage == nil {
+		return Usage{}, false
+	}
+	return *c.lastUsage, true
+}
+
+// checkResponse converts API call errors and safety blocks into errors.
+func (c *GeminiClient) checkResponse(resp *genai.GenerateContentResponse, err error) error {
 	// 1. Check for API call error (network, auth, etc.)
 	if err != nil {
 		if resp != nil && resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason == genai.BlockReasonSafety {
 			c.logger.Warn("Prompt blocked by safety settings during API call", zap.Any("feedback", resp.PromptFeedback))
-			return "", fmt.Errorf("prompt blocked due to safety settings (Reason: %s)", resp.PromptFeedback.BlockReason.String())
+			return fmt.Errorf("prompt %w (Reason: %s)%s", ErrBlocked, resp.PromptFeedback.BlockReason.String(), blockedHint(resp.PromptFeedback.SafetyRatings))
+		}
+		// The genai client reports blocks it notices itself as BlockedError.
+		var blocked *genai.BlockedError
+		if errors.As(err, &blocked) {
+			switch {
+			case blocked.PromptFeedback != nil:
+				c.logger.Warn("Prompt blocked by safety settings", zap.Any("feedback", blocked.PromptFeedback))
+				return fmt.Errorf("prompt %w (Reason: %s)%s", ErrBlocked, blocked.PromptFeedback.BlockReason.String(), blockedHint(blocked.PromptFeedback.SafetyRatings))
+			case blocked.Candidate != nil && blocked.Candidate.FinishReason == genai.FinishReasonSafety:
+				c.logger.Warn("Response candidate blocked by safety settings", zap.Any("candidate", blocked.Candidate))
+				return fmt.Errorf("response %w%s", errResponseBlocked, blockedHint(blocked.Candidate.SafetyRatings))
+			}
 		}
-		return "", fmt.Errorf("API call error: %w", err)
+		return fmt.Errorf("API call error: %w", c.providerError(err))
 	}
 
 	// 2. Check for safety block in prompt feedback (even if err is nil)
 	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason == genai.BlockReasonSafety {
 		c.logger.Warn("Prompt blocked by safety settings", zap.Any("feedback", resp.PromptFeedback))
-		return "", fmt.Errorf("prompt blocked due to safety settings (Reason: %s)", resp.PromptFeedback.BlockReason.String())
+		return fmt.Errorf("prompt %w (Reason: %s)%s", ErrBlocked, resp.PromptFeedback.BlockReason.String(), blockedHint(resp.PromptFeedback.SafetyRatings))
 	}
 
 	// 3. Check for safety block in candidate finish reason
 	if len(resp.Candidates) > 0 && resp.Candidates[0].FinishReason == genai.FinishReasonSafety {
 		c.logger.Warn("Response candidate blocked by safety settings", zap.Any("candidate", resp.Candidates[0]))
-		return "", errors.New("response blocked due to safety settings")
+		return fmt.Errorf("response %w%s", errResponseBlocked, blockedHint(resp.Candidates[0].SafetyRatings))
 	}
+	return nil
+}
 
-	// 4. Extract text response
-	aiResponseText := extractTextFromResponse(resp)
-	if aiResponseText == "" {
-		c.logger.Warn("Received empty text response from Gemini (or response was blocked)")
-		return "", nil
+// buildFindPrompt constructs the system instruction and prompt for finding history
+// entries.
+func (c *GeminiClient) buildFindPrompt(req FindRequest) (system, prompt string) {
+	historyContext := lastEntries(withoutTeam(req.History), req.Options.HistoryLimit)
+	team := teamSnippets(req.History)
+	if len(historyContext) == 0 && len(team) == 0 {
+		c.logger.Warn("Cannot build find prompt: history context is empty")
+		return "", ""
 	}
 
-	return aiResponseText, nil
+	return c.templates.Render(TemplateFind, PromptData{
+		Query:        req.Query,
+		History:      lastEntries(historyContext, findHistoryContextLimit),
+		Team:         team,
+		Annotated:    hasAnnotations(historyContext),
+		HasHosts:     history.HasHosts(historyContext),
+		Anonymized:   anonymized(req.History),
+		Instructions: c.instructions,
+		Language:     c.language,
+		Shell:        c.shell,
+	})
 }
 
-// buildFindPrompt constructs the prompt string for finding history entries.
-func (c *GeminiClient) buildFindPrompt(query string, historyContext []history.HistoryEntry) string {
-	if len(historyContext) == 0 {
-		c.logger.Warn("Cannot build find prompt: history context is empty")
-		return ""
+// buildSuggestPrompt constructs the system instruction and prompt for generating
+// command suggestions.
+func (c *GeminiClient) buildSuggestPrompt(req SuggestRequest) (system, prompt string) {
+	historyContext := lastEntries(withoutTeam(req.History), req.Options.HistoryLimit)
+	return c.templates.Render(TemplateSuggest, PromptData{
+		Query:        req.Task,
+		History:      lastEntries(historyContext, suggestHistoryContextLimit),
+		Saved:        pinnedEntries(req.History),
+		Team:         teamSnippets(req.History),
+		Workflows:    req.Workflows,
+		Environment:  req.Environment,
+		Annotated:    hasAnnotations(historyContext),
+		HasHosts:     history.HasHosts(historyContext),
+		Anonymized:   anonymized(req.History),
+		Instructions: c.instructions,
+		Language:     c.language,
+		Shell:        c.shell,
+	})
+}
+
+// buildExplainPrompt constructs the prompt for explaining a command.
+func (c *GeminiClient) buildExplainPrompt(command string, historyContext []history.HistoryEntry) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an expert in Unix shells and command-line tools.\n")
+	promptBuilder.WriteString("Explain the following shell command to the user:\n")
+	promptBuilder.WriteString(fmt.Sprintf("Command: `%s`\n\n", command))
+
+	promptBuilder.WriteString(formatHistoryContext("How the user previously ran the same program (Optional)", historyContext, explainHistoryContextLimit))
+
+	promptBuilder.WriteString("Instructions for the explanation:\n")
+	promptBuilder.WriteString("1. Start with a one-sentence summary of what the command does.\n")
+	promptBuilder.WriteString("2. Then explain each part (program, subcommand, every flag and argument, pipes and redirections) as a markdown list, one item per part.\n")
+	promptBuilder.WriteString("3. If the command can modify or delete data, or needs elevated privileges, say so in a short **Warning** line.\n")
+	promptBuilder.WriteString("4. If previous uses are provided, briefly point out how this invocation differs from how the user usually runs the program.\n")
+	promptBuilder.WriteString("5. Respond in markdown. Keep the explanation concise.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Explanation:\n")
+
+	return promptBuilder.String()
+}
+
+// buildFixPrompt constructs the prompt for correcting a failed command.
+func (c *GeminiClient) buildFixPrompt(failed history.HistoryEntry, historyContext []history.HistoryEntry) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an expert in Unix shells and command-line tools.\n")
+	promptBuilder.WriteString("The user ran the following shell command and it failed:\n")
+	promptBuilder.WriteString(formatFailure(failed))
+
+	promptBuilder.WriteString(formatHistoryContext("How the user previously ran the same program successfully (Optional)", historyContext, explainHistoryContextLimit))
+
+	promptBuilder.WriteString("Instructions for the fix:\n")
+	promptBuilder.WriteString("1. Find the most likely mistake (typo in the program or subcommand, wrong or misspelled flag, wrong argument order, missing quoting, etc.).\n")
+	promptBuilder.WriteString("2. Keep everything that was not wrong exactly as the user typed it.\n")
+	promptBuilder.WriteString("3. Respond with exactly two lines: first a comment line starting with `# ` that briefly says what was wrong, then the corrected command on its own line. No markdown, no code fences.\n")
+	promptBuilder.WriteString("4. If you cannot tell what is wrong, respond with the exact phrase: 'Cannot fix this command.'\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Fix:\n")
+
+	return promptBuilder.String()
+}
+
+// buildNextPrompt constructs the prompt for predicting the next command in a workflow.
+func (c *GeminiClient) buildNextPrompt(recent []history.HistoryEntry, historyContext []history.HistoryEntry) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an AI assistant expert in shell workflows.\n")
+	promptBuilder.WriteString("Predict the command the user is most likely to run next, given the commands they just ran.\n\n")
+
+	promptBuilder.WriteString(formatHistoryContext("Earlier Shell History (to learn the user's usual sequences)", historyContext, nextHistoryContextLimit))
+	promptBuilder.WriteString(formatHistoryContext("Commands Just Run (oldest first)", recent, 0))
+
+	promptBuilder.WriteString("Instructions for predicting the next command:\n")
+	promptBuilder.WriteString("1. Look for sequences in the earlier history that match the commands just run (e.g. `docker build` usually followed by `docker tag` and `docker push`), and continue the workflow.\n")
+	promptBuilder.WriteString("2. Reuse the concrete arguments (image names, branches, paths) from the commands just run where they apply.\n")
+	promptBuilder.WriteString("3. List up to 3 candidate commands, most likely first, each on a new line, with a brief `#` comment line before each one saying why.\n")
+	promptBuilder.WriteString("4. Provide ONLY the comment and command lines, no other text or formatting.\n")
+	promptBuilder.WriteString("5. If there is no recognizable workflow to continue, respond with the exact phrase: 'Cannot predict the next command.'\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Next Command(s):\n")
+
+	return promptBuilder.String()
+}
+
+// buildChatPrompt constructs the prompt for the next turn of a chat conversation.
+func (c *GeminiClient) buildChatPrompt(messages []ChatMessage, historyContext []history.HistoryEntry) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an AI assistant that helps the user find commands in their shell history, and suggests shell commands.\n")
+	promptBuilder.WriteString("You are in a multi-turn conversation with the user.\n\n")
+
+	promptBuilder.WriteString(formatHistoryContext("User's Shell History (oldest first)", historyContext, chatHistoryContextLimit))
+
+	promptBuilder.WriteString("Instructions for answering:\n")
+	promptBuilder.WriteString("1. Answer the user's latest message. Later messages refine earlier ones (e.g. 'no, the one that used rsync over ssh'), so keep the whole conversation in mind.\n")
+	promptBuilder.WriteString("2. Prefer commands from the user's history, copied exactly. Say so when you suggest a command that is not in the history.\n")
+	promptBuilder.WriteString("3. Put every command in a ```bash code block, one command per line.\n")
+	promptBuilder.WriteString("4. Respond in markdown. Keep it concise.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Conversation:\n")
+	for _, message := range messages {
+		role := "User"
+		if message.Role == RoleAssistant {
+			role = "Assistant"
+		}
+		promptBuilder.WriteString(fmt.Sprintf("%s: %s\n\n", role, message.Content))
+	}
+	promptBuilder.WriteString("Assistant:\n")
+
+	return promptBuilder.String()
+}
+
+// buildAskPrompt constructs the prompt for answering a question about the user's history.
+func (c *GeminiClient) buildAskPrompt(question string, historyContext []history.HistoryEntry) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an AI assistant that analyzes a user's shell command history.\n")
+	promptBuilder.WriteString("The user asks the following question about their history:\n")
+	promptBuilder.WriteString(fmt.Sprintf("Question: \"%s\"\n", question))
+	promptBuilder.WriteString(fmt.Sprintf("Current date: %s\n\n", time.Now().Format("2006-01-02")))
+
+	promptBuilder.WriteString(formatTimedHistoryContext("User's Shell History (oldest first; time | directory | command)", historyContext, askHistoryContextLimit))
+
+	promptBuilder.WriteString("Instructions for answering:\n")
+	promptBuilder.WriteString("1. Answer the question by reasoning over the history above, e.g. counting, grouping or comparing commands. Do not invent commands that are not in the history.\n")
+	promptBuilder.WriteString("2. Use the times and directories when the question is about when or where something was run. Entries without a time cannot be placed in time; say so if it matters.\n")
+	promptBuilder.WriteString("3. If the history does not contain enough information to answer, say so plainly.\n")
+	promptBuilder.WriteString("4. Respond in markdown, putting commands in `backticks`. Keep the answer concise.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Answer:\n")
+
+	return promptBuilder.String()
+}
+
+// buildSummaryPrompt constructs the prompt for summarizing the user's work from their commands.
+func (c *GeminiClient) buildSummaryPrompt(period string, groups []ActivityGroup) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an AI assistant that writes short work summaries for standups and timesheets.\n")
+	promptBuilder.WriteString(fmt.Sprintf("Below are the shell commands the user ran %s, grouped by project or directory.\n\n", period))
+
+	for _, group := range groups {
+		project := group.Project
+		if project == "" {
+			project = "(unknown directory)"
+		}
+		promptBuilder.WriteString(formatTimedHistoryContext("Project "+project, group.Entries, summaryGroupLimit))
 	}
 
+	promptBuilder.WriteString("Instructions for the summary:\n")
+	promptBuilder.WriteString("1. For each project, write one to three bullet points describing what the user worked on (e.g. 'Fixed failing tests in the billing service', 'Deployed v1.4 to staging'), inferred from the commands. Do not list the commands themselves.\n")
+	promptBuilder.WriteString("2. Use the project's directory name as a markdown heading. Skip projects with only trivial navigation commands (cd, ls, clear).\n")
+	promptBuilder.WriteString("3. Mention the approximate time spent when the timestamps make it clear.\n")
+	promptBuilder.WriteString("4. Respond in markdown. Keep the whole summary short enough to read out at a standup.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Summary:\n")
+
+	return promptBuilder.String()
+}
+
+// buildWhyPrompt constructs the prompt for diagnosing a failed command.
+func (c *GeminiClient) buildWhyPrompt(failed history.HistoryEntry, historyContext []history.HistoryEntry) string {
 	var promptBuilder strings.Builder
-	promptBuilder.WriteString("You are an expert shell history analyzer.\n")
-	promptBuilder.WriteString("The user is searching their shell history for commands based on a description.\n")
-	promptBuilder.WriteString(fmt.Sprintf("User's search query: \"%s\"\n\n", query))
-	promptBuilder.WriteString("Please analyze the following shell history entries. Return ONLY the command text of the entry or entries that BEST match the user's query. If multiple commands are good matches, list each matching command on a new line.\n")
-	promptBuilder.WriteString("If NO history entries strongly match the query, return the exact phrase: 'No relevant commands found.'\n\n")
 
-	promptBuilder.WriteString(formatHistoryContext("Shell History Entries Provided", historyContext, findHistoryContextLimit))
+	promptBuilder.WriteString("You are an expert in Unix shells, command-line tools and troubleshooting.\n")
+	promptBuilder.WriteString("The user ran the following shell command and it failed:\n")
+	promptBuilder.WriteString(formatFailure(failed))
+
+	promptBuilder.WriteString(formatHistoryContext("How the user previously ran the same program (Optional)", historyContext, explainHistoryContextLimit))
+
+	promptBuilder.WriteString("Instructions for the diagnosis:\n")
+	promptBuilder.WriteString("1. Explain in one or two sentences the most likely cause of the failure, based on the real error output when it is provided.\n")
+	promptBuilder.WriteString("2. List concrete steps to resolve it. Put any commands to run in ```bash code blocks, one command per line.\n")
+	promptBuilder.WriteString("3. If the cause cannot be determined from the information given, say what additional information would help.\n")
+	promptBuilder.WriteString("4. Respond in markdown. Keep it concise.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
 
-	promptBuilder.WriteString("Matching command(s) from the history above:\n")
+	promptBuilder.WriteString("Diagnosis:\n")
 
 	return promptBuilder.String()
 }
 
-// buildSuggestPrompt constructs the prompt for generating command suggestions.
-func (c *GeminiClient) buildSuggestPrompt(taskDescription string, historyContext []history.HistoryEntry) string {
+// buildFillPrompt constructs the prompt for filling in the placeholders of a snippet.
+func (c *GeminiClient) buildFillPrompt(snippet Snippet, description string, historyContext []history.HistoryEntry) string {
 	var promptBuilder strings.Builder
 
-	promptBuilder.WriteString("You are an AI assistant expert in generating safe and useful POSIX-compliant shell commands (like for Linux or macOS).\n")
-	promptBuilder.WriteString("The user wants a shell command to accomplish the following task:\n")
-	promptBuilder.WriteString(fmt.Sprintf("Task: \"%s\"\n\n", taskDescription))
+	promptBuilder.WriteString("You are an expert in Unix shells and command-line tools.\n")
+	promptBuilder.WriteString("The user keeps the following command template. Words in {braces} are placeholders to fill in:\n")
+	promptBuilder.WriteString(fmt.Sprintf("Template: `%s`\n", snippet.Command))
+	if snippet.Description != "" {
+		promptBuilder.WriteString(fmt.Sprintf("Template description: %s\n", snippet.Description))
+	}
+	promptBuilder.WriteString(fmt.Sprintf("Placeholders: %s\n", strings.Join(snippet.Placeholders, ", ")))
+	promptBuilder.WriteString(fmt.Sprintf("The user wants to: \"%s\"\n\n", description))
 
-	promptBuilder.WriteString(formatHistoryContext("Recent History Context (Optional)", historyContext, suggestHistoryContextLimit))
+	promptBuilder.WriteString(formatHistoryContext("User's Recent Shell History (Optional)", historyContext, fillHistoryContextLimit))
 
-	promptBuilder.WriteString("Instructions for generating the command:\n")
-	promptBuilder.WriteString("1. Generate one or more shell commands that directly address the user's task.\n")
-	promptBuilder.WriteString("2. **Prioritize Safety:** Avoid suggesting potentially destructive commands (like `rm -rf /`, `dd`, etc.) unless absolutely necessary for the task AND explicitly confirmed by the user's request phrasing. If suggesting a command with potential side effects (e.g., modifying files, deleting data), add a brief `# Warning: This command modifies/deletes...` comment before it.\n")
-	promptBuilder.WriteString("3. Provide ONLY the raw command(s), each on a new line.\n")
-	promptBuilder.WriteString("4. If multiple steps or commands are needed, list them sequentially.\n")
-	promptBuilder.WriteString("5. If the task is ambiguous, too complex for a simple command, or cannot be safely achieved, respond with the exact phrase: 'Cannot suggest a command for this task.'\n\n")
+	promptBuilder.WriteString("Instructions for filling in the template:\n")
+	promptBuilder.WriteString("1. Choose a value for each placeholder so that the command does what the user wants.\n")
+	promptBuilder.WriteString("2. Prefer the exact hosts, ports, paths and names that appear in the user's history for the same purpose over generic guesses.\n")
+	promptBuilder.WriteString("3. If a value cannot be determined from the description or the history, use an empty string. Never invent hostnames, credentials or secrets.\n")
+	promptBuilder.WriteString("4. Give raw values: they are inserted into the template verbatim, so don't add quotes unless the value needs shell quoting at that position.\n")
+	promptBuilder.WriteString("5. Respond with only a JSON object mapping every placeholder name to its value, e.g. {\"host\": \"db.staging.internal\", \"port\": \"5432\"}. No markdown and no explanation.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
 
-	promptBuilder.WriteString("Suggested Command(s):\n")
+	promptBuilder.WriteString("JSON:\n")
 
 	return promptBuilder.String()
 }
 
-// formatHistoryContext formats the history entries for inclusion in a prompt.
+// buildScriptPrompt constructs the prompt for turning commands into a shell script.
+func (c *GeminiClient) buildScriptPrompt(commands []history.HistoryEntry, purpose string) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are an expert in Unix shells who writes clean, reusable shell scripts.\n")
+	promptBuilder.WriteString("The user ran the commands below, oldest first, and wants to turn them into a script.\n")
+	if purpose != "" {
+		promptBuilder.WriteString(fmt.Sprintf("What the script is for: \"%s\"\n", purpose))
+	}
+	promptBuilder.WriteString("\n")
+
+	promptBuilder.WriteString(formatScriptCommands(commands))
+
+	promptBuilder.WriteString("Instructions for the script:\n")
+	promptBuilder.WriteString("1. Start with `#!/usr/bin/env bash` and `set -euo pipefail`.\n")
+	promptBuilder.WriteString("2. Keep only the commands needed to redo the work, in their original order. Drop duplicates, typos, failed attempts that were retried, and commands that only inspect things (ls, cat, git status, clear, man).\n")
+	promptBuilder.WriteString("3. Preserve the effect of `cd` and of the working directories shown, e.g. with `cd` or `pushd`/`popd` around the commands that need them.\n")
+	promptBuilder.WriteString("4. Move values used several times (hosts, versions, paths) into variables at the top, and make values that vary between runs overridable with `${VAR:-default}`.\n")
+	promptBuilder.WriteString("5. Group the commands into steps with a short `#` comment above each step saying what it does. Do not add steps the user did not run.\n")
+	promptBuilder.WriteString("6. Respond with ONLY the script, without markdown code fences or any text outside the script's comments.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("Script:\n")
+
+	return promptBuilder.String()
+}
+
+// buildQuizPrompt constructs the prompt for writing quiz questions about commands
+// from the user's history.
+func (c *GeminiClient) buildQuizPrompt(commands []history.HistoryEntry, count int) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are a friendly teacher of Unix shells and command-line tools.\n")
+	promptBuilder.WriteString("The user ran the commands below. Quiz them on their own commands, so they learn what they have been typing.\n\n")
+
+	promptBuilder.WriteString("Commands:\n")
+	for _, entry := range commands {
+		promptBuilder.WriteString(fmt.Sprintf("- `%s`\n", entry.Command))
+	}
+	promptBuilder.WriteString("\n")
+
+	promptBuilder.WriteString("Instructions for the quiz:\n")
+	promptBuilder.WriteString(fmt.Sprintf("1. Write %d questions, each about a different command from the list, choosing the commands whose flags and arguments are most worth learning.\n", count))
+	promptBuilder.WriteString("2. Ask about one specific flag, argument or behavior per question, e.g. \"What does -z do in `tar -czf out.tgz dir`?\". Answerable in one sentence; no trick questions.\n")
+	promptBuilder.WriteString("3. Copy each command exactly as listed.\n")
+	promptBuilder.WriteString("4. Give the expected answer in one or two sentences.\n")
+	promptBuilder.WriteString("5. Respond with only a JSON array of objects with the keys \"command\", \"question\" and \"answer\". No markdown and no explanation.\n\n")
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("JSON:\n")
+
+	return promptBuilder.String()
+}
+
+// buildGradePrompt constructs the prompt for grading the user's answer to a quiz question.
+func (c *GeminiClient) buildGradePrompt(question QuizQuestion, answer string) string {
+	var promptBuilder strings.Builder
+
+	promptBuilder.WriteString("You are a friendly teacher of Unix shells and command-line tools, grading a quiz.\n")
+	promptBuilder.WriteString(fmt.Sprintf("Command: `%s`\n", question.Command))
+	promptBuilder.WriteString(fmt.Sprintf("Question: %s\n", question.Question))
+	promptBuilder.WriteString(fmt.Sprintf("Expected answer: %s\n", question.Answer))
+	promptBuilder.WriteString(fmt.Sprintf("The user's answer: \"%s\"\n\n", answer))
+
+	promptBuilder.WriteString("Instructions for grading:\n")
+	promptBuilder.WriteString("1. The answer is correct if it gets the essential point right, even if worded differently, informally or incompletely.\n")
+	promptBuilder.WriteString("2. Give one or two sentences of feedback: confirm what was right, or explain what the flag or argument actually does.\n")
+	promptBuilder.WriteString("3. Respond with only a JSON object with the keys \"correct\" (true or false) and \"feedback\". No markdown and no explanation.\n\n")
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString("JSON:\n")
+
+	return promptBuilder.String()
+}
+
+// buildTargetsPrompt constructs the prompt for turning repeated workflows into build targets.
+func (c *GeminiClient) buildTargetsPrompt(workflows []Workflow, format string) string {
+	var promptBuilder strings.Builder
+
+	fileName := "Makefile"
+	if format == BuildFormatJust {
+		fileName = "justfile"
+	}
+	promptBuilder.WriteString("You are an expert in build automation with make and just.\n")
+	promptBuilder.WriteString(fmt.Sprintf("The user keeps running the following sequences of shell commands by hand. Turn each into a %s target.\n\n", fileName))
+
+	for i, workflow := range workflows {
+		promptBuilder.WriteString(fmt.Sprintf("Sequence %d (run %d times):\n", i+1, workflow.Count))
+		for _, command := range workflow.Commands {
+			promptBuilder.WriteString("  " + command + "\n")
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	promptBuilder.WriteString("Instructions for the targets:\n")
+	promptBuilder.WriteString("1. Write one target per sequence with a short, descriptive kebab-case name (e.g. `deploy-staging`, `reset-db`) and a one-line `#` comment above it saying what it does.\n")
+	promptBuilder.WriteString("2. Keep the commands and their order. Values that differ between runs or repeat across targets (image tags, hosts, versions) become variables at the top of the file.\n")
+	promptBuilder.WriteString("3. Drop `cd` into the project directory, since targets already run there; keep other directory changes within the recipe.\n")
+	if format == BuildFormatJust {
+		promptBuilder.WriteString("4. Use just syntax: `name:` followed by recipe lines indented with four spaces, and `:=` for variables.\n")
+	} else {
+		promptBuilder.WriteString("4. Use GNU make syntax: recipe lines indented with a tab, `.PHONY` for every target, `?=` for variables and `$$` for dollar signs meant for the shell.\n")
+	}
+	promptBuilder.WriteString(fmt.Sprintf("5. Respond with ONLY the %s content, without markdown code fences or any text outside its comments.\n\n", fileName))
+	promptBuilder.WriteString(formatInstructions(c.instructions))
+	promptBuilder.WriteString(formatLanguage(c.language))
+
+	promptBuilder.WriteString(fileName + ":\n")
+
+	return promptBuilder.String()
+}
+
+// formatScriptCommands lists commands with their working directory and exit status,
+// when known, so that the script can keep directories and skip failed attempts.
+func formatScriptCommands(commands []history.HistoryEntry) string {
+	header := "Commands (directory | exit status | command)"
+	var builder strings.Builder
+	builder.WriteString(header + ":\n")
+	builder.WriteString(strings.Repeat("-", len(header)+1) + "\n")
+	for _, entry := range commands {
+		cwd, status := entry.Cwd, "-"
+		if cwd == "" {
+			cwd = "-"
+		}
+		if entry.ExitCode != nil {
+			status = fmt.Sprint(*entry.ExitCode)
+		}
+		builder.WriteString(fmt.Sprintf("%s | %s | %s%s\n", cwd, status, entry.Command, formatAnnotation(entry)))
+	}
+	builder.WriteString(strings.Repeat("-", len(header)+1) + "\n\n")
+	return builder.String()
+}
+
+// formatFailure describes a failed command, with whatever metadata is known, for inclusion in a prompt.
+func formatFailure(failed history.HistoryEntry) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Command: `%s`\n", failed.Command))
+	if failed.ExitCode != nil {
+		builder.WriteString(fmt.Sprintf("Exit code: %d\n", *failed.ExitCode))
+	}
+	if failed.Cwd != "" {
+		builder.WriteString(fmt.Sprintf("Working directory: %s\n", failed.Cwd))
+	}
+	if failed.Stderr != "" {
+		builder.WriteString("Error output (last lines):\n```\n")
+		builder.WriteString(failed.Stderr)
+		builder.WriteString("\n```\n")
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// formatHistoryContext formats the history entries for inclusion in a prompt, each
+// with what is known of how it ran.
 func formatHistoryContext(header string, historyContext []history.HistoryEntry, maxEntries int) string {
 	if len(historyContext) == 0 {
 		return "No specific user history context provided.\n\n"
@@ -203,23 +1299,402 @@ func formatHistoryContext(header string, historyContext []history.HistoryEntry,
 		startIdx = len(historyContext) - maxEntries
 	}
 
+	now := time.Now()
+	home, _ := os.UserHomeDir()
+	builder.WriteString(metadataLegend(historyContext[startIdx:], now))
+	builder.WriteString(outputLegend(historyContext[startIdx:]))
 	for i := startIdx; i < len(historyContext); i++ {
+		builder.WriteString(formatMetadata(historyContext[i], now, home))
 		builder.WriteString(historyContext[i].Command)
+		builder.WriteString(formatAnnotation(historyContext[i]))
 		builder.WriteString("\n")
+		builder.WriteString(formatOutput(historyContext[i]))
 	}
 	builder.WriteString(strings.Repeat("-", len(header)+1) + "\n\n") // Dynamic underline
 
 	return builder.String()
 }
 
+// formatTimedHistoryContext formats the most recent maxEntries entries with their time
+// (also relative to now), host and working directory, when known, for questions about when and where commands ran.
+func formatTimedHistoryContext(header string, historyContext []history.HistoryEntry, maxEntries int) string {
+	if len(historyContext) == 0 {
+		return "No specific user history context provided.\n\n"
+	}
+
+	var builder strings.Builder
+	builder.WriteString(header + ":\n")
+	builder.WriteString(strings.Repeat("-", len(header)+1) + "\n")
+
+	startIdx := 0
+	if maxEntries > 0 && len(historyContext) > maxEntries {
+		startIdx = len(historyContext) - maxEntries
+	}
+
+	now := time.Now()
+	builder.WriteString(outputLegend(historyContext[startIdx:]))
+	for _, entry := range historyContext[startIdx:] {
+		when := "-"
+		if entry.Timestamp > 0 {
+			ran := time.Unix(entry.Timestamp, 0)
+			when = fmt.Sprintf("%s (%s)", ran.Format("2006-01-02 15:04"), relativeTime(ran, now))
+		}
+		cwd := entry.Cwd
+		if cwd == "" {
+			cwd = "-"
+		}
+		if entry.Host != "" {
+			cwd = entry.Host + ":" + cwd
+		}
+		builder.WriteString(fmt.Sprintf("%s | %s | %s%s\n", when, cwd, entry.Command, formatAnnotation(entry)))
+		builder.WriteString(formatOutput(entry))
+	}
+	builder.WriteString(strings.Repeat("-", len(header)+1) + "\n\n")
+
+	return builder.String()
+}
+
+// formatWorkflows lists workflows, one per line, with how often the user followed them.
+func formatWorkflows(workflows []Workflow) string {
+	var builder strings.Builder
+	for _, workflow := range workflows {
+		builder.WriteString(fmt.Sprintf("- %s (%d times)\n", strings.Join(workflow.Commands, " → "), workflow.Count))
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// formatEnvironment describes the machine commands will run on, so that suggestions
+// use flags and tools that exist there. A nil env yields nothing.
+func formatEnvironment(env *Environment) string {
+	if env == nil {
+		return ""
+	}
+	var builder strings.Builder
+	if env.OS != "" {
+		builder.WriteString(formatMachine(env))
+	}
+	if env.Git != nil {
+		builder.WriteString(formatGit(env.Git))
+	}
+	if env.Cloud != nil {
+		builder.WriteString(formatCloud(env.Cloud))
+	}
+	if len(env.Listing) > 0 {
+		builder.WriteString(formatListing(env.Listing, env.ListingOmitted))
+	}
+	return builder.String()
+}
+
+// formatCloud describes the Kubernetes and cloud accounts in use, so that commands
+// target them explicitly rather than with placeholders.
+func formatCloud(cloud *CloudContext) string {
+	var builder strings.Builder
+	builder.WriteString("The user's current cloud and Kubernetes settings:\n")
+	if cloud.KubeContext != "" {
+		builder.WriteString("- kubectl context: " + cloud.KubeContext)
+		if cloud.KubeNamespace != "" {
+			builder.WriteString(", namespace " + cloud.KubeNamespace)
+		}
+		builder.WriteString("\n")
+	}
+	if cloud.AWSProfile != "" {
+		builder.WriteString("- AWS profile: " + cloud.AWSProfile + "\n")
+	}
+	if cloud.AWSRegion != "" {
+		builder.WriteString("- AWS region: " + cloud.AWSRegion + "\n")
+	}
+	if cloud.GCloudProject != "" {
+		builder.WriteString("- gcloud project: " + cloud.GCloudProject + "\n")
+	}
+	builder.WriteString("Commands act on these unless told otherwise; name another context, namespace, profile or project only when the task asks for it.\n\n")
+	return builder.String()
+}
+
+// formatListing lists the files in the user's working directory, so that commands
+// name the files that actually exist.
+func formatListing(listing []string, omitted int) string {
+	var builder strings.Builder
+	builder.WriteString("Files in the user's current directory (directories end in '/'):\n")
+	for _, name := range listing {
+		builder.WriteString(name + "\n")
+	}
+	if omitted > 0 {
+		builder.WriteString(fmt.Sprintf("... and %d more\n", omitted))
+	}
+	builder.WriteString("When the task refers to a file, use the matching name from this listing.\n\n")
+	return builder.String()
+}
+
+// formatMachine describes the OS, shell and tools of env.
+func formatMachine(env *Environment) string {
+	var builder strings.Builder
+	builder.WriteString("The commands will run on the user's machine:\n")
+	system := env.OS
+	if env.OSVersion != "" {
+		system += " (" + env.OSVersion + ")"
+	}
+	builder.WriteString(fmt.Sprintf("- OS: %s, %s\n", system, env.Arch))
+	if env.Shell != "" {
+		builder.WriteString("- Shell: " + env.Shell + "\n")
+	}
+	switch env.Coreutils {
+	case CoreutilsGNU:
+		builder.WriteString("- Core utilities: GNU (e.g. sed -i without a suffix argument)\n")
+	case CoreutilsBSD:
+		builder.WriteString("- Core utilities: BSD (e.g. sed -i '' and no GNU-only long options)\n")
+	case CoreutilsBusyBox:
+		builder.WriteString("- Core utilities: BusyBox (only the most common options are supported)\n")
+	}
+	if len(env.Tools) > 0 {
+		builder.WriteString("- Installed: " + strings.Join(env.Tools, ", ") + "\n")
+	}
+	if len(env.MissingTools) > 0 {
+		builder.WriteString("- Not installed: " + strings.Join(env.MissingTools, ", ") + "\n")
+	}
+	builder.WriteString("Use flags and tools that work on this machine. Prefer installed tools; if the task needs one that isn't installed, say so in a comment.\n\n")
+	return builder.String()
+}
+
+// formatGit describes the git repository the user is in, so that git commands use
+// the actual branch and remote names.
+func formatGit(repo *GitContext) string {
+	var builder strings.Builder
+	builder.WriteString("The user is in a git repository:\n")
+	switch {
+	case repo.Detached:
+		builder.WriteString("- Branch: none (detached HEAD)\n")
+	case repo.Upstream != "":
+		builder.WriteString(fmt.Sprintf("- Branch: %s, tracking %s (%d ahead, %d behind)\n", repo.Branch, repo.Upstream, repo.Ahead, repo.Behind))
+	default:
+		builder.WriteString(fmt.Sprintf("- Branch: %s, not pushed to a remote branch yet\n", repo.Branch))
+	}
+	if repo.DefaultBranch != "" {
+		builder.WriteString("- Default branch: " + repo.DefaultBranch + "\n")
+	}
+	if repo.Changed == 0 {
+		builder.WriteString("- Working tree: clean\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("- Working tree: %d changed or untracked files\n", repo.Changed))
+	}
+	for _, remote := range repo.Remotes {
+		builder.WriteString(fmt.Sprintf("- Remote %s: %s\n", remote.Name, remote.URL))
+	}
+	builder.WriteString("Use these branch and remote names in git commands instead of placeholders.\n\n")
+	return builder.String()
+}
+
+// outputLegend explains the output lines of formatOutput, when any of entries has them.
+func outputLegend(entries []history.HistoryEntry) string {
+	for _, entry := range entries {
+		if entry.Output != "" {
+			return "(Lines starting with '    > ' are the last lines a command printed.)\n"
+		}
+	}
+	return ""
+}
+
+// formatOutput formats the captured output of a history entry as indented lines
+// below it, or returns "" when there is none.
+func formatOutput(entry history.HistoryEntry) string {
+	if entry.Output == "" {
+		return ""
+	}
+	var builder strings.Builder
+	for _, line := range strings.Split(entry.Output, "\n") {
+		builder.WriteString("    > " + line + "\n")
+	}
+	return builder.String()
+}
+
+// formatAnnotation formats the user's tags and note on a history entry, and the file
+// of a team snippet, as a trailing comment.
+func formatAnnotation(entry history.HistoryEntry) string {
+	var parts []string
+	if entry.Team != "" {
+		parts = append(parts, "from "+entry.Team)
+	}
+	if len(entry.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(entry.Tags, ", "))
+	}
+	if entry.Note != "" {
+		parts = append(parts, "note: "+strings.ReplaceAll(entry.Note, "\n", " "))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "    # " + strings.Join(parts, "; ")
+}
+
+// formatMetadata formats what is known of how a history entry ran as a prefix, e.g.
+// "[2d ago, ~/src/app, exit 1, 3m12s] ", or returns "" when nothing is. Directories
+// under home are shortened to ~.
+func formatMetadata(entry history.HistoryEntry, now time.Time, home string) string {
+	var fields []string
+	if entry.Timestamp > 0 {
+		fields = append(fields, relativeTime(time.Unix(entry.Timestamp, 0), now))
+	}
+	cwd := entry.Cwd
+	if home != "" && (cwd == home || strings.HasPrefix(cwd, home+string(filepath.Separator))) {
+		cwd = "~" + strings.TrimPrefix(cwd, home)
+	}
+	switch {
+	case entry.Host != "" && cwd != "":
+		fields = append(fields, entry.Host+":"+cwd)
+	case entry.Host != "":
+		fields = append(fields, "on "+entry.Host)
+	case cwd != "":
+		fields = append(fields, cwd)
+	}
+	if entry.ExitCode != nil {
+		fields = append(fields, fmt.Sprintf("exit %d", *entry.ExitCode))
+	}
+	if entry.DurationMS >= 1000 {
+		duration := (time.Duration(entry.DurationMS) * time.Millisecond).Round(time.Second).String()
+		if strings.HasSuffix(duration, "m0s") {
+			duration = strings.TrimSuffix(duration, "0s")
+		}
+		fields = append(fields, duration)
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(fields, ", ") + "] "
+}
+
+// metadataLegend explains the prefixes of formatMetadata, when any of entries has one.
+func metadataLegend(entries []history.HistoryEntry, now time.Time) string {
+	for _, entry := range entries {
+		if entry.Timestamp > 0 || entry.Cwd != "" || entry.Host != "" || entry.ExitCode != nil || entry.DurationMS >= 1000 {
+			return fmt.Sprintf("(Entries start with what is known of how they ran, in brackets: how long before now (%s) they ran, e.g. 5m, 3h, 2d, 3w, 4mo or 1y ago, the directory, the exit status and how long they took. The brackets are not part of the command.)\n", now.Format("2006-01-02 15:04"))
+		}
+	}
+	return ""
+}
+
+// relativeTime describes how long before now t was, e.g. "3w ago", so that queries
+// like "last month" have something to anchor to.
+func relativeTime(t, now time.Time) string {
+	const day = 24 * time.Hour
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", age/time.Minute)
+	case age < day:
+		return fmt.Sprintf("%dh ago", age/time.Hour)
+	case age < 7*day:
+		return fmt.Sprintf("%dd ago", age/day)
+	case age < 30*day:
+		return fmt.Sprintf("%dw ago", age/(7*day))
+	case age < 365*day:
+		return fmt.Sprintf("%dmo ago", age/(30*day))
+	default:
+		return fmt.Sprintf("%dy ago", age/(365*day))
+	}
+}
+
+// lastEntries returns the most recent maxEntries entries; all of them when maxEntries is 0.
+func lastEntries(entries []history.HistoryEntry, maxEntries int) []history.HistoryEntry {
+	if maxEntries > 0 && len(entries) > maxEntries {
+		return entries[len(entries)-maxEntries:]
+	}
+	return entries
+}
+
+// pinnedEntries returns the entries the user saved with 'historai save'.
+func pinnedEntries(entries []history.HistoryEntry) []history.HistoryEntry {
+	var pinned []history.HistoryEntry
+	for _, entry := range entries {
+		if entry.Pinned {
+			pinned = append(pinned, entry)
+		}
+	}
+	return pinned
+}
+
+// teamSnippets returns the entries that are snippets shared by the user's team.
+func teamSnippets(entries []history.HistoryEntry) []history.HistoryEntry {
+	var team []history.HistoryEntry
+	for _, entry := range entries {
+		if entry.Team != "" {
+			team = append(team, entry)
+		}
+	}
+	return team
+}
+
+// withoutTeam returns the entries of the user's own history, leaving out the
+// snippets shared by their team.
+func withoutTeam(entries []history.HistoryEntry) []history.HistoryEntry {
+	if len(teamSnippets(entries)) == 0 {
+		return entries
+	}
+	var own []history.HistoryEntry
+	for _, entry := range entries {
+		if entry.Team == "" {
+			own = append(own, entry)
+		}
+	}
+	return own
+}
+
+// hasAnnotations reports whether any entry carries user tags or a note.
+func hasAnnotations(entries []history.HistoryEntry) bool {
+	for _, entry := range entries {
+		if entry.Note != "" || len(entry.Tags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymized reports whether the commands of entries had their arguments replaced
+// by placeholders.
+func anonymized(entries []history.HistoryEntry) bool {
+	for _, entry := range entries {
+		if redact.IsAnonymized(entry.Command) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatInstructions formats user-configured extra instructions for inclusion in a prompt.
+func formatInstructions(instructions string) string {
+	instructions = strings.TrimSpace(instructions)
+	if instructions == "" {
+		return ""
+	}
+	return "Additional instructions from the user's configuration:\n" + instructions + "\n\n"
+}
+
+// formatLanguage asks for explanations in the configured language, keeping commands as-is.
+func formatLanguage(language string) string {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("Write any explanations and comments in %s. Never translate the commands themselves, their flags, arguments, paths or the exact phrases you were asked to return.\n\n", language)
+}
+
 // extractTextFromResponse safely extracts the text content from the Gemini API response candidates.
 func extractTextFromResponse(resp *genai.GenerateContentResponse) string {
-	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+	if resp == nil || len(resp.Candidates) == 0 {
+		return ""
+	}
+	return candidateText(resp.Candidates[0])
+}
+
+// candidateText returns the text content of a response candidate.
+func candidateText(candidate *genai.Candidate) string {
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
 		return ""
 	}
 
 	var result strings.Builder
-	for _, part := range resp.Candidates[0].Content.Parts {
+	for _, part := range candidate.Content.Parts {
 		if text, ok := part.(genai.Text); ok {
 			result.WriteString(string(text))
 		}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDryRunPrompt(t *testing.T) {
+	tests := []struct {
+		name         string
+		configSystem string
+		callSystem   string
+		want         string
+	}{
+		{
+			name: "no system instruction",
+			want: "List files",
+		},
+		{
+			name:       "request's own",
+			callSystem: "Answer with a command.",
+			want:       "System instruction:\nAnswer with a command.\n\nPrompt:\nList files",
+		},
+		{
+			name:         "prompt.system",
+			configSystem: "Use GNU tools.",
+			want:         "System instruction:\nUse GNU tools.\n\nPrompt:\nList files",
+		},
+		{
+			name:         "both",
+			configSystem: "Use GNU tools.",
+			callSystem:   "Answer with a command.",
+			want:         "System instruction:\nAnswer with a command.\n\nUse GNU tools.\n\nPrompt:\nList files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shown []string
+			c := &GeminiClient{logger: zap.NewNop(), system: tt.configSystem}
+			c.SetPromptHook(func(prompt string) error {
+				shown = append(shown, prompt)
+				return ErrDryRun
+			})
+
+			_, err := c.generateGeminiContent(context.Background(), geminiCall{system: tt.callSystem, prompt: "List files"})
+			if !errors.Is(err, ErrDryRun) {
+				t.Fatalf("err = %v, want ErrDryRun", err)
+			}
+			if len(shown) != 1 {
+				t.Fatalf("prompt hook called %d times, want 1", len(shown))
+			}
+			if shown[0] != tt.want {
+				t.Errorf("dry-run output = %q, want %q", shown[0], tt.want)
+			}
+		})
+	}
+}
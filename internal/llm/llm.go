@@ -1,12 +1,546 @@
 package llm
 
-import "github.com/sanspareilsmyn/historai/internal/history"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
 
-// LLMClient defines the interface for interacting with an LLM API.
+	"google.golang.org/api/googleapi"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// ErrBlocked is returned (wrapped) when the prompt or response was blocked by the
+// provider's safety filters.
+var ErrBlocked = errors.New("blocked due to safety settings")
+
+// EmptyResponse is the message of a CommandAnswer when the model's response was
+// empty even after a retry, as opposed to the model saying it has no command.
+const EmptyResponse = "(AI response was empty)"
+
+// ErrDryRun is returned by a PromptHook to stop a call before it is sent.
+var ErrDryRun = errors.New("dry run: the prompt was not sent")
+
+// ErrUnavailable is matched by errors of a provider that can't be reached or used at
+// all, such as those reported by the daemon; see IsUnavailable.
+var ErrUnavailable = errors.New("the LLM provider is unavailable")
+
+// ProviderError is a provider failure the user can act on, such as an invalid API
+// key or an exhausted quota, described with how to fix it rather than as the API's
+// error. It wraps the API's error.
+type ProviderError struct {
+	// Reason says what went wrong, e.g. "the API key was rejected".
+	Reason string
+
+	// Hint says how to fix it.
+	Hint string
+
+	// Config reports that the fix is in the configuration or credentials.
+	Config bool
+
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Reason + ". " + e.Hint
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// IsUnavailable reports whether err means the provider couldn't be used at all, as
+// opposed to an answer it refused or a bad request: no API key, a provider the
+// organization policy forbids, a network error or timeout, rate limiting, or a
+// server error.
+func IsUnavailable(err error) bool {
+	var netErr net.Error
+	var apiErr *googleapi.Error
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrUnavailable), errors.Is(err, config.ErrMissingAPIKey), errors.Is(err, config.ErrPolicy), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.As(err, &netErr):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.Code == 429 || apiErr.Code >= 500
+	default:
+		return false
+	}
+}
+
+// PromptHook is called with every prompt, exactly as it is about to be sent, before
+// the call is made. Returning an error, such as ErrDryRun, cancels the call.
+type PromptHook func(prompt string) error
+
+// PromptObserver is implemented by LLM clients that can show their prompts to a
+// PromptHook, e.g. to audit what is sent.
+type PromptObserver interface {
+	SetPromptHook(hook PromptHook)
+}
+
+// LLMClient defines the interface for interacting with an LLM API. Methods answering
+// with prose return "" when the model gave no answer; callers say so.
 type LLMClient interface {
-	FindHistoryEntries(query string, historyContext []history.HistoryEntry) (string, error)
+	// Find searches req.History for the commands matching req.Query.
+	Find(ctx context.Context, req FindRequest) (FindResponse, error)
+
+	// Suggest suggests commands accomplishing req.Task.
+	Suggest(ctx context.Context, req SuggestRequest) (SuggestResponse, error)
+
+	// ExplainCommand explains req.Command flag by flag.
+	ExplainCommand(ctx context.Context, req ExplainRequest) (string, error)
+
+	// FixCommand proposes a corrected version of req.Failed.
+	FixCommand(ctx context.Context, req FixRequest) (CommandAnswer, error)
+
+	// DiagnoseFailure explains why req.Failed failed, using its captured error output.
+	DiagnoseFailure(ctx context.Context, req FixRequest) (string, error)
+
+	// PredictNext suggests the likely next command(s) after req.Recent, learning the
+	// user's workflows from req.History.
+	PredictNext(ctx context.Context, req NextRequest) (CommandAnswer, error)
+
+	// Chat answers the last user message of a multi-turn conversation, grounded in
+	// req.History.
+	Chat(ctx context.Context, req ChatRequest) (string, error)
+
+	// AnswerQuestion answers a free-form question about the user's habits by
+	// reasoning over req.History.
+	AnswerQuestion(ctx context.Context, req AskRequest) (string, error)
+
+	// SummarizeActivity writes a short summary of the work done during req.Period
+	// (e.g. "since yesterday"), from commands grouped by project.
+	SummarizeActivity(ctx context.Context, req SummaryRequest) (string, error)
+
+	// FillSnippet chooses values for the placeholders of req.Snippet that accomplish
+	// req.Description, preferring hosts, ports and paths seen in req.History. It
+	// returns a value for every placeholder, "" when none could be determined.
+	FillSnippet(ctx context.Context, req FillRequest) (map[string]string, error)
 
-	SuggestCommands(taskDescription string, historyContext []history.HistoryEntry) (string, error)
+	// WriteScript turns req.Commands into a cleaned-up and commented shell script.
+	WriteScript(ctx context.Context, req ScriptRequest) (string, error)
+
+	// WriteBuildTargets turns workflows the user repeats into targets of a build file,
+	// with names and comments.
+	WriteBuildTargets(ctx context.Context, req TargetsRequest) (string, error)
+
+	// WriteQuiz writes up to req.Count questions on what the flags and arguments of
+	// req.Commands, taken from the user's history, do.
+	WriteQuiz(ctx context.Context, req QuizRequest) ([]QuizQuestion, error)
+
+	// GradeAnswer grades the user's answer to a quiz question.
+	GradeAnswer(ctx context.Context, req GradeRequest) (QuizGrade, error)
 
 	Close() error
 }
+
+// Options tune a single request. Their zero values keep the configured settings
+// and the provider's defaults, so new options don't change existing callers.
+type Options struct {
+	// Model overrides llm.model, e.g. to answer with a lighter or stronger model.
+	Model string `json:"model,omitempty"`
+
+	// Temperature overrides the model's sampling temperature when not nil.
+	Temperature *float32 `json:"temperature,omitempty"`
+
+	// MaxOutputTokens limits the length of the answer.
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	// HistoryLimit caps the number of history entries sent as context, most recent
+	// first, below the provider's own limit.
+	HistoryLimit int `json:"history_limit,omitempty"`
+}
+
+// FindRequest asks which of the commands in History match Query.
+type FindRequest struct {
+	Query string
+
+	// History holds the entries to search, oldest first, already redacted.
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// FindResponse is the answer to a FindRequest: the matching commands of the
+// history, best first.
+type FindResponse struct {
+	CommandAnswer
+}
+
+// SuggestRequest asks for commands accomplishing Task.
+type SuggestRequest struct {
+	Task string
+
+	// History holds recent entries, and the commands the user saved, as context.
+	History []history.HistoryEntry
+
+	// Workflows holds the sequences of steps the user habitually runs, best first,
+	// and may be empty.
+	Workflows []Workflow
+
+	// Environment describes the machine the commands will run on, and is nil when
+	// unknown.
+	Environment *Environment
+
+	Options Options
+}
+
+// SuggestResponse is the answer to a SuggestRequest: the suggested commands, with
+// the comments explaining them.
+type SuggestResponse struct {
+	CommandAnswer
+}
+
+// ExplainRequest asks what Command does.
+type ExplainRequest struct {
+	Command string
+
+	// History holds previous uses of the same program.
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// FixRequest asks how to fix Failed, or why it failed.
+type FixRequest struct {
+	// Failed is the failed command, with its exit code, working directory and error
+	// output when known.
+	Failed history.HistoryEntry
+
+	// History holds previous uses of the same program.
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// NextRequest asks which commands the user will run after Recent.
+type NextRequest struct {
+	// Recent holds the latest commands, oldest first.
+	Recent []history.HistoryEntry
+
+	// History holds the earlier commands, oldest first, to learn workflows from.
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// ChatRequest asks for the answer to the last user message of Messages.
+type ChatRequest struct {
+	// Messages is the conversation so far, oldest first.
+	Messages []ChatMessage
+
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// AskRequest asks Question about the user's habits.
+type AskRequest struct {
+	Question string
+
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// SummaryRequest asks for a summary of the work done during Period.
+type SummaryRequest struct {
+	// Period describes the time summarized, e.g. "since yesterday".
+	Period string
+
+	Groups []ActivityGroup
+
+	Options Options
+}
+
+// FillRequest asks for values of the placeholders of Snippet accomplishing
+// Description.
+type FillRequest struct {
+	Snippet     Snippet
+	Description string
+
+	History []history.HistoryEntry
+
+	Options Options
+}
+
+// ScriptRequest asks for a shell script running Commands, oldest first.
+type ScriptRequest struct {
+	Commands []history.HistoryEntry
+
+	// Purpose optionally says what the script is for.
+	Purpose string
+
+	Options Options
+}
+
+// TargetsRequest asks for build file targets running Workflows.
+type TargetsRequest struct {
+	Workflows []Workflow
+
+	// Format is the build file format, BuildFormatMake or BuildFormatJust.
+	Format string
+
+	Options Options
+}
+
+// QuizRequest asks for up to Count questions about Commands.
+type QuizRequest struct {
+	Commands []history.HistoryEntry
+	Count    int
+
+	Options Options
+}
+
+// GradeRequest asks whether Answer answers Question.
+type GradeRequest struct {
+	Question QuizQuestion
+	Answer   string
+
+	Options Options
+}
+
+// Chat message roles.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatMessage is a single turn of a chat conversation.
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ActivityGroup holds the commands run in one project or directory.
+type ActivityGroup struct {
+	// Project is the repository root or working directory, or "" when unknown.
+	Project string                 `json:"project"`
+	Entries []history.HistoryEntry `json:"entries"`
+}
+
+// Build file formats for WriteBuildTargets.
+const (
+	BuildFormatMake = "make"
+	BuildFormatJust = "just"
+)
+
+// Workflow is a series of commands the user runs one after the other, oldest first,
+// and how often they did.
+type Workflow struct {
+	Commands []string `json:"commands"`
+	Count    int      `json:"count"`
+}
+
+// Environment describes where suggested commands will run: the machine and, when
+// enabled, the git repository. Fields that weren't collected are empty.
+type Environment struct {
+	// OS and Arch are runtime.GOOS and runtime.GOARCH, e.g. "linux" and "arm64".
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+
+	// OSVersion is the name and version of the OS release, e.g. "Ubuntu 24.04 LTS".
+	OSVersion string `json:"os_version,omitempty"`
+
+	// Shell is the name of the user's shell, e.g. "zsh".
+	Shell string `json:"shell,omitempty"`
+
+	// Coreutils is the flavor of the core utilities: CoreutilsGNU, CoreutilsBSD or
+	// CoreutilsBusyBox.
+	Coreutils string `json:"coreutils,omitempty"`
+
+	// Tools and MissingTools are the commonly used tools found and not found on PATH.
+	Tools        []string `json:"tools,omitempty"`
+	MissingTools []string `json:"missing_tools,omitempty"`
+
+	// Git describes the git repository of the working directory, if any.
+	Git *GitContext `json:"git,omitempty"`
+
+	// Cloud describes the Kubernetes and cloud accounts in use, if any.
+	Cloud *CloudContext `json:"cloud,omitempty"`
+
+	// Listing holds the names in the working directory, directories ending in "/",
+	// when the user asked for it. ListingOmitted counts the names left out for length.
+	Listing        []string `json:"listing,omitempty"`
+	ListingOmitted int      `json:"listing_omitted,omitempty"`
+}
+
+// GitContext describes the git repository the user is in.
+type GitContext struct {
+	// Branch is the current branch, empty when Detached.
+	Branch   string `json:"branch,omitempty"`
+	Detached bool   `json:"detached,omitempty"`
+
+	// Upstream is the branch Branch tracks, e.g. "origin/main", with how many commits
+	// Branch is ahead of and behind it.
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead,omitempty"`
+	Behind   int    `json:"behind,omitempty"`
+
+	// DefaultBranch is the remote's default branch, e.g. "origin/main", if known.
+	DefaultBranch string `json:"default_branch,omitempty"`
+
+	// Changed is the number of modified, staged and untracked files.
+	Changed int `json:"changed"`
+
+	Remotes []GitRemote `json:"remotes,omitempty"`
+}
+
+// GitRemote is a remote of a git repository, with its fetch URL.
+type GitRemote struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// CloudContext describes the Kubernetes and cloud accounts the user's commands act
+// on. Empty fields are unknown or unset.
+type CloudContext struct {
+	// KubeContext is kubectl's current context, with its namespace.
+	KubeContext   string `json:"kube_context,omitempty"`
+	KubeNamespace string `json:"kube_namespace,omitempty"`
+
+	// AWSProfile and AWSRegion are the AWS CLI profile and region set in the shell.
+	AWSProfile string `json:"aws_profile,omitempty"`
+	AWSRegion  string `json:"aws_region,omitempty"`
+
+	// GCloudProject is the project of the active gcloud configuration.
+	GCloudProject string `json:"gcloud_project,omitempty"`
+}
+
+// Core utility flavors for Environment.Coreutils.
+const (
+	CoreutilsGNU     = "gnu"
+	CoreutilsBSD     = "bsd"
+	CoreutilsBusyBox = "busybox"
+)
+
+// Snippet is a command template whose {placeholders} are to be filled in.
+type Snippet struct {
+	Command      string   `json:"command"`
+	Description  string   `json:"description,omitempty"`
+	Placeholders []string `json:"placeholders"`
+}
+
+// QuizQuestion is a question about a command from the user's history.
+type QuizQuestion struct {
+	Command  string `json:"command" yaml:"command"`
+	Question string `json:"question" yaml:"question"`
+
+	// Answer is the expected answer, shown after the user answered.
+	Answer string `json:"answer" yaml:"answer"`
+}
+
+// QuizGrade is the verdict on an answer to a QuizQuestion.
+type QuizGrade struct {
+	Correct bool `json:"correct" yaml:"correct"`
+
+	// Feedback explains what was right or missing in the answer.
+	Feedback string `json:"feedback" yaml:"feedback"`
+}
+
+// parseQuizQuestions reads the JSON array of questions an LLM returned, tolerating a
+// surrounding markdown code block. Questions without a command or text are dropped.
+func parseQuizQuestions(text string) ([]QuizQuestion, error) {
+	text = stripCodeFence(text)
+	var raw []QuizQuestion
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("invalid quiz %q: %w", text, err)
+	}
+	questions := make([]QuizQuestion, 0, len(raw))
+	for _, question := range raw {
+		if strings.TrimSpace(question.Command) != "" && strings.TrimSpace(question.Question) != "" {
+			questions = append(questions, question)
+		}
+	}
+	return questions, nil
+}
+
+// parseQuizGrade reads the JSON object of a grade an LLM returned, tolerating a
+// surrounding markdown code block.
+func parseQuizGrade(text string) (QuizGrade, error) {
+	text = stripCodeFence(text)
+	var grade QuizGrade
+	if err := json.Unmarshal([]byte(text), &grade); err != nil {
+		return QuizGrade{}, fmt.Errorf("invalid grade %q: %w", text, err)
+	}
+	return grade, nil
+}
+
+// parsePlaceholderValues reads the JSON object of placeholder values an LLM returned
+// for snippet, tolerating a surrounding markdown code block. Missing placeholders get "".
+func parsePlaceholderValues(snippet Snippet, text string) (map[string]string, error) {
+	text = stripCodeFence(text)
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("invalid placeholder values %q: %w", text, err)
+	}
+	values := make(map[string]string, len(snippet.Placeholders))
+	for _, name := range snippet.Placeholders {
+		switch value := raw[name].(type) {
+		case nil:
+			values[name] = ""
+		case string:
+			values[name] = strings.TrimSpace(value)
+		default:
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+// stripCodeFence removes a markdown code block wrapped around the whole of text,
+// such as ```json ... ```, which models add even when asked not to.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") {
+		return text
+	}
+	text = strings.TrimSuffix(text, "```")
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		return strings.TrimSpace(text[newline+1:])
+	}
+	return strings.TrimSpace(strings.TrimPrefix(text, "```"))
+}
+
+// Usage is the number of tokens an LLM call consumed.
+type Usage struct {
+	PromptTokens int `json:"prompt_tokens" yaml:"prompt_tokens"`
+	OutputTokens int `json:"output_tokens" yaml:"output_tokens"`
+}
+
+// UsageReporter is implemented by LLM clients that know how many tokens their
+// calls consumed.
+type UsageReporter interface {
+	// LastUsage returns the usage of the most recent call, and false if the provider
+	// didn't report it.
+	LastUsage() (Usage, bool)
+}
+
+// Verifier is implemented by LLM clients that can check their credentials and model
+// with a request that generates nothing, e.g. for 'historai doctor'.
+type Verifier interface {
+	Verify(ctx context.Context) error
+}
+
+// ModelLister is implemented by LLM clients that can list the models their
+// provider offers, e.g. to complete --model in the shell.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// ChunkFunc receives response text as it is generated.
+type ChunkFunc func(text string)
+
+// StreamingClient is implemented by LLM clients that can deliver their response
+// incrementally. The full response is still returned once generation completes.
+type StreamingClient interface {
+	StreamFind(ctx context.Context, req FindRequest, onChunk ChunkFunc) (FindResponse, error)
+
+	StreamSuggest(ctx context.Context, req SuggestRequest, onChunk ChunkFunc) (SuggestResponse, error)
+}
@@ -0,0 +1,452 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/corrector"
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// ProviderMock is the built-in offline provider, for tests, demos and CI: it answers
+// without network access or API key, from canned responses or simple local rules.
+const ProviderMock = "mock"
+
+// mockMaxResults is the most commands the rule-based find and suggest return.
+const mockMaxResults = 5
+
+// mockStopWords are ignored when matching a query against commands.
+var mockStopWords = map[string]bool{
+	"the": true, "an": true, "my": true, "to": true, "of": true, "and": true, "or": true,
+	"that": true, "this": true, "used": true, "use": true, "command": true, "on": true,
+	"for": true, "in": true, "with": true, "what": true, "how": true, "was": true, "it": true,
+}
+
+// MockClient implements the LLMClient interface without calling any LLM. Each method
+// returns the canned response configured for it in mock.responses, if any, and
+// otherwise a deterministic answer built from the request by local rules: find and
+// suggest return the history commands sharing the most words with the query, fix
+// uses the local corrector, and so on.
+//
+// Canned responses use the result formats of the provider plugin protocol (e.g. a
+// JSON object for "fill"), keyed by its method names.
+type MockClient struct {
+	logger    *zap.Logger
+	responses map[string]mockResponse
+	system    string
+
+	mu         sync.Mutex
+	lastUsage  *Usage
+	promptHook PromptHook
+}
+
+// mockResponse is the canned response to a method: the same for every query, or one
+// per query with "" as the fallback.
+type mockResponse map[string]string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface method.
+func (r *mockResponse) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = mockResponse{"": node.Value}
+		return nil
+	}
+	var byQuery map[string]string
+	if err := node.Decode(&byQuery); err != nil {
+		return err
+	}
+	*r = byQuery
+	return nil
+}
+
+// NewMockClient creates the mock client, loading the canned responses file named
+// by mock.responses when it is set.
+func NewMockClient(logger *zap.Logger, cfg *config.Config) (*MockClient, error) {
+	client := &MockClient{logger: logger, system: strings.TrimSpace(cfg.Prompt.System)}
+	if cfg.Mock.Responses == "" {
+		return client, nil
+	}
+	data, err := os.ReadFile(config.ExpandHome(cfg.Mock.Responses))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock responses: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &client.responses); err != nil {
+		return nil, fmt.Errorf("failed to parse mock responses %s: %w", cfg.Mock.Responses, err)
+	}
+	logger.Debug("Loaded mock responses", zap.String("path", cfg.Mock.Responses), zap.Int("methods_count", len(client.responses)))
+	return client, nil
+}
+
+// Find implements the LLMClient interface method.
+func (c *MockClient) Find(_ context.Context, req FindRequest) (FindResponse, error) {
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.respond(pluginMethodFind, req.Query, historyContext, func() string {
+		if commands := matchingCommands(req.Query, historyContext); len(commands) > 0 {
+			return strings.Join(commands, "\n")
+		}
+		return ""
+	})
+	return FindResponse{newCommandAnswer(result, historyContext, NoMatchesResponse)}, err
+}
+
+// Suggest implements the LLMClient interface method.
+func (c *MockClient) Suggest(_ context.Context, req SuggestRequest) (SuggestResponse, error) {
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.respond(pluginMethodSuggest, req.Task, historyContext, func() string {
+		commands := matchingCommands(req.Task, historyContext)
+		if len(commands) == 0 {
+			return ""
+		}
+		return "# " + req.Task + "\n" + commands[0]
+	})
+	return SuggestResponse{newCommandAnswer(result, historyContext, CannotSuggestResponse)}, err
+}
+
+// ExplainCommand implements the LLMClient interface method.
+func (c *MockClient) ExplainCommand(_ context.Context, req ExplainRequest) (string, error) {
+	command := req.Command
+	return c.respond(pluginMethodExplain, command, lastEntries(req.History, req.Options.HistoryLimit), func() string {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return ""
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "`%s` runs %s.\n", command, fields[0])
+		for _, field := range fields[1:] {
+			fmt.Fprintf(&b, "- `%s`: an argument of %s\n", field, fields[0])
+		}
+		return b.String()
+	})
+}
+
+// FixCommand implements the LLMClient interface method.
+func (c *MockClient) FixCommand(_ context.Context, req FixRequest) (CommandAnswer, error) {
+	failed, historyContext := req.Failed, lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.respond(pluginMethodFix, failed.Command, historyContext, func() string {
+		correction, ok := corrector.New(historyContext, nil).Correct(failed)
+		if !ok {
+			return ""
+		}
+		return "# " + correction.Reason + "\n" + correction.Command
+	})
+	return newCommandAnswer(result, historyContext, CannotFixResponse), err
+}
+
+// DiagnoseFailure implements the LLMClient interface method.
+func (c *MockClient) DiagnoseFailure(_ context.Context, req FixRequest) (string, error) {
+	failed := req.Failed
+	return c.respond(pluginMethodWhy, failed.Command, lastEntries(req.History, req.Options.HistoryLimit), func() string {
+		diagnosis := fmt.Sprintf("`%s` failed", failed.Command)
+		if failed.ExitCode != nil {
+			diagnosis += fmt.Sprintf(" with exit status %d", *failed.ExitCode)
+		}
+		if stderr := strings.TrimSpace(failed.Stderr); stderr != "" {
+			lines := strings.Split(stderr, "\n")
+			diagnosis += ": " + lines[len(lines)-1]
+		}
+		return diagnosis + "."
+	})
+}
+
+// PredictNext implements the LLMClient interface method. The rule-based prediction is
+// the command that most often followed the last recent one in the history.
+func (c *MockClient) PredictNext(_ context.Context, req NextRequest) (CommandAnswer, error) {
+	recent, historyContext := req.Recent, lastEntries(req.History, req.Options.HistoryLimit)
+	query := ""
+	if len(recent) > 0 {
+		query = recent[len(recent)-1].Command
+	}
+	result, err := c.respond(pluginMethodNext, query, historyContext, func() string {
+		counts := make(map[string]int)
+		for i := 1; i < len(historyContext); i++ {
+			if historyContext[i-1].Command == query && historyContext[i].Command != query {
+				counts[historyContext[i].Command]++
+			}
+		}
+		best := ""
+		for command, count := range counts {
+			if count > counts[best] || (count == counts[best] && command < best) {
+				best = command
+			}
+		}
+		return best
+	})
+	return newCommandAnswer(result, slices.Concat(historyContext, recent), CannotPredictResponse), err
+}
+
+// Chat implements the LLMClient interface method.
+func (c *MockClient) Chat(_ context.Context, req ChatRequest) (string, error) {
+	query, historyContext := "", lastEntries(req.History, req.Options.HistoryLimit)
+	if len(req.Messages) > 0 {
+		query = req.Messages[len(req.Messages)-1].Content
+	}
+	return c.respond(pluginMethodChat, query, historyContext, func() string {
+		return mockAnswer(query, historyContext)
+	})
+}
+
+// AnswerQuestion implements the LLMClient interface method.
+func (c *MockClient) AnswerQuestion(_ context.Context, req AskRequest) (string, error) {
+	question, historyContext := req.Question, lastEntries(req.History, req.Options.HistoryLimit)
+	return c.respond(pluginMethodAsk, question, historyContext, func() string {
+		return mockAnswer(question, historyContext)
+	})
+}
+
+// SummarizeActivity implements the LLMClient interface method.
+func (c *MockClient) SummarizeActivity(_ context.Context, req SummaryRequest) (string, error) {
+	groups := req.Groups
+	return c.respond(pluginMethodSummary, req.Period, nil, func() string {
+		if len(groups) == 0 {
+			return ""
+		}
+		var b strings.Builder
+		for _, group := range groups {
+			project := group.Project
+			if project == "" {
+				project = "(unknown directory)"
+			}
+			fmt.Fprintf(&b, "- %s: %d commands\n", project, len(group.Entries))
+		}
+		return b.String()
+	})
+}
+
+// FillSnippet implements the LLMClient interface method. The rule-based values are
+// empty, leaving every placeholder to the user.
+func (c *MockClient) FillSnippet(_ context.Context, req FillRequest) (map[string]string, error) {
+	result, err := c.respond(pluginMethodFill, req.Description, lastEntries(req.History, req.Options.HistoryLimit), func() string {
+		return "{}"
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parsePlaceholderValues(req.Snippet, result)
+}
+
+// WriteScript implements the LLMClient interface method.
+func (c *MockClient) WriteScript(_ context.Context, req ScriptRequest) (string, error) {
+	commands, purpose := req.Commands, req.Purpose
+	result, err := c.respond(pluginMethodScript, purpose, commands, func() string {
+		var b strings.Builder
+		b.WriteString("#!/usr/bin/env bash\nset -euo pipefail\n")
+		if purpose != "" {
+			b.WriteString("# " + purpose + "\n")
+		}
+		for _, command := range commands {
+			b.WriteString(command.Command + "\n")
+		}
+		return b.String()
+	})
+	return stripCodeFence(result), err
+}
+
+// WriteBuildTargets implements the LLMClient interface method.
+func (c *MockClient) WriteBuildTargets(_ context.Context, req TargetsRequest) (string, error) {
+	workflows, format := req.Workflows, req.Format
+	result, err := c.respond(pluginMethodTargets, format, nil, func() string {
+		var b strings.Builder
+		for i, workflow := range workflows {
+			name := fmt.Sprintf("workflow-%d", i+1)
+			if format == BuildFormatMake {
+				fmt.Fprintf(&b, ".PHONY: %s\n%s:\n", name, name)
+			} else {
+				fmt.Fprintf(&b, "%s:\n", name)
+			}
+			for _, command := range workflow.Commands {
+				if format == BuildFormatMake {
+					b.WriteString("\t" + command + "\n")
+				} else {
+					b.WriteString("    " + command + "\n")
+				}
+			}
+			b.WriteString("\n")
+		}
+		return b.String()
+	})
+	return stripCodeFence(result), err
+}
+
+// WriteQuiz implements the LLMClient interface method. The rule-based questions ask
+// about the first flag of each command.
+func (c *MockClient) WriteQuiz(_ context.Context, req QuizRequest) ([]QuizQuestion, error) {
+	commands, count := req.Commands, req.Count
+	result, err := c.respond(pluginMethodQuiz, "", commands, func() string {
+		var questions []QuizQuestion
+		for _, command := range commands {
+			if len(questions) == count {
+				break
+			}
+			fields := strings.Fields(command.Command)
+			for _, field := range fields[min(1, len(fields)):] {
+				if strings.HasPrefix(field, "-") {
+					questions = append(questions, QuizQuestion{
+						Command:  command.Command,
+						Question: fmt.Sprintf("What does %s do here?", field),
+						Answer:   fmt.Sprintf("%s is an option of %s.", field, fields[0]),
+					})
+					break
+				}
+			}
+		}
+		data, _ := json.Marshal(questions)
+		return string(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseQuizQuestions(result)
+}
+
+// GradeAnswer implements the LLMClient interface method. The rule-based grade accepts
+// answers sharing a word with the expected answer.
+func (c *MockClient) GradeAnswer(_ context.Context, req GradeRequest) (QuizGrade, error) {
+	question, answer := req.Question, req.Answer
+	result, err := c.respond(pluginMethodGrade, answer, nil, func() string {
+		grade := QuizGrade{Correct: sharedWords(answer, question.Answer) > 0, Feedback: "Graded by the mock provider."}
+		data, _ := json.Marshal(grade)
+		return string(data)
+	})
+	if err != nil {
+		return QuizGrade{}, err
+	}
+	return parseQuizGrade(result)
+}
+
+// LastUsage implements the UsageReporter interface method. Tokens are estimated at
+// four characters each, so that cost reporting can be exercised too.
+func (c *MockClient) LastUsage() (Usage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastUsage == nil {
+		return Usage{}, false
+	}
+	return *c.lastUsage, true
+}
+
+// Close implements the LLMClient interface method.
+func (c *MockClient) Close() error {
+	return nil
+}
+
+// SetPromptHook implements the PromptObserver interface method. As there is no real
+// prompt, the hook sees the request as the plugin protocol would send it.
+func (c *MockClient) SetPromptHook(hook PromptHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.promptHook = hook
+}
+
+// respond returns the canned response to method for query, or the result of rule.
+func (c *MockClient) respond(method, query string, historyContext []history.HistoryEntry, rule func() string) (string, error) {
+	c.mu.Lock()
+	hook := c.promptHook
+	c.mu.Unlock()
+	if hook != nil {
+		data, err := json.Marshal(pluginRequest{Method: method, Query: query, History: historyContext, System: c.system})
+		if err != nil {
+			return "", err
+		}
+		if err := hook(string(data)); err != nil {
+			return "", err
+		}
+	}
+
+	result, canned := c.responses[method][query]
+	if !canned {
+		result, canned = c.responses[method][""]
+	}
+	if !canned {
+		result = rule()
+	}
+	c.logger.Debug("Mock provider answered", zap.String("method", method), zap.Bool("canned", canned))
+
+	promptLength := len(query)
+	for _, entry := range historyContext {
+		promptLength += len(entry.Command) + 1
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastUsage = &Usage{PromptTokens: (promptLength + 3) / 4, OutputTokens: (len(result) + 3) / 4}
+	return result, nil
+}
+
+// matchingCommands returns the distinct commands of historyContext sharing words with
+// query, in the command or its captured output, the most shared first and then the
+// most recent.
+func matchingCommands(query string, historyContext []history.HistoryEntry) []string {
+	type match struct {
+		command string
+		score   int
+		index   int
+	}
+	seen := make(map[string]bool)
+	var matches []match
+	for i := len(historyContext) - 1; i >= 0; i-- {
+		command := historyContext[i].Command
+		if seen[command] {
+			continue
+		}
+		seen[command] = true
+		if score := sharedWords(query, command+" "+historyContext[i].Output); score > 0 {
+			matches = append(matches, match{command: command, score: score, index: i})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	commands := make([]string, 0, mockMaxResults)
+	for _, m := range matches[:min(len(matches), mockMaxResults)] {
+		commands = append(commands, m.command)
+	}
+	return commands
+}
+
+// sharedWords counts the words of query found in text. Words match when one is a
+// prefix of the other, e.g. "log" and "logs".
+func sharedWords(query, text string) int {
+	textWords := words(text)
+	shared := 0
+	for _, word := range words(query) {
+		for _, candidate := range textWords {
+			if strings.HasPrefix(candidate, word) || (len(candidate) >= 3 && strings.HasPrefix(word, candidate)) {
+				shared++
+				break
+			}
+		}
+	}
+	return shared
+}
+
+// words splits s into lowercase words of two or more letters or digits, without stop
+// words.
+func words(s string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 2 && !mockStopWords[word] {
+			result = append(result, word)
+		}
+	}
+	return result
+}
+
+// mockAnswer is the rule-based answer to a question: the matching commands, if any.
+func mockAnswer(question string, historyContext []history.HistoryEntry) string {
+	commands := matchingCommands(question, historyContext)
+	if len(commands) == 0 {
+		return fmt.Sprintf("None of the %d commands in your history relate to that.", len(historyContext))
+	}
+	return "These commands in your history relate to that:\n" + strings.Join(commands, "\n")
+}
@@ -0,0 +1,485 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/httpclient"
+)
+
+// ProviderPluginPrefix is the executable name prefix of LLM provider plugins.
+// A plugin for provider "gateway" is an executable named "historai-provider-gateway" on PATH.
+const ProviderPluginPrefix = "historai-provider-"
+
+// Plugin protocol methods.
+const (
+	pluginMethodFind    = "find"
+	pluginMethodSuggest = "suggest"
+	pluginMethodExplain = "explain"
+	pluginMethodFix     = "fix"
+	pluginMethodWhy     = "why"
+	pluginMethodNext    = "next"
+	pluginMethodChat    = "chat"
+	pluginMethodAsk     = "ask"
+	pluginMethodSummary = "summarize"
+	pluginMethodFill    = "fill"
+	pluginMethodScript  = "script"
+	pluginMethodTargets = "targets"
+	pluginMethodQuiz    = "quiz"
+	pluginMethodGrade   = "grade"
+)
+
+// pluginRequest is written as one JSON line to the plugin's stdin per call.
+type pluginRequest struct {
+	ID      int                    `json:"id"`
+	Method  string                 `json:"method"`
+	Query   string                 `json:"query"`
+	History []history.HistoryEntry `json:"history"`
+
+	// Instructions are user-configured extra prompt instructions, if any.
+	Instructions string `json:"instructions,omitempty"`
+
+	// Language is the language explanations should be written in, if set.
+	Language string `json:"language,omitempty"`
+
+	// System is the user's system instruction, if any, to be sent to the model as
+	// such (e.g. a system message) rather than as part of the prompt.
+	System string `json:"system,omitempty"`
+
+	// Failed is the failed command for "fix" and "why" requests, with its exit code,
+	// working directory and error output when known. Query holds its command line.
+	Failed *history.HistoryEntry `json:"failed,omitempty"`
+
+	// Recent holds the latest commands, oldest first, for "next" requests.
+	// Query holds the most recent one.
+	Recent []history.HistoryEntry `json:"recent,omitempty"`
+
+	// Messages is the conversation so far, oldest first, for "chat" requests.
+	// Query holds the last user message.
+	Messages []ChatMessage `json:"messages,omitempty"`
+
+	// Groups holds commands grouped by project for "summarize" requests.
+	// Query holds the period, e.g. "since yesterday".
+	Groups []ActivityGroup `json:"groups,omitempty"`
+
+	// Snippet is the command template for "fill" requests. Query holds the user's
+	// description; the result must be a JSON object mapping each placeholder to its value.
+	Snippet *Snippet `json:"snippet,omitempty"`
+
+	// Workflows holds repeated command sequences: the ones to turn into build targets
+	// for "targets" requests, where Query holds the build file format ("make" or
+	// "just"), and the user's habitual workflows for "suggest" requests.
+	Workflows []Workflow `json:"workflows,omitempty"`
+
+	// Environment describes the machine suggested commands will run on, for
+	// "suggest" requests.
+	Environment *Environment `json:"environment,omitempty"`
+
+	// Count is the number of questions to write for "quiz" requests, whose History
+	// holds the commands to ask about. The result must be a JSON array of QuizQuestion.
+	Count int `json:"count,omitempty"`
+
+	// Question is the question answered for "grade" requests. Query holds the user's
+	// answer; the result must be a JSON QuizGrade object.
+	Question *QuizQuestion `json:"question,omitempty"`
+
+	// Options tune the request, e.g. with another model or temperature, when any
+	// are set. History is already limited to history_limit.
+	Options *Options `json:"options,omitempty"`
+}
+
+// pluginResponse is read as one JSON line from the plugin's stdout per call.
+type pluginResponse struct {
+	ID     int    `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error"`
+
+	// Usage is the number of tokens the call consumed, if the plugin knows it.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// PluginClient implements the LLMClient interface by talking to an external
+// provider process over stdio.
+//
+// Protocol: the plugin is started once and kept alive until Close. For every call
+// historai writes a pluginRequest JSON line to its stdin and expects exactly one
+// pluginResponse JSON line with the same id on stdout. A non-empty "error" fails
+// the call; an optional "usage" reports the tokens it consumed. A cancelled call
+// returns at once, without waiting for its response, which is discarded when it
+// comes, so the next request may be written before the previous one is answered.
+// Closing stdin asks the plugin to exit. Anything on stderr is logged.
+type PluginClient struct {
+	logger       *zap.Logger
+	name         string
+	instructions string
+	language     string
+	system       string
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+
+	// mu guards the fields below and the writes to stdin.
+	mu         sync.Mutex
+	nextID     int
+	lastUsage  *Usage
+	promptHook PromptHook
+
+	// pending holds the channel each unanswered call waits on, by request id, and
+	// readErr the error that stopped readResponses, after which every call fails.
+	pending map[int]chan pluginResponse
+	readErr error
+}
+
+// NewPluginClient starts the plugin for the configured provider, found on PATH.
+// Prompt instructions, language and system instruction are forwarded with every request, and network settings
+// (proxy, CA file, endpoint) are passed to the plugin as environment variables.
+func NewPluginClient(ctx context.Context, logger *zap.Logger, cfg *config.Config) (*PluginClient, error) {
+	name := cfg.LLM.Provider
+	path, err := exec.LookPath(ProviderPluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown LLM provider %q (no %s%s executable on PATH): %w", name, ProviderPluginPrefix, name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), httpclient.PluginEnv(cfg.Network)...)
+	if cfg.LLM.Endpoint != "" {
+		cmd.Env = append(cmd.Env, "HISTORAI_LLM_ENDPOINT="+cfg.LLM.Endpoint)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		logger.Error("Failed to start provider plugin", zap.String("path", path), zap.Error(err))
+		return nil, fmt.Errorf("failed to start provider plugin %q: %w", name, err)
+	}
+	logger.Debug("Started LLM provider plugin", zap.String("provider", name), zap.String("path", path))
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Debug("Provider plugin stderr", zap.String("provider", name), zap.String("line", scanner.Text()))
+		}
+	}()
+
+	c := &PluginClient{
+		logger:       logger,
+		name:         name,
+		instructions: cfg.Prompt.Instructions,
+		language:     cfg.Prompt.Language,
+		system:       strings.TrimSpace(cfg.Prompt.System),
+		cmd:          cmd,
+		stdin:        stdin,
+		pending:      make(map[int]chan pluginResponse),
+	}
+	go c.readResponses(bufio.NewReader(stdout))
+	return c, nil
+}
+
+// Find implements the LLMClient interface method.
+func (c *PluginClient) Find(ctx context.Context, req FindRequest) (FindResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return FindResponse{}, err
+	}
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodFind, Query: req.Query, History: historyContext, Options: requestOptions(req.Options)})
+	return FindResponse{newCommandAnswer(result, historyContext, NoMatchesResponse)}, err
+}
+
+// Suggest implements the LLMClient interface method.
+func (c *PluginClient) Suggest(ctx context.Context, req SuggestRequest) (SuggestResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return SuggestResponse{}, err
+	}
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodSuggest, Query: req.Task, History: historyContext, Workflows: req.Workflows, Environment: req.Environment, Options: requestOptions(req.Options)})
+	return SuggestResponse{newCommandAnswer(result, historyContext, CannotSuggestResponse)}, err
+}
+
+// requestOptions returns the options to send with a request, or nil when none are
+// set, so that plugins see no "options" field.
+func requestOptions(options Options) *Options {
+	if options == (Options{}) {
+		return nil
+	}
+	return &options
+}
+
+// ExplainCommand implements the LLMClient interface method.
+func (c *PluginClient) ExplainCommand(ctx context.Context, req ExplainRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.send(ctx, pluginRequest{Method: pluginMethodExplain, Query: req.Command, History: lastEntries(req.History, req.Options.HistoryLimit), Options: requestOptions(req.Options)})
+}
+
+// FixCommand implements the LLMClient interface method.
+func (c *PluginClient) FixCommand(ctx context.Context, req FixRequest) (CommandAnswer, error) {
+	if err := ctx.Err(); err != nil {
+		return CommandAnswer{}, err
+	}
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodFix, Query: req.Failed.Command, History: historyContext, Failed: &req.Failed, Options: requestOptions(req.Options)})
+	return newCommandAnswer(result, historyContext, CannotFixResponse), err
+}
+
+// DiagnoseFailure implements the LLMClient interface method.
+func (c *PluginClient) DiagnoseFailure(ctx context.Context, req FixRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.send(ctx, pluginRequest{Method: pluginMethodWhy, Query: req.Failed.Command, History: lastEntries(req.History, req.Options.HistoryLimit), Failed: &req.Failed, Options: requestOptions(req.Options)})
+}
+
+// PredictNext implements the LLMClient interface method.
+func (c *PluginClient) PredictNext(ctx context.Context, req NextRequest) (CommandAnswer, error) {
+	if err := ctx.Err(); err != nil {
+		return CommandAnswer{}, err
+	}
+	query := ""
+	if len(req.Recent) > 0 {
+		query = req.Recent[len(req.Recent)-1].Command
+	}
+	historyContext := lastEntries(req.History, req.Options.HistoryLimit)
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodNext, Query: query, History: historyContext, Recent: req.Recent, Options: requestOptions(req.Options)})
+	return newCommandAnswer(result, slices.Concat(historyContext, req.Recent), CannotPredictResponse), err
+}
+
+// Chat implements the LLMClient interface method.
+func (c *PluginClient) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	query := ""
+	if len(req.Messages) > 0 {
+		query = req.Messages[len(req.Messages)-1].Content
+	}
+	return c.send(ctx, pluginRequest{Method: pluginMethodChat, Query: query, History: lastEntries(req.History, req.Options.HistoryLimit), Messages: req.Messages, Options: requestOptions(req.Options)})
+}
+
+// AnswerQuestion implements the LLMClient interface method.
+func (c *PluginClient) AnswerQuestion(ctx context.Context, req AskRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.send(ctx, pluginRequest{Method: pluginMethodAsk, Query: req.Question, History: lastEntries(req.History, req.Options.HistoryLimit), Options: requestOptions(req.Options)})
+}
+
+// SummarizeActivity implements the LLMClient interface method.
+func (c *PluginClient) SummarizeActivity(ctx context.Context, req SummaryRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.send(ctx, pluginRequest{Method: pluginMethodSummary, Query: req.Period, Groups: req.Groups, Options: requestOptions(req.Options)})
+}
+
+// FillSnippet implements the LLMClient interface method.
+func (c *PluginClient) FillSnippet(ctx context.Context, req FillRequest) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodFill, Query: req.Description, History: lastEntries(req.History, req.Options.HistoryLimit), Snippet: &req.Snippet, Options: requestOptions(req.Options)})
+	if err != nil {
+		return nil, err
+	}
+	return parsePlaceholderValues(req.Snippet, result)
+}
+
+// WriteScript implements the LLMClient interface method. The commands are sent as
+// the history and the purpose as the query.
+func (c *PluginClient) WriteScript(ctx context.Context, req ScriptRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodScript, Query: req.Purpose, History: req.Commands, Options: requestOptions(req.Options)})
+	if err != nil {
+		return "", err
+	}
+	return stripCodeFence(result), nil
+}
+
+// WriteBuildTargets implements the LLMClient interface method.
+func (c *PluginClient) WriteBuildTargets(ctx context.Context, req TargetsRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodTargets, Query: req.Format, Workflows: req.Workflows, Options: requestOptions(req.Options)})
+	if err != nil {
+		return "", err
+	}
+	return stripCodeFence(result), nil
+}
+
+// WriteQuiz implements the LLMClient interface method.
+func (c *PluginClient) WriteQuiz(ctx context.Context, req QuizRequest) ([]QuizQuestion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodQuiz, History: req.Commands, Count: req.Count, Options: requestOptions(req.Options)})
+	if err != nil {
+		return nil, err
+	}
+	return parseQuizQuestions(result)
+}
+
+// GradeAnswer implements the LLMClient interface method.
+func (c *PluginClient) GradeAnswer(ctx context.Context, req GradeRequest) (QuizGrade, error) {
+	if err := ctx.Err(); err != nil {
+		return QuizGrade{}, err
+	}
+	result, err := c.send(ctx, pluginRequest{Method: pluginMethodGrade, Query: req.Answer, Question: &req.Question, Options: requestOptions(req.Options)})
+	if err != nil {
+		return QuizGrade{}, err
+	}
+	return parseQuizGrade(result)
+}
+
+// SetPromptHook implements the PromptObserver interface method. The prompts are the
+// JSON request lines.
+func (c *PluginClient) SetPromptHook(hook PromptHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.promptHook = hook
+}
+
+// LastUsage implements the UsageReporter interface method.
+func (c *PluginClient) LastUsage() (Usage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastUsage == nil {
+		return Usage{}, false
+	}
+	return *c.lastUsage, true
+}
+
+// Close closes the plugin's stdin and waits for it to exit.
+func (c *PluginClient) Close() error {
+	if err := c.stdin.Close(); err != nil {
+		return err
+	}
+	return c.cmd.Wait()
+}
+
+// send fills in the request id and configured prompt settings, writes req and
+// waits for the matching response, or for ctx to be done.
+func (c *PluginClient) send(ctx context.Context, req pluginRequest) (string, error) {
+	c.mu.Lock()
+	if c.readErr != nil {
+		defer c.mu.Unlock()
+		return "", c.readErr
+	}
+	c.nextID++
+	req.ID = c.nextID
+	req.Instructions = c.instructions
+	req.Language = c.language
+	req.System = c.system
+	if req.History == nil {
+		req.History = []history.HistoryEntry{} // always send an array, never null
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		c.mu.Unlock()
+		return "", fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+	// The request line is the plugin's whole prompt.
+	if c.promptHook != nil {
+		if err := c.promptHook(string(data)); err != nil {
+			c.mu.Unlock()
+			return "", err
+		}
+	}
+	answered := make(chan pluginResponse, 1)
+	c.pending[req.ID] = answered
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+		return "", fmt.Errorf("failed to send request to provider plugin %q: %w", c.name, err)
+	}
+	c.mu.Unlock()
+
+	var resp pluginResponse
+	select {
+	case r, ok := <-answered:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return "", c.readErr
+		}
+		resp = r
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+		return "", ctx.Err()
+	}
+
+	c.mu.Lock()
+	c.lastUsage = resp.Usage
+	c.mu.Unlock()
+	if resp.Error != "" {
+		c.logger.Error("Provider plugin returned an error", zap.String("provider", c.name), zap.String("error", resp.Error))
+		return "", errors.New(resp.Error)
+	}
+
+	return strings.TrimSpace(resp.Result), nil
+}
+
+// readResponses reads the plugin's responses until stdout is closed and hands each
+// to the call waiting for it. Responses to cancelled calls are dropped. When stdout
+// can't be read or holds something other than a response, the calls waiting and
+// all later ones fail.
+func (c *PluginClient) readResponses(stdout *bufio.Reader) {
+	for {
+		line, err := stdout.ReadBytes('\n')
+		if err != nil {
+			c.fail(fmt.Errorf("failed to read response from provider plugin %q: %w", c.name, err))
+			return
+		}
+		var resp pluginResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			c.fail(fmt.Errorf("invalid response from provider plugin %q: %w", c.name, err))
+			return
+		}
+
+		c.mu.Lock()
+		answered, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if !ok {
+			c.logger.Debug("Dropping response to a cancelled or unknown request", zap.String("provider", c.name), zap.Int("id", resp.ID))
+			continue
+		}
+		answered <- resp
+	}
+}
+
+// fail records err as the reason calls fail from now on, and wakes those waiting.
+func (c *PluginClient) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readErr = err
+	for id, answered := range c.pending {
+		close(answered)
+		delete(c.pending, id)
+	}
+}
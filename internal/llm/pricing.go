@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+)
+
+// Price is what a model charges, in US dollars per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// modelPrices are the list prices of Gemini models for prompts up to 128k tokens,
+// keyed by model name prefix. They are a guide for choosing a model, not a bill.
+var modelPrices = map[string]Price{
+	"gemini-1.5-flash-8b": {Input: 0.0375, Output: 0.15},
+	"gemini-1.5-flash":    {Input: 0.075, Output: 0.30},
+	"gemini-1.5-pro":      {Input: 1.25, Output: 5.00},
+	"gemini-2.0-flash":    {Input: 0.10, Output: 0.40},
+	"gemini-2.5-flash":    {Input: 0.30, Output: 2.50},
+	"gemini-2.5-pro":      {Input: 1.25, Output: 10.00},
+}
+
+// KnownModels returns the names of the models with a known price, sorted, e.g. to
+// offer when the provider can't list its models.
+func KnownModels() []string {
+	models := make([]string, 0, len(modelPrices))
+	for model := range modelPrices {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// Cost returns the cost in US dollars of usage with model, and false if the model's
+// price is unknown. The longest matching name prefix wins, so that versioned names
+// such as gemini-1.5-flash-002 are priced like their family.
+func Cost(model string, usage Usage) (float64, bool) {
+	var price Price
+	best := ""
+	for prefix, p := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			price, best = p, prefix
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.OutputTokens)*price.Output) / 1e6, true
+}
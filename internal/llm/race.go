@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// RaceClient sends find, suggest and next to two clients at once, keeping the first
+// acceptable answer and cancelling the other request, for when latency matters more
+// than tokens, as in the shell widgets. Other methods use the primary client only.
+// Answers are not streamed, as the winner is only known once it has answered.
+type RaceClient struct {
+	LLMClient
+	rival LLMClient
+
+	logger      *zap.Logger
+	primaryName string
+	rivalName   string
+
+	// winner is the client whose answer the last race returned, the primary until
+	// the first race, whose usage LastUsage reports.
+	mu     sync.Mutex
+	winner LLMClient
+}
+
+// raceOutcome is the answer of one of the raced clients.
+type raceOutcome[T any] struct {
+	answer T
+	err    error
+	rival  bool
+}
+
+// NewRaceClient creates a RaceClient racing primary against rival. The names
+// identify them in logs.
+func NewRaceClient(logger *zap.Logger, primary, rival LLMClient, primaryName, rivalName string) *RaceClient {
+	return &RaceClient{
+		LLMClient:   primary,
+		rival:       rival,
+		logger:      logger,
+		primaryName: primaryName,
+		rivalName:   rivalName,
+		winner:      primary,
+	}
+}
+
+// Find implements the LLMClient interface method.
+func (c *RaceClient) Find(ctx context.Context, req FindRequest) (FindResponse, error) {
+	return race(ctx, c, "find",
+		func(ctx context.Context, client LLMClient) (FindResponse, error) { return client.Find(ctx, req) },
+		func(resp FindResponse) bool { return !resp.Empty })
+}
+
+// Suggest implements the LLMClient interface method.
+func (c *RaceClient) Suggest(ctx context.Context, req SuggestRequest) (SuggestResponse, error) {
+	return race(ctx, c, "suggest",
+		func(ctx context.Context, client LLMClient) (SuggestResponse, error) { return client.Suggest(ctx, req) },
+		func(resp SuggestResponse) bool { return !resp.Empty })
+}
+
+// PredictNext implements the LLMClient interface method.
+func (c *RaceClient) PredictNext(ctx context.Context, req NextRequest) (CommandAnswer, error) {
+	return race(ctx, c, "next",
+		func(ctx context.Context, client LLMClient) (CommandAnswer, error) {
+			return client.PredictNext(ctx, req)
+		},
+		func(answer CommandAnswer) bool { return !answer.Empty })
+}
+
+// race calls both clients of c and returns the first answer without error that
+// acceptable accepts, cancelling the other call. When neither is acceptable, the
+// primary's answer is returned, unless only it failed.
+func race[T any](ctx context.Context, c *RaceClient, op string, call func(ctx context.Context, client LLMClient) (T, error), acceptable func(T) bool) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Both calls can send their outcome without waiting, so that the loser's
+	// goroutine ends once its call does.
+	outcomes := make(chan raceOutcome[T], 2)
+	for _, rival := range []bool{false, true} {
+		client := c.client(rival)
+		go func() {
+			answer, err := call(ctx, client)
+			outcomes <- raceOutcome[T]{answer: answer, err: err, rival: rival}
+		}()
+	}
+
+	var primary, rival raceOutcome[T]
+	for range 2 {
+		outcome := <-outcomes
+		if outcome.err == nil && acceptable(outcome.answer) {
+			c.logger.Debug("Race won", zap.String("op", op), zap.String("model", c.name(outcome.rival)))
+			c.setWinner(outcome.rival)
+			return outcome.answer, nil
+		}
+		c.logger.Debug("Raced model gave no acceptable answer", zap.String("op", op), zap.String("model", c.name(outcome.rival)), zap.Error(outcome.err))
+		if outcome.rival {
+			rival = outcome
+		} else {
+			primary = outcome
+		}
+	}
+	if primary.err != nil && rival.err == nil {
+		c.setWinner(true)
+		return rival.answer, nil
+	}
+	c.setWinner(false)
+	return primary.answer, primary.err
+}
+
+// name returns the name of the rival client, or of the primary one.
+func (c *RaceClient) name(rival bool) string {
+	if rival {
+		return c.rivalName
+	}
+	return c.primaryName
+}
+
+// client returns the rival client, or the primary one.
+func (c *RaceClient) client(rival bool) LLMClient {
+	if rival {
+		return c.rival
+	}
+	return c.LLMClient
+}
+
+// setWinner records whose answer the last race returned.
+func (c *RaceClient) setWinner(rival bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.winner = c.client(rival)
+}
+
+// LastUsage implements the UsageReporter interface method, reporting the usage of
+// the client whose answer the last find, suggest or next returned. The tokens the
+// cancelled call used are not counted.
+func (c *RaceClient) LastUsage() (Usage, bool) {
+	c.mu.Lock()
+	winner := c.winner
+	c.mu.Unlock()
+	if reporter, ok := winner.(UsageReporter); ok {
+		return reporter.LastUsage()
+	}
+	return Usage{}, false
+}
+
+// SetPromptHook implements the PromptObserver interface method, observing the
+// prompts of both clients with hook.
+func (c *RaceClient) SetPromptHook(hook PromptHook) {
+	c.SetPromptHooks(hook, hook)
+}
+
+// SetPromptHooks observes the prompts of the primary client with primary, and those
+// of the rival with rival, e.g. to record which model each prompt went to.
+func (c *RaceClient) SetPromptHooks(primary, rival PromptHook) {
+	if observer, ok := c.LLMClient.(PromptObserver); ok {
+		observer.SetPromptHook(primary)
+	}
+	if observer, ok := c.rival.(PromptObserver); ok {
+		observer.SetPromptHook(rival)
+	}
+}
+
+// SetHistorySearcher implements the ToolCaller interface method for both clients.
+func (c *RaceClient) SetHistorySearcher(searcher HistorySearcher) {
+	for _, client := range []LLMClient{c.LLMClient, c.rival} {
+		if caller, ok := client.(ToolCaller); ok {
+			caller.SetHistorySearcher(searcher)
+		}
+	}
+}
+
+// Verify implements the Verifier interface method, checking both clients.
+func (c *RaceClient) Verify(ctx context.Context) error {
+	if verifier, ok := c.LLMClient.(Verifier); ok {
+		if err := verifier.Verify(ctx); err != nil {
+			return err
+		}
+	}
+	if verifier, ok := c.rival.(Verifier); ok {
+		if err := verifier.Verify(ctx); err != nil {
+			return fmt.Errorf("llm.race model %s: %w", c.rivalName, err)
+		}
+	}
+	return nil
+}
+
+// Close closes both clients.
+func (c *RaceClient) Close() error {
+	return errors.Join(c.LLMClient.Close(), c.rival.Close())
+}
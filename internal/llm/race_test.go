@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// racer is an LLMClient answering Find with answer, and reporting usage.
+type racer struct {
+	LLMClient
+	answer CommandAnswer
+	usage  Usage
+}
+
+func (r *racer) Find(context.Context, FindRequest) (FindResponse, error) {
+	return FindResponse{r.answer}, nil
+}
+
+func (r *racer) LastUsage() (Usage, bool) {
+	return r.usage, true
+}
+
+func TestRaceClientLastUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		primaryAnswer CommandAnswer
+		rivalAnswer   CommandAnswer
+		want          int
+	}{
+		{"primary answers", CommandAnswer{Text: "ls"}, CommandAnswer{Empty: true}, 10},
+		{"rival answers", CommandAnswer{Empty: true}, CommandAnswer{Text: "ls"}, 20},
+		{"neither answers", CommandAnswer{Empty: true}, CommandAnswer{Empty: true}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &racer{answer: tt.primaryAnswer, usage: Usage{PromptTokens: 10}}
+			rival := &racer{answer: tt.rivalAnswer, usage: Usage{PromptTokens: 20}}
+			c := NewRaceClient(zap.NewNop(), primary, rival, "primary", "rival")
+
+			if _, err := c.Find(context.Background(), FindRequest{Query: "list files"}); err != nil {
+				t.Fatal(err)
+			}
+			usage, ok := c.LastUsage()
+			if !ok || usage.PromptTokens != tt.want {
+				t.Errorf("LastUsage() = %+v, %v, want %d prompt tokens", usage, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// The exact phrases the prompts ask the model to answer when it has no command to
+// give.
+const (
+	noMatchesAnswer     = "No relevant commands found."
+	cannotSuggestAnswer = "Cannot suggest a command for this task."
+	cannotFixAnswer     = "Cannot fix this command."
+	cannotPredictAnswer = "Cannot predict the next command."
+)
+
+// The messages of answers without commands, shown instead of them.
+const (
+	NoMatchesResponse     = "(No relevant commands found)"
+	CannotSuggestResponse = "(AI could not suggest a command for this task)"
+	CannotFixResponse     = "(AI could not fix this command)"
+	CannotPredictResponse = "(AI could not predict the next command)"
+)
+
+// Risk rates how much harm running a command can do.
+type Risk string
+
+// Values of CommandResult.Risk.
+const (
+	RiskLow Risk = "low"
+
+	// RiskHigh marks commands that can destroy data or a system or run code from the
+	// network, and those the answer warned about.
+	RiskHigh Risk = "high"
+)
+
+// Source says where a command comes from.
+type Source string
+
+// Values of CommandResult.Source.
+const (
+	// SourceHistory marks commands that were run before, as found in the history.
+	SourceHistory Source = "history"
+
+	// SourceModel marks commands the model wrote.
+	SourceModel Source = "model"
+
+	// SourceTeam marks commands from the snippets shared by the user's team.
+	SourceTeam Source = "team"
+)
+
+// CommandResult is a command of an answer, with what the answer says about it.
+type CommandResult struct {
+	Command     string `json:"command" yaml:"command"`
+	Explanation string `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	Risk        Risk   `json:"risk,omitempty" yaml:"risk,omitempty"`
+
+	// Source is empty when not known, e.g. for commands read from an answer as it
+	// streams in.
+	Source Source `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// CommandAnswer is the answer of find, suggest, fix and next.
+type CommandAnswer struct {
+	// Text is the answer as written, e.g. with comments and markdown, for display.
+	Text string `json:"text,omitempty"`
+
+	// Commands are the commands of Text, best first.
+	Commands []CommandResult `json:"commands,omitempty"`
+
+	// Message says why there are no Commands, e.g. NoMatchesResponse.
+	Message string `json:"message,omitempty"`
+
+	// Empty reports that the model's answer was empty even when asked again, as
+	// opposed to saying it had no command. Message is then EmptyResponse.
+	Empty bool `json:"empty,omitempty"`
+}
+
+// String returns the answer as shown to users: Text, or Message if there are no
+// commands.
+func (a CommandAnswer) String() string {
+	if len(a.Commands) == 0 && a.Message != "" {
+		return a.Message
+	}
+	return a.Text
+}
+
+// newCommandAnswer reads the commands of text. Those found in historyContext come
+// from the history, or from the team's snippets, and the others from the model. An
+// answer without any has message.
+func newCommandAnswer(text string, historyContext []history.HistoryEntry, message string) CommandAnswer {
+	answer := CommandAnswer{Text: strings.TrimSpace(text), Commands: ParseCommands(text)}
+	if len(answer.Commands) == 0 {
+		answer.Message = message
+		return answer
+	}
+
+	// A command the user ran themselves comes from their history, even if the team
+	// shares it too.
+	known := make(map[string]Source, len(historyContext))
+	for _, entry := range historyContext {
+		command := strings.TrimSpace(entry.Command)
+		if entry.Team == "" {
+			known[command] = SourceHistory
+		} else if _, ok := known[command]; !ok {
+			known[command] = SourceTeam
+		}
+	}
+	for i, command := range answer.Commands {
+		answer.Commands[i].Source = SourceModel
+		if source, ok := known[command.Command]; ok {
+			answer.Commands[i].Source = source
+		}
+	}
+	return answer
+}
+
+// emptyCommandAnswer is the answer when the model's response was empty.
+func emptyCommandAnswer() CommandAnswer {
+	return CommandAnswer{Message: EmptyResponse, Empty: true}
+}
+
+// RiskOf rates the risk of command by itself, without what an answer says about it.
+func RiskOf(command string) Risk {
+	if riskyCommand.MatchString(command) {
+		return RiskHigh
+	}
+	return RiskLow
+}
+
+// markdownLine matches lines that only make sense as markdown prose: headings
+// (with a space after at least two #, so shell comments don't count), list items,
+// numbered steps and bold text.
+var markdownLine = regexp.MustCompile(`^(#{2,6} |[-*] |\d+\. |.*\*\*[^*]+\*\*)`)
+
+// IsMarkdownProse reports whether line is markdown prose rather than a command.
+func IsMarkdownProse(line string) bool {
+	return markdownLine.MatchString(line)
+}
+
+// ParseCommands returns the commands of an answer, in order; see CommandParser.
+func ParseCommands(text string) []CommandResult {
+	var parser CommandParser
+	var commands []CommandResult
+	for _, line := range strings.Split(text, "\n") {
+		if command, ok := parser.AddLine(line); ok {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// CommandParser turns the lines of an answer into commands. Comment lines ("# ...")
+// become the explanation of the command that follows them, and make it a high risk
+// when they start with "warning". Once a markdown code fence has been seen, only
+// lines inside fences are commands and the prose around them is explanation;
+// markdown prose outside fences is always explanation. The answers the prompts ask
+// for when there is no command, such as "No relevant commands found.", are skipped.
+type CommandParser struct {
+	pendingExplanation []string
+	warned             bool
+	fenced             bool
+	inFence            bool
+}
+
+// AddLine consumes one line and returns the command it completes, if any.
+func (p *CommandParser) AddLine(line string) (CommandResult, bool) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "```"):
+		p.fenced = true
+		p.inFence = !p.inFence
+		return CommandResult{}, false
+	case line == "", isNoCommandAnswer(line):
+		return CommandResult{}, false
+	case strings.HasPrefix(line, "#"),
+		p.fenced && !p.inFence,
+		!p.inFence && markdownLine.MatchString(line):
+		explanation := strings.TrimSpace(strings.TrimLeft(line, "#-* "))
+		p.warned = p.warned || strings.HasPrefix(strings.ToLower(explanation), "warning")
+		p.pendingExplanation = append(p.pendingExplanation, explanation)
+		return CommandResult{}, false
+	default:
+		// Models occasionally wrap a command in inline-code backticks.
+		if len(line) > 1 && strings.HasPrefix(line, "`") && strings.HasSuffix(line, "`") {
+			line = strings.Trim(line, "`")
+		}
+		command := CommandResult{
+			Command:     line,
+			Explanation: strings.Join(p.pendingExplanation, " "),
+			Risk:        RiskOf(line),
+		}
+		if p.warned {
+			command.Risk = RiskHigh
+		}
+		p.pendingExplanation = nil
+		p.warned = false
+		return command, true
+	}
+}
+
+// isNoCommandAnswer reports whether line is the answer a prompt asks for when the
+// model has no command to give.
+func isNoCommandAnswer(line string) bool {
+	switch line {
+	case noMatchesAnswer, cannotSuggestAnswer, cannotFixAnswer, cannotPredictAnswer:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package llm
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Names of the prompt templates users can override.
+const (
+	TemplateFind    = "find"
+	TemplateSuggest = "suggest"
+)
+
+// TemplateSystem names the template a prompt template defines, with
+// {{define "system"}}, to render the system instruction sent along with the prompt:
+// the model's role and instructions, so that the prompt only holds the query and its
+// context. Templates that don't define it send everything as the prompt.
+const TemplateSystem = "system"
+
+// TemplateNames lists the prompt templates users can override, each in a file named
+// after it with a .tmpl extension.
+var TemplateNames = []string{TemplateFind, TemplateSuggest}
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// PromptData is what prompt templates are executed with.
+type PromptData struct {
+	// Query is the search query for find, or the task description for suggest.
+	Query string
+
+	// History holds the history entries sent as context, oldest first, already
+	// limited and redacted.
+	History []history.HistoryEntry
+
+	// Saved holds the commands among History the user saved, for suggest.
+	Saved []history.HistoryEntry
+
+	// Team holds the snippets shared by the user's team (see team.repo), which
+	// History leaves out.
+	Team []history.HistoryEntry
+
+	// Workflows holds the sequences of steps the user habitually runs, for suggest.
+	Workflows []Workflow
+
+	// Environment describes the machine suggested commands will run on, or is nil.
+	Environment *Environment
+
+	// Annotated reports that some entries carry the user's tags or notes.
+	Annotated bool
+
+	// HasHosts reports that some entries ran on other machines.
+	HasHosts bool
+
+	// Anonymized reports that the arguments of the commands were replaced by typed
+	// placeholders (redaction.anonymize).
+	Anonymized bool
+
+	// Instructions and Language are prompt.instructions and prompt.language.
+	Instructions string
+	Language     string
+
+	// OS is the operating system (runtime.GOOS, e.g. "linux" or "darwin") and Shell
+	// the name of the user's shell, e.g. "zsh": the caller's, or the one from $SHELL.
+	OS    string
+	Shell string
+}
+
+// templateFuncs are the functions available in prompt templates, rendering parts
+// of a prompt the way the built-in prompts do.
+var templateFuncs = template.FuncMap{
+	// history lists entries under a header; timedHistory adds when and where they ran.
+	"history": func(header string, entries []history.HistoryEntry) string {
+		return formatHistoryContext(header, entries, 0)
+	},
+	"timedHistory": func(header string, entries []history.HistoryEntry) string {
+		return formatTimedHistoryContext(header, entries, 0)
+	},
+	"workflows":    formatWorkflows,
+	"environment":  formatEnvironment,
+	"annotation":   formatAnnotation,
+	"instructions": formatInstructions,
+	"language":     formatLanguage,
+}
+
+// PromptTemplates renders the prompts that users can override with their own
+// text/template files.
+type PromptTemplates struct {
+	logger    *zap.Logger
+	templates map[string]*template.Template
+	defaults  map[string]*template.Template
+}
+
+// TemplatesDir returns the directory prompt templates are loaded from: prompt.templates,
+// or "templates" in historai's config directory.
+func TemplatesDir(cfg *config.Config) (string, error) {
+	if cfg.Prompt.Templates != "" {
+		return config.ExpandHome(cfg.Prompt.Templates), nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "templates"), nil
+}
+
+// DefaultTemplate returns the built-in text of the named prompt template.
+func DefaultTemplate(name string) ([]byte, error) {
+	return defaultTemplates.ReadFile("templates/" + name + ".tmpl")
+}
+
+// LoadPromptTemplates parses the built-in templates and the user's overrides in dir.
+// A missing dir or file means the built-in template is used; a template that
+// doesn't parse is an error, so that mistakes are noticed.
+func LoadPromptTemplates(logger *zap.Logger, dir string) (*PromptTemplates, error) {
+	t := &PromptTemplates{
+		logger:    logger,
+		templates: make(map[string]*template.Template),
+		defaults:  make(map[string]*template.Template),
+	}
+	for _, name := range TemplateNames {
+		text, err := DefaultTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		t.defaults[name] = template.Must(template.New(name).Funcs(templateFuncs).Parse(string(text)))
+		t.templates[name] = t.defaults[name]
+
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name+".tmpl")
+		text, err = os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		custom, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+		}
+		logger.Debug("Using custom prompt template", zap.String("path", path))
+		t.templates[name] = custom
+	}
+	return t, nil
+}
+
+// Render executes the named template with data, filling in OS and Shell, and returns
+// the system instruction it defines, if any, and the prompt. If a custom template
+// fails, e.g. on a field that doesn't exist, the built-in one is used.
+func (t *PromptTemplates) Render(name string, data PromptData) (system, prompt string) {
+	data.OS = runtime.GOOS
+	if data.Shell == "" {
+		data.Shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	if data.Shell == "." {
+		data.Shell = ""
+	}
+
+	system, prompt, err := execute(t.templates[name], data)
+	if err == nil {
+		return system, prompt
+	}
+	t.logger.Warn("Custom prompt template failed; using the built-in one", zap.String("template", name), zap.Error(err))
+	// The built-in templates only use fields that exist, so they can't fail.
+	system, prompt, _ = execute(t.defaults[name], data)
+	return system, prompt
+}
+
+// execute executes tmpl and the system template it defines, if any, with data.
+func execute(tmpl *template.Template, data PromptData) (system, prompt string, err error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	prompt = b.String()
+	if tmpl.Lookup(TemplateSystem) == nil {
+		return "", prompt, nil
+	}
+	b.Reset()
+	if err := tmpl.ExecuteTemplate(&b, TemplateSystem, data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(b.String()), prompt, nil
+}
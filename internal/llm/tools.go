@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Names of the tools the model can call to look at more of the user's history.
+const (
+	toolFetchHistory  = "fetch_history"
+	toolSearchHistory = "search_history"
+)
+
+const (
+	// toolDefaultEntries and toolMaxEntries are the default and maximum number of
+	// entries a single tool call returns.
+	toolDefaultEntries = 100
+	toolMaxEntries     = 500
+)
+
+// HistorySearcher gives the model access to more of the user's history than was
+// sent with the prompt. Entries are returned oldest first, redacted and annotated
+// like the prompt's own.
+type HistorySearcher interface {
+	// HistoryBefore returns the limit most recent entries run before before (when
+	// not zero), once the skip most recent entries of the history are left out.
+	HistoryBefore(before time.Time, skip, limit int) ([]history.HistoryEntry, error)
+
+	// SearchHistory returns up to limit of the most recent entries whose command,
+	// tags or note contain keyword, ignoring case.
+	SearchHistory(keyword string, limit int) ([]history.HistoryEntry, error)
+}
+
+// ToolCaller is implemented by LLM clients that can let the model call tools to
+// fetch more history while answering, in a bounded loop (see llm.max_tool_calls).
+type ToolCaller interface {
+	SetHistorySearcher(searcher HistorySearcher)
+}
+
+// historyTools declares the history tools to Gemini.
+func historyTools() []*genai.Tool {
+	limit := &genai.Schema{
+		Type:        genai.TypeInteger,
+		Description: fmt.Sprintf("Maximum number of entries to return (default %d, at most %d).", toolDefaultEntries, toolMaxEntries),
+	}
+	return []*genai.Tool{{
+		FunctionDeclarations: []*genai.FunctionDeclaration{
+			{
+				Name:        toolFetchHistory,
+				Description: "Fetch older shell history entries than those listed in the prompt, most recent first, returned oldest first with their time and directory.",
+				Parameters: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"before": {
+							Type:        genai.TypeString,
+							Description: "Only return entries run before this date or time, as YYYY-MM-DD or YYYY-MM-DD HH:MM. Optional.",
+						},
+						"skip": {
+							Type:        genai.TypeInteger,
+							Description: "Number of most recent entries to skip, e.g. the number of entries already listed in the prompt. Optional.",
+						},
+						"limit": limit,
+					},
+				},
+			},
+			{
+				Name:        toolSearchHistory,
+				Description: "Search the user's whole shell history for entries whose command, tags or note contain a keyword, ignoring case. Returns the most recent matches, oldest first, with their time and directory.",
+				Parameters: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"keyword": {
+							Type:        genai.TypeString,
+							Description: "Text to look for, e.g. a program name, host or path.",
+						},
+						"limit": limit,
+					},
+					Required: []string{"keyword"},
+				},
+			},
+		},
+	}}
+}
+
+// callHistoryTool runs a tool call of the model against searcher and returns the
+// result to send back, describing failures rather than failing the request.
+func callHistoryTool(searcher HistorySearcher, call genai.FunctionCall) string {
+	limit := toolDefaultEntries
+	if n, ok := call.Args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), toolMaxEntries)
+	}
+
+	var entries []history.HistoryEntry
+	var err error
+	switch call.Name {
+	case toolFetchHistory:
+		var before time.Time
+		if value, _ := call.Args["before"].(string); value != "" {
+			if before, err = parseToolTime(value); err != nil {
+				return err.Error()
+			}
+		}
+		skip, _ := call.Args["skip"].(float64)
+		entries, err = searcher.HistoryBefore(before, max(int(skip), 0), limit)
+	case toolSearchHistory:
+		keyword, _ := call.Args["keyword"].(string)
+		if strings.TrimSpace(keyword) == "" {
+			return "keyword is required"
+		}
+		entries, err = searcher.SearchHistory(keyword, limit)
+	default:
+		return fmt.Sprintf("unknown tool %q", call.Name)
+	}
+	if err != nil {
+		return "failed to read history: " + err.Error()
+	}
+	if len(entries) == 0 {
+		return "No matching history entries."
+	}
+	return formatTimedHistoryContext(fmt.Sprintf("%d history entries", len(entries)), entries, 0)
+}
+
+// parseToolTime parses a date or time given by the model, in local time.
+func parseToolTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or YYYY-MM-DD HH:MM)", value)
+}
+
+// SetHistorySearcher implements the ToolCaller interface method.
+func (c *GeminiClient) SetHistorySearcher(searcher HistorySearcher) {
+	c.searcher = searcher
+}
+
+// toolsEnabled reports whether the model may call the history tools.
+func (c *GeminiClient) toolsEnabled() bool {
+	return c.searcher != nil && c.maxToolCalls > 0
+}
+
+// generateWithTools is like generate, but lets the model call the history tools, up
+// to maxToolCalls rounds, before it answers. Tool results are shown to the prompt
+// hook, as they are sent to the model too. The answer isn't streamed while tools
+// may be called, so it is passed to onChunk at once.
+func (c *GeminiClient) generateWithTools(ctx context.Context, call geminiCall, onChunk ChunkFunc) (string, error) {
+	if !c.toolsEnabled() {
+		return c.generate(ctx, call, onChunk)
+	}
+	if err := c.observePrompt(call.system, call.prompt); err != nil {
+		return "", err
+	}
+
+	// modelFor returns a copy of the model, so that tools don't leak into other requests.
+	model := c.modelFor(call)
+	model.Tools = historyTools()
+	session := model.StartChat()
+	var usage Usage
+	parts := []genai.Part{genai.Text(call.prompt)}
+	for round := 0; ; round++ {
+		if round == c.maxToolCalls {
+			// Out of tool calls: the model must answer with what it has.
+			model.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingNone}}
+		}
+		resp, err := session.SendMessage(ctx, parts...)
+		if err := c.checkResponse(resp, err); err != nil {
+			return "", err
+		}
+		if resp.UsageMetadata != nil {
+			usage.PromptTokens += int(resp.UsageMetadata.PromptTokenCount)
+			usage.OutputTokens += int(resp.UsageMetadata.CandidatesTokenCount)
+		}
+
+		var calls []genai.FunctionCall
+		if len(resp.Candidates) > 0 {
+			calls = resp.Candidates[0].FunctionCalls()
+		}
+		// Tool calls past the limit are ignored, in case the model makes them anyway.
+		if len(calls) == 0 || round == c.maxToolCalls {
+			c.setUsage(usage)
+			text := extractTextFromResponse(resp)
+			if text == "" {
+				c.logger.Warn("Received empty text response from Gemini (or response was blocked)")
+			} else if onChunk != nil {
+				onChunk(text)
+			}
+			return text, nil
+		}
+
+		// The session keeps the parts sent, so don't reuse their slice.
+		parts = nil
+		for _, call := range calls {
+			result := callHistoryTool(c.searcher, call)
+			c.logger.Debug("Model called a history tool", zap.String("tool", call.Name), zap.Any("args", call.Args), zap.Int("round", round+1))
+			if c.promptHook != nil {
+				args, _ := json.Marshal(call.Args)
+				if err := c.promptHook(fmt.Sprintf("Result of %s(%s):\n%s", call.Name, args, result)); err != nil {
+					return "", err
+				}
+			}
+			parts = append(parts, genai.FunctionResponse{Name: call.Name, Response: map[string]any{"result": result}})
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+const (
+	logDirName  = "historai"
+	logFileName = "historai.log"
+)
+
+// DefaultPath returns the location of the log file,
+// $XDG_STATE_HOME/historai/historai.log or ~/.local/state/historai/historai.log.
+func DefaultPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logFileName), nil
+}
+
+// StateDir returns historai's state directory, holding its logs:
+// $XDG_STATE_HOME/historai, or ~/.local/state/historai when XDG_STATE_HOME is not set
+// (%LOCALAPPDATA%\historai\logs on Windows, next to the data directory).
+func StateDir() (string, error) {
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, logDirName), nil
+	}
+	if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" && runtime.GOOS == "windows" {
+		return filepath.Join(localAppData, logDirName, "logs"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", logDirName), nil
+}
+
+// Writer appends to a log file, rotating it when it would grow past maxSize bytes:
+// the file is renamed to <path>.1, older copies shift to <path>.2 and so on, and
+// copies beyond maxBackups are removed. Several processes (the CLI, the daemon,
+// shell widgets) may share the file; a Writer notices when another process rotated
+// it and reopens the path.
+type Writer struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+}
+
+// Open opens path for appending, creating it and its directory as needed.
+func Open(path string, maxSize int64, maxBackups int) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	w := &Writer{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Path returns the file the Writer appends to.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Write appends p to the file, rotating it first if p would make it too large.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := w.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	// Another process may have rotated the file since it was opened.
+	if current, err := os.Stat(w.path); err != nil || !os.SameFile(info, current) {
+		if err := w.reopen(); err != nil {
+			return 0, err
+		}
+		if info, err = w.file.Stat(); err != nil {
+			return 0, err
+		}
+	}
+	if info.Size() > 0 && info.Size()+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return w.file.Write(p)
+}
+
+// Sync flushes the file to disk.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Sync()
+}
+
+// Close closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", w.path, err)
+	}
+	w.file = file
+	return nil
+}
+
+func (w *Writer) reopen() error {
+	_ = w.file.Close()
+	return w.open()
+}
+
+// rotate shifts the backups up by one, dropping the oldest, and starts a new file.
+func (w *Writer) rotate() error {
+	if w.maxBackups < 1 {
+		if err := os.Truncate(w.path, 0); err != nil {
+			return fmt.Errorf("failed to truncate log file: %w", err)
+		}
+		return nil
+	}
+	_ = os.Remove(backupPath(w.path, w.maxBackups))
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(backupPath(w.path, i), backupPath(w.path, i+1))
+	}
+	if err := os.Rename(w.path, backupPath(w.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return w.reopen()
+}
+
+// backupPath returns the name of the n-th most recent rotated copy of path.
+func backupPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
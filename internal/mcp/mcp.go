@@ -0,0 +1,265 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/jsonrpc"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+)
+
+const (
+	protocolVersion = "2024-11-05"
+	serverName      = "historai"
+
+	// defaultHistoryLimit is the number of entries recent_history returns. The other
+	// tools default to find.limit and suggest.limit, like the CLI.
+	defaultHistoryLimit = 50
+)
+
+// Tool names exposed to MCP clients.
+const (
+	toolFindCommands    = "find_commands"
+	toolSuggestCommands = "suggest_commands"
+	toolRecentHistory   = "recent_history"
+)
+
+// Server exposes the engine as Model Context Protocol tools over stdio.
+type Server struct {
+	logger  *zap.Logger
+	engine  *engine.Engine
+	cfg     *config.Config
+	version string
+}
+
+// tool describes a single MCP tool in a tools/list response.
+type tool struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	InputSchema  map[string]any `json:"inputSchema"`
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+}
+
+// toolCallParams are the parameters of a tools/call request.
+type toolCallParams struct {
+	Name      string         `json:"name"`
+	Arguments toolArguments  `json:"arguments"`
+	Meta      map[string]any `json:"_meta,omitempty"`
+}
+
+// toolArguments is the union of the arguments accepted by historai's tools.
+type toolArguments struct {
+	Query            string `json:"query"`
+	Limit            int    `json:"limit"`
+	NoHistoryContext bool   `json:"no_history_context"`
+}
+
+// textContent is an MCP text content block.
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// commandsContent is the structured content of find_commands and suggest_commands.
+type commandsContent struct {
+	Commands []llm.CommandResult `json:"commands"`
+	Message  string              `json:"message,omitempty"`
+}
+
+// toolResult is the result of a tools/call request. StructuredContent holds the
+// same result as Content, for clients that read a tool's output schema.
+type toolResult struct {
+	Content           []textContent `json:"content"`
+	StructuredContent any           `json:"structuredContent,omitempty"`
+	IsError           bool          `json:"isError,omitempty"`
+}
+
+// NewServer creates an MCP server backed by eng. cfg provides the default limits of
+// the tools.
+func NewServer(logger *zap.Logger, eng *engine.Engine, cfg *config.Config, version string) *Server {
+	return &Server{
+		logger:  logger,
+		engine:  eng,
+		cfg:     cfg,
+		version: version,
+	}
+}
+
+// Serve handles MCP messages from r, writing responses to w, until EOF.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	conn := jsonrpc.NewConn(s.logger, r, w)
+	return conn.Serve(ctx, s.handle)
+}
+
+// handle dispatches a single MCP request.
+func (s *Server) handle(_ context.Context, req *jsonrpc.Request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]any{
+				"name":    serverName,
+				"version": s.version,
+			},
+		}, nil
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": tools()}, nil
+	case "tools/call":
+		var params toolCallParams
+		if err := jsonrpc.DecodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.callTool(params), nil
+	default:
+		return nil, jsonrpc.NewError(jsonrpc.CodeMethodNotFound, "method not found: "+req.Method)
+	}
+}
+
+// callTool runs a tool. Tool failures are reported in the result, not as protocol errors.
+func (s *Server) callTool(params toolCallParams) toolResult {
+	s.logger.Debug("MCP tool call", zap.String("tool", params.Name))
+
+	args := params.Arguments
+	var text string
+	var structured any
+	var err error
+	switch params.Name {
+	case toolFindCommands:
+		if args.Query == "" {
+			return errorResult("query cannot be empty")
+		}
+		var answer llm.CommandAnswer
+		answer, err = s.engine.Find(args.Query, limitOrDefault(args.Limit, s.cfg.Find.Limit))
+		text, structured = answer.String(), newCommandsContent(answer)
+	case toolSuggestCommands:
+		if args.Query == "" {
+			return errorResult("task description cannot be empty")
+		}
+		var answer llm.CommandAnswer
+		answer, err = s.engine.Suggest(args.Query, limitOrDefault(args.Limit, s.cfg.Suggest.Limit), args.NoHistoryContext)
+		text, structured = answer.String(), newCommandsContent(answer)
+	case toolRecentHistory:
+		entries, historyErr := s.engine.History(limitOrDefault(args.Limit, defaultHistoryLimit))
+		if historyErr != nil {
+			err = historyErr
+			break
+		}
+		commands := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			commands = append(commands, entry.Command)
+		}
+		text = strings.Join(commands, "\n")
+	default:
+		return errorResult(fmt.Sprintf("unknown tool %q", params.Name))
+	}
+
+	if err != nil {
+		return errorResult(err.Error())
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}, StructuredContent: structured}
+}
+
+// newCommandsContent converts an answer to structured content. Commands is always
+// an array, empty rather than null when there are none.
+func newCommandsContent(answer llm.CommandAnswer) commandsContent {
+	commands := answer.Commands
+	if commands == nil {
+		commands = []llm.CommandResult{}
+	}
+	return commandsContent{Commands: commands, Message: answer.Message}
+}
+
+// tools returns the definitions of every tool exposed by the server.
+func tools() []tool {
+	limitSchema := map[string]any{
+		"type":        "integer",
+		"description": "Number of most recent history entries to consider",
+		"minimum":     1,
+	}
+	commandsSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"commands": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"command":     map[string]any{"type": "string"},
+						"explanation": map[string]any{"type": "string"},
+						"risk":        map[string]any{"type": "string", "enum": []string{string(llm.RiskLow), string(llm.RiskHigh)}},
+						"source":      map[string]any{"type": "string", "enum": []string{string(llm.SourceHistory), string(llm.SourceModel), string(llm.SourceTeam)}},
+					},
+					"required": []string{"command"},
+				},
+			},
+			"message": map[string]any{"type": "string", "description": "Set when the answer holds no commands"},
+		},
+		"required": []string{"commands"},
+	}
+	return []tool{
+		{
+			Name:        toolFindCommands,
+			Description: "Search the user's shell history for previously executed commands matching a natural language description. Secrets are redacted.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string", "description": "Description of the command to find"},
+					"limit": limitSchema,
+				},
+				"required": []string{"query"},
+			},
+			OutputSchema: commandsSchema,
+		},
+		{
+			Name:        toolSuggestCommands,
+			Description: "Suggest shell commands for a task, optionally using the user's recent shell history as context.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query":              map[string]any{"type": "string", "description": "Description of the task"},
+					"limit":              limitSchema,
+					"no_history_context": map[string]any{"type": "boolean", "description": "Do not use shell history as context"},
+				},
+				"required": []string{"query"},
+			},
+			OutputSchema: commandsSchema,
+		},
+		{
+			Name:        toolRecentHistory,
+			Description: "Return the user's most recent shell commands, oldest first. Secrets are redacted.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"limit": limitSchema,
+				},
+			},
+		},
+	}
+}
+
+func errorResult(message string) toolResult {
+	return toolResult{
+		Content: []textContent{{Type: "text", Text: message}},
+		IsError: true,
+	}
+}
+
+func limitOrDefault(limit, fallback int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	return limit
+}
@@ -0,0 +1,258 @@
+// Package metrics counts the requests of historai's long-running servers (serve and
+// daemon) and exposes them, in the Prometheus text format, at /metrics, next to a
+// /healthz liveness check.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/version"
+)
+
+// Values of the status label of requests.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// durationBuckets are the upper bounds, in seconds, of the request duration
+// histogram: from a cached local answer to a slow provider.
+var durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// CacheStatsFunc returns the hits and misses of a cache; ok is false when there is
+// no cache.
+type CacheStatsFunc func() (hits, misses uint64, ok bool)
+
+// Registry holds the metrics of a server. It is safe for concurrent use.
+type Registry struct {
+	version    string
+	started    time.Time
+	cacheStats CacheStatsFunc
+
+	mu             sync.Mutex
+	requests       map[requestKey]uint64
+	durations      map[string]*histogram
+	providerErrors map[string]uint64
+}
+
+// requestKey identifies a request counter.
+type requestKey struct {
+	op     string
+	status string
+}
+
+// histogram counts observations per bucket of durationBuckets.
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// New creates a registry for a server, reporting the history cache with cacheStats,
+// which may be nil.
+func New(cacheStats CacheStatsFunc) *Registry {
+	return &Registry{
+		version:        version.Get().Version,
+		started:        time.Now(),
+		cacheStats:     cacheStats,
+		requests:       make(map[requestKey]uint64),
+		durations:      make(map[string]*histogram),
+		providerErrors: make(map[string]uint64),
+	}
+}
+
+// Observe records a request for op that took d and failed with err, if not nil.
+// Failures of the provider are also counted by reason.
+func (r *Registry) Observe(op string, d time.Duration, err error) {
+	status := StatusOK
+	if err != nil {
+		status = StatusError
+	}
+	reason, providerFailed := ProviderErrorReason(err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[requestKey{op: op, status: status}]++
+	h, ok := r.durations[op]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		r.durations[op] = h
+	}
+	seconds := d.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+	if providerFailed {
+		r.providerErrors[reason]++
+	}
+}
+
+// ProviderErrorReason classifies a failure of the LLM provider: "blocked" by the
+// safety filters, "unavailable" when it couldn't be reached, or the reason of an
+// llm.ProviderError. ok is false for other errors.
+func ProviderErrorReason(err error) (reason string, ok bool) {
+	var providerErr *llm.ProviderError
+	switch {
+	case err == nil:
+		return "", false
+	case errors.Is(err, llm.ErrBlocked):
+		return "blocked", true
+	case llm.IsUnavailable(err):
+		return "unavailable", true
+	case errors.As(err, &providerErr):
+		return providerErr.Reason, true
+	default:
+		return "", false
+	}
+}
+
+// Handler returns the handler serving GET /metrics and GET /healthz.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", r.handleMetrics)
+	mux.HandleFunc("GET /healthz", r.handleHealth)
+	return mux
+}
+
+// ListenAndServe serves Handler on addr until ctx is cancelled, for servers that
+// don't speak HTTP themselves.
+func (r *Registry) ListenAndServe(ctx context.Context, logger *zap.Logger, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           r.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("Metrics listening", zap.String("addr", addr))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Write(w)
+}
+
+// health is the body of /healthz.
+type health struct {
+	Status        string `json:"status"`
+	Version       string `json:"version"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+func (r *Registry) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(health{
+		Status:        "ok",
+		Version:       r.version,
+		UptimeSeconds: int64(time.Since(r.started).Seconds()),
+	})
+}
+
+// Write writes every metric to w in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	var b strings.Builder
+
+	writeHeader(&b, "historai_build_info", "gauge", "Version of the running historai.")
+	fmt.Fprintf(&b, "historai_build_info{version=%s} 1\n", quote(r.version))
+	writeHeader(&b, "historai_start_time_seconds", "gauge", "Start time of the server, in seconds since the epoch.")
+	fmt.Fprintf(&b, "historai_start_time_seconds %d\n", r.started.Unix())
+
+	r.mu.Lock()
+	writeHeader(&b, "historai_requests_total", "counter", "Requests handled, by operation and status.")
+	keys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].op != keys[j].op {
+			return keys[i].op < keys[j].op
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&b, "historai_requests_total{op=%s,status=%s} %d\n", quote(key.op), quote(key.status), r.requests[key])
+	}
+
+	writeHeader(&b, "historai_request_duration_seconds", "histogram", "Time taken to handle requests, by operation.")
+	for _, op := range sortedKeys(r.durations) {
+		h := r.durations[op]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(&b, "historai_request_duration_seconds_bucket{op=%s,le=%s} %d\n", quote(op), quote(formatFloat(bound)), h.buckets[i])
+		}
+		fmt.Fprintf(&b, "historai_request_duration_seconds_bucket{op=%s,le=\"+Inf\"} %d\n", quote(op), h.count)
+		fmt.Fprintf(&b, "historai_request_duration_seconds_sum{op=%s} %s\n", quote(op), formatFloat(h.sum))
+		fmt.Fprintf(&b, "historai_request_duration_seconds_count{op=%s} %d\n", quote(op), h.count)
+	}
+
+	writeHeader(&b, "historai_provider_errors_total", "counter", "Failed calls to the LLM provider, by reason.")
+	for _, reason := range sortedKeys(r.providerErrors) {
+		fmt.Fprintf(&b, "historai_provider_errors_total{reason=%s} %d\n", quote(reason), r.providerErrors[reason])
+	}
+	r.mu.Unlock()
+
+	if r.cacheStats != nil {
+		if hits, misses, ok := r.cacheStats(); ok {
+			writeHeader(&b, "historai_history_cache_hits_total", "counter", "History reads answered from memory.")
+			fmt.Fprintf(&b, "historai_history_cache_hits_total %d\n", hits)
+			writeHeader(&b, "historai_history_cache_misses_total", "counter", "History reads that parsed the history file.")
+			fmt.Fprintf(&b, "historai_history_cache_misses_total %d\n", misses)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// quote quotes a label value, escaping backslashes, quotes and newlines.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+package outbound
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/logfile"
+)
+
+const fileName = "outbound.jsonl"
+
+// Record is a prompt sent to an LLM provider, as written to the outbound log.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model,omitempty"`
+	PID      int       `json:"pid"`
+	Bytes    int       `json:"bytes"`
+	Prompt   string    `json:"prompt"`
+}
+
+// DefaultPath returns the location of the outbound log,
+// $XDG_STATE_HOME/historai/outbound.jsonl or ~/.local/state/historai/outbound.jsonl.
+func DefaultPath() (string, error) {
+	dir, err := logfile.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Log appends Records to a file, one JSON object per line. It never rewrites or
+// truncates the file.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// New returns a Log appending to path, or to DefaultPath when it is empty.
+func New(path string) (*Log, error) {
+	if path == "" {
+		defaultPath, err := DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	return &Log{path: path}, nil
+}
+
+// Path returns the file the Log appends to.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append writes record as a single line, so that concurrent writers, such as the
+// daemon and the CLI, don't interleave.
+func (l *Log) Append(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbound record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create outbound log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open outbound log %s: %w", l.path, err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write outbound log %s: %w", l.path, err)
+	}
+	return file.Close()
+}
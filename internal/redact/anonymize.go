@@ -0,0 +1,395 @@
+package redact
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Typed placeholders replacing the literal arguments of anonymized commands.
+const (
+	PlaceholderFile   = "<FILE>"
+	PlaceholderPath   = "<PATH>"
+	PlaceholderHost   = "<HOST>"
+	PlaceholderUser   = "<USER>"
+	PlaceholderIP     = "<IP>"
+	PlaceholderPort   = "<PORT>"
+	PlaceholderURL    = "<URL>"
+	PlaceholderNum    = "<NUM>"
+	PlaceholderString = "<STRING>"
+	PlaceholderValue  = "<VALUE>"
+	PlaceholderArg    = "<ARG>"
+)
+
+// placeholders lists the typed placeholders, to recognize anonymized commands.
+var placeholders = []string{
+	PlaceholderFile, PlaceholderPath, PlaceholderHost, PlaceholderUser, PlaceholderIP, PlaceholderPort,
+	PlaceholderURL, PlaceholderNum, PlaceholderString, PlaceholderValue, PlaceholderArg,
+}
+
+// maxSubcommands is how many plain words after a program name are kept as its
+// subcommands, as in "docker compose up" or "kubectl get pods".
+const maxSubcommands = 2
+
+var (
+	// plainWord matches words kept as subcommands, like compose, s3 or get-pods, but
+	// not generated names like api-7d9f.
+	plainWord = regexp.MustCompile(`^[a-z][a-z0-9]{0,19}(?:-[a-z]+)*$`)
+
+	// variable matches a lone shell variable, which names rather than holds a value.
+	variable = regexp.MustCompile(`^\$(?:\w+|\{\w+\})$`)
+
+	assignment = regexp.MustCompile(`^([A-Za-z_]\w*)=(.*)$`)
+	number     = regexp.MustCompile(`^\d+(?:\.\d+)?[kKmMgG]?$`)
+	ipv4       = regexp.MustCompile(`^(?:\d{1,3}\.){3}\d{1,3}$`)
+	hostname   = regexp.MustCompile(`^[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+$`)
+	userHost   = regexp.MustCompile(`^([^@/:\s]+)@([^@/:\s]+)(?::(.*))?$`)
+	hostPath   = regexp.MustCompile(`^([A-Za-z0-9.-]+):([/~].*)?$`)
+	urlScheme  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+)
+
+// hostSuffixes are the top-level domains that tell a host name like example.com
+// from a file name like notes.txt.
+var hostSuffixes = map[string]bool{
+	".com": true, ".net": true, ".org": true, ".io": true, ".dev": true, ".app": true,
+	".cloud": true, ".internal": true, ".local": true, ".lan": true, ".corp": true,
+}
+
+// fileSuffixes are the extensions that tell a file name like backup.tar.gz from a
+// host name like db.eu.acme.
+var fileSuffixes = map[string]bool{
+	".gz": true, ".bz2": true, ".xz": true, ".zst": true, ".tar": true, ".tgz": true, ".zip": true,
+	".txt": true, ".log": true, ".json": true, ".yaml": true, ".yml": true, ".toml": true,
+	".conf": true, ".csv": true, ".sql": true, ".md": true, ".sh": true, ".py": true, ".go": true,
+	".js": true, ".ts": true, ".pem": true, ".key": true, ".crt": true, ".bak": true,
+}
+
+// prefixCommands run the command that follows them, whose name is kept too.
+var prefixCommands = map[string]bool{
+	"sudo": true, "env": true, "time": true, "nohup": true, "exec": true, "command": true,
+	"xargs": true, "watch": true, "nice": true, "doas": true,
+}
+
+// hostCommands take a host as their first argument, which may look like any word.
+var hostCommands = map[string]bool{
+	"ssh": true, "mosh": true, "ping": true, "ping6": true, "telnet": true, "nc": true,
+	"dig": true, "host": true, "nslookup": true, "traceroute": true, "ssh-copy-id": true,
+	"whois": true, "mtr": true,
+}
+
+// Anonymize returns command with its literal arguments replaced by typed
+// placeholders, keeping the program names, subcommands, flags and operators that
+// make up its structure: "scp ./db.tar.gz deploy@10.0.0.7:/srv/backups" becomes
+// "scp <FILE> <USER>@<IP>:<PATH>".
+func Anonymize(command string) string {
+	var b strings.Builder
+	state := anonymizeState{expectProgram: true}
+	for _, token := range shellTokens(command) {
+		switch {
+		case token.space:
+			b.WriteString(token.text)
+		case token.operator && strings.ContainsAny(token.text, "<>"):
+			// A redirection is followed by its target, unless it duplicates a descriptor
+			// (2>&1), and then the command goes on.
+			b.WriteString(token.text)
+			last := token.text[len(token.text)-1]
+			state.redirect = last < '0' || last > '9'
+		case token.operator:
+			b.WriteString(token.text)
+			state = anonymizeState{expectProgram: true}
+		default:
+			b.WriteString(state.word(token.text))
+		}
+	}
+	return b.String()
+}
+
+// anonymizeState tracks where a word stands in its simple command.
+type anonymizeState struct {
+	expectProgram bool
+	redirect      bool
+	program       string
+	subcommands   int
+	positional    int
+}
+
+// word returns the anonymized form of word, and advances the state past it.
+func (s *anonymizeState) word(word string) string {
+	if s.redirect {
+		// The target of a redirection is a file; what follows continues the command.
+		s.redirect = false
+		if s.program == "" {
+			s.expectProgram = true
+		}
+		return pathPlaceholder(word)
+	}
+	if s.expectProgram {
+		if m := assignment.FindStringSubmatch(word); m != nil {
+			return m[1] + "=" + valuePlaceholder(m[2])
+		}
+		if prefixCommands[s.program] && strings.HasPrefix(word, "-") {
+			// A flag of sudo or env, before the command they run.
+			return word
+		}
+		s.program = path.Base(word)
+		s.expectProgram = prefixCommands[s.program]
+		s.subcommands = 0
+		if strings.Contains(word, "/") {
+			return PlaceholderPath + "/" + s.program
+		}
+		return word
+	}
+
+	switch {
+	case strings.HasPrefix(word, "-") && len(word) > 1 && !number.MatchString(word[1:]):
+		if flag, value, ok := strings.Cut(word, "="); ok {
+			return flag + "=" + valuePlaceholder(value)
+		}
+		return word
+	case variable.MatchString(word), strings.Contains(word, Placeholder):
+		return word
+	}
+	s.positional++
+	if s.positional == 1 && hostCommands[s.program] && !strings.ContainsAny(word, "@:/") {
+		return PlaceholderHost
+	}
+	if s.subcommands < maxSubcommands && s.positional == s.subcommands+1 && plainWord.MatchString(word) {
+		s.subcommands++
+		return word
+	}
+	return argPlaceholder(word)
+}
+
+// argPlaceholder returns the typed placeholder of a positional argument.
+func argPlaceholder(word string) string {
+	if quoted(word) {
+		return PlaceholderString
+	}
+	if m := userHost.FindStringSubmatch(word); m != nil {
+		anonymized := PlaceholderUser + "@" + hostPlaceholder(m[2])
+		if strings.Contains(word, ":") {
+			anonymized += ":"
+			if m[3] != "" {
+				anonymized += pathPlaceholder(m[3])
+			}
+		}
+		return anonymized
+	}
+	if m := hostPath.FindStringSubmatch(word); m != nil && strings.Contains(m[1], ".") {
+		anonymized := hostPlaceholder(m[1]) + ":"
+		if m[2] != "" {
+			anonymized += pathPlaceholder(m[2])
+		}
+		return anonymized
+	}
+	if m := assignment.FindStringSubmatch(word); m != nil {
+		return m[1] + "=" + valuePlaceholder(m[2])
+	}
+	switch {
+	case urlScheme.MatchString(word):
+		return PlaceholderURL
+	case ipv4.MatchString(word):
+		return PlaceholderIP
+	case strings.Count(word, ":") == 1 && ipv4.MatchString(strings.Split(word, ":")[0]):
+		return PlaceholderIP + ":" + PlaceholderPort
+	case number.MatchString(word):
+		return PlaceholderNum
+	case strings.ContainsAny(word, "/~") || strings.HasPrefix(word, "."):
+		return pathPlaceholder(word)
+	case hostname.MatchString(word):
+		ext := strings.ToLower(path.Ext(word))
+		if hostSuffixes[ext] || (strings.Count(word, ".") > 1 && !fileSuffixes[ext]) {
+			return PlaceholderHost
+		}
+		return PlaceholderFile
+	}
+	return PlaceholderArg
+}
+
+// IsAnonymized reports whether command holds a placeholder left by Anonymize.
+func IsAnonymized(command string) bool {
+	for _, placeholder := range placeholders {
+		if strings.Contains(command, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePlaceholder returns the placeholder of the value of a flag or assignment.
+func valuePlaceholder(value string) string {
+	switch {
+	case value == "", variable.MatchString(value), strings.Contains(value, Placeholder):
+		return value
+	case quoted(value):
+		return PlaceholderString
+	case urlScheme.MatchString(value):
+		return PlaceholderURL
+	case number.MatchString(value):
+		return PlaceholderNum
+	case strings.ContainsAny(value, "/~"):
+		return pathPlaceholder(value)
+	}
+	return PlaceholderValue
+}
+
+// hostPlaceholder returns the placeholder of a host name or address.
+func hostPlaceholder(host string) string {
+	if ipv4.MatchString(host) {
+		return PlaceholderIP
+	}
+	return PlaceholderHost
+}
+
+// pathPlaceholder returns <FILE> for a path naming a file with an extension, and
+// <PATH> for other paths, such as directories.
+func pathPlaceholder(word string) string {
+	if quoted(word) {
+		word = word[1 : len(word)-1]
+	}
+	if variable.MatchString(word) {
+		return word
+	}
+	if strings.HasSuffix(word, "/") {
+		return PlaceholderPath
+	}
+	base := path.Base(word)
+	if dot := strings.LastIndex(base, "."); dot > 0 && dot < len(base)-1 {
+		return PlaceholderFile
+	}
+	return PlaceholderPath
+}
+
+// quoted reports whether word is a single quoted string.
+func quoted(word string) bool {
+	return len(word) >= 2 && (word[0] == '\'' || word[0] == '"') && word[len(word)-1] == word[0]
+}
+
+// shellToken is a word, an operator or a run of whitespace of a command line.
+type shellToken struct {
+	text     string
+	space    bool
+	operator bool
+}
+
+// shellTokens splits command into words, operators and whitespace, keeping quoted
+// strings, $(...) substitutions and escaped characters within their word. It only
+// needs to be good enough to tell arguments apart, not to parse every command.
+func shellTokens(command string) []shellToken {
+	var tokens []shellToken
+	runes := []rune(command)
+	for i := 0; i < len(runes); {
+		start := i
+		switch {
+		case unicode.IsSpace(runes[i]):
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, shellToken{text: string(runes[start:i]), space: true})
+		case strings.ContainsRune("|&;<>", runes[i]) || (runes[i] == '2' && i+1 < len(runes) && runes[i+1] == '>' && (i == 0 || unicode.IsSpace(runes[i-1]))):
+			i++
+			for i < len(runes) && strings.ContainsRune("|&;<>", runes[i]) {
+				i++
+			}
+			if runes[i-1] == '&' && strings.ContainsRune(string(runes[start:i]), '>') {
+				// The descriptor a redirection duplicates, as in 2>&1.
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, shellToken{text: string(runes[start:i]), operator: true})
+		default:
+			depth := 0
+			var quote rune
+			for i < len(runes) {
+				r := runes[i]
+				if quote != 0 {
+					if r == quote {
+						quote = 0
+					} else if r == '\\' && quote == '"' {
+						i++
+					}
+					i++
+					continue
+				}
+				if depth == 0 && (unicode.IsSpace(r) || strings.ContainsRune("|&;<>", r)) {
+					break
+				}
+				switch {
+				case r == '\'' || r == '"':
+					quote = r
+				case r == '\\':
+					i++
+				case r == '(' && i > start && runes[i-1] == '$':
+					depth++
+				case r == ')' && depth > 0:
+					depth--
+				}
+				i++
+			}
+			tokens = append(tokens, shellToken{text: string(runes[start:min(i, len(runes))])})
+		}
+	}
+	return tokens
+}
+
+// Anonymizer anonymizes the commands sent to the LLM with Anonymize, remembering the
+// commands behind each template so that those the model picks can be mapped back.
+// It is safe for concurrent use.
+type Anonymizer struct {
+	mu        sync.Mutex
+	originals map[string]string
+}
+
+// NewAnonymizer returns an Anonymizer that has seen no commands yet.
+func NewAnonymizer() *Anonymizer {
+	return &Anonymizer{originals: make(map[string]string)}
+}
+
+// Command returns the anonymized command, remembering it. A nil Anonymizer returns
+// command unchanged.
+func (a *Anonymizer) Command(command string) string {
+	if a == nil {
+		return command
+	}
+	anonymized := Anonymize(command)
+	if anonymized != command {
+		a.mu.Lock()
+		a.originals[strings.TrimSpace(anonymized)] = strings.TrimSpace(command)
+		a.mu.Unlock()
+	}
+	return anonymized
+}
+
+// Entries returns a copy of entries, oldest first, with their commands anonymized
+// and their directory, host and captured output left out. Notes and tags are kept,
+// as the user wrote them to be searched. A nil Anonymizer returns entries.
+func (a *Anonymizer) Entries(entries []history.HistoryEntry) []history.HistoryEntry {
+	if a == nil || entries == nil {
+		return entries
+	}
+	anonymized := make([]history.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		entry.Command = a.Command(entry.Command)
+		entry.Cwd, entry.Host, entry.Output, entry.Stderr = "", "", "", ""
+		anonymized[i] = entry
+	}
+	return anonymized
+}
+
+// Original returns the command behind the anonymized command, and false if it isn't
+// one the Anonymizer has anonymized. When several commands share a template, the
+// one seen last, the most recent, is returned.
+func (a *Anonymizer) Original(anonymized string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	original, ok := a.originals[strings.TrimSpace(anonymized)]
+	return original, ok
+}
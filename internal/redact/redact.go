@@ -0,0 +1,139 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Placeholder replaces every secret removed by a Redactor.
+const Placeholder = "[REDACTED]"
+
+// Rule describes a single class of secret. Replacement follows regexp.Expand
+// syntax, so capture groups can keep the non-secret prefix (e.g. a flag name).
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Redactor masks secrets in commands before they leave the machine.
+type Redactor struct {
+	rules []Rule
+}
+
+// defaultRules cover the most common ways credentials end up in shell history.
+var defaultRules = []Rule{
+	{
+		Name:        "secret-flag",
+		Pattern:     regexp.MustCompile(`(?i)(--?(?:password|passwd|pass|pwd|token|secret|api[-_]?key|access[-_]?key|auth)[= ])\S+`),
+		Replacement: "${1}" + Placeholder,
+	},
+	{
+		Name:        "secret-env-assignment",
+		Pattern:     regexp.MustCompile(`(?i)\b([A-Z0-9_]*(?:PASSWORD|PASSWD|TOKEN|SECRET|API_?KEY|ACCESS_KEY)[A-Z0-9_]*=)\S+`),
+		Replacement: "${1}" + Placeholder,
+	},
+	{
+		Name:        "authorization-header",
+		Pattern:     regexp.MustCompile(`(?i)(authorization:\s*(?:bearer|basic|token)\s+)[^\s'"]+`),
+		Replacement: "${1}" + Placeholder,
+	},
+	{
+		Name:        "url-credentials",
+		Pattern:     regexp.MustCompile(`(://[^/\s:@]+:)[^@\s/]+@`),
+		Replacement: "${1}" + Placeholder + "@",
+	},
+	{
+		Name:        "aws-access-key",
+		Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		Replacement: Placeholder,
+	},
+	{
+		Name:        "google-api-key",
+		Pattern:     regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`),
+		Replacement: Placeholder,
+	},
+	{
+		Name:        "github-token",
+		Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`),
+		Replacement: Placeholder,
+	},
+	{
+		Name:        "slack-token",
+		Pattern:     regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+		Replacement: Placeholder,
+	},
+	{
+		Name:        "openai-key",
+		Pattern:     regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{20,}\b`),
+		Replacement: Placeholder,
+	},
+}
+
+// New creates a Redactor using the default rules plus any extra rules.
+func New(extra ...Rule) *Redactor {
+	rules := make([]Rule, 0, len(defaultRules)+len(extra))
+	rules = append(rules, defaultRules...)
+	rules = append(rules, extra...)
+	return &Redactor{rules: rules}
+}
+
+// FromPatterns creates a Redactor using the default rules plus one rule per
+// user-supplied regular expression. Whole matches are replaced.
+func FromPatterns(patterns []string) (*Redactor, error) {
+	extra := make([]Rule, 0, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		extra = append(extra, Rule{
+			Name:        fmt.Sprintf("custom-%d", i+1),
+			Pattern:     re,
+			Replacement: Placeholder,
+		})
+	}
+	return New(extra...), nil
+}
+
+// Rules returns the rules applied by the redactor.
+func (r *Redactor) Rules() []Rule {
+	return r.rules
+}
+
+// Redact returns s with every secret matched by the rules replaced.
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.rules {
+		s = rule.Pattern.ReplaceAllString(s, rule.Replacement)
+	}
+	return s
+}
+
+// Matches returns the names of the rules that match s, in rule order.
+func (r *Redactor) Matches(s string) []string {
+	var names []string
+	for _, rule := range r.rules {
+		if rule.Pattern.MatchString(s) {
+			names = append(names, rule.Name)
+		}
+	}
+	return names
+}
+
+// RedactEntries returns a copy of entries with every command redacted.
+func (r *Redactor) RedactEntries(entries []history.HistoryEntry) []history.HistoryEntry {
+	if entries == nil {
+		return nil
+	}
+	redacted := make([]history.HistoryEntry, len(entries))
+	for i, entry := range entries {
+		entry.Command = r.Redact(entry.Command)
+		entry.Stderr = r.Redact(entry.Stderr)
+		entry.Output = r.Redact(entry.Output)
+		entry.Note = r.Redact(entry.Note)
+		redacted[i] = entry
+	}
+	return redacted
+}
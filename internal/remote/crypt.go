@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+const identityFileName = "sync.key"
+
+// IdentityFile returns the age identity file to use: path when set, otherwise
+// sync.key in the config directory.
+func IdentityFile(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, identityFileName), nil
+}
+
+// LoadIdentity reads an X25519 age identity from path.
+func LoadIdentity(path string) (*age.X25519Identity, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no sync key at %s (create one with `historai sync keygen`, or copy it from a machine that already syncs)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync key %s: %w", path, err)
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync key %s: %w", path, err)
+	}
+	for _, identity := range identities {
+		if x25519, ok := identity.(*age.X25519Identity); ok {
+			return x25519, nil
+		}
+	}
+	return nil, fmt.Errorf("sync key %s holds no X25519 identity", path)
+}
+
+// GenerateIdentity creates a new age identity at path, readable only by the user.
+// It refuses to overwrite an existing key, as data encrypted to it would be lost.
+func GenerateIdentity(path string) (*age.X25519Identity, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sync key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("a sync key already exists at %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sync key %s: %w", path, err)
+	}
+	defer file.Close()
+	_, err = fmt.Fprintf(file, "# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), identity.Recipient(), identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write sync key %s: %w", path, err)
+	}
+	return identity, nil
+}
+
+// Encrypt encrypts data to identity's recipient.
+func Encrypt(data []byte, identity *age.X25519Identity) ([]byte, error) {
+	var out bytes.Buffer
+	writer, err := age.Encrypt(&out, identity.Recipient())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt with the same identity.
+func Decrypt(data []byte, identity *age.X25519Identity) ([]byte, error) {
+	reader, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (was it encrypted with a different sync key?): %w", err)
+	}
+	plain, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plain, nil
+}
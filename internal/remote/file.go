@@ -0,0 +1,60 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+// fileStore keeps objects in a local directory, such as a mounted network drive or
+// a folder synced by another tool.
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(_ context.Context, _ *zap.Logger, location *url.URL, _ *config.Config) (Store, error) {
+	if location.Path == "" {
+		return nil, fmt.Errorf("sync.url %q has no directory", location.String())
+	}
+	return &fileStore{dir: config.ExpandHome(location.Path)}, nil
+}
+
+func (s *fileStore) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Put writes to a temporary file and renames it, so readers never see a partial object.
+func (s *fileStore) Put(_ context.Context, name string, data []byte) error {
+	path := filepath.Join(s.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fileStore) String() string {
+	return s.dir
+}
@@ -0,0 +1,65 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+	storage "google.golang.org/api/storage/v1"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+// gcsStore stores objects in Google Cloud Storage, authenticating with Application
+// Default Credentials (gcloud auth application-default login, or
+// $GOOGLE_APPLICATION_CREDENTIALS).
+type gcsStore struct {
+	logger  *zap.Logger
+	service *storage.Service
+	bucket  string
+	prefix  string
+}
+
+func newGCSStore(ctx context.Context, logger *zap.Logger, location *url.URL, _ *config.Config) (Store, error) {
+	if location.Host == "" {
+		return nil, fmt.Errorf("sync.url %q has no bucket", location.String())
+	}
+	service, err := storage.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Storage client: %w", err)
+	}
+	return &gcsStore{logger: logger, service: service, bucket: location.Host, prefix: location.Path}, nil
+}
+
+func (s *gcsStore) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.service.Objects.Get(s.bucket, joinKey(s.prefix, name)).Context(ctx).Download()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to download %s/%s: %w", s, name, err)
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *gcsStore) Put(ctx context.Context, name string, data []byte) error {
+	object := &storage.Object{Name: joinKey(s.prefix, name), ContentType: "application/octet-stream"}
+	_, err := s.service.Objects.Insert(s.bucket, object).Media(bytes.NewReader(data)).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to upload %s/%s: %w", s, name, err)
+	}
+	return nil
+}
+
+func (s *gcsStore) String() string {
+	return "gs://" + s.bucket + "/" + strings.Trim(s.prefix, "/")
+}
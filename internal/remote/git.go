@@ -0,0 +1,160 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+const defaultGitBranch = "main"
+
+// gitStore keeps objects in a git repository, committing and pushing on every Put.
+// It runs the git executable, so the user's SSH keys, credential helpers and proxy
+// settings apply as they do for any other repository.
+type gitStore struct {
+	logger *zap.Logger
+	remote string // the URL given to git
+	branch string
+	dir    string // the local clone
+}
+
+// newGitStore handles git+ssh://, git+https:// and git+file:// URLs. The branch may
+// be given as a fragment, e.g. git+ssh://git@github.com/me/history.git#sync.
+func newGitStore(_ context.Context, logger *zap.Logger, location *url.URL, _ *config.Config) (Store, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git-backed sync requires the git executable in PATH")
+	}
+	branch := location.Fragment
+	if branch == "" {
+		branch = defaultGitBranch
+	}
+	remoteURL := *location
+	remoteURL.Scheme = strings.TrimPrefix(location.Scheme, "git+")
+	remoteURL.Fragment = ""
+
+	dataDir, err := history.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(remoteURL.String()))
+	return &gitStore{
+		logger: logger,
+		remote: remoteURL.String(),
+		branch: branch,
+		dir:    filepath.Join(dataDir, "sync", hex.EncodeToString(sum[:8])),
+	}, nil
+}
+
+// Get brings the clone up to date with the remote branch and reads name from it.
+func (s *gitStore) Get(ctx context.Context, name string) ([]byte, error) {
+	exists, err := s.update(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Put commits name to the clone and pushes it. Callers Get first, so the commit
+// is based on the latest remote state; if another machine pushed in between,
+// the push is rejected and the next sync merges both.
+func (s *gitStore) Put(ctx context.Context, name string, data []byte) error {
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); err != nil {
+		if _, err := s.update(ctx); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	hostname, _ := os.Hostname()
+	if _, err := s.git(ctx, "add", "--", name); err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "commit", "--quiet", "--no-verify", "--no-gpg-sign", "-m", "Sync history from "+hostname); err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "push", "--quiet", "origin", "HEAD:refs/heads/"+s.branch); err != nil {
+		return fmt.Errorf("%w (another machine may have synced at the same time; run sync again)", err)
+	}
+	return nil
+}
+
+func (s *gitStore) String() string {
+	return s.remote + "#" + s.branch
+}
+
+// update clones the repository on first use, then resets the clone to the remote
+// branch, discarding commits left over from a rejected push. It reports whether
+// the remote branch exists; in a new, empty repository it doesn't yet.
+func (s *gitStore) update(ctx context.Context) (bool, error) {
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		s.logger.Debug("Cloning sync repository", zap.String("dir", s.dir))
+		if err := os.MkdirAll(filepath.Dir(s.dir), 0o700); err != nil {
+			return false, fmt.Errorf("failed to create %s: %w", filepath.Dir(s.dir), err)
+		}
+		if _, err := s.run(ctx, "", "clone", "--quiet", "--", s.remote, s.dir); err != nil {
+			return false, err
+		}
+	}
+	if _, err := s.git(ctx, "fetch", "--quiet", "origin"); err != nil {
+		return false, err
+	}
+	remoteBranch := "refs/remotes/origin/" + s.branch
+	if _, err := s.git(ctx, "rev-parse", "--verify", "--quiet", remoteBranch); err != nil {
+		s.logger.Debug("Sync branch does not exist yet", zap.String("branch", s.branch))
+		_, err := s.git(ctx, "symbolic-ref", "HEAD", "refs/heads/"+s.branch)
+		return false, err
+	}
+	if _, err := s.git(ctx, "checkout", "--quiet", "-B", s.branch, remoteBranch); err != nil {
+		return false, err
+	}
+	_, err := s.git(ctx, "reset", "--quiet", "--hard", remoteBranch)
+	return err == nil, err
+}
+
+func (s *gitStore) git(ctx context.Context, args ...string) (string, error) {
+	return s.run(ctx, s.dir, args...)
+}
+
+// run executes git in dir. Commits are attributed to historai, so syncing works
+// on machines without a configured git identity.
+func (s *gitStore) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	hostname, _ := os.Hostname()
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=historai", "GIT_AUTHOR_EMAIL=historai@"+hostname,
+		"GIT_COMMITTER_NAME=historai", "GIT_COMMITTER_EMAIL=historai@"+hostname)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	s.logger.Debug("Running git", zap.Strings("args", args))
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+	return stdout.String(), nil
+}
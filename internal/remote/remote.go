@@ -0,0 +1,86 @@
+// Package remote stores files outside the machine, for syncing historai's history
+// log between machines. Backends are selected by the scheme of sync.url.
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+)
+
+// ErrNotFound is returned by Store.Get when the object does not exist yet.
+var ErrNotFound = errors.New("remote object not found")
+
+// Store reads and writes whole objects by name, relative to the configured location.
+type Store interface {
+	Get(ctx context.Context, name string) ([]byte, error)
+	Put(ctx context.Context, name string, data []byte) error
+
+	// String describes the location, for messages.
+	String() string
+}
+
+// opener creates a Store for a parsed sync.url.
+type opener func(ctx context.Context, logger *zap.Logger, location *url.URL, cfg *config.Config) (Store, error)
+
+// backends maps sync.url schemes to their Store implementation.
+var backends = map[string]opener{
+	"s3":    newS3Store,
+	"gs":    newGCSStore,
+	"http":  newWebDAVStore,
+	"https": newWebDAVStore,
+	"file":  newFileStore,
+
+	"git+ssh":   newGitStore,
+	"git+https": newGitStore,
+	"git+http":  newGitStore,
+	"git+file":  newGitStore,
+}
+
+// New returns the Store for cfg.Sync.URL.
+func New(ctx context.Context, logger *zap.Logger, cfg *config.Config) (Store, error) {
+	if cfg.Sync.URL == "" {
+		return nil, errors.New("sync.url is not set (e.g. s3://bucket/historai, gs://bucket/historai, https://dav.example.com/historai, git+ssh://git@github.com/me/history.git or file:///mnt/backup)")
+	}
+	location, err := url.Parse(cfg.Sync.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync.url %q: %w", cfg.Sync.URL, err)
+	}
+	open, ok := backends[location.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sync.url scheme %q (expected one of %s)", location.Scheme, strings.Join(schemes(), ", "))
+	}
+	if location.Scheme != "file" && location.Scheme != "git+file" {
+		if err := cfg.Policy.RequireNetwork("sync to " + location.Scheme + "://"); err != nil {
+			return nil, err
+		}
+	}
+	logger.Debug("Opening remote store", zap.String("scheme", location.Scheme), zap.String("host", location.Host))
+	return open(ctx, logger, location, cfg)
+}
+
+// schemes returns the supported sync.url schemes, sorted.
+func schemes() []string {
+	names := make([]string, 0, len(backends))
+	for scheme := range backends {
+		names = append(names, scheme)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// joinKey joins an object name to a prefix taken from a URL path.
+func joinKey(prefix, name string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
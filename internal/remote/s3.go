@@ -0,0 +1,249 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/httpclient"
+)
+
+const defaultS3Region = "us-east-1"
+
+// s3Store stores objects with the S3 REST API, signed with AWS Signature Version 4.
+// Requests use path-style URLs, so sync.endpoint can point at any S3-compatible
+// service (MinIO, Cloudflare R2, or GCS with HMAC keys).
+type s3Store struct {
+	logger   *zap.Logger
+	client   *http.Client
+	endpoint *url.URL
+	region   string
+	bucket   string
+	prefix   string
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// newS3Store reads credentials from the standard AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func newS3Store(_ context.Context, logger *zap.Logger, location *url.URL, cfg *config.Config) (Store, error) {
+	if location.Host == "" {
+		return nil, fmt.Errorf("sync.url %q has no bucket", location.String())
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to sync with S3")
+	}
+
+	region := cfg.Sync.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	endpoint := cfg.Sync.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid sync.endpoint %q", endpoint)
+	}
+	client, err := httpclient.New(cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{
+		logger:       logger,
+		client:       client,
+		endpoint:     endpointURL,
+		region:       region,
+		bucket:       location.Host,
+		prefix:       location.Path,
+		accessKey:    accessKey,
+		secretKey:    secretKey,
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}, nil
+}
+
+func (s *s3Store) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, statusError(http.MethodGet, s.String()+"/"+name, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3Store) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(http.MethodPut, s.String()+"/"+name, resp)
+	}
+	return nil
+}
+
+func (s *s3Store) String() string {
+	return "s3://" + s.bucket + "/" + strings.Trim(s.prefix, "/")
+}
+
+func (s *s3Store) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	key := joinKey(s.prefix, name)
+	target := *s.endpoint
+	target.Path = "/" + s.bucket + "/" + key
+	target.RawPath = "/" + s.bucket + "/" + escapePath(key)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	s.sign(req, body, time.Now().UTC())
+	s.logger.Debug("Sending S3 request", zap.String("method", method), zap.String("key", key))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s failed: %w", method, err)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := signingKey(s.secretKey, day, s.region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the SigV4 key signing requests to service in region on day
+// (YYYYMMDD) from the secret access key.
+func signingKey(secretKey, day, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return key
+}
+
+// canonicalQuery returns the query string as SigV4 signs it: parameters sorted by
+// name, then value, each encoded with uriEncode and given an "=" even when empty.
+func canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		name, value, _ := strings.Cut(param, "=")
+		params = append(params, uriEncode(queryUnescape(name))+"="+uriEncode(queryUnescape(value)))
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// queryUnescape decodes a query parameter, leaving it as is when malformed.
+func queryUnescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// escapePath percent-encodes each segment of key as SigV4 requires, keeping slashes.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode percent-encodes every byte of s but the unreserved characters.
+func uriEncode(s string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(s) {
+		if isUnreserved(b) {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func isUnreserved(b byte) bool {
+	return b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' ||
+		b == '-' || b == '.' || b == '_' || b == '~'
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,131 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/config"
+	"github.com/sanspareilsmyn/historai/internal/httpclient"
+)
+
+// EnvWebDAVPassword holds the WebDAV password, which is never stored in the config file.
+const EnvWebDAVPassword = "HISTORAI_WEBDAV_PASSWORD"
+
+// webdavStore stores objects with plain GET and PUT requests below a collection URL,
+// as supported by Nextcloud, ownCloud, Apache mod_dav and most NAS devices.
+type webdavStore struct {
+	logger   *zap.Logger
+	client   *http.Client
+	base     *url.URL
+	username string
+	password string
+}
+
+func newWebDAVStore(_ context.Context, logger *zap.Logger, location *url.URL, cfg *config.Config) (Store, error) {
+	client, err := httpclient.New(cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	base := *location
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/"
+	return &webdavStore{
+		logger:   logger,
+		client:   client,
+		base:     &base,
+		username: cfg.Sync.Username,
+		password: os.Getenv(EnvWebDAVPassword),
+	}, nil
+}
+
+func (s *webdavStore) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, statusError(http.MethodGet, s.objectURL(name), resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put uploads the object. A 409 Conflict means the collection doesn't exist, so it
+// is created with MKCOL and the upload retried once.
+func (s *webdavStore) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		s.logger.Debug("WebDAV collection missing, creating it", zap.String("url", s.base.String()))
+		if err := s.mkcol(ctx); err != nil {
+			return err
+		}
+		if resp, err = s.do(ctx, http.MethodPut, name, data); err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(http.MethodPut, s.objectURL(name), resp)
+	}
+	return nil
+}
+
+// mkcol creates the base collection. Parent collections must already exist.
+func (s *webdavStore) mkcol(ctx context.Context) error {
+	resp, err := s.do(ctx, "MKCOL", "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+		return statusError("MKCOL", s.base.String(), resp)
+	}
+	return nil
+}
+
+func (s *webdavStore) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WebDAV request: %w", err)
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("WebDAV %s failed: %w", method, err)
+	}
+	return resp, nil
+}
+
+func (s *webdavStore) objectURL(name string) string {
+	return s.base.ResolveReference(&url.URL{Path: name}).String()
+}
+
+func (s *webdavStore) String() string {
+	return s.base.Redacted()
+}
+
+// statusError describes an unexpected HTTP response, including the start of its body.
+func statusError(method, target string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		return fmt.Errorf("%s %s: %s", method, target, resp.Status)
+	}
+	return fmt.Errorf("%s %s: %s: %s", method, target, resp.Status, message)
+}
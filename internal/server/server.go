@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sanspareilsmyn/historai/internal/engine"
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/llm"
+	"github.com/sanspareilsmyn/historai/internal/metrics"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+	maxRequestBytes   = 64 * 1024
+)
+
+// Server exposes the engine as a local JSON HTTP API.
+type Server struct {
+	logger  *zap.Logger
+	engine  *engine.Engine
+	token   string
+	metrics *metrics.Registry
+}
+
+// findRequest is the JSON body accepted by /find.
+type findRequest struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// suggestRequest is the JSON body accepted by /suggest.
+type suggestRequest struct {
+	Query            string `json:"query"`
+	Limit            int    `json:"limit"`
+	NoHistoryContext bool   `json:"no_history_context"`
+}
+
+// resultResponse is returned by /find and /suggest. Result is the answer as the CLI
+// prints it; Commands holds the same commands with their explanation, risk and source.
+type resultResponse struct {
+	Result   string              `json:"result"`
+	Commands []llm.CommandResult `json:"commands"`
+	Message  string              `json:"message,omitempty"`
+}
+
+// historyResponse is returned by /history.
+type historyResponse struct {
+	Entries []history.HistoryEntry `json:"entries"`
+}
+
+// errorResponse is returned for any failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a server. An empty token disables authentication, in which case only
+// requests addressed to a loopback host are served and ListenAndServe only listens
+// on a loopback address.
+func New(logger *zap.Logger, eng *engine.Engine, token string) *Server {
+	return &Server{
+		logger:  logger,
+		engine:  eng,
+		token:   token,
+		metrics: metrics.New(eng.HistoryCacheStats),
+	}
+}
+
+// Handler returns the HTTP handler with all routes registered. /healthz is served
+// without authentication, for liveness probes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /find", s.handleFind)
+	mux.HandleFunc("POST /suggest", s.handleSuggest)
+	mux.HandleFunc("GET /history", s.handleHistory)
+	mux.Handle("GET /metrics", s.metrics.Handler())
+
+	root := http.NewServeMux()
+	root.Handle("GET /healthz", s.metrics.Handler())
+	root.Handle("/", s.withAuth(mux))
+	return root
+}
+
+// ListenAndServe serves on addr until ctx is cancelled. Without a token, addr must
+// be a loopback address, as anyone who can reach the server could otherwise use it.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if s.token == "" && !IsLoopback(addr) {
+		return fmt.Errorf("refusing to listen on non-loopback address %s without a token", addr)
+	}
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("HTTP API listening", zap.String("addr", addr), zap.Bool("auth", s.token != ""))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("http server failed: %w", err)
+	}
+	return nil
+}
+
+// withAuth rejects requests without the expected bearer token, if one is configured.
+// Otherwise it rejects requests whose Host isn't a loopback host, so that a web page
+// can't reach the server by rebinding its own domain name to 127.0.0.1.
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	if s.token == "" {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !IsLoopback(r.Host) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed without a bearer token", r.Host))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsLoopback reports whether hostport, with or without a port, names a loopback
+// host: localhost or a loopback IP address.
+func IsLoopback(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) handleFind(w http.ResponseWriter, r *http.Request) {
+	var req findRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("query cannot be empty"))
+		return
+	}
+
+	started := time.Now()
+	result, err := s.engine.Find(req.Query, req.Limit)
+	s.metrics.Observe("find", time.Since(started), err)
+	if err != nil {
+		s.logger.Error("Find request failed", zap.Error(err))
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newResultResponse(result))
+}
+
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	var req suggestRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("task description cannot be empty"))
+		return
+	}
+
+	started := time.Now()
+	result, err := s.engine.Suggest(req.Query, req.Limit, req.NoHistoryContext)
+	s.metrics.Observe("suggest", time.Since(started), err)
+	if err != nil {
+		s.logger.Error("Suggest request failed", zap.Error(err))
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newResultResponse(result))
+}
+
+// newResultResponse converts an answer to its response body. Commands is always an
+// array, empty rather than null when there are none.
+func newResultResponse(answer llm.CommandAnswer) resultResponse {
+	commands := answer.Commands
+	if commands == nil {
+		commands = []llm.CommandResult{}
+	}
+	return resultResponse{Result: answer.String(), Commands: commands, Message: answer.Message}
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", raw))
+			return
+		}
+		limit = parsed
+	}
+
+	started := time.Now()
+	entries, err := s.engine.History(limit)
+	s.metrics.Observe("history", time.Since(started), err)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, historyResponse{Entries: entries})
+}
+
+// decodeBody decodes a size-limited JSON request body into v.
+func decodeBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,165 @@
+// Package snippets keeps the user's parameterized command templates, such as
+// "ssh {host} -L {port}:localhost:{port}", in a file in historai's data directory.
+package snippets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+const fileName = "snippets.json"
+
+// Snippet is a named command template. Placeholders are written {name}; ${name}
+// is left alone, as it is a shell variable.
+type Snippet struct {
+	Name        string    `json:"name"`
+	Command     string    `json:"command"`
+	Description string    `json:"description,omitempty"`
+	Updated     time.Time `json:"updated"`
+}
+
+var (
+	placeholderPattern = regexp.MustCompile(`\$?\{([A-Za-z_][A-Za-z0-9_-]*)\}`)
+	validName          = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+// Placeholders returns the names of the snippet's placeholders, in order of first use.
+func (s *Snippet) Placeholders() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(s.Command, -1) {
+		if name := match[1]; !strings.HasPrefix(match[0], "$") && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Fill returns the command with every placeholder replaced by its value. Placeholders
+// without a value are left as they are.
+func (s *Snippet) Fill(values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s.Command, func(match string) string {
+		if strings.HasPrefix(match, "$") {
+			return match
+		}
+		value, ok := values[match[1:len(match)-1]]
+		if !ok {
+			return match
+		}
+		return value
+	})
+}
+
+// Store holds the user's snippets, keyed by name.
+type Store struct {
+	path     string
+	Snippets map[string]*Snippet `json:"snippets"`
+}
+
+// DefaultPath returns the snippets file in historai's data directory.
+func DefaultPath() (string, error) {
+	dir, err := history.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the store at path (DefaultPath when empty). A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	if path == "" {
+		defaultPath, err := DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	store := &Store{path: path, Snippets: make(map[string]*Snippet)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snippets %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid snippets file %s: %w", path, err)
+	}
+	if store.Snippets == nil {
+		store.Snippets = make(map[string]*Snippet)
+	}
+	return store, nil
+}
+
+// Get returns the snippet called name, or nil.
+func (s *Store) Get(name string) *Snippet {
+	return s.Snippets[name]
+}
+
+// Set adds a snippet, replacing any snippet with the same name. It reports whether
+// one was replaced.
+func (s *Store) Set(name, command, description string) (bool, error) {
+	if !validName.MatchString(name) {
+		return false, fmt.Errorf("invalid snippet name %q: use letters, digits, '-', '_' or '.'", name)
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return false, errors.New("snippet command cannot be empty")
+	}
+	_, replaced := s.Snippets[name]
+	s.Snippets[name] = &Snippet{
+		Name:        name,
+		Command:     command,
+		Description: strings.TrimSpace(description),
+		Updated:     time.Now().UTC(),
+	}
+	return replaced, nil
+}
+
+// Remove deletes the snippet called name. It reports false if there was none.
+func (s *Store) Remove(name string) bool {
+	if _, ok := s.Snippets[name]; !ok {
+		return false
+	}
+	delete(s.Snippets, name)
+	return true
+}
+
+// Sorted returns the snippets ordered by name.
+func (s *Store) Sorted() []*Snippet {
+	sorted := make([]*Snippet, 0, len(s.Snippets))
+	for _, snippet := range s.Snippets {
+		sorted = append(sorted, snippet)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// Save writes the store to a temporary file and renames it over the previous one.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snippets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(s.path), err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write snippets: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write snippets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// maxFunctionFields bounds the length of the commands searched for an argument that
+// varies in the middle, as the search is quadratic in it.
+const maxFunctionFields = 12
+
+// Alias is a proposed shell alias or function for something typed repeatedly.
+type Alias struct {
+	// Command is what the alias expands to. For a function, it contains a single "$1"
+	// where the argument that varied between runs goes.
+	Command string `json:"command" yaml:"command"`
+
+	// Function is set when an argument varies in the middle of the command, which an
+	// alias can't express.
+	Function bool `json:"function,omitempty" yaml:"function,omitempty"`
+
+	// Count is how many times it was typed.
+	Count int `json:"count" yaml:"count"`
+}
+
+// Aliases finds long commands typed repeatedly: whole command lines, and commands
+// typed with different arguments, either at the end (an alias, as the shell appends
+// them) or in the middle (a function). The n that would save the most typing are
+// returned, best first.
+func Aliases(entries []history.HistoryEntry, n int) []Alias {
+	lines := make(map[string]int)
+	prefixes := make(map[string]map[string]int)
+	templates := make(map[string]map[string]int)
+	for _, entry := range entries {
+		command := strings.TrimSpace(entry.Command)
+		if command == "" || history.ProgramName(command) == "historai" {
+			continue
+		}
+		lines[command]++
+
+		fields := strings.Fields(command)
+		if len(fields) < 3 || len(fields) > maxFunctionFields {
+			continue
+		}
+		addVariant(prefixes, strings.Join(fields[:len(fields)-1], " "), fields[len(fields)-1])
+		for i := 1; i < len(fields)-1; i++ {
+			template := strings.Join(fields[:i], " ") + ` "$1" ` + strings.Join(fields[i+1:], " ")
+			addVariant(templates, template, fields[i])
+		}
+	}
+
+	var found []Alias
+	for command, count := range lines {
+		if count >= minAliasRuns && len(command) >= minAliasLength {
+			found = append(found, Alias{Command: command, Count: count})
+		}
+	}
+	found = append(found, variedAliases(prefixes, false)...)
+	found = append(found, variedAliases(templates, true)...)
+
+	sort.Slice(found, func(i, j int) bool {
+		si, sj := found[i].Count*len(found[i].Command), found[j].Count*len(found[j].Command)
+		if si != sj {
+			return si > sj
+		}
+		return found[i].Command < found[j].Command
+	})
+	return dedupeAliases(found, n)
+}
+
+// addVariant counts one run of key with the varying argument value.
+func addVariant(variants map[string]map[string]int, key, value string) {
+	if variants[key] == nil {
+		variants[key] = make(map[string]int)
+	}
+	variants[key][value]++
+}
+
+// variedAliases returns the keys typed often enough with at least two different values.
+func variedAliases(variants map[string]map[string]int, function bool) []Alias {
+	var found []Alias
+	for command, values := range variants {
+		count := 0
+		for _, n := range values {
+			count += n
+		}
+		if len(values) >= 2 && count >= minAliasRuns && len(command) >= minAliasLength {
+			found = append(found, Alias{Command: command, Function: function, Count: count})
+		}
+	}
+	return found
+}
+
+// dedupeAliases keeps the first n aliases, skipping those that only shorten a command
+// already covered by a better one, e.g. a prefix of a kept command line.
+func dedupeAliases(sorted []Alias, n int) []Alias {
+	var kept []Alias
+	for _, alias := range sorted {
+		covered := false
+		for _, k := range kept {
+			if !k.Function && !alias.Function && strings.HasPrefix(k.Command, alias.Command) && k.Count >= alias.Count {
+				covered = true
+				break
+			}
+			if k.Command == alias.Command {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		kept = append(kept, alias)
+		if len(kept) == n {
+			break
+		}
+	}
+	return kept
+}
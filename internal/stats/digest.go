@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+const (
+	// minRepeatedFailures is how often a command must fail in the period to be reported.
+	minRepeatedFailures = 2
+
+	// minAliasRuns and minAliasLength select commands worth an alias: typed often, and
+	// long enough that an alias saves real effort.
+	minAliasRuns   = 3
+	minAliasLength = 20
+)
+
+// Digest summarizes a period of history for a periodic report.
+type Digest struct {
+	Since  time.Time `json:"since" yaml:"since"`
+	Until  time.Time `json:"until" yaml:"until"`
+	Report Report    `json:"report" yaml:"report"`
+
+	// NewPrograms are programs first run during the period, with their run counts.
+	// It is empty when there is no history before the period to compare with.
+	NewPrograms []Count `json:"new_programs,omitempty" yaml:"new_programs,omitempty"`
+
+	// RepeatedFailures are command lines that failed more than once during the period.
+	RepeatedFailures []Count `json:"repeated_failures,omitempty" yaml:"repeated_failures,omitempty"`
+
+	// AliasCandidates are long command lines typed repeatedly during the period.
+	AliasCandidates []Count `json:"alias_candidates,omitempty" yaml:"alias_candidates,omitempty"`
+}
+
+// ComputeDigest builds a Digest of the entries run from since to until, using the
+// earlier entries to tell which programs are new. It keeps the top n items of each list.
+func ComputeDigest(entries []history.HistoryEntry, since, until time.Time, n int) Digest {
+	digest := Digest{Since: since, Until: until}
+
+	known := make(map[string]bool)
+	var period []history.HistoryEntry
+	for _, entry := range entries {
+		switch {
+		case entry.Timestamp <= 0 || entry.Timestamp > until.Unix():
+			continue
+		case entry.Timestamp < since.Unix():
+			known[history.ProgramName(entry.Command)] = true
+		default:
+			period = append(period, entry)
+		}
+	}
+	digest.Report = Compute(period, n)
+
+	newPrograms := make(map[string]int)
+	failures := make(map[string]int)
+	runs := make(map[string]int)
+	for _, entry := range period {
+		command := strings.TrimSpace(entry.Command)
+		if command == "" {
+			continue
+		}
+		if program := history.ProgramName(command); len(known) > 0 && !known[program] {
+			newPrograms[program]++
+		}
+		if entry.Failed() {
+			failures[command]++
+		}
+		if len(command) >= minAliasLength {
+			runs[command]++
+		}
+	}
+	digest.NewPrograms = top(newPrograms, n)
+	digest.RepeatedFailures = top(atLeast(failures, minRepeatedFailures), n)
+	digest.AliasCandidates = top(atLeast(runs, minAliasRuns), n)
+
+	return digest
+}
+
+// atLeast returns the counts of at least minCount.
+func atLeast(counts map[string]int, minCount int) map[string]int {
+	filtered := make(map[string]int)
+	for name, count := range counts {
+		if count >= minCount {
+			filtered[name] = count
+		}
+	}
+	return filtered
+}
+
+// AliasName proposes a short alias for command from the initials of its words,
+// e.g. "dcud" for "docker compose up -d".
+func AliasName(command string) string {
+	const maxLength = 4
+	var name strings.Builder
+	for _, field := range strings.Fields(command) {
+		for _, r := range strings.TrimLeft(field, "-") {
+			if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				name.WriteRune(unicode.ToLower(r))
+				break
+			}
+		}
+		if name.Len() == maxLength {
+			break
+		}
+	}
+	return name.String()
+}
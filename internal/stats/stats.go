@@ -0,0 +1,172 @@
+// Package stats computes local analytics over shell history, without any LLM call.
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+	"github.com/sanspareilsmyn/historai/internal/workflow"
+)
+
+// minRunsForFailureRate is the number of runs with a known exit code a program needs
+// before its failure rate is reported, so one-off failures don't top the list.
+const minRunsForFailureRate = 5
+
+// Count is a name with the number of times it occurred.
+type Count struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// FailureRate is how often a program exited with a non-zero status.
+type FailureRate struct {
+	Program  string  `json:"program" yaml:"program"`
+	Runs     int     `json:"runs" yaml:"runs"`
+	Failures int     `json:"failures" yaml:"failures"`
+	Rate     float64 `json:"rate" yaml:"rate"`
+}
+
+// Report holds the statistics computed from a list of history entries. Sections that
+// need metadata the history source doesn't record (times, directories, exit codes,
+// durations) are left empty.
+type Report struct {
+	Total  int `json:"total" yaml:"total"`
+	Unique int `json:"unique" yaml:"unique"`
+
+	// First and Last bound the entries' timestamps, when known.
+	First *time.Time `json:"first,omitempty" yaml:"first,omitempty"`
+	Last  *time.Time `json:"last,omitempty" yaml:"last,omitempty"`
+
+	TopPrograms    []Count `json:"top_programs" yaml:"top_programs"`
+	TopCommands    []Count `json:"top_commands" yaml:"top_commands"`
+	TopDirectories []Count `json:"top_directories,omitempty" yaml:"top_directories,omitempty"`
+
+	// Hours counts commands by local hour of day, 0 to 23.
+	Hours []int `json:"hours,omitempty" yaml:"hours,omitempty"`
+
+	FailureRates []FailureRate          `json:"failure_rates,omitempty" yaml:"failure_rates,omitempty"`
+	Slowest      []history.HistoryEntry `json:"slowest,omitempty" yaml:"slowest,omitempty"`
+
+	// Workflows are the steps the user habitually runs one after the other.
+	Workflows []workflow.Rule `json:"workflows,omitempty" yaml:"workflows,omitempty"`
+}
+
+// Compute builds a Report from entries, keeping the top n items of each ranking.
+func Compute(entries []history.HistoryEntry, n int) Report {
+	report := Report{Total: len(entries)}
+
+	programs := make(map[string]int)
+	commands := make(map[string]int)
+	directories := make(map[string]int)
+	hours := make([]int, 24)
+	hasTime := false
+	runs := make(map[string]int)
+	failures := make(map[string]int)
+	var timed []history.HistoryEntry
+	timedIndex := make(map[string]int)
+
+	for _, entry := range entries {
+		command := strings.TrimSpace(entry.Command)
+		if command == "" {
+			continue
+		}
+		commands[command]++
+		program := history.ProgramName(command)
+		programs[program]++
+
+		if entry.Cwd != "" {
+			directories[entry.Cwd]++
+		}
+		if entry.Timestamp > 0 {
+			at := time.Unix(entry.Timestamp, 0)
+			hours[at.Hour()]++
+			hasTime = true
+			if report.First == nil || at.Before(*report.First) {
+				report.First = &at
+			}
+			if report.Last == nil || at.After(*report.Last) {
+				report.Last = &at
+			}
+		}
+		if entry.ExitCode != nil {
+			runs[program]++
+			if entry.Failed() {
+				failures[program]++
+			}
+		}
+		if entry.DurationMS > 0 {
+			// Keep only the longest run of each command line.
+			if i, ok := timedIndex[command]; !ok {
+				timedIndex[command] = len(timed)
+				timed = append(timed, entry)
+			} else if entry.DurationMS > timed[i].DurationMS {
+				timed[i] = entry
+			}
+		}
+	}
+
+	report.Unique = len(commands)
+	report.TopPrograms = top(programs, n)
+	report.TopCommands = top(commands, n)
+	report.TopDirectories = top(directories, n)
+	if hasTime {
+		report.Hours = hours
+	}
+	report.FailureRates = failureRates(runs, failures, n)
+
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].DurationMS > timed[j].DurationMS })
+	if len(timed) > n {
+		timed = timed[:n]
+	}
+	report.Slowest = timed
+	report.Workflows = workflow.Rules(entries, n)
+
+	return report
+}
+
+// top returns the n most frequent names, most frequent first, ties broken by name.
+func top(counts map[string]int, n int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// failureRates returns the n programs that fail most often among those run at
+// least minRunsForFailureRate times, highest rate first.
+func failureRates(runs, failures map[string]int, n int) []FailureRate {
+	var result []FailureRate
+	for program, count := range runs {
+		if count < minRunsForFailureRate || failures[program] == 0 {
+			continue
+		}
+		result = append(result, FailureRate{
+			Program:  program,
+			Runs:     count,
+			Failures: failures[program],
+			Rate:     float64(failures[program]) / float64(count),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rate != result[j].Rate {
+			return result[i].Rate > result[j].Rate
+		}
+		return result[i].Program < result[j].Program
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
@@ -0,0 +1,251 @@
+// Package team reads the snippets and runbooks a team shares in a git repository,
+// so that find and suggest can offer "how we deploy" alongside the user's own
+// history.
+//
+// The repository holds YAML files, each a list of snippets with a command and
+// optionally a name and description, and Markdown runbooks, whose shell code blocks
+// are read as commands described by the heading above them.
+package team
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+// Snippet is a command shared by the team.
+type Snippet struct {
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Command     string `json:"command" yaml:"command"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// File is the file of the repository the snippet was read from, relative to its root.
+	File string `json:"file" yaml:"file"`
+}
+
+// Label describes the snippet by its name and description.
+func (s Snippet) Label() string {
+	switch {
+	case s.Name != "" && s.Description != "":
+		return s.Name + ": " + s.Description
+	case s.Name != "":
+		return s.Name
+	default:
+		return s.Description
+	}
+}
+
+// shellLanguages are the info strings of the Markdown code blocks read as commands.
+// Blocks without one are read too.
+var shellLanguages = map[string]bool{"sh": true, "bash": true, "zsh": true, "shell": true, "console": true}
+
+// IsLocal reports whether repo is a directory on this machine rather than a git URL.
+func IsLocal(repo string) bool {
+	if strings.Contains(repo, "://") {
+		return false
+	}
+	info, err := os.Stat(repo)
+	return err == nil && info.IsDir()
+}
+
+// Dir returns the directory the snippets of repo are read from: repo itself when it
+// is a local directory, and otherwise its clone in historai's data directory.
+func Dir(repo string) (string, error) {
+	if IsLocal(repo) {
+		return repo, nil
+	}
+	dataDir, err := history.DataDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(repo))
+	return filepath.Join(dataDir, "team", hex.EncodeToString(sum[:8])), nil
+}
+
+// Pull clones repo on first use, and then updates the clone to the latest commit of
+// branch (the default branch when empty), discarding local changes. Local
+// directories are left alone. It returns the directory the snippets are read from.
+func Pull(ctx context.Context, logger *zap.Logger, repo, branch string) (string, error) {
+	dir, err := Dir(repo)
+	if err != nil || IsLocal(repo) {
+		return dir, err
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", errors.New("team snippets require the git executable in PATH")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		logger.Debug("Cloning team repository", zap.String("dir", dir))
+		if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(dir), err)
+		}
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if branch != "" {
+			args = append(args, "--branch", branch)
+		}
+		if _, err := git(ctx, logger, "", append(args, "--", repo, dir)...); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+
+	ref := "HEAD"
+	if branch != "" {
+		ref = branch
+	}
+	if _, err := git(ctx, logger, dir, "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+		return "", err
+	}
+	if _, err := git(ctx, logger, dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Updated returns when the clone in dir was last updated, as the date of its
+// checked out commit, or "" when dir isn't a git checkout.
+func Updated(ctx context.Context, dir string) string {
+	date, err := git(ctx, zap.NewNop(), dir, "log", "-1", "--format=%cd", "--date=short")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(date)
+}
+
+// git runs git in dir and returns its output.
+func git(ctx context.Context, logger *zap.Logger, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logger.Debug("Running git", zap.Strings("args", args))
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+	return stdout.String(), nil
+}
+
+// Load reads the snippets of the YAML files and Markdown runbooks under dir, ordered
+// by file. A missing dir is an error; files that can't be parsed are skipped and
+// logged, so one bad file doesn't hide the others.
+func Load(logger *zap.Logger, dir string) ([]Snippet, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("no team snippets at %s: %w", dir, err)
+	}
+	var snippets []Snippet
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		var parse func([]byte) ([]Snippet, error)
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			parse = parseYAML
+		case ".md", ".markdown":
+			parse = parseMarkdown
+		default:
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		parsed, err := parse(data)
+		if err != nil {
+			logger.Warn("Skipping team snippets file", zap.String("path", path), zap.Error(err))
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		for _, snippet := range parsed {
+			snippet.File = filepath.ToSlash(rel)
+			snippets = append(snippets, snippet)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read team snippets: %w", err)
+	}
+	sort.SliceStable(snippets, func(i, j int) bool { return snippets[i].File < snippets[j].File })
+	logger.Debug("Loaded team snippets", zap.String("dir", dir), zap.Int("snippets_count", len(snippets)))
+	return snippets, nil
+}
+
+// parseYAML reads a YAML list of snippets, skipping those without a command.
+func parseYAML(data []byte) ([]Snippet, error) {
+	var parsed []Snippet
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	var snippets []Snippet
+	for _, snippet := range parsed {
+		snippet.Command = strings.TrimSpace(snippet.Command)
+		snippet.Description = strings.TrimSpace(snippet.Description)
+		if snippet.Command != "" {
+			snippets = append(snippets, snippet)
+		}
+	}
+	return snippets, nil
+}
+
+// parseMarkdown reads the commands of a runbook's shell code blocks, each described
+// by the closest heading above it. Lines ending in a backslash continue on the next
+// one, and a leading "$ " prompt is dropped.
+func parseMarkdown(data []byte) ([]Snippet, error) {
+	var snippets []Snippet
+	var heading, fence, pending string
+	inShell := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+			language := strings.ToLower(strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])))
+			language, _, _ = strings.Cut(language, " ")
+			inShell = language == "" || shellLanguages[language]
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			fence, pending = "", ""
+		case fence == "" && strings.HasPrefix(trimmed, "#"):
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		case fence != "" && inShell:
+			if pending == "" && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+				continue
+			}
+			trimmed = strings.TrimPrefix(trimmed, "$ ")
+			if strings.HasSuffix(trimmed, "\\") {
+				pending += strings.TrimSuffix(trimmed, "\\")
+				continue
+			}
+			snippets = append(snippets, Snippet{Command: strings.TrimSpace(pending + trimmed), Description: heading})
+			pending = ""
+		}
+	}
+	return snippets, scanner.Err()
+}
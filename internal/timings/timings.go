@@ -0,0 +1,250 @@
+// Package timings measures how long each stage of a historai invocation takes, and
+// keeps a log of them in the state directory, so that 'historai stats --latency' can
+// show where time goes and which providers are slow.
+package timings
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/logfile"
+)
+
+// Stages of an invocation, in the order they happen.
+const (
+	// StageParse reads and parses the history file.
+	StageParse = "parse"
+
+	// StageRetrieve selects the context sent with the prompt: filtering, annotations,
+	// redaction, workflows and the environment.
+	StageRetrieve = "retrieve"
+
+	// StageLLM waits for the LLM provider.
+	StageLLM = "llm"
+
+	// StageDaemon waits for the daemon, which parses, retrieves and calls the
+	// provider in its place.
+	StageDaemon = "daemon"
+
+	// StageRender prints the answer.
+	StageRender = "render"
+)
+
+// Stages lists the stages in display order.
+var Stages = []string{StageParse, StageRetrieve, StageLLM, StageDaemon, StageRender}
+
+const (
+	fileName = "timings.jsonl"
+
+	// maxSize is the size at which the log is rotated to timings.jsonl.1, the only
+	// older copy kept.
+	maxSize = 1024 * 1024
+)
+
+// Timings adds up the time spent in each stage of an invocation. It is safe for
+// concurrent use, and a nil *Timings measures nothing.
+type Timings struct {
+	mu     sync.Mutex
+	stages map[string]time.Duration
+}
+
+// New creates empty Timings.
+func New() *Timings {
+	return &Timings{stages: make(map[string]time.Duration)}
+}
+
+// Add adds d to the time spent in stage.
+func (t *Timings) Add(stage string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stages[stage] += d
+}
+
+// Start starts measuring stage, until the returned function is called.
+func (t *Timings) Start(stage string) (stop func()) {
+	started := time.Now()
+	return func() {
+		t.Add(stage, time.Since(started))
+	}
+}
+
+// Stages returns the time spent in each stage that was measured.
+func (t *Timings) Stages() map[string]time.Duration {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stages := make(map[string]time.Duration, len(t.stages))
+	for stage, d := range t.stages {
+		stages[stage] = d
+	}
+	return stages
+}
+
+// Record is an invocation, as written to the timings log.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+
+	// Stages holds the milliseconds spent in each stage that was measured.
+	Stages  map[string]int64 `json:"stages_ms"`
+	TotalMS int64            `json:"total_ms"`
+	Failed  bool             `json:"failed,omitempty"`
+}
+
+// NewRecord creates the record of command, which took total and spent t in its
+// stages.
+func NewRecord(command string, t *Timings, total time.Duration, failed bool) Record {
+	record := Record{
+		Time:    time.Now().UTC(),
+		Command: command,
+		Stages:  make(map[string]int64),
+		TotalMS: total.Milliseconds(),
+		Failed:  failed,
+	}
+	for stage, d := range t.Stages() {
+		record.Stages[stage] = d.Milliseconds()
+	}
+	return record
+}
+
+// DefaultPath returns the location of the timings log,
+// $XDG_STATE_HOME/historai/timings.jsonl or ~/.local/state/historai/timings.jsonl.
+func DefaultPath() (string, error) {
+	dir, err := logfile.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Append writes record to the timings log at path, rotating it when it gets large.
+func Append(path string, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode timings: %w", err)
+	}
+	writer, err := logfile.Open(path, maxSize, 1)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(append(data, '\n')); err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("failed to write timings log %s: %w", path, err)
+	}
+	return writer.Close()
+}
+
+// Read returns the records of the timings log at path and its rotated copy, oldest
+// first. A missing log has no records; malformed lines are skipped.
+func Read(path string) ([]Record, error) {
+	var records []Record
+	for _, name := range []string{path + ".1", path} {
+		file, err := os.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open timings log: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record Record
+			if json.Unmarshal(scanner.Bytes(), &record) == nil {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read timings log %s: %w", name, err)
+		}
+	}
+	return records, nil
+}
+
+// Summary describes the latency of a group of invocations, such as those of a
+// command or of a model.
+type Summary struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+
+	// Failed counts the invocations that failed.
+	Failed int `json:"failed,omitempty" yaml:"failed,omitempty"`
+
+	// P50MS, P90MS and P99MS are percentiles of the total time, in milliseconds.
+	P50MS int64 `json:"p50_ms" yaml:"p50_ms"`
+	P90MS int64 `json:"p90_ms" yaml:"p90_ms"`
+	P99MS int64 `json:"p99_ms" yaml:"p99_ms"`
+
+	// StagesP50MS holds the median of each stage, over the invocations that had it.
+	StagesP50MS map[string]int64 `json:"stages_p50_ms" yaml:"stages_p50_ms"`
+}
+
+// Summarize groups records by the name key gives them, skipping those it gives
+// none, and summarizes each group. Groups are sorted by decreasing count.
+func Summarize(records []Record, key func(Record) string) []Summary {
+	groups := make(map[string][]Record)
+	var names []string
+	for _, record := range records {
+		name := key(record)
+		if name == "" {
+			continue
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], record)
+	}
+
+	summaries := make([]Summary, 0, len(names))
+	for _, name := range names {
+		group := groups[name]
+		summary := Summary{Name: name, Count: len(group), StagesP50MS: make(map[string]int64)}
+		totals := make([]int64, 0, len(group))
+		stages := make(map[string][]int64)
+		for _, record := range group {
+			if record.Failed {
+				summary.Failed++
+			}
+			totals = append(totals, record.TotalMS)
+			for stage, ms := range record.Stages {
+				stages[stage] = append(stages[stage], ms)
+			}
+		}
+		summary.P50MS = percentile(totals, 50)
+		summary.P90MS = percentile(totals, 90)
+		summary.P99MS = percentile(totals, 99)
+		for stage, values := range stages {
+			summary.StagesP50MS[stage] = percentile(values, 50)
+		}
+		summaries = append(summaries, summary)
+	}
+	slices.SortStableFunc(summaries, func(a, b Summary) int { return b.Count - a.Count })
+	return summaries
+}
+
+// percentile returns the p-th percentile of values, by the nearest-rank method.
+// It sorts values.
+func percentile(values []int64, p int) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+	rank := (p*len(values) + 99) / 100
+	return values[max(rank, 1)-1]
+}
@@ -0,0 +1,66 @@
+// Package version holds historai's build metadata. Release builds set it at link
+// time, for example:
+//
+//	go build -ldflags "-X github.com/sanspareilsmyn/historai/internal/version.Version=v1.2.0
+//	  -X github.com/sanspareilsmyn/historai/internal/version.Commit=$(git rev-parse HEAD)
+//	  -X github.com/sanspareilsmyn/historai/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/historai
+//
+// Other builds fall back to what the Go toolchain embeds: the module version for
+// 'go install', and the commit and its time for builds from a git checkout.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X by release builds.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Date      string `json:"date,omitempty" yaml:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty" yaml:"modified,omitempty"`
+	GoVersion string `json:"go_version" yaml:"go_version"`
+	Platform  string `json:"platform" yaml:"platform"`
+}
+
+// Get returns the build metadata, "dev" being the version of untagged builds.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
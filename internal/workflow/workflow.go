@@ -0,0 +1,289 @@
+// Package workflow mines the user's history, locally and without any LLM call, for
+// the series of commands they repeat: exact command sequences worth turning into
+// build targets, and habits such as "after git checkout you run npm install, then
+// npm test".
+package workflow
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sanspareilsmyn/historai/internal/history"
+)
+
+const (
+	// minSequenceRuns is how often a series of commands must recur to be reported.
+	minSequenceRuns = 3
+
+	// maxSequenceLength bounds the length of the series looked for.
+	maxSequenceLength = 6
+
+	// maxFollowLength bounds the number of steps a Rule predicts.
+	maxFollowLength = 3
+
+	// minConfidence is the share of a step's runs that must be followed by the same
+	// steps for a Rule to be reported.
+	minConfidence = 0.6
+
+	// sessionGap splits history into sessions: commands further apart than this are
+	// not considered part of the same workflow.
+	sessionGap = time.Hour
+)
+
+// noiseCommands are programs that are typed between the steps of a workflow without
+// being part of it.
+var noiseCommands = map[string]bool{
+	"ls": true, "ll": true, "la": true, "l": true, "clear": true, "pwd": true,
+	"history": true, "exit": true, "historai": true,
+}
+
+// Sequence is a series of command lines run one after the other, and how often it recurred.
+type Sequence struct {
+	Commands []string `json:"commands" yaml:"commands"`
+	Count    int      `json:"count" yaml:"count"`
+}
+
+// Rule is a habit: after the step After, the user went on with the steps Then in
+// Count of the Runs times they ran After. Steps are normalized with Step.
+type Rule struct {
+	After      string   `json:"after" yaml:"after"`
+	Then       []string `json:"then" yaml:"then"`
+	Count      int      `json:"count" yaml:"count"`
+	Runs       int      `json:"runs" yaml:"runs"`
+	Confidence float64  `json:"confidence" yaml:"confidence"`
+}
+
+// Steps returns After followed by Then.
+func (r Rule) Steps() []string {
+	return append([]string{r.After}, r.Then...)
+}
+
+// Sequences finds series of two or more distinct command lines that were run one
+// after the other at least minSequenceRuns times. A series contained in a longer one
+// that recurred as often is not reported separately. The n series that would save
+// the most typing (runs times length) are returned, best first.
+func Sequences(entries []history.HistoryEntry, n int) []Sequence {
+	counts := make(map[string]int)
+	for _, session := range sessions(entries, strings.TrimSpace) {
+		for length := 2; length <= maxSequenceLength; length++ {
+			for i := 0; i+length <= len(session); i++ {
+				if gram := session[i : i+length]; distinct(gram) {
+					counts[strings.Join(gram, "\n")]++
+				}
+			}
+		}
+	}
+
+	var found []Sequence
+	for key, count := range counts {
+		if count >= minSequenceRuns {
+			found = append(found, Sequence{Commands: strings.Split(key, "\n"), Count: count})
+		}
+	}
+	found = maximal(found, func(s Sequence) ([]string, int) { return s.Commands, s.Count })
+	sort.Slice(found, func(i, j int) bool {
+		si, sj := found[i].Count*len(found[i].Commands), found[j].Count*len(found[j].Commands)
+		if si != sj {
+			return si > sj
+		}
+		return strings.Join(found[i].Commands, "\n") < strings.Join(found[j].Commands, "\n")
+	})
+	if len(found) > n {
+		found = found[:n]
+	}
+	return found
+}
+
+// Rules finds the steps that the user usually follows with the same steps, e.g.
+// "git checkout" → "npm install" → "npm test". Commands are compared by Step, so
+// that "git checkout main" and "git checkout fix-123" count as the same step. For
+// each step, the longest continuation seen in at least minConfidence of its runs is
+// kept. The n rules covering the most steps run are returned, best first.
+func Rules(entries []history.HistoryEntry, n int) []Rule {
+	runs := make(map[string]int)
+	follows := make(map[string]map[string]int)
+	for _, session := range sessions(entries, Step) {
+		for i, step := range session {
+			runs[step]++
+			for length := 1; length <= maxFollowLength && i+1+length <= len(session); length++ {
+				gram := session[i : i+1+length]
+				if !distinct(gram) {
+					break
+				}
+				if follows[step] == nil {
+					follows[step] = make(map[string]int)
+				}
+				follows[step][strings.Join(gram[1:], "\n")]++
+			}
+		}
+	}
+
+	var found []Rule
+	for step, stepRuns := range runs {
+		if stepRuns < minSequenceRuns {
+			continue
+		}
+		var best *Rule
+		for key, count := range follows[step] {
+			confidence := float64(count) / float64(stepRuns)
+			if count < minSequenceRuns || confidence < minConfidence {
+				continue
+			}
+			rule := Rule{After: step, Then: strings.Split(key, "\n"), Count: count, Runs: stepRuns, Confidence: confidence}
+			if best == nil || len(rule.Then) > len(best.Then) || (len(rule.Then) == len(best.Then) && rule.Count > best.Count) {
+				best = &rule
+			}
+		}
+		if best != nil {
+			found = append(found, *best)
+		}
+	}
+	found = maximal(found, func(r Rule) ([]string, int) { return r.Steps(), r.Count })
+	sort.Slice(found, func(i, j int) bool {
+		si, sj := found[i].Count*len(found[i].Then), found[j].Count*len(found[j].Then)
+		if si != sj {
+			return si > sj
+		}
+		return found[i].After < found[j].After
+	})
+	if len(found) > n {
+		found = found[:n]
+	}
+	return found
+}
+
+// Step normalizes a command line to the step of a workflow it performs: the program
+// and, for programs with subcommands, the subcommand. Arguments that vary between
+// runs, such as branches, paths and flags, are dropped: "git checkout -b fix-1" is
+// "git checkout", "npm run build" is "npm run build" and "vim main.go" is "vim".
+func Step(command string) string {
+	fields := strings.Fields(command)
+	for i, field := range fields {
+		if field == "sudo" || strings.Contains(field, "=") {
+			continue
+		}
+		step := filepath.Base(field)
+		if argumentPrograms[step] {
+			return step
+		}
+		for _, arg := range fields[i+1:] {
+			if !isSubcommand(arg) {
+				break
+			}
+			step += " " + arg
+			if !nestedSubcommands[step] {
+				break
+			}
+		}
+		return step
+	}
+	return ""
+}
+
+// argumentPrograms take a value, such as a file or host, rather than a subcommand
+// as their first argument.
+var argumentPrograms = map[string]bool{
+	"cd": true, "vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "code": true,
+	"cat": true, "less": true, "man": true, "open": true, "ssh": true, "ping": true,
+	"rm": true, "cp": true, "mv": true, "mkdir": true, "touch": true, "echo": true, "source": true,
+}
+
+// nestedSubcommands are subcommands that take a subcommand of their own.
+var nestedSubcommands = map[string]bool{
+	"npm run": true, "yarn run": true, "pnpm run": true, "docker compose": true,
+	"kubectl rollout": true, "kubectl config": true, "go mod": true, "git stash": true,
+}
+
+// isSubcommand reports whether arg looks like a subcommand (a lowercase word, possibly
+// hyphenated) rather than a flag, path, number or other value.
+func isSubcommand(arg string) bool {
+	if arg == "" || arg[0] < 'a' || arg[0] > 'z' {
+		return false
+	}
+	for _, r := range arg {
+		if !('a' <= r && r <= 'z') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// sessions splits entries into runs of command lines, normalized by key, without
+// noise, breaking where the host changes or the timestamps are more than sessionGap apart.
+func sessions(entries []history.HistoryEntry, key func(string) string) [][]string {
+	var result [][]string
+	var current []string
+	var last history.HistoryEntry
+	for i, entry := range entries {
+		command := strings.TrimSpace(entry.Command)
+		if command == "" || noiseCommands[history.ProgramName(command)] {
+			continue
+		}
+		gap := last.Timestamp > 0 && entry.Timestamp > 0 && time.Duration(entry.Timestamp-last.Timestamp)*time.Second > sessionGap
+		if i > 0 && (gap || entry.Host != last.Host) && len(current) > 0 {
+			result = append(result, current)
+			current = nil
+		}
+		// A step repeated back to back is one step of the workflow.
+		if step := key(command); len(current) == 0 || current[len(current)-1] != step {
+			current = append(current, step)
+		}
+		last = entry
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// distinct reports whether no step occurs twice in steps.
+func distinct(steps []string) bool {
+	seen := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if seen[step] {
+			return false
+		}
+		seen[step] = true
+	}
+	return true
+}
+
+// maximal drops the items whose steps are contained in those of a longer item that
+// recurred at least as often.
+func maximal[T any](items []T, steps func(T) ([]string, int)) []T {
+	var kept []T
+	for _, item := range items {
+		itemSteps, itemCount := steps(item)
+		subsumed := false
+		for _, other := range items {
+			otherSteps, otherCount := steps(other)
+			if len(otherSteps) > len(itemSteps) && otherCount >= itemCount && contains(otherSteps, itemSteps) {
+				subsumed = true
+				break
+			}
+		}
+		if !subsumed {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// contains reports whether part occurs contiguously in whole.
+func contains(whole, part []string) bool {
+	for i := 0; i+len(part) <= len(whole); i++ {
+		match := true
+		for j := range part {
+			if whole[i+j] != part[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}